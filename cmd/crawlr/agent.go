@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"crawlr/internal/config"
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+	"crawlr/internal/logger"
+	"crawlr/internal/queue"
+
+	"github.com/spf13/cobra"
+)
+
+// agentFlagMappings maps agent flags to their viper configuration keys
+var agentFlagMappings = map[string]string{
+	"queue":   "queue_url",
+	"subject": "queue_subject",
+	"group":   "queue_group",
+}
+
+var submitWait bool
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run as a worker pulling crawl jobs from a shared queue",
+	Long: `Run crawlr as an agent that pulls crawl jobs from a shared queue. Multiple
+agents of the same group share the workload, each job being delivered to
+exactly one agent. Agents should point --output at shared storage.
+
+The queue is a JetStream work-queue stream, so the NATS server must run with
+JetStream enabled. Submitted jobs are stored until an agent takes them, even
+when no agent is running yet.
+
+Job fields left empty (max depth, max URLs, ...) fall back to the agent's own
+configuration.`,
+	Example: `crawlr agent --queue nats://queue.internal:4222 --output /mnt/shared/libraries
+  crawlr agent submit --queue nats://queue.internal:4222 -u https://docs.example.com -l example-docs`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		agentCfg, _, err := loadConfig(cmd, agentFlagMappings)
+		if err != nil {
+			return err
+		}
+		if agentCfg.QueueURL == "" {
+			return errors.New(errors.ValidationError, "queue URL is required (--queue or queue_url)")
+		}
+
+		appLogger, err = newLogger(agentCfg)
+		if err != nil {
+			return err
+		}
+		defer appLogger.Close()
+
+		flushTraces, err := setupTracing(agentCfg, appLogger)
+		if err != nil {
+			return err
+		}
+		defer flushTraces()
+
+		hostname, _ := os.Hostname()
+		agentName := fmt.Sprintf("crawlr-agent@%s-%d", hostname, os.Getpid())
+
+		q, err := queue.Open(agentCfg.QueueURL, queue.Options{
+			Subject: agentCfg.QueueSubject,
+			Group:   agentCfg.QueueGroup,
+			Name:    agentName,
+		})
+		if err != nil {
+			return errors.Wrap(err, errors.NetworkError, "failed to connect to queue")
+		}
+		defer q.Close()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		appLogger.Info("Agent started", map[string]interface{}{
+			"agent":   agentName,
+			"subject": agentCfg.QueueSubject,
+			"group":   agentCfg.QueueGroup,
+			"output":  agentCfg.Output,
+		})
+
+		for {
+			delivery, err := q.Receive(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				if stderrors.Is(err, queue.ErrMalformedJob) {
+					appLogger.Warn("Discarding malformed job", map[string]interface{}{"error": err})
+					continue
+				}
+				return errors.Wrap(err, errors.NetworkError, "failed to receive job")
+			}
+
+			result := runJob(ctx, agentCfg, delivery.Job, agentName, appLogger)
+			if err := delivery.Respond(result); err != nil {
+				appLogger.Warn("Failed to report job result", map[string]interface{}{
+					"jobID": result.JobID,
+					"error": err,
+				})
+			}
+		}
+
+		appLogger.Info("Agent stopped", map[string]interface{}{"agent": agentName})
+		return nil
+	},
+}
+
+var agentSubmitCmd = &cobra.Command{
+	Use:   "submit",
+	Short: "Submit a crawl job to the shared queue",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		submitCfg, _, err := loadConfig(cmd, agentFlagMappings)
+		if err != nil {
+			return err
+		}
+		if submitCfg.QueueURL == "" {
+			return errors.New(errors.ValidationError, "queue URL is required (--queue or queue_url)")
+		}
+		if submitCfg.URL == "" || submitCfg.Library == "" {
+			return errors.New(errors.ValidationError, "url and library are required")
+		}
+
+		job := &queue.Job{
+			ID:          newJobID(),
+			URL:         submitCfg.URL,
+			Library:     submitCfg.Library,
+			SubmittedAt: time.Now(),
+		}
+		// Only forward crawl limits that were set explicitly, so agents apply their own defaults otherwise
+		changed := changedConfigKeys(cmd)
+		if changed["max_depth"] {
+			job.MaxDepth = submitCfg.MaxDepth
+		}
+		if changed["max_urls"] {
+			job.MaxURLs = submitCfg.MaxURLs
+		}
+		if changed["batch_size"] {
+			job.BatchSize = submitCfg.BatchSize
+		}
+		if changed["exclude_patterns"] {
+			job.ExcludePatterns = submitCfg.ExcludePatterns
+		}
+
+		q, err := queue.Open(submitCfg.QueueURL, queue.Options{
+			Subject: submitCfg.QueueSubject,
+			Group:   submitCfg.QueueGroup,
+		})
+		if err != nil {
+			return errors.Wrap(err, errors.NetworkError, "failed to connect to queue")
+		}
+		defer q.Close()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if !submitWait {
+			if err := q.Publish(ctx, job); err != nil {
+				return errors.Wrap(err, errors.NetworkError, "failed to submit job")
+			}
+			fmt.Fprint(cmd.OutOrStdout(), i18n.T("agent.submitted", job.ID))
+			return nil
+		}
+
+		result, err := q.Request(ctx, job)
+		if err != nil {
+			return errors.Wrap(err, errors.NetworkError, "failed to submit job")
+		}
+		if !result.Success {
+			return errors.New(errors.CrawlerError, fmt.Sprintf("job %s failed on %s: %s", result.JobID, result.Agent, result.Error))
+		}
+		fmt.Fprint(cmd.OutOrStdout(), i18n.T("agent.completed",
+			result.JobID, result.Agent, result.FinishedAt.Sub(result.StartedAt).Round(time.Millisecond)))
+		return nil
+	},
+}
+
+// runJob applies a job to a copy of the agent configuration and crawls it
+func runJob(ctx context.Context, agentCfg *config.Config, job *queue.Job, agentName string, appLogger *logger.Logger) *queue.JobResult {
+	result := &queue.JobResult{
+		JobID:     job.ID,
+		Agent:     agentName,
+		StartedAt: time.Now(),
+	}
+
+	jobCfg := jobConfig(agentCfg, job)
+
+	appLogger.Info("Received job", map[string]interface{}{
+		"jobID":   job.ID,
+		"url":     job.URL,
+		"library": job.Library,
+	})
+
+	err := jobCfg.Validate()
+	if err != nil {
+		err = errors.Wrap(err, errors.ValidationError, "invalid job")
+	} else {
+		err = runCrawl(ctx, &jobCfg, appLogger, crawlOptions{})
+	}
+
+	result.FinishedAt = time.Now()
+	if err != nil {
+		result.Error = err.Error()
+		appLogger.Error("Job failed", map[string]interface{}{
+			"jobID": job.ID,
+			"error": err,
+		})
+	} else {
+		result.Success = true
+		appLogger.Info("Job completed", map[string]interface{}{
+			"jobID":    job.ID,
+			"duration": result.FinishedAt.Sub(result.StartedAt).Round(time.Millisecond),
+		})
+	}
+
+	return result
+}
+
+// jobConfig returns a copy of base with the settings of a job applied
+func jobConfig(base *config.Config, job *queue.Job) config.Config {
+	jobCfg := *base
+	jobCfg.URL = job.URL
+	jobCfg.Library = job.Library
+	if job.MaxDepth > 0 {
+		jobCfg.MaxDepth = job.MaxDepth
+	}
+	if job.MaxURLs > 0 {
+		jobCfg.MaxURLs = job.MaxURLs
+	}
+	if job.BatchSize > 0 {
+		jobCfg.BatchSize = job.BatchSize
+	}
+	if job.ExcludePatterns != "" {
+		jobCfg.ExcludePatterns = job.ExcludePatterns
+	}
+	return jobCfg
+}
+
+// newJobID returns a random identifier for a job
+func newJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+func init() {
+	agentCmd.PersistentFlags().String("queue", "", "Job queue URL (e.g. nats://localhost:4222)")
+	agentCmd.PersistentFlags().String("subject", "crawlr.jobs", "Queue subject jobs are published on")
+	agentCmd.PersistentFlags().String("group", "crawlr-agents", "Queue group shared by cooperating agents")
+
+	agentSubmitCmd.Flags().BoolVar(&submitWait, "wait", false, "Wait for an agent to report the job result")
+
+	agentCmd.AddCommand(agentSubmitCmd)
+	rootCmd.AddCommand(agentCmd)
+}
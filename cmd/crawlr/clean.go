@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+	"crawlr/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cleanAll    bool
+	cleanDryRun bool
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove the files earlier crawls left in a library",
+	Long: `Remove the files earlier crawls left at the top of a library besides its
+content: the checkpoint of a cancelled crawl, the previous manifest kept for
+crawlr diff, and the crawl tree and sitemap coverage reports.
+
+With --all, the whole library is removed.`,
+	Example: `crawlr clean -l mylib -o ./libraries --dry-run
+  crawlr clean -l mylib -o ./libraries --all`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cleanCfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if cleanCfg.Library == "" {
+			return errors.New(errors.ConfigurationError, "library is required")
+		}
+
+		libraryPath := filepath.Join(cleanCfg.Output, cleanCfg.Library)
+		removed, err := storage.CleanLibrary(libraryPath, cleanAll, cleanDryRun)
+		if os.IsNotExist(err) {
+			return errors.Wrap(err, errors.StorageError, "library not found").WithContext("library", libraryPath)
+		}
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to clean library")
+		}
+
+		out := cmd.OutOrStdout()
+		for _, name := range removed {
+			fmt.Fprintln(out, name)
+		}
+		key := "clean.removed"
+		if cleanDryRun {
+			key = "clean.would_remove"
+		}
+		fmt.Fprint(out, i18n.T(key, len(removed)))
+		return nil
+	},
+}
+
+func init() {
+	cleanCmd.Flags().BoolVar(&cleanAll, "all", false, "Remove the whole library")
+	cleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "List the files to remove without removing them")
+
+	rootCmd.AddCommand(cleanCmd)
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"crawlr/internal/config"
+	"crawlr/internal/crawler"
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	showResolved    bool
+	validateOffline bool
+	initForce       bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Create, inspect and edit crawlr configuration",
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a starter configuration file",
+	Long: `Write a starter configuration file listing every setting with its default
+value and a comment, and commented-out examples of the nested sections such as
+auth and transforms. The file is $XDG_CONFIG_HOME/crawlr/config.yaml
+(~/.config/crawlr/config.yaml), or the one given with --config.
+
+crawlr runs with its defaults when there is no configuration file; it never
+creates one by itself. An existing file is kept unless --force is set.`,
+	Example: `crawlr config init
+  crawlr config init --config ./crawlr.yaml
+  crawlr config init --force`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := configFilePath()
+		if path == "" {
+			path = config.UserConfigPath()
+		}
+		if path == "" {
+			return errors.New(errors.ConfigurationError, "no home directory to write the configuration file to, use --config")
+		}
+		if _, err := os.Stat(path); err == nil && !initForce {
+			return errors.New(errors.ConfigurationError, "configuration file already exists, use --force to replace it").
+				WithContext("path", path)
+		}
+		if err := config.WriteStarterConfig(path); err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to write configuration file")
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), i18n.T("config.init.done", path))
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Set a value in the configuration file",
+	Long: `Set a top-level key of the configuration file in use, keeping its other
+settings and comments. Without one, the file of the user is created, as by
+crawlr config init.
+
+The value is checked against the type and the rules of the key before the file
+is written; lists are given comma-separated. Nested sections, such as auth and
+transforms, can only be edited in the file.`,
+	Example: `crawlr config set max_depth 3
+  crawlr config set server_url http://localhost:11235
+  crawlr config set expect_urls '/api/,/guides/install$'`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, text := args[0], args[1]
+		_, v, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		value, err := config.ParseValue(key, text)
+		if err != nil {
+			return errors.Wrap(err, errors.ValidationError, "invalid value")
+		}
+
+		// Check the value as the configuration would have it, reporting the
+		// violations of this key only
+		v.Set(key, value)
+		updated, err := config.LoadConfigWithViper(v)
+		if err != nil {
+			return errors.Wrap(err, errors.ConfigurationError, "failed to load configuration")
+		}
+		var invalid, keyInvalid config.ValidationErrors
+		if stderrors.As(updated.Validate(), &invalid) {
+			for _, fieldErr := range invalid {
+				if fieldErr.Field == key || strings.HasPrefix(fieldErr.Field, key+"[") {
+					keyInvalid = append(keyInvalid, fieldErr)
+				}
+			}
+		}
+		if len(keyInvalid) > 0 {
+			return errors.Wrap(keyInvalid, errors.ValidationError, "invalid value")
+		}
+
+		path := configWritePath(v)
+		if path == "" {
+			return errors.New(errors.ConfigurationError, "no home directory to write the configuration file to, use --config")
+		}
+		if err := config.SetFileValues(path, map[string]interface{}{key: value}, []string{key}); err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to write configuration file")
+		}
+		if config.IsSecretKey(key) {
+			text = config.RedactedValue
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), i18n.T("config.set.done", key, text, path))
+		return nil
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	Long: `Print the effective configuration after merging defaults, the config file,
+CRAWLR_* environment variables and command-line flags. Secrets are redacted.
+
+With --resolved, the source of each value (default, file, env or flag) and the
+environment variable that sets it are printed as well.`,
+	Example: `crawlr config show
+  crawlr config show --resolved
+  CRAWLR_TIMEOUT=60 crawlr config show --resolved --max-depth 3`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, v, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		if file := v.ConfigFileUsed(); file != "" {
+			fmt.Fprintf(cmd.OutOrStdout(), "# config file: %s\n", file)
+		}
+
+		resolved := config.Resolve(v, changedConfigKeys(cmd))
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		if showResolved {
+			fmt.Fprintln(w, "KEY\tVALUE\tSOURCE\tENV")
+		}
+		for _, rv := range resolved {
+			if showResolved {
+				envVar := rv.EnvVar
+				if envVar == "" {
+					envVar = "-"
+				}
+				fmt.Fprintf(w, "%s\t%v\t%s\t%s\n", rv.Key, rv.Value, rv.Source, envVar)
+			} else {
+				fmt.Fprintf(w, "%s\t%v\n", rv.Key, rv.Value)
+			}
+		}
+		return w.Flush()
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration without crawling",
+	Long: `Load the effective configuration and check every value: URL formats,
+numeric ranges, regular expressions, enumerations such as log_level, and
+conflicting options. The output directory must be writable, or creatable, and
+the crawl4ai server must be reachable unless --offline is set.
+
+Each invalid value is printed with its key. url, library and output are
+required as for a crawl; pass them as flags to validate the configuration of a
+given crawl. Nothing is crawled or written, and the exit status is non-zero if
+any check fails.`,
+	Example: `crawlr config validate
+  crawlr config validate -u https://docs.example.com -l docs -o ./libraries
+  crawlr config validate --offline`,
+	Args: cobra.NoArgs,
+	// The checks already explain what failed
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+
+		validateCfg, v, err := loadConfig(cmd)
+		if err != nil {
+			printCheck(out, checkResult{
+				Name:   "config",
+				Status: checkFail,
+				Detail: err.Error(),
+				Hint:   i18n.T("doctor.config.hint"),
+			})
+			return errors.New(errors.ConfigurationError, i18n.T("config.validate.problems", 1))
+		}
+		if file := v.ConfigFileUsed(); file != "" {
+			fmt.Fprintf(out, "# config file: %s\n", file)
+		}
+
+		var results []checkResult
+		var invalid config.ValidationErrors
+		if err := validateCfg.Validate(); stderrors.As(err, &invalid) {
+			for _, fieldErr := range invalid {
+				results = append(results, checkResult{Name: fieldErr.Field, Status: checkFail, Detail: fieldErr.Message})
+			}
+		} else if err != nil {
+			results = append(results, checkResult{Name: "config", Status: checkFail, Detail: err.Error()})
+		} else {
+			results = append(results, checkResult{Name: "config", Status: checkOK, Detail: i18n.T("config.validate.valid")})
+		}
+		if validateCfg.Output != "" {
+			results = append(results, checkOutputWritable(validateCfg))
+		}
+		results = append(results, checkServerReachable(cmd, validateCfg))
+
+		failed := 0
+		for _, result := range results {
+			printCheck(out, result)
+			if result.Status == checkFail {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return errors.New(errors.ValidationError, i18n.T("config.validate.problems", failed))
+		}
+		fmt.Fprintln(out, "\n"+i18n.T("config.validate.passed"))
+		return nil
+	},
+}
+
+// checkOutputWritable verifies that files can be created in the output
+// directory without creating it: a directory that does not exist yet must be
+// creatable in its nearest existing parent
+func checkOutputWritable(cfg *config.Config) checkResult {
+	result := checkResult{Name: "output", Status: checkOK, Detail: i18n.T("doctor.output.ok", cfg.Output)}
+
+	dir := existingParent(cfg.Output)
+	if abs, _ := filepath.Abs(cfg.Output); abs != dir {
+		result.Detail = i18n.T("config.validate.output.created", cfg.Output, dir)
+	}
+	file, err := os.CreateTemp(dir, ".crawlr-validate-*")
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = i18n.T("doctor.output.not_writable", dir, err)
+		result.Hint = i18n.T("doctor.output.hint")
+		return result
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return result
+}
+
+// checkServerReachable runs the server check of doctor, unless --offline is
+// set or the server URL is invalid, which the configuration check reports
+func checkServerReachable(cmd *cobra.Command, cfg *config.Config) checkResult {
+	if validateOffline {
+		return checkResult{Name: "server", Status: checkSkip, Detail: i18n.T("config.validate.server.skipped")}
+	}
+	if result := checkConfig(cfg); result.Status != checkOK {
+		return checkResult{Name: "server", Status: checkSkip, Detail: result.Detail}
+	}
+
+	// Keep the crawler quiet, whatever the logging settings being validated
+	logCfg := *cfg
+	logCfg.LogLevel = "ERROR"
+	logCfg.LogOutput = "console"
+	var err error
+	appLogger, err = newLogger(&logCfg)
+	if err != nil {
+		return checkResult{Name: "server", Status: checkFail, Detail: err.Error()}
+	}
+	defer appLogger.Close()
+
+	return checkServer(cmd.Context(), crawler.NewCrawler(cfg, appLogger), cfg)
+}
+
+func init() {
+	configShowCmd.Flags().BoolVar(&showResolved, "resolved", false, "Show the source and environment variable of each value")
+	configValidateCmd.Flags().BoolVar(&validateOffline, "offline", false, "Do not check that the crawl4ai server is reachable")
+	configInitCmd.Flags().BoolVar(&initForce, "force", false, "Replace an existing configuration file")
+
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
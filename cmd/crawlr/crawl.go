@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"crawlr/internal/config"
+	"crawlr/internal/crawler"
+	"crawlr/internal/errors"
+	"crawlr/internal/progress"
+	"crawlr/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// terminalDisplay is a progress renderer drawing on the terminal, through
+// which log messages are written so that they do not garble it
+type terminalDisplay interface {
+	progress.Renderer
+	io.Writer
+}
+
+// progressDisplay draws the progress of the crawl command on the terminal, as
+// bars or as the --tui dashboard. It is nil when stdout is not a terminal or
+// with --quiet, progress being logged.
+var progressDisplay terminalDisplay
+
+// tui is set by --tui to show the dashboard instead of progress bars
+var tui bool
+
+// crawlStdin is set by crawlr crawl --stdin to crawl the URLs read from stdin
+var crawlStdin bool
+
+var crawlCmd = &cobra.Command{
+	Use:   "crawl",
+	Short: "Crawl a website into a library",
+	Long: `Crawl a website through the crawl4ai server and store its pages and media
+in a library of the output folder.
+
+With --stdin, the URLs read from stdin, one per line, are fetched without
+following their links.
+
+If the crawl is interrupted, the pages crawled so far are kept and the URLs
+left to crawl are saved to checkpoint.json; crawlr resume picks it up from
+there.`,
+	Example: `crawlr crawl --url https://example.com --library my-library --output ./assets
+  crawlr crawl -u https://example.com -l my-library -o ./assets --max-depth 3
+  cat urls.txt | crawlr crawl --stdin -l my-library -o ./assets`,
+	Args: cobra.NoArgs,
+	RunE: runCrawlCommand,
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume a cancelled crawl from its checkpoint",
+	Long: `Resume the crawl of a library that was cancelled before completion, from
+the checkpoint.json it left. The pending URLs are crawled with the current
+configuration while the pages already in the library are not crawled again.
+The start URL defaults to the one of the cancelled crawl.
+
+The checkpoint is removed once the crawl completes; interrupting the resumed
+crawl saves a new one.`,
+	Example: `crawlr resume -l my-library -o ./assets
+  crawlr resume -l my-library -o ./assets --max-urls 500`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resumeCfg, err := crawlConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if resumeCfg.Library == "" {
+			return errors.New(errors.ConfigurationError, "library is required")
+		}
+
+		libraryPath := filepath.Join(resumeCfg.Output, resumeCfg.Library)
+		checkpoint, err := crawler.LoadCheckpoint(libraryPath)
+		if os.IsNotExist(err) {
+			return errors.New(errors.ValidationError, "no crawl to resume").WithContext("library", libraryPath)
+		}
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to load checkpoint")
+		}
+		if resumeCfg.URL == "" {
+			resumeCfg.URL = checkpoint.StartURL
+		}
+
+		if err := executeCrawl(resumeCfg, checkpoint); err != nil {
+			return err
+		}
+		if err := os.Remove(filepath.Join(libraryPath, storage.CheckpointFile)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, errors.StorageError, "failed to remove checkpoint")
+		}
+		return nil
+	},
+}
+
+// runCrawlCommand crawls the configured URL, for crawlr crawl and the
+// flag-only invocation of crawlr
+func runCrawlCommand(cmd *cobra.Command, args []string) error {
+	var err error
+	cfg, err = crawlConfig(cmd)
+	if err != nil {
+		return err
+	}
+	return executeCrawl(cfg, nil)
+}
+
+// crawlConfig loads the configuration of a crawl from defaults, config file,
+// environment and flags
+func crawlConfig(cmd *cobra.Command) (*config.Config, error) {
+	crawlCfg, _, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Override config with flag values if provided
+	if cmd.Flags().Changed("library") {
+		crawlCfg.Library = library
+	}
+	if cmd.Flags().Changed("output") {
+		crawlCfg.Output = output
+	}
+
+	// --stdin fetches the URLs piped in, and the other start URLs if any,
+	// without following their links
+	if crawlStdin {
+		stdinURLs, err := config.ReadSeeds(cmd.InOrStdin())
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ValidationError, "failed to read URLs from stdin")
+		}
+		if len(stdinURLs) == 0 {
+			return nil, errors.New(errors.ValidationError, "no URLs on stdin")
+		}
+		crawlCfg.Seeds = append(crawlCfg.Seeds, stdinURLs...)
+		crawlCfg.DiscoveryMethod = "none"
+	}
+
+	// The seeds file adds start URLs to those of --url and seeds
+	if err := crawlCfg.ResolveSeeds(); err != nil {
+		return nil, errors.Wrap(err, errors.ConfigurationError, "failed to load seeds")
+	}
+
+	// Every URL of the list is fetched unless --max-urls says otherwise
+	if crawlStdin && !cmd.Flags().Changed("max-urls") {
+		crawlCfg.MaxURLs = max(crawlCfg.MaxURLs, 1+len(crawlCfg.Seeds))
+	}
+	return crawlCfg, nil
+}
+
+// executeCrawl validates crawlCfg and runs the crawl, resuming the one of
+// checkpoint if not nil
+func executeCrawl(crawlCfg *config.Config, checkpoint *crawler.Checkpoint) error {
+	// Validate the resolved configuration, reporting every violation at once
+	if err := crawlCfg.Validate(); err != nil {
+		return errors.Wrap(err, errors.ValidationError, "invalid configuration")
+	}
+
+	// Draw progress bars, or the dashboard, on terminals, log messages being
+	// written above the bars or into the dashboard
+	var dashboard *progress.Dashboard
+	if !quiet && progress.IsTerminal(os.Stdout) {
+		if tui {
+			dashboard = progress.NewDashboard(os.Stdout, "crawlr: "+crawlCfg.Library+" <- "+crawlCfg.URL, "crawl")
+			progressDisplay = dashboard
+		} else {
+			progressDisplay = progress.NewTerminalRenderer(os.Stdout)
+		}
+	}
+
+	// Initialize logger
+	var err error
+	appLogger, err = newLogger(crawlCfg)
+	if err != nil {
+		return err
+	}
+	defer appLogger.Close()
+	if tui && dashboard == nil {
+		appLogger.Warn("The dashboard needs a terminal, progress is logged instead")
+	}
+
+	flushTraces, err := setupTracing(crawlCfg, appLogger)
+	if err != nil {
+		return err
+	}
+	defer flushTraces()
+
+	// Interrupting the crawl stops it after saving the pages crawled so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := crawlOptions{resume: checkpoint, dashboard: dashboard}
+	if progressDisplay != nil {
+		opts.progress = progress.NewProgressManagerWithRenderer(appLogger, progressDisplay)
+	}
+	if dashboard != nil {
+		dashboard.Start()
+		defer dashboard.Stop()
+	}
+	if err := runCrawl(ctx, crawlCfg, appLogger, opts); err != nil {
+		return err
+	}
+
+	appLogger.Info("Crawlr application completed successfully")
+	return nil
+}
+
+func init() {
+	crawlCmd.Flags().BoolVar(&crawlStdin, "stdin", false, "Crawl the URLs read from stdin, one per line, without following their links")
+	rootCmd.AddCommand(crawlCmd, resumeCmd)
+}
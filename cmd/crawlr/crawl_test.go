@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"crawlr/internal/config"
+	"crawlr/internal/mockserver"
+	"crawlr/internal/report"
+	"crawlr/internal/storage"
+)
+
+// TestRunCrawlMockServer crawls the built-in site of the mock server twice,
+// the second time incrementally
+func TestRunCrawlMockServer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	cfg := config.DefaultConfig()
+	cfg.LogLevel = "ERROR"
+	cfg.Library = "mock"
+	cfg.Output = t.TempDir()
+	cfg.Incremental = true
+
+	var err error
+	appLogger, err = newLogger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer appLogger.Close()
+
+	mock, err := mockserver.New("", appLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(mock.Handler())
+	defer server.Close()
+
+	cfg.URL = server.URL + "/"
+	cfg.ServerURL = server.URL
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid configuration: %v", err)
+	}
+
+	libraryPath := filepath.Join(cfg.Output, cfg.Library)
+	crawl := func() *report.Summary {
+		t.Helper()
+		runCfg := *cfg
+		if err := runCrawl(context.Background(), &runCfg, appLogger, crawlOptions{}); err != nil {
+			t.Fatalf("runCrawl: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(libraryPath, "report.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var summary report.Summary
+		if err := json.Unmarshal(data, &summary); err != nil {
+			t.Fatal(err)
+		}
+		return &summary
+	}
+
+	first := crawl()
+	if first.PagesSaved != 4 || first.PagesFailed != 0 || first.MediaSaved != 1 {
+		t.Errorf("first crawl saved %d pages, %d media files, with %d failures, want 4, 1, 0",
+			first.PagesSaved, first.MediaSaved, first.PagesFailed)
+	}
+
+	wantFiles := []string{
+		"markdown/api.md",
+		"markdown/guide.md",
+		"markdown/guide/install.md",
+		"markdown/index.md",
+		"media/img/logo.png",
+	}
+	for _, name := range wantFiles {
+		if info, err := os.Stat(filepath.Join(libraryPath, name)); err != nil {
+			t.Errorf("missing %s: %v", name, err)
+		} else if info.Size() == 0 {
+			t.Errorf("%s is empty", name)
+		}
+	}
+
+	manifest, err := storage.LoadManifest(libraryPath)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	var paths []string
+	for _, entry := range manifest.Pages {
+		paths = append(paths, entry.Path)
+		if entry.ETag == "" || entry.Hash == "" {
+			t.Errorf("manifest entry of %s lacks its ETag or hash", entry.URL)
+		}
+	}
+	for _, entry := range manifest.Media {
+		paths = append(paths, entry.Path)
+	}
+	sort.Strings(paths)
+	if len(paths) != len(wantFiles) {
+		t.Fatalf("manifest lists %v, want %v", paths, wantFiles)
+	}
+	for i := range paths {
+		if paths[i] != wantFiles[i] {
+			t.Errorf("manifest lists %v, want %v", paths, wantFiles)
+			break
+		}
+	}
+
+	// The pages are unchanged on the mock server, so the incremental crawl
+	// keeps their files and manifest entries
+	second := crawl()
+	if second.PagesUnchanged != 4 || second.PagesSaved != 0 || second.PagesFailed != 0 {
+		t.Errorf("second crawl found %d pages unchanged and saved %d, with %d failures, want 4, 0, 0",
+			second.PagesUnchanged, second.PagesSaved, second.PagesFailed)
+	}
+	for _, name := range wantFiles {
+		if _, err := os.Stat(filepath.Join(libraryPath, name)); err != nil {
+			t.Errorf("missing %s after incremental crawl: %v", name, err)
+		}
+	}
+	manifest, err = storage.LoadManifest(libraryPath)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	if len(manifest.Pages) != 4 || len(manifest.Media) != 1 {
+		t.Errorf("manifest lists %d pages and %d media files after incremental crawl, want 4 and 1",
+			len(manifest.Pages), len(manifest.Media))
+	}
+}
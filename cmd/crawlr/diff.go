@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+	"crawlr/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffFormat   string
+	diffPrevious string
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare a library with its previous crawl",
+	Long: `Compare the manifest of a library with the manifest of its previous crawl and
+report the pages added, removed and changed, by content hash.
+
+Each crawl that changes the library keeps the manifest it replaces as
+manifest.previous.json; --previous compares with another manifest instead,
+e.g. one kept from an older snapshot.`,
+	Example: `crawlr diff -l mylib -o ./libraries
+  crawlr diff -l mylib -o ./libraries --format markdown > CHANGES.md
+  crawlr diff -l mylib -o ./libraries --previous ./snapshots/manifest.json --format json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		diffCfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if diffCfg.Library == "" {
+			return errors.New(errors.ConfigurationError, "library is required")
+		}
+		if diffFormat != "text" && diffFormat != "json" && diffFormat != "markdown" {
+			return errors.New(errors.ValidationError, "format must be one of text, json, markdown").WithContext("format", diffFormat)
+		}
+
+		libraryPath := filepath.Join(diffCfg.Output, diffCfg.Library)
+		previousPath := diffPrevious
+		if previousPath == "" {
+			previousPath = filepath.Join(libraryPath, storage.PreviousManifestFile)
+		}
+		previous, err := storage.ReadManifestFile(previousPath)
+		if os.IsNotExist(err) {
+			return errors.New(errors.StorageError, "no previous crawl to compare with").WithContext("path", previousPath)
+		}
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to read previous manifest")
+		}
+		current, err := storage.ReadManifestFile(filepath.Join(libraryPath, storage.ManifestFile))
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to read manifest")
+		}
+
+		diff := storage.DiffManifests(previous, current)
+		out := cmd.OutOrStdout()
+		switch diffFormat {
+		case "json":
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(diff)
+		case "markdown":
+			writeMarkdownDiff(out, diff)
+		default:
+			writeTextDiff(out, diff)
+		}
+		return nil
+	},
+}
+
+// writeTextDiff writes a diff as plain text, one page per line
+func writeTextDiff(w io.Writer, diff *storage.ManifestDiff) {
+	sections := []struct {
+		key     string
+		marker  string
+		changes []storage.PageChange
+	}{
+		{"diff.added", "+", diff.Added},
+		{"diff.removed", "-", diff.Removed},
+		{"diff.changed", "~", diff.Changed},
+	}
+	for _, section := range sections {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%d)\n", i18n.T(section.key), len(section.changes))
+		for _, change := range section.changes {
+			fmt.Fprintf(w, "  %s %s  %s\n", section.marker, change.URL, change.Path)
+		}
+	}
+	fmt.Fprint(w, i18n.T("diff.summary", len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged))
+}
+
+// writeMarkdownDiff writes a diff as a markdown document linking the saved
+// pages, e.g. for a changelog or a pull request description
+func writeMarkdownDiff(w io.Writer, diff *storage.ManifestDiff) {
+	fmt.Fprintf(w, "## %s\n\n", i18n.T("diff.title", diff.Library))
+	fmt.Fprint(w, i18n.T("diff.summary", len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged))
+
+	sections := []struct {
+		key     string
+		changes []storage.PageChange
+	}{
+		{"diff.added", diff.Added},
+		{"diff.removed", diff.Removed},
+		{"diff.changed", diff.Changed},
+	}
+	for _, section := range sections {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n### %s\n\n", i18n.T(section.key))
+		for _, change := range section.changes {
+			fmt.Fprintf(w, "- [%s](%s)\n", change.URL, change.Path)
+		}
+	}
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffFormat, "format", "text", "Output format (text, json, markdown)")
+	diffCmd.Flags().StringVar(&diffPrevious, "previous", "", "Manifest to compare with (default: the library's manifest.previous.json)")
+
+	rootCmd.AddCommand(diffCmd)
+}
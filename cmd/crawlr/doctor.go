@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"crawlr/internal/auth"
+	"crawlr/internal/config"
+	"crawlr/internal/crawler"
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+	"crawlr/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// doctorTestURL is crawled when no --url is configured
+const doctorTestURL = "https://example.com/"
+
+// doctorMinFreeSpace is the free disk space below which doctor warns
+const doctorMinFreeSpace = 1 << 30
+
+// checkStatus is the outcome of a doctor check
+type checkStatus string
+
+const (
+	checkOK   checkStatus = "OK"
+	checkWarn checkStatus = "WARN"
+	checkFail checkStatus = "FAIL"
+	checkSkip checkStatus = "SKIP"
+)
+
+// checkResult is the outcome of a single doctor check
+type checkResult struct {
+	Name   string
+	Status checkStatus
+	Detail string
+	Hint   string
+}
+
+var doctorTestURLFlag string
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the crawl4ai server, credentials and output directory",
+	Long: `Check that crawlr is ready to crawl: the configuration loads, the crawl4ai
+server is reachable and accepts requests, a one-page test crawl succeeds, and
+the output directory is writable with enough free disk space.
+
+Each failing check prints a hint on how to fix it. The exit status is non-zero
+if any check fails.`,
+	Example: `crawlr doctor
+  crawlr doctor --server-url http://localhost:11235 --output ./libraries
+  crawlr doctor --test-url https://docs.example.com`,
+	Args: cobra.NoArgs,
+	// The diagnostics already explain what failed
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+
+		doctorCfg, _, err := loadConfig(cmd)
+		if err != nil {
+			printCheck(out, checkResult{
+				Name:   "config",
+				Status: checkFail,
+				Detail: err.Error(),
+				Hint:   i18n.T("doctor.config.hint"),
+			})
+			return errors.New(errors.ConfigurationError, i18n.T("doctor.problems", 1))
+		}
+
+		// Keep the crawler quiet unless a log level was requested explicitly
+		if !changedConfigKeys(cmd)["log_level"] {
+			doctorCfg.LogLevel = "ERROR"
+		}
+		appLogger, err = newLogger(doctorCfg)
+		if err != nil {
+			return err
+		}
+		defer appLogger.Close()
+
+		testURL := doctorTestURLFlag
+		if testURL == "" {
+			testURL = doctorCfg.URL
+		}
+		if testURL == "" {
+			testURL = doctorTestURL
+		}
+
+		c := crawler.NewCrawler(doctorCfg, appLogger)
+
+		checks := []func() checkResult{
+			func() checkResult { return checkConfig(doctorCfg) },
+			func() checkResult { return checkServer(cmd.Context(), c, doctorCfg) },
+			func() checkResult { return checkTestCrawl(cmd.Context(), c, doctorCfg, testURL) },
+			func() checkResult { return checkOutput(doctorCfg) },
+			func() checkResult { return checkDiskSpace(doctorCfg) },
+		}
+
+		failed := 0
+		for _, check := range checks {
+			result := check()
+			printCheck(out, result)
+			if result.Status == checkFail {
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			return errors.New(errors.ValidationError, i18n.T("doctor.problems", failed))
+		}
+		fmt.Fprintln(out, "\n"+i18n.T("doctor.passed"))
+		return nil
+	},
+}
+
+// printCheck writes a check result and its hint
+func printCheck(out io.Writer, result checkResult) {
+	fmt.Fprintf(out, "[%-4s] %-10s %s\n", result.Status, result.Name, result.Detail)
+	if result.Hint != "" && result.Status != checkOK {
+		fmt.Fprintf(out, "       %-10s %s: %s\n", "", i18n.T("doctor.hint"), result.Hint)
+	}
+}
+
+// checkConfig validates the parts of the configuration needed by any crawl
+func checkConfig(cfg *config.Config) checkResult {
+	result := checkResult{Name: "config", Status: checkOK, Detail: i18n.T("doctor.config.loaded")}
+
+	if u, err := neturl.Parse(cfg.ServerURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		result.Status = checkFail
+		result.Detail = i18n.T("doctor.config.server_url", cfg.ServerURL)
+		result.Hint = i18n.T("doctor.config.server_url.hint")
+		return result
+	}
+
+	if _, err := auth.NewRegistry(cfg.Auth); err != nil {
+		result.Status = checkFail
+		result.Detail = i18n.T("doctor.config.auth", err)
+		result.Hint = i18n.T("doctor.config.auth.hint")
+		return result
+	}
+
+	return result
+}
+
+// checkServer verifies that the crawl4ai server answers its health endpoint,
+// which the local engine does not use
+func checkServer(parent context.Context, c *crawler.Crawler, cfg *config.Config) checkResult {
+	result := checkResult{Name: "server"}
+	if cfg.Engine == "local" {
+		result.Status = checkSkip
+		result.Detail = i18n.T("doctor.server.local")
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(parent, time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	health, err := c.Health(ctx)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var apiErr *crawler.APIError
+	switch {
+	case err == nil:
+		result.Status = checkOK
+		result.Detail = i18n.T("doctor.server.ok", cfg.ServerURL, health.Status, valueOr(health.Version, i18n.T("doctor.server.unknown")), elapsed)
+	case stderrors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden):
+		result.Status = checkFail
+		result.Detail = i18n.T("doctor.server.rejected", cfg.ServerURL, apiErr.StatusCode)
+		result.Hint = i18n.T("doctor.server.auth.hint")
+	case stderrors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		result.Status = checkWarn
+		result.Detail = i18n.T("doctor.server.no_health", cfg.ServerURL)
+		result.Hint = i18n.T("doctor.server.no_health.hint")
+	case ctx.Err() != nil:
+		result.Status = checkFail
+		result.Detail = i18n.T("doctor.server.timeout", cfg.ServerURL, cfg.Timeout)
+		result.Hint = i18n.T("doctor.server.timeout.hint")
+	default:
+		result.Status = checkFail
+		result.Detail = i18n.T("doctor.server.unreachable", cfg.ServerURL, err)
+		result.Hint = i18n.T("doctor.server.unreachable.hint")
+	}
+
+	return result
+}
+
+// checkTestCrawl crawls a single page without following links
+func checkTestCrawl(parent context.Context, c *crawler.Crawler, cfg *config.Config, testURL string) checkResult {
+	result := checkResult{Name: "crawl"}
+
+	ctx, cancel := context.WithTimeout(parent, time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	noMedia := false
+	start := time.Now()
+	resp, err := c.StartCrawlWithConfig(ctx, []string{testURL}, &noMedia, 0, true, 1)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var apiErr *crawler.APIError
+	switch {
+	case err == nil && resp.Success && len(resp.Results) > 0 && resp.Results[0].Success:
+		result.Status = checkOK
+		result.Detail = i18n.T("doctor.crawl.ok", testURL, len(resp.Results[0].Markdown.RawMarkdown), elapsed)
+		if resp.Results[0].Markdown.RawMarkdown == "" {
+			result.Status = checkWarn
+			result.Detail = i18n.T("doctor.crawl.empty", testURL)
+			result.Hint = i18n.T("doctor.crawl.empty.hint")
+		}
+	case err == nil:
+		result.Status = checkFail
+		result.Detail = i18n.T("doctor.crawl.failed", testURL)
+		result.Hint = i18n.T("doctor.crawl.failed.hint")
+	case stderrors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden):
+		result.Status = checkFail
+		result.Detail = i18n.T("doctor.crawl.rejected", apiErr.StatusCode)
+		result.Hint = i18n.T("doctor.server.auth.hint")
+	case ctx.Err() != nil:
+		result.Status = checkFail
+		result.Detail = i18n.T("doctor.crawl.timeout", testURL, cfg.Timeout)
+		result.Hint = i18n.T("doctor.crawl.timeout.hint")
+	default:
+		result.Status = checkFail
+		result.Detail = i18n.T("doctor.crawl.error", testURL, err)
+		result.Hint = i18n.T("doctor.crawl.error.hint")
+	}
+
+	return result
+}
+
+// checkOutput verifies that files can be created in the output directory
+func checkOutput(cfg *config.Config) checkResult {
+	result := checkResult{Name: "output"}
+	if cfg.Output == "" {
+		result.Status = checkSkip
+		result.Detail = i18n.T("doctor.output.none")
+		return result
+	}
+
+	if err := os.MkdirAll(cfg.Output, 0755); err != nil {
+		result.Status = checkFail
+		result.Detail = i18n.T("doctor.output.mkdir", cfg.Output, err)
+		result.Hint = i18n.T("doctor.output.hint")
+		return result
+	}
+
+	file, err := os.CreateTemp(cfg.Output, ".crawlr-doctor-*")
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = i18n.T("doctor.output.not_writable", cfg.Output, err)
+		result.Hint = i18n.T("doctor.output.hint")
+		return result
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	result.Status = checkOK
+	result.Detail = i18n.T("doctor.output.ok", cfg.Output)
+	return result
+}
+
+// checkDiskSpace warns when the output filesystem is nearly full
+func checkDiskSpace(cfg *config.Config) checkResult {
+	result := checkResult{Name: "disk"}
+
+	dir := cfg.Output
+	if dir == "" {
+		dir = "."
+	}
+	// Use the nearest existing parent if the output directory does not exist yet
+	dir = existingParent(dir)
+
+	free, err := storage.FreeSpace(dir)
+	if err != nil {
+		result.Status = checkSkip
+		result.Detail = i18n.T("doctor.disk.unknown", err)
+		return result
+	}
+
+	result.Detail = i18n.T("doctor.disk.free", formatBytes(free), dir)
+	if free < doctorMinFreeSpace {
+		result.Status = checkWarn
+		result.Hint = i18n.T("doctor.disk.low.hint")
+	} else {
+		result.Status = checkOK
+	}
+	return result
+}
+
+// existingParent returns the absolute path of dir, or of its nearest parent
+// that exists if dir does not
+func existingParent(dir string) string {
+	dir, _ = filepath.Abs(dir)
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			return dir
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// valueOr returns value, or fallback if value is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func init() {
+	doctorCmd.Flags().StringVar(&doctorTestURLFlag, "test-url", "", "URL of the test crawl (defaults to --url, then "+doctorTestURL+")")
+
+	rootCmd.AddCommand(doctorCmd)
+}
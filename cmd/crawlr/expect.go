@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"crawlr/internal/config"
+	"crawlr/internal/crawler"
+	"crawlr/internal/errors"
+)
+
+// checkCoverage verifies the coverage expectations of a crawl: the minimum
+// number of pages crawled successfully and the URL patterns each matched by at
+// least one of them. Unchanged pages of an incremental re-crawl count as
+// crawled. The returned error lists every unmet expectation.
+func checkCoverage(cfg *config.Config, results []crawler.PageResult) error {
+	if cfg.ExpectMinPages == 0 && len(cfg.ExpectURLs) == 0 {
+		return nil
+	}
+
+	var crawled []string
+	for _, result := range results {
+		if result.Success {
+			crawled = append(crawled, result.URL)
+		}
+	}
+
+	var failures []string
+	if len(crawled) < cfg.ExpectMinPages {
+		failures = append(failures, fmt.Sprintf("expected at least %d pages, crawled %d", cfg.ExpectMinPages, len(crawled)))
+	}
+	for _, pattern := range cfg.ExpectURLs {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return errors.Wrap(err, errors.ConfigurationError, "invalid expected URL pattern")
+		}
+		matched := false
+		for _, url := range crawled {
+			if re.MatchString(url) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			failures = append(failures, fmt.Sprintf("no crawled page matches %q", pattern))
+		}
+	}
+
+	if len(failures) == 0 {
+		appLogger.Info("Coverage expectations met", map[string]interface{}{"pages": len(crawled)})
+		return nil
+	}
+	for _, failure := range failures {
+		appLogger.Error("Coverage expectation not met", map[string]interface{}{"reason": failure})
+	}
+	return errors.New(errors.ValidationError, "coverage expectations not met").
+		WithContext("failures", strings.Join(failures, "; "))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+	"crawlr/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var exportFilter string
+
+var exportCmd = &cobra.Command{
+	Use:   "export <destination>",
+	Short: "Export the pages of a library matching a filter",
+	Long: `Copy the pages of a library selected by --filter, with their HTML, crawl
+result, extraction and media link files, into a new directory, or into a zip
+or tar.gz archive when the destination ends in .zip or .tar.gz. With the cas
+media layout, the media objects of the exported pages are included. The
+export has its own manifest listing only what it contains.
+
+Filters combine terms with AND, OR, NOT and parentheses; adjacent terms are
+ANDed:
+  tag:api              pages tagged api (keywords meta tag, or front matter)
+  path:/reference/*    pages whose URL path matches the glob
+  url:https://docs.example.com/v2/*
+In globs, * matches any characters, slashes included. Without --filter, every
+page is exported.`,
+	Example: `crawlr export -l mylib -o ./libraries --filter 'tag:api OR path:/reference/*' ./api-docs
+  crawlr export -l mylib -o ./libraries --filter 'path:/guide/* AND NOT tag:deprecated' guide.zip`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exportCfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if exportCfg.Library == "" {
+			return errors.New(errors.ConfigurationError, "library is required")
+		}
+
+		match := func(storage.ManifestEntry) bool { return true }
+		if exportFilter != "" {
+			filter, err := storage.ParsePageFilter(exportFilter)
+			if err != nil {
+				return errors.Wrap(err, errors.ValidationError, "invalid export filter").WithContext("filter", exportFilter)
+			}
+			match = filter.Match
+		}
+
+		libraryPath := filepath.Join(exportCfg.Output, exportCfg.Library)
+		dest := args[0]
+		var result *storage.ExportResult
+		if format := archiveFormat(dest); format != "" {
+			result, err = exportArchive(libraryPath, dest, format, match)
+		} else {
+			result, err = storage.ExportPages(libraryPath, dest, match)
+		}
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to export library").WithContext("destination", dest)
+		}
+
+		fmt.Fprint(cmd.OutOrStdout(), i18n.T("export.exported", result.Pages, result.Media, dest, formatBytes(uint64(result.Bytes))))
+		return nil
+	},
+}
+
+var (
+	graphFormat   string
+	graphExternal bool
+)
+
+var exportGraphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Export the link graph of a library as DOT or GraphML",
+	Long: `Write the page-to-page link structure of a library to stdout, as Graphviz DOT
+or GraphML, to visualize the architecture of a site. Edges come from the links
+recorded in the manifest: pages below the maximum depth always record theirs,
+and crawls run with --link-graph record the links of every page. Links to
+pages outside the library are left out unless --external is set.`,
+	Example: `crawlr export graph -l mylib -o ./libraries | dot -Tsvg > site.svg
+  crawlr export graph -l mylib -o ./libraries --format graphml --external > site.graphml`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if graphFormat != "dot" && graphFormat != "graphml" {
+			return errors.New(errors.ValidationError, "format must be one of dot, graphml").WithContext("format", graphFormat)
+		}
+		graphCfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if graphCfg.Library == "" {
+			return errors.New(errors.ConfigurationError, "library is required")
+		}
+
+		manifest, err := storage.LoadManifest(filepath.Join(graphCfg.Output, graphCfg.Library))
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to read manifest")
+		}
+		graph := storage.BuildLinkGraph(manifest, graphExternal)
+		if graphFormat == "graphml" {
+			err = graph.WriteGraphML(cmd.OutOrStdout())
+		} else {
+			err = graph.WriteDOT(cmd.OutOrStdout())
+		}
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to write link graph")
+		}
+		return nil
+	},
+}
+
+// archiveFormat returns the archive format of an export destination, or ""
+// for a directory
+func archiveFormat(dest string) string {
+	for _, format := range []string{"zip", "tar.gz"} {
+		if strings.HasSuffix(strings.ToLower(dest), "."+format) {
+			return format
+		}
+	}
+	return ""
+}
+
+// exportArchive exports pages into a temporary directory next to dest and
+// packs it into the archive dest
+func exportArchive(libraryPath, dest, format string, match func(storage.ManifestEntry) bool) (*storage.ExportResult, error) {
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dest), ".export-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Entries of the archive are stored under its name without extension
+	name := filepath.Base(dest)
+	name = name[:len(name)-len(format)-1]
+	result, err := storage.ExportPages(libraryPath, filepath.Join(tmpDir, name), match)
+	if err != nil {
+		return nil, err
+	}
+	archivePath, err := storage.ArchiveDirectory(filepath.Join(tmpDir, name), format)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(archivePath, dest); err != nil {
+		return nil, fmt.Errorf("failed to move archive into place: %w", err)
+	}
+	if info, err := os.Stat(dest); err == nil {
+		result.Bytes = info.Size()
+	}
+	return result, nil
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFilter, "filter", "", "Pages to export, e.g. 'tag:api OR path:/reference/*'")
+
+	exportGraphCmd.Flags().StringVar(&graphFormat, "format", "dot", "Output format (dot, graphml)")
+	exportGraphCmd.Flags().BoolVar(&graphExternal, "external", false, "Include linked pages missing from the library")
+
+	exportCmd.AddCommand(exportGraphCmd)
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"crawlr/internal/config"
+	"crawlr/internal/errors"
+)
+
+// errorPolicy decides what errors do to a run. With fail_fast, the first
+// storage or API error aborts the crawl; with max_error_rate, the run fails
+// once the crawl is over if more than that percentage of pages could not be
+// crawled or saved. Otherwise the crawl carries on and errors are only
+// reported.
+type errorPolicy struct {
+	failFast     bool
+	maxErrorRate float64
+	abort        func() // stops the crawl
+
+	mutex  sync.Mutex
+	pages  int                 // pages processed, or failed before they could be
+	failed map[string]bool     // pages that could not be crawled or saved
+	cause  *errors.CrawlrError // error the crawl was aborted on, if any
+}
+
+// newErrorPolicy creates the error policy of cfg, calling abort to stop the
+// crawl on a fatal error
+func newErrorPolicy(cfg *config.Config, abort func()) *errorPolicy {
+	return &errorPolicy{
+		failFast:     cfg.FailFast,
+		maxErrorRate: cfg.MaxErrorRate,
+		abort:        abort,
+		failed:       make(map[string]bool),
+	}
+}
+
+// pageProcessed counts a page handed over by the crawler, saved or not
+func (p *errorPolicy) pageProcessed() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.pages++
+}
+
+// pageFailed records an error about the page url, counting the page as
+// processed when the crawler never handed it over
+func (p *errorPolicy) pageFailed(url string, err *errors.CrawlrError, processed bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !processed && !p.failed[url] {
+		p.pages++
+	}
+	p.failed[url] = true
+	p.check(url, err)
+}
+
+// fileFailed records an error about a file other than a page, such as a media
+// file, which does not count towards the error rate
+func (p *errorPolicy) fileFailed(url string, err *errors.CrawlrError) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.check(url, err)
+}
+
+// check aborts the crawl on the first storage or API error with fail_fast
+func (p *errorPolicy) check(url string, err *errors.CrawlrError) {
+	if !p.failFast || p.cause != nil {
+		return
+	}
+	if err.Type == errors.StorageError || err.Type == errors.APIError {
+		p.cause = errors.Wrap(err, err.Type, "crawl aborted on the first storage or API error").
+			WithContext("url", url)
+		p.abort()
+	}
+}
+
+// aborted returns the error the crawl was aborted on with fail_fast, or nil
+func (p *errorPolicy) aborted() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.cause == nil {
+		return nil
+	}
+	return p.cause
+}
+
+// errorRate returns the percentage of pages that could not be crawled or
+// saved
+func (p *errorPolicy) errorRate() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.pages == 0 {
+		return 0
+	}
+	return float64(len(p.failed)) * 100 / float64(p.pages)
+}
+
+// checkErrorRate fails the run if the error rate exceeds max_error_rate
+func (p *errorPolicy) checkErrorRate() error {
+	rate := p.errorRate()
+	if p.maxErrorRate == 0 || rate <= p.maxErrorRate {
+		return nil
+	}
+	return errors.New(errors.CrawlerError, "error rate exceeded").
+		WithContext("errorRate", fmt.Sprintf("%.1f%%", rate)).
+		WithContext("maxErrorRate", fmt.Sprintf("%g%%", p.maxErrorRate))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"crawlr/internal/crawler"
+	"crawlr/internal/errors"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	fetchFilter string
+	fetchQuery  string
+	fetchFile   string
+)
+
+var fetchCmd = &cobra.Command{
+	Use:   "fetch <url>",
+	Short: "Print the markdown of a single page",
+	Long: `Fetch a single page through the lightweight /md endpoint of crawl4ai, which
+skips the link discovery, media and HTML of a crawl, and print its markdown,
+or write it to --file. Nothing is stored in a library.
+
+--filter selects what the markdown holds: the whole page (raw), its main
+content (fit), or the passages relevant to --query (bm25). Servers without
+the /md endpoint crawl the page instead, returning its raw markdown.`,
+	Example: `crawlr fetch https://docs.example.com/guide/install
+  crawlr fetch https://docs.example.com/guide/install --filter fit --file install.md
+  crawlr fetch https://docs.example.com/api --filter bm25 --query "rate limits"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fetchCfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		pageURL := args[0]
+		if u, err := neturl.Parse(pageURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New(errors.ValidationError, "url must be an absolute http or https URL").WithContext("url", pageURL)
+		}
+		if !slices.Contains(crawler.MarkdownFilters, fetchFilter) {
+			return errors.New(errors.ValidationError, "filter must be one of "+strings.Join(crawler.MarkdownFilters, ", ")).
+				WithContext("filter", fetchFilter)
+		}
+		if fetchFilter == "bm25" && fetchQuery == "" {
+			return errors.New(errors.ValidationError, "the bm25 filter needs a --query")
+		}
+
+		// Keep the markdown alone on stdout unless a log level was requested
+		// explicitly
+		if !changedConfigKeys(cmd)["log_level"] {
+			fetchCfg.LogLevel = "ERROR"
+		}
+		appLogger, err = newLogger(fetchCfg)
+		if err != nil {
+			return err
+		}
+		defer appLogger.Close()
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), time.Duration(fetchCfg.Timeout)*time.Second)
+		defer cancel()
+
+		page, err := crawler.NewCrawler(fetchCfg, appLogger).FetchMarkdown(ctx, pageURL, fetchFilter, fetchQuery)
+		var apiErr *crawler.APIError
+		switch {
+		case stderrors.As(err, &apiErr):
+			return errors.Wrap(err, errors.APIError, "crawl4ai failed to fetch the page").WithContext("url", pageURL)
+		case err != nil:
+			return errors.Wrap(err, errors.NetworkError, "failed to fetch the page").WithContext("url", pageURL)
+		case !page.Success:
+			return errors.New(errors.CrawlerError, "crawl4ai could not fetch the page").WithContext("url", pageURL)
+		}
+
+		markdown := strings.TrimRight(page.Markdown, "\n") + "\n"
+		if fetchFile == "" {
+			_, err := fmt.Fprint(cmd.OutOrStdout(), markdown)
+			return err
+		}
+		if dir := filepath.Dir(fetchFile); dir != "." {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return errors.Wrap(err, errors.StorageError, "failed to create directory").WithContext("path", dir)
+			}
+		}
+		if err := os.WriteFile(fetchFile, []byte(markdown), 0644); err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to write markdown").WithContext("path", fetchFile)
+		}
+		return nil
+	},
+}
+
+func init() {
+	fetchCmd.Flags().StringVar(&fetchFilter, "filter", "raw", "Content of the markdown: raw (whole page), fit (main content) or bm25 (passages relevant to --query)")
+	fetchCmd.Flags().StringVar(&fetchQuery, "query", "", "Query of the bm25 filter")
+	fetchCmd.Flags().StringVar(&fetchFile, "file", "", "File to write the markdown to instead of stdout")
+
+	rootCmd.AddCommand(fetchCmd)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+	"crawlr/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var gcDryRun bool
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove media objects no page references",
+	Long: `Remove the media objects of a library stored with the cas media layout
+(media/objects/<sha256>) that no page link file (<page>.media.json) references
+anymore, along with their manifest entries.
+
+Objects become unreferenced when link files are deleted or rewritten, e.g.
+after pages are removed from the library or recrawled with different media.`,
+	Example: `crawlr gc -l mylib -o ./libraries --dry-run
+  crawlr gc -l mylib -o ./libraries`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		gcCfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if gcCfg.Library == "" {
+			return errors.New(errors.ConfigurationError, "library is required")
+		}
+
+		libraryPath := filepath.Join(gcCfg.Output, gcCfg.Library)
+		result, err := storage.CollectMediaGarbage(libraryPath, gcDryRun)
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to collect media garbage")
+		}
+
+		out := cmd.OutOrStdout()
+		for _, path := range result.Removed {
+			fmt.Fprintln(out, path)
+		}
+		key := "gc.removed"
+		if gcDryRun {
+			key = "gc.would_remove"
+		}
+		fmt.Fprint(out, i18n.T(key, len(result.Removed), result.Objects, formatBytes(uint64(result.Freed))))
+		return nil
+	},
+}
+
+func init() {
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "List unreferenced media objects without removing them")
+
+	rootCmd.AddCommand(gcCmd)
+}
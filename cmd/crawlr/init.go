@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"crawlr/internal/config"
+	"crawlr/internal/crawler"
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+
+	"github.com/spf13/cobra"
+)
+
+// initPreviewLines is the number of markdown lines shown after the test crawl
+const initPreviewLines = 15
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a crawl profile interactively",
+	Long: `Ask for the URL to crawl, the crawl4ai server, the output directory and the
+crawl depth, then crawl the first page to preview the markdown it produces.
+When the page was built with a documentation platform crawlr has a preset for,
+the preset is offered and the preview repeated with it.
+
+The answers are written to the configuration file in use, or else to
+$XDG_CONFIG_HOME/crawlr/config.yaml, keeping its other settings and comments,
+so that running crawlr without flags crawls the site.`,
+	Example: `crawlr init
+  crawlr init --server-url http://localhost:11235`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		initCfg, v, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Keep the crawler quiet unless a log level was requested explicitly
+		if !changedConfigKeys(cmd)["log_level"] {
+			initCfg.LogLevel = "ERROR"
+		}
+		appLogger, err = newLogger(initCfg)
+		if err != nil {
+			return err
+		}
+		defer appLogger.Close()
+
+		p := &prompter{in: bufio.NewReader(cmd.InOrStdin()), out: cmd.OutOrStdout()}
+		fmt.Fprintln(p.out, i18n.T("init.welcome"))
+
+		if err := askProfile(p, initCfg); err != nil {
+			return err
+		}
+
+		// Preview the markdown of the first page, offering the detected preset
+		save, err := previewProfile(cmd.Context(), p, initCfg)
+		if err != nil {
+			return err
+		}
+		if !save {
+			fmt.Fprintln(p.out, i18n.T("init.aborted"))
+			return nil
+		}
+
+		path := configWritePath(v)
+		if ok, err := p.confirm(i18n.T("init.write", path), true); err != nil || !ok {
+			fmt.Fprintln(p.out, i18n.T("init.aborted"))
+			return err
+		}
+
+		values := map[string]interface{}{
+			"url":        initCfg.URL,
+			"library":    initCfg.Library,
+			"server_url": initCfg.ServerURL,
+			"output":     initCfg.Output,
+			"max_depth":  initCfg.MaxDepth,
+			"preset":     initCfg.Preset,
+		}
+		keys := []string{"url", "library", "server_url", "output", "max_depth", "preset"}
+		if err := config.SetFileValues(path, values, keys); err != nil {
+			return errors.Wrap(err, errors.ConfigurationError, "failed to write profile")
+		}
+
+		fmt.Fprintln(p.out, i18n.T("init.done", path))
+		return nil
+	},
+}
+
+// askProfile asks for the settings of the profile, offering the current
+// configuration as defaults
+func askProfile(p *prompter, cfg *config.Config) error {
+	var err error
+	if cfg.URL, err = p.askURL(i18n.T("init.url"), cfg.URL); err != nil {
+		return err
+	}
+	if cfg.Library, err = p.ask(i18n.T("init.library"), valueOr(cfg.Library, libraryName(cfg.URL))); err != nil {
+		return err
+	}
+	if cfg.ServerURL, err = p.askURL(i18n.T("init.server_url"), cfg.ServerURL); err != nil {
+		return err
+	}
+	if cfg.Output, err = p.ask(i18n.T("init.output"), valueOr(cfg.Output, "./libraries")); err != nil {
+		return err
+	}
+	if cfg.MaxDepth, err = p.askInt(i18n.T("init.max_depth"), cfg.MaxDepth); err != nil {
+		return err
+	}
+	return nil
+}
+
+// previewProfile crawls the first page and prints the start of its markdown.
+// It reports whether the profile should be saved.
+func previewProfile(ctx context.Context, p *prompter, cfg *config.Config) (bool, error) {
+	result, err := previewCrawl(ctx, p, cfg)
+	if err != nil {
+		fmt.Fprintln(p.out, i18n.T("init.crawl.failed", err))
+		return p.confirm(i18n.T("init.save_anyway"), false)
+	}
+
+	if cfg.Preset == "" {
+		if preset, ok := config.DetectPreset(result.HTML); ok {
+			apply, err := p.confirm(i18n.T("init.preset", preset.Description, preset.Name), true)
+			if err != nil {
+				return false, err
+			}
+			if apply {
+				cfg.UsePreset(preset.Name)
+				if _, err := previewCrawl(ctx, p, cfg); err != nil {
+					fmt.Fprintln(p.out, i18n.T("init.crawl.failed", err))
+				}
+			}
+		}
+	}
+	return true, nil
+}
+
+// previewCrawl crawls the profile URL without following links or downloading
+// media and prints the start of its markdown
+func previewCrawl(parent context.Context, p *prompter, cfg *config.Config) (*crawler.PageResult, error) {
+	ctx, cancel := context.WithTimeout(parent, time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	fmt.Fprintln(p.out, "\n"+i18n.T("init.crawling", cfg.URL))
+	c := crawler.NewCrawler(cfg, appLogger)
+	noMedia := false
+	resp, err := c.StartCrawlWithConfig(ctx, []string{cfg.URL}, &noMedia, 0, true, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) == 0 || !resp.Results[0].Success {
+		message := ""
+		if len(resp.Results) > 0 {
+			message = resp.Results[0].ErrorMessage
+		}
+		return nil, fmt.Errorf("%s", valueOr(message, i18n.T("doctor.crawl.failed", cfg.URL)))
+	}
+
+	result := &resp.Results[0]
+	markdown := strings.TrimSpace(result.Markdown.RawMarkdown)
+	if markdown == "" {
+		fmt.Fprintln(p.out, i18n.T("init.crawl.empty"))
+		return result, nil
+	}
+
+	lines := strings.Split(markdown, "\n")
+	fmt.Fprintln(p.out, i18n.T("init.preview", len(result.Markdown.RawMarkdown)))
+	for _, line := range lines[:min(len(lines), initPreviewLines)] {
+		if len(line) > 100 {
+			line = line[:100] + "…"
+		}
+		fmt.Fprintln(p.out, "  │ "+line)
+	}
+	if len(lines) > initPreviewLines {
+		fmt.Fprintln(p.out, "  │ "+i18n.T("init.preview.more", len(lines)-initPreviewLines))
+	}
+	fmt.Fprintln(p.out)
+	return result, nil
+}
+
+// libraryName derives a library name from the host of a URL
+func libraryName(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	return strings.ReplaceAll(strings.TrimPrefix(u.Hostname(), "www."), ".", "-")
+}
+
+// prompter asks questions on the terminal
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask prints a question with its default value and returns the answer, or the
+// default if the answer is empty
+func (p *prompter) ask(question, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Fprintln(p.out)
+		return "", errors.Wrap(err, errors.ValidationError, "no answer")
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return defaultValue, nil
+}
+
+// askURL asks until an http or https URL is given
+func (p *prompter) askURL(question, defaultValue string) (string, error) {
+	for {
+		answer, err := p.ask(question, defaultValue)
+		if err != nil {
+			return "", err
+		}
+		if u, err := neturl.Parse(answer); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			return answer, nil
+		}
+		fmt.Fprintln(p.out, i18n.T("init.invalid_url"))
+	}
+}
+
+// askInt asks until a number of 0 or more is given
+func (p *prompter) askInt(question string, defaultValue int) (int, error) {
+	for {
+		answer, err := p.ask(question, strconv.Itoa(defaultValue))
+		if err != nil {
+			return 0, err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 0 {
+			return n, nil
+		}
+		fmt.Fprintln(p.out, i18n.T("init.invalid_number"))
+	}
+}
+
+// confirm asks a yes/no question
+func (p *prompter) confirm(question string, defaultYes bool) (bool, error) {
+	choices := i18n.T("init.no_yes")
+	if defaultYes {
+		choices = i18n.T("init.yes_no")
+	}
+	fmt.Fprintf(p.out, "%s %s: ", question, choices)
+
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Fprintln(p.out)
+		return false, errors.Wrap(err, errors.ValidationError, "no answer")
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "":
+		return defaultYes, nil
+	case "y", "yes", "o", "oui":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+}
@@ -1,17 +1,13 @@
 package main
 
 import (
-	"context"
-	"fmt"
 	"os"
-	"time"
+	"strings"
 
 	"crawlr/internal/config"
-	"crawlr/internal/crawler"
 	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
 	"crawlr/internal/logger"
-	"crawlr/internal/progress"
-	"crawlr/internal/storage"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -19,251 +15,348 @@ import (
 
 var (
 	cfg       *config.Config
-	url       string
+	urls      []string
 	library   string
 	output    string
 	appLogger *logger.Logger
 )
 
-var rootCmd = &cobra.Command{
-	Use:   "crawlr",
-	Short: "Crawlr is a web crawling tool for extracting and storing content",
-	Long: `Crawlr is a powerful web crawling tool that connects to a crawl4ai server
-to extract content from websites and store markdown and media files locally.`,
-	Example: `crawlr --url https://example.com --library my-library --output ./assets
-  crawlr -u https://example.com -l my-library -o ./assets`,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		// Create a new viper instance
-		v := viper.New()
-
-		// Bind flags to viper
-		flagMappings := map[string]string{
-			"url":              "url",
-			"library":          "library",
-			"output":           "output",
-			"server-url":       "server_url",
-			"timeout":          "timeout",
-			"max-concurrent":   "max_concurrent",
-			"include-media":    "include_media",
-			"overwrite-files":  "overwrite_files",
-			"max-depth":        "max_depth",
-			"discovery-method": "discovery_method",
-			"batch-size":       "batch_size",
-			"exclude-patterns": "exclude_patterns",
-			"max-urls":         "max_urls",
-			"log-level":        "log_level",
-			"log-output":       "log_output",
-			"log-file-path":    "log_file_path",
-			"log-include-time": "log_include_time",
-			"log-structured":   "log_structured",
-		}
-		if err := config.BindFlags(v, cmd, flagMappings); err != nil {
-			return errors.Wrap(err, errors.ConfigurationError, "failed to bind flags")
-		}
-
-		// Load configuration with the viper instance that has flags bound
-		var err error
-		cfg, err = config.LoadConfigWithViper(v)
-		if err != nil {
-			return errors.Wrap(err, errors.ConfigurationError, "failed to load configuration")
-		}
-
-		// Override config with flag values if provided
-		if cmd.Flags().Changed("url") {
-			cfg.URL = url
-		}
-		if cmd.Flags().Changed("library") {
-			cfg.Library = library
-		}
-		if cmd.Flags().Changed("output") {
-			cfg.Output = output
-		}
-
-		// Initialize logger
-		logLevel := logger.INFO
-		switch cfg.LogLevel {
-		case "DEBUG":
-			logLevel = logger.DEBUG
-		case "INFO":
-			logLevel = logger.INFO
-		case "WARN":
-			logLevel = logger.WARN
-		case "ERROR":
-			logLevel = logger.ERROR
-		default:
-			return errors.New(errors.ConfigurationError, "invalid log level: "+cfg.LogLevel)
-		}
-
-		logOutput := logger.Console
-		switch cfg.LogOutput {
-		case "console":
-			logOutput = logger.Console
-		case "file":
-			logOutput = logger.File
-		case "both":
-			logOutput = logger.Both
-		default:
-			return errors.New(errors.ConfigurationError, "invalid log output: "+cfg.LogOutput)
-		}
-
-		loggerConfig := logger.LoggerConfig{
-			Level:       logLevel,
-			Output:      logOutput,
-			FilePath:    cfg.LogFilePath,
-			IncludeTime: cfg.LogIncludeTime,
-			Structured:  cfg.LogStructured,
-		}
-
-		var loggerErr error
-		appLogger, loggerErr = logger.NewLogger(loggerConfig)
-		if loggerErr != nil {
-			return errors.Wrap(loggerErr, errors.ConfigurationError, "failed to initialize logger")
-		}
-		defer appLogger.Close()
-
-		// Validate required parameters
-		if cfg.URL == "" {
-			return errors.New(errors.ValidationError, "url is required")
-		}
-		if cfg.Library == "" {
-			return errors.New(errors.ValidationError, "library name is required")
-		}
-		if cfg.Output == "" {
-			return errors.New(errors.ValidationError, "output folder is required")
-		}
-
-		appLogger.Info("Starting crawlr application", map[string]interface{}{
-			"url":      cfg.URL,
-			"library":  cfg.Library,
-			"output":   cfg.Output,
-			"logLevel": cfg.LogLevel,
-		})
-
-		// Initialize the crawler with the configuration
-		c := crawler.NewCrawler(cfg, appLogger)
+// configFile is the configuration file set with --config or CRAWLR_CONFIG,
+// read instead of looking for one
+var configFile string
+
+// flagMappings maps configuration flags to their viper configuration keys
+var flagMappings = map[string]string{
+	"library":                  "library",
+	"output":                   "output",
+	"server-url":               "server_url",
+	"api-version":              "api_version",
+	"engine":                   "engine",
+	"fallback":                 "fallback",
+	"user-agent":               "user_agents",
+	"tls-ca-file":              "tls_ca_file",
+	"tls-cert-file":            "tls_cert_file",
+	"tls-key-file":             "tls_key_file",
+	"insecure-skip-verify":     "insecure_skip_verify",
+	"max-idle-conns-per-host":  "max_idle_conns_per_host",
+	"idle-conn-timeout":        "idle_conn_timeout",
+	"http2":                    "http2",
+	"keep-alive":               "keep_alive",
+	"max-response-size":        "max_response_size",
+	"memory-budget":            "memory_budget",
+	"timeout":                  "timeout",
+	"max-concurrent":           "max_concurrent",
+	"retry-max":                "retry_max",
+	"retry-backoff":            "retry_backoff",
+	"retry-multiplier":         "retry_multiplier",
+	"retry-max-backoff":        "retry_max_backoff",
+	"retry-jitter":             "retry_jitter",
+	"max-retry-after":          "max_retry_after",
+	"breaker-threshold":        "breaker_threshold",
+	"breaker-cooldown":         "breaker_cooldown",
+	"include-media":            "include_media",
+	"media-rate-limit":         "media_rate_limit",
+	"media-backlog":            "media_backlog",
+	"shard-storage":            "shard_storage",
+	"media-max-size":           "media_max_size",
+	"media-types":              "media_types",
+	"media-extensions":         "media_extensions",
+	"media-exclude-extensions": "media_exclude_extensions",
+	"media-layout":             "media_layout",
+	"overwrite-files":          "overwrite_files",
+	"durable-writes":           "durable_writes",
+	"incremental":              "incremental",
+	"sync":                     "sync",
+	"sync-removed":             "sync_removed",
+	"seeds-file":               "seeds_file",
+	"save-html":                "save_html",
+	"save-cleaned-html":        "save_cleaned_html",
+	"save-json":                "save_json",
+	"export":                   "export",
+	"frontmatter":              "frontmatter",
+	"rewrite-links":            "rewrite_links",
+	"opt-out-policy":           "opt_out_policy",
+	"report-template":          "report_template",
+	"report-file":              "report_file",
+	"archive":                  "archive",
+	"archive-only":             "archive_only",
+	"snapshot":                 "snapshot",
+	"snapshot-dir":             "snapshot_dir",
+	"snapshot-keep":            "snapshot_keep",
+	"warc":                     "warc",
+	"crawl-tree":               "crawl_tree",
+	"crawl-tree-interval":      "crawl_tree_interval",
+	"sitemap-report":           "sitemap_report",
+	"link-graph":               "link_graph",
+	"expect-min-pages":         "expect_min_pages",
+	"expect-url":               "expect_urls",
+	"fail-fast":                "fail_fast",
+	"max-error-rate":           "max_error_rate",
+	"webhook":                  "webhook_urls",
+	"webhook-every":            "webhook_every",
+	"webhook-timeout":          "webhook_timeout",
+	"otlp-endpoint":            "otlp_endpoint",
+	"trace-sample-ratio":       "trace_sample_ratio",
+	"capture":                  "capture",
+	"replay-server":            "replay_server",
+	"max-depth":                "max_depth",
+	"discovery-method":         "discovery_method",
+	"strategy":                 "strategy",
+	"batch-size":               "batch_size",
+	"exclude-patterns":         "exclude_patterns",
+	"max-urls":                 "max_urls",
+	"max-duration":             "max_duration",
+	"include-subdomains":       "include_subdomains",
+	"allowed-domains":          "allowed_domains",
+	"max-pages-per-domain":     "max_pages_per_domain",
+	"ignore-meta-robots":       "ignore_meta_robots",
+	"use-canonical":            "use_canonical",
+	"languages":                "languages",
+	"crawl-content-types":      "crawl_content_types",
+	"probe-content-types":      "probe_content_types",
+	"convert-pdfs":             "convert_pdfs",
+	"budget-bytes":             "budget_bytes",
+	"budget-time":              "budget_time",
+	"budget-policy":            "budget_policy",
+	"preset":                   "preset",
+	"auto-preset":              "auto_preset",
+	"css-selector":             "css_selector",
+	"excluded-selector":        "excluded_selector",
+	"pagination-pattern":       "pagination_pattern",
+	"max-pagination-pages":     "max_pagination_pages",
+	"log-level":                "log_level",
+	"log-output":               "log_output",
+	"log-format":               "log_format",
+	"log-file-path":            "log_file_path",
+	"log-include-time":         "log_include_time",
+	"log-structured":           "log_structured",
+	"errors-format":            "errors_format",
+	"language":                 "language",
+}
 
-		// Set authentication token if needed (for now, we'll leave it empty)
-		// c.SetAuthToken("your-auth-token")
+// loadConfig binds the command's flags and loads the layered configuration.
+// Subcommands pass the mappings of their own flags as extra.
+func loadConfig(cmd *cobra.Command, extra ...map[string]string) (*config.Config, *viper.Viper, error) {
+	// Create a new viper instance, reading the configuration file given, if
+	// any, rather than looking for one
+	v := config.NewViper()
+	if path := configFilePath(); path != "" {
+		v.SetConfigFile(path)
+	}
 
-		// Initialize storage system
-		storage, err := storage.NewStorage(cfg, appLogger)
-		if err != nil {
-			return errors.Wrap(err, errors.StorageError, "failed to initialize storage")
+	// Bind flags to viper
+	for _, mappings := range append([]map[string]string{flagMappings}, extra...) {
+		if err := config.BindFlags(v, cmd, mappings); err != nil {
+			return nil, nil, errors.Wrap(err, errors.ConfigurationError, "failed to bind flags")
 		}
+	}
 
-		// Set storage for the crawler
-		c.SetStorage(storage)
-
-		// Create progress manager
-		progressManager := progress.NewProgressManager(appLogger)
-
-		// Start the crawling job
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Timeout)*time.Second)
-		defer cancel()
-
-		appLogger.Info("Starting crawl", map[string]interface{}{
-			"url": cfg.URL,
-			"maxDepth": cfg.MaxDepth,
-			"discoveryMethod": cfg.DiscoveryMethod,
-		})
-
-		// Create overall progress reporter with estimated total
-		crawlProgress := progressManager.CreateReporter("crawl", "Crawling URLs", cfg.MaxURLs)
-		defer crawlProgress.Complete()
+	// --url is repeatable: the first one is the start URL, the others seeds
+	if cmd.Flags().Changed("url") {
+		v.Set("url", urls[0])
+		v.Set("seeds", urls[1:])
+	}
 
-		// Use the recursive crawling method for true multi-level crawling with configured batch size
-		startResp, err := c.StartBatchRecursiveCrawling(ctx, cfg.URL, nil, cfg.MaxDepth, cfg.MaxURLs, cfg.BatchSize)
-		if err != nil {
-			return errors.Wrap(err, errors.CrawlerError, "failed to start crawl")
-		}
+	// Load configuration with the viper instance that has flags bound
+	loaded, err := config.LoadConfigWithViper(v)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errors.ConfigurationError, "failed to load configuration")
+	}
 
-		// Check if the crawl was successful
-		if !startResp.Success {
-			return errors.New(errors.CrawlerError, "crawl failed")
-		}
+	// Messages are shown in the configured language; Validate reports unknown ones
+	if i18n.Supported(loaded.Language) {
+		i18n.SetLanguage(loaded.Language)
+	}
 
-		if len(startResp.Results) == 0 {
-			return errors.New(errors.CrawlerError, "no results returned from crawl")
-		}
+	if err := applyVerbosity(loaded); err != nil {
+		return nil, nil, err
+	}
 
-		// Update progress to show discovered URLs
-		crawlProgress.SetTotal(len(startResp.Results))
+	// Wrappers parsing JSON errors get them on stderr without cobra's text
+	errorsFormat = loaded.ErrorsFormat
+	if jsonErrors() {
+		cmd.Root().SilenceErrors = true
+		cmd.Root().SilenceUsage = true
+	}
 
-		// Process all results
-		for i, result := range startResp.Results {
-			// Update progress
-			crawlProgress.SetCurrent(i + 1)
-			
-			if !result.Success {
-				appLogger.Warn("Skipping unsuccessful result", map[string]interface{}{"url": result.URL})
-				continue
-			}
+	return loaded, v, nil
+}
 
-			appLogger.Info("Processing result", map[string]interface{}{"url": result.URL})
+// configFilePath returns the configuration file set with --config or
+// CRAWLR_CONFIG, or an empty string
+func configFilePath() string {
+	if configFile != "" {
+		return configFile
+	}
+	return os.Getenv(config.EnvPrefix + "_CONFIG")
+}
 
-			// Save markdown if available
-			if result.Markdown.RawMarkdown != "" {
-				markdownPath, err := storage.SaveMarkdown(result.Markdown.RawMarkdown, result.URL)
-				if err != nil {
-					appLogger.Error("Failed to save markdown", map[string]interface{}{"error": err, "url": result.URL})
-				} else {
-					appLogger.Info("Saved markdown", map[string]interface{}{"path": markdownPath.Path, "url": result.URL})
-				}
-			}
+// configWritePath returns the configuration file commands editing the
+// configuration write: the one set with --config, the one read by v, or else
+// the configuration file of the user
+func configWritePath(v *viper.Viper) string {
+	if path := configFilePath(); path != "" {
+		return path
+	}
+	if path := v.ConfigFileUsed(); path != "" {
+		return path
+	}
+	return config.UserConfigPath()
+}
 
-			// Save media files if available
-			if len(result.Media.Images) > 0 {
-				// Create a response wrapper for this specific result
-				mediaStartResp := c.CreateSingleResultResponse(result)
-				
-				mediaProgress := progressManager.CreateReporter("media", fmt.Sprintf("Downloading media for %s", result.URL), len(result.Media.Images))
-				defer mediaProgress.Complete()
-				
-				mediaFiles, err := c.DownloadAndSaveMediaFromStartResponse(ctx, mediaStartResp, mediaProgress)
-				if err != nil {
-					appLogger.Error("Failed to save media files", map[string]interface{}{"error": err, "url": result.URL})
-				} else {
-					appLogger.Info("Saved media files", map[string]interface{}{"count": len(mediaFiles), "url": result.URL})
-				}
-			}
+// changedConfigKeys returns the config keys whose flags were set on the command line
+func changedConfigKeys(cmd *cobra.Command) map[string]bool {
+	changed := make(map[string]bool)
+	for flagName, configKey := range flagMappings {
+		if cmd.Flags().Changed(flagName) {
+			changed[configKey] = true
 		}
+	}
+	if cmd.Flags().Changed("url") {
+		changed["url"] = true
+		changed["seeds"] = true
+	}
+	return changed
+}
 
-		appLogger.Info("Crawlr application completed successfully")
-		return nil
-	},
+var rootCmd = &cobra.Command{
+	Use:   "crawlr",
+	Short: "Crawlr is a web crawling tool for extracting and storing content",
+	Long: `Crawlr is a powerful web crawling tool that connects to a crawl4ai server
+to extract content from websites and store markdown and media files locally.
+
+Crawls are started with crawlr crawl; running crawlr with flags only, as in
+earlier versions, crawls as well.`,
+	Example: `crawlr crawl --url https://example.com --library my-library --output ./assets
+  crawlr crawl -u https://example.com -l my-library -o ./assets
+  crawlr status -l my-library -o ./assets`,
+	RunE: runCrawlCommand,
 }
 
 func init() {
-	// Add flags to the root command
-	rootCmd.Flags().StringVarP(&url, "url", "u", "", "The root URL to crawl (required)")
-	rootCmd.Flags().StringVarP(&library, "library", "l", "", "The name of the library (required)")
-	rootCmd.Flags().StringVarP(&output, "output", "o", "", "The destination folder to store assets (required)")
+	// Add flags to the root command. Configuration flags are persistent so that
+	// subcommands such as "config show" resolve the same layers.
+	rootCmd.PersistentFlags().StringArrayVarP(&urls, "url", "u", nil, "The root URL to crawl (required; repeatable to crawl several roots into the same library)")
+	rootCmd.PersistentFlags().StringVarP(&library, "library", "l", "", "The name of the library (required)")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "The destination folder to store assets (required)")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Configuration file (default: config/config.yaml or config.yaml in the working directory, then $XDG_CONFIG_HOME/crawlr/config.yaml)")
 
 	// Add configuration flags
-	rootCmd.Flags().String("server-url", "http://192.168.1.27:8888/", "Crawl4ai server URL")
-	rootCmd.Flags().Int("timeout", 30, "Timeout for HTTP requests in seconds")
-	rootCmd.Flags().Int("max-concurrent", 5, "Maximum number of concurrent requests")
-	rootCmd.Flags().Bool("include-media", true, "Whether to include media files")
-	rootCmd.Flags().Bool("overwrite-files", false, "Whether to overwrite existing files")
+	rootCmd.PersistentFlags().String("server-url", "http://192.168.1.27:8888/", "Crawl4ai server URL")
+	rootCmd.PersistentFlags().String("api-version", "auto", "Crawl4ai API version (auto to detect it from the server, 0.4, 0.5 or 0.6)")
+	rootCmd.PersistentFlags().String("engine", "crawl4ai", "Engine fetching pages: crawl4ai, or local to fetch them directly and convert their HTML to markdown without a server")
+	rootCmd.PersistentFlags().StringArray("user-agent", nil, "User agent sent to origin servers and crawl4ai (repeatable to rotate several, one per request)")
+	rootCmd.PersistentFlags().String("tls-ca-file", "", "PEM bundle of CAs trusted, besides the system ones, by requests to crawl4ai and origin servers")
+	rootCmd.PersistentFlags().String("tls-cert-file", "", "PEM client certificate presented to servers requiring mutual TLS")
+	rootCmd.PersistentFlags().String("tls-key-file", "", "PEM private key of --tls-cert-file")
+	rootCmd.PersistentFlags().Bool("insecure-skip-verify", false, "Accept any TLS certificate, e.g. self-signed ones (insecure)")
+	rootCmd.PersistentFlags().Int("max-idle-conns-per-host", 0, "Idle connections kept open to each host for reuse (0 for --max-concurrent)")
+	rootCmd.PersistentFlags().Int("idle-conn-timeout", 90, "Seconds an idle connection is kept open (0 for no limit)")
+	rootCmd.PersistentFlags().Bool("http2", true, "Negotiate HTTP/2 with servers supporting it")
+	rootCmd.PersistentFlags().Bool("keep-alive", true, "Reuse connections between requests")
+	rootCmd.PersistentFlags().String("max-response-size", "1GB", "Fail responses of crawl4ai and media downloads larger than this size, e.g. 500MB (empty for no limit)")
+	rootCmd.PersistentFlags().String("memory-budget", "", "Page content held in memory before batches shrink and the crawl waits for saving to catch up, e.g. 512MB (empty for no limit)")
+	rootCmd.PersistentFlags().String("fallback", "none", "Fetch pages directly, converting their HTML to markdown, while crawl4ai is unavailable (none or local)")
+	rootCmd.PersistentFlags().Int("timeout", 30, "Timeout for HTTP requests in seconds")
+	rootCmd.PersistentFlags().Int("max-concurrent", 5, "Maximum number of concurrent requests")
+	rootCmd.PersistentFlags().Int("retry-max", 1, "Maximum number of retries of a failed crawl4ai request")
+	rootCmd.PersistentFlags().Float64("retry-backoff", 1, "Seconds waited before the first retry")
+	rootCmd.PersistentFlags().Float64("retry-multiplier", 2, "Factor applied to the wait before each further retry")
+	rootCmd.PersistentFlags().Float64("retry-max-backoff", 30, "Maximum seconds waited before a retry")
+	rootCmd.PersistentFlags().Float64("retry-jitter", 0.2, "Fraction of each wait randomized, spreading the retries of concurrent crawlers (0 to 1)")
+	rootCmd.PersistentFlags().Int("max-retry-after", 120, "Maximum seconds waited when a 429 or 503 response asks to retry later with Retry-After")
+	rootCmd.PersistentFlags().Int("breaker-threshold", 5, "Consecutive failures of a host, or of the crawl4ai server, after which its requests are held back (0 to disable)")
+	rootCmd.PersistentFlags().Int("breaker-cooldown", 60, "Seconds requests to a failing host are held back")
+	rootCmd.PersistentFlags().Bool("include-media", true, "Whether to include media files")
+	rootCmd.PersistentFlags().Float64("media-rate-limit", 0, "Maximum media downloads per second from each host (0 for no limit)")
+	rootCmd.PersistentFlags().Int("media-backlog", 2, "Crawled batches waiting for their pages and media to be saved before the crawl pauses")
+	rootCmd.PersistentFlags().Bool("shard-storage", false, "Save the top-level sections of the site in parallel, each into its own manifest segment merged at the end")
+	rootCmd.PersistentFlags().String("media-max-size", "", "Skip media files larger than this size, e.g. 2MB (default: no limit)")
+	rootCmd.PersistentFlags().String("media-types", "", "Comma-separated media types to download: image, video, audio, document (default: all)")
+	rootCmd.PersistentFlags().String("media-extensions", "", "Comma-separated file extensions to download, e.g. jpg,png (default: all)")
+	rootCmd.PersistentFlags().String("media-exclude-extensions", "", "Comma-separated file extensions never to download, e.g. gif,svg")
+	rootCmd.PersistentFlags().String("media-layout", "path", "Media layout: path (mirror media URLs) or cas (content-addressed objects referenced from per-page link files)")
+	rootCmd.PersistentFlags().Bool("overwrite-files", false, "Whether to overwrite existing files")
+	rootCmd.PersistentFlags().Bool("durable-writes", false, "Flush files and their directories to disk before moving on, so that a crash or power loss cannot lose saved files")
+	rootCmd.PersistentFlags().Bool("incremental", false, "Re-crawl only what changed: skip pages and media whose ETag or Last-Modified show no change since the last crawl, overwrite the others")
+	rootCmd.PersistentFlags().Bool("sync", false, "Keep the library an exact mirror of the site: prune pages of earlier crawls that are gone (404/410) or no longer reached by a complete crawl")
+	rootCmd.PersistentFlags().String("seeds-file", "", "File of further root URLs, one per line, # starting comments")
+	rootCmd.PersistentFlags().String("sync-removed", "move", "What --sync does with pruned pages: move (to _removed/ in the library) or delete")
+	rootCmd.PersistentFlags().Bool("save-html", false, "Save the raw HTML of each page next to its markdown")
+	rootCmd.PersistentFlags().Bool("save-cleaned-html", false, "Save the cleaned HTML of each page next to its markdown")
+	rootCmd.PersistentFlags().Bool("save-json", false, "Save the full crawl4ai result of each page as a JSON sidecar")
+	rootCmd.PersistentFlags().String("export", "", "Export crawled pages to the library in the given format (jsonl)")
+	rootCmd.PersistentFlags().Bool("frontmatter", false, "Prepend YAML front matter (source, title, description, date, depth, tags) to saved markdown")
+	rootCmd.PersistentFlags().Bool("rewrite-links", false, "Rewrite links between crawled pages to relative .md paths")
+	rootCmd.PersistentFlags().String("opt-out-policy", "warn", "Handling of pages that opt out of archiving or AI use (ignore, warn, skip)")
+	rootCmd.PersistentFlags().String("report-template", "", "Go template (text, or HTML if named *.html[.tmpl]) rendered into an end-of-run report")
+	rootCmd.PersistentFlags().String("report-file", "", "Path of the report (default: the template name without .tmpl, in the library)")
+	rootCmd.PersistentFlags().String("archive", "", "Pack the library into a single archive after the crawl (zip, tar.gz)")
+	rootCmd.PersistentFlags().Bool("archive-only", false, "Remove the library directory once it has been archived")
+	rootCmd.PersistentFlags().Bool("snapshot", false, "Keep a deduplicated snapshot of the library after the crawl")
+	rootCmd.PersistentFlags().String("snapshot-dir", "", "Snapshot store directory (default <library>.snapshots next to the library)")
+	rootCmd.PersistentFlags().Int("snapshot-keep", 0, "Number of snapshots to keep, pruning older ones (0 keeps all)")
+	rootCmd.PersistentFlags().Bool("warc", false, "Record fetched pages and media as WARC response records in the library's warc/ directory")
+	rootCmd.PersistentFlags().Bool("crawl-tree", false, "Export the crawl tree (pages by depth with parents and statuses) to crawl-tree.json in the library during the crawl")
+	rootCmd.PersistentFlags().Int("crawl-tree-interval", 10, "Seconds between crawl tree exports")
+	rootCmd.PersistentFlags().Bool("sitemap-report", false, "Compare the crawled pages with the sitemap and report sitemap pages never reached by following links, and vice versa")
+	rootCmd.PersistentFlags().Bool("link-graph", false, "Record the links of every page, pages at the maximum depth included, for crawlr export graph")
+	rootCmd.PersistentFlags().Int("expect-min-pages", 0, "Fail the run unless at least this many pages are crawled successfully (0 disables the check)")
+	rootCmd.PersistentFlags().StringArray("expect-url", nil, "Fail the run unless a crawled page matches this regex (repeatable)")
+	rootCmd.PersistentFlags().Bool("fail-fast", false, "Abort the crawl on the first storage or API error, keeping the pages saved so far")
+	rootCmd.PersistentFlags().Float64("max-error-rate", 0, "Fail the run if more than this percentage of pages could not be crawled or saved (0 for no limit)")
+	rootCmd.PersistentFlags().StringArray("webhook", nil, "POST a JSON payload with the summary stats to this URL when the crawl starts, completes or fails (repeatable)")
+	rootCmd.PersistentFlags().Int("webhook-every", 0, "Also notify webhooks every this many pages (0 to disable)")
+	rootCmd.PersistentFlags().Int("webhook-timeout", 10, "Seconds a webhook request may take")
+	rootCmd.PersistentFlags().String("otlp-endpoint", "", "Export OpenTelemetry spans of the crawl to this OTLP/HTTP endpoint, e.g. http://localhost:4318")
+	rootCmd.PersistentFlags().Float64("trace-sample-ratio", 1, "Fraction of crawls traced with --otlp-endpoint")
+	rootCmd.PersistentFlags().String("capture", "", "Capture every HTTP exchange of the crawl to this directory for replay with crawlr mock-server --replay")
+	rootCmd.PersistentFlags().String("replay-server", "", "Send every request, to crawl4ai and origin servers, to this replay server (crawlr mock-server --replay)")
 
 	// Add crawling configuration flags
-	rootCmd.Flags().Int("max-depth", 2, "Maximum crawling depth")
-	rootCmd.Flags().String("discovery-method", "auto", "URL discovery method (auto, sitemap, links)")
-	rootCmd.Flags().Int("batch-size", 5, "Number of URLs to process in each batch")
-	rootCmd.Flags().String("exclude-patterns", "", "Regex patterns to exclude from crawling")
-	rootCmd.Flags().Int("max-urls", 50, "Maximum number of URLs to crawl")
+	rootCmd.PersistentFlags().Int("max-depth", 2, "Maximum crawling depth")
+	rootCmd.PersistentFlags().String("discovery-method", "auto", "URL discovery method (auto, sitemap, links, feed to crawl the entries of the RSS or Atom feeds of the start URLs, or none to crawl the start URLs only)")
+	rootCmd.PersistentFlags().String("strategy", "bfs", "Order in which discovered pages are crawled (bfs, dfs, bestfirst)")
+	rootCmd.PersistentFlags().Int("batch-size", 5, "Number of URLs to process in each batch")
+	rootCmd.PersistentFlags().String("exclude-patterns", "", "Regex patterns to exclude from crawling")
+	rootCmd.PersistentFlags().Int("max-urls", 50, "Maximum number of URLs to crawl")
+	rootCmd.PersistentFlags().Int("max-duration", 0, "Seconds after which no further batches are crawled, the pages crawled so far being saved (0 for no limit)")
+	rootCmd.PersistentFlags().Bool("include-subdomains", false, "Also crawl the subdomains of the start URL's host")
+	rootCmd.PersistentFlags().String("allowed-domains", "", "Comma-separated domains, with their subdomains, crawled in addition to the start URL's host")
+	rootCmd.PersistentFlags().Int("max-pages-per-domain", 0, "Maximum number of pages crawled from each host (0 for no limit)")
+	rootCmd.PersistentFlags().Bool("ignore-meta-robots", false, "Save noindex pages and follow nofollow links")
+	rootCmd.PersistentFlags().Bool("use-canonical", false, "Save pages under their declared canonical URL and crawl each canonical page once")
+	rootCmd.PersistentFlags().String("languages", "", "Comma-separated languages crawled, skipping pages and hreflang alternates in other languages (empty for all)")
+	rootCmd.PersistentFlags().String("crawl-content-types", "", "Comma-separated content types crawled as pages, e.g. text/html; links to media and PDFs are downloaded as media and others skipped (empty to crawl every link)")
+	rootCmd.PersistentFlags().Bool("probe-content-types", false, "Send a HEAD request for links whose extension tells no content type, with --crawl-content-types")
+	rootCmd.PersistentFlags().Bool("convert-pdfs", true, "Crawl linked PDF documents with crawl4ai's PDF processing and save their text as markdown")
+	rootCmd.PersistentFlags().String("budget-bytes", "", "Total bytes the crawl may write, pages and media, e.g. 5GB (empty for no limit)")
+	rootCmd.PersistentFlags().Int("budget-time", 0, "Total seconds the crawl may take, pages and media (0 for no limit)")
+	rootCmd.PersistentFlags().String("budget-policy", "balanced", "How the budget is split between pages and media (pages, media, balanced)")
+	rootCmd.PersistentFlags().String("preset", "", "Crawl preset for a documentation platform ("+strings.Join(config.PresetNames(), ", ")+")")
+	rootCmd.PersistentFlags().Bool("auto-preset", false, "Apply the preset of the documentation platform detected on the start page")
+	rootCmd.PersistentFlags().String("css-selector", "", "CSS selector of the page content to convert to markdown")
+	rootCmd.PersistentFlags().String("excluded-selector", "", "CSS selector of page elements to leave out of the markdown")
+	rootCmd.PersistentFlags().String("pagination-pattern", "", "Regex matched against links to follow as pagination (next page) without increasing the depth")
+	rootCmd.PersistentFlags().Int("max-pagination-pages", 0, "Maximum number of pages followed into each paginated listing (0 for no limit)")
 
 	// Add logging configuration flags
-	rootCmd.Flags().String("log-level", "INFO", "Log level (DEBUG, INFO, WARN, ERROR)")
-	rootCmd.Flags().String("log-output", "console", "Log output (console, file, both)")
-	rootCmd.Flags().String("log-file-path", "crawlr.log", "Path to log file")
-	rootCmd.Flags().Bool("log-include-time", true, "Include timestamp in logs")
-	rootCmd.Flags().Bool("log-structured", true, "Use structured logging format")
+	rootCmd.PersistentFlags().String("log-level", "INFO", "Log level (DEBUG, INFO, WARN, ERROR)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Log errors only and print the summary as a JSON line")
+	rootCmd.PersistentFlags().BoolVar(&tui, "tui", false, "Show a live dashboard of the crawl (pages/s, ETA, frontier, workers, recent pages and errors) instead of progress bars")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Log every page and file saved (-v), and debug messages (-vv)")
+	rootCmd.PersistentFlags().String("log-output", "console", "Log output (console, file, both)")
+	rootCmd.PersistentFlags().String("log-format", "text", "Log format (text, json); json writes a JSON object per line")
+	rootCmd.PersistentFlags().String("log-file-path", "", "Path to log file (default: $XDG_STATE_HOME/crawlr/crawlr.log)")
+	rootCmd.PersistentFlags().Bool("log-include-time", true, "Include timestamp in logs")
+	rootCmd.PersistentFlags().Bool("log-structured", true, "Use structured logging format")
+	rootCmd.PersistentFlags().StringVar(&errorsFormat, "errors-format", "text", "Format of errors written to stderr (text, json)")
+	rootCmd.PersistentFlags().String("language", "en", "Language of messages and reports ("+strings.Join(i18n.Languages(), ", ")+")")
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Whoops. There was an error while executing your CLI '%s'", err)
-		os.Exit(1)
+		reportFatal(err)
+		os.Exit(errors.ExitCode(err))
 	}
 }
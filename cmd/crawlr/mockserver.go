@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"crawlr/internal/errors"
+	"crawlr/internal/mockserver"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	mockAddr     string
+	mockFixtures string
+	mockReplay   string
+)
+
+var mockServerCmd = &cobra.Command{
+	Use:   "mock-server",
+	Short: "Run a mock crawl4ai server replaying canned responses",
+	Long: `Run a server that answers the crawl4ai API with canned results, to try
+configurations, filters and storage layouts without a crawl4ai deployment.
+
+Without --fixtures, a small built-in documentation site is served. A fixtures
+directory holds JSON files with crawl4ai result objects, lists of them or whole
+crawl responses, and a files/ subdirectory served as is (e.g. media).
+` + mockserver.BaseURLPlaceholder + ` in fixtures is replaced by the address of the mock server,
+which also serves the HTML of fixture pages, so that links, media downloads and
+platform detection stay on the mock server.
+
+With --replay, the server instead replays a run captured with --capture: a
+crawl run with --replay-server sends every request, to crawl4ai and origin
+servers alike, to the mock server, which answers with the captured responses
+in the order they were captured. Filters, storage options and post-processing
+can then be changed and tried offline against exactly the same responses.
+
+Endpoints:
+  POST /crawl    results of the requested URLs (failed results for unknown ones)
+  POST /md       markdown of the requested URL
+  GET  /health   health check
+  GET  /{path}   fixture files and page HTML
+  ANY  /replay    captured responses, with --replay`,
+	Example: `crawlr mock-server
+  crawlr -u http://127.0.0.1:11235/ -l mock -o ./libraries --server-url http://127.0.0.1:11235/
+  crawlr mock-server --addr 127.0.0.1:9999 --fixtures ./testdata/site
+  crawlr -u https://docs.example.com -l docs -o ./libraries --capture ./capture
+  crawlr mock-server --replay ./capture
+  crawlr -u https://docs.example.com -l docs -o ./replayed --replay-server http://127.0.0.1:11235/`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mockCfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		appLogger, err = newLogger(mockCfg)
+		if err != nil {
+			return err
+		}
+		defer appLogger.Close()
+
+		var mock *mockserver.Server
+		if mockReplay != "" {
+			mock, err = mockserver.NewReplay(mockReplay, appLogger)
+			if err != nil {
+				return errors.Wrap(err, errors.ConfigurationError, "failed to load capture")
+			}
+		} else {
+			mock, err = mockserver.New(mockFixtures, appLogger)
+			if err != nil {
+				return errors.Wrap(err, errors.ConfigurationError, "failed to load fixtures")
+			}
+		}
+
+		listener, err := net.Listen("tcp", mockAddr)
+		if err != nil {
+			return errors.Wrap(err, errors.NetworkError, "failed to listen")
+		}
+
+		srv := &http.Server{
+			Handler:           mock.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		serveErr := make(chan error, 1)
+		go func() {
+			serveErr <- srv.Serve(listener)
+		}()
+
+		if mockReplay != "" {
+			appLogger.Info("Replaying captured run", map[string]interface{}{
+				"addr":      listener.Addr().String(),
+				"capture":   mockReplay,
+				"exchanges": mock.Exchanges(),
+			})
+		} else {
+			appLogger.Info("Serving mock crawl4ai API", map[string]interface{}{
+				"addr":     listener.Addr().String(),
+				"fixtures": valueOr(mockFixtures, "built-in"),
+				"pages":    mock.Pages(),
+			})
+		}
+
+		select {
+		case err := <-serveErr:
+			if !stderrors.Is(err, http.ErrServerClosed) {
+				return errors.Wrap(err, errors.NetworkError, "failed to serve")
+			}
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				return errors.Wrap(err, errors.NetworkError, "failed to shut down server")
+			}
+		}
+
+		appLogger.Info("Mock server stopped")
+		return nil
+	},
+}
+
+func init() {
+	mockServerCmd.Flags().StringVar(&mockAddr, "addr", "127.0.0.1:11235", "Address to listen on")
+	mockServerCmd.Flags().StringVar(&mockFixtures, "fixtures", "", "Directory of fixtures to replay (default: built-in example site)")
+	mockServerCmd.Flags().StringVar(&mockReplay, "replay", "", "Capture directory of a run to replay (see --capture and --replay-server)")
+	mockServerCmd.MarkFlagsMutuallyExclusive("fixtures", "replay")
+
+	rootCmd.AddCommand(mockServerCmd)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"crawlr/internal/crawler"
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+
+	"github.com/spf13/cobra"
+)
+
+var pingFormat string
+
+var pingCmd = &cobra.Command{
+	Use:   "ping",
+	Short: "Check that the crawl4ai server is up",
+	Long: `Query the /health endpoint and the root of the crawl4ai server and report its
+version, the latency of each request and whether it requires authentication.
+
+The exit status is non-zero if the server is unreachable or rejects the
+requests, so ping can gate a long crawl in CI. crawlr doctor goes further with
+a test crawl.`,
+	Example: `crawlr ping
+  crawlr ping --server-url http://localhost:11235 --format json`,
+	Args: cobra.NoArgs,
+	// The report already explains what failed
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pingCfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if pingFormat != "text" && pingFormat != "json" {
+			return errors.New(errors.ValidationError, "format must be one of text, json").WithContext("format", pingFormat)
+		}
+
+		// Keep the crawler quiet unless a log level was requested explicitly
+		if !changedConfigKeys(cmd)["log_level"] {
+			pingCfg.LogLevel = "ERROR"
+		}
+		appLogger, err = newLogger(pingCfg)
+		if err != nil {
+			return err
+		}
+		defer appLogger.Close()
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), time.Duration(pingCfg.Timeout)*time.Second)
+		defer cancel()
+
+		result, err := crawler.NewCrawler(pingCfg, appLogger).Ping(ctx)
+		if err != nil && cmd.Context().Err() != nil {
+			return errors.Wrap(err, errors.NetworkError, "ping interrupted")
+		}
+
+		out := cmd.OutOrStdout()
+		if pingFormat == "json" {
+			if err := writePingJSON(out, result); err != nil {
+				return err
+			}
+		} else {
+			writePingText(out, result)
+		}
+
+		switch {
+		case !result.Reachable():
+			return errors.New(errors.NetworkError, "crawl4ai server unreachable").WithContext("serverURL", pingCfg.ServerURL)
+		case result.Rejected():
+			return errors.New(errors.APIError, "crawl4ai server rejected the requests").WithContext("serverURL", pingCfg.ServerURL)
+		}
+		return nil
+	},
+}
+
+// writePingText writes the result of a ping for a terminal
+func writePingText(out io.Writer, result *crawler.PingResult) {
+	fmt.Fprint(out, i18n.T("ping.server", result.ServerURL))
+	for _, endpoint := range result.Endpoints {
+		if endpoint.Answered() {
+			fmt.Fprint(out, i18n.T("ping.endpoint", endpoint.Path, endpoint.StatusCode, endpoint.Latency.Round(10*time.Microsecond)))
+		} else {
+			fmt.Fprint(out, i18n.T("ping.endpoint.failed", endpoint.Path, endpoint.Error))
+		}
+	}
+	if result.Version != "" || result.Status != "" {
+		fmt.Fprint(out, i18n.T("ping.version", valueOr(result.Version, i18n.T("doctor.server.unknown")), result.Status))
+	}
+	fmt.Fprint(out, pingAuth(result))
+}
+
+// pingAuth describes whether the server accepted the requests, with or
+// without a token
+func pingAuth(result *crawler.PingResult) string {
+	for _, endpoint := range result.Endpoints {
+		if !endpoint.Rejected() {
+			continue
+		}
+		if result.TokenSent {
+			return i18n.T("ping.auth.rejected", endpoint.StatusCode)
+		}
+		return i18n.T("ping.auth.required", endpoint.StatusCode)
+	}
+	switch {
+	case !result.Reachable():
+		return i18n.T("ping.auth.unknown")
+	case result.TokenSent:
+		return i18n.T("ping.auth.accepted")
+	default:
+		return i18n.T("ping.auth.none")
+	}
+}
+
+// writePingJSON writes the result of a ping as a JSON object
+func writePingJSON(out io.Writer, result *crawler.PingResult) error {
+	data, err := json.MarshalIndent(struct {
+		*crawler.PingResult
+		Reachable bool `json:"reachable"`
+		Rejected  bool `json:"rejected"`
+	}{result, result.Reachable(), result.Rejected()}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, errors.ValidationError, "failed to encode ping result")
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+func init() {
+	pingCmd.Flags().StringVar(&pingFormat, "format", "text", "Output format (text, json)")
+
+	rootCmd.AddCommand(pingCmd)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+)
+
+// errorsFormat selects how errors are written to stderr (text or json). It is
+// set by --errors-format and replaced by the resolved configuration once loaded.
+var errorsFormat = "text"
+
+// jsonErrors reports whether errors are emitted as JSON lines
+func jsonErrors() bool {
+	return errorsFormat == "json"
+}
+
+// reportFatal writes the error that ended the command to stderr
+func reportFatal(err error) {
+	if jsonErrors() {
+		report := errors.NewReport(err)
+		report.Fatal = true
+		report.ExitCode = errors.ExitCode(err)
+		fmt.Fprintf(os.Stderr, "%s\n", report.JSON())
+		return
+	}
+	fmt.Fprint(os.Stderr, i18n.T("error.fatal", err))
+}
+
+// reportURLError logs an error affecting a single URL and, in json mode, also
+// writes it to stderr as a JSON line
+func reportURLError(errorType errors.ErrorType, message string, err error, url string) {
+	appLogger.Error(message, map[string]interface{}{"error": err, "url": url})
+	writeURLError(errors.Wrap(err, errorType, message), url)
+}
+
+// writeURLError writes an error affecting a single URL to stderr as a JSON
+// line in json mode
+func writeURLError(err error, url string) {
+	if !jsonErrors() {
+		return
+	}
+	report := errors.NewReport(err)
+	report.URL = url
+	fmt.Fprintf(os.Stderr, "%s\n", report.JSON())
+}
@@ -0,0 +1,1002 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"crawlr/internal/auth"
+	"crawlr/internal/budget"
+	"crawlr/internal/config"
+	"crawlr/internal/crawler"
+	"crawlr/internal/errors"
+	"crawlr/internal/frontier"
+	"crawlr/internal/hooks"
+	"crawlr/internal/logger"
+	"crawlr/internal/progress"
+	"crawlr/internal/replay"
+	"crawlr/internal/report"
+	"crawlr/internal/storage"
+	"crawlr/internal/tracing"
+	"crawlr/internal/transform"
+	"crawlr/internal/webhook"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// newLogger creates the application logger from the configuration
+func newLogger(cfg *config.Config) (*logger.Logger, error) {
+	logLevel := logger.INFO
+	switch cfg.LogLevel {
+	case "DEBUG":
+		logLevel = logger.DEBUG
+	case "INFO":
+		logLevel = logger.INFO
+	case "WARN":
+		logLevel = logger.WARN
+	case "ERROR":
+		logLevel = logger.ERROR
+	default:
+		return nil, errors.New(errors.ConfigurationError, "invalid log level: "+cfg.LogLevel)
+	}
+
+	logOutput := logger.Console
+	switch cfg.LogOutput {
+	case "console":
+		logOutput = logger.Console
+	case "file":
+		logOutput = logger.File
+	case "both":
+		logOutput = logger.Both
+	default:
+		return nil, errors.New(errors.ConfigurationError, "invalid log output: "+cfg.LogOutput)
+	}
+
+	logFormat := logger.TextFormat
+	switch cfg.LogFormat {
+	case "text":
+		logFormat = logger.TextFormat
+	case "json":
+		logFormat = logger.JSONFormat
+	default:
+		return nil, errors.New(errors.ConfigurationError, "invalid log format: "+cfg.LogFormat)
+	}
+
+	loggerConfig := logger.LoggerConfig{
+		Level:       logLevel,
+		Output:      logOutput,
+		Format:      logFormat,
+		FilePath:    valueOr(cfg.LogFilePath, config.DefaultLogPath()),
+		IncludeTime: cfg.LogIncludeTime,
+		Structured:  cfg.LogStructured,
+		Details:     verbosity > 0,
+	}
+	if progressDisplay != nil {
+		loggerConfig.Console = progressDisplay
+	}
+
+	l, err := logger.NewLogger(loggerConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ConfigurationError, "failed to initialize logger")
+	}
+	return l, nil
+}
+
+// setupTracing exports the spans of crawls to the configured OTLP endpoint,
+// returning a function flushing the spans not exported yet
+func setupTracing(cfg *config.Config, appLogger *logger.Logger) (func(), error) {
+	shutdown, err := tracing.Setup(context.Background(), cfg.OTLPEndpoint, cfg.TraceSampleRatio)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ConfigurationError, "failed to set up tracing")
+	}
+	if cfg.OTLPEndpoint != "" {
+		appLogger.Info("Exporting traces", map[string]interface{}{
+			"endpoint":    cfg.OTLPEndpoint,
+			"sampleRatio": cfg.TraceSampleRatio,
+		})
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			appLogger.Warn("Failed to export traces", map[string]interface{}{"error": err})
+		}
+	}, nil
+}
+
+// detectPreset detects the documentation platform of the start page. It
+// applies the matching preset with auto_preset, returning true, and otherwise
+// only suggests it.
+func detectPreset(ctx context.Context, c *crawler.Crawler, cfg *config.Config, appLogger *logger.Logger) bool {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	preset, ok, err := c.DetectPreset(ctx, cfg.URL)
+	if err != nil {
+		appLogger.Debug("Failed to detect documentation platform", map[string]interface{}{"url": cfg.URL, "error": err})
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	if !cfg.AutoPreset {
+		appLogger.Info(fmt.Sprintf("Detected %s; run with --preset %s (or --auto-preset) for better markdown", preset.Description, preset.Name), map[string]interface{}{
+			"preset": preset.Name,
+		})
+		return false
+	}
+
+	cfg.UsePreset(preset.Name)
+	appLogger.Info("Applying detected preset", map[string]interface{}{
+		"preset":   preset.Name,
+		"platform": preset.Description,
+	})
+	return true
+}
+
+// crawlOptions are the settings of a run that do not come from the configuration
+type crawlOptions struct {
+	resume    *crawler.Checkpoint       // cancelled crawl to pick up, if not nil
+	progress  *progress.ProgressManager // progress of the run, created if nil
+	dashboard *progress.Dashboard       // dashboard of the run, stopped before the summary is printed, if not nil
+}
+
+// runCrawl crawls cfg.URL and stores the results in the configured library
+func runCrawl(parent context.Context, cfg *config.Config, appLogger *logger.Logger, opts crawlOptions) (err error) {
+	appLogger.Info("Starting crawlr application", map[string]interface{}{
+		"url":      cfg.URL,
+		"library":  cfg.Library,
+		"output":   cfg.Output,
+		"logLevel": cfg.LogLevel,
+	})
+
+	// The spans of the run are children of the crawl span
+	parent, span := tracing.Start(parent, "crawl",
+		attribute.String("url.full", cfg.URL),
+		attribute.String("crawlr.library", cfg.Library),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	// Capture the HTTP exchanges of the run for replay if configured
+	var recorder *replay.Recorder
+	if cfg.Capture != "" {
+		var err error
+		recorder, err = replay.NewRecorder(cfg.Capture)
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to initialize capture")
+		}
+		defer func() {
+			appLogger.Info("Captured HTTP exchanges", map[string]interface{}{
+				"dir":       cfg.Capture,
+				"exchanges": recorder.Exchanges(),
+			})
+		}()
+	}
+
+	// Initialize the crawler with the configuration
+	c := crawler.NewCrawler(cfg, appLogger)
+	if recorder != nil {
+		c.SetRecorder(recorder)
+	}
+
+	// Set authentication token if needed (for now, we'll leave it empty)
+	// c.SetAuthToken("your-auth-token")
+
+	// Configure credentials for origin servers
+	originAuth, err := auth.NewRegistry(cfg.Auth)
+	if err != nil {
+		return errors.Wrap(err, errors.ConfigurationError, "failed to load auth configuration")
+	}
+	c.SetOriginAuth(originAuth)
+	c.SetSeeds(cfg.Seeds)
+	if originAuth.Len() > 0 {
+		appLogger.Info("Loaded origin credentials", map[string]interface{}{"domains": originAuth.Len()})
+	}
+	if len(cfg.Domains) > 0 {
+		appLogger.Info("Loaded domain policies", map[string]interface{}{"domains": len(cfg.Domains)})
+	}
+
+	// Suggest or apply the preset of the documentation platform; the crawler is
+	// recreated to pick up the settings of an applied preset
+	if cfg.Preset == "" && detectPreset(parent, c, cfg, appLogger) {
+		c = crawler.NewCrawler(cfg, appLogger)
+		c.SetOriginAuth(originAuth)
+		c.SetSeeds(cfg.Seeds)
+		if recorder != nil {
+			c.SetRecorder(recorder)
+		}
+	}
+
+	// Share the frontier and visited set with cooperating crawlers if configured
+	if cfg.StateBackend != "memory" {
+		backend, err := frontier.NewBackend(cfg.StateBackend, frontier.Options{
+			RedisURL:  cfg.RedisURL,
+			KeyPrefix: cfg.RedisKeyPrefix,
+			TTL:       time.Duration(cfg.RedisTTL) * time.Second,
+			Strategy:  cfg.Strategy,
+		})
+		if err != nil {
+			return errors.Wrap(err, errors.ConfigurationError, "failed to initialize crawl state backend")
+		}
+		defer backend.Close()
+		c.SetStateBackend(backend)
+		appLogger.Info("Using shared crawl state", map[string]interface{}{"backend": cfg.StateBackend})
+	}
+
+	// Initialize storage system
+	store, err := storage.NewStorage(cfg, appLogger)
+	if err != nil {
+		return errors.Wrap(err, errors.StorageError, "failed to initialize storage")
+	}
+
+	defer func() {
+		if err := store.Close(); err != nil {
+			appLogger.Error("Failed to close storage", map[string]interface{}{"error": err})
+		}
+	}()
+
+	// Set storage for the crawler
+	c.SetStorage(store)
+	if opts.resume != nil {
+		c.SetResume(opts.resume)
+	}
+
+	pipeline, err := transform.New(cfg.Transforms)
+	if err != nil {
+		return errors.Wrap(err, errors.ConfigurationError, "invalid transforms")
+	}
+	pageHooks, err := hooks.New(cfg.Hooks)
+	if err != nil {
+		return errors.Wrap(err, errors.ConfigurationError, "invalid hooks")
+	}
+
+	// Balance the crawl budget, if any, between pages and media
+	budgetBytes, _ := config.ParseSize(cfg.BudgetBytes)
+	crawlBudget := budget.New(cfg.BudgetPolicy, budgetBytes, time.Duration(cfg.BudgetTime)*time.Second, cfg.MaxURLs)
+	if crawlBudget.Enabled() {
+		c.SetBudget(crawlBudget)
+		appLogger.Info("Using crawl budget", map[string]interface{}{
+			"bytes":  budgetBytes,
+			"time":   cfg.BudgetTime,
+			"policy": cfg.BudgetPolicy,
+		})
+	}
+
+	summary := &report.Summary{
+		Library:   cfg.Library,
+		URL:       cfg.URL,
+		Output:    store.GetLibraryPath(),
+		StartedAt: time.Now(),
+		Pages:     []report.Page{},
+		Errors:    []report.Error{},
+	}
+
+	// Notify webhooks and chat channels of the crawl, its end included when
+	// it was cancelled
+	notifier := webhook.New(cfg.WebhookURLs, time.Duration(cfg.WebhookTimeout)*time.Second)
+	notifier.AddChat(cfg.Notifications)
+	notify := func(event string, processed int, crawlErr error) {
+		if err := notifier.Notify(context.WithoutCancel(parent), event, summary, processed, crawlErr); err != nil {
+			appLogger.Warn("Failed to notify webhook", map[string]interface{}{"event": event, "error": err})
+		}
+	}
+
+	// Create progress manager
+	progressManager := opts.progress
+	if progressManager == nil {
+		progressManager = progress.NewProgressManager(appLogger)
+	}
+
+	// Start the crawling job. The timeout applies to each HTTP request; the
+	// crawl as a whole is bounded by max_duration, which lets it finish
+	// saving, and stops early only when interrupted.
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	// With fail_fast, the first storage or API error cancels the crawl like
+	// an interruption
+	policy := newErrorPolicy(cfg, cancel)
+
+	appLogger.Info("Starting crawl", map[string]interface{}{
+		"url":             cfg.URL,
+		"maxDepth":        cfg.MaxDepth,
+		"discoveryMethod": cfg.DiscoveryMethod,
+	})
+
+	// Create overall progress reporter with estimated total
+	crawlProgress := progressManager.CreateReporter("crawl", "Crawling URLs", cfg.MaxURLs)
+	defer crawlProgress.Complete()
+
+	notify(webhook.EventStarted, 0, nil)
+	defer func() {
+		processed, _ := crawlProgress.GetProgress()
+		if err != nil {
+			notify(webhook.EventFailed, processed, err)
+		} else {
+			notify(webhook.EventCompleted, processed, nil)
+		}
+	}()
+
+	// pageProcessed counts a page saved, skipped or failed with the bytes
+	// written for it, listing it on the dashboard if any
+	pageProcessed := func(url string, written int64) {
+		crawlProgress.AddBytes(written)
+		crawlProgress.Increment()
+		policy.pageProcessed()
+		if opts.dashboard != nil {
+			opts.dashboard.PageDone(url)
+		}
+	}
+	// pageFailed lists an error about a page on the dashboard if any
+	pageFailed := func(url, message string) {
+		if opts.dashboard != nil {
+			opts.dashboard.PageFailed(url, message)
+		}
+	}
+
+	// saveResult saves a crawled page with its media, counting it in summary
+	// and recording it in the manifest through pages
+	saveResult := func(result crawler.PageResult, crawledAt time.Time, summary *report.Summary, pages pageRecorder) {
+		ctx, span := tracing.Start(ctx, "page.save", attribute.String("url.full", result.URL))
+		defer span.End()
+
+		pageError := func(errorType errors.ErrorType, message string, err error, url string) {
+			reportURLError(errorType, message, err, url)
+			summary.AddError(url, errorType, message, err)
+			pageFailed(url, message)
+			policy.pageFailed(url, errors.Wrap(err, errorType, message), true)
+		}
+
+		if !result.Success {
+			appLogger.Warn("Skipping unsuccessful result", map[string]interface{}{"url": result.URL, "error": result.ErrorMessage})
+			writeURLError(errors.New(errors.CrawlerError, "crawl failed").WithContext("reason", result.ErrorMessage), result.URL)
+			summary.PagesFailed++
+			summary.AddError(result.URL, errors.CrawlerError, valueOr(result.ErrorMessage, "crawl failed"), nil)
+			pageFailed(result.URL, valueOr(result.ErrorMessage, "crawl failed"))
+			policy.pageFailed(result.URL, errors.New(errors.CrawlerError, valueOr(result.ErrorMessage, "crawl failed")), true)
+			return
+		}
+
+		// Pages an incremental re-crawl found unchanged keep their files
+		if result.Unchanged {
+			appLogger.Debug("Skipping unchanged page", map[string]interface{}{"url": result.URL})
+			summary.PagesUnchanged++
+			return
+		}
+
+		// Pages marked noindex are not saved
+		if !cfg.IgnoreMetaRobots && crawler.DetectMetaRobots(result).NoIndex {
+			appLogger.Info("Skipping noindex page", map[string]interface{}{"url": result.URL})
+			summary.PagesNoIndex++
+			return
+		}
+
+		// Honor opt-out signals according to the configured policy
+		var optOutSignals []string
+		skipMedia := false
+		if optOut := crawler.DetectOptOut(result); optOut.Any() && cfg.OptOutPolicy != "ignore" {
+			if cfg.OptOutPolicy == "skip" {
+				if len(optOut.Page) > 0 {
+					appLogger.Info("Skipping page that opts out of archiving", map[string]interface{}{"url": result.URL, "signals": optOut.Page})
+					summary.PagesOptedOut++
+					return
+				}
+				appLogger.Info("Skipping media of page that opts out of image AI use", map[string]interface{}{"url": result.URL, "signals": optOut.Media})
+				skipMedia = true
+			} else {
+				appLogger.Warn("Archiving page that opts out of archiving or AI use", map[string]interface{}{"url": result.URL, "signals": optOut.Signals()})
+				optOutSignals = optOut.Signals()
+			}
+		}
+
+		// With use_canonical, files are saved under the path of the canonical
+		// URL the page declares
+		canonicalURL := result.CanonicalURL()
+		pathURL := result.URL
+		if cfg.UseCanonical && canonicalURL != "" {
+			pathURL = canonicalURL
+		}
+
+		page := report.Page{
+			URL:        result.URL,
+			Title:      pageTitle(result.Metadata),
+			StatusCode: result.StatusCode,
+			Depth:      result.Depth,
+		}
+
+		appLogger.Detail("Processing result", map[string]interface{}{"url": result.URL})
+
+		// Save markdown if available, keeping it for post-save hooks
+		var savedMarkdown string
+		if result.Markdown.RawMarkdown != "" {
+			markdown := result.Markdown.RawMarkdown
+			if cfg.FrontMatter {
+				var err error
+				markdown, err = storage.WithFrontMatter(markdown, newFrontMatter(result, crawledAt))
+				if err != nil {
+					appLogger.Warn("Failed to add front matter", map[string]interface{}{"error": err, "url": result.URL})
+					markdown = result.Markdown.RawMarkdown
+				}
+			}
+
+			if !pipeline.Empty() {
+				transformed, err := pipeline.Apply(markdown, transform.Page{URL: result.URL, Title: pageTitle(result.Metadata)})
+				if err != nil {
+					appLogger.Warn("Failed to transform page", map[string]interface{}{"error": err, "url": result.URL})
+				} else {
+					markdown = transformed
+				}
+			}
+
+			// Pre-save hooks may transform the markdown or reject the page
+			// before anything of it is saved
+			if len(pageHooks) > 0 {
+				hookPage := &hooks.Page{
+					URL:        result.URL,
+					Title:      page.Title,
+					StatusCode: result.StatusCode,
+					Depth:      result.Depth,
+					Markdown:   markdown,
+				}
+				err := pageHooks.PreSave(ctx, hookPage)
+				switch {
+				case stderrors.Is(err, hooks.ErrReject):
+					appLogger.Info("Skipping page rejected by hook", map[string]interface{}{"url": result.URL})
+					summary.PagesRejected++
+					return
+				case err != nil:
+					appLogger.Warn("Failed to run pre-save hook", map[string]interface{}{"error": err, "url": result.URL})
+				default:
+					markdown = hookPage.Markdown
+				}
+			}
+
+			_, writeSpan := tracing.Start(ctx, "storage.write", attribute.String("storage.kind", "markdown"))
+			markdownPath, err := store.SaveMarkdown(markdown, pathURL)
+			tracing.End(writeSpan, err)
+			if err == nil {
+				markdownPath.URL = result.URL
+				markdownPath.CanonicalURL = canonicalURL
+				markdownPath.OptOut = optOutSignals
+				markdownPath.Tags = pageTags(result.Metadata)
+				markdownPath.ETag = result.Header().Get("ETag")
+				markdownPath.LastModified = result.Header().Get("Last-Modified")
+				markdownPath.Links = result.Links
+				if result.Redirected() {
+					markdownPath.FinalURL = result.FinalURL()
+				}
+			}
+			if err != nil {
+				pageError(errors.StorageError, "Failed to save markdown", err, result.URL)
+			} else {
+				appLogger.Detail("Saved markdown", map[string]interface{}{"path": markdownPath.Path, "url": result.URL})
+				summary.PagesSaved++
+				summary.BytesWritten += markdownPath.Size
+				crawlBudget.AddPage(markdownPath.Size)
+				page.Path = markdownPath.Path
+				page.Size = markdownPath.Size
+				savedMarkdown = markdown
+				if err := pages.RecordPage(markdownPath, result.StatusCode, crawledAt); err != nil {
+					appLogger.Warn("Failed to record page in manifest", map[string]interface{}{"error": err, "url": result.URL})
+				}
+			}
+		}
+
+		// Save raw and cleaned HTML if requested
+		if cfg.SaveHTML && result.HTML != "" {
+			htmlPath, err := store.SaveHTML(result.HTML, pathURL, false)
+			if err != nil {
+				pageError(errors.StorageError, "Failed to save HTML", err, result.URL)
+			} else {
+				appLogger.Detail("Saved HTML", map[string]interface{}{"path": htmlPath.Path, "url": result.URL})
+			}
+		}
+		if cfg.SaveCleanedHTML && result.CleanedHTML != "" {
+			htmlPath, err := store.SaveHTML(result.CleanedHTML, pathURL, true)
+			if err != nil {
+				pageError(errors.StorageError, "Failed to save cleaned HTML", err, result.URL)
+			} else {
+				appLogger.Detail("Saved cleaned HTML", map[string]interface{}{"path": htmlPath.Path, "url": result.URL})
+			}
+		}
+
+		// Append the page to the JSONL export if requested
+		if cfg.Export == "jsonl" {
+			record := &storage.PageRecord{
+				URL:       result.URL,
+				Title:     pageTitle(result.Metadata),
+				Markdown:  result.Markdown.RawMarkdown,
+				Metadata:  result.Metadata,
+				CrawledAt: crawledAt,
+			}
+			if err := store.AppendPageRecord(record); err != nil {
+				pageError(errors.StorageError, "Failed to export page", err, result.URL)
+			}
+		}
+
+		// Record the page in the WARC file if requested
+		if cfg.WARC && result.HTML != "" {
+			if err := store.WriteWARCPage(result.URL, result.StatusCode, result.Header(), result.HTML); err != nil {
+				pageError(errors.StorageError, "Failed to write WARC record", err, result.URL)
+			}
+		}
+
+		// Save the full crawl result as a JSON sidecar if requested
+		if cfg.SaveJSON && len(result.Raw) > 0 {
+			jsonPath, err := store.SaveResultJSON(result.Raw, pathURL)
+			if err != nil {
+				pageError(errors.StorageError, "Failed to save crawl result", err, result.URL)
+			} else {
+				appLogger.Detail("Saved crawl result", map[string]interface{}{"path": jsonPath.Path, "url": result.URL})
+			}
+		}
+
+		// Save structured extraction result if available
+		if result.ExtractedContent != "" {
+			extractionPath, err := store.SaveExtraction(result.ExtractedContent, pathURL)
+			if err != nil {
+				pageError(errors.StorageError, "Failed to save extraction result", err, result.URL)
+			} else {
+				appLogger.Detail("Saved extraction result", map[string]interface{}{"path": extractionPath.Path, "url": result.URL})
+			}
+		}
+
+		// Save media files if available
+		if skipMedia {
+			summary.MediaSkipped += result.Media.Count()
+		} else if result.Media.Count() > 0 {
+			// Create a response wrapper for this specific result
+			mediaStartResp := c.CreateSingleResultResponse(result)
+
+			mediaProgress := progressManager.CreateReporter("media", fmt.Sprintf("Downloading media for %s", result.URL), result.Media.Count())
+			defer mediaProgress.Complete()
+
+			mediaFiles, err := c.DownloadAndSaveMediaFromStartResponse(ctx, mediaStartResp, mediaProgress)
+			if err != nil {
+				pageError(errors.NetworkError, "Failed to save media files", err, result.URL)
+			} else {
+				appLogger.Detail("Saved media files", map[string]interface{}{"count": len(mediaFiles), "url": result.URL})
+			}
+			page.Media = len(mediaFiles)
+			summary.MediaSaved += len(mediaFiles)
+			summary.MediaSkipped += result.Media.Count() - len(mediaFiles)
+			for _, file := range mediaFiles {
+				summary.BytesWritten += file.Size
+			}
+		}
+
+		// Post-save hooks are told about pages whose markdown was saved
+		if len(pageHooks) > 0 && page.Path != "" {
+			hookPage := &hooks.Page{
+				URL:        result.URL,
+				Title:      page.Title,
+				StatusCode: result.StatusCode,
+				Depth:      result.Depth,
+				Markdown:   savedMarkdown,
+				Path:       page.Path,
+			}
+			if err := pageHooks.PostSave(ctx, hookPage); err != nil {
+				appLogger.Warn("Failed to run post-save hook", map[string]interface{}{"error": err, "url": result.URL})
+			}
+		}
+
+		summary.Pages = append(summary.Pages, page)
+	}
+
+	// With shard_storage, the sections of a batch are saved in parallel, each
+	// recording its pages into a manifest segment merged after the crawl
+	segments := make(map[string]*storage.ManifestSegment)
+	saveSections := func(results []crawler.PageResult, crawledAt time.Time) {
+		sections := make(map[string][]crawler.PageResult)
+		var order []string
+		for _, result := range results {
+			section := storage.PageSection(result.URL)
+			if _, ok := sections[section]; !ok {
+				order = append(order, section)
+				if segments[section] == nil {
+					segments[section] = store.NewManifestSegment()
+				}
+			}
+			sections[section] = append(sections[section], result)
+		}
+
+		partials := make([]*report.Summary, len(order))
+		slots := make(chan struct{}, cfg.MaxConcurrent)
+		var wg sync.WaitGroup
+		for i, section := range order {
+			partials[i] = &report.Summary{}
+			wg.Add(1)
+			go func(results []crawler.PageResult, partial *report.Summary, segment *storage.ManifestSegment) {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+				for _, result := range results {
+					written := partial.BytesWritten
+					saveResult(result, crawledAt, partial, segment)
+					pageProcessed(result.URL, partial.BytesWritten-written)
+				}
+			}(sections[section], partials[i], segments[section])
+		}
+		wg.Wait()
+		for _, partial := range partials {
+			summary.Merge(partial)
+		}
+	}
+
+	// Pages are saved with their media batch by batch while the crawl goes on.
+	// When saving falls behind by more than media_backlog batches, handing over
+	// the next batch blocks and the crawl pauses until it catches up, so memory
+	// stays flat and an interrupted run leaves complete pages with their assets.
+	batches := make(chan []crawler.PageResult, cfg.MediaBacklog)
+	saved := make(chan struct{})
+	go func() {
+		defer close(saved)
+		notified := 0
+		for results := range batches {
+			crawledAt := time.Now().UTC()
+			if cfg.ShardStorage {
+				saveSections(results, crawledAt)
+			} else {
+				for _, result := range results {
+					written := summary.BytesWritten
+					saveResult(result, crawledAt, summary, store)
+					pageProcessed(result.URL, summary.BytesWritten-written)
+				}
+			}
+			c.ReleaseResults(results)
+
+			// Notify webhooks of the progress once a batch crosses a
+			// multiple of webhook_every pages
+			if cfg.WebhookEvery > 0 {
+				processed, _ := crawlProgress.GetProgress()
+				if processed/cfg.WebhookEvery > notified/cfg.WebhookEvery {
+					notify(webhook.EventProgress, processed, nil)
+					notified = processed
+				}
+			}
+		}
+	}()
+	if opts.dashboard != nil {
+		c.SetFrontierHandler(opts.dashboard.SetFrontier)
+	}
+
+	// Pages that could not be crawled and media that could not be downloaded
+	// or saved are collected for the error report, the crawler logging them.
+	// The crawler reports pages crawl4ai failed to crawl as API errors.
+	var (
+		crawlErrorsMutex sync.Mutex
+		crawlErrors      report.Summary
+	)
+	c.SetErrorHandler(func(url string, err *errors.CrawlrError) {
+		writeURLError(err, url)
+		pageFailed(url, err.Message)
+		if err.Type == errors.APIError {
+			policy.pageFailed(url, err, false)
+		} else {
+			policy.fileFailed(url, err)
+		}
+		crawlErrorsMutex.Lock()
+		defer crawlErrorsMutex.Unlock()
+		crawlErrors.AddError(url, err.Type, err.Message, err.Err)
+	})
+	// writeErrorReport adds the failures reported by the crawler to the
+	// summary and writes the errors grouped by type and host to the library
+	writeErrorReport := func() {
+		crawlErrorsMutex.Lock()
+		summary.Errors = append(summary.Errors, crawlErrors.Errors...)
+		crawlErrors.Errors = nil
+		crawlErrorsMutex.Unlock()
+		if err := report.WriteErrors(filepath.Join(store.GetLibraryPath(), report.ErrorsFile), summary); err != nil {
+			appLogger.Error("Failed to write error report", map[string]interface{}{"error": err})
+		}
+	}
+	c.SetBatchHandler(func(ctx context.Context, results []crawler.PageResult) {
+		select {
+		case batches <- results:
+		default:
+			appLogger.Info("Saving pages and media is behind the crawl, pausing batch submission", map[string]interface{}{
+				"backlog": cfg.MediaBacklog,
+			})
+			batches <- results
+		}
+	})
+
+	// Use the recursive crawling method for true multi-level crawling with configured batch size
+	startResp, err := c.StartBatchRecursiveCrawling(ctx, cfg.URL, nil, cfg.MaxDepth, cfg.MaxURLs, cfg.BatchSize)
+	close(batches)
+	<-saved
+	// The crawl may stop short of max_urls; its total is the pages processed
+	processed, _ := crawlProgress.GetProgress()
+	crawlProgress.SetTotal(processed)
+	for _, segment := range segments {
+		if err := store.MergeManifestSegment(segment); err != nil {
+			appLogger.Warn("Failed to record pages in manifest", map[string]interface{}{"error": err})
+		}
+	}
+	if err != nil {
+		return errors.Wrap(err, errors.CrawlerError, "failed to start crawl")
+	}
+
+	// A cancelled crawl keeps the pages crawled so far; the library and
+	// reports are still written, and the run then fails
+	if startResp.Cancelled {
+		summary.Cancelled = true
+		message := "Crawl cancelled, keeping the pages crawled so far"
+		if policy.aborted() != nil {
+			summary.FailedFast = true
+			message = "Crawl aborted on the first storage or API error, keeping the pages crawled so far"
+		}
+		appLogger.Warn(message, map[string]interface{}{
+			"pages":      len(startResp.Results),
+			"checkpoint": storage.CheckpointFile,
+		})
+	}
+
+	if startResp.DurationExceeded {
+		summary.DurationLimit = true
+	}
+	summary.Circuits = startResp.Circuits
+
+	// Check if the crawl was successful
+	if !startResp.Success {
+		writeErrorReport()
+		if err := policy.aborted(); err != nil {
+			return err
+		}
+		if startResp.Cancelled {
+			return errors.Wrap(ctx.Err(), errors.CrawlerError, "crawl cancelled")
+		}
+		return errors.New(errors.CrawlerError, "crawl failed")
+	}
+
+	if len(startResp.Results) == 0 {
+		writeErrorReport()
+		return errors.New(errors.CrawlerError, "no results returned from crawl")
+	}
+
+	// Update progress to show discovered URLs
+	crawlProgress.SetTotal(len(startResp.Results))
+	summary.PagesCrawled = len(startResp.Results)
+	summary.ServerTime = time.Duration(startResp.ServerProcessingTimeS * float64(time.Second))
+	summary.StatusCodes = make(map[int]int)
+	for _, result := range startResp.Results {
+		if result.StatusCode != 0 {
+			summary.StatusCodes[result.StatusCode]++
+		}
+	}
+
+	// Report the trade-off made between pages and media to stay within budget
+	if crawlBudget.Enabled() {
+		summary.Budget = crawlBudget.Report()
+		appLogger.Info("Crawl budget trade-off", map[string]interface{}{
+			"policy":       summary.Budget.Policy,
+			"pageBytes":    summary.Budget.PageBytes,
+			"mediaBytes":   summary.Budget.MediaBytes,
+			"mediaSkipped": summary.Budget.MediaSkipped,
+			"exhausted":    summary.Budget.Exhausted,
+		})
+	}
+
+	// Prune pages of earlier crawls that are gone from the site
+	if cfg.Sync {
+		pruned, err := pruneRemovedPages(cfg, store, startResp, appLogger)
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to prune removed pages")
+		}
+		summary.PagesRemoved = pruned
+	}
+
+	// Point links between crawled pages at the local markdown files
+	if cfg.RewriteLinks {
+		changed, err := store.RewriteInternalLinks()
+		if err != nil {
+			appLogger.Error("Failed to rewrite internal links", map[string]interface{}{"error": err})
+		} else {
+			appLogger.Info("Rewrote internal links", map[string]interface{}{"files": changed})
+		}
+	}
+
+	// Report the pages of the sitemap never reached by following links, and
+	// the crawled pages missing from it
+	if cfg.SitemapReport && !startResp.Cancelled {
+		if err := writeSitemapCoverage(ctx, c, store, startResp, summary, appLogger); err != nil {
+			appLogger.Error("Failed to compare the crawl with the sitemap", map[string]interface{}{"error": err})
+		}
+	}
+
+	// Write the end-of-run summary to the library and stdout, below the
+	// progress bar of the crawl or once the dashboard is closed
+	crawlProgress.Complete()
+	if opts.dashboard != nil {
+		opts.dashboard.Stop()
+	}
+	writeErrorReport()
+	summary.ErrorRate = policy.errorRate()
+	summary.Finish(time.Now())
+	if err := report.WriteJSON(filepath.Join(store.GetLibraryPath(), report.JSONFile), summary); err != nil {
+		appLogger.Error("Failed to write summary", map[string]interface{}{"error": err})
+	}
+	printSummary := report.WriteText
+	if quiet {
+		printSummary = report.WriteJSONLine
+	}
+	if err := printSummary(os.Stdout, summary); err != nil {
+		appLogger.Error("Failed to print summary", map[string]interface{}{"error": err})
+	}
+
+	// Render the end-of-run report if a template is configured
+	if cfg.ReportTemplate != "" {
+		reportPath := cfg.ReportFile
+		if reportPath == "" {
+			reportPath = filepath.Join(store.GetLibraryPath(), report.OutputName(cfg.ReportTemplate))
+		}
+		if err := report.WriteFile(reportPath, cfg.ReportTemplate, summary); err != nil {
+			return errors.Wrap(err, errors.ConfigurationError, "failed to generate report")
+		}
+		appLogger.Info("Wrote report", map[string]interface{}{"path": reportPath})
+	}
+
+	// Snapshot the library into the deduplicated store, closing the storage
+	// first so the manifest is complete
+	if cfg.Snapshot {
+		if err := store.Close(); err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to close storage")
+		}
+		if err := snapshotLibrary(cfg, store.GetLibraryPath()); err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to snapshot library")
+		}
+	}
+
+	// Pack the library into a single archive, closing the storage first so the
+	// manifest is complete
+	if cfg.Archive != "" {
+		if err := store.Close(); err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to close storage")
+		}
+		archivePath, err := store.WriteArchive(cfg.Archive)
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to archive library")
+		}
+		appLogger.Info("Wrote archive", map[string]interface{}{"path": archivePath})
+
+		if cfg.ArchiveOnly {
+			if err := store.RemoveLibrary(); err != nil {
+				return errors.Wrap(err, errors.StorageError, "failed to remove archived library")
+			}
+		}
+	}
+
+	if err := policy.aborted(); err != nil {
+		return err
+	}
+	if startResp.Cancelled {
+		return errors.Wrap(ctx.Err(), errors.CrawlerError, "crawl cancelled")
+	}
+
+	// Fail the run if too many pages failed or the crawl covered less of the
+	// site than expected, once the library and reports are written
+	if err := policy.checkErrorRate(); err != nil {
+		appLogger.Error("Error rate exceeded", map[string]interface{}{
+			"errorRate":    summary.ErrorRate,
+			"maxErrorRate": cfg.MaxErrorRate,
+		})
+		return err
+	}
+	return checkCoverage(cfg, startResp.Results)
+}
+
+// pageRecorder records saved pages in the manifest, directly or through a
+// manifest segment
+type pageRecorder interface {
+	RecordPage(info *storage.FileInfo, statusCode int, crawledAt time.Time) error
+}
+
+// pruneRemovedPages removes the pages of the library that no longer exist on
+// the site: pages answering 404 or 410, and, when the crawl was complete,
+// pages it did not reach. It returns the number of pages removed.
+func pruneRemovedPages(cfg *config.Config, store *storage.Storage, startResp *crawler.StartCrawlResponse, appLogger *logger.Logger) (int, error) {
+	reached := make(map[string]bool, len(startResp.Results))
+	gone := make(map[string]bool)
+	for _, result := range startResp.Results {
+		reached[result.URL] = true
+		if result.StatusCode == http.StatusNotFound || result.StatusCode == http.StatusGone {
+			gone[result.URL] = true
+		}
+	}
+
+	// Pages missing from a partial crawl may still exist, and with shared
+	// crawl state other crawlers fetch part of the site
+	pruneUnreached := startResp.Complete && cfg.StateBackend == "memory"
+	if !pruneUnreached {
+		appLogger.Warn("Crawl did not cover the whole site; only pruning pages that are gone", map[string]interface{}{
+			"complete":     startResp.Complete,
+			"stateBackend": cfg.StateBackend,
+		})
+	}
+
+	keep := func(pageURL string) bool {
+		if gone[pageURL] {
+			return false
+		}
+		return reached[pageURL] || !pruneUnreached
+	}
+	pruned, err := store.PrunePages(keep, cfg.SyncRemoved == "move")
+	for _, entry := range pruned {
+		appLogger.Info("Pruned removed page", map[string]interface{}{"url": entry.URL, "path": entry.Path})
+	}
+	return len(pruned), err
+}
+
+// writeSitemapCoverage compares a recursive crawl with the site's sitemap and
+// writes the result to the library
+func writeSitemapCoverage(ctx context.Context, c *crawler.Crawler, store *storage.Storage, startResp *crawler.StartCrawlResponse, summary *report.Summary, appLogger *logger.Logger) error {
+	coverage, err := c.SitemapCoverage(ctx, summary.URL, startResp)
+	if err != nil {
+		return err
+	}
+	summary.SitemapURLs = coverage.SitemapURLs
+	summary.SitemapOrphans = len(coverage.Orphans)
+	summary.NotInSitemap = len(coverage.NotInSitemap)
+
+	data, err := json.MarshalIndent(coverage, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := store.WriteLibraryFile(storage.SitemapCoverageFile, append(data, '\n')); err != nil {
+		return err
+	}
+	appLogger.Info("Compared the crawl with the sitemap", map[string]interface{}{
+		"sitemapURLs":  coverage.SitemapURLs,
+		"orphans":      len(coverage.Orphans),
+		"notInSitemap": len(coverage.NotInSitemap),
+	})
+	return nil
+}
+
+// pageTitle returns the page title reported by crawl4ai, if any
+func pageTitle(metadata map[string]interface{}) string {
+	if title, ok := metadata["title"].(string); ok {
+		return title
+	}
+	return ""
+}
+
+// newFrontMatter builds the front matter of a page from its crawl4ai metadata
+func newFrontMatter(result crawler.PageResult, crawledAt time.Time) *storage.FrontMatter {
+	fm := &storage.FrontMatter{
+		Source:    result.URL,
+		Canonical: result.CanonicalURL(),
+		Title:     pageTitle(result.Metadata),
+		Date:      crawledAt,
+		Depth:     result.Depth,
+		Tags:      pageTags(result.Metadata),
+	}
+	if description, ok := result.Metadata["description"].(string); ok {
+		fm.Description = strings.TrimSpace(description)
+	}
+	return fm
+}
+
+// pageTags returns the comma-separated keywords of a page as tags
+func pageTags(metadata map[string]interface{}) []string {
+	keywords, _ := metadata["keywords"].(string)
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, keyword := range strings.Split(keywords, ",") {
+		tag := strings.TrimSpace(keyword)
+		if tag != "" && !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"crawlr/internal/errors"
+	"crawlr/internal/progress"
+	"crawlr/internal/queue"
+	"crawlr/internal/server"
+
+	"github.com/spf13/cobra"
+)
+
+// serveFlagMappings maps serve flags to their viper configuration keys
+var serveFlagMappings = map[string]string{
+	"addr":       "serve_addr",
+	"graphql":    "serve_graphql",
+	"public":     "serve_public",
+	"rate-limit": "serve_rate_limit",
+	"jobs":       "serve_jobs",
+	"max-jobs":   "serve_max_jobs",
+}
+
+// serveShutdownTimeout bounds the time given to in-flight requests on shutdown
+const serveShutdownTimeout = 10 * time.Second
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve crawled libraries over HTTP and run crawl jobs",
+	Long: `Serve the libraries stored in the output folder over HTTP. With --jobs,
+the server also crawls the jobs submitted to it into the output folder, so
+that other services can drive crawlr.
+
+Endpoints:
+  GET  /libraries                        list the libraries
+  GET  /libraries/{name}/search?q=...    full-text search (limit=N, default 20)
+  POST /libraries/{name}/reindex         rebuild the search index of a library
+  GET  /libraries/{name}/pages/{path}    render a stored page to HTML
+  GET  /libraries/{name}/media/{path}    downloaded media files
+  GET|POST /graphql                      GraphQL over library manifests (--graphql)
+  GET  /robots.txt                       robots rules for public mirrors (--public)
+  POST /jobs                             submit a crawl job (--jobs)
+  GET  /jobs                             list the jobs with their state and progress
+  GET  /jobs/{id}                        state and progress of a job
+  POST /jobs/{id}/cancel                 cancel a job, keeping the pages crawled so far
+  GET  /jobs/{id}/manifest               manifest of the library of a job
+
+The search index of a library is built on its first query. Search results are
+JSON, with snippets HTML-escaped and matches wrapped in <mark> tags.
+
+With --public, pages carry a noindex robots directive and a canonical link back
+to their origin so that the mirror does not compete with the source site in
+search engines. Combine it with --rate-limit when exposing the mirror.
+
+A job is a JSON object with the url and library to crawl and, optionally,
+max_depth, max_urls, batch_size and exclude_patterns overriding the
+configuration. At most --max-jobs jobs run at a time, the others waiting
+their turn. Jobs are kept in memory: they are forgotten when the server
+stops, and stopping it cancels the running ones.`,
+	Example: `crawlr serve --output ./libraries
+  crawlr serve --output ./libraries --addr :9000
+  crawlr serve --output ./libraries --addr :80 --public --rate-limit 5
+  curl 'http://127.0.0.1:8080/libraries/example-docs/search?q=install'
+  crawlr serve --output ./libraries --jobs --max-jobs 2
+  curl -d '{"url": "https://example.com", "library": "example"}' http://127.0.0.1:8080/jobs`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serveCfg, _, err := loadConfig(cmd, serveFlagMappings)
+		if err != nil {
+			return err
+		}
+		if serveCfg.Output == "" {
+			return errors.New(errors.ValidationError, "output is required (--output or output)")
+		}
+		if serveCfg.ServeRateLimit < 0 {
+			return errors.New(errors.ValidationError, "rate limit must not be negative")
+		}
+		if serveCfg.ServeJobs && serveCfg.ServePublic {
+			return errors.New(errors.ValidationError, "jobs cannot be submitted to a public mirror")
+		}
+		if serveCfg.ServeMaxJobs < 1 {
+			return errors.New(errors.ValidationError, "max jobs must be positive")
+		}
+
+		appLogger, err = newLogger(serveCfg)
+		if err != nil {
+			return err
+		}
+		defer appLogger.Close()
+
+		libraryServer, err := server.NewServer(serveCfg, appLogger)
+		if err != nil {
+			return errors.Wrap(err, errors.ConfigurationError, "failed to initialize server")
+		}
+		if serveCfg.ServeJobs {
+			flushTraces, err := setupTracing(serveCfg, appLogger)
+			if err != nil {
+				return err
+			}
+			defer flushTraces()
+
+			libraryServer.SetJobRunner(func(ctx context.Context, job *queue.Job, progress *progress.ProgressManager) error {
+				jobCfg := jobConfig(serveCfg, job)
+				if err := jobCfg.Validate(); err != nil {
+					return errors.Wrap(err, errors.ValidationError, "invalid job")
+				}
+				return runCrawl(ctx, &jobCfg, appLogger, crawlOptions{progress: progress})
+			})
+		}
+		defer libraryServer.Close()
+
+		srv := &http.Server{
+			Addr:              serveCfg.ServeAddr,
+			Handler:           libraryServer.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		serveErr := make(chan error, 1)
+		go func() {
+			serveErr <- srv.ListenAndServe()
+		}()
+
+		appLogger.Info("Serving libraries", map[string]interface{}{
+			"addr":   serveCfg.ServeAddr,
+			"output": serveCfg.Output,
+			"jobs":   serveCfg.ServeJobs,
+		})
+
+		select {
+		case err := <-serveErr:
+			if !stderrors.Is(err, http.ErrServerClosed) {
+				return errors.Wrap(err, errors.NetworkError, "failed to serve")
+			}
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				return errors.Wrap(err, errors.NetworkError, "failed to shut down server")
+			}
+		}
+
+		appLogger.Info("Server stopped")
+		return nil
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("addr", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().Bool("graphql", false, "Expose a GraphQL endpoint over the library manifests at /graphql")
+	serveCmd.Flags().Bool("public", false, "Serve as a public mirror: robots.txt, noindex and canonical links to the origin")
+	serveCmd.Flags().Float64("rate-limit", 0, "Maximum requests per second per client (0 for unlimited)")
+	serveCmd.Flags().Bool("jobs", false, "Accept crawl jobs at /jobs, crawling them into the output folder")
+	serveCmd.Flags().Int("max-jobs", 1, "Maximum number of jobs crawled at a time")
+
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+
+	"crawlr/internal/config"
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+	"crawlr/internal/snapshot"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotPruneKeep   int
+	snapshotPruneDryRun bool
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Manage the deduplicated snapshots of a library",
+	Long: `Manage the snapshots taken by crawls run with --snapshot. Files are split into
+content-defined chunks stored once, compressed, under their SHA-256 in
+<library>.snapshots/chunks (or --snapshot-dir), and each snapshot lists the
+chunks of its files. Snapshots of a mostly unchanged site share their chunks,
+so keeping many of them costs little more than the pages that changed.`,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the snapshots of a library",
+	Example: `crawlr snapshot list -l mylib -o ./libraries`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openSnapshotStore(cmd)
+		if err != nil {
+			return err
+		}
+		snapshots, err := store.List()
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to list snapshots")
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCREATED\tFILES\tSIZE")
+		for _, snap := range snapshots {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", snap.ID, snap.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+				len(snap.Files), formatBytes(uint64(snap.Size())))
+		}
+		return w.Flush()
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <id> <directory>",
+	Short: "Restore a snapshot of a library into a directory",
+	Long: `Restore the files of a snapshot into a new or empty directory. The content of
+every file is checked against the hash recorded by the snapshot.`,
+	Example: `crawlr snapshot restore -l mylib -o ./libraries 20250101T120000Z ./mylib-20250101`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openSnapshotStore(cmd)
+		if err != nil {
+			return err
+		}
+		if err := store.Restore(args[0], args[1]); err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to restore snapshot").WithContext("snapshot", args[0])
+		}
+		fmt.Fprint(cmd.OutOrStdout(), i18n.T("snapshot.restored", args[0], args[1]))
+		return nil
+	},
+}
+
+var snapshotPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old snapshots and the chunks only they used",
+	Example: `crawlr snapshot prune -l mylib -o ./libraries --keep 30 --dry-run
+  crawlr snapshot prune -l mylib -o ./libraries --keep 30`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if snapshotPruneKeep < 1 {
+			return errors.New(errors.ValidationError, "--keep must be at least 1")
+		}
+		store, err := openSnapshotStore(cmd)
+		if err != nil {
+			return err
+		}
+		result, err := store.Prune(snapshotPruneKeep, snapshotPruneDryRun)
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to prune snapshots")
+		}
+
+		out := cmd.OutOrStdout()
+		for _, id := range result.Removed {
+			fmt.Fprintln(out, id)
+		}
+		key := "snapshot.pruned"
+		if snapshotPruneDryRun {
+			key = "snapshot.would_prune"
+		}
+		fmt.Fprint(out, i18n.T(key, len(result.Removed), result.Chunks, formatBytes(uint64(result.Freed))))
+		return nil
+	},
+}
+
+// openSnapshotStore returns the snapshot store of the configured library
+func openSnapshotStore(cmd *cobra.Command) (*snapshot.Store, error) {
+	snapshotCfg, _, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if snapshotCfg.Library == "" {
+		return nil, errors.New(errors.ConfigurationError, "library is required")
+	}
+	return snapshot.Open(snapshotDir(snapshotCfg, filepath.Join(snapshotCfg.Output, snapshotCfg.Library))), nil
+}
+
+// snapshotDir returns the snapshot store directory of a library
+func snapshotDir(cfg *config.Config, libraryPath string) string {
+	if cfg.SnapshotDir != "" {
+		return cfg.SnapshotDir
+	}
+	return snapshot.DefaultDir(libraryPath)
+}
+
+// snapshotLibrary takes a snapshot of the library after a crawl, then prunes
+// the snapshots beyond snapshot_keep
+func snapshotLibrary(cfg *config.Config, libraryPath string) error {
+	store := snapshot.Open(snapshotDir(cfg, libraryPath))
+	result, err := store.Create(libraryPath)
+	if err != nil {
+		return err
+	}
+	appLogger.Info("Saved snapshot", map[string]interface{}{
+		"id":        result.Snapshot.ID,
+		"files":     len(result.Snapshot.Files),
+		"size":      result.Snapshot.Size(),
+		"chunks":    result.Chunks,
+		"newChunks": result.NewChunks,
+		"stored":    result.Stored,
+	})
+
+	if cfg.SnapshotKeep > 0 {
+		pruned, err := store.Prune(cfg.SnapshotKeep, false)
+		if err != nil {
+			return err
+		}
+		if len(pruned.Removed) > 0 {
+			appLogger.Info("Pruned old snapshots", map[string]interface{}{
+				"snapshots": len(pruned.Removed),
+				"chunks":    pruned.Chunks,
+				"freed":     pruned.Freed,
+			})
+		}
+	}
+	return nil
+}
+
+func init() {
+	snapshotPruneCmd.Flags().IntVar(&snapshotPruneKeep, "keep", 0, "Number of most recent snapshots to keep")
+	snapshotPruneCmd.Flags().BoolVar(&snapshotPruneDryRun, "dry-run", false, "List the snapshots that would be removed without removing them")
+	snapshotPruneCmd.MarkFlagRequired("keep")
+
+	snapshotCmd.AddCommand(snapshotListCmd, snapshotRestoreCmd, snapshotPruneCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"crawlr/internal/crawler"
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+	"crawlr/internal/report"
+	"crawlr/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of a library",
+	Long: `Show what a library holds according to its manifest, how its last run went
+according to report.json, and whether a cancelled crawl is waiting to be
+resumed with crawlr resume.`,
+	Example: `crawlr status -l mylib -o ./libraries`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		statusCfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if statusCfg.Library == "" {
+			return errors.New(errors.ConfigurationError, "library is required")
+		}
+
+		libraryPath := filepath.Join(statusCfg.Output, statusCfg.Library)
+		if _, err := os.Stat(libraryPath); err != nil {
+			return errors.Wrap(err, errors.StorageError, "library not found").WithContext("library", libraryPath)
+		}
+		manifest, err := storage.LoadManifest(libraryPath)
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to load manifest")
+		}
+
+		out := cmd.OutOrStdout()
+		fmt.Fprint(out, i18n.T("status.library", statusCfg.Library, libraryPath))
+		if len(manifest.Pages) == 0 && len(manifest.Media) == 0 {
+			fmt.Fprint(out, i18n.T("status.empty"))
+		} else {
+			fmt.Fprint(out, i18n.T("status.content", len(manifest.Pages), len(manifest.Media), manifest.UpdatedAt.Local().Format(time.DateTime)))
+		}
+
+		// The report of the last run is optional; libraries of older
+		// versions have none
+		if data, err := os.ReadFile(filepath.Join(libraryPath, report.JSONFile)); err == nil {
+			var summary report.Summary
+			if err := json.Unmarshal(data, &summary); err == nil {
+				fmt.Fprint(out, i18n.T("status.last_run", summary.FinishedAt.Local().Format(time.DateTime), summary.PagesSaved, summary.PagesFailed))
+			}
+		}
+
+		checkpoint, err := crawler.LoadCheckpoint(libraryPath)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, errors.StorageError, "failed to load checkpoint")
+		}
+		if checkpoint != nil {
+			fmt.Fprint(out, i18n.T("status.cancelled", checkpoint.CancelledAt.Local().Format(time.DateTime), len(checkpoint.Pending)))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
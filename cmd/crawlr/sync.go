@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+	"crawlr/internal/mirror"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncDelete bool
+	syncDryRun bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync <target>",
+	Short: "Push a library to a remote mirror, transferring only changed files",
+	Long: `Push a library to a mirror: another directory, an S3 bucket or an SFTP server.
+Only files that are new or changed since the last sync to the target are
+uploaded, by comparing their hashes (taken from the manifest where possible)
+with the state recorded on the target in .crawlr-sync.json. The manifest is
+uploaded last, so a mirror never lists files it does not have yet.
+
+Targets:
+  /path/to/dir, file:///path/to/dir   a local directory
+  s3://bucket/prefix                  AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+                                      AWS_SESSION_TOKEN, AWS_REGION and, for
+                                      S3-compatible stores, AWS_ENDPOINT_URL
+  sftp://user@host[:port]/path        ~/.ssh/known_hosts, the SSH agent or
+                                      ~/.ssh/id_* keys (/~/path: home-relative)`,
+	Example: `crawlr sync -l mylib -o ./libraries /mnt/mirror/mylib
+  crawlr sync -l mylib -o ./libraries s3://docs-archive/mylib --dry-run
+  crawlr sync -l mylib -o ./libraries sftp://deploy@docs.example.com/srv/mylib --delete`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		syncCfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if syncCfg.Library == "" {
+			return errors.New(errors.ConfigurationError, "library is required")
+		}
+
+		ctx := cmd.Context()
+		target, err := mirror.Open(ctx, args[0])
+		if err != nil {
+			return errors.Wrap(err, errors.ConfigurationError, "failed to open sync target")
+		}
+		defer target.Close()
+
+		libraryPath := filepath.Join(syncCfg.Output, syncCfg.Library)
+		result, err := mirror.Sync(ctx, libraryPath, target, mirror.Options{Delete: syncDelete, DryRun: syncDryRun})
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to sync library").WithContext("target", args[0])
+		}
+
+		out := cmd.OutOrStdout()
+		for _, path := range result.Uploaded {
+			fmt.Fprintln(out, "+", path)
+		}
+		for _, path := range result.Deleted {
+			fmt.Fprintln(out, "-", path)
+		}
+		key := "sync.synced"
+		if syncDryRun {
+			key = "sync.dry_run"
+		}
+		fmt.Fprint(out, i18n.T(key, len(result.Uploaded), formatBytes(uint64(result.Transferred)), len(result.Deleted), result.Unchanged))
+		return nil
+	},
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncDelete, "delete", false, "Remove files of the mirror that the library no longer has")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "List the files that would be transferred without changing the mirror")
+
+	rootCmd.AddCommand(syncCmd)
+}
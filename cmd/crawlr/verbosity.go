@@ -0,0 +1,30 @@
+package main
+
+import (
+	"crawlr/internal/config"
+	"crawlr/internal/errors"
+)
+
+// quiet and verbosity are set by -q/--quiet and -v/--verbose. They override
+// the configured log level: quiet logs errors only and prints the summary as
+// a JSON line, -v logs the messages about each page and file, -vv debug
+// messages as well.
+var (
+	quiet     bool
+	verbosity int
+)
+
+// applyVerbosity replaces the log level of cfg with the one of -q or -v
+func applyVerbosity(cfg *config.Config) error {
+	switch {
+	case quiet && verbosity > 0:
+		return errors.New(errors.ConfigurationError, "--quiet and --verbose cannot be used together")
+	case quiet:
+		cfg.LogLevel = "ERROR"
+	case verbosity == 1:
+		cfg.LogLevel = "INFO"
+	case verbosity > 1:
+		cfg.LogLevel = "DEBUG"
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"crawlr/internal/errors"
+	"crawlr/internal/i18n"
+	"crawlr/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the files of a library against its manifest",
+	Long: `Check that every page and media file listed in the manifest of a library is
+present with the size and SHA-256 recorded when it was saved. Files missing
+or modified since are listed, and the command then fails.`,
+	Example:      `crawlr verify -l mylib -o ./libraries`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		verifyCfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if verifyCfg.Library == "" {
+			return errors.New(errors.ConfigurationError, "library is required")
+		}
+
+		libraryPath := filepath.Join(verifyCfg.Output, verifyCfg.Library)
+		result, err := storage.VerifyLibrary(libraryPath)
+		if err != nil {
+			return errors.Wrap(err, errors.StorageError, "failed to verify library")
+		}
+
+		out := cmd.OutOrStdout()
+		for _, path := range result.Missing {
+			fmt.Fprintf(out, "%s\t%s\n", i18n.T("verify.missing"), path)
+		}
+		for _, path := range result.Modified {
+			fmt.Fprintf(out, "%s\t%s\n", i18n.T("verify.modified"), path)
+		}
+		if result.OK() {
+			fmt.Fprint(out, i18n.T("verify.ok", result.Checked))
+			return nil
+		}
+		fmt.Fprint(out, i18n.T("verify.failed", len(result.Missing)+len(result.Modified), result.Checked, len(result.Missing), len(result.Modified)))
+		return errors.New(errors.ValidationError, "library does not match its manifest").WithContext("library", libraryPath)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
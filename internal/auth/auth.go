@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"crawlr/internal/config"
+)
+
+// Scheme applies credentials to an outgoing HTTP request
+type Scheme interface {
+	// Apply adds the credentials to the request
+	Apply(req *http.Request)
+	// Name returns the name of the scheme
+	Name() string
+}
+
+// BasicAuth authenticates requests using HTTP Basic authentication
+type BasicAuth struct {
+	User     string
+	Password string
+}
+
+// Apply implements the Scheme interface
+func (b *BasicAuth) Apply(req *http.Request) {
+	req.SetBasicAuth(b.User, b.Password)
+}
+
+// Name implements the Scheme interface
+func (b *BasicAuth) Name() string {
+	return "basic"
+}
+
+// BearerAuth authenticates requests using a bearer token
+type BearerAuth struct {
+	Token string
+}
+
+// Apply implements the Scheme interface
+func (b *BearerAuth) Apply(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+b.Token)
+}
+
+// Name implements the Scheme interface
+func (b *BearerAuth) Name() string {
+	return "bearer"
+}
+
+// APIKeyAuth authenticates requests by sending a key in a custom header
+type APIKeyAuth struct {
+	Header string
+	Key    string
+}
+
+// Apply implements the Scheme interface
+func (a *APIKeyAuth) Apply(req *http.Request) {
+	req.Header.Set(a.Header, a.Key)
+}
+
+// Name implements the Scheme interface
+func (a *APIKeyAuth) Name() string {
+	return "api_key"
+}
+
+// DefaultAPIKeyHeader is the header used by api_key schemes that don't name one
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// NewScheme creates a Scheme from its configuration
+func NewScheme(cfg config.AuthConfig) (Scheme, error) {
+	switch strings.ToLower(cfg.Type) {
+	case "basic":
+		if cfg.User == "" {
+			return nil, fmt.Errorf("basic auth requires a user")
+		}
+		return &BasicAuth{User: cfg.User, Password: cfg.Password}, nil
+	case "bearer":
+		if cfg.Token == "" {
+			return nil, fmt.Errorf("bearer auth requires a token")
+		}
+		return &BearerAuth{Token: cfg.Token}, nil
+	case "api_key", "apikey":
+		if cfg.Token == "" {
+			return nil, fmt.Errorf("api_key auth requires a token")
+		}
+		header := cfg.Header
+		if header == "" {
+			header = DefaultAPIKeyHeader
+		}
+		return &APIKeyAuth{Header: header, Key: cfg.Token}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth type: %q", cfg.Type)
+	}
+}
+
+// Registry maps domains to the scheme used to authenticate against them
+type Registry struct {
+	schemes map[string]Scheme
+}
+
+// NewRegistry creates a Registry from the auth section of the configuration
+func NewRegistry(cfg map[string]config.AuthConfig) (*Registry, error) {
+	r := &Registry{
+		schemes: make(map[string]Scheme),
+	}
+
+	for domain, authCfg := range cfg {
+		scheme, err := NewScheme(authCfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth configuration for %s: %w", domain, err)
+		}
+		r.Register(domain, scheme)
+	}
+
+	return r, nil
+}
+
+// Register sets the scheme used for a domain, replacing any existing one
+func (r *Registry) Register(domain string, scheme Scheme) {
+	r.schemes[strings.ToLower(strings.TrimSpace(domain))] = scheme
+}
+
+// Lookup returns the scheme for a host. An exact match wins; otherwise the
+// closest parent domain is used so that "example.com" also covers
+// "cdn.example.com".
+func (r *Registry) Lookup(host string) (Scheme, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	host = strings.ToLower(host)
+	for host != "" {
+		if scheme, ok := r.schemes[host]; ok {
+			return scheme, true
+		}
+		idx := strings.Index(host, ".")
+		if idx < 0 {
+			break
+		}
+		host = host[idx+1:]
+	}
+
+	return nil, false
+}
+
+// Apply adds credentials to the request if a scheme is registered for its host
+func (r *Registry) Apply(req *http.Request) bool {
+	scheme, ok := r.Lookup(req.URL.Hostname())
+	if !ok {
+		return false
+	}
+	scheme.Apply(req)
+	return true
+}
+
+// Len returns the number of registered domains
+func (r *Registry) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.schemes)
+}
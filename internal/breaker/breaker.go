@@ -0,0 +1,133 @@
+// Package breaker implements per-host circuit breakers: once a host fails a
+// number of times in a row, requests to it are held back for a cool-down
+// period instead of spending the crawl on requests bound to fail.
+package breaker
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Breaker tracks a circuit per host. A circuit opens after threshold
+// consecutive failures and stays open for the cool-down period; the next
+// request then probes the host, a failure reopening the circuit at once and a
+// success closing it. A nil Breaker never opens. It is safe for concurrent
+// use.
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	circuits  map[string]*circuit
+	now       func() time.Time // the clock, replaced by tests
+}
+
+type circuit struct {
+	failures  int // consecutive failures
+	total     int // failures overall
+	opened    int // times the circuit opened
+	openUntil time.Time
+}
+
+// State describes the circuit of a host, for the end-of-run summary
+type State struct {
+	Host     string `json:"host"`
+	Failures int    `json:"failures"` // failed requests overall
+	Opened   int    `json:"opened"`   // times the circuit opened
+	Open     bool   `json:"open"`     // whether the circuit was open at the end of the crawl
+}
+
+// New returns a breaker opening circuits after threshold consecutive
+// failures for cooldown, or nil if threshold is not positive
+func New(threshold int, cooldown time.Duration) *Breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		circuits:  make(map[string]*circuit),
+		now:       time.Now,
+	}
+}
+
+// SetClock replaces the clock of the breaker, time.Now by default, so that
+// tests control the cool-downs
+func (b *Breaker) SetClock(now func() time.Time) {
+	if b != nil {
+		b.now = now
+	}
+}
+
+// Remaining returns how long the circuit of host stays open, 0 if requests
+// to it are allowed
+func (b *Breaker) Remaining(host string) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[host]
+	if !ok {
+		return 0
+	}
+	return max(c.openUntil.Sub(b.now()), 0)
+}
+
+// Success records a successful request to host, closing its circuit
+func (b *Breaker) Success(host string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.circuits[host]; ok {
+		c.failures = 0
+	}
+}
+
+// Failure records a failed request to host, reporting whether it opened the
+// circuit of the host
+func (b *Breaker) Failure(host string) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[host]
+	if !ok {
+		c = &circuit{}
+		b.circuits[host] = c
+	}
+	c.failures++
+	c.total++
+	now := b.now()
+	if c.failures < b.threshold || now.Before(c.openUntil) {
+		return false
+	}
+	c.opened++
+	c.openUntil = now.Add(b.cooldown)
+	return true
+}
+
+// States returns the circuits of the hosts that failed at least once, sorted
+// by host
+func (b *Breaker) States() []State {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	var states []State
+	for host, c := range b.circuits {
+		states = append(states, State{
+			Host:     host,
+			Failures: c.total,
+			Opened:   c.opened,
+			Open:     now.Before(c.openUntil),
+		})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Host < states[j].Host })
+	return states
+}
@@ -0,0 +1,105 @@
+package breaker
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// clock is a manual clock for breakers under test
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newTestBreaker returns a breaker running on a manual clock
+func newTestBreaker(threshold int, cooldown time.Duration) (*Breaker, *clock) {
+	clk := &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := New(threshold, cooldown)
+	b.SetClock(clk.Now)
+	return b, clk
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	b, clk := newTestBreaker(3, 30*time.Second)
+	const host = "docs.example.com"
+
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		success   bool
+		opened    bool // whether the failure opens the circuit
+		remaining time.Duration
+	}{
+		{name: "first failure", remaining: 0},
+		{name: "second failure", remaining: 0},
+		{name: "threshold reached", opened: true, remaining: 30 * time.Second},
+		{name: "failure while open", advance: 10 * time.Second, remaining: 20 * time.Second},
+		{name: "cool-down over, probe fails", advance: 25 * time.Second, opened: true, remaining: 30 * time.Second},
+		{name: "probe succeeds", advance: 30 * time.Second, success: true, remaining: 0},
+		{name: "failure after closing", remaining: 0},
+		{name: "second failure after closing", remaining: 0},
+		{name: "threshold reached again", opened: true, remaining: 30 * time.Second},
+	}
+	for _, step := range steps {
+		clk.advance(step.advance)
+		if step.success {
+			b.Success(host)
+		} else if opened := b.Failure(host); opened != step.opened {
+			t.Errorf("%s: Failure() = %v, want %v", step.name, opened, step.opened)
+		}
+		if got := b.Remaining(host); got != step.remaining {
+			t.Errorf("%s: Remaining() = %v, want %v", step.name, got, step.remaining)
+		}
+	}
+
+	want := []State{{Host: host, Failures: 8, Opened: 3, Open: true}}
+	if got := b.States(); !slices.Equal(got, want) {
+		t.Errorf("States() = %+v, want %+v", got, want)
+	}
+	clk.advance(30 * time.Second)
+	want[0].Open = false
+	if got := b.States(); !slices.Equal(got, want) {
+		t.Errorf("States() after the cool-down = %+v, want %+v", got, want)
+	}
+}
+
+func TestBreakerHosts(t *testing.T) {
+	b, _ := newTestBreaker(1, time.Minute)
+	b.Failure("b.example.com")
+	b.Failure("a.example.com")
+	b.Success("c.example.com")
+
+	if got := b.Remaining("c.example.com"); got != 0 {
+		t.Errorf("Remaining() of a host that never failed = %v, want 0", got)
+	}
+	want := []State{
+		{Host: "a.example.com", Failures: 1, Opened: 1, Open: true},
+		{Host: "b.example.com", Failures: 1, Opened: 1, Open: true},
+	}
+	if got := b.States(); !slices.Equal(got, want) {
+		t.Errorf("States() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := New(0, time.Minute)
+	if b != nil {
+		t.Fatalf("New(0) = %v, want nil", b)
+	}
+	for range 5 {
+		if b.Failure("docs.example.com") {
+			t.Fatal("Failure() opened the circuit of a nil breaker")
+		}
+	}
+	b.Success("docs.example.com")
+	if got := b.Remaining("docs.example.com"); got != 0 {
+		t.Errorf("Remaining() = %v, want 0", got)
+	}
+	if got := b.States(); got != nil {
+		t.Errorf("States() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,183 @@
+// Package budget balances the bytes written and the time spent by a crawl
+// between fetching more pages and downloading the media of the pages already
+// fetched, under a hard total budget.
+package budget
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Policies deciding how the budget is split between pages and media
+const (
+	// Pages reserves what the remaining pages are expected to cost, and only
+	// spends the rest on media
+	Pages = "pages"
+	// Media downloads the media of every page while the budget lasts, at the
+	// expense of pages the crawl then never reaches
+	Media = "media"
+	// Balanced gives every remaining page an equal share of what is left,
+	// media included
+	Balanced = "balanced"
+)
+
+// Budget tracks the bytes and time spent by a crawl against its limits. It
+// is safe for concurrent use.
+type Budget struct {
+	mu          sync.Mutex
+	policy      string
+	maxBytes    int64
+	maxTime     time.Duration
+	targetPages int
+	started     time.Time
+
+	pages        int
+	pageBytes    int64
+	mediaListed  int // media files of pages whose media were downloaded
+	mediaBytes   int64
+	mediaTime    time.Duration
+	mediaSkipped int // media files left out to stay within the budget
+}
+
+// Report is the trade-off a budget made, for the end-of-run summary
+type Report struct {
+	Policy       string  `json:"policy"`
+	MaxBytes     int64   `json:"max_bytes,omitempty"`
+	MaxTime      float64 `json:"max_time_s,omitempty"`
+	Pages        int     `json:"pages"`
+	PageBytes    int64   `json:"page_bytes"`
+	MediaBytes   int64   `json:"media_bytes"`
+	MediaSkipped int     `json:"media_skipped"`
+	MediaShare   float64 `json:"media_share"` // fraction of the bytes spent on media
+	Exhausted    bool    `json:"exhausted"`
+}
+
+// New returns a budget of maxBytes and maxTime, either of which may be 0 for
+// no limit, for a crawl aiming at targetPages pages
+func New(policy string, maxBytes int64, maxTime time.Duration, targetPages int) *Budget {
+	return &Budget{
+		policy:      policy,
+		maxBytes:    maxBytes,
+		maxTime:     maxTime,
+		targetPages: targetPages,
+		started:     time.Now(),
+	}
+}
+
+// Enabled reports whether the budget has a limit
+func (b *Budget) Enabled() bool {
+	return b != nil && (b.maxBytes > 0 || b.maxTime > 0)
+}
+
+// AddPage counts the bytes written for a saved page
+func (b *Budget) AddPage(bytes int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pages++
+	b.pageBytes += bytes
+}
+
+// AddMedia counts the media of a page: files listed, bytes written and the
+// time spent downloading them
+func (b *Budget) AddMedia(files int, bytes int64, elapsed time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mediaListed += files
+	b.mediaBytes += bytes
+	b.mediaTime += elapsed
+}
+
+// AllowMedia returns how many of the media files of a page, files in all, fit
+// in the budget, counting the others as skipped. Costs are estimated from the media
+// downloaded so far, so the media of the first page are always allowed.
+func (b *Budget) AllowMedia(files int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if files == 0 || b.mediaListed == 0 {
+		return files
+	}
+
+	allowed := files
+	remainingPages := max(b.targetPages-b.pages, 0)
+	if b.maxBytes > 0 {
+		left := b.maxBytes - b.pageBytes - b.mediaBytes
+		var reserve int64
+		if b.pages > 0 {
+			reserve = b.pageBytes / int64(b.pages) * int64(remainingPages)
+		}
+		perFile := b.mediaBytes / int64(b.mediaListed)
+		allowed = min(allowed, b.fit(float64(left), float64(reserve), float64(perFile), remainingPages))
+	}
+	if b.maxTime > 0 {
+		elapsed := time.Since(b.started)
+		left := b.maxTime - elapsed
+		var reserve time.Duration
+		if b.pages > 0 {
+			reserve = (elapsed - b.mediaTime) / time.Duration(b.pages) * time.Duration(remainingPages)
+		}
+		perFile := b.mediaTime / time.Duration(b.mediaListed)
+		allowed = min(allowed, b.fit(float64(left), float64(reserve), float64(perFile), remainingPages))
+	}
+	allowed = max(min(allowed, files), 0)
+
+	b.mediaSkipped += files - allowed
+	return allowed
+}
+
+// fit returns the number of media files costing perFile each that the policy
+// allows out of what is left of a resource, reserve being the expected cost
+// of the remaining pages
+func (b *Budget) fit(left, reserve, perFile float64, remainingPages int) int {
+	var allowance float64
+	switch b.policy {
+	case Media:
+		allowance = left
+	case Pages:
+		allowance = left - reserve
+	default:
+		allowance = left / float64(remainingPages+1)
+	}
+	if perFile <= 0 {
+		if allowance > 0 {
+			return math.MaxInt
+		}
+		return 0
+	}
+	return int(max(allowance, 0) / perFile)
+}
+
+// Exhausted reports whether a limit of the budget is reached, after which no
+// more pages should be crawled
+func (b *Budget) Exhausted() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.exhausted()
+}
+
+func (b *Budget) exhausted() bool {
+	if b.maxBytes > 0 && b.pageBytes+b.mediaBytes >= b.maxBytes {
+		return true
+	}
+	return b.maxTime > 0 && time.Since(b.started) >= b.maxTime
+}
+
+// Report returns the trade-off made by the budget so far
+func (b *Budget) Report() *Report {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	report := &Report{
+		Policy:       b.policy,
+		MaxBytes:     b.maxBytes,
+		MaxTime:      b.maxTime.Seconds(),
+		Pages:        b.pages,
+		PageBytes:    b.pageBytes,
+		MediaBytes:   b.mediaBytes,
+		MediaSkipped: b.mediaSkipped,
+		Exhausted:    b.exhausted(),
+	}
+	if total := b.pageBytes + b.mediaBytes; total > 0 {
+		report.MediaShare = float64(b.mediaBytes) / float64(total)
+	}
+	return report
+}
@@ -2,8 +2,7 @@ package config
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -11,113 +10,553 @@ import (
 
 // Config represents the application configuration
 type Config struct {
-	ServerURL      string `mapstructure:"server_url"`
-	Timeout        int    `mapstructure:"timeout"`
-	MaxConcurrent  int    `mapstructure:"max_concurrent"`
-	IncludeMedia   bool   `mapstructure:"include_media"`
-	OverwriteFiles bool   `mapstructure:"overwrite_files"`
-	URL            string `mapstructure:"url"`
-	Library        string `mapstructure:"library"`
-	Output         string `mapstructure:"output"`
+	ServerURL              string   `mapstructure:"server_url"`
+	APIVersion             string   `mapstructure:"api_version"`             // crawl4ai API spoken: auto, 0.4, 0.5 or 0.6
+	Engine                 string   `mapstructure:"engine"`                  // crawl4ai, or local to fetch pages without a crawl4ai server
+	Fallback               string   `mapstructure:"fallback"`                // none, or local to fetch pages directly while crawl4ai is unavailable
+	UserAgents             []string `mapstructure:"user_agents"`             // user agents sent to origin servers and crawl4ai, in turn
+	TLSCAFile              string   `mapstructure:"tls_ca_file"`             // PEM bundle of CAs trusted besides the system roots
+	TLSCertFile            string   `mapstructure:"tls_cert_file"`           // PEM client certificate presented for mutual TLS
+	TLSKeyFile             string   `mapstructure:"tls_key_file"`            // PEM private key of tls_cert_file
+	InsecureSkipVerify     bool     `mapstructure:"insecure_skip_verify"`    // accept any server certificate
+	MaxIdleConnsPerHost    int      `mapstructure:"max_idle_conns_per_host"` // idle connections kept per host, 0 for max_concurrent
+	IdleConnTimeout        int      `mapstructure:"idle_conn_timeout"`       // seconds an idle connection is kept, 0 for no limit
+	HTTP2                  bool     `mapstructure:"http2"`                   // negotiate HTTP/2 with servers supporting it
+	KeepAlive              bool     `mapstructure:"keep_alive"`              // reuse connections between requests
+	MaxResponseSize        string   `mapstructure:"max_response_size"`       // size of the largest response read, e.g. 1GB, empty for no limit
+	MemoryBudget           string   `mapstructure:"memory_budget"`           // page content held in memory before backpressure, e.g. 512MB, empty for no limit
+	Timeout                int      `mapstructure:"timeout"`
+	MaxConcurrent          int      `mapstructure:"max_concurrent"`
+	RetryMax               int      `mapstructure:"retry_max"`
+	RetryBackoff           float64  `mapstructure:"retry_backoff"`
+	RetryMultiplier        float64  `mapstructure:"retry_multiplier"`
+	RetryMaxBackoff        float64  `mapstructure:"retry_max_backoff"`
+	RetryJitter            float64  `mapstructure:"retry_jitter"`
+	MaxRetryAfter          int      `mapstructure:"max_retry_after"`
+	BreakerThreshold       int      `mapstructure:"breaker_threshold"`
+	BreakerCooldown        int      `mapstructure:"breaker_cooldown"`
+	IncludeMedia           bool     `mapstructure:"include_media"`
+	MediaRateLimit         float64  `mapstructure:"media_rate_limit"`
+	MediaBacklog           int      `mapstructure:"media_backlog"`
+	ShardStorage           bool     `mapstructure:"shard_storage"`
+	MediaMaxSize           string   `mapstructure:"media_max_size"`
+	MediaTypes             string   `mapstructure:"media_types"`
+	MediaExtensions        string   `mapstructure:"media_extensions"`
+	MediaExcludeExtensions string   `mapstructure:"media_exclude_extensions"`
+	MediaLayout            string   `mapstructure:"media_layout"`
+	OverwriteFiles         bool     `mapstructure:"overwrite_files"`
+	DurableWrites          bool     `mapstructure:"durable_writes"`
+	Incremental            bool     `mapstructure:"incremental"`
+	Sync                   bool     `mapstructure:"sync"`
+	SyncRemoved            string   `mapstructure:"sync_removed"`
+	URL                    string   `mapstructure:"url"`
+	Seeds                  []string `mapstructure:"seeds"`      // start URLs crawled besides url
+	SeedsFile              string   `mapstructure:"seeds_file"` // file of start URLs, one per line
+	Library                string   `mapstructure:"library"`
+	Output                 string   `mapstructure:"output"`
+	SaveHTML               bool     `mapstructure:"save_html"`
+	SaveCleanedHTML        bool     `mapstructure:"save_cleaned_html"`
+	SaveJSON               bool     `mapstructure:"save_json"`
+	Export                 string   `mapstructure:"export"`
+	FrontMatter            bool     `mapstructure:"frontmatter"`
+	RewriteLinks           bool     `mapstructure:"rewrite_links"`
+	OptOutPolicy           string   `mapstructure:"opt_out_policy"`
+	ReportTemplate         string   `mapstructure:"report_template"`
+	ReportFile             string   `mapstructure:"report_file"`
+	Archive                string   `mapstructure:"archive"`
+	ArchiveOnly            bool     `mapstructure:"archive_only"`
+	Snapshot               bool     `mapstructure:"snapshot"`
+	SnapshotDir            string   `mapstructure:"snapshot_dir"`
+	SnapshotKeep           int      `mapstructure:"snapshot_keep"`
+	WARC                   bool     `mapstructure:"warc"`
+	CrawlTree              bool     `mapstructure:"crawl_tree"`
+	CrawlTreeInterval      int      `mapstructure:"crawl_tree_interval"`
+	SitemapReport          bool     `mapstructure:"sitemap_report"`
+	LinkGraph              bool     `mapstructure:"link_graph"`
+	ExpectMinPages         int      `mapstructure:"expect_min_pages"`
+	ExpectURLs             []string `mapstructure:"expect_urls"`
+	FailFast               bool     `mapstructure:"fail_fast"`          // abort the crawl on the first storage or API error
+	MaxErrorRate           float64  `mapstructure:"max_error_rate"`     // percentage of pages that may fail, 0 for no limit
+	WebhookURLs            []string `mapstructure:"webhook_urls"`       // URLs notified of the start, progress and end of crawls
+	WebhookEvery           int      `mapstructure:"webhook_every"`      // pages between progress notifications, 0 for none
+	WebhookTimeout         int      `mapstructure:"webhook_timeout"`    // seconds a webhook request may take
+	OTLPEndpoint           string   `mapstructure:"otlp_endpoint"`      // OTLP/HTTP endpoint spans are exported to, tracing disabled if empty
+	TraceSampleRatio       float64  `mapstructure:"trace_sample_ratio"` // fraction of crawls traced
+	Capture                string   `mapstructure:"capture"`
+	ReplayServer           string   `mapstructure:"replay_server"`
 
 	// Crawling configuration
-	MaxDepth        int    `mapstructure:"max_depth"`
-	DiscoveryMethod string `mapstructure:"discovery_method"`
-	BatchSize       int    `mapstructure:"batch_size"`
-	ExcludePatterns string `mapstructure:"exclude_patterns"`
-	MaxURLs         int    `mapstructure:"max_urls"`
+	MaxDepth           int    `mapstructure:"max_depth"`
+	DiscoveryMethod    string `mapstructure:"discovery_method"`
+	Strategy           string `mapstructure:"strategy"`
+	BatchSize          int    `mapstructure:"batch_size"`
+	ExcludePatterns    string `mapstructure:"exclude_patterns"`
+	MaxURLs            int    `mapstructure:"max_urls"`
+	MaxDuration        int    `mapstructure:"max_duration"`
+	IncludeSubdomains  bool   `mapstructure:"include_subdomains"`
+	AllowedDomains     string `mapstructure:"allowed_domains"`
+	MaxPagesPerDomain  int    `mapstructure:"max_pages_per_domain"`
+	IgnoreMetaRobots   bool   `mapstructure:"ignore_meta_robots"`
+	UseCanonical       bool   `mapstructure:"use_canonical"`
+	Languages          string `mapstructure:"languages"`
+	CrawlContentTypes  string `mapstructure:"crawl_content_types"`
+	ProbeContentTypes  bool   `mapstructure:"probe_content_types"`
+	ConvertPDFs        bool   `mapstructure:"convert_pdfs"`
+	BudgetBytes        string `mapstructure:"budget_bytes"`
+	BudgetTime         int    `mapstructure:"budget_time"`
+	BudgetPolicy       string `mapstructure:"budget_policy"`
+	Preset             string `mapstructure:"preset"`
+	AutoPreset         bool   `mapstructure:"auto_preset"`
+	CSSSelector        string `mapstructure:"css_selector"`
+	ExcludedSelector   string `mapstructure:"excluded_selector"`
+	PaginationPattern  string `mapstructure:"pagination_pattern"`
+	MaxPaginationPages int    `mapstructure:"max_pagination_pages"`
 
 	// Logging configuration
 	LogLevel       string `mapstructure:"log_level"`
 	LogOutput      string `mapstructure:"log_output"`
+	LogFormat      string `mapstructure:"log_format"`
 	LogFilePath    string `mapstructure:"log_file_path"`
 	LogIncludeTime bool   `mapstructure:"log_include_time"`
 	LogStructured  bool   `mapstructure:"log_structured"`
+	ErrorsFormat   string `mapstructure:"errors_format"`
+	Language       string `mapstructure:"language"`
+
+	// Agent configuration
+	QueueURL     string `mapstructure:"queue_url"`
+	QueueSubject string `mapstructure:"queue_subject"`
+	QueueGroup   string `mapstructure:"queue_group"`
+
+	// Serve mode configuration
+	ServeAddr      string  `mapstructure:"serve_addr"`
+	ServeGraphQL   bool    `mapstructure:"serve_graphql"`
+	ServePublic    bool    `mapstructure:"serve_public"`
+	ServeRateLimit float64 `mapstructure:"serve_rate_limit"`
+	ServeJobs      bool    `mapstructure:"serve_jobs"`
+	ServeMaxJobs   int     `mapstructure:"serve_max_jobs"`
+
+	// Crawl state configuration
+	StateBackend   string `mapstructure:"state_backend"`
+	RedisURL       string `mapstructure:"redis_url"`
+	RedisKeyPrefix string `mapstructure:"redis_key_prefix"`
+	RedisTTL       int    `mapstructure:"redis_ttl"`
+
+	// Origin server credentials, keyed by domain
+	Auth map[string]AuthConfig `mapstructure:"auth"`
+
+	// Structured extraction schema forwarded to crawl4ai
+	Extraction ExtractionConfig `mapstructure:"extraction"`
+
+	// Transformation steps applied to the markdown of every page, in order
+	Transforms []TransformStep `mapstructure:"transforms"`
+
+	// Maximum depths overriding max_depth below URL paths; the first matching
+	// rule applies
+	DepthRules []DepthRule `mapstructure:"depth_rules"`
+
+	// Settings overriding the crawl settings on some domains, keyed by domain
+	Domains map[string]DomainPolicy `mapstructure:"domains"`
+
+	// Commands run on every page around saving
+	Hooks HooksConfig `mapstructure:"hooks"`
+
+	// Chat webhooks told when crawls complete or fail
+	Notifications NotificationsConfig `mapstructure:"notifications"`
+}
+
+// NotificationsConfig sets the chat webhooks posted a message when a crawl
+// completes or fails
+type NotificationsConfig struct {
+	Slack   ChatNotifierConfig `mapstructure:"slack"`
+	Discord ChatNotifierConfig `mapstructure:"discord"`
+}
+
+// ChatNotifierConfig sets an incoming webhook of a chat service, disabled if
+// its URL is empty
+type ChatNotifierConfig struct {
+	WebhookURL   string `mapstructure:"webhook_url"`
+	OnlyFailures bool   `mapstructure:"only_failures"` // leave out completed crawls
+}
+
+// HooksConfig lists the hooks run on every page, each as exec:<command> run
+// with sh -c. Pre-save hooks get the markdown on stdin and print the
+// markdown to save, or reject the page; post-save hooks run once it is saved.
+type HooksConfig struct {
+	PreSave  []string `mapstructure:"pre_save"`
+	PostSave []string `mapstructure:"post_save"`
+	Timeout  int      `mapstructure:"timeout"` // seconds a hook may run, 30 if 0
+}
+
+// DepthRule sets the maximum crawl depth of the URLs whose path matches a
+// glob such as /docs/**
+type DepthRule struct {
+	Path     string `mapstructure:"path"`
+	MaxDepth int    `mapstructure:"max_depth"`
+}
+
+// DomainPolicy overrides the crawl settings for the pages and media of a
+// domain and its subdomains. Unset fields keep the global setting.
+type DomainPolicy struct {
+	RateLimit    float64           `mapstructure:"rate_limit"` // requests per second, 0 for no limit
+	Headers      map[string]string `mapstructure:"headers"`    // sent with every request
+	Auth         *AuthConfig       `mapstructure:"auth"`       // replaces the auth section
+	IncludeMedia *bool             `mapstructure:"include_media"`
+	MaxDepth     *int              `mapstructure:"max_depth"` // below depth_rules, above max_depth
+}
+
+// TransformStep is a step of the page transformation pipeline. Exactly one of
+// its fields is set.
+type TransformStep struct {
+	StripSelector     string                 `mapstructure:"strip_selector"`     // elements left out of the markdown, by crawl4ai
+	RegexReplace      *ReplaceStep           `mapstructure:"regex_replace"`      // applied to the markdown body
+	InjectFrontMatter map[string]interface{} `mapstructure:"inject_frontmatter"` // keys set in the front matter
+	RewriteLinks      *ReplaceStep           `mapstructure:"rewrite_links"`      // applied to link targets
+	Truncate          *TruncateStep          `mapstructure:"truncate"`
+}
+
+// ReplaceStep replaces the matches of a regular expression. The replacement
+// may refer to groups as $1 or ${name}.
+type ReplaceStep struct {
+	Pattern     string `mapstructure:"pattern"`
+	Replacement string `mapstructure:"replacement"`
+}
+
+// TruncateStep cuts the markdown body after MaxChars characters, at the last
+// paragraph break before the limit, and appends Marker
+type TruncateStep struct {
+	MaxChars int    `mapstructure:"max_chars"`
+	Marker   string `mapstructure:"marker"`
+}
+
+// ExtractionConfig describes a JsonCssExtractionStrategy-style schema. When
+// Fields is empty no extraction strategy is sent to crawl4ai.
+type ExtractionConfig struct {
+	Type         string            `mapstructure:"type"` // css, xpath
+	Name         string            `mapstructure:"name"`
+	BaseSelector string            `mapstructure:"base_selector"`
+	Fields       []ExtractionField `mapstructure:"fields"`
+}
+
+// ExtractionField maps a selector relative to the base selector to an output field
+type ExtractionField struct {
+	Name      string            `mapstructure:"name"`
+	Selector  string            `mapstructure:"selector"`
+	Type      string            `mapstructure:"type"` // text, attribute, html, regex, nested, list
+	Attribute string            `mapstructure:"attribute"`
+	Pattern   string            `mapstructure:"pattern"`
+	Fields    []ExtractionField `mapstructure:"fields"` // sub-fields for nested and list types
+}
+
+// Enabled reports whether an extraction schema has been configured
+func (e ExtractionConfig) Enabled() bool {
+	return len(e.Fields) > 0
+}
+
+// AuthConfig holds the credentials used to authenticate against an origin server
+type AuthConfig struct {
+	Type     string `mapstructure:"type"` // basic, bearer, api_key
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	Token    string `mapstructure:"token"`
+	Header   string `mapstructure:"header"` // header name for api_key, defaults to X-API-Key
 }
 
 // DefaultConfig returns a configuration with default values
 func DefaultConfig() *Config {
 	return &Config{
-		ServerURL:      "http://192.168.1.27:8888/",
-		Timeout:        30,
-		MaxConcurrent:  5,
-		IncludeMedia:   true,
-		OverwriteFiles: false,
+		ServerURL:              "http://192.168.1.27:8888/",
+		APIVersion:             "auto",
+		Engine:                 "crawl4ai",
+		Fallback:               "none",
+		IdleConnTimeout:        90,
+		HTTP2:                  true,
+		KeepAlive:              true,
+		MaxResponseSize:        "1GB",
+		Timeout:                30,
+		MaxConcurrent:          5,
+		RetryMax:               1,
+		RetryBackoff:           1,
+		RetryMultiplier:        2,
+		RetryMaxBackoff:        30,
+		RetryJitter:            0.2,
+		MaxRetryAfter:          120,
+		BreakerThreshold:       5,
+		BreakerCooldown:        60,
+		IncludeMedia:           true,
+		MediaRateLimit:         0,
+		MediaBacklog:           2,
+		ShardStorage:           false,
+		MediaMaxSize:           "",
+		MediaTypes:             "",
+		MediaExtensions:        "",
+		MediaExcludeExtensions: "",
+		MediaLayout:            "path",
+		OverwriteFiles:         false,
+		DurableWrites:          false,
+		Incremental:            false,
+		Sync:                   false,
+		SyncRemoved:            "move",
+		Seeds:                  nil,
+		SeedsFile:              "",
+		SaveHTML:               false,
+		SaveCleanedHTML:        false,
+		SaveJSON:               false,
+		Export:                 "",
+		FrontMatter:            false,
+		RewriteLinks:           false,
+		OptOutPolicy:           "warn",
+		ReportTemplate:         "",
+		ReportFile:             "",
+		Archive:                "",
+		ArchiveOnly:            false,
+		Snapshot:               false,
+		SnapshotDir:            "",
+		SnapshotKeep:           0,
+		WARC:                   false,
+		CrawlTree:              false,
+		CrawlTreeInterval:      10,
+		SitemapReport:          false,
+		LinkGraph:              false,
+		ExpectMinPages:         0,
+		ExpectURLs:             nil,
+		FailFast:               false,
+		MaxErrorRate:           0,
+		WebhookURLs:            nil,
+		WebhookEvery:           0,
+		WebhookTimeout:         10,
+		OTLPEndpoint:           "",
+		TraceSampleRatio:       1,
+		Capture:                "",
+		ReplayServer:           "",
 		// Crawling defaults
-		MaxDepth:        2,
-		DiscoveryMethod: "auto",
-		BatchSize:       5,
-		ExcludePatterns: "",
-		MaxURLs:         50,
+		MaxDepth:           2,
+		DiscoveryMethod:    "auto",
+		Strategy:           "bfs",
+		BatchSize:          5,
+		ExcludePatterns:    "",
+		MaxURLs:            50,
+		MaxDuration:        0,
+		IncludeSubdomains:  false,
+		AllowedDomains:     "",
+		MaxPagesPerDomain:  0,
+		IgnoreMetaRobots:   false,
+		UseCanonical:       false,
+		Languages:          "",
+		CrawlContentTypes:  "",
+		ProbeContentTypes:  false,
+		ConvertPDFs:        true,
+		BudgetBytes:        "",
+		BudgetTime:         0,
+		BudgetPolicy:       "balanced",
+		Preset:             "",
+		AutoPreset:         false,
+		CSSSelector:        "",
+		ExcludedSelector:   "",
+		PaginationPattern:  "",
+		MaxPaginationPages: 0,
 		// Logging defaults
 		LogLevel:       "INFO",
 		LogOutput:      "console",
-		LogFilePath:    "crawlr.log",
+		LogFormat:      "text",
+		LogFilePath:    "",
 		LogIncludeTime: true,
 		LogStructured:  true,
+		ErrorsFormat:   "text",
+		Language:       "en",
+		// Agent defaults
+		QueueURL:     "",
+		QueueSubject: "crawlr.jobs",
+		QueueGroup:   "crawlr-agents",
+		// Serve mode defaults
+		ServeAddr:      "127.0.0.1:8080",
+		ServeGraphQL:   false,
+		ServePublic:    false,
+		ServeRateLimit: 0,
+		ServeJobs:      false,
+		ServeMaxJobs:   1,
+		// Crawl state defaults
+		StateBackend:   "memory",
+		RedisURL:       "",
+		RedisKeyPrefix: "crawlr",
+		RedisTTL:       86400,
 	}
 }
 
+// hostSections are the sections keyed by domain names, which viper would
+// split at their dots into nested maps when unmarshalling the configuration
+var hostSections = []string{"auth", "domains"}
+
+// NewViper creates a viper instance configured for crawlr's configuration
+// layout, in which nested keys such as extraction.base_selector are set by
+// environment variables such as CRAWLR_EXTRACTION_BASE_SELECTOR
+func NewViper() *viper.Viper {
+	return viper.NewWithOptions(viper.EnvKeyReplacer(strings.NewReplacer(".", "_")))
+}
+
+// unmarshal decodes the configuration of v, reading the sections keyed by
+// domain names as raw maps so that the domains keep their dots
+func unmarshal(v *viper.Viper) (*Config, error) {
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	cfg.Auth, cfg.Domains = nil, nil
+	if err := v.UnmarshalKey("auth", &cfg.Auth); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal auth: %w", err)
+	}
+	if err := v.UnmarshalKey("domains", &cfg.Domains); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal domains: %w", err)
+	}
+	return &cfg, nil
+}
+
 // LoadConfig loads configuration from multiple sources (file, environment variables, flags)
 func LoadConfig() (*Config, error) {
-	v := viper.New()
+	v := NewViper()
 
 	// Set default values
 	config := DefaultConfig()
 	v.SetDefault("server_url", config.ServerURL)
+	v.SetDefault("api_version", config.APIVersion)
+	v.SetDefault("engine", config.Engine)
+	v.SetDefault("fallback", config.Fallback)
+	v.SetDefault("user_agents", config.UserAgents)
+	v.SetDefault("tls_ca_file", config.TLSCAFile)
+	v.SetDefault("tls_cert_file", config.TLSCertFile)
+	v.SetDefault("tls_key_file", config.TLSKeyFile)
+	v.SetDefault("insecure_skip_verify", config.InsecureSkipVerify)
+	v.SetDefault("max_idle_conns_per_host", config.MaxIdleConnsPerHost)
+	v.SetDefault("idle_conn_timeout", config.IdleConnTimeout)
+	v.SetDefault("http2", config.HTTP2)
+	v.SetDefault("keep_alive", config.KeepAlive)
+	v.SetDefault("max_response_size", config.MaxResponseSize)
+	v.SetDefault("memory_budget", config.MemoryBudget)
 	v.SetDefault("timeout", config.Timeout)
 	v.SetDefault("max_concurrent", config.MaxConcurrent)
+	v.SetDefault("retry_max", config.RetryMax)
+	v.SetDefault("retry_backoff", config.RetryBackoff)
+	v.SetDefault("retry_multiplier", config.RetryMultiplier)
+	v.SetDefault("retry_max_backoff", config.RetryMaxBackoff)
+	v.SetDefault("retry_jitter", config.RetryJitter)
+	v.SetDefault("max_retry_after", config.MaxRetryAfter)
+	v.SetDefault("breaker_threshold", config.BreakerThreshold)
+	v.SetDefault("breaker_cooldown", config.BreakerCooldown)
 	v.SetDefault("include_media", config.IncludeMedia)
+	v.SetDefault("media_rate_limit", config.MediaRateLimit)
+	v.SetDefault("media_backlog", config.MediaBacklog)
+	v.SetDefault("shard_storage", config.ShardStorage)
+	v.SetDefault("media_max_size", config.MediaMaxSize)
+	v.SetDefault("media_types", config.MediaTypes)
+	v.SetDefault("media_extensions", config.MediaExtensions)
+	v.SetDefault("media_exclude_extensions", config.MediaExcludeExtensions)
+	v.SetDefault("media_layout", config.MediaLayout)
 	v.SetDefault("overwrite_files", config.OverwriteFiles)
+	v.SetDefault("durable_writes", config.DurableWrites)
+	v.SetDefault("incremental", config.Incremental)
+	v.SetDefault("sync", config.Sync)
+	v.SetDefault("sync_removed", config.SyncRemoved)
+	v.SetDefault("seeds", config.Seeds)
+	v.SetDefault("seeds_file", config.SeedsFile)
+	v.SetDefault("save_html", config.SaveHTML)
+	v.SetDefault("save_cleaned_html", config.SaveCleanedHTML)
+	v.SetDefault("save_json", config.SaveJSON)
+	v.SetDefault("export", config.Export)
+	v.SetDefault("frontmatter", config.FrontMatter)
+	v.SetDefault("rewrite_links", config.RewriteLinks)
+	v.SetDefault("opt_out_policy", config.OptOutPolicy)
+	v.SetDefault("report_template", config.ReportTemplate)
+	v.SetDefault("report_file", config.ReportFile)
+	v.SetDefault("archive", config.Archive)
+	v.SetDefault("archive_only", config.ArchiveOnly)
+	v.SetDefault("snapshot", config.Snapshot)
+	v.SetDefault("snapshot_dir", config.SnapshotDir)
+	v.SetDefault("snapshot_keep", config.SnapshotKeep)
+	v.SetDefault("warc", config.WARC)
+	v.SetDefault("crawl_tree", config.CrawlTree)
+	v.SetDefault("crawl_tree_interval", config.CrawlTreeInterval)
+	v.SetDefault("sitemap_report", config.SitemapReport)
+	v.SetDefault("link_graph", config.LinkGraph)
+	v.SetDefault("expect_min_pages", config.ExpectMinPages)
+	v.SetDefault("expect_urls", config.ExpectURLs)
+	v.SetDefault("fail_fast", config.FailFast)
+	v.SetDefault("max_error_rate", config.MaxErrorRate)
+	v.SetDefault("webhook_urls", config.WebhookURLs)
+	v.SetDefault("webhook_every", config.WebhookEvery)
+	v.SetDefault("webhook_timeout", config.WebhookTimeout)
+	v.SetDefault("otlp_endpoint", config.OTLPEndpoint)
+	v.SetDefault("trace_sample_ratio", config.TraceSampleRatio)
+	v.SetDefault("capture", config.Capture)
+	v.SetDefault("replay_server", config.ReplayServer)
 	// Crawling defaults
 	v.SetDefault("max_depth", config.MaxDepth)
 	v.SetDefault("discovery_method", config.DiscoveryMethod)
+	v.SetDefault("strategy", config.Strategy)
 	v.SetDefault("batch_size", config.BatchSize)
 	v.SetDefault("exclude_patterns", config.ExcludePatterns)
 	v.SetDefault("max_urls", config.MaxURLs)
+	v.SetDefault("max_duration", config.MaxDuration)
+	v.SetDefault("include_subdomains", config.IncludeSubdomains)
+	v.SetDefault("allowed_domains", config.AllowedDomains)
+	v.SetDefault("max_pages_per_domain", config.MaxPagesPerDomain)
+	v.SetDefault("ignore_meta_robots", config.IgnoreMetaRobots)
+	v.SetDefault("use_canonical", config.UseCanonical)
+	v.SetDefault("languages", config.Languages)
+	v.SetDefault("crawl_content_types", config.CrawlContentTypes)
+	v.SetDefault("probe_content_types", config.ProbeContentTypes)
+	v.SetDefault("convert_pdfs", config.ConvertPDFs)
+	v.SetDefault("budget_bytes", config.BudgetBytes)
+	v.SetDefault("budget_time", config.BudgetTime)
+	v.SetDefault("budget_policy", config.BudgetPolicy)
+	v.SetDefault("preset", config.Preset)
+	v.SetDefault("auto_preset", config.AutoPreset)
+	v.SetDefault("css_selector", config.CSSSelector)
+	v.SetDefault("excluded_selector", config.ExcludedSelector)
+	v.SetDefault("pagination_pattern", config.PaginationPattern)
+	v.SetDefault("max_pagination_pages", config.MaxPaginationPages)
 	// Logging defaults
 	v.SetDefault("log_level", config.LogLevel)
 	v.SetDefault("log_output", config.LogOutput)
+	v.SetDefault("log_format", config.LogFormat)
 	v.SetDefault("log_file_path", config.LogFilePath)
 	v.SetDefault("log_include_time", config.LogIncludeTime)
 	v.SetDefault("log_structured", config.LogStructured)
+	v.SetDefault("errors_format", config.ErrorsFormat)
+	v.SetDefault("language", config.Language)
+	// Agent defaults
+	v.SetDefault("queue_url", config.QueueURL)
+	v.SetDefault("queue_subject", config.QueueSubject)
+	v.SetDefault("queue_group", config.QueueGroup)
+	// Serve mode defaults
+	v.SetDefault("serve_addr", config.ServeAddr)
+	v.SetDefault("serve_graphql", config.ServeGraphQL)
+	v.SetDefault("serve_public", config.ServePublic)
+	v.SetDefault("serve_rate_limit", config.ServeRateLimit)
+	v.SetDefault("serve_jobs", config.ServeJobs)
+	v.SetDefault("serve_max_jobs", config.ServeMaxJobs)
+	// Crawl state defaults
+	v.SetDefault("state_backend", config.StateBackend)
+	v.SetDefault("redis_url", config.RedisURL)
+	v.SetDefault("redis_key_prefix", config.RedisKeyPrefix)
+	v.SetDefault("redis_ttl", config.RedisTTL)
 
 	// Configure viper to read from environment variables
 	v.AutomaticEnv()
-	v.SetEnvPrefix("CRAWLR") // Will look for CRAWLR_SERVER_URL, etc.
+	v.SetEnvPrefix(EnvPrefix) // Will look for CRAWLR_SERVER_URL, etc.
 
-	// Configure viper to read from config file
-	configDir := "config"
-	configName := "config"
-
-	// Create config directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	// Set config file path
-	v.SetConfigName(configName)
-	v.AddConfigPath(configDir)
-	v.AddConfigPath(".") // Also look in the current directory
-
-	// Try to read the config file, but don't fail if it doesn't exist
-	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("error reading config file: %w", err)
-		}
-		// Config file not found, we'll create a default one
-		if err := createDefaultConfigFile(configDir, configName); err != nil {
-			return nil, fmt.Errorf("failed to create default config file: %w", err)
-		}
+	if err := readConfigFile(v); err != nil {
+		return nil, err
 	}
 
 	// Unmarshal the configuration
-	var cfg Config
-	if err := v.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	cfg, err := unmarshal(v)
+	if err != nil {
+		return nil, err
 	}
 
-	return &cfg, nil
+	// Fill in the settings of the selected crawl preset
+	cfg.applyPreset()
+
+	return cfg, nil
 }
 
 // LoadConfigWithViper loads configuration using the provided viper instance
@@ -125,97 +564,171 @@ func LoadConfigWithViper(v *viper.Viper) (*Config, error) {
 	// Set default values if not already set
 	config := DefaultConfig()
 	v.SetDefault("server_url", config.ServerURL)
+	v.SetDefault("api_version", config.APIVersion)
+	v.SetDefault("engine", config.Engine)
+	v.SetDefault("fallback", config.Fallback)
+	v.SetDefault("user_agents", config.UserAgents)
+	v.SetDefault("tls_ca_file", config.TLSCAFile)
+	v.SetDefault("tls_cert_file", config.TLSCertFile)
+	v.SetDefault("tls_key_file", config.TLSKeyFile)
+	v.SetDefault("insecure_skip_verify", config.InsecureSkipVerify)
+	v.SetDefault("max_idle_conns_per_host", config.MaxIdleConnsPerHost)
+	v.SetDefault("idle_conn_timeout", config.IdleConnTimeout)
+	v.SetDefault("http2", config.HTTP2)
+	v.SetDefault("keep_alive", config.KeepAlive)
+	v.SetDefault("max_response_size", config.MaxResponseSize)
+	v.SetDefault("memory_budget", config.MemoryBudget)
 	v.SetDefault("timeout", config.Timeout)
 	v.SetDefault("max_concurrent", config.MaxConcurrent)
+	v.SetDefault("retry_max", config.RetryMax)
+	v.SetDefault("retry_backoff", config.RetryBackoff)
+	v.SetDefault("retry_multiplier", config.RetryMultiplier)
+	v.SetDefault("retry_max_backoff", config.RetryMaxBackoff)
+	v.SetDefault("retry_jitter", config.RetryJitter)
+	v.SetDefault("max_retry_after", config.MaxRetryAfter)
+	v.SetDefault("breaker_threshold", config.BreakerThreshold)
+	v.SetDefault("breaker_cooldown", config.BreakerCooldown)
 	v.SetDefault("include_media", config.IncludeMedia)
+	v.SetDefault("media_rate_limit", config.MediaRateLimit)
+	v.SetDefault("media_backlog", config.MediaBacklog)
+	v.SetDefault("shard_storage", config.ShardStorage)
+	v.SetDefault("media_max_size", config.MediaMaxSize)
+	v.SetDefault("media_types", config.MediaTypes)
+	v.SetDefault("media_extensions", config.MediaExtensions)
+	v.SetDefault("media_exclude_extensions", config.MediaExcludeExtensions)
+	v.SetDefault("media_layout", config.MediaLayout)
 	v.SetDefault("overwrite_files", config.OverwriteFiles)
+	v.SetDefault("durable_writes", config.DurableWrites)
+	v.SetDefault("incremental", config.Incremental)
+	v.SetDefault("sync", config.Sync)
+	v.SetDefault("sync_removed", config.SyncRemoved)
+	v.SetDefault("seeds", config.Seeds)
+	v.SetDefault("seeds_file", config.SeedsFile)
+	v.SetDefault("save_html", config.SaveHTML)
+	v.SetDefault("save_cleaned_html", config.SaveCleanedHTML)
+	v.SetDefault("save_json", config.SaveJSON)
+	v.SetDefault("export", config.Export)
+	v.SetDefault("frontmatter", config.FrontMatter)
+	v.SetDefault("rewrite_links", config.RewriteLinks)
+	v.SetDefault("opt_out_policy", config.OptOutPolicy)
+	v.SetDefault("report_template", config.ReportTemplate)
+	v.SetDefault("report_file", config.ReportFile)
+	v.SetDefault("archive", config.Archive)
+	v.SetDefault("archive_only", config.ArchiveOnly)
+	v.SetDefault("snapshot", config.Snapshot)
+	v.SetDefault("snapshot_dir", config.SnapshotDir)
+	v.SetDefault("snapshot_keep", config.SnapshotKeep)
+	v.SetDefault("warc", config.WARC)
+	v.SetDefault("crawl_tree", config.CrawlTree)
+	v.SetDefault("crawl_tree_interval", config.CrawlTreeInterval)
+	v.SetDefault("sitemap_report", config.SitemapReport)
+	v.SetDefault("link_graph", config.LinkGraph)
+	v.SetDefault("expect_min_pages", config.ExpectMinPages)
+	v.SetDefault("expect_urls", config.ExpectURLs)
+	v.SetDefault("fail_fast", config.FailFast)
+	v.SetDefault("max_error_rate", config.MaxErrorRate)
+	v.SetDefault("webhook_urls", config.WebhookURLs)
+	v.SetDefault("webhook_every", config.WebhookEvery)
+	v.SetDefault("webhook_timeout", config.WebhookTimeout)
+	v.SetDefault("otlp_endpoint", config.OTLPEndpoint)
+	v.SetDefault("trace_sample_ratio", config.TraceSampleRatio)
+	v.SetDefault("capture", config.Capture)
+	v.SetDefault("replay_server", config.ReplayServer)
 	// Crawling defaults
 	v.SetDefault("max_depth", config.MaxDepth)
 	v.SetDefault("discovery_method", config.DiscoveryMethod)
+	v.SetDefault("strategy", config.Strategy)
 	v.SetDefault("batch_size", config.BatchSize)
 	v.SetDefault("exclude_patterns", config.ExcludePatterns)
 	v.SetDefault("max_urls", config.MaxURLs)
+	v.SetDefault("max_duration", config.MaxDuration)
+	v.SetDefault("include_subdomains", config.IncludeSubdomains)
+	v.SetDefault("allowed_domains", config.AllowedDomains)
+	v.SetDefault("max_pages_per_domain", config.MaxPagesPerDomain)
+	v.SetDefault("ignore_meta_robots", config.IgnoreMetaRobots)
+	v.SetDefault("use_canonical", config.UseCanonical)
+	v.SetDefault("languages", config.Languages)
+	v.SetDefault("crawl_content_types", config.CrawlContentTypes)
+	v.SetDefault("probe_content_types", config.ProbeContentTypes)
+	v.SetDefault("convert_pdfs", config.ConvertPDFs)
+	v.SetDefault("budget_bytes", config.BudgetBytes)
+	v.SetDefault("budget_time", config.BudgetTime)
+	v.SetDefault("budget_policy", config.BudgetPolicy)
+	v.SetDefault("preset", config.Preset)
+	v.SetDefault("auto_preset", config.AutoPreset)
+	v.SetDefault("css_selector", config.CSSSelector)
+	v.SetDefault("excluded_selector", config.ExcludedSelector)
+	v.SetDefault("pagination_pattern", config.PaginationPattern)
+	v.SetDefault("max_pagination_pages", config.MaxPaginationPages)
 	// Logging defaults
 	v.SetDefault("log_level", config.LogLevel)
 	v.SetDefault("log_output", config.LogOutput)
+	v.SetDefault("log_format", config.LogFormat)
 	v.SetDefault("log_file_path", config.LogFilePath)
 	v.SetDefault("log_include_time", config.LogIncludeTime)
 	v.SetDefault("log_structured", config.LogStructured)
+	v.SetDefault("errors_format", config.ErrorsFormat)
+	v.SetDefault("language", config.Language)
+	// Agent defaults
+	v.SetDefault("queue_url", config.QueueURL)
+	v.SetDefault("queue_subject", config.QueueSubject)
+	v.SetDefault("queue_group", config.QueueGroup)
+	// Serve mode defaults
+	v.SetDefault("serve_addr", config.ServeAddr)
+	v.SetDefault("serve_graphql", config.ServeGraphQL)
+	v.SetDefault("serve_public", config.ServePublic)
+	v.SetDefault("serve_rate_limit", config.ServeRateLimit)
+	v.SetDefault("serve_jobs", config.ServeJobs)
+	v.SetDefault("serve_max_jobs", config.ServeMaxJobs)
+	// Crawl state defaults
+	v.SetDefault("state_backend", config.StateBackend)
+	v.SetDefault("redis_url", config.RedisURL)
+	v.SetDefault("redis_key_prefix", config.RedisKeyPrefix)
+	v.SetDefault("redis_ttl", config.RedisTTL)
 
 	// Configure viper to read from environment variables
 	v.AutomaticEnv()
-	v.SetEnvPrefix("CRAWLR") // Will look for CRAWLR_SERVER_URL, etc.
-
-	// Configure viper to read from config file
-	configDir := "config"
-	configName := "config"
-
-	// Create config directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	// Set config file path
-	v.SetConfigName(configName)
-	v.AddConfigPath(configDir)
-	v.AddConfigPath(".") // Also look in the current directory
+	v.SetEnvPrefix(EnvPrefix) // Will look for CRAWLR_SERVER_URL, etc.
 
-	// Try to read the config file, but don't fail if it doesn't exist
-	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("error reading config file: %w", err)
-		}
-		// Config file not found, we'll create a default one
-		if err := createDefaultConfigFile(configDir, configName); err != nil {
-			return nil, fmt.Errorf("failed to create default config file: %w", err)
-		}
+	if err := readConfigFile(v); err != nil {
+		return nil, err
 	}
 
 	// Unmarshal the configuration
-	var cfg Config
-	if err := v.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	cfg, err := unmarshal(v)
+	if err != nil {
+		return nil, err
 	}
 
-	return &cfg, nil
-}
+	// Fill in the settings of the selected crawl preset
+	cfg.applyPreset()
 
-// createDefaultConfigFile creates a default configuration file
-func createDefaultConfigFile(configDir, configName string) error {
-	configPath := filepath.Join(configDir, configName+".yaml")
+	return cfg, nil
+}
 
-	// Check if config file already exists
-	if _, err := os.Stat(configPath); err == nil {
-		return nil // File already exists, no need to create
+// readConfigFile reads the configuration file set with v.SetConfigFile, which
+// must exist. Otherwise config/config.yaml and config.yaml in the working
+// directory and then the file of UserConfigPath are looked for, the defaults
+// applying if there is none; crawlr config init writes a starter file.
+func readConfigFile(v *viper.Viper) error {
+	if v.ConfigFileUsed() != "" {
+		if err := v.ReadInConfig(); err != nil {
+			return fmt.Errorf("error reading config file: %w", err)
+		}
+		return nil
 	}
 
-	// Create default config file
-	defaultConfig := DefaultConfig()
-
-	v := viper.New()
-	v.Set("server_url", defaultConfig.ServerURL)
-	v.Set("timeout", defaultConfig.Timeout)
-	v.Set("max_concurrent", defaultConfig.MaxConcurrent)
-	v.Set("include_media", defaultConfig.IncludeMedia)
-	v.Set("overwrite_files", defaultConfig.OverwriteFiles)
-	// Crawling defaults
-	v.Set("max_depth", defaultConfig.MaxDepth)
-	v.Set("discovery_method", defaultConfig.DiscoveryMethod)
-	v.Set("batch_size", defaultConfig.BatchSize)
-	v.Set("exclude_patterns", defaultConfig.ExcludePatterns)
-	v.Set("max_urls", defaultConfig.MaxURLs)
-	// Logging defaults
-	v.Set("log_level", defaultConfig.LogLevel)
-	v.Set("log_output", defaultConfig.LogOutput)
-	v.Set("log_file_path", defaultConfig.LogFilePath)
-	v.Set("log_include_time", defaultConfig.LogIncludeTime)
-	v.Set("log_structured", defaultConfig.LogStructured)
-
-	// Write the config file
-	if err := v.WriteConfigAs(configPath); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	v.SetConfigName("config")
+	v.AddConfigPath("config")
+	v.AddConfigPath(".") // Also look in the current directory
+	if dir := UserConfigDir(); dir != "" {
+		v.AddConfigPath(dir)
+	}
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return fmt.Errorf("error reading config file: %w", err)
+		}
 	}
-
 	return nil
 }
 
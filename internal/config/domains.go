@@ -0,0 +1,36 @@
+package config
+
+import "strings"
+
+// ParentDomains returns host followed by its parent domains, closest first,
+// in lower case: "docs.example.com" gives "docs.example.com", "example.com"
+// and "com". Settings keyed by domain look them up in this order, so that
+// "example.com" also covers "cdn.example.com".
+func ParentDomains(host string) []string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	var domains []string
+	for host != "" {
+		domains = append(domains, host)
+		idx := strings.Index(host, ".")
+		if idx < 0 {
+			break
+		}
+		host = host[idx+1:]
+	}
+	return domains
+}
+
+// LookupDomain returns the policy applying to host in domains, keyed by lower
+// case domain, with the domain it is keyed by: that of host itself, or else
+// of its closest parent
+func LookupDomain(domains map[string]DomainPolicy, host string) (string, DomainPolicy, bool) {
+	if len(domains) == 0 {
+		return "", DomainPolicy{}, false
+	}
+	for _, domain := range ParentDomains(host) {
+		if policy, ok := domains[domain]; ok {
+			return domain, policy, true
+		}
+	}
+	return "", DomainPolicy{}, false
+}
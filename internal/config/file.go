@@ -0,0 +1,154 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// starterConfig is the configuration file written by crawlr config init: the
+// defaults of every top-level key, with comments, and commented-out examples
+// of the nested sections
+//
+//go:embed starter.yaml
+var starterConfig []byte
+
+// WriteStarterConfig writes the starter configuration file to path, creating
+// its directory, and replacing the file if it exists
+func WriteStarterConfig(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, starterConfig, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// ParseValue converts the text of a value to the type of the top-level key it
+// is set to, for SetFileValues. Lists are comma-separated. Nested sections,
+// such as auth, are not supported.
+func ParseValue(key, text string) (interface{}, error) {
+	field, ok := configField(key)
+	if !ok {
+		return nil, fmt.Errorf("unknown configuration key %q", key)
+	}
+
+	switch field.Type.Kind() {
+	case reflect.String:
+		return text, nil
+	case reflect.Bool:
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, got %q", key, text)
+		}
+		return value, nil
+	case reflect.Int:
+		value, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer, got %q", key, text)
+		}
+		return value, nil
+	case reflect.Float64:
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number, got %q", key, text)
+		}
+		return value, nil
+	case reflect.Slice:
+		if field.Type.Elem().Kind() == reflect.String {
+			items := []string{}
+			for _, item := range strings.Split(text, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			return items, nil
+		}
+	}
+	return nil, fmt.Errorf("%s can only be edited in the configuration file", key)
+}
+
+// configField returns the field of Config set by a top-level key
+func configField(key string) (reflect.StructField, bool) {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.Tag.Get("mapstructure") == key {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// SetFileValues sets top-level scalar keys of a YAML configuration file,
+// creating the file if it does not exist. Existing keys are replaced in place
+// and new ones are added at the top, in the order of keys, so that the rest of
+// the file, including comments and blank lines, is kept as it is.
+func SetFileValues(path string, values map[string]interface{}, keys []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	var added []string
+	for _, key := range keys {
+		encoded, err := yaml.Marshal(map[string]interface{}{key: values[key]})
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		line := strings.TrimSuffix(string(encoded), "\n")
+
+		if i := topLevelKeyLine(lines, key); i >= 0 {
+			// Drop the continuation lines of a multi-line value
+			end := i + 1
+			for end < len(lines) && (strings.HasPrefix(lines[end], " ") || strings.HasPrefix(lines[end], "\t")) {
+				end++
+			}
+			lines = append(lines[:i], append([]string{line}, lines[end:]...)...)
+		} else {
+			added = append(added, line)
+		}
+	}
+	lines = append(added, lines...)
+	content := strings.Join(lines, "\n") + "\n"
+
+	// Refuse to write a file crawlr could no longer read
+	var check map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &check); err != nil {
+		return fmt.Errorf("failed to update %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// topLevelKeyLine returns the index of the line defining a top-level key, or -1
+func topLevelKeyLine(lines []string, key string) int {
+	for i, line := range lines {
+		if strings.HasPrefix(line, key+":") {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// UserConfigDir returns the directory of the configuration of the user,
+// $XDG_CONFIG_HOME/crawlr or ~/.config/crawlr, or an empty string if the home
+// directory is unknown
+func UserConfigDir() string {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// UserConfigPath returns the configuration file of the user, which crawlr
+// config init writes, or an empty string if the home directory is unknown
+func UserConfigPath() string {
+	dir := UserConfigDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "config.yaml")
+}
+
+// DefaultLogPath returns the log file written when log_file_path is empty,
+// $XDG_STATE_HOME/crawlr/crawlr.log or ~/.local/state/crawlr/crawlr.log, or
+// crawlr.log if the home directory is unknown
+func DefaultLogPath() string {
+	dir := xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+	if dir == "" {
+		return "crawlr.log"
+	}
+	return filepath.Join(dir, "crawlr.log")
+}
+
+// xdgDir returns the crawlr directory of an XDG base directory: the one set
+// by the environment variable env, or home relative to the home directory
+func xdgDir(env, home string) string {
+	if base := os.Getenv(env); filepath.IsAbs(base) {
+		return filepath.Join(base, "crawlr")
+	}
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, home, "crawlr")
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Preset holds the crawl settings tuned for a documentation platform
+type Preset struct {
+	Name             string
+	Description      string
+	CSSSelector      string // page content converted to markdown
+	ExcludedSelector string // navigation and chrome left out of the markdown
+	ExcludePatterns  string // URLs that are not documentation pages
+	// PaginationPattern matches the "next page" links of the platform, which
+	// are followed without increasing the crawl depth
+	PaginationPattern string
+
+	// markers identify pages built with the platform, such as generator meta tags
+	markers []*regexp.Regexp
+}
+
+// presets are the presets shipped with crawlr, keyed by name
+var presets = map[string]Preset{
+	"readthedocs": {
+		Name:              "readthedocs",
+		Description:       "Read the Docs and other Sphinx sites",
+		CSSSelector:       "div[role='main']",
+		ExcludedSelector:  "a.headerlink, .rst-versions, .rst-footer-buttons, div[role='navigation']",
+		ExcludePatterns:   `/_sources/|/_static/|/_modules/|/genindex\.html|/py-modindex\.html|/search\.html`,
+		PaginationPattern: `rel=["']next["']`,
+		markers: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)READTHEDOCS_DATA|readthedocs-addons|sphinx_rtd_theme`),
+			regexp.MustCompile(`(?i)<meta[^>]+content=["']Sphinx\b`),
+		},
+	},
+	"docusaurus": {
+		Name:              "docusaurus",
+		Description:       "Docusaurus v2 and v3 sites",
+		CSSSelector:       "article",
+		ExcludedSelector:  ".theme-doc-breadcrumbs, .theme-doc-toc-mobile, .theme-edit-this-page, .theme-last-updated, .pagination-nav, .hash-link",
+		ExcludePatterns:   `/tags(/|$)|/search(/|$)|/blog/page/`,
+		PaginationPattern: `pagination-nav__link--next`,
+		markers: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)<meta[^>]+content=["']Docusaurus\b`),
+			regexp.MustCompile(`id=["']__docusaurus["']`),
+		},
+	},
+	"gitbook": {
+		Name:              "gitbook",
+		Description:       "GitBook published spaces",
+		CSSSelector:       "main",
+		ExcludedSelector:  "header, aside, nav, [data-testid='page-footer-navigation']",
+		ExcludePatterns:   `/~gitbook/|\?fallback=true|/pdf(/|\?|$)`,
+		PaginationPattern: `(?i)>\s*next\s*<`,
+		markers: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)<meta[^>]+content=["']GitBook\b`),
+			regexp.MustCompile(`/~gitbook/`),
+		},
+	},
+	"mkdocs": {
+		Name:              "mkdocs",
+		Description:       "MkDocs sites, including Material for MkDocs",
+		CSSSelector:       "div[role='main'], article.md-content__inner",
+		ExcludedSelector:  "a.headerlink, .md-source-file, .md-content__button, .md-footer",
+		ExcludePatterns:   `/search(\.html|/)|/404\.html|/assets/|/sitemap\.xml`,
+		PaginationPattern: `md-footer__link--next|rel=["']next["']`,
+		markers: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)<meta[^>]+content=["']mkdocs-`),
+			regexp.MustCompile(`data-md-component=`),
+		},
+	},
+	"confluence": {
+		Name:             "confluence",
+		Description:      "Public Confluence spaces",
+		CSSSelector:      "#main-content",
+		ExcludedSelector: "#likes-and-labels-container, #comments-section, .page-metadata, #page-metadata-banner",
+		ExcludePatterns:  `/pages/viewpreviousversions|/pages/diffpages|/pages/viewpageattachments|/pages/viewinfo|/spaces/flyingpdf|/login\.action|os_destination=|/display/~`,
+		markers: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)<meta[^>]+name=["']confluence-base-url["']`),
+			regexp.MustCompile(`(?i)<meta[^>]+name=["']ajs-page-id["']`),
+		},
+	},
+}
+
+// PresetNames returns the names of the shipped presets in alphabetical order
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetPreset returns the preset with the given name
+func GetPreset(name string) (Preset, bool) {
+	preset, ok := presets[strings.ToLower(name)]
+	return preset, ok
+}
+
+// DetectPreset returns the preset of the documentation platform a page was
+// built with, judging from generator meta tags and platform-specific markup
+func DetectPreset(html string) (Preset, bool) {
+	for _, name := range PresetNames() {
+		preset := presets[name]
+		for _, marker := range preset.markers {
+			if marker.MatchString(html) {
+				return preset, true
+			}
+		}
+	}
+	return Preset{}, false
+}
+
+// UsePreset selects a preset and applies it to the configuration
+func (c *Config) UsePreset(name string) {
+	c.Preset = name
+	c.applyPreset()
+}
+
+// merge returns the value of a configuration key once the preset is applied:
+// selectors and the pagination pattern left empty take the value of the
+// preset, and its exclude patterns are added to the configured ones
+func (p Preset) merge(key string, value string) string {
+	var presetValue string
+	switch key {
+	case "css_selector":
+		presetValue = p.CSSSelector
+	case "excluded_selector":
+		presetValue = p.ExcludedSelector
+	case "pagination_pattern":
+		presetValue = p.PaginationPattern
+	case "exclude_patterns":
+		if value != "" && p.ExcludePatterns != "" {
+			return value + "|" + p.ExcludePatterns
+		}
+		presetValue = p.ExcludePatterns
+	default:
+		return value
+	}
+	if value == "" {
+		return presetValue
+	}
+	return value
+}
+
+// applyPreset applies the configured preset. Unknown presets are left to
+// Validate.
+func (c *Config) applyPreset() {
+	preset, ok := GetPreset(c.Preset)
+	if !ok {
+		return
+	}
+	c.CSSSelector = preset.merge("css_selector", c.CSSSelector)
+	c.ExcludedSelector = preset.merge("excluded_selector", c.ExcludedSelector)
+	c.PaginationPattern = preset.merge("pagination_pattern", c.PaginationPattern)
+	c.ExcludePatterns = preset.merge("exclude_patterns", c.ExcludePatterns)
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadSeeds reads start URLs from r, one per line. Blank lines are skipped,
+// as are comments from a # at the start of a line or after the URL.
+func ReadSeeds(r io.Reader) ([]string, error) {
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 1 && !strings.HasPrefix(fields[1], "#") {
+			return nil, fmt.Errorf("line %d: expected one URL, got %q", line, scanner.Text())
+		}
+		urls = append(urls, fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// ResolveSeeds adds the URLs of seeds_file to seeds and, without url, makes
+// the first seed the start URL, so that url is followed by the other start
+// URLs of the crawl, each once
+func (c *Config) ResolveSeeds() error {
+	seeds := c.Seeds
+	if c.SeedsFile != "" {
+		f, err := os.Open(c.SeedsFile)
+		if err != nil {
+			return fmt.Errorf("failed to open seeds file: %w", err)
+		}
+		defer f.Close()
+		urls, err := ReadSeeds(f)
+		if err != nil {
+			return fmt.Errorf("failed to read seeds file %s: %w", c.SeedsFile, err)
+		}
+		seeds = append(seeds, urls...)
+	}
+
+	seen := make(map[string]bool)
+	if c.URL != "" {
+		seen[c.URL] = true
+	}
+	c.Seeds = nil
+	for _, url := range seeds {
+		if seen[url] {
+			continue
+		}
+		seen[url] = true
+		if c.URL == "" {
+			c.URL = url
+		} else {
+			c.Seeds = append(c.Seeds, url)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,418 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"crawlr/internal/i18n"
+)
+
+// FieldError describes a single invalid configuration value
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Error implements the error interface
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors collects every violation found while validating a configuration
+type ValidationErrors []FieldError
+
+// Error implements the error interface
+func (v ValidationErrors) Error() string {
+	var parts []string
+	for _, e := range v {
+		parts = append(parts, e.Error())
+	}
+	return fmt.Sprintf("%d configuration error(s): %s", len(v), strings.Join(parts, "; "))
+}
+
+// validator accumulates field errors so that all of them can be reported together
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) addf(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf(field, "is required")
+	}
+}
+
+func (v *validator) positive(field string, value int) {
+	if value <= 0 {
+		v.addf(field, "must be greater than 0, got %d", value)
+	}
+}
+
+func (v *validator) nonNegative(field string, value int) {
+	if value < 0 {
+		v.addf(field, "must not be negative, got %d", value)
+	}
+}
+
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.addf(field, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+func (v *validator) httpURL(field, value string) {
+	if value == "" {
+		return
+	}
+	u, err := neturl.Parse(value)
+	if err != nil {
+		v.addf(field, "is not a valid URL: %v", err)
+		return
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		v.addf(field, "must use http or https, got %q", value)
+		return
+	}
+	if u.Host == "" {
+		v.addf(field, "must include a host, got %q", value)
+	}
+}
+
+func (v *validator) regex(field, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := regexp.Compile(value); err != nil {
+		v.addf(field, "is not a valid regular expression: %v", err)
+	}
+}
+
+func (v *validator) exclusive(field string, a, b string, aSet, bSet bool) {
+	if aSet && bSet {
+		v.addf(field, "%s and %s are mutually exclusive", a, b)
+	}
+}
+
+func (v *validator) extractionFields(field string, fields []ExtractionField) {
+	for i, f := range fields {
+		name := fmt.Sprintf("%s[%d]", field, i)
+		v.required(name+".name", f.Name)
+		v.oneOf(name+".type", f.Type, "text", "attribute", "html", "regex", "nested", "list")
+		switch f.Type {
+		case "attribute":
+			v.required(name+".attribute", f.Attribute)
+		case "regex":
+			v.required(name+".pattern", f.Pattern)
+			v.regex(name+".pattern", f.Pattern)
+		case "nested", "list":
+			if len(f.Fields) == 0 {
+				v.addf(name+".fields", "is required for %s fields", f.Type)
+			}
+			v.extractionFields(name+".fields", f.Fields)
+		}
+	}
+}
+
+func (v *validator) transformStep(field string, step TransformStep) {
+	set := 0
+	for _, ok := range []bool{step.StripSelector != "", step.RegexReplace != nil, len(step.InjectFrontMatter) > 0, step.RewriteLinks != nil, step.Truncate != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		v.addf(field, "must set exactly one of strip_selector, regex_replace, inject_frontmatter, rewrite_links, truncate")
+		return
+	}
+
+	switch {
+	case step.RegexReplace != nil:
+		v.required(field+".regex_replace.pattern", step.RegexReplace.Pattern)
+		v.regex(field+".regex_replace.pattern", step.RegexReplace.Pattern)
+	case step.RewriteLinks != nil:
+		v.required(field+".rewrite_links.pattern", step.RewriteLinks.Pattern)
+		v.regex(field+".rewrite_links.pattern", step.RewriteLinks.Pattern)
+	case step.Truncate != nil:
+		v.positive(field+".truncate.max_chars", step.Truncate.MaxChars)
+	}
+}
+
+func (v *validator) auth(field string, a AuthConfig) {
+	switch strings.ToLower(a.Type) {
+	case "basic":
+		if a.User == "" {
+			v.addf(field+".user", "is required for basic auth")
+		}
+		v.exclusive(field, "password", "token", a.Password != "", a.Token != "")
+	case "bearer", "api_key", "apikey":
+		if a.Token == "" {
+			v.addf(field+".token", "is required for %s auth", a.Type)
+		}
+		v.exclusive(field, "user", "token", a.User != "", a.Token != "")
+	default:
+		v.addf(field+".type", "must be one of basic, bearer, api_key, got %q", a.Type)
+	}
+}
+
+func (v *validator) domainPolicy(field, domain string, p DomainPolicy) {
+	if domain == "" || strings.ContainsAny(domain, "/:*") {
+		v.addf(field, "must be a domain such as docs.example.com, got %q", domain)
+	}
+	if p.RateLimit < 0 {
+		v.addf(field+".rate_limit", "must not be negative, got %g", p.RateLimit)
+	}
+	for name := range p.Headers {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, " :") {
+			v.addf(field+".headers", "has an invalid header name %q", name)
+		}
+	}
+	if p.Auth != nil {
+		v.auth(field+".auth", *p.Auth)
+	}
+	if p.MaxDepth != nil {
+		v.nonNegative(field+".max_depth", *p.MaxDepth)
+	}
+}
+
+// tlsFiles loads the CA bundle and the client certificate, so that a crawl
+// never falls back to the default TLS settings without them
+func (v *validator) tlsFiles(c *Config) {
+	if c.TLSCAFile != "" {
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			v.addf("tls_ca_file", "cannot be read: %v", err)
+		} else if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			v.addf("tls_ca_file", "holds no PEM certificate: %s", c.TLSCAFile)
+		}
+	}
+	if c.TLSCertFile != "" && c.TLSKeyFile != "" {
+		if _, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile); err != nil {
+			v.addf("tls_cert_file", "cannot be loaded with tls_key_file: %v", err)
+		}
+	}
+}
+
+func (v *validator) execHook(field, hook string) {
+	if command, ok := strings.CutPrefix(hook, "exec:"); !ok || strings.TrimSpace(command) == "" {
+		v.addf(field, "must be exec:<command>, got %q", hook)
+	}
+}
+
+// Validate checks the configuration for out-of-range values, malformed URLs and
+// patterns, and conflicting options. All violations are returned together as
+// ValidationErrors, or nil if the configuration is valid.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	// Required parameters; seeds may give the start URL
+	if len(c.Seeds) == 0 && c.SeedsFile == "" {
+		v.required("url", c.URL)
+	}
+	v.required("library", c.Library)
+	v.required("output", c.Output)
+
+	// Well-formed URLs
+	v.required("server_url", c.ServerURL)
+	v.httpURL("server_url", c.ServerURL)
+	v.httpURL("replay_server", c.ReplayServer)
+	v.httpURL("url", c.URL)
+	for i, seed := range c.Seeds {
+		v.httpURL(fmt.Sprintf("seeds[%d]", i), seed)
+	}
+
+	// Numeric ranges
+	v.positive("timeout", c.Timeout)
+	v.positive("max_concurrent", c.MaxConcurrent)
+	v.nonNegative("max_idle_conns_per_host", c.MaxIdleConnsPerHost)
+	v.nonNegative("idle_conn_timeout", c.IdleConnTimeout)
+	v.nonNegative("max_depth", c.MaxDepth)
+	if c.MediaRateLimit < 0 {
+		v.addf("media_rate_limit", "must not be negative, got %g", c.MediaRateLimit)
+	}
+	v.nonNegative("media_backlog", c.MediaBacklog)
+	v.nonNegative("retry_max", c.RetryMax)
+	if c.RetryBackoff < 0 {
+		v.addf("retry_backoff", "must not be negative, got %g", c.RetryBackoff)
+	}
+	if c.RetryMultiplier < 1 {
+		v.addf("retry_multiplier", "must be at least 1, got %g", c.RetryMultiplier)
+	}
+	if c.RetryMaxBackoff < c.RetryBackoff {
+		v.addf("retry_max_backoff", "must not be less than retry_backoff (%g), got %g", c.RetryBackoff, c.RetryMaxBackoff)
+	}
+	if c.RetryJitter < 0 || c.RetryJitter > 1 {
+		v.addf("retry_jitter", "must be between 0 and 1, got %g", c.RetryJitter)
+	}
+	v.nonNegative("max_retry_after", c.MaxRetryAfter)
+	v.nonNegative("breaker_threshold", c.BreakerThreshold)
+	v.nonNegative("breaker_cooldown", c.BreakerCooldown)
+	v.positive("batch_size", c.BatchSize)
+	v.positive("max_urls", c.MaxURLs)
+	v.nonNegative("max_duration", c.MaxDuration)
+	v.nonNegative("max_pages_per_domain", c.MaxPagesPerDomain)
+	v.nonNegative("max_pagination_pages", c.MaxPaginationPages)
+	v.positive("crawl_tree_interval", c.CrawlTreeInterval)
+	v.nonNegative("expect_min_pages", c.ExpectMinPages)
+	if c.MaxErrorRate < 0 || c.MaxErrorRate > 100 {
+		v.addf("max_error_rate", "must be a percentage between 0 and 100, got %g", c.MaxErrorRate)
+	}
+	if c.BatchSize > 0 && c.MaxURLs > 0 && c.BatchSize > c.MaxURLs {
+		v.addf("batch_size", "must not exceed max_urls (%d), got %d", c.MaxURLs, c.BatchSize)
+	}
+
+	// Enumerations
+	v.oneOf("api_version", c.APIVersion, "auto", "0.4", "0.5", "0.6")
+	v.oneOf("engine", c.Engine, "crawl4ai", "local")
+	if c.TLSCertFile != "" && c.TLSKeyFile == "" {
+		v.addf("tls_key_file", "is required with tls_cert_file")
+	}
+	if c.TLSKeyFile != "" && c.TLSCertFile == "" {
+		v.addf("tls_cert_file", "is required with tls_key_file")
+	}
+	v.tlsFiles(c)
+	v.oneOf("fallback", c.Fallback, "none", "local")
+	for i, agent := range c.UserAgents {
+		field := fmt.Sprintf("user_agents[%d]", i)
+		v.required(field, strings.TrimSpace(agent))
+		if strings.ContainsAny(agent, "\r\n") {
+			v.addf(field, "must be a single line")
+		}
+	}
+	v.oneOf("discovery_method", c.DiscoveryMethod, "auto", "sitemap", "links", "feed", "none")
+	v.oneOf("strategy", c.Strategy, "bfs", "dfs", "bestfirst")
+	v.oneOf("log_level", c.LogLevel, "DEBUG", "INFO", "WARN", "ERROR")
+	v.oneOf("log_output", c.LogOutput, "console", "file", "both")
+	v.oneOf("log_format", c.LogFormat, "text", "json")
+	v.oneOf("errors_format", c.ErrorsFormat, "text", "json")
+	v.oneOf("language", c.Language, i18n.Languages()...)
+
+	v.oneOf("opt_out_policy", c.OptOutPolicy, "ignore", "warn", "skip")
+	v.oneOf("sync_removed", c.SyncRemoved, "move", "delete")
+
+	if c.Archive != "" {
+		v.oneOf("archive", c.Archive, "zip", "tar.gz")
+	} else if c.ArchiveOnly {
+		v.addf("archive_only", "requires archive to be set")
+	}
+	v.nonNegative("snapshot_keep", c.SnapshotKeep)
+
+	if c.Export != "" {
+		v.oneOf("export", c.Export, "jsonl")
+	}
+
+	// Crawl budget
+	if _, err := ParseSize(c.BudgetBytes); err != nil {
+		v.addf("budget_bytes", "must be a size such as 500MB or 5GB, got %q", c.BudgetBytes)
+	}
+	v.nonNegative("budget_time", c.BudgetTime)
+	v.oneOf("budget_policy", c.BudgetPolicy, "pages", "media", "balanced")
+
+	// Media filters
+	if _, err := ParseSize(c.MaxResponseSize); err != nil {
+		v.addf("max_response_size", "must be a size such as 500MB or 1GB, got %q", c.MaxResponseSize)
+	}
+	if _, err := ParseSize(c.MemoryBudget); err != nil {
+		v.addf("memory_budget", "must be a size such as 256MB or 1GB, got %q", c.MemoryBudget)
+	}
+	if _, err := ParseSize(c.MediaMaxSize); err != nil {
+		v.addf("media_max_size", "must be a size such as 500KB or 2MB, got %q", c.MediaMaxSize)
+	}
+	for _, mediaType := range SplitList(c.MediaTypes) {
+		v.oneOf("media_types", mediaType, "image", "video", "audio", "document")
+	}
+	v.oneOf("media_layout", c.MediaLayout, "path", "cas")
+
+	// Presets and patterns
+	if c.Preset != "" {
+		if _, ok := GetPreset(c.Preset); !ok {
+			v.oneOf("preset", c.Preset, PresetNames()...)
+		}
+	}
+	v.regex("exclude_patterns", c.ExcludePatterns)
+	v.regex("pagination_pattern", c.PaginationPattern)
+	for i, pattern := range c.ExpectURLs {
+		v.regex(fmt.Sprintf("expect_urls[%d]", i), pattern)
+	}
+
+	// Webhooks
+	for i, webhookURL := range c.WebhookURLs {
+		field := fmt.Sprintf("webhook_urls[%d]", i)
+		v.required(field, webhookURL)
+		v.httpURL(field, webhookURL)
+	}
+	v.nonNegative("webhook_every", c.WebhookEvery)
+	v.positive("webhook_timeout", c.WebhookTimeout)
+	v.httpURL("notifications.slack.webhook_url", c.Notifications.Slack.WebhookURL)
+	v.httpURL("notifications.discord.webhook_url", c.Notifications.Discord.WebhookURL)
+
+	// Tracing
+	v.httpURL("otlp_endpoint", c.OTLPEndpoint)
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		v.addf("trace_sample_ratio", "must be between 0 and 1, got %g", c.TraceSampleRatio)
+	}
+
+	// Crawl state
+	v.oneOf("state_backend", c.StateBackend, "memory", "redis")
+	if c.StateBackend == "redis" {
+		v.required("redis_url", c.RedisURL)
+	}
+	v.nonNegative("redis_ttl", c.RedisTTL)
+
+	// Origin credentials
+	for domain, a := range c.Auth {
+		v.auth("auth."+domain, a)
+	}
+
+	// Extraction schema
+	if c.Extraction.Enabled() {
+		if c.Extraction.Type != "" {
+			v.oneOf("extraction.type", c.Extraction.Type, "css", "xpath")
+		}
+		v.required("extraction.base_selector", c.Extraction.BaseSelector)
+		v.extractionFields("extraction.fields", c.Extraction.Fields)
+	}
+
+	// Depth overrides
+	for i, rule := range c.DepthRules {
+		field := fmt.Sprintf("depth_rules[%d]", i)
+		if !strings.HasPrefix(rule.Path, "/") {
+			v.addf(field+".path", "must be a URL path starting with /, got %q", rule.Path)
+		}
+		v.nonNegative(field+".max_depth", rule.MaxDepth)
+	}
+
+	// Domain policies
+	for domain, p := range c.Domains {
+		v.domainPolicy("domains."+domain, domain, p)
+	}
+
+	// Transformation pipeline
+	for i, step := range c.Transforms {
+		v.transformStep(fmt.Sprintf("transforms[%d]", i), step)
+	}
+
+	// Page hooks
+	for i, hook := range c.Hooks.PreSave {
+		v.execHook(fmt.Sprintf("hooks.pre_save[%d]", i), hook)
+	}
+	for i, hook := range c.Hooks.PostSave {
+		v.execHook(fmt.Sprintf("hooks.post_save[%d]", i), hook)
+	}
+	v.nonNegative("hooks.timeout", c.Hooks.Timeout)
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
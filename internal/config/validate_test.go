@@ -0,0 +1,161 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		fields []string // fields reported invalid, none for a valid configuration
+	}{
+		{"defaults", func(c *Config) {}, nil},
+		{"required", func(c *Config) { c.URL, c.Library, c.Output = "", "", "" }, []string{"url", "library", "output"}},
+		{"seeds without url", func(c *Config) {
+			c.URL = ""
+			c.Seeds = []string{"https://docs.example.com/guide/"}
+		}, nil},
+		{"invalid seed", func(c *Config) { c.Seeds = []string{"docs.example.com"} }, []string{"seeds[0]"}},
+		{"server url scheme", func(c *Config) { c.ServerURL = "ftp://crawl4ai.internal" }, []string{"server_url"}},
+		{"server url host", func(c *Config) { c.ServerURL = "http://" }, []string{"server_url"}},
+		{"timeout", func(c *Config) { c.Timeout = 0 }, []string{"timeout"}},
+		{"max depth", func(c *Config) { c.MaxDepth = -1 }, []string{"max_depth"}},
+		{"batch size over max urls", func(c *Config) { c.BatchSize, c.MaxURLs = 20, 10 }, []string{"batch_size"}},
+		{"retry jitter", func(c *Config) { c.RetryJitter = 1.5 }, []string{"retry_jitter"}},
+		{"retry max backoff", func(c *Config) { c.RetryBackoff, c.RetryMaxBackoff = 10, 5 }, []string{"retry_max_backoff"}},
+		{"log level", func(c *Config) { c.LogLevel = "TRACE" }, []string{"log_level"}},
+		{"discovery method", func(c *Config) { c.DiscoveryMethod = "crawl" }, []string{"discovery_method"}},
+		{"exclude pattern", func(c *Config) { c.ExcludePatterns = "(" }, []string{"exclude_patterns"}},
+		{"expect urls", func(c *Config) { c.ExpectURLs = []string{"/api/", "["} }, []string{"expect_urls[1]"}},
+		{"budget bytes", func(c *Config) { c.BudgetBytes = "lots" }, []string{"budget_bytes"}},
+		{"archive only", func(c *Config) { c.Archive, c.ArchiveOnly = "", true }, []string{"archive_only"}},
+		{"tls key", func(c *Config) { c.TLSCertFile = "cert.pem" }, []string{"tls_key_file"}},
+		{"redis url", func(c *Config) { c.StateBackend, c.RedisURL = "redis", "" }, []string{"redis_url"}},
+		{"webhook url", func(c *Config) { c.WebhookURLs = []string{"hooks.example.com"} }, []string{"webhook_urls[0]"}},
+		{"basic auth", func(c *Config) {
+			c.Auth = map[string]AuthConfig{"docs.example.com": {Type: "basic", Password: "secret", Token: "token"}}
+		}, []string{"auth.docs.example.com.user", "auth.docs.example.com"}},
+		{"auth type", func(c *Config) {
+			c.Auth = map[string]AuthConfig{"docs.example.com": {Type: "digest"}}
+		}, []string{"auth.docs.example.com.type"}},
+		{"domain policy", func(c *Config) {
+			c.Domains = map[string]DomainPolicy{"docs.example.com": {RateLimit: -1, Headers: map[string]string{"X Trace": "1"}}}
+		}, []string{"domains.docs.example.com.rate_limit", "domains.docs.example.com.headers"}},
+		{"domain name", func(c *Config) {
+			c.Domains = map[string]DomainPolicy{"https://docs.example.com": {}}
+		}, []string{"domains.https://docs.example.com"}},
+		{"transform step", func(c *Config) {
+			c.Transforms = []TransformStep{{StripSelector: "nav", Truncate: &TruncateStep{MaxChars: 100}}}
+		}, []string{"transforms[0]"}},
+		{"hook", func(c *Config) { c.Hooks.PreSave = []string{"./redact.sh"} }, []string{"hooks.pre_save[0]"}},
+		{"several", func(c *Config) { c.Timeout, c.LogFormat = -1, "xml" }, []string{"timeout", "log_format"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidate(t, tt.modify, tt.fields)
+		})
+	}
+}
+
+func TestValidateTLSFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "client")
+	_, otherKeyFile := writeKeyPair(t, dir, "other")
+	garbageFile := filepath.Join(dir, "garbage.pem")
+	if err := os.WriteFile(garbageFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		fields []string
+	}{
+		{"valid", func(c *Config) { c.TLSCAFile, c.TLSCertFile, c.TLSKeyFile = certFile, certFile, keyFile }, nil},
+		{"unreadable CA file", func(c *Config) { c.TLSCAFile = filepath.Join(dir, "missing.pem") }, []string{"tls_ca_file"}},
+		{"CA file without certificate", func(c *Config) { c.TLSCAFile = garbageFile }, []string{"tls_ca_file"}},
+		{"mismatched key", func(c *Config) { c.TLSCertFile, c.TLSKeyFile = certFile, otherKeyFile }, []string{"tls_cert_file"}},
+		{"invalid key", func(c *Config) { c.TLSCertFile, c.TLSKeyFile = certFile, garbageFile }, []string{"tls_cert_file"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidate(t, tt.modify, tt.fields)
+		})
+	}
+}
+
+// checkValidate validates a valid configuration changed by modify, expecting
+// the given fields to be reported invalid
+func checkValidate(t *testing.T, modify func(c *Config), fields []string) {
+	t.Helper()
+	c := DefaultConfig()
+	c.URL = "https://docs.example.com/"
+	c.Library = "docs"
+	c.Output = "./libraries"
+	modify(c)
+
+	err := c.Validate()
+	var invalid ValidationErrors
+	if err != nil && !errors.As(err, &invalid) {
+		t.Fatalf("Validate() = %v, want ValidationErrors", err)
+	}
+	var got []string
+	for _, fieldErr := range invalid {
+		got = append(got, fieldErr.Field)
+	}
+	slices.Sort(got)
+	want := slices.Clone(fields)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("Validate() reported %v (%v), want %v", got, err, want)
+	}
+}
+
+// writeKeyPair writes a self-signed certificate and its key to dir, returning
+// their paths
+func writeKeyPair(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
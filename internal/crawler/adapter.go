@@ -0,0 +1,274 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultAPIVersion is the crawl4ai API spoken to servers whose version is
+// unknown
+const defaultAPIVersion = "0.5"
+
+// apiAdapter translates /crawl requests and responses between crawlr and one
+// generation of the crawl4ai REST API, whose schema changes between releases
+type apiAdapter interface {
+	// version returns the first crawl4ai version speaking the API
+	version() string
+	// encodeRequest returns the body of a /crawl request
+	encodeRequest(req StartCrawlRequest) ([]byte, error)
+	// decodeResponse decodes the body of a /crawl response
+	decodeResponse(body []byte) (*StartCrawlResponse, error)
+}
+
+// apiAdapters are the adapters of the supported APIs, keyed by version
+var apiAdapters = map[string]apiAdapter{
+	"0.4": legacyAPI{},
+	"0.5": flatAPI{},
+	"0.6": typedAPI{},
+}
+
+// adapterFor returns the adapter of the API of a crawl4ai release, or nil if
+// version cannot be parsed
+func adapterFor(version string) apiAdapter {
+	major, minor, ok := parseAPIVersion(version)
+	switch {
+	case !ok:
+		return nil
+	case major == 0 && minor < 5:
+		return apiAdapters["0.4"]
+	case major == 0 && minor == 5:
+		return apiAdapters["0.5"]
+	default:
+		return apiAdapters["0.6"]
+	}
+}
+
+// parseAPIVersion returns the major and minor numbers of a version such as
+// "0.6.3" or "v0.5.1-d1"
+func parseAPIVersion(version string) (major, minor int, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(strings.TrimRightFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' }))
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// crawlAPI returns the adapter of the API of the crawl4ai server: that of the
+// configured api_version, or of the version its health endpoint reports,
+// detected once before the first crawl request. Servers that do not report a
+// known version get the API of defaultAPIVersion.
+func (c *Crawler) crawlAPI(ctx context.Context) apiAdapter {
+	c.apiOnce.Do(func() {
+		c.api = c.detectAPI(ctx)
+	})
+	return c.api
+}
+
+// detectAPI selects the adapter of the crawl4ai server
+func (c *Crawler) detectAPI(ctx context.Context) apiAdapter {
+	if adapter, ok := apiAdapters[c.apiVersion]; ok {
+		return adapter
+	}
+
+	health, err := c.Health(ctx)
+	if err != nil {
+		c.logger.Warn("Failed to detect the crawl4ai version", map[string]interface{}{
+			"error":      err,
+			"apiVersion": defaultAPIVersion,
+		})
+		return apiAdapters[defaultAPIVersion]
+	}
+	adapter := adapterFor(health.Version)
+	if adapter == nil {
+		c.logger.Info("Unknown crawl4ai version, assuming the default API", map[string]interface{}{
+			"serverVersion": health.Version,
+			"apiVersion":    defaultAPIVersion,
+		})
+		return apiAdapters[defaultAPIVersion]
+	}
+	c.logger.Info("Detected crawl4ai version", map[string]interface{}{
+		"serverVersion": health.Version,
+		"apiVersion":    adapter.version(),
+	})
+	return adapter
+}
+
+// flatAPI is the API of crawl4ai 0.5, taking the crawler and browser
+// configurations as plain objects
+type flatAPI struct{}
+
+func (flatAPI) version() string { return "0.5" }
+
+func (flatAPI) encodeRequest(req StartCrawlRequest) ([]byte, error) {
+	return json.Marshal(req)
+}
+
+func (flatAPI) decodeResponse(body []byte) (*StartCrawlResponse, error) {
+	var result StartCrawlResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// typedConfig is a configuration object of crawl4ai 0.6 and later, naming
+// the class it is loaded into
+type typedConfig struct {
+	Type   string      `json:"type"`
+	Params interface{} `json:"params"`
+}
+
+// typedAPI is the API of crawl4ai 0.6 and later, taking the crawler and
+// browser configurations as typed objects
+type typedAPI struct{}
+
+func (typedAPI) version() string { return "0.6" }
+
+func (typedAPI) encodeRequest(req StartCrawlRequest) ([]byte, error) {
+	var browserConfig *typedConfig
+	if req.BrowserConfig != nil {
+		browserConfig = &typedConfig{Type: "BrowserConfig", Params: req.BrowserConfig}
+	}
+	return json.Marshal(struct {
+		Urls               []string     `json:"urls"`
+		IncludeRawHTML     bool         `json:"include_raw_html,omitempty"`
+		WordCountThreshold int          `json:"word_count_threshold,omitempty"`
+		Priority           int          `json:"priority,omitempty"`
+		TTL                int          `json:"ttl,omitempty"`
+		CrawlerConfig      typedConfig  `json:"crawler_config"`
+		ProcessURLs        bool         `json:"process_urls,omitempty"`
+		BrowserConfig      *typedConfig `json:"browser_config,omitempty"`
+	}{
+		Urls:               req.Urls,
+		IncludeRawHTML:     req.IncludeRawHTML,
+		WordCountThreshold: req.WordCountThreshold,
+		Priority:           req.Priority,
+		TTL:                req.TTL,
+		CrawlerConfig:      typedConfig{Type: "CrawlerRunConfig", Params: req.CrawlerConfig},
+		ProcessURLs:        req.ProcessURLs,
+		BrowserConfig:      browserConfig,
+	})
+}
+
+func (typedAPI) decodeResponse(body []byte) (*StartCrawlResponse, error) {
+	return flatAPI{}.decodeResponse(body)
+}
+
+// legacyAPI is the API of crawl4ai before 0.5, taking the crawler settings
+// and browser headers as crawler_params and returning the markdown of a page
+// as a string, with the markdown object as markdown_v2
+type legacyAPI struct{}
+
+func (legacyAPI) version() string { return "0.4" }
+
+func (legacyAPI) encodeRequest(req StartCrawlRequest) ([]byte, error) {
+	params, err := toParams(req.CrawlerConfig)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range req.BrowserConfig {
+		params[name] = value
+	}
+	return json.Marshal(struct {
+		Urls               []string               `json:"urls"`
+		IncludeRawHTML     bool                   `json:"include_raw_html,omitempty"`
+		WordCountThreshold int                    `json:"word_count_threshold,omitempty"`
+		Priority           int                    `json:"priority,omitempty"`
+		TTL                int                    `json:"ttl,omitempty"`
+		CrawlerParams      map[string]interface{} `json:"crawler_params,omitempty"`
+		ProcessURLs        bool                   `json:"process_urls,omitempty"`
+	}{
+		Urls:               req.Urls,
+		IncludeRawHTML:     req.IncludeRawHTML,
+		WordCountThreshold: req.WordCountThreshold,
+		Priority:           req.Priority,
+		TTL:                req.TTL,
+		CrawlerParams:      params,
+		ProcessURLs:        req.ProcessURLs,
+	})
+}
+
+func (legacyAPI) decodeResponse(body []byte) (*StartCrawlResponse, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+	if _, ok := envelope["task_id"]; ok && envelope["results"] == nil && envelope["result"] == nil {
+		return nil, fmt.Errorf("the server queued the crawl as a task; crawlr needs a crawl4ai server answering /crawl synchronously")
+	}
+
+	// A single result is returned as result rather than results
+	results := envelope["results"]
+	if results == nil && envelope["result"] != nil {
+		results = append(append(json.RawMessage("["), envelope["result"]...), ']')
+	}
+	var pages []map[string]json.RawMessage
+	if results != nil {
+		if err := json.Unmarshal(results, &pages); err != nil {
+			return nil, err
+		}
+	}
+	for _, page := range pages {
+		if err := normalizeMarkdown(page); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	delete(envelope, "result")
+	if envelope["results"], err = json.Marshal(pages); err != nil {
+		return nil, err
+	}
+	if body, err = json.Marshal(envelope); err != nil {
+		return nil, err
+	}
+	return flatAPI{}.decodeResponse(body)
+}
+
+// normalizeMarkdown replaces the markdown string of a legacy page result by
+// its markdown_v2 object, or by an object holding the string as raw markdown
+func normalizeMarkdown(page map[string]json.RawMessage) error {
+	if v2, ok := page["markdown_v2"]; ok && string(v2) != "null" {
+		page["markdown"] = v2
+		delete(page, "markdown_v2")
+		return nil
+	}
+	markdown := page["markdown"]
+	if len(markdown) == 0 || markdown[0] != '"' {
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(markdown, &text); err != nil {
+		return err
+	}
+	object, err := json.Marshal(map[string]string{"raw_markdown": text})
+	if err != nil {
+		return err
+	}
+	page["markdown"] = object
+	return nil
+}
+
+// toParams converts a configuration to a map of its JSON fields
+func toParams(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	params := make(map[string]interface{})
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
@@ -0,0 +1,219 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"crawlr/internal/config"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// checkGolden compares got with the golden file at path, rewriting it with
+// -update
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// indentJSON re-encodes JSON indented, for readable golden files
+func indentJSON(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// goldenRequest is the crawl request encoded by every adapter
+var goldenRequest = StartCrawlRequest{
+	Urls:               []string{"https://docs.example.com/", "https://docs.example.com/guide"},
+	WordCountThreshold: 10,
+	CrawlerConfig: CrawlerConfig{
+		MaxDepth:         2,
+		Strategy:         "bfs",
+		CSSSelector:      "main",
+		ExcludedSelector: "nav, footer",
+	},
+	BrowserConfig: map[string]interface{}{
+		"user_agent": "crawlr-test",
+		"headers":    map[string]string{"Accept-Language": "en"},
+	},
+}
+
+func TestAdapterGolden(t *testing.T) {
+	for version, adapter := range apiAdapters {
+		t.Run(version, func(t *testing.T) {
+			dir := filepath.Join("testdata", "adapter", version)
+			if got := adapter.version(); got != version {
+				t.Errorf("version() = %q, want %q", got, version)
+			}
+
+			request, err := adapter.encodeRequest(goldenRequest)
+			if err != nil {
+				t.Fatalf("encodeRequest() error = %v", err)
+			}
+			checkGolden(t, filepath.Join(dir, "request.golden.json"), indentJSON(t, request))
+
+			body, err := os.ReadFile(filepath.Join(dir, "response.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := adapter.decodeResponse(body)
+			if err != nil {
+				t.Fatalf("decodeResponse() error = %v", err)
+			}
+			var decoded bytes.Buffer
+			encoder := json.NewEncoder(&decoded)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(response); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, filepath.Join(dir, "result.golden.json"), decoded.Bytes())
+		})
+	}
+}
+
+func TestLegacyDecodeResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		markdown []string // raw markdown of each page
+		err      string
+	}{
+		{
+			name:     "single result",
+			body:     `{"success": true, "result": {"url": "https://docs.example.com/", "success": true, "markdown": "# Docs"}}`,
+			markdown: []string{"# Docs"},
+		},
+		{
+			name:     "null markdown_v2",
+			body:     `{"success": true, "results": [{"url": "https://docs.example.com/", "markdown": "# Docs", "markdown_v2": null}]}`,
+			markdown: []string{"# Docs"},
+		},
+		{
+			name: "no results",
+			body: `{"success": false}`,
+		},
+		{
+			name: "queued task",
+			body: `{"task_id": "abc123"}`,
+			err:  "queued the crawl as a task",
+		},
+		{
+			name: "invalid body",
+			body: `<html>`,
+			err:  "invalid character",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := legacyAPI{}.decodeResponse([]byte(tt.body))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("decodeResponse() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeResponse() error = %v", err)
+			}
+			var markdown []string
+			for _, page := range response.Results {
+				markdown = append(markdown, page.Markdown.RawMarkdown)
+			}
+			if strings.Join(markdown, "|") != strings.Join(tt.markdown, "|") || len(markdown) != len(tt.markdown) {
+				t.Errorf("markdown = %q, want %q", markdown, tt.markdown)
+			}
+		})
+	}
+}
+
+func TestAdapterFor(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string // version of the adapter, empty for none
+	}{
+		{"0.4.248", "0.4"},
+		{"0.3", "0.4"},
+		{"0.5.0", "0.5"},
+		{"v0.5.1-d1", "0.5"},
+		{" 0.6.3 ", "0.6"},
+		{"0.7.0", "0.6"},
+		{"1.0", "0.6"},
+		{"0.6-beta", "0.6"},
+		{"", ""},
+		{"latest", ""},
+		{"0", ""},
+		{"x.5", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			var got string
+			if adapter := adapterFor(tt.version); adapter != nil {
+				got = adapter.version()
+			}
+			if got != tt.want {
+				t.Errorf("adapterFor(%q) = %q, want %q", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectAPI(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiVersion string
+		health     string // body of /health, empty for a failing endpoint
+		want       string
+	}{
+		{"configured", "0.4", `{"status": "ok", "version": "0.6.3"}`, "0.4"},
+		{"detected", "auto", `{"status": "ok", "version": "0.6.3"}`, "0.6"},
+		{"detected legacy", "auto", `{"status": "ok", "version": "0.4.248"}`, "0.4"},
+		{"unknown version", "auto", `{"status": "ok", "version": "nightly"}`, defaultAPIVersion},
+		{"health failing", "auto", "", defaultAPIVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/health" || tt.health == "" {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte(tt.health))
+			}))
+			defer server.Close()
+
+			c := newTestCrawler(t, func(cfg *config.Config) {
+				cfg.ServerURL = server.URL
+				cfg.APIVersion = tt.apiVersion
+			})
+			if got := c.crawlAPI(context.Background()).version(); got != tt.want {
+				t.Errorf("crawlAPI() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package crawler
+
+import (
+	"context"
+	"time"
+
+	"crawlr/internal/budget"
+	"crawlr/internal/progress"
+	"crawlr/internal/storage"
+)
+
+// SetBudget limits the bytes written and the time spent by the crawl,
+// balancing them between pages and media according to the budget's policy
+func (c *Crawler) SetBudget(b *budget.Budget) {
+	c.budget = b
+}
+
+// budgetExhausted reports whether the crawl budget is spent, after which no
+// more batches are crawled
+func (c *Crawler) budgetExhausted() bool {
+	return c.budget.Enabled() && c.budget.Exhausted()
+}
+
+// downloadBudgetedMedia downloads the media files of a page that fit in the
+// crawl budget, counting what they cost. Files left out advance
+// progressReporter as done.
+func (c *Crawler) downloadBudgetedMedia(ctx context.Context, jobs []mediaJob, hints *licenseHints, progressReporter *progress.ProgressReporter) ([]*storage.FileInfo, error) {
+	if !c.budget.Enabled() {
+		return c.downloadMedia(ctx, jobs, hints, progressReporter)
+	}
+
+	allowed := c.budget.AllowMedia(len(jobs))
+	if allowed < len(jobs) {
+		c.logger.Info("Skipping media files to stay within the crawl budget", map[string]interface{}{
+			"skipped": len(jobs) - allowed,
+			"allowed": allowed,
+		})
+		for range jobs[allowed:] {
+			progressReporter.Increment()
+		}
+		jobs = jobs[:allowed]
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+
+	started := time.Now()
+	savedFiles, err := c.downloadMedia(ctx, jobs, hints, progressReporter)
+	var written int64
+	for _, file := range savedFiles {
+		written += file.Size
+	}
+	c.budget.AddMedia(len(jobs), written, time.Since(started))
+	return savedFiles, err
+}
@@ -0,0 +1,40 @@
+package crawler
+
+import (
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+// linkTagPattern matches <link> tags
+var linkTagPattern = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+
+// CanonicalURL returns the absolute canonical URL the page declares with a
+// <link rel="canonical"> tag, without fragment, or an empty string if it
+// declares none
+func (r PageResult) CanonicalURL() string {
+	for _, tag := range linkTagPattern.FindAllString(r.HTML, -1) {
+		if !hasRel(tag, "canonical") {
+			continue
+		}
+		href := hrefPattern.FindStringSubmatch(tag)
+		if href == nil {
+			continue
+		}
+		base, err := neturl.Parse(r.FinalURL())
+		if err != nil {
+			return ""
+		}
+		ref, err := neturl.Parse(strings.TrimSpace(href[1]))
+		if err != nil {
+			return ""
+		}
+		canonical := base.ResolveReference(ref)
+		if canonical.Scheme != "http" && canonical.Scheme != "https" {
+			return ""
+		}
+		canonical.Fragment = ""
+		return canonical.String()
+	}
+	return ""
+}
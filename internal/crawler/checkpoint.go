@@ -0,0 +1,116 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"crawlr/internal/frontier"
+	"crawlr/internal/storage"
+)
+
+// Checkpoint records where a cancelled recursive crawl stopped: how far it
+// got and the URLs it had yet to crawl
+type Checkpoint struct {
+	StartURL    string    `json:"start_url"`
+	CancelledAt time.Time `json:"cancelled_at"`
+	Reason      string    `json:"reason"`
+	Processed   int       `json:"processed"` // pages crawled before cancellation
+	Visited     int       `json:"visited"`   // URLs claimed, crawled or not
+
+	// Pending lists the URLs left to crawl: those of the batch in progress and,
+	// unless the frontier is shared with cooperating crawlers, the frontier
+	Pending []URLWithDepth `json:"pending"`
+}
+
+// writeCheckpoint saves the checkpoint of a cancelled crawl to the library,
+// draining the frontier into it unless it is shared. The cancelled context
+// is only used for its values, so the state can still be read.
+func (c *Crawler) writeCheckpoint(ctx context.Context, checkpoint *Checkpoint, urlFrontier frontier.Frontier, visited frontier.VisitedSet) {
+	if c.storage == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	if _, local := c.stateBackend.(*frontier.MemoryBackend); local {
+		for {
+			items, err := urlFrontier.Pop(ctx, 1000)
+			if err != nil || len(items) == 0 {
+				break
+			}
+			checkpoint.Pending = append(checkpoint.Pending, items...)
+		}
+	}
+	checkpoint.Visited, _ = visited.Len(ctx)
+	if checkpoint.Pending == nil {
+		checkpoint.Pending = []URLWithDepth{}
+	}
+
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err == nil {
+		err = c.storage.WriteLibraryFile(storage.CheckpointFile, append(data, '\n'))
+	}
+	if err != nil {
+		c.logger.Warn("Failed to save crawl checkpoint", map[string]interface{}{"error": err})
+		return
+	}
+	c.logger.Info("Saved crawl checkpoint", map[string]interface{}{
+		"file":    storage.CheckpointFile,
+		"pending": len(checkpoint.Pending),
+	})
+}
+
+// LoadCheckpoint reads the checkpoint of the library stored at libraryPath.
+// The error of a library without checkpoint satisfies os.IsNotExist.
+func LoadCheckpoint(libraryPath string) (*Checkpoint, error) {
+	data, err := os.ReadFile(filepath.Join(libraryPath, storage.CheckpointFile))
+	if os.IsNotExist(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	return &checkpoint, nil
+}
+
+// SetResume makes the next recursive crawl pick up where the crawl of
+// checkpoint stopped, instead of starting over from the start URL
+func (c *Crawler) SetResume(checkpoint *Checkpoint) {
+	c.resume = checkpoint
+}
+
+// resumeCheckpoint seeds the crawl state from the checkpoint to resume: the
+// pages already in the library are claimed so that they are not crawled
+// again, and the pending URLs are put in the frontier
+func (c *Crawler) resumeCheckpoint(ctx context.Context, urlFrontier frontier.Frontier, visited frontier.VisitedSet) error {
+	if c.storage != nil {
+		manifest, err := storage.LoadManifest(c.storage.GetLibraryPath())
+		if err != nil {
+			return err
+		}
+		crawled := make([]string, 0, len(manifest.Pages))
+		for _, entry := range manifest.Pages {
+			crawled = append(crawled, entry.URL)
+		}
+		if _, err := visited.Claim(ctx, crawled); err != nil {
+			return fmt.Errorf("failed to claim crawled pages: %w", err)
+		}
+	}
+	if err := urlFrontier.Push(ctx, c.resume.Pending); err != nil {
+		return fmt.Errorf("failed to seed frontier: %w", err)
+	}
+
+	c.logger.Info("Resuming crawl from checkpoint", map[string]interface{}{
+		"cancelledAt": c.resume.CancelledAt,
+		"pending":     len(c.resume.Pending),
+	})
+	return nil
+}
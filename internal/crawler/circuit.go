@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"crawlr/internal/frontier"
+)
+
+// serverCircuit is the circuit breaker key of the crawl4ai server, whose
+// failed batches are tracked apart from the hosts of the pages
+const serverCircuit = "crawl4ai"
+
+// pageFailed reports whether a page result counts as a failure of its host:
+// the page could not be crawled, or the host was rate limiting or failing
+func pageFailed(result PageResult) bool {
+	return !result.Success || result.StatusCode == http.StatusTooManyRequests || result.StatusCode >= 500
+}
+
+// recordCircuit records the outcome of a request to host, logging when its
+// circuit opens
+func (c *Crawler) recordCircuit(host string, success bool) {
+	if success {
+		c.breaker.Success(host)
+		return
+	}
+	if c.breaker.Failure(host) {
+		c.logger.Warn("Opening circuit after repeated failures", map[string]interface{}{
+			"host":     host,
+			"cooldown": c.breaker.Remaining(host).Round(time.Second).String(),
+		})
+	}
+}
+
+// waitCircuit waits until the circuit of host closes, returning false if ctx
+// was cancelled meanwhile
+func (c *Crawler) waitCircuit(ctx context.Context, host string) bool {
+	wait := c.breaker.Remaining(host)
+	if wait <= 0 {
+		return true
+	}
+	c.logger.Info("Waiting for circuit to close", map[string]interface{}{
+		"host": host,
+		"wait": wait.Round(time.Second).String(),
+	})
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(wait):
+		return true
+	}
+}
+
+// deferOpenCircuits puts URLs of hosts whose circuit is open back in the
+// frontier. When they make up the whole batch, it waits until the first of
+// their circuits closes, so that the crawl does not spin on them. It returns
+// false if the frontier could not be updated or ctx was cancelled.
+func (c *Crawler) deferOpenCircuits(ctx context.Context, urlFrontier frontier.Frontier, deferred []URLWithDepth, wholeBatch bool) bool {
+	if err := urlFrontier.Push(ctx, deferred); err != nil {
+		if ctx.Err() == nil {
+			c.logger.Warn("Failed to put back URLs of open circuits", map[string]interface{}{
+				"count": len(deferred),
+				"error": err,
+			})
+		}
+		return false
+	}
+	c.logger.Debug("Holding back URLs of open circuits", map[string]interface{}{"count": len(deferred)})
+	if !wholeBatch {
+		return true
+	}
+
+	first := deferred[0].URL
+	for _, item := range deferred[1:] {
+		if c.breaker.Remaining(quotaHost(item.URL)) < c.breaker.Remaining(quotaHost(first)) {
+			first = item.URL
+		}
+	}
+	return c.waitCircuit(ctx, quotaHost(first))
+}
@@ -0,0 +1,149 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"crawlr/internal/config"
+	"crawlr/internal/frontier"
+)
+
+// testClock is a manual clock for the circuit breaker of a crawler under test
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newCircuitCrawler returns a crawler whose circuits open after threshold
+// failures for a minute, running on a manual clock
+func newCircuitCrawler(t *testing.T, threshold int) (*Crawler, *testClock) {
+	t.Helper()
+	c := newTestCrawler(t, func(cfg *config.Config) {
+		cfg.BreakerThreshold = threshold
+		cfg.BreakerCooldown = 60
+	})
+	clk := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c.breaker.SetClock(clk.Now)
+	return c, clk
+}
+
+func TestPageFailed(t *testing.T) {
+	tests := []struct {
+		name   string
+		result PageResult
+		want   bool
+	}{
+		{"success", PageResult{Success: true, StatusCode: http.StatusOK}, false},
+		{"not found", PageResult{Success: true, StatusCode: http.StatusNotFound}, false},
+		{"crawl failure", PageResult{Success: false}, true},
+		{"rate limited", PageResult{Success: true, StatusCode: http.StatusTooManyRequests}, true},
+		{"server error", PageResult{Success: true, StatusCode: http.StatusBadGateway}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pageFailed(tt.result); got != tt.want {
+				t.Errorf("pageFailed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordCircuit(t *testing.T) {
+	c, clk := newCircuitCrawler(t, 2)
+	const host = "docs.example.com"
+
+	c.recordCircuit(host, false)
+	if got := c.breaker.Remaining(host); got != 0 {
+		t.Fatalf("circuit open after one failure, remaining %v", got)
+	}
+	c.recordCircuit(host, false)
+	if got := c.breaker.Remaining(host); got != time.Minute {
+		t.Fatalf("Remaining() after two failures = %v, want 1m", got)
+	}
+
+	// Half-open after the cool-down: a success closes the circuit
+	clk.advance(time.Minute)
+	if got := c.breaker.Remaining(host); got != 0 {
+		t.Fatalf("Remaining() after the cool-down = %v, want 0", got)
+	}
+	c.recordCircuit(host, true)
+	c.recordCircuit(host, false)
+	if got := c.breaker.Remaining(host); got != 0 {
+		t.Errorf("circuit reopened after one failure following a success, remaining %v", got)
+	}
+}
+
+func TestWaitCircuit(t *testing.T) {
+	c, clk := newCircuitCrawler(t, 1)
+	const host = "docs.example.com"
+
+	if !c.waitCircuit(context.Background(), "other.example.com") {
+		t.Error("waitCircuit() on a closed circuit = false, want true")
+	}
+
+	c.recordCircuit(host, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if c.waitCircuit(ctx, host) {
+		t.Error("waitCircuit() with a cancelled context = true, want false")
+	}
+
+	// The wait is what the clock leaves of the cool-down
+	clk.advance(time.Minute - 20*time.Millisecond)
+	started := time.Now()
+	if !c.waitCircuit(context.Background(), host) {
+		t.Error("waitCircuit() = false, want true")
+	}
+	if elapsed := time.Since(started); elapsed < 20*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("waitCircuit() waited %v, want the 20ms left of the cool-down", elapsed)
+	}
+}
+
+func TestDeferOpenCircuits(t *testing.T) {
+	c, clk := newCircuitCrawler(t, 1)
+	ctx := context.Background()
+	c.recordCircuit("a.example.com", false)
+	clk.advance(time.Minute - 20*time.Millisecond)
+	c.recordCircuit("b.example.com", false)
+
+	urlFrontier, _, err := frontier.NewMemoryBackend(config.DefaultConfig().Strategy).Open(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deferred := []URLWithDepth{
+		{URL: "https://b.example.com/page", Depth: 1},
+		{URL: "https://a.example.com/page", Depth: 1},
+	}
+
+	// Part of a batch: the URLs are put back without waiting
+	started := time.Now()
+	if !c.deferOpenCircuits(ctx, urlFrontier, deferred, false) {
+		t.Fatal("deferOpenCircuits() = false, want true")
+	}
+	if n, _ := urlFrontier.Len(ctx); n != 2 {
+		t.Errorf("frontier holds %d URLs, want 2", n)
+	}
+
+	// The whole batch: waits for the first circuit to close, that of
+	// a.example.com with 20ms left
+	if !c.deferOpenCircuits(ctx, urlFrontier, deferred, true) {
+		t.Fatal("deferOpenCircuits() = false, want true")
+	}
+	if elapsed := time.Since(started); elapsed < 20*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("deferOpenCircuits() waited %v, want the 20ms left of the first cool-down", elapsed)
+	}
+	if n, _ := urlFrontier.Len(ctx); n != 4 {
+		t.Errorf("frontier holds %d URLs, want 4", n)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if c.deferOpenCircuits(cancelled, urlFrontier, deferred, true) {
+		t.Error("deferOpenCircuits() with a cancelled context = true, want false")
+	}
+}
@@ -0,0 +1,125 @@
+package crawler
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"path"
+	"strings"
+)
+
+// contentTypes decides from their content type which links are crawled as
+// pages. A nil contentTypes crawls every link.
+type contentTypes struct {
+	pages []string // content types crawled as pages, "text/*" matching any text type
+	probe bool     // send a HEAD request for URLs whose extension tells no type
+}
+
+func newContentTypes(types []string, probe bool) *contentTypes {
+	if len(types) == 0 {
+		return nil
+	}
+	pages := make([]string, len(types))
+	for i, contentType := range types {
+		pages[i] = strings.ToLower(contentType)
+	}
+	return &contentTypes{pages: pages, probe: probe}
+}
+
+// isPage reports whether contentType is crawled as a page
+func (t *contentTypes) isPage(contentType string) bool {
+	for _, page := range t.pages {
+		if page == contentType || (strings.HasSuffix(page, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(page, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaTypeOf returns the media type under which files of contentType are
+// downloaded, or an empty string for types that are not media
+func mediaTypeOf(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return "image"
+	case strings.HasPrefix(contentType, "video/"):
+		return "video"
+	case strings.HasPrefix(contentType, "audio/"):
+		return "audio"
+	case contentType == "application/pdf":
+		return "document"
+	}
+	return ""
+}
+
+// classifyLinks splits the links of a page into those crawled as pages, PDF
+// documents included with convert_pdfs, and those routed to the media
+// pipeline, dropping the other resources such as archives and executables.
+// The content type of a link is guessed from its extension or, with probing,
+// asked with a HEAD request; links of unknown type are crawled.
+func (c *Crawler) classifyLinks(ctx context.Context, urls []string) (pages []string, media PageMedia) {
+	if c.contentTypes == nil {
+		return urls, media
+	}
+	for _, url := range urls {
+		contentType := c.linkContentType(ctx, url)
+		if contentType == "" || c.contentTypes.isPage(contentType) || (c.convertPDFs && contentType == "application/pdf") {
+			pages = append(pages, url)
+			continue
+		}
+		switch mediaTypeOf(contentType) {
+		case "image":
+			media.Images = append(media.Images, MediaItem{URL: url})
+		case "video":
+			media.Videos = append(media.Videos, MediaItem{URL: url})
+		case "audio":
+			media.Audios = append(media.Audios, MediaItem{URL: url})
+		case "document":
+			media.Documents = append(media.Documents, MediaItem{URL: url})
+		default:
+			c.logger.Debug("Skipping link to a resource that is not a page", map[string]interface{}{
+				"url":         url,
+				"contentType": contentType,
+			})
+		}
+	}
+	return pages, media
+}
+
+// linkContentType returns the content type of a link, without parameters, or
+// an empty string if it is unknown
+func (c *Crawler) linkContentType(ctx context.Context, url string) string {
+	parsed, err := neturl.Parse(url)
+	if err != nil {
+		return ""
+	}
+	if ext := path.Ext(parsed.Path); ext != "" {
+		if contentType := mime.TypeByExtension(strings.ToLower(ext)); contentType != "" {
+			return baseContentType(contentType)
+		}
+	}
+	if !c.contentTypes.probe {
+		return ""
+	}
+
+	resp, err := c.requestOrigin(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		c.logger.Debug("Failed to probe content type", map[string]interface{}{"url": url, "error": err})
+		return ""
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return ""
+	}
+	return baseContentType(resp.Header.Get("Content-Type"))
+}
+
+// baseContentType returns a content type without its parameters, lowercased
+func baseContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
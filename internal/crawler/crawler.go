@@ -5,44 +5,187 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	neturl "net/url"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"crawlr/internal/auth"
+	"crawlr/internal/breaker"
+	"crawlr/internal/budget"
 	"crawlr/internal/config"
 	"crawlr/internal/errors"
+	"crawlr/internal/frontier"
 	"crawlr/internal/logger"
 	"crawlr/internal/progress"
+	"crawlr/internal/replay"
 	"crawlr/internal/storage"
+	"crawlr/internal/tracing"
+	"crawlr/internal/transform"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Crawler represents the HTTP client for communicating with the crawl4ai API
 type Crawler struct {
-	client        *http.Client
-	serverURL     string
-	timeout       time.Duration
-	maxConcurrent int
-	includeMedia  bool
-	authToken     string
-	logger        *logger.Logger
-	storage       *storage.Storage
+	client            *http.Client
+	serverURL         string
+	strategy          string
+	discovery         string
+	timeout           time.Duration
+	maxDuration       time.Duration
+	maxConcurrent     int
+	includeMedia      bool
+	authToken         string
+	apiVersion        string // configured crawl4ai API version, or auto
+	apiOnce           sync.Once
+	api               apiAdapter
+	engine            string // crawl4ai, or local to fetch pages directly
+	fallback          string // none, or local to fetch pages directly while crawl4ai is unavailable
+	userAgents        *userAgents
+	maxResponseSize   int64 // 0 means no limit
+	memory            *memoryBudget
+	logger            *logger.Logger
+	storage           *storage.Storage
+	originAuth        *auth.Registry
+	extraction        config.ExtractionConfig
+	stateBackend      frontier.Backend
+	stateNamespace    string
+	mediaLimiters     *hostLimiters
+	mediaFilter       *mediaFilter
+	depthRules        []config.DepthRule
+	domainSettings    map[string]config.DomainPolicy
+	domains           domainPolicies
+	includeSubdomains bool
+	allowedDomains    []string
+	maxPagesPerDomain int
+	ignoreMetaRobots  bool
+	useCanonical      bool
+	maxPagination     int
+	languages         []string
+	contentTypes      *contentTypes
+	convertPDFs       bool
+	retry             retryPolicy
+	breaker           *breaker.Breaker
+	crawlTree         bool
+	incremental       bool
+	treeInterval      time.Duration
+	sitemapReport     bool
+	linkGraph         bool
+	batchHandler      BatchHandler
+	frontierHandler   FrontierHandler
+	errorHandler      ErrorHandler
+	budget            *budget.Budget
+	resume            *Checkpoint
+	seeds             []string
+	seedHosts         []string
+
+	cssSelector       string
+	excludedSelector  string
+	excludePattern    *regexp.Regexp
+	paginationPattern *regexp.Regexp
 }
 
 // NewCrawler creates a new Crawler instance with the provided configuration
 func NewCrawler(cfg *config.Config, logger *logger.Logger) *Crawler {
-	return &Crawler{
-		client: &http.Client{
-			Timeout: time.Duration(cfg.Timeout) * time.Second,
-		},
-		serverURL:     cfg.ServerURL,
-		timeout:       time.Duration(cfg.Timeout) * time.Second,
-		maxConcurrent: cfg.MaxConcurrent,
-		includeMedia:  cfg.IncludeMedia,
-		logger:        logger,
+	// Invalid sizes are rejected by config validation and mean no limit here
+	maxResponseSize, _ := config.ParseSize(cfg.MaxResponseSize)
+	memoryBudget, _ := config.ParseSize(cfg.MemoryBudget)
+	transport, err := newTransport(cfg)
+	if err != nil {
+		logger.Error("Failed to configure TLS", map[string]interface{}{"error": err})
+	} else if cfg.InsecureSkipVerify {
+		logger.Warn("TLS certificate verification is disabled", nil)
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(cfg.Timeout) * time.Second,
+	}
+	// Replay a captured run by sending every request to the replay server
+	if cfg.ReplayServer != "" {
+		transport, err := replay.Redirect(cfg.ReplayServer, client.Transport)
+		if err != nil {
+			logger.Error("Failed to configure replay server", map[string]interface{}{"error": err})
+		} else {
+			client.Transport = transport
+		}
 	}
+
+	c := &Crawler{
+		client:            client,
+		serverURL:         cfg.ServerURL,
+		apiVersion:        cfg.APIVersion,
+		engine:            cfg.Engine,
+		fallback:          cfg.Fallback,
+		userAgents:        newUserAgents(cfg.UserAgents),
+		maxResponseSize:   maxResponseSize,
+		memory:            newMemoryBudget(memoryBudget),
+		strategy:          cfg.Strategy,
+		discovery:         cfg.DiscoveryMethod,
+		timeout:           time.Duration(cfg.Timeout) * time.Second,
+		maxDuration:       time.Duration(cfg.MaxDuration) * time.Second,
+		maxConcurrent:     cfg.MaxConcurrent,
+		includeMedia:      cfg.IncludeMedia,
+		logger:            logger,
+		extraction:        cfg.Extraction,
+		stateBackend:      frontier.NewMemoryBackend(cfg.Strategy),
+		stateNamespace:    cfg.Library,
+		mediaLimiters:     newHostLimiters(cfg.MediaRateLimit),
+		mediaFilter:       newMediaFilter(cfg),
+		depthRules:        cfg.DepthRules,
+		domainSettings:    cfg.Domains,
+		domains:           newDomainPolicies(cfg.Domains, logger),
+		includeSubdomains: cfg.IncludeSubdomains,
+		allowedDomains:    config.SplitList(cfg.AllowedDomains),
+		maxPagesPerDomain: cfg.MaxPagesPerDomain,
+		ignoreMetaRobots:  cfg.IgnoreMetaRobots,
+		useCanonical:      cfg.UseCanonical,
+		maxPagination:     cfg.MaxPaginationPages,
+		languages:         config.SplitList(cfg.Languages),
+		contentTypes:      newContentTypes(config.SplitList(cfg.CrawlContentTypes), cfg.ProbeContentTypes),
+		convertPDFs:       cfg.ConvertPDFs,
+		retry:             newRetryPolicy(cfg),
+		breaker:           breaker.New(cfg.BreakerThreshold, time.Duration(cfg.BreakerCooldown)*time.Second),
+		crawlTree:         cfg.CrawlTree,
+		incremental:       cfg.Incremental,
+		treeInterval:      time.Duration(cfg.CrawlTreeInterval) * time.Second,
+		sitemapReport:     cfg.SitemapReport,
+		linkGraph:         cfg.LinkGraph,
+
+		cssSelector:       cfg.CSSSelector,
+		excludedSelector:  joinSelectors(cfg.ExcludedSelector, transform.StripSelectors(cfg.Transforms)),
+		excludePattern:    compileOptional(cfg.ExcludePatterns),
+		paginationPattern: compileOptional(cfg.PaginationPattern),
+	}
+	client.CheckRedirect = c.checkRedirect
+	return c
+}
+
+// compileOptional compiles a configured pattern, returning nil for an empty or
+// invalid one (invalid patterns are rejected by config validation)
+func compileOptional(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// joinSelectors combines CSS selector lists, skipping empty ones
+func joinSelectors(lists ...string) string {
+	var selectors []string
+	for _, list := range lists {
+		if strings.TrimSpace(list) != "" {
+			selectors = append(selectors, list)
+		}
+	}
+	return strings.Join(selectors, ", ")
 }
 
 // SetStorage sets the storage instance for saving crawled content
@@ -50,6 +193,61 @@ func (c *Crawler) SetStorage(storage *storage.Storage) {
 	c.storage = storage
 }
 
+// SetRecorder captures every HTTP exchange of the crawler with recorder
+func (c *Crawler) SetRecorder(recorder *replay.Recorder) {
+	c.client.Transport = recorder.Wrap(c.client.Transport)
+}
+
+// SetOriginAuth sets the credentials used when fetching content from origin servers
+func (c *Crawler) SetOriginAuth(registry *auth.Registry) {
+	c.originAuth = registry
+}
+
+// SetStateBackend sets the backend holding the frontier and visited set
+func (c *Crawler) SetStateBackend(backend frontier.Backend) {
+	c.stateBackend = backend
+}
+
+// BatchHandler receives the results of each batch as soon as it is crawled.
+// Recursive crawling waits for it to return before submitting the next batch.
+type BatchHandler func(ctx context.Context, results []PageResult)
+
+// SetBatchHandler hands the results of every batch to handler during
+// recursive crawling. The results returned at the end of the crawl then no
+// longer hold page content, which the handler is expected to have saved, and
+// to report saved with ReleaseResults under a memory budget.
+func (c *Crawler) SetBatchHandler(handler BatchHandler) {
+	c.batchHandler = handler
+}
+
+// FrontierHandler receives the number of URLs waiting in the frontier after
+// each batch of recursive crawling
+type FrontierHandler func(queued int)
+
+// SetFrontierHandler reports the size of the frontier to handler during
+// recursive crawling
+func (c *Crawler) SetFrontierHandler(handler FrontierHandler) {
+	c.frontierHandler = handler
+}
+
+// ErrorHandler receives the failures affecting a single URL, pages that
+// could not be crawled and media that could not be downloaded or saved, each
+// a CrawlrError telling its type. It may be called from several goroutines.
+type ErrorHandler func(url string, err *errors.CrawlrError)
+
+// SetErrorHandler hands the failures affecting a single URL to handler, in
+// addition to logging them
+func (c *Crawler) SetErrorHandler(handler ErrorHandler) {
+	c.errorHandler = handler
+}
+
+// reportError hands a failure affecting url to the error handler, if any
+func (c *Crawler) reportError(url string, err *errors.CrawlrError) {
+	if c.errorHandler != nil {
+		c.errorHandler(url, err)
+	}
+}
+
 // SetAuthToken sets the authentication token for API requests
 func (c *Crawler) SetAuthToken(token string) {
 	c.authToken = token
@@ -57,51 +255,154 @@ func (c *Crawler) SetAuthToken(token string) {
 
 // StartCrawlRequest represents the request to start a crawling job
 type StartCrawlRequest struct {
-	Urls                 []string               `json:"urls"`                     // URLs array as expected by crawl4ai API
-	IncludeRawHTML       bool                   `json:"include_raw_html,omitempty"`
-	WordCountThreshold   int                    `json:"word_count_threshold,omitempty"`
-	Priority             int                    `json:"priority,omitempty"`
-	TTL                  int                    `json:"ttl,omitempty"`
+	Urls               []string `json:"urls"` // URLs array as expected by crawl4ai API
+	IncludeRawHTML     bool     `json:"include_raw_html,omitempty"`
+	WordCountThreshold int      `json:"word_count_threshold,omitempty"`
+	Priority           int      `json:"priority,omitempty"`
+	TTL                int      `json:"ttl,omitempty"`
 	// Crawl4ai crawler configuration for multi-URL crawling
-	CrawlerConfig        CrawlerConfig          `json:"crawler_config,omitempty"`
+	CrawlerConfig CrawlerConfig `json:"crawler_config,omitempty"`
 	// Extraction and processing options
-	ProcessURLs          bool                   `json:"process_urls,omitempty"`
+	ProcessURLs bool `json:"process_urls,omitempty"`
 	// Browser configuration for crawling
-	BrowserConfig        map[string]interface{} `json:"browser_config,omitempty"`
+	BrowserConfig map[string]interface{} `json:"browser_config,omitempty"`
 }
 
 // CrawlerConfig contains configuration for automatic URL discovery and crawling
 type CrawlerConfig struct {
-	MaxDepth        int    `json:"max_depth,omitempty"`
-	MaxURLs         int    `json:"max_urls,omitempty"`
-	Strategy        string `json:"strategy,omitempty"`        // bfs, dfs, bestfirst
-	ExternalLinks   bool   `json:"external_links,omitempty"` // false = stay in domain
-	OnlyText        bool   `json:"only_text,omitempty"`
-	WordCountThreshold int `json:"word_count_threshold,omitempty"`
+	MaxDepth           int    `json:"max_depth,omitempty"`
+	MaxURLs            int    `json:"max_urls,omitempty"`
+	Strategy           string `json:"strategy,omitempty"`       // bfs, dfs, bestfirst
+	ExternalLinks      bool   `json:"external_links,omitempty"` // false = stay in domain
+	OnlyText           bool   `json:"only_text,omitempty"`
+	WordCountThreshold int    `json:"word_count_threshold,omitempty"`
+	CSSSelector        string `json:"css_selector,omitempty"`      // content to convert to markdown
+	ExcludedSelector   string `json:"excluded_selector,omitempty"` // elements to leave out
+	// Structured extraction strategy, e.g. JsonCssExtractionStrategy
+	ExtractionStrategy map[string]interface{} `json:"extraction_strategy,omitempty"`
+	// Scraping strategy, e.g. PDFContentScrapingStrategy for PDF documents
+	ScrapingStrategy map[string]interface{} `json:"scraping_strategy,omitempty"`
 }
 
 // StartCrawlResponse represents the response from starting a crawling job
 type StartCrawlResponse struct {
-	Success                bool `json:"success"`
-	Results                []struct {
-		URL             string `json:"url"`
-		HTML            string `json:"html"`
-		Success         bool   `json:"success"`
-		CleanedHTML     string `json:"cleaned_html"`
-		Markdown        struct {
-			RawMarkdown         string `json:"raw_markdown"`
-			MarkdownWithCitations string `json:"markdown_with_citations"`
-		} `json:"markdown"`
-		Media           struct {
-			Images []struct {
-				URL string `json:"url"`
-			} `json:"images"`
-		} `json:"media"`
-		Metadata        map[string]interface{} `json:"metadata"`
-	} `json:"results"`
-	ServerProcessingTimeS float64 `json:"server_processing_time_s"`
-	ServerMemoryDeltaMB  float64 `json:"server_memory_delta_mb"`
-	ServerPeakMemoryMB   float64 `json:"server_peak_memory_mb"`
+	Success               bool         `json:"success"`
+	Results               []PageResult `json:"results"`
+	ServerProcessingTimeS float64      `json:"server_processing_time_s"`
+	ServerMemoryDeltaMB   float64      `json:"server_memory_delta_mb"`
+	ServerPeakMemoryMB    float64      `json:"server_peak_memory_mb"`
+
+	// Discovered lists the URLs linked from the pages of a recursive crawl,
+	// crawled or not. It is only collected for sitemap reports.
+	Discovered []string `json:"-"`
+
+	// Fallback reports whether some pages were fetched locally, without
+	// crawl4ai, because the server was unavailable
+	Fallback bool `json:"-"`
+
+	// Complete reports whether a recursive crawl reached every page in its
+	// scope: the frontier was exhausted without failed batches or cancellation
+	Complete bool `json:"-"`
+
+	// Cancelled reports whether a recursive crawl stopped early because its
+	// context was cancelled; Results then hold the pages crawled until then
+	Cancelled bool `json:"-"`
+
+	// DurationExceeded reports whether a recursive crawl stopped early because
+	// its maximum duration elapsed
+	DurationExceeded bool `json:"-"`
+
+	// Circuits describes the circuit breakers of the hosts that failed during
+	// a recursive crawl
+	Circuits []breaker.State `json:"-"`
+}
+
+// PageResult represents the crawl4ai result for a single page
+type PageResult struct {
+	URL         string `json:"url"`
+	HTML        string `json:"html"`
+	Success     bool   `json:"success"`
+	CleanedHTML string `json:"cleaned_html"`
+	Markdown    struct {
+		RawMarkdown           string `json:"raw_markdown"`
+		MarkdownWithCitations string `json:"markdown_with_citations"`
+	} `json:"markdown"`
+	Media            PageMedia              `json:"media"`
+	Metadata         map[string]interface{} `json:"metadata"`
+	ExtractedContent string                 `json:"extracted_content"`
+	StatusCode       int                    `json:"status_code"`
+	ErrorMessage     string                 `json:"error_message"`
+	ResponseHeaders  map[string]interface{} `json:"response_headers"`
+	RedirectedURL    string                 `json:"redirected_url"` // final URL after redirects
+
+	// Depth is the link distance from the start URL, set by recursive crawling
+	Depth int `json:"-"`
+
+	// Links are the links found on the page, set by recursive crawling when
+	// it follows them
+	Links []string `json:"-"`
+
+	// Unchanged marks pages an incremental re-crawl skipped because they did
+	// not change since the last crawl; only URL, StatusCode and Links are set
+	Unchanged bool `json:"-"`
+
+	// Raw holds the complete result object as returned by crawl4ai, including
+	// fields crawlr does not model
+	Raw json.RawMessage `json:"-"`
+}
+
+// withoutContent returns the result without the page content and media, for
+// results kept until the end of a crawl once saved
+func (r PageResult) withoutContent() PageResult {
+	r.HTML = ""
+	r.CleanedHTML = ""
+	r.Markdown.RawMarkdown = ""
+	r.Markdown.MarkdownWithCitations = ""
+	r.ExtractedContent = ""
+	r.Media = PageMedia{}
+	r.Raw = nil
+	return r
+}
+
+// UnmarshalJSON decodes a page result and keeps a copy of the raw object
+func (r *PageResult) UnmarshalJSON(data []byte) error {
+	type pageResult PageResult
+	var decoded pageResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*r = PageResult(decoded)
+	r.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// MediaItem is a media file referenced by a page
+type MediaItem struct {
+	URL string `json:"url"`
+}
+
+// PageMedia lists the media files crawl4ai found on a page
+type PageMedia struct {
+	Images []MediaItem `json:"images"`
+	Videos []MediaItem `json:"videos"`
+	Audios []MediaItem `json:"audios"`
+
+	// Documents are the links to documents such as PDFs routed to the media
+	// pipeline by crawl_content_types, which crawl4ai does not report
+	Documents []MediaItem `json:"documents,omitempty"`
+}
+
+// add appends the media files of other
+func (m *PageMedia) add(other PageMedia) {
+	m.Images = append(m.Images, other.Images...)
+	m.Videos = append(m.Videos, other.Videos...)
+	m.Audios = append(m.Audios, other.Audios...)
+	m.Documents = append(m.Documents, other.Documents...)
+}
+
+// Count returns the number of media files of all types
+func (m PageMedia) Count() int {
+	return len(m.Images) + len(m.Videos) + len(m.Audios) + len(m.Documents)
 }
 
 // CrawlResult represents a crawl result for media processing compatibility
@@ -114,11 +415,7 @@ type CrawlResult struct {
 		Markdown struct {
 			RawMarkdown string `json:"raw_markdown"`
 		} `json:"markdown"`
-		Media struct {
-			Images []struct {
-				URL string `json:"url"`
-			} `json:"images"`
-		} `json:"media"`
+		Media    PageMedia              `json:"media"`
 		Metadata map[string]interface{} `json:"metadata,omitempty"`
 	} `json:"results"`
 }
@@ -136,6 +433,9 @@ type APIError struct {
 	StatusCode int    `json:"status_code"`
 	Message    string `json:"message"`
 	Details    string `json:"details,omitempty"`
+
+	// RetryAfter is the wait a 429 or 503 response asked for before retrying
+	RetryAfter time.Duration `json:"-"`
 }
 
 // Error implements the error interface
@@ -148,27 +448,49 @@ func (c *Crawler) StartCrawl(ctx context.Context, url string, includeMedia *bool
 	return c.StartCrawlWithConfig(ctx, []string{url}, includeMedia, 2, true, 50)
 }
 
-// StartCrawlWithConfig starts a crawling job with custom configuration
-func (c *Crawler) StartCrawlWithConfig(ctx context.Context, urls []string, includeMedia *bool, maxDepth int, excludeExternalLinks bool, maxURLs int) (*StartCrawlResponse, error) {
+// StartCrawlWithConfig starts a crawling job with custom configuration. The
+// local engine fetches urls itself, ignoring maxDepth and maxURLs.
+func (c *Crawler) StartCrawlWithConfig(ctx context.Context, urls []string, includeMedia *bool, maxDepth int, excludeExternalLinks bool, maxURLs int) (response *StartCrawlResponse, err error) {
+	if c.localEngine() {
+		return c.fetchLocal(ctx, urls)
+	}
+
+	ctx, span := tracing.Start(ctx, "crawl4ai.crawl", attribute.Int("crawl4ai.url_count", len(urls)))
+	defer func() {
+		if response != nil {
+			span.SetAttributes(
+				attribute.Int("crawl4ai.result_count", len(response.Results)),
+				attribute.Float64("crawl4ai.server_processing_time_s", response.ServerProcessingTimeS),
+			)
+		}
+		tracing.End(span, err)
+	}()
+
 	// Optimize for batch processing: disable internal URL discovery when doing our own discovery
-	discoveryEnabled := len(urls) == 1 // Only enable discovery for single URL calls
-	
+	discoveryEnabled := len(urls) == 1 && c.followsLinks() // Only enable discovery for single URL calls
+
 	// Use the format that matches crawl4ai's expected structure
 	req := StartCrawlRequest{
-		Urls:           urls,   // Use URLs array format as expected by crawl4ai API
-		IncludeRawHTML: true,   // Include raw HTML in response
-		ProcessURLs:    discoveryEnabled,   // Enable URL processing only for single URLs
+		Urls:           urls,             // Use URLs array format as expected by crawl4ai API
+		IncludeRawHTML: true,             // Include raw HTML in response
+		ProcessURLs:    discoveryEnabled, // Enable URL processing only for single URLs
 		CrawlerConfig: CrawlerConfig{
-			MaxDepth:         maxDepth,        // Limit crawling depth
-			MaxURLs:          maxURLs,         // Limit total URLs to crawl
-			Strategy:         "bfs",           // Use breadth-first search for comprehensive crawling
-			ExternalLinks:    false,           // Stay within the same domain
-			OnlyText:         true,            // Focus on text content
-			WordCountThreshold: 10,           // Skip low-content pages
+			MaxDepth:           maxDepth, // Limit crawling depth
+			MaxURLs:            maxURLs,  // Limit total URLs to crawl
+			Strategy:           c.strategy,
+			ExternalLinks:      false, // Stay within the same domain
+			OnlyText:           true,  // Focus on text content
+			WordCountThreshold: 10,    // Skip low-content pages
+			CSSSelector:        c.cssSelector,
+			ExcludedSelector:   c.excludedSelector,
+			ExtractionStrategy: c.extractionStrategy(),
+			ScrapingStrategy:   c.scrapingStrategy(urls),
 		},
+		BrowserConfig: c.browserConfig(urls),
 	}
 
-	reqBody, err := json.Marshal(req)
+	api := c.crawlAPI(ctx)
+	reqBody, err := api.encodeRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
@@ -185,19 +507,20 @@ func (c *Crawler) StartCrawlWithConfig(ctx context.Context, urls []string, inclu
 	if c.authToken != "" {
 		httpReq.Header.Set("Authorization", "Bearer "+c.authToken)
 	}
+	tracing.Inject(ctx, httpReq.Header)
 
 	c.logger.Info("Starting crawl for URLs", map[string]interface{}{
-		"urlCount": len(urls),
-		"maxDepth": maxDepth,
-		"maxURLs": maxURLs,
-		"excludeExternal": excludeExternalLinks,
+		"urlCount":         len(urls),
+		"maxDepth":         maxDepth,
+		"maxURLs":          maxURLs,
+		"excludeExternal":  excludeExternalLinks,
 		"discoveryEnabled": discoveryEnabled,
-		"isBatch": len(urls) > 1,
+		"isBatch":          len(urls) > 1,
 		"crawlerConfig": map[string]interface{}{
-			"process_urls": discoveryEnabled,
-			"strategy": "bfs",
-			"external_links": false,
-			"only_text": true,
+			"process_urls":         discoveryEnabled,
+			"strategy":             c.strategy,
+			"external_links":       false,
+			"only_text":            true,
 			"word_count_threshold": 10,
 		},
 	})
@@ -207,8 +530,9 @@ func (c *Crawler) StartCrawlWithConfig(ctx context.Context, urls []string, inclu
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.readBody(resp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
@@ -216,7 +540,7 @@ func (c *Crawler) StartCrawlWithConfig(ctx context.Context, urls []string, inclu
 	c.logger.Debug("Request sent", map[string]interface{}{
 		"requestBody": string(reqBody),
 	})
-	
+
 	c.logger.Debug("Start crawl response", map[string]interface{}{
 		"statusCode": resp.StatusCode,
 		"body":       string(body),
@@ -225,33 +549,87 @@ func (c *Crawler) StartCrawlWithConfig(ctx context.Context, urls []string, inclu
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
 		var apiErr APIError
 		if err := json.Unmarshal(body, &apiErr); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal error response: %w, status code: %d", err, resp.StatusCode)
+			// Proxies in front of crawl4ai answer 502 or 503 with plain text
+			apiErr.Message = strings.TrimSpace(string(body))
 		}
 		apiErr.StatusCode = resp.StatusCode
+		apiErr.RetryAfter, _ = c.retry.retryAfter(resp)
 		return nil, &apiErr
 	}
 
-	var result StartCrawlResponse
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := api.decodeResponse(body)
+	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	c.logger.Info("Crawl completed", map[string]interface{}{
-		"success": result.Success,
-		"resultCount": len(result.Results),
+		"success":        result.Success,
+		"resultCount":    len(result.Results),
 		"processingTime": result.ServerProcessingTimeS,
 	})
-	
+
 	// If we only got one result but expected more, log a warning
 	if len(urls) == 1 && maxURLs > 1 && len(result.Results) == 1 {
 		c.logger.Warn("Expected multiple URLs but got only one result. The crawl4ai server may not support multi-URL crawling or different parameters are needed.", map[string]interface{}{
 			"requestedURLs": maxURLs,
 			"actualResults": len(result.Results),
-			"startingURL": urls[0],
+			"startingURL":   urls[0],
+			"apiVersion":    api.version(),
 		})
 	}
 
-	return &result, nil
+	return result, nil
+}
+
+// extractionStrategy builds the crawl4ai extraction strategy from the configured
+// schema, or returns nil if no schema is configured
+func (c *Crawler) extractionStrategy() map[string]interface{} {
+	if !c.extraction.Enabled() {
+		return nil
+	}
+
+	strategyType := "JsonCssExtractionStrategy"
+	if c.extraction.Type == "xpath" {
+		strategyType = "JsonXPathExtractionStrategy"
+	}
+
+	schema := map[string]interface{}{
+		"name":         c.extraction.Name,
+		"baseSelector": c.extraction.BaseSelector,
+		"fields":       extractionFields(c.extraction.Fields),
+	}
+
+	return map[string]interface{}{
+		"type": strategyType,
+		"params": map[string]interface{}{
+			"schema": schema,
+		},
+	}
+}
+
+// extractionFields converts configured fields to the crawl4ai schema format
+func extractionFields(fields []config.ExtractionField) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, f := range fields {
+		field := map[string]interface{}{
+			"name": f.Name,
+			"type": f.Type,
+		}
+		if f.Selector != "" {
+			field["selector"] = f.Selector
+		}
+		if f.Attribute != "" {
+			field["attribute"] = f.Attribute
+		}
+		if f.Pattern != "" {
+			field["pattern"] = f.Pattern
+		}
+		if len(f.Fields) > 0 {
+			field["fields"] = extractionFields(f.Fields)
+		}
+		result = append(result, field)
+	}
+	return result
 }
 
 // ExtractURLsFromHTML extracts URLs from HTML content using regex
@@ -259,47 +637,52 @@ func (c *Crawler) ExtractURLsFromHTML(html string, baseURL string) ([]string, er
 	// Simple regex to find href attributes
 	hrefRegex := regexp.MustCompile(`<a[^>]+href\s*=\s*["']([^"']+)["'][^>]*>`)
 	matches := hrefRegex.FindAllStringSubmatch(html, -1)
-	
+
 	var urls []string
 	seen := make(map[string]bool)
-	
+
 	for _, match := range matches {
 		if len(match) < 2 {
 			continue
 		}
-		
+
 		url := strings.TrimSpace(match[1])
-		
+
 		// Skip anchors, javascript, mailto, etc.
 		if strings.HasPrefix(url, "#") || strings.HasPrefix(url, "javascript:") || strings.HasPrefix(url, "mailto:") {
 			continue
 		}
-		
+
+		// Skip links the page asks not to follow
+		if !c.ignoreMetaRobots && isNoFollow(match[0]) {
+			continue
+		}
+
 		// Make URL absolute
 		absoluteURL, err := c.makeAbsoluteURL(url, baseURL)
 		if err != nil {
 			c.logger.Debug("Failed to make URL absolute", map[string]interface{}{
-				"url": url,
+				"url":     url,
 				"baseURL": baseURL,
-				"error": err,
+				"error":   err,
 			})
 			continue
 		}
-		
+
 		// Skip if already seen
 		if seen[absoluteURL] {
 			continue
 		}
-		
+
 		seen[absoluteURL] = true
 		urls = append(urls, absoluteURL)
 	}
-	
-	c.logger.Info("Extracted URLs from HTML", map[string]interface{}{
+
+	c.logger.Detail("Extracted URLs from HTML", map[string]interface{}{
 		"totalURLs": len(urls),
-		"baseURL": baseURL,
+		"baseURL":   baseURL,
 	})
-	
+
 	return urls, nil
 }
 
@@ -308,25 +691,22 @@ func (c *Crawler) makeAbsoluteURL(url, baseURL string) (string, error) {
 	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
 		return url, nil
 	}
-	
+
 	base, err := neturl.Parse(baseURL)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse base URL: %w", err)
 	}
-	
+
 	rel, err := neturl.Parse(url)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse relative URL: %w", err)
 	}
-	
+
 	return base.ResolveReference(rel).String(), nil
 }
 
 // URLWithDepth represents a URL with its crawl depth
-type URLWithDepth struct {
-	URL   string
-	Depth int
-}
+type URLWithDepth = frontier.URLWithDepth
 
 // StartRecursiveCrawling performs true recursive crawling with depth-based discovery
 func (c *Crawler) StartRecursiveCrawling(ctx context.Context, startURL string, includeMedia *bool, maxDepth int, maxURLs int) (*StartCrawlResponse, error) {
@@ -336,182 +716,490 @@ func (c *Crawler) StartRecursiveCrawling(ctx context.Context, startURL string, i
 // StartBatchRecursiveCrawling performs recursive crawling with batch processing for efficiency
 func (c *Crawler) StartBatchRecursiveCrawling(ctx context.Context, startURL string, includeMedia *bool, maxDepth int, maxURLs int, batchSize int) (*StartCrawlResponse, error) {
 	c.logger.Info("Starting batch recursive crawling", map[string]interface{}{
-		"startURL": startURL,
-		"maxDepth": maxDepth,
-		"maxURLs": maxURLs,
+		"startURL":  startURL,
+		"maxDepth":  maxDepth,
+		"maxURLs":   maxURLs,
 		"batchSize": batchSize,
 	})
-	
-	// Initialize crawling state
-	frontier := []URLWithDepth{{URL: startURL, Depth: 0}}
-	visited := make(map[string]bool)
-	
+
+	// Initialize crawling state. With a shared backend, crawlers using the same
+	// namespace cooperate on a single frontier and visited set.
+	urlFrontier, visited, err := c.stateBackend.Open(ctx, c.stateNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open crawl state: %w", err)
+	}
+
+	// Seed the frontier with the start URL and further seeds, except those a
+	// cooperating crawler already claimed, or with the pending URLs of the
+	// crawl to resume. In feed discovery, the entries of the feeds the seeds
+	// declare are seeded along with them.
+	var entries []string
+	if c.discovery == "feed" && c.resume == nil {
+		entries = c.feedEntries(ctx, startURL, append([]string{startURL}, c.seeds...))
+	}
+	roots := c.seedItems(startURL, entries...)
+	if c.resume != nil {
+		if err := c.resumeCheckpoint(ctx, urlFrontier, visited); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := c.pushSeeds(ctx, urlFrontier, visited, roots); err != nil {
+			return nil, err
+		}
+	}
+	initialFrontierSize, _ := urlFrontier.Len(ctx)
+
+	// Depth rules override maxDepth below their paths, domain policies on
+	// their domains
+	depths := frontier.NewDepthPolicy(maxDepth, c.depthRules, c.domainSettings)
+
+	// No batch is started once the maximum duration has elapsed
+	var deadline time.Time
+	if c.maxDuration > 0 {
+		deadline = time.Now().Add(c.maxDuration)
+	}
+	var durationExceeded bool
+
+	// Pages crawled per domain, when crawling beyond the start host
+	quota := newDomainQuota(c.maxPagesPerDomain)
+
+	// Position of the pages reached through pagination links in their
+	// listing, for max_pagination_pages
+	listingPositions := make(map[string]int)
+
+	tree := c.newCrawlTree(startURL)
+	tree.queued(roots)
+	defer c.flushCrawlTree(tree, true)
+
 	c.logger.Info("Batch recursive crawling initialized", map[string]interface{}{
-		"startURL": startURL,
-		"maxDepth": maxDepth,
-		"maxURLs": maxURLs,
-		"batchSize": batchSize,
-		"initialFrontierSize": len(frontier),
+		"startURL":            startURL,
+		"maxDepth":            maxDepth,
+		"maxURLs":             maxURLs,
+		"batchSize":           batchSize,
+		"initialFrontierSize": initialFrontierSize,
 	})
-	var allResults []struct {
-		URL             string `json:"url"`
-		HTML            string `json:"html"`
-		Success         bool   `json:"success"`
-		CleanedHTML     string `json:"cleaned_html"`
-		Markdown        struct {
-			RawMarkdown         string `json:"raw_markdown"`
-			MarkdownWithCitations string `json:"markdown_with_citations"`
-		} `json:"markdown"`
-		Media           struct {
-			Images []struct {
-				URL string `json:"url"`
-			} `json:"images"`
-		} `json:"media"`
-		Metadata        map[string]interface{} `json:"metadata"`
-	}
-	
+	var allResults []PageResult
+	var failedBatches int
+	var serverTime float64 // seconds of crawl4ai processing, summed over batches
+	var discovered []string
+	discoveredSet := make(map[string]bool)
+
+	// pending holds the URLs popped from the frontier and not crawled yet, for
+	// the checkpoint written if the crawl is cancelled half-way through a batch
+	var pending []URLWithDepth
+
 	// Progress reporter will be managed by the caller
-	
-	for len(frontier) > 0 && len(allResults) < maxURLs {
-		// Check context for cancellation
-		select {
-		case <-ctx.Done():
-			c.logger.Warn("Batch crawling cancelled by context", map[string]interface{}{
+
+	// Every stage of a batch that waits on the network stops once ctx is
+	// cancelled; the loop then ends with the results crawled so far
+	for len(allResults) < maxURLs && ctx.Err() == nil {
+		// Stop once the maximum duration has elapsed, letting the caller save
+		// the batches crawled so far
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			c.logger.Info("Maximum crawl duration reached, not crawling further batches", map[string]interface{}{
+				"maxDuration":   c.maxDuration.String(),
 				"processedURLs": len(allResults),
-				"remainingFrontier": len(frontier),
 			})
+			durationExceeded = true
+			break
+		}
+
+		// Stop once the crawl budget is spent
+		if c.budgetExhausted() {
+			c.logger.Info("Crawl budget exhausted, not crawling further pages", map[string]interface{}{
+				"processedURLs": len(allResults),
+			})
+			break
+		}
+
+		// Hold back while the circuit of the crawl4ai server is open, unless
+		// pages are fetched locally meanwhile
+		if !c.localFallback() && !c.waitCircuit(ctx, serverCircuit) {
+			break
+		}
+
+		// Process URLs in batches for efficiency, smaller ones while the
+		// memory budget is nearly spent, and hold back while the pages handed
+		// over for saving exceed it
+		currentBatchSize, shrunk := c.memory.batchSize(batchSize)
+		if shrunk {
+			c.logger.Info("Memory budget nearly spent, shrinking batches", map[string]interface{}{
+				"batchSize": currentBatchSize,
+			})
+		}
+		if !c.waitMemory(ctx) {
 			break
-		default:
 		}
-		
-		// Process URLs in batches for efficiency
-		batchSizeToProcess := min(batchSize, min(len(frontier), maxURLs-len(allResults)))
+		batchSizeToProcess := min(currentBatchSize, maxURLs-len(allResults))
 		if batchSizeToProcess <= 0 {
 			break
 		}
-		
+
 		// Extract current batch
-		var currentBatch []URLWithDepth
-		for i := 0; i < batchSizeToProcess; i++ {
-			if i >= len(frontier) {
+		items, err := urlFrontier.Pop(ctx, batchSizeToProcess)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.logger.Warn("Failed to read from frontier", map[string]interface{}{
+					"error": err,
+				})
+			}
+			break
+		}
+		if len(items) == 0 {
+			break
+		}
+		pending = items
+
+		// Skip URLs that are too deep or beyond the page quota of their domain,
+		// hold back those of hosts whose circuit is open, then claim the rest
+		// so no other crawler fetches them
+		var candidates []string
+		var deferred []URLWithDepth
+		isCandidate := make(map[string]bool, len(items))
+		for _, item := range items {
+			switch {
+			case item.Depth > depths.MaxDepth(item.URL):
+				tree.setStatus(item.URL, TreeTooDeep, 0, "")
+			case c.breaker.Remaining(quotaHost(item.URL)) > 0:
+				deferred = append(deferred, item)
+			case !quota.take(item.URL):
+				tree.setStatus(item.URL, TreeOverQuota, 0, "")
+			default:
+				candidates = append(candidates, item.URL)
+				isCandidate[item.URL] = true
+			}
+		}
+		if len(deferred) > 0 {
+			if !c.deferOpenCircuits(ctx, urlFrontier, deferred, len(candidates) == 0) {
 				break
 			}
-			current := frontier[i]
-			
-			// Skip if already visited or too deep
-			if !visited[current.URL] && current.Depth <= maxDepth {
-				currentBatch = append(currentBatch, current)
+		}
+		claimed, err := visited.Claim(ctx, candidates)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.logger.Warn("Failed to claim URLs", map[string]interface{}{
+					"error": err,
+				})
 			}
+			break
+		}
+		claimedSet := make(map[string]bool, len(claimed))
+		for _, url := range claimed {
+			claimedSet[url] = true
 		}
-		
-		// Remove processed URLs from frontier
-		frontier = frontier[batchSizeToProcess:]
-		
+
+		var currentBatch []URLWithDepth
+		for _, item := range items {
+			if claimedSet[item.URL] {
+				currentBatch = append(currentBatch, item)
+				delete(claimedSet, item.URL)
+			} else if isCandidate[item.URL] {
+				quota.release(item.URL)
+				tree.claimedElsewhere(item.URL)
+			}
+		}
+
+		pending = currentBatch
 		if len(currentBatch) == 0 {
 			continue
 		}
-		
+
+		remainingFrontier, _ := urlFrontier.Len(ctx)
 		c.logger.Info("Processing batch", map[string]interface{}{
-			"batchSize": len(currentBatch),
-			"batchDepth": currentBatch[0].Depth,
-			"processedCount": len(allResults),
-			"remainingFrontier": len(frontier),
+			"batchSize":         len(currentBatch),
+			"batchDepth":        currentBatch[0].Depth,
+			"processedCount":    len(allResults),
+			"remainingFrontier": remainingFrontier,
 		})
-		
-		// Extract URLs for batch processing
+
+		// Pages that did not change since the last crawl are not crawled again;
+		// the links recorded for them are followed instead
 		var batchURLs []string
 		for _, item := range currentBatch {
 			batchURLs = append(batchURLs, item.URL)
-			visited[item.URL] = true
 		}
-		
+		unchanged := c.unchangedPages(ctx, batchURLs)
+
+		var batchItems, crawlItems []URLWithDepth
+		var batchResults []PageResult
+		batchURLs = batchURLs[:0]
+		for _, item := range currentBatch {
+			if entry, ok := unchanged[item.URL]; ok {
+				batchItems = append(batchItems, item)
+				batchResults = append(batchResults, unchangedResult(item.URL, entry))
+				continue
+			}
+			crawlItems = append(crawlItems, item)
+		}
+		c.groupDomains(crawlItems)
+		pdfStart := c.splitPDFs(crawlItems)
+		for _, item := range crawlItems {
+			batchURLs = append(batchURLs, item.URL)
+		}
+		if len(unchanged) > 0 {
+			c.logger.Info("Skipping unchanged pages", map[string]interface{}{"count": len(unchanged)})
+		}
+
 		// Crawl the batch with optimized parameters for batch processing
-		result, err := c.StartCrawlWithRetry(ctx, batchURLs, includeMedia, 1, true, len(batchURLs), 1)
-		if err != nil {
-			c.logger.Warn("Failed to crawl batch", map[string]interface{}{
-				"batchSize": len(batchURLs),
-				"error": err,
-			})
-			continue
+		if len(batchURLs) > 0 {
+			result, err := c.crawlBatch(ctx, batchURLs, pdfStart, includeMedia)
+			if err != nil && ctx.Err() != nil {
+				// Cancelled: the batch stays pending rather than failed
+				break
+			} else if err != nil {
+				c.logger.Warn("Failed to crawl batch", map[string]interface{}{
+					"batchSize": len(batchURLs),
+					"error":     err,
+				})
+				c.recordCircuit(serverCircuit, false)
+				failedBatches++
+				for _, url := range batchURLs {
+					tree.setStatus(url, TreeFailed, 0, err.Error())
+					c.reportError(url, errors.Wrap(err, errors.APIError, "Failed to crawl batch"))
+				}
+				c.flushCrawlTree(tree, false)
+			} else {
+				serverTime += result.ServerProcessingTimeS
+				c.recordCircuit(serverCircuit, !result.Fallback)
+				for i, crawlResult := range result.Results {
+					if i >= len(crawlItems) {
+						break // Safety check
+					}
+					c.recordCircuit(quotaHost(crawlResult.URL), !pageFailed(crawlResult))
+					batchItems = append(batchItems, crawlItems[i])
+					batchResults = append(batchResults, crawlResult)
+				}
+			}
 		}
-		
-		if len(result.Results) == 0 {
+
+		if len(batchResults) == 0 {
+			pending = nil
 			continue
 		}
-		
+
+		// Pages redirected to a page already crawled, or declaring it as their
+		// canonical page, are aliases of it
+		aliases := c.claimAliases(ctx, batchResults, visited)
+
 		// Add results and extract new URLs
+		batchStart := len(allResults)
 		var newFrontierItems []URLWithDepth
-		for i, crawlResult := range result.Results {
-			if i >= len(currentBatch) {
-				break // Safety check
+		for i, crawlResult := range batchResults {
+			if aliases[i] {
+				c.logger.Info("Skipping alias of a page already crawled", map[string]interface{}{
+					"url":          crawlResult.URL,
+					"finalURL":     crawlResult.FinalURL(),
+					"canonicalURL": crawlResult.CanonicalURL(),
+				})
+				tree.setStatus(crawlResult.URL, TreeAlias, crawlResult.StatusCode, "")
+				continue
 			}
-			
-			// Add to results
-			allResults = append(allResults, crawlResult)
-			
-			// Extract URLs from this page if we haven't reached max depth
-			if currentBatch[i].Depth < maxDepth {
-				html := crawlResult.HTML
-				extractedURLs, err := c.ExtractURLsFromHTML(html, crawlResult.URL)
+
+			// Pages declaring a language that is not crawled are dropped with
+			// their links
+			if lang := pageLanguage(crawlResult.HTML); !c.wantsLanguage(lang) {
+				c.logger.Info("Skipping page in another language", map[string]interface{}{
+					"url":      crawlResult.URL,
+					"language": lang,
+				})
+				tree.setStatus(crawlResult.URL, TreeLanguage, crawlResult.StatusCode, "")
+				continue
+			}
+
+			// Extract URLs from this page; the depth of each link is checked
+			// against its own limit when popped. Pagination links stay at the
+			// depth of the page, so they are followed at max depth too.
+			// Incremental re-crawls record the links of every page, for later runs
+			// that skip it, sitemap reports need every linked page and link graphs
+			// every link.
+			depth := batchItems[i].Depth
+			crawlResult.Depth = depth
+			html := crawlResult.HTML
+			extractedURLs := crawlResult.Links
+			if !crawlResult.Unchanged {
+				var err error
+				extractedURLs, err = c.extractLinks(crawlResult)
 				if err != nil {
 					c.logger.Warn("Failed to extract URLs from page", map[string]interface{}{
-						"url": crawlResult.URL,
+						"url":   crawlResult.URL,
 						"error": err,
 					})
-					continue
 				}
-				
-				// Filter and add new URLs to frontier
-				filteredURLs := c.filterURLsForRecursive(extractedURLs, startURL, visited)
+				crawlResult.Links = extractedURLs
+			}
+			if c.sitemapReport {
+				for _, url := range extractedURLs {
+					if !discoveredSet[url] {
+						discoveredSet[url] = true
+						discovered = append(discovered, url)
+					}
+				}
+			}
+			// Pagination links, <link rel="next"> included, are followed up to
+			// max_pagination_pages pages into each listing
+			position, found := listingPositions[crawlResult.URL]
+			if !found {
+				position = pageNumber(crawlResult.FinalURL())
+			}
+			nextPages := c.paginationURLs(html, crawlResult.FinalURL(), position)
+			extractedURLs = appendMissing(extractedURLs, nextPages)
+			if c.maxPagination > 0 {
+				extractedURLs = keepURLs(extractedURLs, func(url string) bool {
+					next, found := nextPages[url]
+					return !found || next <= c.maxPagination
+				})
+			}
+			if depth >= depths.Deepest() {
+				extractedURLs = keepURLs(extractedURLs, func(url string) bool {
+					_, next := nextPages[url]
+					return next
+				})
+			}
+
+			// Alternates of the page in languages that are not crawled are
+			// not followed
+			if otherLanguages := c.otherLanguageURLs(html, crawlResult.FinalURL()); len(otherLanguages) > 0 {
+				extractedURLs = keepURLs(extractedURLs, func(url string) bool { return !otherLanguages[url] })
+			}
+
+			// Pages marked nofollow keep their links but do not add them
+			// to the frontier
+			if c.metaRobots(crawlResult).NoFollow {
+				c.logger.Info("Not following links of nofollow page", map[string]interface{}{"url": crawlResult.URL})
+				extractedURLs = nil
+			}
+
+			// Without discovery only the seeds, and the entries of their
+			// feeds, are crawled
+			if !c.followsLinks() {
+				extractedURLs = nil
+			}
+
+			// Filter and add new URLs to frontier; with crawl_content_types,
+			// links to media are downloaded with the media of the page instead
+			filteredURLs := c.filterURLsForRecursive(ctx, extractedURLs, startURL, visited)
+			filteredURLs, linkedMedia := c.classifyLinks(ctx, filteredURLs)
+			crawlResult.Media.add(linkedMedia)
+			visitedCount, _ := visited.Len(ctx)
+			if visitedCount < maxURLs {
 				for _, url := range filteredURLs {
-					if len(visited) < maxURLs {
-						newFrontierItems = append(newFrontierItems, URLWithDepth{
-							URL:   url,
-							Depth: currentBatch[i].Depth + 1,
-						})
+					if quota.full(url) {
+						continue
+					}
+					item := URLWithDepth{
+						URL:    url,
+						Depth:  depth + 1,
+						Parent: crawlResult.URL,
+						Score:  scoreURL(url),
+					}
+					if next, found := nextPages[url]; found {
+						item.Depth = depth
+						item.Score += paginationPriority
+						listingPositions[url] = next
 					}
+					newFrontierItems = append(newFrontierItems, item)
 				}
 			}
+
+			// Add to results
+			allResults = append(allResults, crawlResult)
+			tree.result(crawlResult)
+		}
+		pending = nil
+
+		// Add new URLs to frontier, keeping them for the checkpoint if the
+		// crawl was cancelled meanwhile
+		if err := urlFrontier.Push(ctx, newFrontierItems); err != nil {
+			if ctx.Err() != nil {
+				pending = newFrontierItems
+			} else {
+				c.logger.Warn("Failed to add URLs to frontier", map[string]interface{}{
+					"count": len(newFrontierItems),
+					"error": err,
+				})
+			}
+		}
+		tree.queued(newFrontierItems)
+		c.flushCrawlTree(tree, false)
+
+		// Hand the batch over before crawling the next one, so a slow handler
+		// holds back the crawl instead of letting pages pile up in memory. A
+		// cancelled crawl still hands over the pages it crawled.
+		c.memory.hold(allResults[batchStart:])
+		if c.batchHandler != nil {
+			_, span := tracing.Start(ctx, "crawl.batch.handoff", attribute.Int("crawl.batch.pages", len(allResults)-batchStart))
+			c.batchHandler(ctx, append([]PageResult(nil), allResults[batchStart:]...))
+			span.End()
+			for i := batchStart; i < len(allResults); i++ {
+				allResults[i] = allResults[i].withoutContent()
+			}
+		}
+
+		frontierSize, _ := urlFrontier.Len(ctx)
+		visitedCount, _ := visited.Len(ctx)
+		if c.frontierHandler != nil {
+			c.frontierHandler(frontierSize)
 		}
-		
-		// Add new URLs to frontier
-		frontier = append(newFrontierItems, frontier...)
-		
 		c.logger.Info("Batch completed", map[string]interface{}{
-			"batchSize": len(batchURLs),
-			"resultsCount": len(result.Results),
-			"newURLs": len(newFrontierItems),
-			"frontierSize": len(frontier),
-			"visitedCount": len(visited),
+			"batchSize":      len(batchURLs),
+			"resultsCount":   len(batchResults),
+			"newURLs":        len(newFrontierItems),
+			"frontierSize":   frontierSize,
+			"visitedCount":   visitedCount,
 			"processedCount": len(allResults),
-			"maxURLs": maxURLs,
+			"maxURLs":        maxURLs,
 		})
 	}
-	
-	// Log frontier exhaustion
-	if len(frontier) == 0 {
+
+	// Log frontier exhaustion, or save where a cancelled crawl stopped. The
+	// state is still read once ctx is cancelled.
+	stateCtx := context.WithoutCancel(ctx)
+	frontierSize, _ := urlFrontier.Len(stateCtx)
+	visitedCount, _ := visited.Len(stateCtx)
+	cancelled := ctx.Err() != nil
+	if cancelled {
+		c.logger.Warn("Batch crawling cancelled", map[string]interface{}{
+			"processedURLs": len(allResults),
+			"reason":        ctx.Err().Error(),
+		})
+		c.writeCheckpoint(ctx, &Checkpoint{
+			StartURL:    startURL,
+			CancelledAt: time.Now().UTC(),
+			Reason:      ctx.Err().Error(),
+			Processed:   len(allResults),
+			Pending:     pending,
+		}, urlFrontier, visited)
+	} else if frontierSize == 0 {
 		c.logger.Info("Frontier exhausted - batch crawling completed", map[string]interface{}{
 			"finalProcessedCount": len(allResults),
-			"totalVisited": len(visited),
-			"maxURLsReached": len(visited) >= maxURLs,
+			"totalVisited":        visitedCount,
+			"maxURLsReached":      visitedCount >= maxURLs,
 		})
 	}
-	
+
 	// Create combined response
 	combinedResponse := &StartCrawlResponse{
-		Success: len(allResults) > 0,
-		Results: allResults,
+		Success:    len(allResults) > 0,
+		Results:    allResults,
+		Discovered: discovered,
+		Complete:   frontierSize == 0 && failedBatches == 0 && !cancelled,
+		Cancelled:  cancelled,
+
+		DurationExceeded: durationExceeded,
+		Circuits:         c.breaker.States(),
+
+		ServerProcessingTimeS: serverTime,
 	}
-	
+
 	c.logger.Info("Batch recursive crawling completed", map[string]interface{}{
 		"totalResults": len(allResults),
-		"visitedURLs": len(visited),
-		"startURL": startURL,
-		"maxDepth": maxDepth,
-		"maxURLs": maxURLs,
-		"batchSize": batchSize,
+		"visitedURLs":  visitedCount,
+		"startURL":     startURL,
+		"maxDepth":     maxDepth,
+		"maxURLs":      maxURLs,
+		"batchSize":    batchSize,
 	})
-	
+
 	return combinedResponse, nil
 }
 
@@ -522,80 +1210,87 @@ func (c *Crawler) filterURLs(urls []string, baseURL string, maxCount int) []stri
 	if err != nil {
 		c.logger.Error("Failed to parse base URL for filtering", map[string]interface{}{
 			"baseURL": baseURL,
-			"error": err,
+			"error":   err,
 		})
 		return urls[:min(maxCount, len(urls))]
 	}
-	
+
 	baseDomain := base.Hostname()
-	
+
 	for _, url := range urls {
 		if len(filtered) >= maxCount {
 			break
 		}
-		
+
 		parsed, err := neturl.Parse(url)
 		if err != nil {
 			continue
 		}
-		
-		// Stay within the same domain
-		if parsed.Hostname() == baseDomain {
+
+		// Stay within the crawl scope and skip excluded URLs
+		if c.inScope(parsed.Hostname(), baseDomain) && !c.isExcluded(url) {
 			filtered = append(filtered, url)
 		}
 	}
-	
+
 	c.logger.Info("Filtered URLs", map[string]interface{}{
 		"originalCount": len(urls),
 		"filteredCount": len(filtered),
-		"baseDomain": baseDomain,
-		"maxCount": maxCount,
+		"baseDomain":    baseDomain,
+		"maxCount":      maxCount,
 	})
-	
+
 	return filtered
 }
 
 // filterURLsForRecursive filters URLs for recursive crawling, avoiding already visited URLs
-func (c *Crawler) filterURLsForRecursive(urls []string, baseURL string, visited map[string]bool) []string {
+func (c *Crawler) filterURLsForRecursive(ctx context.Context, urls []string, baseURL string, visited frontier.VisitedSet) []string {
 	var filtered []string
 	base, err := neturl.Parse(baseURL)
 	if err != nil {
 		c.logger.Error("Failed to parse base URL for filtering", map[string]interface{}{
 			"baseURL": baseURL,
-			"error": err,
+			"error":   err,
 		})
 		return urls
 	}
-	
+
 	baseDomain := base.Hostname()
-	
-	for _, url := range urls {
+
+	seen, err := visited.Contains(ctx, urls)
+	if err != nil {
+		c.logger.Warn("Failed to check visited URLs", map[string]interface{}{
+			"error": err,
+		})
+		seen = make([]bool, len(urls))
+	}
+
+	for i, url := range urls {
 		// Skip if already visited
-		if visited[url] {
+		if seen[i] {
 			continue
 		}
-		
+
 		parsed, err := neturl.Parse(url)
 		if err != nil {
 			continue
 		}
-		
-		// Stay within the same domain
-		if parsed.Hostname() == baseDomain {
+
+		// Stay within the crawl scope and skip excluded URLs
+		if c.inScope(parsed.Hostname(), baseDomain) && !c.isExcluded(url) {
 			filtered = append(filtered, url)
 		}
 	}
-	
+
 	// Sort URLs by priority (high-value discovery pages first)
 	filtered = c.prioritizeURLs(filtered)
-	
+
 	c.logger.Info("Filtered URLs for recursive crawling", map[string]interface{}{
 		"originalCount": len(urls),
 		"filteredCount": len(filtered),
-		"baseDomain": baseDomain,
-		"visitedCount": len(visited),
+		"baseDomain":    baseDomain,
 	})
-	
+
 	return filtered
 }
 
@@ -605,63 +1300,18 @@ func (c *Crawler) prioritizeURLs(urls []string) []string {
 	if len(urls) <= 1 {
 		return urls
 	}
-	
-	// Define high-value discovery patterns
-	discoveryPatterns := []string{
-		"/overview",
-		"/docs", 
-		"/documentation",
-		"/api",
-		"/components",
-		"/reference",
-		"/guides",
-		"/examples",
-		"/tutorials",
-		"/index",
-		"/introduction",
-		"/getting-started",
-	}
-	
+
 	// Calculate priority scores
 	type URLScore struct {
 		URL   string
 		Score int
 	}
-	
+
 	var scoredURLs []URLScore
 	for _, url := range urls {
-		score := 0
-		lowerURL := strings.ToLower(url)
-		
-		// High priority for discovery patterns
-		for _, pattern := range discoveryPatterns {
-			if strings.Contains(lowerURL, pattern) {
-				score += 10
-				break
-			}
-		}
-		
-		// Additional scoring based on URL characteristics
-		if strings.Contains(lowerURL, "/list") {
-			score += 8
-		}
-		if strings.HasSuffix(lowerURL, "/") {
-			score += 3 // Index pages
-		}
-		if !strings.Contains(lowerURL, "#") {
-			score += 2 // Prefer pages without anchors
-		}
-		
-		// Penalize certain patterns
-		if strings.Contains(lowerURL, "/demo") ||
-		   strings.Contains(lowerURL, "/example") ||
-		   strings.Contains(lowerURL, "/playground") {
-			score -= 5
-		}
-		
-		scoredURLs = append(scoredURLs, URLScore{URL: url, Score: score})
+		scoredURLs = append(scoredURLs, URLScore{URL: url, Score: scoreURL(url)})
 	}
-	
+
 	// Sort by score (descending)
 	for i := 0; i < len(scoredURLs)-1; i++ {
 		for j := i + 1; j < len(scoredURLs); j++ {
@@ -670,28 +1320,85 @@ func (c *Crawler) prioritizeURLs(urls []string) []string {
 			}
 		}
 	}
-	
+
 	// Extract sorted URLs
 	var result []string
 	for _, scored := range scoredURLs {
 		result = append(result, scored.URL)
 	}
-	
+
 	c.logger.Debug("URL prioritization completed", map[string]interface{}{
 		"urlCount": len(urls),
-		"topScore": func() int { if len(scoredURLs) > 0 { return scoredURLs[0].Score } else { return 0 } }(),
-		"samplePrioritized": func() []string { 
-			if len(result) > 3 { 
-				return result[:3] 
-			} else { 
-				return result 
-			} 
+		"topScore": func() int {
+			if len(scoredURLs) > 0 {
+				return scoredURLs[0].Score
+			} else {
+				return 0
+			}
+		}(),
+		"samplePrioritized": func() []string {
+			if len(result) > 3 {
+				return result[:3]
+			} else {
+				return result
+			}
 		}(),
 	})
-	
+
 	return result
 }
 
+// discoveryPatterns mark high-value discovery pages
+var discoveryPatterns = []string{
+	"/overview",
+	"/docs",
+	"/documentation",
+	"/api",
+	"/components",
+	"/reference",
+	"/guides",
+	"/examples",
+	"/tutorials",
+	"/index",
+	"/introduction",
+	"/getting-started",
+}
+
+// scoreURL rates how likely a URL is to lead to many links, ranking the
+// frontier for best-first traversal
+func scoreURL(url string) int {
+	score := 0
+	lowerURL := strings.ToLower(url)
+
+	// High priority for discovery patterns
+	for _, pattern := range discoveryPatterns {
+		if strings.Contains(lowerURL, pattern) {
+			score += 10
+			break
+		}
+	}
+
+	// Additional scoring based on URL characteristics
+	if strings.Contains(lowerURL, "/list") {
+		score += 8
+	}
+	if strings.HasSuffix(lowerURL, "/") {
+		score += 3 // Index pages
+	}
+	if !strings.Contains(lowerURL, "#") {
+		score += 2 // Prefer pages without anchors
+	}
+
+	// Penalize certain patterns
+	if strings.Contains(lowerURL, "/demo") ||
+		strings.Contains(lowerURL, "/example") ||
+		strings.Contains(lowerURL, "/playground") {
+		score -= 5
+	}
+
+	return score
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -699,79 +1406,55 @@ func min(a, b int) int {
 	return b
 }
 
-// StartCrawlWithRetry starts a crawling job with retry logic
+// StartCrawlWithRetry starts a crawling job, retrying it up to maxRetries
+// times on retryable errors with the backoff of the retry policy
 func (c *Crawler) StartCrawlWithRetry(ctx context.Context, urls []string, includeMedia *bool, maxDepth int, excludeExternalLinks bool, maxURLs int, maxRetries int) (*StartCrawlResponse, error) {
 	var lastErr error
-	
-	for attempt := 0; attempt <= maxRetries; attempt++ {
+
+	attempt := 0
+	for ; attempt <= maxRetries; attempt++ {
 		if attempt > 0 {
+			wait := c.retry.delay(attempt, lastErr)
 			c.logger.Info("Retrying crawl", map[string]interface{}{
-				"attempt": attempt + 1,
+				"attempt":    attempt + 1,
 				"maxRetries": maxRetries + 1,
-				"urlCount": len(urls),
+				"urlCount":   len(urls),
+				"wait":       wait.String(),
 			})
-			
-			// Add exponential backoff
-			backoffDuration := time.Duration(attempt*attempt) * time.Second
+
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
-			case <-time.After(backoffDuration):
+			case <-time.After(wait):
 				// Continue with retry
 			}
 		}
-		
+
 		result, err := c.StartCrawlWithConfig(ctx, urls, includeMedia, maxDepth, excludeExternalLinks, maxURLs)
 		if err == nil {
 			return result, nil
 		}
-		
+
 		lastErr = err
 		c.logger.Warn("Crawl attempt failed", map[string]interface{}{
-			"attempt": attempt + 1,
-			"error": err,
+			"attempt":  attempt + 1,
+			"error":    err,
 			"urlCount": len(urls),
 		})
+		if !retryable(err) {
+			attempt++
+			break
+		}
 	}
-	
-	return nil, fmt.Errorf("crawl failed after %d attempts: %w", maxRetries+1, lastErr)
+
+	return nil, fmt.Errorf("crawl failed after %d attempts: %w", attempt, lastErr)
 }
 
 // CreateSingleResultResponse creates a StartCrawlResponse for a single result
-func (c *Crawler) CreateSingleResultResponse(result interface{}) *StartCrawlResponse {
+func (c *Crawler) CreateSingleResultResponse(result PageResult) *StartCrawlResponse {
 	return &StartCrawlResponse{
 		Success: true,
-		Results: []struct {
-			URL             string `json:"url"`
-			HTML            string `json:"html"`
-			Success         bool   `json:"success"`
-			CleanedHTML     string `json:"cleaned_html"`
-			Markdown        struct {
-				RawMarkdown         string `json:"raw_markdown"`
-				MarkdownWithCitations string `json:"markdown_with_citations"`
-			} `json:"markdown"`
-			Media           struct {
-				Images []struct {
-					URL string `json:"url"`
-				} `json:"images"`
-			} `json:"media"`
-			Metadata        map[string]interface{} `json:"metadata"`
-		}{result.(struct {
-			URL             string `json:"url"`
-			HTML            string `json:"html"`
-			Success         bool   `json:"success"`
-			CleanedHTML     string `json:"cleaned_html"`
-			Markdown        struct {
-				RawMarkdown         string `json:"raw_markdown"`
-				MarkdownWithCitations string `json:"markdown_with_citations"`
-			} `json:"markdown"`
-			Media           struct {
-				Images []struct {
-					URL string `json:"url"`
-				} `json:"images"`
-			} `json:"media"`
-			Metadata        map[string]interface{} `json:"metadata"`
-		})},
+		Results: []PageResult{result},
 	}
 }
 
@@ -785,15 +1468,11 @@ func (r *StartCrawlResponse) ConvertToCrawlResult() *CrawlResult {
 			Markdown struct {
 				RawMarkdown string `json:"raw_markdown"`
 			} `json:"markdown"`
-			Media struct {
-				Images []struct {
-					URL string `json:"url"`
-				} `json:"images"`
-			} `json:"media"`
+			Media    PageMedia              `json:"media"`
 			Metadata map[string]interface{} `json:"metadata,omitempty"`
 		}{}}
 	}
-	
+
 	result := &CrawlResult{
 		Success: r.Success,
 		Results: make([]struct {
@@ -803,15 +1482,11 @@ func (r *StartCrawlResponse) ConvertToCrawlResult() *CrawlResult {
 			Markdown struct {
 				RawMarkdown string `json:"raw_markdown"`
 			} `json:"markdown"`
-			Media struct {
-				Images []struct {
-					URL string `json:"url"`
-				} `json:"images"`
-			} `json:"media"`
+			Media    PageMedia              `json:"media"`
 			Metadata map[string]interface{} `json:"metadata,omitempty"`
 		}, len(r.Results)),
 	}
-	
+
 	for i, res := range r.Results {
 		result.Results[i] = struct {
 			URL      string `json:"url"`
@@ -820,11 +1495,7 @@ func (r *StartCrawlResponse) ConvertToCrawlResult() *CrawlResult {
 			Markdown struct {
 				RawMarkdown string `json:"raw_markdown"`
 			} `json:"markdown"`
-			Media struct {
-				Images []struct {
-					URL string `json:"url"`
-				} `json:"images"`
-			} `json:"media"`
+			Media    PageMedia              `json:"media"`
 			Metadata map[string]interface{} `json:"metadata,omitempty"`
 		}{
 			URL:     res.URL,
@@ -839,13 +1510,13 @@ func (r *StartCrawlResponse) ConvertToCrawlResult() *CrawlResult {
 			Metadata: res.Metadata,
 		}
 	}
-	
+
 	return result
 }
 
 // DownloadAndSaveMediaFromStartResponse downloads and saves media files directly from StartCrawlResponse
 func (c *Crawler) DownloadAndSaveMediaFromStartResponse(ctx context.Context, startResp *StartCrawlResponse, progressReporter *progress.ProgressReporter) ([]*storage.FileInfo, error) {
-	if !c.includeMedia || len(startResp.Results) == 0 || len(startResp.Results[0].Media.Images) == 0 {
+	if len(startResp.Results) == 0 || !c.includesMedia(startResp.Results[0].URL) || startResp.Results[0].Media.Count() == 0 {
 		return nil, nil
 	}
 
@@ -853,130 +1524,50 @@ func (c *Crawler) DownloadAndSaveMediaFromStartResponse(ctx context.Context, sta
 		return nil, errors.New(errors.StorageError, "storage not initialized")
 	}
 
-	var savedFiles []*storage.FileInfo
-
-	for i, mediaFile := range startResp.Results[0].Media.Images {
-		select {
-		case <-ctx.Done():
-			return savedFiles, ctx.Err()
-		default:
-		}
-
-		// Update progress
-		progressReporter.SetCurrent(i)
+	total := startResp.Results[0].Media.Count()
+	jobs := c.resolveMediaJobs(startResp.Results[0].URL, startResp.Results[0].Media)
 
-		// Resolve the media URL
-		mediaURL, err := neturl.Parse(mediaFile.URL)
-		if err != nil {
-			c.logger.Error("Failed to resolve media URL", map[string]interface{}{
-				"url":   mediaFile.URL,
-				"error": err,
-			})
-			continue
-		}
+	// Media that is skipped counts as done
+	progressReporter.SetCurrent(total - len(jobs))
 
-		// Make the media URL absolute if it's relative
-		if !mediaURL.IsAbs() {
-			baseURL, err := neturl.Parse(startResp.Results[0].URL)
-			if err != nil {
-				c.logger.Error("Failed to parse base URL", map[string]interface{}{
-					"url":   startResp.Results[0].URL,
-					"error": err,
-				})
-				continue
-			}
-			mediaURL = baseURL.ResolveReference(mediaURL)
-		}
-
-		// Download the media file
-		resp, err := c.client.Get(mediaURL.String())
-		if err != nil {
-			c.logger.Error("Failed to download media file", map[string]interface{}{
-				"url":   mediaURL.String(),
-				"error": err,
-			})
-			continue
-		}
-		defer resp.Body.Close()
-
-		// Check if the response is successful
-		if resp.StatusCode != http.StatusOK {
-			c.logger.Error("Failed to download media file", map[string]interface{}{
-				"url":        mediaURL.String(),
-				"statusCode": resp.StatusCode,
-			})
-			continue
-		}
-
-		// Save the media file
-		fileInfo, err := c.storage.SaveMediaFile(resp.Body, mediaURL.String(), "")
-		if err != nil {
-			c.logger.Error("Failed to save media file", map[string]interface{}{
-				"url":   mediaURL.String(),
-				"error": err,
-			})
-			continue
-		}
-
-		c.logger.Info("Saved media file", map[string]interface{}{
-			"path": fileInfo.Path,
-			"size": fileInfo.Size,
-		})
-
-		savedFiles = append(savedFiles, fileInfo)
+	hints := parseLicenseHints(startResp.Results[0].HTML, startResp.Results[0].URL)
+	savedFiles, err := c.downloadBudgetedMedia(ctx, jobs, hints, progressReporter)
+	if err != nil {
+		return savedFiles, err
 	}
+	c.saveMediaLinks(startResp.Results[0].URL, startResp.Results[0].Media)
 
 	// Mark progress as complete
-	progressReporter.SetCurrent(len(startResp.Results[0].Media.Images))
+	progressReporter.SetCurrent(total)
 
 	return savedFiles, nil
 }
 
-// DownloadAndSaveMedia downloads and saves media files from the crawl result
+// recordMedia adds a saved media file to the library manifest
+func (c *Crawler) recordMedia(fileInfo *storage.FileInfo, statusCode int) {
+	if err := c.storage.RecordMedia(fileInfo, statusCode, time.Now()); err != nil {
+		c.logger.Warn("Failed to record media file in manifest", map[string]interface{}{
+			"path":  fileInfo.Path,
+			"error": err,
+		})
+	}
+}
+
+// DownloadAndSaveMedia downloads and saves the images of the crawl result,
+// one at a time
 func (c *Crawler) DownloadAndSaveMedia(ctx context.Context, result *CrawlResult) ([]*storage.FileInfo, error) {
-	if !c.includeMedia || c.storage == nil || len(result.Results) == 0 || len(result.Results[0].Media.Images) == 0 {
+	if c.storage == nil || len(result.Results) == 0 || !c.includesMedia(result.Results[0].URL) || len(result.Results[0].Media.Images) == 0 {
 		return nil, nil
 	}
 
 	var savedFiles []*storage.FileInfo
-
-	for _, mediaFile := range result.Results[0].Media.Images {
-		// Parse the media URL to make it absolute if it's relative
-		mediaURL, err := c.resolveURL(result.Results[0].Metadata, mediaFile.URL)
-		if err != nil {
-			c.logger.Error("Failed to resolve media URL", map[string]interface{}{
-				"url":   mediaFile.URL,
-				"error": err,
-			})
-			continue
+	page := result.Results[0]
+	for _, job := range c.resolveMediaJobs(page.URL, PageMedia{Images: page.Media.Images}) {
+		if err := ctx.Err(); err != nil {
+			return savedFiles, err
 		}
-
-		// Download the media file
-		fileData, err := c.downloadFile(ctx, mediaURL)
-		if err != nil {
-			c.logger.Error("Failed to download media file", map[string]interface{}{
-				"url":   mediaURL,
-				"error": err,
-			})
-			continue
-		}
-
-		// Save the media file using the storage system
-		fileInfo, err := c.storage.SaveMedia(fileData, mediaURL, "")
-		if err != nil {
-			c.logger.Error("Failed to save media file", map[string]interface{}{
-				"url":   mediaURL,
-				"error": err,
-			})
-			continue
-		}
-
-		if fileInfo != nil {
+		if fileInfo := c.downloadMediaFile(ctx, job, nil); fileInfo != nil {
 			savedFiles = append(savedFiles, fileInfo)
-			c.logger.Info("Saved media file", map[string]interface{}{
-				"path": fileInfo.Path,
-				"size": fileInfo.Size,
-			})
 		}
 	}
 
@@ -985,7 +1576,7 @@ func (c *Crawler) DownloadAndSaveMedia(ctx context.Context, result *CrawlResult)
 
 // DownloadAndSaveMediaWithProgress downloads and saves media files with progress reporting
 func (c *Crawler) DownloadAndSaveMediaWithProgress(ctx context.Context, result *CrawlResult, progressReporter *progress.ProgressReporter) ([]*storage.FileInfo, error) {
-	if !c.includeMedia || len(result.Results) == 0 || len(result.Results[0].Media.Images) == 0 {
+	if len(result.Results) == 0 || !c.includesMedia(result.Results[0].URL) || result.Results[0].Media.Count() == 0 {
 		return nil, nil
 	}
 
@@ -993,141 +1584,110 @@ func (c *Crawler) DownloadAndSaveMediaWithProgress(ctx context.Context, result *
 		return nil, errors.New(errors.StorageError, "storage not initialized")
 	}
 
-	var savedFiles []*storage.FileInfo
-
-	for i, mediaFile := range result.Results[0].Media.Images {
-		select {
-		case <-ctx.Done():
-			return savedFiles, ctx.Err()
-		default:
-		}
+	total := result.Results[0].Media.Count()
+	jobs := c.resolveMediaJobs(result.Results[0].URL, result.Results[0].Media)
 
-		// Update progress
-		progressReporter.SetCurrent(i)
+	// Media that is skipped counts as done
+	progressReporter.SetCurrent(total - len(jobs))
 
-		// Resolve the media URL
-		mediaURL, err := neturl.Parse(mediaFile.URL)
-		if err != nil {
-			c.logger.Error("Failed to resolve media URL", map[string]interface{}{
-				"url":   mediaFile.URL,
-				"error": err,
-			})
-			continue
-		}
-
-		// Make the media URL absolute if it's relative
-		if !mediaURL.IsAbs() {
-			baseURL, err := neturl.Parse(result.Results[0].URL)
-			if err != nil {
-				c.logger.Error("Failed to parse base URL", map[string]interface{}{
-					"url":   result.Results[0].URL,
-					"error": err,
-				})
-				continue
-			}
-			mediaURL = baseURL.ResolveReference(mediaURL)
-		}
-
-		// Download the media file
-		resp, err := c.client.Get(mediaURL.String())
-		if err != nil {
-			c.logger.Error("Failed to download media file", map[string]interface{}{
-				"url":   mediaURL.String(),
-				"error": err,
-			})
-			continue
-		}
-		defer resp.Body.Close()
-
-		// Check if the response is successful
-		if resp.StatusCode != http.StatusOK {
-			c.logger.Error("Failed to download media file", map[string]interface{}{
-				"url":        mediaURL.String(),
-				"statusCode": resp.StatusCode,
-			})
-			continue
-		}
-
-		// Save the media file
-		fileInfo, err := c.storage.SaveMediaFile(resp.Body, mediaURL.String(), "")
-		if err != nil {
-			c.logger.Error("Failed to save media file", map[string]interface{}{
-				"url":   mediaURL.String(),
-				"error": err,
-			})
-			continue
-		}
-
-		c.logger.Info("Saved media file", map[string]interface{}{
-			"path": fileInfo.Path,
-			"size": fileInfo.Size,
-		})
-
-		savedFiles = append(savedFiles, fileInfo)
+	hints := parseLicenseHints(result.Results[0].HTML, result.Results[0].URL)
+	savedFiles, err := c.downloadBudgetedMedia(ctx, jobs, hints, progressReporter)
+	if err != nil {
+		return savedFiles, err
 	}
+	c.saveMediaLinks(result.Results[0].URL, result.Results[0].Media)
 
 	// Mark progress as complete
-	progressReporter.SetCurrent(len(result.Results[0].Media.Images))
+	progressReporter.SetCurrent(total)
 
 	return savedFiles, nil
 }
 
-// resolveURL resolves a potentially relative URL based on the context
-func (c *Crawler) resolveURL(metadata map[string]interface{}, mediaURL string) (string, error) {
-	// If the URL is already absolute, return it as is
-	if strings.HasPrefix(mediaURL, "http://") || strings.HasPrefix(mediaURL, "https://") {
-		return mediaURL, nil
-	}
-
-	// Try to get the base URL from metadata
-	baseURLStr, ok := metadata["base_url"].(string)
-	if !ok {
-		return "", fmt.Errorf("base URL not found in metadata")
-	}
+// getOrigin performs a GET request against an origin server, applying any
+// credentials configured for its domain
+func (c *Crawler) getOrigin(ctx context.Context, fileURL string) (*http.Response, error) {
+	return c.requestOrigin(ctx, http.MethodGet, fileURL, nil)
+}
 
-	// Parse the base URL
-	baseURL, err := neturl.Parse(baseURLStr)
+// requestOrigin sends a request with extra headers to an origin server,
+// applying any credentials configured for its domain
+func (c *Crawler) requestOrigin(ctx context.Context, method string, fileURL string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fileURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse base URL: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-
-	// Parse the media URL
-	mediaURLParsed, err := neturl.Parse(mediaURL)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse media URL: %w", err)
+	c.setUserAgent(req)
+	for name, values := range header {
+		req.Header[name] = values
 	}
 
-	// Resolve the media URL against the base URL
-	resolvedURL := baseURL.ResolveReference(mediaURLParsed)
+	c.applyOriginAuth(req)
 
-	return resolvedURL.String(), nil
+	return c.client.Do(req)
 }
 
-// downloadFile downloads a file from the given URL
-func (c *Crawler) downloadFile(ctx context.Context, fileURL string) (io.Reader, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// applyOriginAuth adds the headers and credentials configured for the
+// request's domain, those of its domain policy replacing the auth section.
+// The crawl4ai token is deliberately never sent to origin servers.
+func (c *Crawler) applyOriginAuth(req *http.Request) {
+	if policy := c.domains.lookup(req.URL.Hostname()); policy != nil {
+		policy.apply(req)
+		if policy.scheme != nil {
+			c.logger.Debug("Applied domain policy credentials", map[string]interface{}{
+				"host":   req.URL.Hostname(),
+				"domain": policy.domain,
+			})
+			return
+		}
 	}
-
-	// Set headers to mimic a browser
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-	req.Header.Set("Accept", "image/webp,image/apng,image/*,*/*;q=0.8")
-
-	if c.authToken != "" {
-		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	if c.originAuth.Apply(req) {
+		c.logger.Debug("Applied origin credentials", map[string]interface{}{
+			"host": req.URL.Hostname(),
+		})
 	}
+}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download file: %w", err)
+// originHeader returns the headers and credentials applyOriginAuth sets on
+// the requests to host
+func (c *Crawler) originHeader(host string) http.Header {
+	req := &http.Request{URL: &neturl.URL{Host: host}, Header: make(http.Header)}
+	policy := c.domains.lookup(host)
+	if policy != nil {
+		policy.apply(req)
 	}
-
-	// Check if the response is successful
-	if resp.StatusCode != http.StatusOK {
-		resp.Body.Close()
-		return nil, fmt.Errorf("failed to download file, status code: %d", resp.StatusCode)
+	if policy == nil || policy.scheme == nil {
+		c.originAuth.Apply(req)
 	}
+	return req.Header
+}
 
-	return resp.Body, nil
+// maxRedirects is the number of redirects followed, as by http.Client by
+// default
+const maxRedirects = 10
+
+// checkRedirect is the redirect policy of the crawler's client. The client
+// copies the headers of the original request to each redirect, stripping
+// only Authorization and Cookie on cross-domain ones: the headers and
+// credentials configured for the original host are removed unless the same
+// apply to the redirect target, so that they never leak to another host.
+func (c *Crawler) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	from, to := via[0].URL.Hostname(), req.URL.Hostname()
+	if strings.EqualFold(from, to) {
+		return nil
+	}
+	target := c.originHeader(to)
+	for name, values := range c.originHeader(from) {
+		if !slices.Equal(target[name], values) {
+			req.Header.Del(name)
+			c.logger.Debug("Dropped origin header on redirect", map[string]interface{}{
+				"header": name,
+				"from":   from,
+				"to":     to,
+			})
+		}
+	}
+	return nil
 }
@@ -0,0 +1,195 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"crawlr/internal/auth"
+	"crawlr/internal/config"
+	"crawlr/internal/logger"
+)
+
+// newTestCrawler returns a crawler with the default configuration changed by
+// modify, discarding its logs
+func newTestCrawler(t *testing.T, modify func(cfg *config.Config)) *Crawler {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	if modify != nil {
+		modify(cfg)
+	}
+	return NewCrawler(cfg, logger.NewWithHandler(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// redirectServers starts an origin redirecting /away to the /target of
+// another host and /here to its own /target, both recording the headers
+// /target is requested with. The other host is the origin's own address
+// under the name localhost.
+func redirectServers(t *testing.T) (origin *httptest.Server, received chan http.Header) {
+	t.Helper()
+	received = make(chan http.Header, 1)
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	t.Cleanup(other.Close)
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+
+	origin = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/away":
+			http.Redirect(w, r, otherURL+"/target", http.StatusFound)
+		case "/here":
+			http.Redirect(w, r, "/target", http.StatusFound)
+		default:
+			received <- r.Header.Clone()
+		}
+	}))
+	t.Cleanup(origin.Close)
+	return origin, received
+}
+
+func TestCheckRedirectDropsCredentials(t *testing.T) {
+	tests := []struct {
+		name   string
+		auth   map[string]config.AuthConfig
+		path   string
+		header string
+		want   string
+	}{
+		{
+			name:   "same host",
+			auth:   map[string]config.AuthConfig{"127.0.0.1": {Type: "api_key", Token: "secret"}},
+			path:   "/here",
+			header: auth.DefaultAPIKeyHeader,
+			want:   "secret",
+		},
+		{
+			name:   "other host",
+			auth:   map[string]config.AuthConfig{"127.0.0.1": {Type: "api_key", Token: "secret"}},
+			path:   "/away",
+			header: auth.DefaultAPIKeyHeader,
+		},
+		{
+			name:   "other host named header",
+			auth:   map[string]config.AuthConfig{"127.0.0.1": {Type: "api_key", Token: "secret", Header: "X-Token"}},
+			path:   "/away",
+			header: "X-Token",
+		},
+		{
+			name: "other host with the same credentials",
+			auth: map[string]config.AuthConfig{
+				"127.0.0.1": {Type: "api_key", Token: "secret"},
+				"localhost": {Type: "api_key", Token: "secret"},
+			},
+			path:   "/away",
+			header: auth.DefaultAPIKeyHeader,
+			want:   "secret",
+		},
+		{
+			name: "other host with other credentials",
+			auth: map[string]config.AuthConfig{
+				"127.0.0.1": {Type: "api_key", Token: "secret"},
+				"localhost": {Type: "api_key", Token: "other"},
+			},
+			path:   "/away",
+			header: auth.DefaultAPIKeyHeader,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origin, received := redirectServers(t)
+			c := newTestCrawler(t, nil)
+			registry, err := auth.NewRegistry(tt.auth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetOriginAuth(registry)
+
+			resp, err := c.getOrigin(context.Background(), origin.URL+tt.path)
+			if err != nil {
+				t.Fatalf("getOrigin() error = %v", err)
+			}
+			resp.Body.Close()
+			if got := (<-received).Get(tt.header); got != tt.want {
+				t.Errorf("%s = %q after redirect, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRedirectDropsDomainHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		domains map[string]config.DomainPolicy
+		path    string
+		want    http.Header
+	}{
+		{
+			name: "same host",
+			domains: map[string]config.DomainPolicy{"127.0.0.1": {
+				Headers: map[string]string{"X-Tenant": "acme"},
+				Auth:    &config.AuthConfig{Type: "api_key", Token: "secret"},
+			}},
+			path: "/here",
+			want: http.Header{"X-Tenant": {"acme"}, "X-Api-Key": {"secret"}},
+		},
+		{
+			name: "other host",
+			domains: map[string]config.DomainPolicy{"127.0.0.1": {
+				Headers: map[string]string{"X-Tenant": "acme"},
+				Auth:    &config.AuthConfig{Type: "api_key", Token: "secret"},
+			}},
+			path: "/away",
+			want: http.Header{},
+		},
+		{
+			name: "other host sharing a header",
+			domains: map[string]config.DomainPolicy{
+				"127.0.0.1": {Headers: map[string]string{"X-Tenant": "acme", "X-Region": "eu"}},
+				"localhost": {Headers: map[string]string{"X-Tenant": "acme"}},
+			},
+			path: "/away",
+			want: http.Header{"X-Tenant": {"acme"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origin, received := redirectServers(t)
+			c := newTestCrawler(t, func(cfg *config.Config) { cfg.Domains = tt.domains })
+
+			resp, err := c.getOrigin(context.Background(), origin.URL+tt.path)
+			if err != nil {
+				t.Fatalf("getOrigin() error = %v", err)
+			}
+			resp.Body.Close()
+			header := <-received
+			for _, name := range []string{"X-Tenant", "X-Region", "X-Api-Key"} {
+				if got, want := header.Get(name), tt.want.Get(name); got != want {
+					t.Errorf("%s = %q after redirect, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckRedirectLimit(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL+r.URL.Path+"x", http.StatusFound)
+	}))
+	defer server.Close()
+
+	c := newTestCrawler(t, nil)
+	resp, err := c.getOrigin(context.Background(), server.URL+"/")
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("getOrigin() followed an endless redirect loop")
+	}
+	if !strings.Contains(err.Error(), "stopped after 10 redirects") {
+		t.Errorf("getOrigin() error = %v, want the redirect limit", err)
+	}
+}
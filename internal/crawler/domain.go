@@ -0,0 +1,199 @@
+package crawler
+
+import (
+	"context"
+	"math"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+
+	"crawlr/internal/auth"
+	"crawlr/internal/config"
+	"crawlr/internal/logger"
+
+	"golang.org/x/time/rate"
+)
+
+// domainPolicy is a compiled config.DomainPolicy
+type domainPolicy struct {
+	domain       string
+	headers      map[string]string
+	scheme       auth.Scheme   // nil to use the auth section
+	limiter      *rate.Limiter // shared by the domain and its subdomains, nil for no limit
+	includeMedia *bool
+}
+
+// domainPolicies holds the policies of the domains section, keyed by domain
+type domainPolicies map[string]*domainPolicy
+
+// newDomainPolicies compiles the policies of the domains section. Policies
+// with invalid credentials, which config validation rejects, are loaded
+// without them.
+func newDomainPolicies(domains map[string]config.DomainPolicy, log *logger.Logger) domainPolicies {
+	if len(domains) == 0 {
+		return nil
+	}
+	policies := make(domainPolicies, len(domains))
+	for domain, cfg := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		policy := &domainPolicy{
+			domain:       domain,
+			headers:      cfg.Headers,
+			includeMedia: cfg.IncludeMedia,
+		}
+		if cfg.RateLimit > 0 {
+			policy.limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), max(1, int(math.Ceil(cfg.RateLimit))))
+		}
+		if cfg.Auth != nil {
+			scheme, err := auth.NewScheme(*cfg.Auth)
+			if err != nil {
+				log.Error("Invalid credentials in domain policy", map[string]interface{}{
+					"domain": domain,
+					"error":  err,
+				})
+			} else {
+				policy.scheme = scheme
+			}
+		}
+		policies[domain] = policy
+	}
+	return policies
+}
+
+// lookup returns the policy applying to host, that of the host itself or of
+// its closest parent domain, or nil if there is none
+func (p domainPolicies) lookup(host string) *domainPolicy {
+	if len(p) == 0 {
+		return nil
+	}
+	for _, domain := range config.ParentDomains(host) {
+		if policy, ok := p[domain]; ok {
+			return policy
+		}
+	}
+	return nil
+}
+
+// forURL returns the policy applying to the host of rawURL, or nil
+func (p domainPolicies) forURL(rawURL string) *domainPolicy {
+	if len(p) == 0 {
+		return nil
+	}
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	return p.lookup(u.Hostname())
+}
+
+// key returns the domain of a policy, empty for the nil policy of the URLs
+// that have none
+func (p *domainPolicy) key() string {
+	if p == nil {
+		return ""
+	}
+	return p.domain
+}
+
+// wait blocks until the rate limit of the policy allows another request. A
+// nil policy never blocks.
+func (p *domainPolicy) wait(ctx context.Context) error {
+	if p == nil || p.limiter == nil {
+		return nil
+	}
+	return p.limiter.Wait(ctx)
+}
+
+// header returns the headers the policy sends with every request, its
+// credentials included, or nil if it sends none
+func (p *domainPolicy) header() http.Header {
+	if p == nil || (len(p.headers) == 0 && p.scheme == nil) {
+		return nil
+	}
+	req := &http.Request{Header: make(http.Header)}
+	p.apply(req)
+	return req.Header
+}
+
+// apply sets the headers and credentials of the policy on req
+func (p *domainPolicy) apply(req *http.Request) {
+	for name, value := range p.headers {
+		req.Header.Set(name, value)
+	}
+	if p.scheme != nil {
+		p.scheme.Apply(req)
+	}
+}
+
+// includesMedia reports whether the media of pageURL is downloaded: the
+// include_media of its domain policy if set, else the global setting
+func (c *Crawler) includesMedia(pageURL string) bool {
+	if policy := c.domains.forURL(pageURL); policy != nil && policy.includeMedia != nil {
+		return *policy.includeMedia
+	}
+	return c.includeMedia
+}
+
+// groupDomains orders the items of a batch by domain policy, keeping the
+// order of the items sharing one, so that each policy is crawled in a
+// request of its own
+func (c *Crawler) groupDomains(items []URLWithDepth) {
+	if len(c.domains) == 0 {
+		return
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return c.domains.forURL(items[i].URL).key() < c.domains.forURL(items[j].URL).key()
+	})
+}
+
+// domainRuns splits urls into runs of consecutive URLs sharing a domain
+// policy
+func (c *Crawler) domainRuns(urls []string) [][]string {
+	if len(c.domains) == 0 {
+		return [][]string{urls}
+	}
+	var runs [][]string
+	start := 0
+	for i := 1; i <= len(urls); i++ {
+		if i == len(urls) || c.domains.forURL(urls[i]).key() != c.domains.forURL(urls[start]).key() {
+			runs = append(runs, urls[start:i])
+			start = i
+		}
+	}
+	return runs
+}
+
+// browserConfig returns the crawl4ai browser configuration of a request for
+// urls, sharing a domain policy: the headers of the policy, or nil
+func (c *Crawler) browserConfig(urls []string) map[string]interface{} {
+	if len(urls) == 0 {
+		return nil
+	}
+	browser := make(map[string]interface{})
+	if agent := c.userAgents.pick(); agent != "" {
+		browser["user_agent"] = agent
+	}
+	if header := c.domains.forURL(urls[0]).header(); header != nil {
+		headers := make(map[string]string, len(header))
+		for name := range header {
+			headers[name] = header.Get(name)
+		}
+		browser["headers"] = headers
+	}
+	if len(browser) == 0 {
+		return nil
+	}
+	return browser
+}
+
+// waitDomains blocks until the rate limits of their domain policies allow a
+// request for each of urls
+func (c *Crawler) waitDomains(ctx context.Context, urls []string) error {
+	for _, url := range urls {
+		if err := c.domains.forURL(url).wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package crawler
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+// maxFeedSize bounds the size of a page or feed read to discover the entries
+// of a feed
+const maxFeedSize = 10 << 20
+
+// typePattern captures the type attribute of a tag
+var typePattern = regexp.MustCompile(`(?is)\btype\s*=\s*["']([^"']*)["']`)
+
+// feedTypes are the content types of the feeds declared with
+// <link rel="alternate">
+var feedTypes = []string{"application/rss+xml", "application/atom+xml"}
+
+// feedLink is a <link> of a feed entry: the text of an RSS link, or the href
+// and rel attributes of an Atom link
+type feedLink struct {
+	Text string `xml:",chardata"`
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// feedEntry is an RSS <item> or an Atom <entry>
+type feedEntry struct {
+	Links []feedLink `xml:"link"`
+}
+
+// followsLinks reports whether the links of crawled pages are added to the
+// frontier: not when only the start URLs, or the entries of their feeds, are
+// crawled
+func (c *Crawler) followsLinks() bool {
+	return c.discovery != "none" && c.discovery != "feed"
+}
+
+// feedEntries returns the pages listed in the RSS and Atom feeds declared by
+// the pages of urls, in feed order and once each, except those out of the
+// crawl scope of startURL or excluded. A page or feed that cannot be read is
+// skipped.
+func (c *Crawler) feedEntries(ctx context.Context, startURL string, urls []string) []string {
+	start, err := neturl.Parse(startURL)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var entries []string
+	var skipped int
+	for _, pageURL := range urls {
+		feeds, err := c.pageFeeds(ctx, pageURL)
+		if err != nil {
+			c.logger.Warn("Failed to discover feeds", map[string]interface{}{
+				"url":   pageURL,
+				"error": err,
+			})
+			continue
+		}
+		if len(feeds) == 0 {
+			c.logger.Warn("Page declares no feed", map[string]interface{}{"url": pageURL})
+			continue
+		}
+
+		for _, feedURL := range feeds {
+			if seen[feedURL] {
+				continue
+			}
+			seen[feedURL] = true
+
+			var found int
+			err := c.fetchFeed(ctx, feedURL, func(entryURL string) {
+				parsed, err := neturl.Parse(entryURL)
+				if err != nil || seen[entryURL] {
+					return
+				}
+				seen[entryURL] = true
+				if !c.inScope(parsed.Hostname(), start.Hostname()) || c.isExcluded(entryURL) {
+					skipped++
+					return
+				}
+				entries = append(entries, entryURL)
+				found++
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return entries
+				}
+				c.logger.Warn("Skipping unreadable feed", map[string]interface{}{
+					"url":   feedURL,
+					"page":  pageURL,
+					"error": err,
+				})
+				continue
+			}
+			c.logger.Info("Read feed", map[string]interface{}{
+				"url":     feedURL,
+				"entries": found,
+			})
+		}
+	}
+
+	if skipped > 0 {
+		c.logger.Info("Skipped feed entries out of scope", map[string]interface{}{"entries": skipped})
+	}
+	return entries
+}
+
+// pageFeeds downloads a page and returns the absolute URLs of the RSS and
+// Atom feeds it declares with <link rel="alternate"> tags
+func (c *Crawler) pageFeeds(ctx context.Context, pageURL string) ([]string, error) {
+	resp, err := c.getOrigin(ctx, pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", pageURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s, status code: %d", pageURL, resp.StatusCode)
+	}
+	html, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", pageURL, err)
+	}
+
+	var feeds []string
+	for _, tag := range linkTagPattern.FindAllString(string(html), -1) {
+		if !hasRel(tag, "alternate") || !isFeedType(tag) {
+			continue
+		}
+		href := hrefPattern.FindStringSubmatch(tag)
+		if href == nil {
+			continue
+		}
+		feedURL, err := c.makeAbsoluteURL(strings.TrimSpace(href[1]), pageURL)
+		if err != nil {
+			continue
+		}
+		feeds = append(feeds, feedURL)
+	}
+	return feeds, nil
+}
+
+// isFeedType reports whether the type attribute of a tag is that of an RSS
+// or Atom feed
+func isFeedType(tag string) bool {
+	match := typePattern.FindStringSubmatch(tag)
+	if match == nil {
+		return false
+	}
+	mediaType, _, _ := strings.Cut(match[1], ";")
+	for _, feedType := range feedTypes {
+		if strings.EqualFold(strings.TrimSpace(mediaType), feedType) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchFeed downloads an RSS or Atom feed and calls entry with the absolute
+// URL of every entry it lists, as they are decoded
+func (c *Crawler) fetchFeed(ctx context.Context, feedURL string, entry func(url string)) error {
+	resp, err := c.getOrigin(ctx, feedURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch feed %s: %w", feedURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch feed %s, status code: %d", feedURL, resp.StatusCode)
+	}
+
+	err = decodeFeed(io.LimitReader(resp.Body, maxFeedSize), func(link string) {
+		if entryURL, err := c.makeAbsoluteURL(link, feedURL); err == nil {
+			entry(entryURL)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("invalid feed %s: %w", feedURL, err)
+	}
+	return nil
+}
+
+// decodeFeed decodes the <item> entries of an RSS feed or the <entry>
+// entries of an Atom feed one at a time, calling entry with their links
+func decodeFeed(r io.Reader, entry func(link string)) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || (start.Name.Local != "item" && start.Name.Local != "entry") {
+			continue
+		}
+		var e feedEntry
+		if err := decoder.DecodeElement(&e, &start); err != nil {
+			return err
+		}
+		if link := e.link(); link != "" {
+			entry(link)
+		}
+	}
+}
+
+// link returns the link of an entry to its page: the text of an RSS link, or
+// the href of the alternate Atom link
+func (e feedEntry) link() string {
+	for _, l := range e.Links {
+		if text := strings.TrimSpace(l.Text); text != "" {
+			return text
+		}
+		if href := strings.TrimSpace(l.Href); href != "" && (l.Rel == "" || l.Rel == "alternate") {
+			return href
+		}
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HealthResponse is the body returned by the crawl4ai health endpoint
+type HealthResponse struct {
+	Status    string  `json:"status"`
+	Version   string  `json:"version"`
+	Timestamp float64 `json:"timestamp,omitempty"`
+}
+
+// EndpointCheck is the answer of a crawl4ai endpoint to a ping
+type EndpointCheck struct {
+	Path       string        `json:"path"`
+	StatusCode int           `json:"status_code,omitempty"` // 0 if the server did not answer
+	Latency    time.Duration `json:"-"`
+	LatencyMs  float64       `json:"latency_ms"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Answered reports whether the server answered the request, whatever its
+// status
+func (e EndpointCheck) Answered() bool {
+	return e.StatusCode != 0
+}
+
+// Rejected reports whether the server refused the request for lack of valid
+// credentials
+func (e EndpointCheck) Rejected() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// PingResult is the outcome of a ping of the crawl4ai server
+type PingResult struct {
+	ServerURL string          `json:"server_url"`
+	Status    string          `json:"status,omitempty"`  // reported by the health endpoint
+	Version   string          `json:"version,omitempty"` // reported by the health endpoint
+	TokenSent bool            `json:"token_sent"`
+	Endpoints []EndpointCheck `json:"endpoints"`
+}
+
+// Reachable reports whether any endpoint answered
+func (r *PingResult) Reachable() bool {
+	for _, endpoint := range r.Endpoints {
+		if endpoint.Answered() {
+			return true
+		}
+	}
+	return false
+}
+
+// Rejected reports whether any endpoint refused the credentials, or their
+// absence
+func (r *PingResult) Rejected() bool {
+	for _, endpoint := range r.Endpoints {
+		if endpoint.Rejected() {
+			return true
+		}
+	}
+	return false
+}
+
+// Health queries the crawl4ai health endpoint
+func (c *Crawler) Health(ctx context.Context) (*HealthResponse, error) {
+	body, err := c.getServer(ctx, "/health")
+	if err != nil {
+		return nil, err
+	}
+
+	var health HealthResponse
+	if err := json.Unmarshal(body, &health); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal health response: %w", err)
+	}
+	return &health, nil
+}
+
+// Ping queries the health endpoint and the root of the crawl4ai server,
+// timing each, and reports how they answered. It only fails if ctx is
+// cancelled; an unreachable server is reported in the result.
+func (c *Crawler) Ping(ctx context.Context) (*PingResult, error) {
+	result := &PingResult{ServerURL: c.serverURL, TokenSent: c.authToken != ""}
+
+	start := time.Now()
+	body, err := c.getServer(ctx, "/health")
+	result.Endpoints = append(result.Endpoints, endpointCheck("/health", time.Since(start), err))
+	var health HealthResponse
+	if err == nil && json.Unmarshal(body, &health) == nil {
+		result.Status = health.Status
+		result.Version = health.Version
+	}
+
+	start = time.Now()
+	_, err = c.getServer(ctx, "/")
+	result.Endpoints = append(result.Endpoints, endpointCheck("/", time.Since(start), err))
+
+	return result, ctx.Err()
+}
+
+// endpointCheck describes the answer of an endpoint from the error of the
+// request, if any
+func endpointCheck(path string, latency time.Duration, err error) EndpointCheck {
+	check := EndpointCheck{
+		Path:       path,
+		StatusCode: http.StatusOK,
+		Latency:    latency,
+		LatencyMs:  float64(latency.Microseconds()) / 1000,
+	}
+	if err == nil {
+		return check
+	}
+	check.StatusCode = 0
+	check.Error = err.Error()
+	var apiErr *APIError
+	if stderrors.As(err, &apiErr) {
+		check.StatusCode = apiErr.StatusCode
+	}
+	return check
+}
+
+// getServer sends a GET request to path on the crawl4ai server and returns
+// the body of a 200 response
+func (c *Crawler) getServer(ctx context.Context, path string) ([]byte, error) {
+	apiURL := strings.TrimSuffix(c.serverURL, "/") + path
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.authToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := c.readBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	}
+	return body, nil
+}
@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"crawlr/internal/storage"
+)
+
+// setValidators makes a request conditional on the validators recorded for
+// its content by an earlier crawl
+func setValidators(header http.Header, entry storage.ManifestEntry) {
+	if entry.ETag != "" {
+		header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
+
+// notModified reports whether a response to a conditional request shows that
+// the content is unchanged: a 304, or validators equal to the recorded ones
+// from servers that ignore conditional requests
+func notModified(resp *http.Response, entry storage.ManifestEntry) bool {
+	if resp.StatusCode == http.StatusNotModified {
+		return true
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" && entry.ETag != "" {
+		return etag == entry.ETag
+	}
+	lastModified := resp.Header.Get("Last-Modified")
+	return lastModified != "" && lastModified == entry.LastModified
+}
+
+// unchangedPages checks, with conditional HEAD requests to the origin, which
+// of the given pages saved by an earlier crawl are unchanged, returning their
+// manifest entries by URL. Pages without validators are always re-crawled.
+func (c *Crawler) unchangedPages(ctx context.Context, urls []string) map[string]storage.ManifestEntry {
+	unchanged := make(map[string]storage.ManifestEntry)
+	if !c.incremental || c.storage == nil {
+		return unchanged
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		slots = make(chan struct{}, max(1, c.maxConcurrent))
+	)
+	for _, url := range urls {
+		entry, ok := c.storage.KnownPage(url)
+		if !ok || (entry.ETag == "" && entry.LastModified == "") {
+			continue
+		}
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(url string, entry storage.ManifestEntry) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			header := make(http.Header)
+			setValidators(header, entry)
+			resp, err := c.requestOrigin(ctx, http.MethodHead, url, header)
+			if err != nil {
+				c.logger.Debug("Failed to check page for changes", map[string]interface{}{"url": url, "error": err})
+				return
+			}
+			resp.Body.Close()
+
+			if notModified(resp, entry) {
+				mutex.Lock()
+				unchanged[url] = entry
+				mutex.Unlock()
+			}
+		}(url, entry)
+	}
+	wg.Wait()
+	return unchanged
+}
+
+// unchangedResult stands in for the crawl result of a page that did not
+// change since the last crawl, carrying the links recorded for it
+func unchangedResult(url string, entry storage.ManifestEntry) PageResult {
+	return PageResult{
+		URL:        url,
+		Success:    true,
+		StatusCode: http.StatusNotModified,
+		Unchanged:  true,
+		Links:      entry.Links,
+	}
+}
@@ -0,0 +1,66 @@
+package crawler
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlTagPattern  = regexp.MustCompile(`(?is)<html\b[^>]*>`)
+	langPattern     = regexp.MustCompile(`(?is)\blang\s*=\s*["']([^"']*)["']`)
+	hreflangPattern = regexp.MustCompile(`(?is)\bhreflang\s*=\s*["']([^"']*)["']`)
+	hreflangTag     = regexp.MustCompile(`(?is)<(?:link|a)\b[^>]*\bhreflang\s*=[^>]*>`)
+)
+
+// pageLanguage returns the language declared by the <html lang> attribute of
+// a page, or an empty string
+func pageLanguage(html string) string {
+	tag := htmlTagPattern.FindString(html)
+	if tag == "" {
+		return ""
+	}
+	if lang := langPattern.FindStringSubmatch(tag); lang != nil {
+		return strings.TrimSpace(lang[1])
+	}
+	return ""
+}
+
+// wantsLanguage reports whether pages in lang are crawled: any language
+// without the languages setting, and otherwise the configured languages and
+// their regional variants ("en" covers "en-US"). Undeclared languages and the
+// x-default alternate are always crawled.
+func (c *Crawler) wantsLanguage(lang string) bool {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if len(c.languages) == 0 || lang == "" || lang == "x-default" {
+		return true
+	}
+	for _, wanted := range c.languages {
+		wanted = strings.ToLower(wanted)
+		if lang == wanted || strings.HasPrefix(lang, wanted+"-") || strings.HasPrefix(lang, wanted+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// otherLanguageURLs returns the absolute URLs of the alternates of a page
+// declared with hreflang in languages that are not crawled
+func (c *Crawler) otherLanguageURLs(html string, baseURL string) map[string]bool {
+	urls := make(map[string]bool)
+	if len(c.languages) == 0 {
+		return urls
+	}
+	for _, tag := range hreflangTag.FindAllString(html, -1) {
+		lang := hreflangPattern.FindStringSubmatch(tag)
+		href := hrefPattern.FindStringSubmatch(tag)
+		if lang == nil || href == nil || c.wantsLanguage(lang[1]) {
+			continue
+		}
+		absoluteURL, err := c.makeAbsoluteURL(strings.TrimSpace(href[1]), baseURL)
+		if err != nil {
+			continue
+		}
+		urls[absoluteURL] = true
+	}
+	return urls
+}
@@ -0,0 +1,126 @@
+package crawler
+
+import (
+	"encoding/json"
+	neturl "net/url"
+	"regexp"
+	"strings"
+
+	"crawlr/internal/storage"
+)
+
+var (
+	// relLicensePattern matches <link> and <a> tags whose rel includes "license"
+	relLicensePattern = regexp.MustCompile(`(?is)<(?:link|a)\b[^>]*\brel\s*=\s*["'][^"']*\blicense\b[^"']*["'][^>]*>`)
+	hrefPattern       = regexp.MustCompile(`(?is)\bhref\s*=\s*["']([^"']+)["']`)
+
+	// jsonLDPattern captures the content of JSON-LD script elements
+	jsonLDPattern = regexp.MustCompile(`(?is)<script\b[^>]*type\s*=\s*["']application/ld\+json["'][^>]*>(.*?)</script>`)
+)
+
+// licenseHints are the license hints found in the HTML of a page
+type licenseHints struct {
+	page   *storage.License            // license of the page as a whole
+	images map[string]*storage.License // schema.org ImageObject licenses keyed by absolute content URL
+}
+
+// parseLicenseHints looks for rel=license links and schema.org license
+// properties in the HTML of the page at pageURL
+func parseLicenseHints(html string, pageURL string) *licenseHints {
+	hints := &licenseHints{images: make(map[string]*storage.License)}
+	if html == "" {
+		return hints
+	}
+	base, _ := neturl.Parse(pageURL)
+
+	for _, tag := range relLicensePattern.FindAllString(html, -1) {
+		if match := hrefPattern.FindStringSubmatch(tag); match != nil {
+			hints.page = &storage.License{
+				URL:     resolveAgainst(base, match[1]),
+				Sources: []string{storage.LicenseSourceRelLicense},
+			}
+			break
+		}
+	}
+
+	for _, match := range jsonLDPattern.FindAllStringSubmatch(html, -1) {
+		var doc interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(match[1])), &doc); err != nil {
+			continue
+		}
+		hints.collectSchemaOrg(doc, base)
+	}
+
+	return hints
+}
+
+// collectSchemaOrg walks a JSON-LD document, recording the license of image
+// objects by content URL and the first other license as the page license
+func (h *licenseHints) collectSchemaOrg(node interface{}, base *neturl.URL) {
+	switch v := node.(type) {
+	case []interface{}:
+		for _, item := range v {
+			h.collectSchemaOrg(item, base)
+		}
+	case map[string]interface{}:
+		if licenseURL := schemaLicenseURL(v["license"]); licenseURL != "" {
+			license := &storage.License{
+				URL:     resolveAgainst(base, licenseURL),
+				Sources: []string{storage.LicenseSourceSchemaOrg},
+			}
+			contentURL, _ := v["contentUrl"].(string)
+			if schemaType, _ := v["@type"].(string); schemaType == "ImageObject" && contentURL != "" {
+				h.images[resolveAgainst(base, contentURL)] = license
+			} else if h.page == nil {
+				h.page = license
+			}
+		}
+		for key, value := range v {
+			if key != "license" {
+				h.collectSchemaOrg(value, base)
+			}
+		}
+	}
+}
+
+// forMedia returns the hints that apply to a media file, preferring those
+// specific to the file over those of the page
+func (h *licenseHints) forMedia(mediaURL string) *storage.License {
+	license := &storage.License{}
+	if h != nil {
+		license.Merge(h.images[mediaURL])
+		license.Merge(h.page)
+	}
+	return license
+}
+
+// schemaLicenseURL returns the URL of a schema.org license value, which is
+// either a URL or a CreativeWork with a url or @id
+func schemaLicenseURL(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]interface{}:
+		for _, key := range []string{"url", "@id"} {
+			if s, ok := v[key].(string); ok && s != "" {
+				return strings.TrimSpace(s)
+			}
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			return schemaLicenseURL(v[0])
+		}
+	}
+	return ""
+}
+
+// resolveAgainst resolves ref against base, returning ref unchanged if either
+// cannot be parsed
+func resolveAgainst(base *neturl.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	u, err := neturl.Parse(ref)
+	if err != nil || base == nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// errResponseTooLarge aborts reading a response exceeding max_response_size,
+// so that a mis-identified huge file cannot exhaust memory or fill the disk
+var errResponseTooLarge = errors.New("response exceeds the maximum response size")
+
+// readBody reads the whole body of a response within max_response_size
+func (c *Crawler) readBody(resp *http.Response) ([]byte, error) {
+	if c.maxResponseSize <= 0 {
+		return io.ReadAll(resp.Body)
+	}
+	if resp.ContentLength > c.maxResponseSize {
+		return nil, fmt.Errorf("%w of %d bytes (%d bytes)", errResponseTooLarge, c.maxResponseSize, resp.ContentLength)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.maxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > c.maxResponseSize {
+		return nil, fmt.Errorf("%w of %d bytes", errResponseTooLarge, c.maxResponseSize)
+	}
+	return body, nil
+}
+
+// limitResponse returns the body of a response capped to max_response_size
+func (c *Crawler) limitResponse(resp *http.Response) (io.Reader, error) {
+	if c.maxResponseSize <= 0 {
+		return resp.Body, nil
+	}
+	if resp.ContentLength > c.maxResponseSize {
+		return nil, fmt.Errorf("%w of %d bytes (%d bytes)", errResponseTooLarge, c.maxResponseSize, resp.ContentLength)
+	}
+	return &sizeLimitedReader{reader: resp.Body, remaining: c.maxResponseSize, err: errResponseTooLarge}, nil
+}
@@ -0,0 +1,118 @@
+package crawler
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Patterns of the links of markdown content: inline links and images, told
+// apart by their leading "!", and autolinks
+var (
+	markdownLinkPattern = regexp.MustCompile(`(!?)\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+["'][^"']*["'])?\s*\)`)
+	autolinkPattern     = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+)
+
+// anchorPattern captures the href and the whole element of <a> tags, so that
+// pagination patterns can match attributes as well as the link text
+var anchorPattern = regexp.MustCompile(`(?is)<a\b[^>]*\bhref\s*=\s*["']([^"']+)["'][^>]*>.*?</a>`)
+
+// isExcluded reports whether a URL matches the configured exclude patterns
+func (c *Crawler) isExcluded(url string) bool {
+	return c.excludePattern != nil && c.excludePattern.MatchString(url)
+}
+
+// extractLinks returns the absolute URLs of the links of a page. Some crawl4ai
+// configurations return no HTML, in which case the links are taken from the
+// links block of the metadata and from the markdown.
+func (c *Crawler) extractLinks(result PageResult) ([]string, error) {
+	if strings.TrimSpace(result.HTML) != "" {
+		return c.ExtractURLsFromHTML(result.HTML, result.FinalURL())
+	}
+
+	var hrefs []string
+	collectHrefs(result.Metadata["links"], &hrefs)
+	for _, match := range markdownLinkPattern.FindAllStringSubmatch(result.Markdown.RawMarkdown, -1) {
+		if match[1] == "" {
+			hrefs = append(hrefs, match[2])
+		}
+	}
+	for _, match := range autolinkPattern.FindAllStringSubmatch(result.Markdown.RawMarkdown, -1) {
+		hrefs = append(hrefs, match[1])
+	}
+
+	var urls []string
+	seen := make(map[string]bool)
+	for _, href := range hrefs {
+		href = strings.TrimSpace(href)
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") || strings.HasPrefix(href, "mailto:") {
+			continue
+		}
+		absoluteURL, err := c.makeAbsoluteURL(href, result.FinalURL())
+		if err != nil || seen[absoluteURL] {
+			continue
+		}
+		seen[absoluteURL] = true
+		urls = append(urls, absoluteURL)
+	}
+
+	c.logger.Detail("Extracted URLs from markdown and metadata", map[string]interface{}{
+		"totalURLs": len(urls),
+		"baseURL":   result.FinalURL(),
+	})
+	return urls, nil
+}
+
+// collectHrefs appends the URLs of a links metadata block to hrefs. The block
+// is a list of URLs or of link objects with an href, or an object grouping
+// such lists, as in {"internal": [...], "external": [...]}.
+func collectHrefs(value interface{}, hrefs *[]string) {
+	switch v := value.(type) {
+	case string:
+		*hrefs = append(*hrefs, v)
+	case []interface{}:
+		for _, item := range v {
+			collectHrefs(item, hrefs)
+		}
+	case map[string]interface{}:
+		if href, ok := v["href"].(string); ok {
+			*hrefs = append(*hrefs, href)
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectHrefs(v[key], hrefs)
+		}
+	}
+}
+
+// keepURLs returns the URLs for which keep returns true, in their order
+func keepURLs(urls []string, keep func(url string) bool) []string {
+	var kept []string
+	for _, url := range urls {
+		if keep(url) {
+			kept = append(kept, url)
+		}
+	}
+	return kept
+}
+
+// appendMissing appends the URLs of set missing from urls, in sorted order
+func appendMissing(urls []string, set map[string]int) []string {
+	listed := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		listed[url] = true
+	}
+	var missing []string
+	for url := range set {
+		if !listed[url] {
+			missing = append(missing, url)
+		}
+	}
+	sort.Strings(missing)
+	return append(urls, missing...)
+}
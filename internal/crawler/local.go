@@ -0,0 +1,131 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"crawlr/internal/htmlmd"
+)
+
+// maxLocalPageSize bounds the size of a page fetched without crawl4ai
+const maxLocalPageSize = 20 << 20
+
+// localEngine reports whether pages are always fetched locally, without a
+// crawl4ai server
+func (c *Crawler) localEngine() bool {
+	return c.engine == "local"
+}
+
+// localFallback reports whether pages are fetched locally when the crawl4ai
+// server is unavailable
+func (c *Crawler) localFallback() bool {
+	return c.fallback == "local" && !c.localEngine()
+}
+
+// crawlOrFallback crawls urls through crawl4ai with retries. With the local
+// fallback, the pages are fetched locally instead when the server keeps
+// failing or its circuit is open, and the response is marked Fallback.
+func (c *Crawler) crawlOrFallback(ctx context.Context, urls []string, includeMedia *bool) (*StartCrawlResponse, error) {
+	if c.localFallback() && c.breaker.Remaining(serverCircuit) > 0 {
+		return c.fallbackLocal(ctx, urls)
+	}
+	result, err := c.StartCrawlWithRetry(ctx, urls, includeMedia, 1, true, len(urls), c.retry.maxRetries)
+	if err == nil || !c.localFallback() || ctx.Err() != nil {
+		return result, err
+	}
+	c.logger.Warn("crawl4ai unavailable, fetching pages locally", map[string]interface{}{
+		"urlCount": len(urls),
+		"error":    err,
+	})
+	return c.fallbackLocal(ctx, urls)
+}
+
+// fallbackLocal fetches pages locally in place of the crawl4ai server,
+// marking the response Fallback
+func (c *Crawler) fallbackLocal(ctx context.Context, urls []string) (*StartCrawlResponse, error) {
+	response, err := c.fetchLocal(ctx, urls)
+	if err != nil {
+		return nil, err
+	}
+	response.Fallback = true
+	return response, nil
+}
+
+// fetchLocal fetches pages from their origin servers and converts their HTML
+// to markdown, without running their JavaScript or following their links.
+// Pages that are not HTML, such as PDF documents, cannot be converted and
+// fail.
+func (c *Crawler) fetchLocal(ctx context.Context, urls []string) (*StartCrawlResponse, error) {
+	response := &StartCrawlResponse{Success: true}
+	for _, url := range urls {
+		result := c.fetchLocalPage(ctx, url)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		response.Results = append(response.Results, result)
+	}
+	return response, nil
+}
+
+// fetchLocalPage fetches a single page and converts it to markdown, returning
+// a failed result if it cannot be
+func (c *Crawler) fetchLocalPage(ctx context.Context, url string) PageResult {
+	result := PageResult{URL: url}
+	fail := func(format string, args ...interface{}) PageResult {
+		result.ErrorMessage = fmt.Sprintf(format, args...)
+		c.logger.Debug("Failed to fetch page locally", map[string]interface{}{
+			"url":   url,
+			"error": result.ErrorMessage,
+		})
+		return result
+	}
+
+	resp, err := c.requestOrigin(ctx, http.MethodGet, url, http.Header{"Accept": {"text/html,application/xhtml+xml"}})
+	if err != nil {
+		return fail("failed to fetch page: %v", err)
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.ResponseHeaders = make(map[string]interface{}, len(resp.Header))
+	for name := range resp.Header {
+		result.ResponseHeaders[strings.ToLower(name)] = resp.Header.Get(name)
+	}
+	if final := resp.Request.URL.String(); final != url {
+		result.RedirectedURL = final
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fail("status code %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "" &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return fail("cannot convert %s to markdown without crawl4ai", mediaType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxLocalPageSize))
+	if err != nil {
+		return fail("failed to read page: %v", err)
+	}
+	page, err := htmlmd.Convert(bytes.NewReader(body), result.FinalURL())
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	result.Success = true
+	result.HTML = string(body)
+	result.Markdown.RawMarkdown = page.Markdown
+	result.Metadata = map[string]interface{}{"title": page.Title}
+	for _, image := range page.Images {
+		result.Media.Images = append(result.Media.Images, MediaItem{URL: image})
+	}
+	if raw, err := json.Marshal(result); err == nil {
+		result.Raw = raw
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchLocal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/guide":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`<html><head><title>Guide</title></head><body>` +
+				`<h1>Guide</h1><p>See <a href="/install">install</a>.</p><img src="/logo.png" alt="Logo"></body></html>`))
+		case "/old":
+			http.Redirect(w, r, "/guide", http.StatusMovedPermanently)
+		case "/manual.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF-1.7"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path       string
+		success    bool
+		statusCode int
+		markdown   string
+		redirected string
+		error      string
+	}{
+		{
+			path:       "/guide",
+			success:    true,
+			statusCode: http.StatusOK,
+			markdown:   "# Guide\n\nSee [install](" + server.URL + "/install).\n\n![Logo](" + server.URL + "/logo.png)",
+		},
+		{
+			path:       "/old",
+			success:    true,
+			statusCode: http.StatusOK,
+			markdown:   "# Guide\n\nSee [install](" + server.URL + "/install).\n\n![Logo](" + server.URL + "/logo.png)",
+			redirected: server.URL + "/guide",
+		},
+		{path: "/missing", statusCode: http.StatusNotFound, error: "status code 404"},
+		{path: "/manual.pdf", statusCode: http.StatusOK, error: "cannot convert application/pdf to markdown without crawl4ai"},
+	}
+
+	c := newTestCrawler(t, nil)
+	var urls []string
+	for _, tt := range tests {
+		urls = append(urls, server.URL+tt.path)
+	}
+	response, err := c.fetchLocal(context.Background(), urls)
+	if err != nil {
+		t.Fatalf("fetchLocal() error = %v", err)
+	}
+	if len(response.Results) != len(tests) {
+		t.Fatalf("fetchLocal() returned %d results, want %d", len(response.Results), len(tests))
+	}
+	for i, tt := range tests {
+		result := response.Results[i]
+		if result.Success != tt.success || result.StatusCode != tt.statusCode || result.ErrorMessage != tt.error {
+			t.Errorf("%s: success %v, status %d, error %q, want %v, %d, %q", tt.path,
+				result.Success, result.StatusCode, result.ErrorMessage, tt.success, tt.statusCode, tt.error)
+		}
+		if result.Markdown.RawMarkdown != tt.markdown {
+			t.Errorf("%s: markdown =\n%s\nwant:\n%s", tt.path, result.Markdown.RawMarkdown, tt.markdown)
+		}
+		if result.RedirectedURL != tt.redirected {
+			t.Errorf("%s: redirected URL = %q, want %q", tt.path, result.RedirectedURL, tt.redirected)
+		}
+		if !tt.success {
+			continue
+		}
+		if title := result.Metadata["title"]; title != "Guide" {
+			t.Errorf("%s: title = %v, want Guide", tt.path, title)
+		}
+		if len(result.Media.Images) != 1 || result.Media.Images[0].URL != server.URL+"/logo.png" {
+			t.Errorf("%s: images = %v, want the logo", tt.path, result.Media.Images)
+		}
+		if etag := result.ResponseHeaders["etag"]; etag != `"v1"` {
+			t.Errorf("%s: etag header = %v, want \"v1\"", tt.path, etag)
+		}
+		if len(result.Raw) == 0 {
+			t.Errorf("%s: raw result is empty", tt.path)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"crawlr/internal/tracing"
+)
+
+// MarkdownFilters are the content filters of the crawl4ai /md endpoint: the
+// whole page, its main content, or the passages relevant to a query
+var MarkdownFilters = []string{"raw", "fit", "bm25"}
+
+// markdownRequest is the body of a request to the crawl4ai /md endpoint
+type markdownRequest struct {
+	URL    string `json:"url"`
+	Filter string `json:"f,omitempty"`
+	Query  string `json:"q,omitempty"`
+}
+
+// MarkdownResponse is the markdown of a single page
+type MarkdownResponse struct {
+	URL      string `json:"url"`
+	Markdown string `json:"markdown"`
+	Success  bool   `json:"success"`
+
+	// Fallback reports whether the server has no /md endpoint, in which case
+	// the page was crawled through /crawl without its filter
+	Fallback bool `json:"-"`
+}
+
+// FetchMarkdown returns the markdown of a single page from the lightweight
+// crawl4ai /md endpoint, which skips the media, links and HTML of the full
+// crawl pipeline. filter is one of MarkdownFilters and query is used by the
+// bm25 filter. Servers without the endpoint crawl the page through /crawl
+// instead, as does the local engine.
+func (c *Crawler) FetchMarkdown(ctx context.Context, url, filter, query string) (response *MarkdownResponse, err error) {
+	if c.localEngine() {
+		return c.crawlMarkdown(ctx, url)
+	}
+
+	ctx, span := tracing.Start(ctx, "crawl4ai.md")
+	defer func() { tracing.End(span, err) }()
+
+	reqBody, err := json.Marshal(markdownRequest{URL: url, Filter: filter, Query: query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	apiURL := strings.TrimSuffix(c.serverURL, "/") + "/md"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.authToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	tracing.Inject(ctx, httpReq.Header)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := c.readBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		c.logger.Info("Server has no /md endpoint, crawling the page instead", map[string]interface{}{
+			"url":        url,
+			"statusCode": resp.StatusCode,
+		})
+		return c.crawlMarkdown(ctx, url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr APIError
+		if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(body))
+		}
+		apiErr.StatusCode = resp.StatusCode
+		return nil, &apiErr
+	}
+
+	var result MarkdownResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if result.URL == "" {
+		result.URL = url
+	}
+	return &result, nil
+}
+
+// crawlMarkdown returns the raw markdown of a single page crawled through
+// /crawl, without following links or downloading media
+func (c *Crawler) crawlMarkdown(ctx context.Context, url string) (*MarkdownResponse, error) {
+	noMedia := false
+	resp, err := c.StartCrawlWithConfig(ctx, []string{url}, &noMedia, 0, true, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) == 0 {
+		return nil, stderrors.New("the server returned no result")
+	}
+	page := resp.Results[0]
+	if !page.Success {
+		return nil, fmt.Errorf("failed to crawl %s: %s", url, page.ErrorMessage)
+	}
+	return &MarkdownResponse{
+		URL:      page.FinalURL(),
+		Markdown: page.Markdown.RawMarkdown,
+		Success:  true,
+		Fallback: true,
+	}, nil
+}
@@ -0,0 +1,320 @@
+package crawler
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"net/http"
+	neturl "net/url"
+	"sync"
+
+	"crawlr/internal/errors"
+	"crawlr/internal/progress"
+	"crawlr/internal/storage"
+	"crawlr/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/time/rate"
+)
+
+// hostLimiters rate-limits media downloads per origin host
+type hostLimiters struct {
+	mutex    sync.Mutex
+	limit    rate.Limit
+	burst    int
+	limiters map[string]*rate.Limiter
+}
+
+func newHostLimiters(requestsPerSecond float64) *hostLimiters {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	return &hostLimiters{
+		limit:    rate.Limit(requestsPerSecond),
+		burst:    max(1, int(math.Ceil(requestsPerSecond))),
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// wait blocks until a request to host is allowed. A nil hostLimiters never blocks.
+func (h *hostLimiters) wait(ctx context.Context, host string) error {
+	if h == nil {
+		return nil
+	}
+
+	h.mutex.Lock()
+	limiter, ok := h.limiters[host]
+	if !ok {
+		limiter = rate.NewLimiter(h.limit, h.burst)
+		h.limiters[host] = limiter
+	}
+	h.mutex.Unlock()
+
+	return limiter.Wait(ctx)
+}
+
+// mediaJob is a media file queued for download
+type mediaJob struct {
+	url       string
+	mediaType string // image, video, audio or document
+}
+
+// resolveMediaJobs makes the media URLs found on a page absolute, dropping
+// duplicates, filtered files and media already saved by this run or an
+// earlier crawl
+func (c *Crawler) resolveMediaJobs(pageURL string, media PageMedia) []mediaJob {
+	var jobs []mediaJob
+	for _, job := range c.resolveMediaURLs(pageURL, media) {
+		// Skip media already saved by this run or an earlier crawl
+		if c.storage.HasMedia(job.url) {
+			c.logger.Debug("Skipping known media file", map[string]interface{}{"url": job.url})
+			continue
+		}
+
+		if ok, reason := c.mediaFilter.allowURL(job.url, job.mediaType); !ok {
+			c.logger.Debug("Skipping filtered media file", map[string]interface{}{"url": job.url, "reason": reason})
+			continue
+		}
+
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// resolveMediaURLs makes the media URLs found on a page absolute, dropping
+// duplicates and URLs that cannot be resolved
+func (c *Crawler) resolveMediaURLs(pageURL string, media PageMedia) []mediaJob {
+	baseURL, baseErr := neturl.Parse(pageURL)
+
+	seen := make(map[string]bool, media.Count())
+	var jobs []mediaJob
+	for _, group := range []struct {
+		mediaType string
+		items     []MediaItem
+	}{
+		{"image", media.Images},
+		{"video", media.Videos},
+		{"audio", media.Audios},
+		{"document", media.Documents},
+	} {
+		for _, mediaFile := range group.items {
+			mediaURL, err := neturl.Parse(mediaFile.URL)
+			if err != nil {
+				c.logger.Error("Failed to resolve media URL", map[string]interface{}{
+					"url":   mediaFile.URL,
+					"error": err,
+				})
+				continue
+			}
+
+			// Make the media URL absolute if it's relative
+			if !mediaURL.IsAbs() {
+				if baseErr != nil {
+					c.logger.Error("Failed to parse base URL", map[string]interface{}{
+						"url":   pageURL,
+						"error": baseErr,
+					})
+					continue
+				}
+				mediaURL = baseURL.ResolveReference(mediaURL)
+			}
+
+			resolved := mediaURL.String()
+			if seen[resolved] {
+				continue
+			}
+			seen[resolved] = true
+
+			jobs = append(jobs, mediaJob{url: resolved, mediaType: group.mediaType})
+		}
+	}
+	return jobs
+}
+
+// saveMediaLinks writes the link file referencing the media objects of a page
+// when media is stored with the cas layout. Media saved by earlier pages or
+// crawls is listed too, so that every page references all of its media.
+func (c *Crawler) saveMediaLinks(pageURL string, media PageMedia) {
+	var links []storage.MediaLink
+	for _, job := range c.resolveMediaURLs(pageURL, media) {
+		links = append(links, storage.MediaLink{URL: job.url, Type: job.mediaType})
+	}
+	if _, err := c.storage.SaveMediaLinks(pageURL, links); err != nil {
+		c.logger.Warn("Failed to save media links", map[string]interface{}{
+			"url":   pageURL,
+			"error": err,
+		})
+	}
+}
+
+// downloadMedia downloads and saves media files using up to maxConcurrent
+// workers, attaching the license hints that apply to each. Each finished file,
+// saved or not, advances progressReporter.
+func (c *Crawler) downloadMedia(ctx context.Context, mediaJobs []mediaJob, hints *licenseHints, progressReporter *progress.ProgressReporter) ([]*storage.FileInfo, error) {
+	jobs := make(chan mediaJob)
+	var (
+		wg         sync.WaitGroup
+		mutex      sync.Mutex
+		savedFiles []*storage.FileInfo
+	)
+
+	workers := min(max(1, c.maxConcurrent), len(mediaJobs))
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				fileInfo := c.downloadMediaFile(ctx, job, hints)
+				if fileInfo != nil {
+					mutex.Lock()
+					savedFiles = append(savedFiles, fileInfo)
+					mutex.Unlock()
+				}
+				progressReporter.Increment()
+			}
+		}()
+	}
+
+	var err error
+feed:
+	for _, job := range mediaJobs {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		case jobs <- job:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return savedFiles, err
+}
+
+// downloadMediaFile downloads a single media file and saves it to storage,
+// logging and returning nil on failure
+func (c *Crawler) downloadMediaFile(ctx context.Context, job mediaJob, hints *licenseHints) *storage.FileInfo {
+	mediaURL := job.url
+	ctx, span := tracing.Start(ctx, "media.download", attribute.String("url.full", mediaURL))
+	defer span.End()
+
+	// The rate limit of a domain policy replaces media_rate_limit
+	if u, err := neturl.Parse(mediaURL); err == nil {
+		if policy := c.domains.lookup(u.Hostname()); policy != nil && policy.limiter != nil {
+			err = policy.wait(ctx)
+		} else {
+			err = c.mediaLimiters.wait(ctx, u.Host)
+		}
+		if err != nil {
+			return nil
+		}
+	}
+
+	// Download the media file, conditionally if an incremental re-crawl
+	// already has it
+	var (
+		header http.Header
+		known  storage.ManifestEntry
+	)
+	if c.incremental {
+		if entry, ok := c.storage.KnownMedia(mediaURL); ok {
+			known, header = entry, make(http.Header)
+			setValidators(header, entry)
+		}
+	}
+	resp, err := c.requestOriginWithRetry(ctx, http.MethodGet, mediaURL, header)
+	if err != nil {
+		c.logger.Error("Failed to download media file", map[string]interface{}{
+			"url":   mediaURL,
+			"error": err,
+		})
+		c.reportError(mediaURL, errors.Wrap(err, errors.NetworkError, "Failed to download media file"))
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if header != nil && notModified(resp, known) {
+		c.logger.Debug("Skipping unchanged media file", map[string]interface{}{"url": mediaURL})
+		return nil
+	}
+
+	// Check if the response is successful
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("Failed to download media file", map[string]interface{}{
+			"url":        mediaURL,
+			"statusCode": resp.StatusCode,
+		})
+		c.reportError(mediaURL, errors.New(errors.NetworkError, fmt.Sprintf("Failed to download media file: status %d", resp.StatusCode)).
+			WithContext("statusCode", resp.StatusCode))
+		return nil
+	}
+
+	if ok, reason := c.mediaFilter.allowResponse(resp); !ok {
+		c.logger.Debug("Skipping filtered media file", map[string]interface{}{"url": mediaURL, "reason": reason})
+		return nil
+	}
+
+	// Save the media file, aborting if it turns out to exceed the size caps
+	var fileInfo *storage.FileInfo
+	body, err := c.limitResponse(resp)
+	if err == nil {
+		fileInfo, err = c.storage.SaveTypedMediaFile(c.mediaFilter.limitBody(body), mediaURL, job.mediaType)
+	}
+	if stderrors.Is(err, errMediaTooLarge) {
+		c.logger.Debug("Skipping filtered media file", map[string]interface{}{"url": mediaURL, "reason": err.Error()})
+		return nil
+	}
+	if stderrors.Is(err, errResponseTooLarge) {
+		c.logger.Error("Failed to download media file", map[string]interface{}{
+			"url":   mediaURL,
+			"error": err,
+		})
+		c.reportError(mediaURL, errors.Wrap(err, errors.NetworkError, "Failed to download media file"))
+		return nil
+	}
+	if err != nil {
+		c.logger.Error("Failed to save media file", map[string]interface{}{
+			"url":   mediaURL,
+			"error": err,
+		})
+		c.reportError(mediaURL, errors.Wrap(err, errors.StorageError, "Failed to save media file"))
+		return nil
+	}
+	if fileInfo == nil {
+		return nil
+	}
+
+	c.logger.Detail("Saved media file", map[string]interface{}{
+		"path": fileInfo.Path,
+		"size": fileInfo.Size,
+	})
+	span.SetAttributes(attribute.Int64("media.size", fileInfo.Size))
+	fileInfo.License = c.mediaLicense(fileInfo.Path, mediaURL, hints)
+	fileInfo.ETag = resp.Header.Get("ETag")
+	fileInfo.LastModified = resp.Header.Get("Last-Modified")
+	c.recordMedia(fileInfo, resp.StatusCode)
+	if err := c.storage.WriteWARCMedia(fileInfo, resp.StatusCode, resp.Header); err != nil {
+		c.logger.Warn("Failed to write WARC record", map[string]interface{}{"url": mediaURL, "error": err})
+	}
+
+	return fileInfo
+}
+
+// mediaLicense combines the EXIF metadata of a saved media file with the
+// license hints of its page
+func (c *Crawler) mediaLicense(path string, mediaURL string, hints *licenseHints) *storage.License {
+	license, err := storage.ReadEXIFLicense(path)
+	if err != nil {
+		c.logger.Debug("Failed to read EXIF metadata", map[string]interface{}{"path": path, "error": err})
+	}
+	if license == nil {
+		license = &storage.License{}
+	}
+	license.Merge(hints.forMedia(mediaURL))
+
+	if license.Empty() {
+		return nil
+	}
+	return license
+}
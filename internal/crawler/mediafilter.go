@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"path"
+	"strings"
+
+	"crawlr/internal/config"
+)
+
+// errMediaTooLarge aborts the download of a media file exceeding the size cap
+var errMediaTooLarge = errors.New("media file exceeds the maximum size")
+
+// mediaFilter decides which media files are downloaded
+type mediaFilter struct {
+	maxSize    int64 // 0 means no limit
+	types      map[string]bool
+	extensions map[string]bool
+	excluded   map[string]bool
+}
+
+// newMediaFilter creates the media filter described by the configuration.
+// Invalid sizes are rejected by config validation and mean no limit here.
+func newMediaFilter(cfg *config.Config) *mediaFilter {
+	maxSize, _ := config.ParseSize(cfg.MediaMaxSize)
+	return &mediaFilter{
+		maxSize:    maxSize,
+		types:      listSet(cfg.MediaTypes),
+		extensions: listSet(cfg.MediaExtensions),
+		excluded:   listSet(cfg.MediaExcludeExtensions),
+	}
+}
+
+func listSet(value string) map[string]bool {
+	set := make(map[string]bool)
+	for _, item := range config.SplitList(value) {
+		set[strings.TrimPrefix(item, ".")] = true
+	}
+	return set
+}
+
+// allowURL checks the extension and type of a media URL before it is
+// downloaded, returning the reason when the file is skipped. mediaType is the
+// type crawl4ai reported the file as.
+func (f *mediaFilter) allowURL(mediaURL string, mediaType string) (bool, string) {
+	u, err := neturl.Parse(mediaURL)
+	if err != nil {
+		return true, ""
+	}
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(u.Path)), ".")
+
+	if f.excluded[ext] {
+		return false, fmt.Sprintf("extension %q is excluded", ext)
+	}
+	if len(f.extensions) > 0 && !f.extensions[ext] {
+		return false, fmt.Sprintf("extension %q is not allowed", ext)
+	}
+	if len(f.types) > 0 && !f.types[mediaType] {
+		return false, fmt.Sprintf("media type %q is not allowed", mediaType)
+	}
+	return true, ""
+}
+
+// allowResponse checks the declared size of a media response
+func (f *mediaFilter) allowResponse(resp *http.Response) (bool, string) {
+	if f.maxSize > 0 && resp.ContentLength > f.maxSize {
+		return false, fmt.Sprintf("size %d exceeds the maximum of %d bytes", resp.ContentLength, f.maxSize)
+	}
+	return true, ""
+}
+
+// limitBody aborts reading a response body once it exceeds the size cap, for
+// servers that send no or a wrong Content-Length
+func (f *mediaFilter) limitBody(body io.Reader) io.Reader {
+	if f.maxSize <= 0 {
+		return body
+	}
+	return &sizeLimitedReader{reader: body, remaining: f.maxSize, err: errMediaTooLarge}
+}
+
+// sizeLimitedReader fails with err after reading more than remaining bytes
+type sizeLimitedReader struct {
+	reader    io.Reader
+	remaining int64
+	err       error
+}
+
+func (r *sizeLimitedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, r.err
+	}
+	return n, err
+}
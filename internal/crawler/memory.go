@@ -0,0 +1,142 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+)
+
+// memoryBudget tracks the approximate size of the page content a recursive
+// crawl holds in memory, from the moment a batch is crawled until the batch
+// handler releases it, and applies backpressure when it nears its limit:
+// batches shrink, and the crawl waits for handed over batches to be saved.
+type memoryBudget struct {
+	limit int64 // 0 means no limit
+
+	mu       sync.Mutex
+	held     int64
+	batch    int           // current batch size, 0 until the crawl starts
+	released chan struct{} // signalled when content is released
+	warned   bool
+}
+
+// newMemoryBudget returns a budget of limit bytes, or an unlimited one if
+// limit is not positive
+func newMemoryBudget(limit int64) *memoryBudget {
+	return &memoryBudget{limit: max(limit, 0), released: make(chan struct{}, 1)}
+}
+
+// contentSize approximates the memory held by the content of a page result
+func contentSize(r PageResult) int64 {
+	return int64(len(r.HTML) + len(r.CleanedHTML) + len(r.Markdown.RawMarkdown) +
+		len(r.Markdown.MarkdownWithCitations) + len(r.ExtractedContent) + len(r.Raw))
+}
+
+// hold records the content of crawled results
+func (m *memoryBudget) hold(results []PageResult) {
+	if m.limit == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, result := range results {
+		m.held += contentSize(result)
+	}
+}
+
+// release records that the content of results is no longer held
+func (m *memoryBudget) release(results []PageResult) {
+	if m.limit == 0 {
+		return
+	}
+	m.mu.Lock()
+	for _, result := range results {
+		m.held -= contentSize(result)
+	}
+	m.held = max(m.held, 0)
+	m.mu.Unlock()
+
+	select {
+	case m.released <- struct{}{}:
+	default:
+	}
+}
+
+// batchSize returns the size of the next batch: halved while the content held
+// exceeds three quarters of the budget, and grown back towards configured
+// once it falls under a quarter
+func (m *memoryBudget) batchSize(configured int) (size int, shrunk bool) {
+	if m.limit == 0 {
+		return configured, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.batch == 0 {
+		m.batch = configured
+	}
+	switch {
+	case m.held >= m.limit/4*3 && m.batch > 1:
+		m.batch = max(m.batch/2, 1)
+		shrunk = true
+	case m.held < m.limit/4 && m.batch < configured:
+		m.batch = min(m.batch*2, configured)
+	}
+	return min(m.batch, configured), shrunk
+}
+
+// over reports whether the content held exceeds the budget, and how much is
+// held
+func (m *memoryBudget) over() (bool, int64) {
+	if m.limit == 0 {
+		return false, 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.held >= m.limit, m.held
+}
+
+// ReleaseResults tells the crawler that the results of a batch handed to the
+// batch handler have been saved, so that their content no longer counts
+// towards the memory budget. Handlers of crawls with a memory budget must call
+// it once done with each batch, or the crawl stalls when the budget is
+// reached.
+func (c *Crawler) ReleaseResults(results []PageResult) {
+	c.memory.release(results)
+}
+
+// waitMemory holds back the next batch while the content held exceeds the
+// memory budget, until the batch handler releases enough of it. Without a
+// batch handler nothing is ever released, so it only warns once. It returns
+// false if ctx was cancelled meanwhile.
+func (c *Crawler) waitMemory(ctx context.Context) bool {
+	over, held := c.memory.over()
+	if !over {
+		return true
+	}
+	if c.batchHandler == nil {
+		c.memory.mu.Lock()
+		warned := c.memory.warned
+		c.memory.warned = true
+		c.memory.mu.Unlock()
+		if !warned {
+			c.logger.Warn("Memory budget exceeded, but results are kept until the end of the crawl", map[string]interface{}{
+				"heldBytes":   held,
+				"budgetBytes": c.memory.limit,
+			})
+		}
+		return true
+	}
+
+	c.logger.Info("Memory budget reached, waiting for crawled pages to be saved", map[string]interface{}{
+		"heldBytes":   held,
+		"budgetBytes": c.memory.limit,
+	})
+	for over {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-c.memory.released:
+		}
+		over, _ = c.memory.over()
+	}
+	return true
+}
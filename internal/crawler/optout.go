@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Opt-out directives that ask crawlers not to archive a page or not to use it
+// for text and data mining
+var (
+	// pageOptOutDirectives apply to the whole page
+	pageOptOutDirectives = map[string]bool{"noai": true, "noarchive": true, "none": true}
+	// mediaOptOutDirectives only apply to the media of the page
+	mediaOptOutDirectives = map[string]bool{"noimageai": true}
+)
+
+var (
+	metaTagPattern     = regexp.MustCompile(`(?is)<meta\b[^>]*>`)
+	metaNamePattern    = regexp.MustCompile(`(?is)\bname\s*=\s*["']([^"']+)["']`)
+	metaContentPattern = regexp.MustCompile(`(?is)\bcontent\s*=\s*["']([^"']*)["']`)
+)
+
+// OptOut describes the opt-out signals found for a page
+type OptOut struct {
+	Page  []string // signals asking not to archive or mine the page
+	Media []string // signals asking not to mine the images of the page
+}
+
+// Any reports whether any signal was found
+func (o OptOut) Any() bool {
+	return len(o.Page) > 0 || len(o.Media) > 0
+}
+
+// Signals returns all signals found
+func (o OptOut) Signals() []string {
+	return append(append([]string(nil), o.Page...), o.Media...)
+}
+
+// DetectOptOut looks for opt-out signals in the response headers and HTML of
+// a page: X-Robots-Tag and TDM-Reservation headers, and robots and
+// tdm-reservation meta tags
+func DetectOptOut(result PageResult) OptOut {
+	var optOut OptOut
+
+	for name, value := range result.ResponseHeaders {
+		switch strings.ToLower(name) {
+		case "x-robots-tag":
+			optOut.addRobots("X-Robots-Tag", headerString(value))
+		case "tdm-reservation":
+			if strings.TrimSpace(headerString(value)) == "1" {
+				optOut.Page = append(optOut.Page, "TDM-Reservation: 1")
+			}
+		}
+	}
+
+	for _, tag := range metaTagPattern.FindAllString(result.HTML, -1) {
+		name := metaNamePattern.FindStringSubmatch(tag)
+		content := metaContentPattern.FindStringSubmatch(tag)
+		if name == nil || content == nil {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(name[1])) {
+		case "robots":
+			optOut.addRobots(`meta robots`, content[1])
+		case "tdm-reservation":
+			if strings.TrimSpace(content[1]) == "1" {
+				optOut.Page = append(optOut.Page, "meta tdm-reservation: 1")
+			}
+		}
+	}
+
+	return optOut
+}
+
+// addRobots records the opt-out directives of a robots directive list such as
+// "noai, noimageai". Directives scoped to another user agent ("googlebot:
+// noarchive") are ignored.
+func (o *OptOut) addRobots(source string, value string) {
+	for _, directive := range strings.Split(value, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if strings.Contains(directive, ":") {
+			continue
+		}
+		signal := fmt.Sprintf("%s: %s", source, directive)
+		if pageOptOutDirectives[directive] {
+			o.Page = append(o.Page, signal)
+		} else if mediaOptOutDirectives[directive] {
+			o.Media = append(o.Media, signal)
+		}
+	}
+}
+
+// Header returns the response headers reported by crawl4ai
+func (r PageResult) Header() http.Header {
+	header := make(http.Header, len(r.ResponseHeaders))
+	for name, value := range r.ResponseHeaders {
+		if s := headerString(value); s != "" {
+			header.Set(name, s)
+		}
+	}
+	return header
+}
+
+// headerString returns a response header value decoded from JSON, which is a
+// string or, for repeated headers, a list of strings
+func headerString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,100 @@
+package crawler
+
+import (
+	neturl "net/url"
+	"strconv"
+	"strings"
+)
+
+// pageParam is the query parameter numbering the pages of a listing
+const pageParam = "page"
+
+// paginationPriority is added to the score of pagination links, ranking them
+// above any other link for best-first traversal
+const paginationPriority = 25
+
+// paginationURLs returns the next pages of a listing linked from the page at
+// baseURL: rel="next" links, links matching the pagination pattern and links
+// to other numbered pages of the same listing (?page=N). Each URL is mapped
+// to its position in the listing, position being that of the page itself.
+func (c *Crawler) paginationURLs(html string, baseURL string, position int) map[string]int {
+	urls := make(map[string]int)
+	base, err := neturl.Parse(baseURL)
+	if err != nil {
+		return urls
+	}
+	add := func(href string, next int) {
+		href = strings.TrimSpace(href)
+		if strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
+			return
+		}
+		absoluteURL, err := c.makeAbsoluteURL(href, baseURL)
+		if err != nil || absoluteURL == baseURL {
+			return
+		}
+		if _, found := urls[absoluteURL]; !found {
+			urls[absoluteURL] = next
+		}
+	}
+
+	for _, tag := range linkTagPattern.FindAllString(html, -1) {
+		if !hasRel(tag, "next") {
+			continue
+		}
+		if href := hrefPattern.FindStringSubmatch(tag); href != nil {
+			add(href[1], position+1)
+		}
+	}
+
+	for _, match := range anchorPattern.FindAllStringSubmatch(html, -1) {
+		if !c.ignoreMetaRobots && isNoFollow(match[0]) {
+			continue
+		}
+		if hasRel(match[0], "next") || (c.paginationPattern != nil && c.paginationPattern.MatchString(match[0])) {
+			add(match[1], position+1)
+			continue
+		}
+		ref, err := neturl.Parse(strings.TrimSpace(match[1]))
+		if err != nil {
+			continue
+		}
+		if number, ok := listingPage(base.ResolveReference(ref), base); ok {
+			add(match[1], number)
+		}
+	}
+	return urls
+}
+
+// pageNumber returns the number of the page of a listing at rawURL, from its
+// ?page=N parameter, or 1
+func pageNumber(rawURL string) int {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return 1
+	}
+	number, err := strconv.Atoi(u.Query().Get(pageParam))
+	if err != nil || number < 1 {
+		return 1
+	}
+	return number
+}
+
+// listingPage reports whether link is a numbered page of the same listing as
+// page: the same URL but for its ?page=N parameter. It returns the number of
+// the linked page.
+func listingPage(link *neturl.URL, page *neturl.URL) (int, bool) {
+	if link.Host != page.Host || link.Path != page.Path {
+		return 0, false
+	}
+	linkQuery, pageQuery := link.Query(), page.Query()
+	number, err := strconv.Atoi(linkQuery.Get(pageParam))
+	if err != nil || number < 1 {
+		return 0, false
+	}
+	linkQuery.Del(pageParam)
+	pageQuery.Del(pageParam)
+	if linkQuery.Encode() != pageQuery.Encode() {
+		return 0, false
+	}
+	return number, true
+}
@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"context"
+	neturl "net/url"
+	"path"
+	"strings"
+
+	"crawlr/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// pdfScrapingStrategy asks crawl4ai to extract the text of PDF documents as
+// markdown instead of rendering them in the browser
+var pdfScrapingStrategy = map[string]interface{}{
+	"type":   "PDFContentScrapingStrategy",
+	"params": map[string]interface{}{},
+}
+
+// isPDF reports whether a URL points to a PDF document, judging by its
+// extension
+func isPDF(rawURL string) bool {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(path.Ext(u.Path), ".pdf")
+}
+
+// scrapingStrategy returns the crawl4ai scraping strategy of a request for
+// urls: the PDF strategy when they are all PDF documents and convert_pdfs is
+// set, the default one otherwise
+func (c *Crawler) scrapingStrategy(urls []string) map[string]interface{} {
+	if !c.convertPDFs || len(urls) == 0 {
+		return nil
+	}
+	for _, url := range urls {
+		if !isPDF(url) {
+			return nil
+		}
+	}
+	return pdfScrapingStrategy
+}
+
+// splitPDFs moves the PDF documents of a batch after its other pages, keeping
+// their order, and returns the number of other pages. PDF documents are
+// crawled in a request of their own with the PDF scraping strategy.
+func (c *Crawler) splitPDFs(items []URLWithDepth) int {
+	if !c.convertPDFs {
+		return len(items)
+	}
+	var pages, pdfs []URLWithDepth
+	for _, item := range items {
+		if isPDF(item.URL) {
+			pdfs = append(pdfs, item)
+		} else {
+			pages = append(pages, item)
+		}
+	}
+	copy(items, append(pages, pdfs...))
+	return len(pages)
+}
+
+// crawlBatch crawls the URLs of a batch, its PDF documents (from index
+// pdfStart on) in a request of their own, returning the results in the order
+// of urls. URLs of different domain policies, grouped beforehand, are crawled
+// in requests of their own too, once their rate limits allow.
+func (c *Crawler) crawlBatch(ctx context.Context, urls []string, pdfStart int, includeMedia *bool) (_ *StartCrawlResponse, err error) {
+	ctx, span := tracing.Start(ctx, "crawl.batch", attribute.Int("crawl.batch.size", len(urls)))
+	defer func() { tracing.End(span, err) }()
+
+	response := &StartCrawlResponse{Success: true}
+	for _, group := range [][]string{urls[:pdfStart], urls[pdfStart:]} {
+		for _, run := range c.domainRuns(group) {
+			if len(run) == 0 {
+				continue
+			}
+			if err := c.waitDomains(ctx, run); err != nil {
+				return nil, err
+			}
+			result, err := c.crawlOrFallback(ctx, run, includeMedia)
+			if err != nil {
+				return nil, err
+			}
+			response.Fallback = response.Fallback || result.Fallback
+			response.Results = append(response.Results, result.Results...)
+			response.ServerProcessingTimeS += result.ServerProcessingTimeS
+		}
+	}
+	return response, nil
+}
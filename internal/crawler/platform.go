@@ -0,0 +1,35 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"crawlr/internal/config"
+)
+
+// platformReadLimit bounds how much of a page is read to detect its platform
+const platformReadLimit = 1 << 20
+
+// DetectPreset fetches a page from its origin and returns the preset of the
+// documentation platform it was built with
+func (c *Crawler) DetectPreset(ctx context.Context, pageURL string) (config.Preset, bool, error) {
+	resp, err := c.getOrigin(ctx, pageURL)
+	if err != nil {
+		return config.Preset{}, false, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return config.Preset{}, false, fmt.Errorf("failed to fetch page, status code: %d", resp.StatusCode)
+	}
+
+	html, err := io.ReadAll(io.LimitReader(resp.Body, platformReadLimit))
+	if err != nil {
+		return config.Preset{}, false, fmt.Errorf("failed to read page: %w", err)
+	}
+
+	preset, ok := config.DetectPreset(string(html))
+	return preset, ok, nil
+}
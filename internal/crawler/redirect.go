@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"context"
+
+	"crawlr/internal/frontier"
+)
+
+// FinalURL returns the URL the page was served from after redirects
+func (r PageResult) FinalURL() string {
+	if r.RedirectedURL != "" {
+		return r.RedirectedURL
+	}
+	return r.URL
+}
+
+// Redirected reports whether the page was served from another URL than the
+// one requested
+func (r PageResult) Redirected() bool {
+	return r.FinalURL() != r.URL
+}
+
+// claimAliases claims the final URL of every redirected page of a batch and,
+// with use_canonical, the canonical URL of pages declaring another one, so
+// that these are not crawled again under their own URL. It returns whether
+// each result is an alias of a page already claimed, crawled under another
+// URL, which should be dropped.
+func (c *Crawler) claimAliases(ctx context.Context, results []PageResult, visited frontier.VisitedSet) []bool {
+	aliases := make([]bool, len(results))
+	for i, result := range results {
+		if result.Unchanged {
+			continue
+		}
+		var urls []string
+		if result.Redirected() {
+			urls = append(urls, result.FinalURL())
+		}
+		if canonical := result.CanonicalURL(); c.useCanonical && canonical != "" &&
+			canonical != result.URL && canonical != result.FinalURL() {
+			urls = append(urls, canonical)
+		}
+		if len(urls) == 0 {
+			continue
+		}
+		claimed, err := visited.Claim(ctx, urls)
+		if err != nil {
+			c.logger.Warn("Failed to claim alias URLs", map[string]interface{}{
+				"url":   result.URL,
+				"error": err,
+			})
+			continue
+		}
+		aliases[i] = len(claimed) < len(urls)
+	}
+	return aliases
+}
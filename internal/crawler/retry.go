@@ -0,0 +1,137 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"crawlr/internal/config"
+)
+
+// retryPolicy decides which failed crawl4ai requests are retried and how long
+// to wait before each retry
+type retryPolicy struct {
+	maxRetries int
+	backoff    time.Duration // wait before the first retry
+	multiplier float64       // factor applied to the wait before each further retry
+	maxBackoff time.Duration
+	jitter     float64 // fraction of each wait randomized
+
+	maxRetryAfter time.Duration // longest Retry-After honored, longer ones being cut to it
+}
+
+func newRetryPolicy(cfg *config.Config) retryPolicy {
+	return retryPolicy{
+		maxRetries: cfg.RetryMax,
+		backoff:    seconds(cfg.RetryBackoff),
+		multiplier: cfg.RetryMultiplier,
+		maxBackoff: seconds(cfg.RetryMaxBackoff),
+		jitter:     cfg.RetryJitter,
+
+		maxRetryAfter: time.Duration(cfg.MaxRetryAfter) * time.Second,
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// wait returns how long to wait before retry number attempt, counting from 1:
+// the backoff grows by the multiplier up to the maximum, and jitter spreads
+// it by up to its fraction either way
+func (p retryPolicy) wait(attempt int) time.Duration {
+	wait := float64(p.backoff) * math.Pow(math.Max(p.multiplier, 1), float64(attempt-1))
+	if p.maxBackoff > 0 {
+		wait = math.Min(wait, float64(p.maxBackoff))
+	}
+	if p.jitter > 0 {
+		wait *= 1 + p.jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(wait)
+}
+
+// delay returns how long to wait before retry number attempt after err: the
+// wait the server asked for with Retry-After, or else the backoff
+func (p retryPolicy) delay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+	return p.wait(attempt)
+}
+
+// retryable reports whether a failed crawl4ai request may succeed when sent
+// again: network failures and timeouts, rate limiting (429) and server errors
+// such as 502 and 503. Other client errors such as 400 and 401 are fatal, as
+// are cancellations and responses exceeding max_response_size.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, errResponseTooLarge) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests ||
+			apiErr.StatusCode == http.StatusRequestTimeout ||
+			apiErr.StatusCode >= 500
+	}
+	return true
+}
+
+// retryAfter returns the wait a 429 or 503 response asks for with its
+// Retry-After header, in seconds or as a date, bounded by max_retry_after. It
+// returns false for other responses and responses without the header.
+func (p retryPolicy) retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+
+	var wait time.Duration
+	if secs, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(secs) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+	} else {
+		return 0, false
+	}
+	if wait > p.maxRetryAfter {
+		wait = p.maxRetryAfter
+	}
+	return max(wait, 0), true
+}
+
+// requestOriginWithRetry sends a request to an origin server, sending it again
+// up to max_retries times when the server answers 429 or 503 with a
+// Retry-After header, after the wait it asks for
+func (c *Crawler) requestOriginWithRetry(ctx context.Context, method string, fileURL string, header http.Header) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.requestOrigin(ctx, method, fileURL, header)
+		if err != nil || attempt > c.retry.maxRetries {
+			return resp, err
+		}
+		wait, ok := c.retry.retryAfter(resp)
+		if !ok {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		c.logger.Info("Retrying after the wait asked by the server", map[string]interface{}{
+			"url":        fileURL,
+			"statusCode": resp.StatusCode,
+			"wait":       wait.String(),
+		})
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
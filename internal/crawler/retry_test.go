@@ -0,0 +1,163 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"crawlr/internal/config"
+)
+
+func TestRetryPolicyWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  retryPolicy
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"first retry", retryPolicy{backoff: time.Second, multiplier: 2}, 1, time.Second, time.Second},
+		{"second retry", retryPolicy{backoff: time.Second, multiplier: 2}, 2, 2 * time.Second, 2 * time.Second},
+		{"fourth retry", retryPolicy{backoff: time.Second, multiplier: 2}, 4, 8 * time.Second, 8 * time.Second},
+		{"fractional multiplier", retryPolicy{backoff: time.Second, multiplier: 1.5}, 3, 2250 * time.Millisecond, 2250 * time.Millisecond},
+		{"multiplier below one", retryPolicy{backoff: time.Second, multiplier: 0.5}, 3, time.Second, time.Second},
+		{"capped", retryPolicy{backoff: time.Second, multiplier: 2, maxBackoff: 5 * time.Second}, 4, 5 * time.Second, 5 * time.Second},
+		{"below the cap", retryPolicy{backoff: time.Second, multiplier: 2, maxBackoff: 5 * time.Second}, 2, 2 * time.Second, 2 * time.Second},
+		{"jitter", retryPolicy{backoff: 4 * time.Second, multiplier: 2, jitter: 0.25}, 1, 3 * time.Second, 5 * time.Second},
+		{"jitter on the cap", retryPolicy{backoff: time.Second, multiplier: 2, maxBackoff: 4 * time.Second, jitter: 0.5}, 10, 2 * time.Second, 6 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Jitter is random: sample it enough to cover its range
+			waits := make(map[time.Duration]bool)
+			for range 200 {
+				got := tt.policy.wait(tt.attempt)
+				if got < tt.min || got > tt.max {
+					t.Fatalf("wait(%d) = %v, want within [%v, %v]", tt.attempt, got, tt.min, tt.max)
+				}
+				waits[got] = true
+			}
+			if tt.min != tt.max && len(waits) == 1 {
+				t.Errorf("wait(%d) is always %v, want it spread by jitter", tt.attempt, tt.min)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"network failure", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"timeout", context.DeadlineExceeded, true},
+		{"canceled", context.Canceled, false},
+		{"wrapped canceled", fmt.Errorf("crawl failed: %w", context.Canceled), false},
+		{"response too large", errResponseTooLarge, false},
+		{"too many requests", &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"request timeout", &APIError{StatusCode: http.StatusRequestTimeout}, true},
+		{"internal server error", &APIError{StatusCode: http.StatusInternalServerError}, true},
+		{"bad gateway", &APIError{StatusCode: http.StatusBadGateway}, true},
+		{"service unavailable", &APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"wrapped server error", fmt.Errorf("batch failed: %w", &APIError{StatusCode: http.StatusBadGateway}), true},
+		{"bad request", &APIError{StatusCode: http.StatusBadRequest}, false},
+		{"unauthorized", &APIError{StatusCode: http.StatusUnauthorized}, false},
+		{"not found", &APIError{StatusCode: http.StatusNotFound}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	policy := retryPolicy{maxRetryAfter: time.Minute}
+	date := func(d time.Duration) string {
+		return time.Now().Add(d).UTC().Format(http.TimeFormat)
+	}
+	tests := []struct {
+		name   string
+		status int
+		header string
+		ok     bool
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"seconds", http.StatusTooManyRequests, "5", true, 5 * time.Second, 5 * time.Second},
+		{"seconds on 503", http.StatusServiceUnavailable, "12", true, 12 * time.Second, 12 * time.Second},
+		{"padded seconds", http.StatusTooManyRequests, " 7 ", true, 7 * time.Second, 7 * time.Second},
+		{"seconds above the cap", http.StatusTooManyRequests, "600", true, time.Minute, time.Minute},
+		{"negative seconds", http.StatusTooManyRequests, "-5", true, 0, 0},
+		{"date", http.StatusTooManyRequests, date(30 * time.Second), true, 28 * time.Second, 30 * time.Second},
+		{"date above the cap", http.StatusServiceUnavailable, date(time.Hour), true, time.Minute, time.Minute},
+		{"past date", http.StatusTooManyRequests, date(-time.Hour), true, 0, 0},
+		{"malformed", http.StatusTooManyRequests, "soon", false, 0, 0},
+		{"missing", http.StatusTooManyRequests, "", false, 0, 0},
+		{"other status", http.StatusInternalServerError, "5", false, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: make(http.Header)}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+			got, ok := policy.retryAfter(resp)
+			if ok != tt.ok {
+				t.Fatalf("retryAfter() ok = %v, want %v", ok, tt.ok)
+			}
+			if got < tt.min || got > tt.max {
+				t.Errorf("retryAfter() = %v, want within [%v, %v]", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRequestOriginWithRetry(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		maxRetries int
+		status     int
+		requests   int32
+	}{
+		{"retried", 3, http.StatusOK, 3},
+		{"out of retries", 1, http.StatusServiceUnavailable, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			c := newTestCrawler(t, func(cfg *config.Config) { cfg.RetryMax = tt.maxRetries })
+			resp, err := c.requestOriginWithRetry(context.Background(), http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatalf("requestOriginWithRetry() error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := requests.Load(); got != tt.requests {
+				t.Errorf("server got %d requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
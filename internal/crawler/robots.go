@@ -0,0 +1,85 @@
+package crawler
+
+import (
+	"regexp"
+	"strings"
+)
+
+// relPattern captures the rel attribute of a tag
+var relPattern = regexp.MustCompile(`(?is)\brel\s*=\s*["']([^"']*)["']`)
+
+// MetaRobots holds the indexing directives of a page
+type MetaRobots struct {
+	NoIndex  bool // the page asks not to be indexed
+	NoFollow bool // the page asks that its links are not followed
+}
+
+// DetectMetaRobots reads the noindex and nofollow directives of a page from
+// its robots meta tags and X-Robots-Tag headers. "none" stands for both.
+// Directives scoped to another user agent ("googlebot: noindex") are ignored.
+func DetectMetaRobots(result PageResult) MetaRobots {
+	var robots MetaRobots
+
+	for name, value := range result.ResponseHeaders {
+		if strings.EqualFold(name, "x-robots-tag") {
+			robots.add(headerString(value))
+		}
+	}
+
+	for _, tag := range metaTagPattern.FindAllString(result.HTML, -1) {
+		name := metaNamePattern.FindStringSubmatch(tag)
+		content := metaContentPattern.FindStringSubmatch(tag)
+		if name == nil || content == nil {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(name[1]), "robots") {
+			robots.add(content[1])
+		}
+	}
+
+	return robots
+}
+
+// add records the directives of a robots directive list such as
+// "noindex, follow"
+func (r *MetaRobots) add(value string) {
+	for _, directive := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "noindex":
+			r.NoIndex = true
+		case "nofollow":
+			r.NoFollow = true
+		case "none":
+			r.NoIndex = true
+			r.NoFollow = true
+		}
+	}
+}
+
+// metaRobots returns the directives of a page, or none with
+// ignore_meta_robots
+func (c *Crawler) metaRobots(result PageResult) MetaRobots {
+	if c.ignoreMetaRobots {
+		return MetaRobots{}
+	}
+	return DetectMetaRobots(result)
+}
+
+// isNoFollow reports whether a link tag is marked rel="nofollow"
+func isNoFollow(tag string) bool {
+	return hasRel(tag, "nofollow")
+}
+
+// hasRel reports whether the rel attribute of a tag lists value
+func hasRel(tag string, value string) bool {
+	rel := relPattern.FindStringSubmatch(tag)
+	if rel == nil {
+		return false
+	}
+	for _, field := range strings.Fields(rel[1]) {
+		if strings.EqualFold(field, value) {
+			return true
+		}
+	}
+	return false
+}
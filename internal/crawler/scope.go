@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	neturl "net/url"
+	"strings"
+)
+
+// inScope reports whether pages of host are crawled by a crawl starting on
+// baseHost: pages of baseHost itself or of the host of another seed, of
+// their subdomains with include_subdomains, and of the allowed domains and
+// their subdomains
+func (c *Crawler) inScope(host, baseHost string) bool {
+	host = strings.ToLower(host)
+	for _, start := range append([]string{baseHost}, c.seedHosts...) {
+		start = strings.ToLower(start)
+		if host == start {
+			return true
+		}
+		if c.includeSubdomains && isSubdomain(host, strings.TrimPrefix(start, "www.")) {
+			return true
+		}
+	}
+	for _, domain := range c.allowedDomains {
+		if host == domain || isSubdomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubdomain reports whether host is domain or one of its subdomains
+func isSubdomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// domainQuota caps the pages crawled per host. A nil quota allows any
+// number of pages.
+type domainQuota struct {
+	max   int
+	pages map[string]int
+}
+
+func newDomainQuota(max int) *domainQuota {
+	if max <= 0 {
+		return nil
+	}
+	return &domainQuota{max: max, pages: make(map[string]int)}
+}
+
+// take reserves a page of the host of url, returning false when the host
+// already has its quota of pages
+func (q *domainQuota) take(url string) bool {
+	if q == nil {
+		return true
+	}
+	host := quotaHost(url)
+	if q.pages[host] >= q.max {
+		return false
+	}
+	q.pages[host]++
+	return true
+}
+
+// full reports whether the host of url already has its quota of pages
+func (q *domainQuota) full(url string) bool {
+	return q != nil && q.pages[quotaHost(url)] >= q.max
+}
+
+// release returns a page reserved with take that was not crawled
+func (q *domainQuota) release(url string) {
+	if q == nil {
+		return
+	}
+	host := quotaHost(url)
+	if q.pages[host] > 0 {
+		q.pages[host]--
+	}
+}
+
+func quotaHost(url string) string {
+	parsed, err := neturl.Parse(url)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	neturl "net/url"
+
+	"crawlr/internal/frontier"
+)
+
+// SetSeeds adds further start URLs to the next recursive crawl. They are
+// seeded at depth 0 into the frontier of the start URL, sharing its visited
+// set, and the pages of their hosts are in scope like those of the start URL.
+func (c *Crawler) SetSeeds(urls []string) {
+	c.seeds = urls
+	c.seedHosts = nil
+	for _, url := range urls {
+		if u, err := neturl.Parse(url); err == nil && u.Hostname() != "" {
+			c.seedHosts = append(c.seedHosts, u.Hostname())
+		}
+	}
+}
+
+// seedItems returns the start URL followed by the other seeds and by extra
+// URLs, once each, as frontier items at depth 0
+func (c *Crawler) seedItems(startURL string, extra ...string) []URLWithDepth {
+	seen := map[string]bool{startURL: true}
+	items := []URLWithDepth{{URL: startURL, Depth: 0}}
+	for _, urls := range [][]string{c.seeds, extra} {
+		for _, url := range urls {
+			if !seen[url] {
+				seen[url] = true
+				items = append(items, URLWithDepth{URL: url, Depth: 0})
+			}
+		}
+	}
+	return items
+}
+
+// pushSeeds pushes the seeds a cooperating crawler did not claim yet into
+// the frontier
+func (c *Crawler) pushSeeds(ctx context.Context, urlFrontier frontier.Frontier, visited frontier.VisitedSet, seeds []URLWithDepth) error {
+	urls := make([]string, len(seeds))
+	for i, seed := range seeds {
+		urls[i] = seed.URL
+	}
+	seeded, err := visited.Contains(ctx, urls)
+	if err != nil {
+		return fmt.Errorf("failed to check crawl state: %w", err)
+	}
+
+	var items []URLWithDepth
+	for i, seed := range seeds {
+		if !seeded[i] {
+			items = append(items, seed)
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	if err := urlFrontier.Push(ctx, items); err != nil {
+		return fmt.Errorf("failed to seed frontier: %w", err)
+	}
+	if len(seeds) > 1 {
+		c.logger.Info("Seeded frontier", map[string]interface{}{
+			"seeds":  len(seeds),
+			"queued": len(items),
+		})
+	}
+	return nil
+}
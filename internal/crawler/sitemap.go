@@ -0,0 +1,245 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"crawlr/internal/storage"
+)
+
+// maxSitemapSize bounds the size of a sitemap read from an origin server,
+// once decompressed; the sitemap protocol allows 50MB
+const maxSitemapSize = 50 << 20
+
+// maxSitemapDepth bounds the nesting of the sitemap indexes followed
+const maxSitemapDepth = 5
+
+// SitemapCoverage compares the pages reached by following links with the
+// pages listed in the site's sitemaps
+type SitemapCoverage struct {
+	StartURL     string    `json:"start_url"`
+	GeneratedAt  time.Time `json:"generated_at"`
+	Sitemaps     []string  `json:"sitemaps"`
+	SitemapURLs  int       `json:"sitemap_urls"`
+	CrawledURLs  int       `json:"crawled_urls"`
+	Orphans      []string  `json:"orphans"`        // in a sitemap, but linked from no crawled page
+	NotInSitemap []string  `json:"not_in_sitemap"` // crawled, but missing from the sitemaps
+}
+
+// sitemapLocation is a <url> entry of a sitemap or a <sitemap> entry of a
+// sitemap index
+type sitemapLocation struct {
+	Loc string `xml:"loc"`
+}
+
+// SitemapCoverage fetches the sitemaps of the site of startURL and compares
+// them with a recursive crawl from it. Sitemap pages outside the crawl scope
+// (other hosts, excluded URLs) are ignored.
+func (c *Crawler) SitemapCoverage(ctx context.Context, startURL string, resp *StartCrawlResponse) (*SitemapCoverage, error) {
+	start, err := neturl.Parse(startURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start URL: %w", err)
+	}
+
+	coverage := &SitemapCoverage{
+		StartURL:     startURL,
+		GeneratedAt:  time.Now().UTC(),
+		Sitemaps:     c.sitemapLocations(ctx, start),
+		Orphans:      []string{},
+		NotInSitemap: []string{},
+	}
+
+	listed := make(map[string]string) // page key to sitemap URL
+	err = c.walkSitemaps(ctx, coverage.Sitemaps, func(url string) {
+		parsed, err := neturl.Parse(url)
+		if err != nil || !c.inScope(parsed.Hostname(), start.Hostname()) || c.isExcluded(url) {
+			return
+		}
+		if key := storage.PageKey(url); listed[key] == "" {
+			listed[key] = url
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	coverage.SitemapURLs = len(listed)
+
+	linked := make(map[string]bool, len(resp.Discovered)+1)
+	linked[storage.PageKey(startURL)] = true
+	for _, url := range resp.Discovered {
+		linked[storage.PageKey(url)] = true
+	}
+	for key, url := range listed {
+		if !linked[key] {
+			coverage.Orphans = append(coverage.Orphans, url)
+		}
+	}
+
+	for _, result := range resp.Results {
+		if !result.Success {
+			continue
+		}
+		coverage.CrawledURLs++
+		if _, ok := listed[storage.PageKey(result.URL)]; !ok {
+			coverage.NotInSitemap = append(coverage.NotInSitemap, result.URL)
+		}
+	}
+
+	sort.Strings(coverage.Orphans)
+	sort.Strings(coverage.NotInSitemap)
+	return coverage, nil
+}
+
+// sitemapLocations returns the sitemaps declared in the robots.txt of a site,
+// or its /sitemap.xml if none are
+func (c *Crawler) sitemapLocations(ctx context.Context, site *neturl.URL) []string {
+	origin := site.Scheme + "://" + site.Host
+	var sitemaps []string
+
+	resp, err := c.getOrigin(ctx, origin+"/robots.txt")
+	if err != nil {
+		c.logger.Debug("Failed to fetch robots.txt", map[string]interface{}{"url": origin, "error": err})
+	} else {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxSitemapSize))
+			for scanner.Scan() {
+				name, value, ok := strings.Cut(scanner.Text(), ":")
+				if ok && strings.EqualFold(strings.TrimSpace(name), "sitemap") {
+					sitemaps = append(sitemaps, strings.TrimSpace(value))
+				}
+			}
+		}
+	}
+
+	if len(sitemaps) == 0 {
+		sitemaps = []string{origin + "/sitemap.xml"}
+	}
+	return sitemaps
+}
+
+// walkSitemaps calls visit with every page URL listed in the sitemaps, in
+// order, following sitemap indexes up to maxSitemapDepth levels deep and
+// reading each sitemap once. Sitemaps are decoded as they are downloaded,
+// gzip-compressed ones (.xml.gz) included, so that sitemaps of tens of
+// thousands of URLs are never held in memory. A sitemap listed in an index
+// that cannot be read is skipped; the sitemaps given must be readable.
+func (c *Crawler) walkSitemaps(ctx context.Context, sitemapURLs []string, visit func(url string)) error {
+	seen := make(map[string]bool)
+	var walk func(sitemapURL string, depth int) error
+	walk = func(sitemapURL string, depth int) error {
+		if seen[sitemapURL] {
+			return nil
+		}
+		seen[sitemapURL] = true
+
+		var nested []string
+		err := c.fetchSitemap(ctx, sitemapURL, visit, func(loc string) {
+			nested = append(nested, loc)
+		})
+		if err != nil {
+			return err
+		}
+		if len(nested) > 0 && depth >= maxSitemapDepth {
+			c.logger.Warn("Skipping sitemap index nested too deep", map[string]interface{}{
+				"url":      sitemapURL,
+				"sitemaps": len(nested),
+			})
+			return nil
+		}
+		for _, loc := range nested {
+			if err := walk(loc, depth+1); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				c.logger.Warn("Skipping unreadable sitemap", map[string]interface{}{
+					"url":   loc,
+					"index": sitemapURL,
+					"error": err,
+				})
+			}
+		}
+		return nil
+	}
+
+	for _, sitemapURL := range sitemapURLs {
+		if err := walk(sitemapURL, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchSitemap downloads a sitemap or sitemap index, decompressing it if it
+// is gzip-compressed, and calls page with every page URL it lists and
+// sitemap with every sitemap an index lists, as they are decoded
+func (c *Crawler) fetchSitemap(ctx context.Context, sitemapURL string, page func(loc string), sitemap func(loc string)) error {
+	resp, err := c.getOrigin(ctx, sitemapURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch sitemap %s: %w", sitemapURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch sitemap %s, status code: %d", sitemapURL, resp.StatusCode)
+	}
+
+	// Compressed sitemaps are told by their content rather than their
+	// extension or content type, which servers set inconsistently
+	body := bufio.NewReader(resp.Body)
+	var r io.Reader = body
+	if magic, _ := body.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return fmt.Errorf("invalid compressed sitemap %s: %w", sitemapURL, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	if err := decodeSitemap(io.LimitReader(r, maxSitemapSize), page, sitemap); err != nil {
+		return fmt.Errorf("invalid sitemap %s: %w", sitemapURL, err)
+	}
+	return nil
+}
+
+// decodeSitemap decodes the <url> and <sitemap> entries of a sitemap or a
+// sitemap index one at a time, calling page or sitemap with their locations
+func decodeSitemap(r io.Reader, page func(loc string), sitemap func(loc string)) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || (start.Name.Local != "url" && start.Name.Local != "sitemap") {
+			continue
+		}
+		var location sitemapLocation
+		if err := decoder.DecodeElement(&location, &start); err != nil {
+			return err
+		}
+		loc := strings.TrimSpace(location.Loc)
+		switch {
+		case loc == "":
+		case start.Name.Local == "url":
+			page(loc)
+		default:
+			sitemap(loc)
+		}
+	}
+}
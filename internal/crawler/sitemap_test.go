@@ -0,0 +1,210 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// sitemapServer serves the given paths; a body of a path ending in .gz is
+// compressed. Every request is counted by path.
+func sitemapServer(t *testing.T, files map[string]string) (*httptest.Server, map[string]int) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		host := "http://" + r.Host
+		body = strings.ReplaceAll(body, "{{host}}", host)
+		if strings.HasSuffix(r.URL.Path, ".gz") {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			gz.Write([]byte(body))
+			gz.Close()
+			w.Write(buf.Bytes())
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func urlset(paths ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, path := range paths {
+		fmt.Fprintf(&b, "<url><loc>{{host}}%s</loc></url>", path)
+	}
+	b.WriteString("</urlset>")
+	return b.String()
+}
+
+func sitemapIndex(paths ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, path := range paths {
+		fmt.Fprintf(&b, "<sitemap><loc>{{host}}%s</loc></sitemap>", path)
+	}
+	b.WriteString("</sitemapindex>")
+	return b.String()
+}
+
+func TestWalkSitemaps(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+		once  []string // sitemaps that must be fetched exactly once
+	}{
+		{
+			name: "urlset",
+			files: map[string]string{
+				"/sitemap.xml": urlset("/a", "/b"),
+			},
+			want: []string{"/a", "/b"},
+		},
+		{
+			name: "nested indexes",
+			files: map[string]string{
+				"/sitemap.xml":      sitemapIndex("/docs/index.xml", "/blog.xml"),
+				"/docs/index.xml":   sitemapIndex("/docs/guide.xml", "/docs/api.xml"),
+				"/docs/guide.xml":   urlset("/docs/guide/1", "/docs/guide/2"),
+				"/docs/api.xml":     urlset("/docs/api"),
+				"/blog.xml":         urlset("/blog/post"),
+				"/unreferenced.xml": urlset("/unreferenced"),
+			},
+			want: []string{"/docs/guide/1", "/docs/guide/2", "/docs/api", "/blog/post"},
+		},
+		{
+			name: "index cycle",
+			files: map[string]string{
+				"/sitemap.xml": sitemapIndex("/sitemap.xml", "/a.xml"),
+				"/a.xml":       sitemapIndex("/b.xml", "/pages.xml"),
+				"/b.xml":       sitemapIndex("/a.xml", "/sitemap.xml"),
+				"/pages.xml":   urlset("/page"),
+			},
+			want: []string{"/page"},
+			once: []string{"/sitemap.xml", "/a.xml", "/b.xml", "/pages.xml"},
+		},
+		{
+			name: "gzip body",
+			files: map[string]string{
+				"/sitemap.xml":      sitemapIndex("/sitemap-1.xml.gz", "/sitemap-2.xml"),
+				"/sitemap-1.xml.gz": urlset("/compressed/1", "/compressed/2"),
+				"/sitemap-2.xml":    urlset("/plain"),
+			},
+			want: []string{"/compressed/1", "/compressed/2", "/plain"},
+		},
+		{
+			name: "unreadable nested sitemap",
+			files: map[string]string{
+				"/sitemap.xml": sitemapIndex("/missing.xml", "/broken.xml", "/pages.xml"),
+				"/broken.xml":  "<urlset><url><loc>",
+				"/pages.xml":   urlset("/page"),
+			},
+			want: []string{"/page"},
+		},
+		{
+			name: "depth limit",
+			files: map[string]string{
+				"/sitemap.xml":  sitemapIndex("/1.xml"),
+				"/1.xml":        sitemapIndex("/2.xml"),
+				"/2.xml":        sitemapIndex("/3.xml"),
+				"/3.xml":        sitemapIndex("/4.xml"),
+				"/4.xml":        sitemapIndex("/5.xml", "/deep.xml"),
+				"/5.xml":        sitemapIndex("/too-deep.xml"),
+				"/deep.xml":     urlset("/deep"),
+				"/too-deep.xml": urlset("/too-deep"),
+			},
+			want: []string{"/deep"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := sitemapServer(t, tt.files)
+			c := newTestCrawler(t, nil)
+
+			var got []string
+			err := c.walkSitemaps(context.Background(), []string{server.URL + "/sitemap.xml"}, func(url string) {
+				got = append(got, strings.TrimPrefix(url, server.URL))
+			})
+			if err != nil {
+				t.Fatalf("walkSitemaps() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("walkSitemaps() visited %v, want %v", got, tt.want)
+			}
+			for _, path := range tt.once {
+				if requests[path] != 1 {
+					t.Errorf("%s fetched %d times, want once", path, requests[path])
+				}
+			}
+		})
+	}
+}
+
+func TestWalkSitemapsUnreadableRoot(t *testing.T) {
+	server, _ := sitemapServer(t, map[string]string{})
+	c := newTestCrawler(t, nil)
+
+	err := c.walkSitemaps(context.Background(), []string{server.URL + "/sitemap.xml"}, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "status code: 404") {
+		t.Errorf("walkSitemaps() error = %v, want a 404 error", err)
+	}
+}
+
+func TestSitemapCoverage(t *testing.T) {
+	server, _ := sitemapServer(t, map[string]string{
+		"/robots.txt":  "User-agent: *\nSitemap: {{host}}/docs.xml.gz\n",
+		"/docs.xml.gz": sitemapIndex("/pages.xml"),
+		"/pages.xml":   urlset("/", "/guide", "/orphan", "/guide#install"),
+		"/sitemap.xml": urlset("/ignored"),
+	})
+	c := newTestCrawler(t, nil)
+
+	resp := &StartCrawlResponse{
+		Discovered: []string{server.URL + "/guide", server.URL + "/not-in-map"},
+		Results: []PageResult{
+			{URL: server.URL + "/", Success: true},
+			{URL: server.URL + "/guide", Success: true},
+			{URL: server.URL + "/not-in-map", Success: true},
+			{URL: server.URL + "/failed", Success: false},
+		},
+	}
+	coverage, err := c.SitemapCoverage(context.Background(), server.URL+"/", resp)
+	if err != nil {
+		t.Fatalf("SitemapCoverage() error = %v", err)
+	}
+
+	if want := []string{server.URL + "/docs.xml.gz"}; !reflect.DeepEqual(coverage.Sitemaps, want) {
+		t.Errorf("Sitemaps = %v, want %v", coverage.Sitemaps, want)
+	}
+	if coverage.SitemapURLs != 3 {
+		t.Errorf("SitemapURLs = %d, want 3", coverage.SitemapURLs)
+	}
+	if coverage.CrawledURLs != 3 {
+		t.Errorf("CrawledURLs = %d, want 3", coverage.CrawledURLs)
+	}
+	if want := []string{server.URL + "/orphan"}; !reflect.DeepEqual(coverage.Orphans, want) {
+		t.Errorf("Orphans = %v, want %v", coverage.Orphans, want)
+	}
+	if want := []string{server.URL + "/not-in-map"}; !reflect.DeepEqual(coverage.NotInSitemap, want) {
+		t.Errorf("NotInSitemap = %v, want %v", coverage.NotInSitemap, want)
+	}
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"crawlr/internal/config"
+)
+
+// newTransport returns the transport of the crawler's client, tuned for the
+// configured concurrency so that parallel media downloads reuse their
+// connections rather than opening new ones, which the two idle connections
+// per host of the default transport would force. If the TLS settings cannot
+// be loaded, the transport keeps the default ones and the error is returned
+// with it.
+func newTransport(cfg *config.Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	idlePerHost := cfg.MaxIdleConnsPerHost
+	if idlePerHost == 0 {
+		idlePerHost = cfg.MaxConcurrent
+	}
+	transport.MaxIdleConnsPerHost = idlePerHost
+	transport.MaxIdleConns = max(transport.MaxIdleConns, idlePerHost)
+	transport.IdleConnTimeout = time.Duration(cfg.IdleConnTimeout) * time.Second
+	transport.DisableKeepAlives = !cfg.KeepAlive
+	if !cfg.HTTP2 {
+		// A non-nil empty map keeps the transport from upgrading to HTTP/2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return transport, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// newTLSConfig returns the TLS configuration of the crawler's client, trusting
+// the configured CA bundle besides the system roots and presenting the
+// configured client certificate, or nil for the defaults when no TLS option is
+// set
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.TLSCAFile == "" && cfg.TLSCertFile == "" && !cfg.InsecureSkipVerify {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificate found in CA file %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if cfg.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
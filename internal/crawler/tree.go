@@ -0,0 +1,218 @@
+package crawler
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"crawlr/internal/storage"
+)
+
+// Statuses of the pages of a crawl tree
+const (
+	TreeQueued    = "queued"     // discovered, waiting in the frontier
+	TreeCrawled   = "crawled"    // fetched successfully
+	TreeUnchanged = "unchanged"  // skipped by an incremental re-crawl
+	TreeFailed    = "failed"     // crawl4ai or the origin returned an error
+	TreeTooDeep   = "too_deep"   // dropped for exceeding the maximum depth
+	TreeElsewhere = "elsewhere"  // claimed by a cooperating crawler
+	TreeOverQuota = "over_quota" // dropped once its domain reached its page quota
+	TreeAlias     = "alias"      // redirected to, or declaring as canonical, a page crawled under another URL
+	TreeLanguage  = "language"   // declares a language that is not crawled
+)
+
+// CrawlTree is the crawl tree exported for visualization tools: every page
+// discovered with its depth, the page it was found on and its status. Pages
+// still queued when a crawl ends are the sections the crawler never reached.
+type CrawlTree struct {
+	StartURL  string         `json:"start_url"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Complete  bool           `json:"complete"`
+	Counts    map[string]int `json:"counts"`
+	Depths    []TreeDepth    `json:"depths"`
+	Nodes     []TreeNode     `json:"nodes"`
+	Edges     []TreeEdge     `json:"edges"`
+}
+
+// TreeDepth counts the pages of a depth by status
+type TreeDepth struct {
+	Depth  int            `json:"depth"`
+	Counts map[string]int `json:"counts"`
+}
+
+// TreeNode is a page of the crawl tree, identified by its URL
+type TreeNode struct {
+	ID         string `json:"id"`
+	Parent     string `json:"parent,omitempty"`
+	Depth      int    `json:"depth"`
+	Status     string `json:"status"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// TreeEdge links a page to a page discovered on it
+type TreeEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// crawlTree tracks the pages of a recursive crawl and periodically saves the
+// tree to the library
+type crawlTree struct {
+	mutex    sync.Mutex
+	startURL string
+	nodes    map[string]*TreeNode
+	order    []string // URLs in discovery order
+
+	save     func(data []byte) error
+	interval time.Duration
+	lastSave time.Time
+}
+
+// newCrawlTree returns a tree saved with save at most every interval, or nil
+// if save is nil. Methods of a nil tree do nothing.
+func newCrawlTree(startURL string, save func(data []byte) error, interval time.Duration) *crawlTree {
+	if save == nil {
+		return nil
+	}
+	return &crawlTree{
+		startURL: startURL,
+		nodes:    make(map[string]*TreeNode),
+		save:     save,
+		interval: interval,
+	}
+}
+
+// queued records discovered URLs. The first discovery of a URL sets its
+// parent and depth.
+func (t *crawlTree) queued(items []URLWithDepth) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, item := range items {
+		if _, ok := t.nodes[item.URL]; ok {
+			continue
+		}
+		t.nodes[item.URL] = &TreeNode{ID: item.URL, Parent: item.Parent, Depth: item.Depth, Status: TreeQueued}
+		t.order = append(t.order, item.URL)
+	}
+}
+
+// setStatus records the status of a page
+func (t *crawlTree) setStatus(url string, status string, statusCode int, errorMessage string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	node, ok := t.nodes[url]
+	if !ok {
+		node = &TreeNode{ID: url}
+		t.nodes[url] = node
+		t.order = append(t.order, url)
+	}
+	node.Status, node.StatusCode, node.Error = status, statusCode, errorMessage
+}
+
+// claimedElsewhere records that a queued page was claimed by a cooperating
+// crawler. Pages this crawler already crawled keep their status.
+func (t *crawlTree) claimedElsewhere(url string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if node, ok := t.nodes[url]; ok && node.Status == TreeQueued {
+		node.Status = TreeElsewhere
+	}
+}
+
+// result records the outcome of a crawled page
+func (t *crawlTree) result(page PageResult) {
+	if page.Unchanged {
+		t.setStatus(page.URL, TreeUnchanged, page.StatusCode, "")
+	} else if page.Success {
+		t.setStatus(page.URL, TreeCrawled, page.StatusCode, "")
+	} else {
+		t.setStatus(page.URL, TreeFailed, page.StatusCode, page.ErrorMessage)
+	}
+}
+
+// newCrawlTree returns the crawl tree of a recursive crawl from startURL, or
+// nil unless the tree is exported to the library
+func (c *Crawler) newCrawlTree(startURL string) *crawlTree {
+	if !c.crawlTree || c.storage == nil {
+		return nil
+	}
+	save := func(data []byte) error {
+		return c.storage.WriteLibraryFile(storage.CrawlTreeFile, data)
+	}
+	return newCrawlTree(startURL, save, c.treeInterval)
+}
+
+// flushCrawlTree saves the crawl tree if it is due, logging failures since
+// the tree is only an aid
+func (c *Crawler) flushCrawlTree(tree *crawlTree, complete bool) {
+	if err := tree.flush(complete); err != nil {
+		c.logger.Warn("Failed to save crawl tree", map[string]interface{}{"error": err})
+	}
+}
+
+// flush saves the tree if the interval elapsed since it was last saved, or
+// unconditionally once the crawl is complete
+func (t *crawlTree) flush(complete bool) error {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !complete && time.Since(t.lastSave) < t.interval {
+		return nil
+	}
+	data, err := json.MarshalIndent(t.snapshot(complete), "", "  ")
+	if err != nil {
+		return err
+	}
+	t.lastSave = time.Now()
+	return t.save(append(data, '\n'))
+}
+
+// snapshot builds the exported tree. The caller must hold mutex.
+func (t *crawlTree) snapshot(complete bool) CrawlTree {
+	tree := CrawlTree{
+		StartURL:  t.startURL,
+		UpdatedAt: time.Now().UTC(),
+		Complete:  complete,
+		Counts:    make(map[string]int),
+		Nodes:     make([]TreeNode, 0, len(t.order)),
+		Edges:     []TreeEdge{},
+	}
+
+	depths := make(map[int]map[string]int)
+	for _, url := range t.order {
+		node := *t.nodes[url]
+		tree.Nodes = append(tree.Nodes, node)
+		if node.Parent != "" {
+			tree.Edges = append(tree.Edges, TreeEdge{Source: node.Parent, Target: node.ID})
+		}
+
+		tree.Counts[node.Status]++
+		if depths[node.Depth] == nil {
+			depths[node.Depth] = make(map[string]int)
+		}
+		depths[node.Depth][node.Status]++
+	}
+
+	for depth, counts := range depths {
+		tree.Depths = append(tree.Depths, TreeDepth{Depth: depth, Counts: counts})
+	}
+	sort.Slice(tree.Depths, func(i, j int) bool { return tree.Depths[i].Depth < tree.Depths[j].Depth })
+	return tree
+}
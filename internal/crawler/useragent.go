@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// userAgents hands out the configured user agents in turn, one per request
+type userAgents struct {
+	agents []string
+	next   atomic.Uint64
+}
+
+// newUserAgents returns the rotation of agents, skipping blank ones
+func newUserAgents(agents []string) *userAgents {
+	u := &userAgents{}
+	for _, agent := range agents {
+		if agent = strings.TrimSpace(agent); agent != "" {
+			u.agents = append(u.agents, agent)
+		}
+	}
+	return u
+}
+
+// pick returns the user agent of the next request, or "" if none is
+// configured
+func (u *userAgents) pick() string {
+	if len(u.agents) == 0 {
+		return ""
+	}
+	return u.agents[(u.next.Add(1)-1)%uint64(len(u.agents))]
+}
+
+// setUserAgent sets the next configured user agent on a request to an origin
+// server, leaving the request's own if none is configured
+func (c *Crawler) setUserAgent(req *http.Request) {
+	if agent := c.userAgents.pick(); agent != "" {
+		req.Header.Set("User-Agent", agent)
+	}
+}
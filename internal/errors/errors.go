@@ -1,6 +1,7 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"runtime"
 	"strings"
@@ -255,40 +256,97 @@ func HandleError(err error) error {
 	return err
 }
 
+// RecoveryHint returns a suggestion on how to recover from errors of the given type
+func RecoveryHint(errorType ErrorType) string {
+	switch errorType {
+	case ConfigurationError:
+		return "Check configuration files and environment variables"
+	case NetworkError:
+		return "Check network connectivity and server status"
+	case StorageError:
+		return "Check disk space and file permissions"
+	case APIError:
+		return "Check API documentation and request parameters"
+	case ValidationError:
+		return "Check input parameters and data formats"
+	case CrawlerError:
+		return "Check crawler configuration and target website accessibility"
+	default:
+		return ""
+	}
+}
+
+// Exit codes of the crawlr process by error type, so that scripts can tell
+// why a command failed
+const (
+	ExitFailure       = 1 // errors of no other class
+	ExitConfiguration = 2
+	ExitValidation    = 3
+	ExitNetwork       = 4
+	ExitStorage       = 5
+	ExitCrawler       = 6
+	ExitAPI           = 7
+)
+
+// ExitCode returns the exit code of the process for the error that ended a
+// command, from the type of the outermost CrawlrError it is or wraps
+func ExitCode(err error) int {
+	var crawlrErr *CrawlrError
+	if !stderrors.As(err, &crawlrErr) {
+		return ExitFailure
+	}
+	switch crawlrErr.Type {
+	case ConfigurationError:
+		return ExitConfiguration
+	case ValidationError:
+		return ExitValidation
+	case NetworkError:
+		return ExitNetwork
+	case StorageError:
+		return ExitStorage
+	case CrawlerError:
+		return ExitCrawler
+	case APIError:
+		return ExitAPI
+	default:
+		return ExitFailure
+	}
+}
+
 // HandleConfigurationError handles configuration errors
 func HandleConfigurationError(err *CrawlrError) error {
 	// Add additional context or perform recovery actions for configuration errors
-	return err.WithContext("recovery", "Check configuration files and environment variables")
+	return err.WithContext("recovery", RecoveryHint(ConfigurationError))
 }
 
 // HandleNetworkError handles network errors
 func HandleNetworkError(err *CrawlrError) error {
 	// Add additional context or perform recovery actions for network errors
-	return err.WithContext("recovery", "Check network connectivity and server status")
+	return err.WithContext("recovery", RecoveryHint(NetworkError))
 }
 
 // HandleStorageError handles storage errors
 func HandleStorageError(err *CrawlrError) error {
 	// Add additional context or perform recovery actions for storage errors
-	return err.WithContext("recovery", "Check disk space and file permissions")
+	return err.WithContext("recovery", RecoveryHint(StorageError))
 }
 
 // HandleAPIError handles API errors
 func HandleAPIError(err *CrawlrError) error {
 	// Add additional context or perform recovery actions for API errors
-	return err.WithContext("recovery", "Check API documentation and request parameters")
+	return err.WithContext("recovery", RecoveryHint(APIError))
 }
 
 // HandleValidationError handles validation errors
 func HandleValidationError(err *CrawlrError) error {
 	// Add additional context or perform recovery actions for validation errors
-	return err.WithContext("recovery", "Check input parameters and data formats")
+	return err.WithContext("recovery", RecoveryHint(ValidationError))
 }
 
 // HandleCrawlerError handles crawler errors
 func HandleCrawlerError(err *CrawlrError) error {
 	// Add additional context or perform recovery actions for crawler errors
-	return err.WithContext("recovery", "Check crawler configuration and target website accessibility")
+	return err.WithContext("recovery", RecoveryHint(CrawlerError))
 }
 
 // RetryableError represents an error that can be retried
@@ -0,0 +1,72 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+)
+
+// Report is the machine-readable representation of an error
+type Report struct {
+	Type     string                 `json:"type"`
+	Message  string                 `json:"message"`
+	Cause    string                 `json:"cause,omitempty"`
+	URL      string                 `json:"url,omitempty"`
+	Fatal    bool                   `json:"fatal"`
+	ExitCode int                    `json:"exit_code,omitempty"` // exit code of the process, for the fatal error
+	Context  map[string]interface{} `json:"context,omitempty"`
+	Recovery string                 `json:"recovery,omitempty"`
+}
+
+// NewReport builds the report of err. Errors that are not, and do not wrap, a
+// CrawlrError are reported with the UnknownError type.
+func NewReport(err error) *Report {
+	var crawlrErr *CrawlrError
+	if !stderrors.As(err, &crawlrErr) {
+		return &Report{Type: ErrorType(-1).String(), Message: err.Error()}
+	}
+
+	report := &Report{
+		Type:     crawlrErr.Type.String(),
+		Message:  crawlrErr.Message,
+		Recovery: RecoveryHint(crawlrErr.Type),
+	}
+	if crawlrErr.Err != nil {
+		report.Cause = crawlrErr.Err.Error()
+	}
+
+	for k, v := range crawlrErr.Context {
+		if k == "recovery" {
+			if hint, ok := v.(string); ok {
+				report.Recovery = hint
+			}
+			continue
+		}
+		if report.Context == nil {
+			report.Context = make(map[string]interface{})
+		}
+		// Errors do not marshal to anything useful
+		if e, ok := v.(error); ok {
+			v = e.Error()
+		}
+		report.Context[k] = v
+	}
+
+	return report
+}
+
+// JSON encodes the report on a single line
+func (r *Report) JSON() []byte {
+	data, err := json.Marshal(r)
+	if err != nil {
+		// Context values that cannot be encoded are rendered as text
+		context := make(map[string]interface{}, len(r.Context))
+		for k, v := range r.Context {
+			context[k] = fmt.Sprint(v)
+		}
+		fallback := *r
+		fallback.Context = context
+		data, _ = json.Marshal(fallback)
+	}
+	return data
+}
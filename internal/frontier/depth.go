@@ -0,0 +1,104 @@
+package frontier
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"crawlr/internal/config"
+)
+
+// DepthPolicy decides how deep the crawl goes below each URL path. The first
+// rule whose glob matches the path of a URL sets its maximum depth; URLs
+// matching no rule use the max_depth of their domain policy, if any, or else
+// the default.
+type DepthPolicy struct {
+	defaultDepth int
+	rules        []depthRule
+	domains      map[string]config.DomainPolicy
+}
+
+// depthRule is a compiled config.DepthRule
+type depthRule struct {
+	pattern  *regexp.Regexp
+	maxDepth int
+}
+
+// NewDepthPolicy compiles the depth rules, with the max_depth of the domain
+// policies, then defaultDepth, applying to the URLs they do not match. In globs, ** matches any run of characters, slashes
+// included, * any run of characters within a path segment and ? a single
+// character. A trailing /** also matches the directory itself.
+func NewDepthPolicy(defaultDepth int, rules []config.DepthRule, domains map[string]config.DomainPolicy) *DepthPolicy {
+	policy := &DepthPolicy{defaultDepth: defaultDepth, domains: domains}
+	for _, rule := range rules {
+		policy.rules = append(policy.rules, depthRule{
+			pattern:  pathGlob(rule.Path),
+			maxDepth: rule.MaxDepth,
+		})
+	}
+	return policy
+}
+
+// MaxDepth returns the maximum depth at which rawURL is crawled
+func (p *DepthPolicy) MaxDepth(rawURL string) int {
+	if len(p.rules) == 0 && len(p.domains) == 0 {
+		return p.defaultDepth
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		u = &url.URL{}
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	for _, rule := range p.rules {
+		if rule.pattern.MatchString(path) {
+			return rule.maxDepth
+		}
+	}
+	if _, policy, ok := config.LookupDomain(p.domains, u.Hostname()); ok && policy.MaxDepth != nil {
+		return *policy.MaxDepth
+	}
+	return p.defaultDepth
+}
+
+// Deepest returns the greatest maximum depth of the policy. Links are
+// followed from pages above it, since some of them may be allowed deeper.
+func (p *DepthPolicy) Deepest() int {
+	deepest := p.defaultDepth
+	for _, rule := range p.rules {
+		deepest = max(deepest, rule.maxDepth)
+	}
+	for _, policy := range p.domains {
+		if policy.MaxDepth != nil {
+			deepest = max(deepest, *policy.MaxDepth)
+		}
+	}
+	return deepest
+}
+
+// pathGlob compiles a path glob into an anchored regular expression
+func pathGlob(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("(/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,76 @@
+package frontier
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Traversal strategies, deciding which of the waiting URLs are crawled next
+const (
+	// BFS crawls URLs in the order they were found, level by level
+	BFS = "bfs"
+	// DFS crawls the URLs found last first, following a branch to its end
+	DFS = "dfs"
+	// BestFirst crawls the URLs with the highest score first
+	BestFirst = "bestfirst"
+)
+
+// URLWithDepth represents a URL with its crawl depth
+type URLWithDepth struct {
+	URL    string `json:"url"`
+	Depth  int    `json:"depth"`
+	Parent string `json:"parent,omitempty"` // page the URL was found on
+	Score  int    `json:"score,omitempty"`  // priority of the URL for best-first traversal
+}
+
+// Frontier holds the URLs waiting to be crawled
+type Frontier interface {
+	// Push adds items to the frontier, ordered by its traversal strategy.
+	// Items of equal rank keep their order.
+	Push(ctx context.Context, items []URLWithDepth) error
+	// Pop removes and returns up to n items from the front of the frontier
+	Pop(ctx context.Context, n int) ([]URLWithDepth, error)
+	// Len returns the number of items waiting in the frontier
+	Len(ctx context.Context) (int, error)
+}
+
+// VisitedSet tracks the URLs that have already been claimed for crawling
+type VisitedSet interface {
+	// Claim marks URLs as visited and returns those that were not visited before.
+	// Claims are atomic, so concurrent crawlers never both receive the same URL.
+	Claim(ctx context.Context, urls []string) ([]string, error)
+	// Contains reports, for each URL, whether it has been visited
+	Contains(ctx context.Context, urls []string) ([]bool, error)
+	// Len returns the number of visited URLs
+	Len(ctx context.Context) (int, error)
+}
+
+// Backend creates the frontier and visited set for a crawl
+type Backend interface {
+	// Open returns the state for the crawl identified by namespace. Crawlers
+	// opening the same namespace on a shared backend cooperate on one crawl.
+	Open(ctx context.Context, namespace string) (Frontier, VisitedSet, error)
+	// Close releases any resources held by the backend
+	Close() error
+}
+
+// Options configures a backend
+type Options struct {
+	RedisURL  string
+	KeyPrefix string
+	TTL       time.Duration
+	Strategy  string // traversal strategy, BFS if empty
+}
+
+// NewBackend creates the backend named by kind ("memory" or "redis")
+func NewBackend(kind string, opts Options) (Backend, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryBackend(opts.Strategy), nil
+	case "redis":
+		return NewRedisBackend(opts)
+	default:
+		return nil, fmt.Errorf("unknown state backend: %q", kind)
+	}
+}
@@ -0,0 +1,228 @@
+package frontier
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// backendFactory creates a backend following a traversal strategy
+type backendFactory func(t *testing.T, strategy string) Backend
+
+// backends returns the backends under test. The Redis backend runs against
+// the server at REDIS_URL and is skipped when it is unset.
+func backends() map[string]backendFactory {
+	return map[string]backendFactory{
+		"memory": func(t *testing.T, strategy string) Backend {
+			return NewMemoryBackend(strategy)
+		},
+		"redis": func(t *testing.T, strategy string) Backend {
+			return newTestRedisBackend(t, strategy, 0)
+		},
+	}
+}
+
+// newTestRedisBackend connects to the server at REDIS_URL under a key prefix
+// of its own, removing its keys when the test ends
+func newTestRedisBackend(t *testing.T, strategy string, ttl time.Duration) *RedisBackend {
+	t.Helper()
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL is not set")
+	}
+	prefix := fmt.Sprintf("crawlr-test-%d", time.Now().UnixNano())
+	backend, err := NewRedisBackend(Options{RedisURL: redisURL, KeyPrefix: prefix, TTL: ttl, Strategy: strategy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx := context.Background()
+		if keys, err := backend.client.Keys(ctx, prefix+":*").Result(); err == nil && len(keys) > 0 {
+			backend.client.Del(ctx, keys...)
+		}
+		backend.Close()
+	})
+	return backend
+}
+
+// open opens the state of a crawl on a backend
+func open(t *testing.T, backend Backend) (Frontier, VisitedSet) {
+	t.Helper()
+	frontier, visited, err := backend.Open(context.Background(), "docs")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return frontier, visited
+}
+
+func TestFrontierOrder(t *testing.T) {
+	first := []URLWithDepth{{URL: "a", Score: 1}, {URL: "b", Score: 3}}
+	second := []URLWithDepth{{URL: "c", Score: 3}, {URL: "d", Score: 2}}
+	tests := []struct {
+		strategy string
+		want     []string
+	}{
+		{BFS, []string{"a", "b", "c", "d"}},
+		// Each push goes ahead of the earlier ones, keeping its own order
+		{DFS, []string{"c", "d", "a", "b"}},
+		// Highest score first, items of equal score in push order
+		{BestFirst, []string{"b", "c", "d", "a"}},
+	}
+	for name, newBackend := range backends() {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.strategy, func(t *testing.T) {
+				ctx := context.Background()
+				frontier, _ := open(t, newBackend(t, tt.strategy))
+
+				for _, items := range [][]URLWithDepth{first, second, nil} {
+					if err := frontier.Push(ctx, items); err != nil {
+						t.Fatalf("Push() error = %v", err)
+					}
+				}
+				if n, err := frontier.Len(ctx); err != nil || n != 4 {
+					t.Fatalf("Len() = %d, %v, want 4", n, err)
+				}
+
+				var got []string
+				for _, n := range []int{3, 3} {
+					items, err := frontier.Pop(ctx, n)
+					if err != nil {
+						t.Fatalf("Pop() error = %v", err)
+					}
+					for _, item := range items {
+						got = append(got, item.URL)
+					}
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("popped %v, want %v", got, tt.want)
+				}
+
+				items, err := frontier.Pop(ctx, 1)
+				if err != nil || len(items) != 0 {
+					t.Errorf("Pop() on an empty frontier = %v, %v, want nothing", items, err)
+				}
+			})
+		}
+	}
+}
+
+func TestFrontierKeepsItems(t *testing.T) {
+	item := URLWithDepth{URL: "https://docs.example.com/guide", Depth: 2, Parent: "https://docs.example.com/", Score: 5}
+	for name, newBackend := range backends() {
+		for _, strategy := range []string{BFS, DFS, BestFirst} {
+			t.Run(name+"/"+strategy, func(t *testing.T) {
+				ctx := context.Background()
+				frontier, _ := open(t, newBackend(t, strategy))
+				if err := frontier.Push(ctx, []URLWithDepth{item}); err != nil {
+					t.Fatalf("Push() error = %v", err)
+				}
+				items, err := frontier.Pop(ctx, 1)
+				if err != nil || len(items) != 1 || items[0] != item {
+					t.Errorf("Pop() = %v, %v, want %v", items, err, item)
+				}
+			})
+		}
+	}
+}
+
+func TestVisitedClaim(t *testing.T) {
+	for name, newBackend := range backends() {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, visited := open(t, newBackend(t, BFS))
+
+			claimed, err := visited.Claim(ctx, []string{"a", "b", "a"})
+			if err != nil || !slices.Equal(claimed, []string{"a", "b"}) {
+				t.Errorf("Claim() = %v, %v, want [a b]", claimed, err)
+			}
+			claimed, err = visited.Claim(ctx, []string{"b", "c"})
+			if err != nil || !slices.Equal(claimed, []string{"c"}) {
+				t.Errorf("Claim() = %v, %v, want [c]", claimed, err)
+			}
+			contains, err := visited.Contains(ctx, []string{"a", "c", "d"})
+			if err != nil || !slices.Equal(contains, []bool{true, true, false}) {
+				t.Errorf("Contains() = %v, %v, want [true true false]", contains, err)
+			}
+			if n, err := visited.Len(ctx); err != nil || n != 3 {
+				t.Errorf("Len() = %d, %v, want 3", n, err)
+			}
+		})
+	}
+}
+
+func TestVisitedConcurrentClaims(t *testing.T) {
+	var urls []string
+	for i := range 50 {
+		urls = append(urls, fmt.Sprintf("https://docs.example.com/%d", i))
+	}
+	for name, newBackend := range backends() {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, visited := open(t, newBackend(t, BFS))
+
+			var (
+				wg      sync.WaitGroup
+				mutex   sync.Mutex
+				claimed []string
+			)
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					got, err := visited.Claim(ctx, urls)
+					if err != nil {
+						t.Errorf("Claim() error = %v", err)
+					}
+					mutex.Lock()
+					claimed = append(claimed, got...)
+					mutex.Unlock()
+				}()
+			}
+			wg.Wait()
+
+			slices.Sort(claimed)
+			want := slices.Clone(urls)
+			slices.Sort(want)
+			if !slices.Equal(claimed, want) {
+				t.Errorf("concurrent claims returned %d URLs, want each of the %d once", len(claimed), len(urls))
+			}
+		})
+	}
+}
+
+func TestRedisTTL(t *testing.T) {
+	for _, strategy := range []string{BFS, BestFirst} {
+		t.Run(strategy, func(t *testing.T) {
+			ctx := context.Background()
+			backend := newTestRedisBackend(t, strategy, time.Minute)
+			frontier, visited := open(t, backend)
+			if err := frontier.Push(ctx, []URLWithDepth{{URL: "a", Score: 1}}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := visited.Claim(ctx, []string{"a"}); err != nil {
+				t.Fatal(err)
+			}
+
+			keys, err := backend.client.Keys(ctx, backend.keyPrefix+":*").Result()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) == 0 {
+				t.Fatal("no keys written")
+			}
+			for _, key := range keys {
+				ttl, err := backend.client.TTL(ctx, key).Result()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ttl <= 0 || ttl > time.Minute {
+					t.Errorf("TTL of %s = %v, want within (0, 1m]", key, ttl)
+				}
+			}
+		})
+	}
+}
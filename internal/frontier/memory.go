@@ -0,0 +1,113 @@
+package frontier
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// MemoryBackend keeps crawl state in process memory. Every Open call returns
+// fresh state, so nothing is shared between crawls.
+type MemoryBackend struct {
+	strategy string
+}
+
+// NewMemoryBackend creates a new in-memory backend whose frontiers follow the
+// given traversal strategy
+func NewMemoryBackend(strategy string) *MemoryBackend {
+	return &MemoryBackend{strategy: strategy}
+}
+
+// Open implements the Backend interface
+func (b *MemoryBackend) Open(ctx context.Context, namespace string) (Frontier, VisitedSet, error) {
+	return &memoryFrontier{strategy: b.strategy}, &memoryVisited{seen: make(map[string]bool)}, nil
+}
+
+// Close implements the Backend interface
+func (b *MemoryBackend) Close() error {
+	return nil
+}
+
+// memoryFrontier is a slice-backed frontier
+type memoryFrontier struct {
+	mutex    sync.Mutex
+	strategy string
+	items    []URLWithDepth
+}
+
+func (f *memoryFrontier) Push(ctx context.Context, items []URLWithDepth) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	switch f.strategy {
+	case DFS:
+		f.items = append(append([]URLWithDepth{}, items...), f.items...)
+	case BestFirst:
+		// Keep the items sorted by descending score, after those of equal score
+		for _, item := range items {
+			i := sort.Search(len(f.items), func(i int) bool { return f.items[i].Score < item.Score })
+			f.items = slices.Insert(f.items, i, item)
+		}
+	default:
+		f.items = append(f.items, items...)
+	}
+	return nil
+}
+
+func (f *memoryFrontier) Pop(ctx context.Context, n int) ([]URLWithDepth, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if n > len(f.items) {
+		n = len(f.items)
+	}
+	popped := f.items[:n:n]
+	f.items = f.items[n:]
+	return popped, nil
+}
+
+func (f *memoryFrontier) Len(ctx context.Context) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return len(f.items), nil
+}
+
+// memoryVisited is a map-backed visited set
+type memoryVisited struct {
+	mutex sync.Mutex
+	seen  map[string]bool
+}
+
+func (v *memoryVisited) Claim(ctx context.Context, urls []string) ([]string, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	var claimed []string
+	for _, url := range urls {
+		if !v.seen[url] {
+			v.seen[url] = true
+			claimed = append(claimed, url)
+		}
+	}
+	return claimed, nil
+}
+
+func (v *memoryVisited) Contains(ctx context.Context, urls []string) ([]bool, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	result := make([]bool, len(urls))
+	for i, url := range urls {
+		result[i] = v.seen[url]
+	}
+	return result, nil
+}
+
+func (v *memoryVisited) Len(ctx context.Context) (int, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	return len(v.seen), nil
+}
@@ -0,0 +1,276 @@
+package frontier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultKeyPrefix prefixes every key written to Redis
+const DefaultKeyPrefix = "crawlr"
+
+// RedisBackend shares crawl state between crawlers through Redis. The visited
+// set is a Redis set and the frontier a Redis list, or a sorted set for
+// best-first traversal, both keyed by namespace.
+type RedisBackend struct {
+	client    *redis.Client
+	keyPrefix string
+	ttl       time.Duration
+	strategy  string
+}
+
+// NewRedisBackend connects to the Redis server at opts.RedisURL
+func NewRedisBackend(opts Options) (*RedisBackend, error) {
+	if opts.RedisURL == "" {
+		return nil, fmt.Errorf("redis URL is required for the redis state backend")
+	}
+
+	redisOpts, err := redis.ParseURL(opts.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis URL: %w", err)
+	}
+
+	keyPrefix := opts.KeyPrefix
+	if keyPrefix == "" {
+		keyPrefix = DefaultKeyPrefix
+	}
+
+	return &RedisBackend{
+		client:    redis.NewClient(redisOpts),
+		keyPrefix: keyPrefix,
+		ttl:       opts.TTL,
+		strategy:  opts.Strategy,
+	}, nil
+}
+
+// Open implements the Backend interface
+func (b *RedisBackend) Open(ctx context.Context, namespace string) (Frontier, VisitedSet, error) {
+	if err := b.client.Ping(ctx).Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	base := fmt.Sprintf("%s:%s", b.keyPrefix, namespace)
+	visited := &redisVisited{client: b.client, key: base + ":visited", ttl: b.ttl}
+	if b.strategy == BestFirst {
+		frontier := &redisRankedFrontier{client: b.client, key: base + ":frontier:ranked", seqKey: base + ":frontier:seq", ttl: b.ttl}
+		return frontier, visited, nil
+	}
+	frontier := &redisFrontier{client: b.client, key: base + ":frontier", ttl: b.ttl, lifo: b.strategy == DFS}
+	return frontier, visited, nil
+}
+
+// Close implements the Backend interface
+func (b *RedisBackend) Close() error {
+	return b.client.Close()
+}
+
+// redisFrontier stores JSON-encoded items in a Redis list, popped from the
+// head. Items are pushed at the tail, or at the head when lifo is set.
+type redisFrontier struct {
+	client *redis.Client
+	key    string
+	ttl    time.Duration
+	lifo   bool
+}
+
+func (f *redisFrontier) Push(ctx context.Context, items []URLWithDepth) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("failed to marshal frontier item: %w", err)
+		}
+		values[i] = data
+	}
+
+	pipe := f.client.TxPipeline()
+	if f.lifo {
+		// LPUSH inserts each value at the head in turn, so push in reverse to keep order
+		slices.Reverse(values)
+		pipe.LPush(ctx, f.key, values...)
+	} else {
+		pipe.RPush(ctx, f.key, values...)
+	}
+	if f.ttl > 0 {
+		pipe.Expire(ctx, f.key, f.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to push to frontier: %w", err)
+	}
+	return nil
+}
+
+func (f *redisFrontier) Pop(ctx context.Context, n int) ([]URLWithDepth, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	values, err := f.client.LPopCount(ctx, f.key, n).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pop from frontier: %w", err)
+	}
+
+	items := make([]URLWithDepth, 0, len(values))
+	for _, value := range values {
+		var item URLWithDepth
+		if err := json.Unmarshal([]byte(value), &item); err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (f *redisFrontier) Len(ctx context.Context) (int, error) {
+	n, err := f.client.LLen(ctx, f.key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get frontier length: %w", err)
+	}
+	return int(n), nil
+}
+
+// redisRankedFrontier stores JSON-encoded items in a Redis sorted set for
+// best-first traversal. Ranks combine the score of an item with a sequence
+// number, so that items of equal score are popped in the order they were pushed.
+type redisRankedFrontier struct {
+	client *redis.Client
+	key    string
+	seqKey string
+	ttl    time.Duration
+}
+
+// rankScale separates scores in ranks; sequence numbers must stay below it
+const rankScale = 1e9
+
+func (f *redisRankedFrontier) Push(ctx context.Context, items []URLWithDepth) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	last, err := f.client.IncrBy(ctx, f.seqKey, int64(len(items))).Result()
+	if err != nil {
+		return fmt.Errorf("failed to push to frontier: %w", err)
+	}
+	first := last - int64(len(items)) + 1
+
+	members := make([]redis.Z, len(items))
+	for i, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("failed to marshal frontier item: %w", err)
+		}
+		seq := (first + int64(i)) % rankScale
+		members[i] = redis.Z{Score: float64(item.Score)*rankScale - float64(seq), Member: data}
+	}
+
+	pipe := f.client.TxPipeline()
+	pipe.ZAdd(ctx, f.key, members...)
+	if f.ttl > 0 {
+		pipe.Expire(ctx, f.key, f.ttl)
+		pipe.Expire(ctx, f.seqKey, f.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to push to frontier: %w", err)
+	}
+	return nil
+}
+
+func (f *redisRankedFrontier) Pop(ctx context.Context, n int) ([]URLWithDepth, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	members, err := f.client.ZPopMax(ctx, f.key, int64(n)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pop from frontier: %w", err)
+	}
+
+	items := make([]URLWithDepth, 0, len(members))
+	for _, member := range members {
+		value, _ := member.Member.(string)
+		var item URLWithDepth
+		if err := json.Unmarshal([]byte(value), &item); err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (f *redisRankedFrontier) Len(ctx context.Context) (int, error) {
+	n, err := f.client.ZCard(ctx, f.key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get frontier length: %w", err)
+	}
+	return int(n), nil
+}
+
+// redisVisited stores visited URLs in a Redis set
+type redisVisited struct {
+	client *redis.Client
+	key    string
+	ttl    time.Duration
+}
+
+func (v *redisVisited) Claim(ctx context.Context, urls []string) ([]string, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	// One SADD per URL tells us exactly which URLs this crawler added first
+	pipe := v.client.TxPipeline()
+	cmds := make([]*redis.IntCmd, len(urls))
+	for i, url := range urls {
+		cmds[i] = pipe.SAdd(ctx, v.key, url)
+	}
+	if v.ttl > 0 {
+		pipe.Expire(ctx, v.key, v.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to claim URLs: %w", err)
+	}
+
+	var claimed []string
+	for i, cmd := range cmds {
+		if cmd.Val() == 1 {
+			claimed = append(claimed, urls[i])
+		}
+	}
+	return claimed, nil
+}
+
+func (v *redisVisited) Contains(ctx context.Context, urls []string) ([]bool, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	members := make([]interface{}, len(urls))
+	for i, url := range urls {
+		members[i] = url
+	}
+
+	result, err := v.client.SMIsMember(ctx, v.key, members...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check visited URLs: %w", err)
+	}
+	return result, nil
+}
+
+func (v *redisVisited) Len(ctx context.Context) (int, error) {
+	n, err := v.client.SCard(ctx, v.key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get visited count: %w", err)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,160 @@
+// Package hooks runs user hooks on every page around saving: pre-save hooks
+// may transform the markdown of a page or reject it, post-save hooks are told
+// about saved pages, e.g. to index them downstream.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"crawlr/internal/config"
+)
+
+// ExecPrefix prefixes the hooks of the configuration run as shell commands
+const ExecPrefix = "exec:"
+
+// RejectExitCode is the exit status with which a pre-save exec hook rejects
+// a page
+const RejectExitCode = 3
+
+// defaultTimeout bounds a hook run when the configuration sets no timeout
+const defaultTimeout = 30 * time.Second
+
+// ErrReject is returned by pre-save hooks rejecting a page, which is then not
+// saved
+var ErrReject = errors.New("page rejected by hook")
+
+// Page is a crawled page handed to hooks
+type Page struct {
+	URL        string
+	Title      string
+	StatusCode int
+	Depth      int
+	Markdown   string // pre-save hooks may change it
+	Path       string // saved markdown file, for post-save hooks
+}
+
+// Hook processes pages around saving. PreSave may change the markdown of the
+// page or return ErrReject; PostSave runs once the page is saved.
+type Hook interface {
+	PreSave(ctx context.Context, page *Page) error
+	PostSave(ctx context.Context, page *Page) error
+}
+
+// Hooks runs hooks in order
+type Hooks []Hook
+
+// New returns the exec hooks of the configuration, in order
+func New(cfg config.HooksConfig) (Hooks, error) {
+	timeout := defaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	var hooks Hooks
+	for _, stage := range []struct {
+		name     string
+		commands []string
+	}{
+		{"pre_save", cfg.PreSave},
+		{"post_save", cfg.PostSave},
+	} {
+		for i, hook := range stage.commands {
+			command, ok := strings.CutPrefix(hook, ExecPrefix)
+			if !ok || strings.TrimSpace(command) == "" {
+				return nil, fmt.Errorf("hooks.%s[%d]: must be %s<command>, got %q", stage.name, i, ExecPrefix, hook)
+			}
+			hooks = append(hooks, &execHook{stage: stage.name, command: command, timeout: timeout})
+		}
+	}
+	return hooks, nil
+}
+
+// PreSave runs the pre-save hooks in order, stopping at the first error
+func (h Hooks) PreSave(ctx context.Context, page *Page) error {
+	for _, hook := range h {
+		if err := hook.PreSave(ctx, page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PostSave runs every post-save hook, returning the errors joined
+func (h Hooks) PostSave(ctx context.Context, page *Page) error {
+	var errs []error
+	for _, hook := range h {
+		if err := hook.PostSave(ctx, page); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// execHook runs a shell command with the markdown of the page on stdin and
+// the page described by CRAWLR_* environment variables. A pre-save command
+// prints the markdown to save, or exits with RejectExitCode to reject the
+// page; the output of a post-save command is ignored.
+type execHook struct {
+	stage   string // pre_save or post_save
+	command string
+	timeout time.Duration
+}
+
+func (h *execHook) PreSave(ctx context.Context, page *Page) error {
+	if h.stage != "pre_save" {
+		return nil
+	}
+	output, err := h.run(ctx, page)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == RejectExitCode {
+		return ErrReject
+	}
+	if err != nil {
+		return err
+	}
+	page.Markdown = string(output)
+	return nil
+}
+
+func (h *execHook) PostSave(ctx context.Context, page *Page) error {
+	if h.stage != "post_save" {
+		return nil
+	}
+	_, err := h.run(ctx, page)
+	return err
+}
+
+func (h *execHook) run(ctx context.Context, page *Page) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.command)
+	cmd.Stdin = strings.NewReader(page.Markdown)
+	cmd.Env = append(os.Environ(),
+		"CRAWLR_HOOK="+h.stage,
+		"CRAWLR_URL="+page.URL,
+		"CRAWLR_TITLE="+page.Title,
+		"CRAWLR_STATUS_CODE="+strconv.Itoa(page.StatusCode),
+		"CRAWLR_DEPTH="+strconv.Itoa(page.Depth),
+		"CRAWLR_PATH="+page.Path,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s hook %q failed: %w: %s", h.stage, h.command, err, msg)
+		}
+		return nil, fmt.Errorf("%s hook %q failed: %w", h.stage, h.command, err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,484 @@
+// Package htmlmd converts HTML pages to markdown without crawl4ai, for the
+// pages crawlr fetches itself when the crawl4ai server is unavailable. The
+// conversion is best-effort: headings, paragraphs, links, images, emphasis,
+// code, lists, quotes and tables are kept, scripts, styles and forms dropped.
+package htmlmd
+
+import (
+	"fmt"
+	"io"
+	neturl "net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Page is the markdown of an HTML page with what was found along the way
+type Page struct {
+	Title    string
+	Markdown string
+	Images   []string // absolute URLs of the images, in page order, once each
+}
+
+// skipped are the elements whose content is not part of the markdown
+var skipped = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Select:   true,
+	atom.Textarea: true,
+}
+
+// blocks are the elements rendered as blocks separated by blank lines
+var blocks = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Div:        true,
+	atom.Section:    true,
+	atom.Article:    true,
+	atom.Main:       true,
+	atom.Header:     true,
+	atom.Footer:     true,
+	atom.Nav:        true,
+	atom.Aside:      true,
+	atom.Figure:     true,
+	atom.Figcaption: true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Dd:         true,
+	atom.Details:    true,
+	atom.Summary:    true,
+	atom.Address:    true,
+}
+
+// spacePattern matches the whitespace collapsed outside preformatted text
+var spacePattern = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+// Convert converts an HTML page to markdown, resolving the URLs of its links
+// and images against baseURL
+func Convert(r io.Reader, baseURL string) (*Page, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	base, err := neturl.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	c := &converter{base: base, seenImages: make(map[string]bool)}
+	if title := find(doc, atom.Title); title != nil {
+		c.page.Title = strings.TrimSpace(spacePattern.ReplaceAllString(text(title), " "))
+	}
+	if b := find(doc, atom.Base); b != nil {
+		if href := attr(b, "href"); href != "" {
+			c.base = c.resolve(href)
+		}
+	}
+
+	body := find(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	c.children(body)
+
+	lines := strings.Split(c.out.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	c.page.Markdown = strings.TrimSpace(strings.Join(lines, "\n"))
+	return &c.page, nil
+}
+
+// converter renders the nodes of a document as markdown
+type converter struct {
+	base       *neturl.URL
+	out        strings.Builder
+	page       Page
+	seenImages map[string]bool
+	prefix     string // prefix of the lines of the current quote or list item
+	pre        bool   // inside preformatted text
+	lists      int    // depth of the current list
+	separate   bool   // a blank line goes before what is written next
+	separator  string // that blank line
+}
+
+// children renders the children of n
+func (c *converter) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.node(child)
+	}
+}
+
+// node renders n and its children
+func (c *converter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		c.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		c.children(n)
+		return
+	}
+	if skipped[n.DataAtom] || hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		c.block()
+		c.write(strings.Repeat("#", level) + " " + c.inline(n))
+		c.block()
+	case atom.Br:
+		c.newline()
+	case atom.Hr:
+		c.block()
+		c.write("---")
+		c.block()
+	case atom.Strong, atom.B:
+		c.wrap(n, "**")
+	case atom.Em, atom.I:
+		c.wrap(n, "_")
+	case atom.Del, atom.S:
+		c.wrap(n, "~~")
+	case atom.Code:
+		if c.pre {
+			c.children(n)
+		} else {
+			c.write("`" + strings.TrimSpace(text(n)) + "`")
+		}
+	case atom.Pre:
+		c.preformatted(n)
+	case atom.A:
+		c.link(n)
+	case atom.Img:
+		c.image(n)
+	case atom.Ul, atom.Ol:
+		c.list(n)
+	case atom.Blockquote:
+		c.quote(n)
+	case atom.Table:
+		c.table(n)
+	default:
+		if blocks[n.DataAtom] {
+			c.block()
+			c.children(n)
+			c.block()
+		} else {
+			c.children(n)
+		}
+	}
+}
+
+// text writes the text of a text node, collapsing whitespace outside
+// preformatted text
+func (c *converter) text(data string) {
+	if c.pre {
+		c.write(data)
+		return
+	}
+	data = spacePattern.ReplaceAllString(data, " ")
+	if c.atLineStart() || strings.HasSuffix(c.out.String(), " ") {
+		data = strings.TrimLeft(data, " ")
+	}
+	c.write(data)
+}
+
+// write writes s, starting its lines with the current prefix
+func (c *converter) write(s string) {
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			c.newline()
+		}
+		if line == "" {
+			continue
+		}
+		if c.atLineStart() {
+			if c.separate {
+				c.out.WriteString(c.separator)
+				c.separate = false
+			}
+			c.out.WriteString(c.prefix)
+		}
+		c.out.WriteString(line)
+	}
+}
+
+// atLineStart reports whether the output is at the start of a line
+func (c *converter) atLineStart() bool {
+	out := c.out.String()
+	return out == "" || strings.HasSuffix(out, "\n")
+}
+
+// newline ends the current line
+func (c *converter) newline() {
+	c.out.WriteString("\n")
+}
+
+// block ends the current line and separates what is written next from it
+// with a blank line. Of the blocks ending or starting there, the outermost
+// prefixes the blank line, so that it is inside a quote only when the lines
+// around it are.
+func (c *converter) block() {
+	if c.out.Len() == 0 {
+		return
+	}
+	if !c.atLineStart() {
+		c.newline()
+	}
+	blank := strings.TrimRight(c.prefix, " ") + "\n"
+	if !c.separate || len(blank) < len(c.separator) {
+		c.separator = blank
+	}
+	c.separate = true
+}
+
+// inline returns the markdown of the children of n on a single line
+func (c *converter) inline(n *html.Node) string {
+	sub := &converter{base: c.base, seenImages: c.seenImages}
+	sub.children(n)
+	c.page.Images = append(c.page.Images, sub.page.Images...)
+	return strings.TrimSpace(spacePattern.ReplaceAllString(sub.out.String(), " "))
+}
+
+// wrap writes the children of n between two markers
+func (c *converter) wrap(n *html.Node, marker string) {
+	if content := c.inline(n); content != "" {
+		c.write(marker + content + marker)
+	}
+}
+
+// link writes a link to its absolute URL, or just its text when it has no
+// usable target
+func (c *converter) link(n *html.Node) {
+	content := c.inline(n)
+	href := strings.TrimSpace(attr(n, "href"))
+	if href == "" || strings.HasPrefix(href, "javascript:") {
+		c.write(content)
+		return
+	}
+	target := c.resolve(href)
+	if content == "" {
+		content = target.String()
+	}
+	c.write("[" + content + "](" + target.String() + ")")
+}
+
+// image writes an image and records its URL
+func (c *converter) image(n *html.Node) {
+	src := strings.TrimSpace(attr(n, "src"))
+	if src == "" || strings.HasPrefix(src, "data:") {
+		return
+	}
+	target := c.resolve(src).String()
+	if !c.seenImages[target] {
+		c.seenImages[target] = true
+		c.page.Images = append(c.page.Images, target)
+	}
+	alt := strings.TrimSpace(spacePattern.ReplaceAllString(attr(n, "alt"), " "))
+	c.write("![" + alt + "](" + target + ")")
+}
+
+// preformatted writes a fenced code block, in the language of a
+// language-xxx class when there is one
+func (c *converter) preformatted(n *html.Node) {
+	language := ""
+	for _, node := range []*html.Node{n, find(n, atom.Code)} {
+		if node == nil {
+			continue
+		}
+		for _, class := range strings.Fields(attr(node, "class")) {
+			if lang, ok := strings.CutPrefix(class, "language-"); ok {
+				language = lang
+			}
+		}
+	}
+
+	c.block()
+	c.write("```" + language + "\n")
+	c.pre = true
+	c.children(n)
+	c.pre = false
+	if !c.atLineStart() {
+		c.newline()
+	}
+	c.write("```")
+	c.block()
+}
+
+// list writes the items of a list, numbered for ordered lists, with their
+// nested blocks indented
+func (c *converter) list(n *html.Node) {
+	if c.lists == 0 {
+		c.block()
+	}
+	c.lists++
+	number := 1
+	for item := n.FirstChild; item != nil; item = item.NextSibling {
+		if item.Type != html.ElementNode || item.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		if !c.atLineStart() {
+			c.newline()
+		}
+		c.write(marker)
+		prefix := c.prefix
+		c.prefix += strings.Repeat(" ", len(marker))
+		c.listItem(item)
+		c.prefix = prefix
+	}
+	c.lists--
+	if c.lists == 0 {
+		c.block()
+	}
+}
+
+// listItem writes the content of a list item, keeping its first paragraph on
+// the line of the marker
+func (c *converter) listItem(item *html.Node) {
+	for child := item.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && (child.DataAtom == atom.Ul || child.DataAtom == atom.Ol) {
+			if !c.atLineStart() {
+				c.newline()
+			}
+			c.node(child)
+			continue
+		}
+		if child.Type == html.ElementNode && child.DataAtom == atom.P {
+			c.children(child)
+			continue
+		}
+		c.node(child)
+	}
+	if !c.atLineStart() {
+		c.newline()
+	}
+}
+
+// quote writes a blockquote, its lines prefixed with >
+func (c *converter) quote(n *html.Node) {
+	c.block()
+	prefix := c.prefix
+	c.prefix += "> "
+	c.children(n)
+	c.prefix = prefix
+	c.block()
+}
+
+// table writes a table as a GFM table, its first row as the header
+func (c *converter) table(n *html.Node) {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if child.DataAtom != atom.Tr {
+				walk(child)
+				continue
+			}
+			var row []string
+			for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+					row = append(row, strings.ReplaceAll(c.inline(cell), "|", `\|`))
+				}
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	c.block()
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		c.write("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			c.write("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	c.block()
+}
+
+// resolve returns the absolute URL of a reference
+func (c *converter) resolve(ref string) *neturl.URL {
+	u, err := neturl.Parse(ref)
+	if err != nil {
+		return &neturl.URL{Path: ref}
+	}
+	return c.base.ResolveReference(u)
+}
+
+// find returns the first element of type a below n, n included, or nil
+func find(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := find(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// attr returns the value of an attribute of n, or an empty string
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether n has an attribute, whatever its value
+func hasAttr(n *html.Node, name string) bool {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// text returns the text content of n
+func text(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			b.WriteString(node.Data)
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return b.String()
+}
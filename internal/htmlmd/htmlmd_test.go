@@ -0,0 +1,126 @@
+package htmlmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "headings",
+			html: "<h1>Title</h1><h2>Section  <em>one</em></h2><h6>Deep</h6>",
+			want: "# Title\n\n## Section _one_\n\n###### Deep",
+		},
+		{
+			name: "paragraphs and whitespace",
+			html: "<p>First\n   paragraph.</p><p>Second<br>line.</p>",
+			want: "First paragraph.\n\nSecond\nline.",
+		},
+		{
+			name: "emphasis",
+			html: "<p><strong>bold</strong>, <b>bold</b>, <em>em</em>, <i>it</i> and <del>gone</del></p>",
+			want: "**bold**, **bold**, _em_, _it_ and ~~gone~~",
+		},
+		{
+			name: "unordered list",
+			html: "<ul><li>One</li><li>Two</li></ul>",
+			want: "- One\n- Two",
+		},
+		{
+			name: "ordered list",
+			html: "<ol><li>First</li><li><p>Second</p></li><li>Third</li></ol>",
+			want: "1. First\n2. Second\n3. Third",
+		},
+		{
+			name: "nested list",
+			html: "<ul><li>Parent<ul><li>Child</li></ul></li><li>Sibling</li></ul>",
+			want: "- Parent\n  - Child\n- Sibling",
+		},
+		{
+			name: "inline code",
+			html: "<p>Run <code> go test </code> now.</p>",
+			want: "Run `go test` now.",
+		},
+		{
+			name: "code block",
+			html: "<pre><code class=\"language-go\">func main() {\n\tfmt.Println(\"hi\")\n}\n</code></pre>",
+			want: "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```",
+		},
+		{
+			name: "code block without language",
+			html: "<p>Before</p><pre>a  b\n c</pre><p>After</p>",
+			want: "Before\n\n```\na  b\n c\n```\n\nAfter",
+		},
+		{
+			name: "links",
+			html: `<p><a href="/install">Install</a>, <a href="guide#setup">setup</a>, <a href="https://other.example.com/">other</a></p>`,
+			want: "[Install](https://docs.example.com/install), [setup](https://docs.example.com/docs/guide#setup), [other](https://other.example.com/)",
+		},
+		{
+			name: "links without target",
+			html: `<p><a>plain</a> <a href="javascript:void(0)">script</a> <a href="/empty"></a></p>`,
+			want: "plain script [https://docs.example.com/empty](https://docs.example.com/empty)",
+		},
+		{
+			name: "images",
+			html: `<p><img src="img/logo.png" alt="The  logo"> <img src="data:image/png;base64,AAAA"></p>`,
+			want: "![The logo](https://docs.example.com/docs/img/logo.png)",
+		},
+		{
+			name: "table",
+			html: "<table><thead><tr><th>Name</th><th>Value</th></tr></thead>" +
+				"<tbody><tr><td>a|b</td><td><code>1</code></td></tr><tr><td>short</td></tr></tbody></table>",
+			want: "| Name | Value |\n| --- | --- |\n| a\\|b | `1` |\n| short |  |",
+		},
+		{
+			name: "blockquote",
+			html: "<blockquote><p>Quoted.</p><p>Again.</p></blockquote><p>After</p>",
+			want: "> Quoted.\n>\n> Again.\n\nAfter",
+		},
+		{
+			name: "horizontal rule",
+			html: "<p>Above</p><hr><p>Below</p>",
+			want: "Above\n\n---\n\nBelow",
+		},
+		{
+			name: "skipped elements",
+			html: `<nav>Menu</nav><script>alert(1)</script><style>p{}</style><form><input></form>` +
+				`<div hidden>Hidden</div><p aria-hidden="true">Aria</p><p>Kept</p>`,
+			want: "Menu\n\nKept",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := Convert(strings.NewReader("<html><body>"+tt.html+"</body></html>"), "https://docs.example.com/docs/")
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+			if page.Markdown != tt.want {
+				t.Errorf("Convert() =\n%s\nwant:\n%s", page.Markdown, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertPage(t *testing.T) {
+	html := `<html><head><title> The
+		Guide </title><base href="https://cdn.example.com/assets/"></head>
+		<body><h1>Guide</h1><img src="a.png"><p><img src="a.png" alt="again"><img src="/b.png"></p></body></html>`
+	page, err := Convert(strings.NewReader(html), "https://docs.example.com/guide")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "The Guide" {
+		t.Errorf("Title = %q, want %q", page.Title, "The Guide")
+	}
+	wantImages := []string{"https://cdn.example.com/assets/a.png", "https://cdn.example.com/b.png"}
+	if !slices.Equal(page.Images, wantImages) {
+		t.Errorf("Images = %v, want %v", page.Images, wantImages)
+	}
+}
@@ -0,0 +1,173 @@
+package i18n
+
+// english is the reference catalog; every message ID must be defined here
+var english = map[string]string{
+	// Fatal errors
+	"error.fatal": "Whoops. There was an error while executing your CLI '%s'",
+
+	// crawlr agent submit
+	"agent.submitted": "submitted job %s\n",
+	"agent.completed": "job %s completed on %s in %v\n",
+
+	// crawlr gc
+	"gc.removed":      "Removed %d of %d media objects (%s freed).\n",
+	"gc.would_remove": "Would remove %d of %d media objects (%s).\n",
+
+	// crawlr status
+	"status.library":   "Library %s (%s)\n",
+	"status.content":   "%d pages and %d media files, updated %s.\n",
+	"status.empty":     "No page crawled yet.\n",
+	"status.last_run":  "Last run finished %s: %d pages saved, %d failed.\n",
+	"status.cancelled": "The last crawl was cancelled on %s with %d URLs pending; run crawlr resume to finish it.\n",
+
+	// crawlr verify
+	"verify.ok":       "All %d files match the manifest.\n",
+	"verify.failed":   "%d of %d files do not match the manifest (%d missing, %d modified).\n",
+	"verify.missing":  "missing",
+	"verify.modified": "modified",
+
+	// crawlr clean
+	"clean.removed":      "Removed %d files.\n",
+	"clean.would_remove": "Would remove %d files.\n",
+
+	// crawlr diff
+	"diff.title":   "Changes in %s",
+	"diff.summary": "%d added, %d removed, %d changed, %d unchanged\n",
+	"diff.added":   "Added",
+	"diff.removed": "Removed",
+	"diff.changed": "Changed",
+
+	// crawlr sync
+	"sync.synced":  "Uploaded %d files (%s), deleted %d, %d unchanged.\n",
+	"sync.dry_run": "Would upload %d files (%s) and delete %d, %d unchanged.\n",
+
+	// crawlr snapshot
+	"snapshot.restored":    "Restored snapshot %s to %s.\n",
+	"snapshot.pruned":      "Removed %d snapshots and %d unreferenced chunks (%s freed).\n",
+	"snapshot.would_prune": "Would remove %d snapshots and %d unreferenced chunks (%s).\n",
+
+	// crawlr export
+	"export.exported": "Exported %d pages and %d media objects to %s (%s).\n",
+
+	// crawlr ping
+	"ping.server":          "Server   %s\n",
+	"ping.endpoint":        "GET %-6s HTTP %d in %v\n",
+	"ping.endpoint.failed": "GET %-6s failed: %s\n",
+	"ping.version":         "Version  %s (status %q)\n",
+	"ping.auth.none":       "Auth     no token sent, none required\n",
+	"ping.auth.accepted":   "Auth     token accepted\n",
+	"ping.auth.required":   "Auth     the server requires a token (HTTP %d)\n",
+	"ping.auth.rejected":   "Auth     the server rejected the token (HTTP %d)\n",
+	"ping.auth.unknown":    "Auth     unknown, the server did not answer\n",
+
+	// crawlr doctor
+	"doctor.passed":            "All checks passed.",
+	"doctor.hint":              "hint",
+	"doctor.config.hint":       "fix config/config.yaml or the CRAWLR_* environment variables",
+	"doctor.config.loaded":     "configuration loaded",
+	"doctor.config.server_url": "invalid server_url %q",
+	"doctor.config.server_url.hint": "set --server-url or CRAWLR_SERVER_URL to the crawl4ai base URL, " +
+		"e.g. http://localhost:11235",
+	"doctor.config.auth":      "invalid auth section: %s",
+	"doctor.config.auth.hint": "each auth entry needs a type (basic, bearer or api_key) and its credentials",
+	"doctor.server.ok":        "%s is reachable (status %q, version %s, %v)",
+	"doctor.server.unknown":   "unknown",
+	"doctor.server.local":     "not used by the local engine",
+	"doctor.server.rejected":  "%s rejected the request (HTTP %d)",
+	"doctor.server.auth.hint": "the server requires authentication; check its API token",
+	"doctor.server.no_health": "%s has no /health endpoint",
+	"doctor.server.no_health.hint": "check that server_url points at a crawl4ai server; " +
+		"the test crawl below is authoritative",
+	"doctor.server.timeout":      "%s did not answer within %ds",
+	"doctor.server.timeout.hint": "check that the server is running and reachable from this host, or raise --timeout",
+	"doctor.server.unreachable":  "%s is unreachable: %v",
+	"doctor.server.unreachable.hint": "check that crawl4ai is running and that --server-url has the right " +
+		"host and port",
+	"doctor.crawl.ok":            "crawled %s (%d bytes of markdown, %v)",
+	"doctor.crawl.empty":         "crawled %s but got no markdown",
+	"doctor.crawl.empty.hint":    "the page may need JavaScript or be empty; try another --test-url",
+	"doctor.crawl.failed":        "the server could not crawl %s",
+	"doctor.crawl.failed.hint":   "check that the crawl4ai server can reach the internet (or pass a reachable --test-url)",
+	"doctor.crawl.rejected":      "the server rejected the crawl request (HTTP %d)",
+	"doctor.crawl.timeout":       "test crawl of %s did not finish within %ds",
+	"doctor.crawl.timeout.hint":  "the server may be overloaded or the page slow; raise --timeout",
+	"doctor.crawl.error":         "test crawl of %s failed: %v",
+	"doctor.crawl.error.hint":    "check the crawl4ai server logs",
+	"doctor.output.none":         "no output directory configured",
+	"doctor.output.mkdir":        "cannot create %s: %v",
+	"doctor.output.not_writable": "%s is not writable: %v",
+	"doctor.output.hint":         "choose another --output or fix the directory permissions",
+	"doctor.output.ok":           "%s is writable",
+	"doctor.disk.unknown":        "cannot determine free space: %v",
+	"doctor.disk.free":           "%s free on %s",
+	"doctor.disk.low.hint":       "free some disk space or point --output at a larger volume",
+	"doctor.problems":            "doctor found %d problem(s)",
+
+	// Configuration file
+	"config.init.done": "Wrote %s",
+	"config.set.done":  "Set %s to %s in %s",
+
+	// Configuration validation
+	"config.validate.valid":          "all values are valid",
+	"config.validate.output.created": "%s does not exist yet and can be created in %s",
+	"config.validate.server.skipped": "not checked (--offline)",
+	"config.validate.passed":         "Configuration is valid.",
+	"config.validate.problems":       "configuration has %d problem(s)",
+
+	// Report templates
+	"report.title":            "Crawl report",
+	"report.library":          "Library",
+	"report.url":              "Start URL",
+	"report.started":          "Started",
+	"report.duration":         "Duration",
+	"report.pages_crawled":    "Pages crawled",
+	"report.pages_saved":      "Pages saved",
+	"report.pages_failed":     "Pages failed",
+	"report.media_saved":      "Media files saved",
+	"report.media_skipped":    "Media files skipped",
+	"report.status_codes":     "Status codes",
+	"report.server_time":      "Server processing time",
+	"report.bytes_written":    "Data written",
+	"report.throughput":       "Throughput",
+	"report.throughput_rates": "%.2f pages/s, %s/s",
+	"report.cancelled":        "Crawl cancelled before completion, see checkpoint.json",
+	"report.duration_limit":   "Crawl stopped once the maximum duration elapsed",
+	"report.failed_fast":      "Crawl aborted on the first storage or API error, see checkpoint.json",
+	"report.budget":           "Budget",
+	"report.budget_tradeoff":  "%s policy: pages %s, media %s (%.0f%% media), %d media files skipped",
+	"report.circuit":          "Circuit breaker",
+	"report.circuit_opened":   "%s opened %d times, %d failures",
+	"report.pages":            "Pages",
+	"report.errors":           "Errors",
+	"report.no_errors":        "No errors.",
+	"report.errors_total":     "%d, see %s",
+	"report.error_rate":       "Error rate",
+	"report.error_group":      "%s on %s: %d",
+	"report.title_column":     "Title",
+	"report.status":           "Status",
+	"report.depth":            "Depth",
+	"report.size":             "Size",
+	"report.media":            "Media",
+
+	// crawlr init
+	"init.welcome":        "This wizard creates a crawl profile. Press Enter to keep the value in brackets.",
+	"init.url":            "URL to crawl",
+	"init.library":        "Library name",
+	"init.server_url":     "crawl4ai server URL",
+	"init.output":         "Output directory",
+	"init.max_depth":      "Maximum crawl depth",
+	"init.invalid_url":    "  please enter an http or https URL",
+	"init.invalid_number": "  please enter a number of 0 or more",
+	"init.crawling":       "Crawling %s to preview its markdown...",
+	"init.preview":        "Preview (%d bytes of markdown):",
+	"init.preview.more":   "... %d more lines",
+	"init.crawl.empty":    "The page produced no markdown; it may need JavaScript.",
+	"init.crawl.failed":   "The test crawl failed: %v",
+	"init.save_anyway":    "Save the profile anyway?",
+	"init.preset":         "This looks like %s. Apply the %s preset for cleaner markdown?",
+	"init.write":          "Write the profile to %s?",
+	"init.done":           "Profile written to %s. Run crawlr to start crawling, or crawlr doctor to check the setup.",
+	"init.aborted":        "No profile written.",
+	"init.yes_no":         "[Y/n]",
+	"init.no_yes":         "[y/N]",
+}
@@ -0,0 +1,172 @@
+package i18n
+
+var french = map[string]string{
+	// Fatal errors
+	"error.fatal": "Oups. Une erreur s'est produite lors de l'exécution de la commande : '%s'",
+
+	// crawlr agent submit
+	"agent.submitted": "tâche %s soumise\n",
+	"agent.completed": "tâche %s terminée sur %s en %v\n",
+
+	// crawlr gc
+	"gc.removed":      "%d objets média sur %d supprimés (%s libérés).\n",
+	"gc.would_remove": "%d objets média sur %d seraient supprimés (%s).\n",
+
+	// crawlr status
+	"status.library":   "Bibliothèque %s (%s)\n",
+	"status.content":   "%d pages et %d fichiers média, mise à jour le %s.\n",
+	"status.empty":     "Aucune page explorée pour l'instant.\n",
+	"status.last_run":  "Dernière exécution terminée le %s : %d pages enregistrées, %d en échec.\n",
+	"status.cancelled": "Le dernier crawl a été annulé le %s avec %d URL en attente ; lancez crawlr resume pour le terminer.\n",
+
+	// crawlr verify
+	"verify.ok":       "Les %d fichiers correspondent au manifeste.\n",
+	"verify.failed":   "%d fichiers sur %d ne correspondent pas au manifeste (%d manquants, %d modifiés).\n",
+	"verify.missing":  "manquant",
+	"verify.modified": "modifié",
+
+	// crawlr clean
+	"clean.removed":      "%d fichiers supprimés.\n",
+	"clean.would_remove": "%d fichiers seraient supprimés.\n",
+
+	// crawlr diff
+	"diff.title":   "Modifications de %s",
+	"diff.summary": "%d ajoutées, %d supprimées, %d modifiées, %d inchangées\n",
+	"diff.added":   "Ajoutées",
+	"diff.removed": "Supprimées",
+	"diff.changed": "Modifiées",
+
+	// crawlr sync
+	"sync.synced":  "%d fichiers envoyés (%s), %d supprimés, %d inchangés.\n",
+	"sync.dry_run": "%d fichiers seraient envoyés (%s) et %d supprimés, %d inchangés.\n",
+
+	// crawlr snapshot
+	"snapshot.restored":    "Instantané %s restauré dans %s.\n",
+	"snapshot.pruned":      "%d instantanés et %d fragments non référencés supprimés (%s libérés).\n",
+	"snapshot.would_prune": "%d instantanés et %d fragments non référencés seraient supprimés (%s).\n",
+
+	// crawlr export
+	"export.exported": "%d pages et %d objets média exportés vers %s (%s).\n",
+
+	// crawlr ping
+	"ping.server":          "Serveur  %s\n",
+	"ping.endpoint":        "GET %-6s HTTP %d en %v\n",
+	"ping.endpoint.failed": "GET %-6s échec : %s\n",
+	"ping.version":         "Version  %s (statut %q)\n",
+	"ping.auth.none":       "Auth     aucun jeton envoyé, aucun requis\n",
+	"ping.auth.accepted":   "Auth     jeton accepté\n",
+	"ping.auth.required":   "Auth     le serveur exige un jeton (HTTP %d)\n",
+	"ping.auth.rejected":   "Auth     le serveur a refusé le jeton (HTTP %d)\n",
+	"ping.auth.unknown":    "Auth     inconnu, le serveur n'a pas répondu\n",
+
+	// crawlr doctor
+	"doctor.passed":            "Toutes les vérifications ont réussi.",
+	"doctor.hint":              "conseil",
+	"doctor.config.hint":       "corrigez config/config.yaml ou les variables d'environnement CRAWLR_*",
+	"doctor.config.loaded":     "configuration chargée",
+	"doctor.config.server_url": "server_url invalide %q",
+	"doctor.config.server_url.hint": "définissez --server-url ou CRAWLR_SERVER_URL avec l'URL de base de crawl4ai, " +
+		"par ex. http://localhost:11235",
+	"doctor.config.auth":      "section auth invalide : %s",
+	"doctor.config.auth.hint": "chaque entrée auth doit avoir un type (basic, bearer ou api_key) et ses identifiants",
+	"doctor.server.ok":        "%s est joignable (statut %q, version %s, %v)",
+	"doctor.server.unknown":   "inconnue",
+	"doctor.server.local":     "inutilisé par le moteur local",
+	"doctor.server.rejected":  "%s a refusé la requête (HTTP %d)",
+	"doctor.server.auth.hint": "le serveur exige une authentification ; vérifiez son jeton d'API",
+	"doctor.server.no_health": "%s n'a pas de point d'accès /health",
+	"doctor.server.no_health.hint": "vérifiez que server_url désigne un serveur crawl4ai ; " +
+		"le crawl de test ci-dessous fait foi",
+	"doctor.server.timeout":      "%s n'a pas répondu en %d s",
+	"doctor.server.timeout.hint": "vérifiez que le serveur est démarré et joignable depuis cette machine, ou augmentez --timeout",
+	"doctor.server.unreachable":  "%s est injoignable : %v",
+	"doctor.server.unreachable.hint": "vérifiez que crawl4ai est démarré et que --server-url indique le bon " +
+		"hôte et le bon port",
+	"doctor.crawl.ok":            "%s crawlé (%d octets de markdown, %v)",
+	"doctor.crawl.empty":         "%s crawlé mais aucun markdown obtenu",
+	"doctor.crawl.empty.hint":    "la page nécessite peut-être JavaScript ou est vide ; essayez une autre --test-url",
+	"doctor.crawl.failed":        "le serveur n'a pas pu crawler %s",
+	"doctor.crawl.failed.hint":   "vérifiez que le serveur crawl4ai accède à Internet (ou passez une --test-url joignable)",
+	"doctor.crawl.rejected":      "le serveur a refusé la requête de crawl (HTTP %d)",
+	"doctor.crawl.timeout":       "le crawl de test de %s ne s'est pas terminé en %d s",
+	"doctor.crawl.timeout.hint":  "le serveur est peut-être surchargé ou la page lente ; augmentez --timeout",
+	"doctor.crawl.error":         "le crawl de test de %s a échoué : %v",
+	"doctor.crawl.error.hint":    "consultez les journaux du serveur crawl4ai",
+	"doctor.output.none":         "aucun répertoire de sortie configuré",
+	"doctor.output.mkdir":        "impossible de créer %s : %v",
+	"doctor.output.not_writable": "%s n'est pas accessible en écriture : %v",
+	"doctor.output.hint":         "choisissez une autre --output ou corrigez les droits du répertoire",
+	"doctor.output.ok":           "%s est accessible en écriture",
+	"doctor.disk.unknown":        "impossible de déterminer l'espace libre : %v",
+	"doctor.disk.free":           "%s libres sur %s",
+	"doctor.disk.low.hint":       "libérez de l'espace disque ou dirigez --output vers un volume plus grand",
+	"doctor.problems":            "doctor a trouvé %d problème(s)",
+
+	// Configuration file
+	"config.init.done": "%s écrit",
+	"config.set.done":  "%s défini à %s dans %s",
+
+	// Configuration validation
+	"config.validate.valid":          "toutes les valeurs sont valides",
+	"config.validate.output.created": "%s n'existe pas encore et peut être créé dans %s",
+	"config.validate.server.skipped": "non vérifié (--offline)",
+	"config.validate.passed":         "La configuration est valide.",
+	"config.validate.problems":       "la configuration a %d problème(s)",
+
+	// Report templates
+	"report.title":            "Rapport de crawl",
+	"report.library":          "Bibliothèque",
+	"report.url":              "URL de départ",
+	"report.started":          "Début",
+	"report.duration":         "Durée",
+	"report.pages_crawled":    "Pages crawlées",
+	"report.pages_saved":      "Pages enregistrées",
+	"report.pages_failed":     "Pages en échec",
+	"report.media_saved":      "Médias enregistrés",
+	"report.media_skipped":    "Médias ignorés",
+	"report.status_codes":     "Codes de statut",
+	"report.server_time":      "Temps de traitement serveur",
+	"report.bytes_written":    "Données écrites",
+	"report.throughput":       "Débit",
+	"report.throughput_rates": "%.2f pages/s, %s/s",
+	"report.cancelled":        "Crawl annulé avant la fin, voir checkpoint.json",
+	"report.duration_limit":   "Crawl arrêté une fois la durée maximale écoulée",
+	"report.failed_fast":      "Crawl interrompu à la première erreur de stockage ou d'API, voir checkpoint.json",
+	"report.budget":           "Budget",
+	"report.budget_tradeoff":  "politique %s : pages %s, médias %s (%.0f%% de médias), %d médias ignorés",
+	"report.circuit":          "Disjoncteur",
+	"report.circuit_opened":   "%s ouvert %d fois, %d échecs",
+	"report.pages":            "Pages",
+	"report.errors":           "Erreurs",
+	"report.no_errors":        "Aucune erreur.",
+	"report.errors_total":     "%d, voir %s",
+	"report.error_rate":       "Taux d'erreur",
+	"report.error_group":      "%s sur %s : %d",
+	"report.title_column":     "Titre",
+	"report.status":           "Statut",
+	"report.depth":            "Profondeur",
+	"report.size":             "Taille",
+	"report.media":            "Médias",
+
+	// crawlr init
+	"init.welcome":        "Cet assistant crée un profil de crawl. Appuyez sur Entrée pour garder la valeur entre crochets.",
+	"init.url":            "URL à crawler",
+	"init.library":        "Nom de la bibliothèque",
+	"init.server_url":     "URL du serveur crawl4ai",
+	"init.output":         "Répertoire de sortie",
+	"init.max_depth":      "Profondeur maximale du crawl",
+	"init.invalid_url":    "  saisissez une URL http ou https",
+	"init.invalid_number": "  saisissez un nombre positif ou nul",
+	"init.crawling":       "Crawl de %s pour prévisualiser son markdown...",
+	"init.preview":        "Aperçu (%d octets de markdown) :",
+	"init.preview.more":   "... %d lignes de plus",
+	"init.crawl.empty":    "La page n'a produit aucun markdown ; elle nécessite peut-être JavaScript.",
+	"init.crawl.failed":   "Le crawl de test a échoué : %v",
+	"init.save_anyway":    "Enregistrer le profil quand même ?",
+	"init.preset":         "Ce site ressemble à %s. Appliquer le preset %s pour un markdown plus propre ?",
+	"init.write":          "Écrire le profil dans %s ?",
+	"init.done":           "Profil écrit dans %s. Lancez crawlr pour démarrer le crawl, ou crawlr doctor pour vérifier l'installation.",
+	"init.aborted":        "Aucun profil écrit.",
+	"init.yes_no":         "[O/n]",
+	"init.no_yes":         "[o/N]",
+}
@@ -0,0 +1,83 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// DefaultLanguage is used for messages missing from the selected catalog
+const DefaultLanguage = "en"
+
+// catalogs maps each supported language to its messages, keyed by message ID.
+// Messages are fmt format strings.
+var catalogs = map[string]map[string]string{
+	"en": english,
+	"fr": french,
+}
+
+var (
+	mutex    sync.RWMutex
+	language = DefaultLanguage
+)
+
+// Languages returns the supported language codes in sorted order
+func Languages() []string {
+	languages := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Supported reports whether lang has a message catalog
+func Supported(lang string) bool {
+	_, ok := catalogs[strings.ToLower(lang)]
+	return ok
+}
+
+// SetLanguage selects the language of subsequent messages
+func SetLanguage(lang string) error {
+	lang = strings.ToLower(lang)
+	if _, ok := catalogs[lang]; !ok {
+		return fmt.Errorf("unsupported language %q (supported: %s)", lang, strings.Join(Languages(), ", "))
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	language = lang
+	return nil
+}
+
+// Language returns the selected language
+func Language() string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return language
+}
+
+// T returns the message with the given ID in the selected language, formatted
+// with args. Messages missing from the catalog fall back to English, then to
+// the ID itself.
+func T(id string, args ...interface{}) string {
+	message, ok := catalogs[Language()][id]
+	if !ok {
+		message, ok = catalogs[DefaultLanguage][id]
+	}
+	if !ok {
+		message = id
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// FuncMap exposes T to templates as "t", e.g. {{t "report.title"}}
+func FuncMap() template.FuncMap {
+	return template.FuncMap{"t": T}
+}
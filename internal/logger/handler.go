@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// textHandler is the slog.Handler of the text format. A message is written as
+// "[time] [LEVEL] message", or, when structured and with attributes, as
+// key=value pairs starting with timestamp, level, message and caller.
+type textHandler struct {
+	mutex       *sync.Mutex
+	w           io.Writer
+	level       slog.Leveler
+	includeTime bool
+	structured  bool
+	attrs       []slog.Attr // added with WithAttrs, keys qualified by their groups
+	group       string      // prefix of the keys of further attributes
+}
+
+func newTextHandler(w io.Writer, level slog.Leveler, includeTime, structured bool) *textHandler {
+	return &textHandler{
+		mutex:       &sync.Mutex{},
+		w:           w,
+		level:       level,
+		includeTime: includeTime,
+		structured:  structured,
+	}
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := append([]slog.Attr(nil), h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = appendAttr(attrs, h.group, a)
+		return true
+	})
+
+	var parts []string
+	if h.structured && len(attrs) > 0 {
+		parts = append(parts,
+			"timestamp="+r.Time.Format(time.RFC3339),
+			"level="+r.Level.String(),
+			"message="+r.Message,
+			"caller="+caller(r.PC),
+		)
+		for _, a := range attrs {
+			parts = append(parts, fmt.Sprintf("%s=%v", a.Key, a.Value.Any()))
+		}
+	} else {
+		if h.includeTime {
+			parts = append(parts, r.Time.Format("2006-01-02 15:04:05"))
+		}
+		parts = append(parts, "["+r.Level.String()+"]", r.Message)
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	_, err := io.WriteString(h.w, strings.Join(parts, " ")+"\n")
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		clone.attrs = appendAttr(clone.attrs, h.group, a)
+	}
+	return &clone
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group = h.group + name + "."
+	return &clone
+}
+
+// appendAttr appends a to attrs with its key prefixed by group, flattening
+// group attributes
+func appendAttr(attrs []slog.Attr, group string, a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		prefix := group
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, member := range a.Value.Group() {
+			attrs = appendAttr(attrs, prefix, member)
+		}
+		return attrs
+	}
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+	a.Key = group + a.Key
+	return append(attrs, a)
+}
+
+// newJSONHandler returns the slog.Handler of the JSON format: slog's, with
+// the time, message and source written as timestamp, message and caller
+func newJSONHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return a
+			}
+			switch a.Key {
+			case slog.TimeKey:
+				return slog.String("timestamp", a.Value.Time().Format(time.RFC3339))
+			case slog.MessageKey:
+				a.Key = "message"
+			case slog.SourceKey:
+				if source, ok := a.Value.Any().(*slog.Source); ok {
+					return slog.String("caller", fmt.Sprintf("%s:%d", source.File, source.Line))
+				}
+			}
+			return a
+		},
+	})
+}
+
+// caller returns the file and line number of the program counter of a record
+func caller(pc uintptr) string {
+	if pc == 0 {
+		return "unknown:0"
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+}
@@ -1,12 +1,19 @@
+// Package logger is the leveled, structured logger of crawlr. It is a façade
+// over a log/slog handler: NewLogger builds the handler of the configured
+// format and outputs, and NewWithHandler logs through any slog.Handler, such
+// as one provided by users of the library API.
 package logger
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"runtime"
-	"strings"
+	"sort"
 	"time"
 )
 
@@ -36,6 +43,20 @@ func (l LogLevel) String() string {
 	}
 }
 
+// slogLevel returns the slog level of a LogLevel
+func (l LogLevel) slogLevel() slog.Level {
+	switch l {
+	case DEBUG:
+		return slog.LevelDebug
+	case WARN:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
 // LogOutput represents where logs should be written
 type LogOutput int
 
@@ -45,57 +66,53 @@ const (
 	Both
 )
 
+// LogFormat represents how log messages are encoded
+type LogFormat int
+
+const (
+	// TextFormat writes a line of text per message, with key=value fields when
+	// structured
+	TextFormat LogFormat = iota
+	// JSONFormat writes a JSON object per line, for log pipelines
+	JSONFormat
+)
+
 // LoggerConfig holds configuration for the logger
 type LoggerConfig struct {
 	Level       LogLevel
 	Output      LogOutput
+	Format      LogFormat
 	FilePath    string
+	Console     io.Writer // where console output is written, os.Stdout if nil
 	IncludeTime bool
-	Structured  bool
+	Structured  bool // always true with JSONFormat
+	Details     bool // log the messages of Detail at INFO rather than DEBUG
 }
 
 // Logger represents a structured logger with configurable levels and outputs
 type Logger struct {
-	config      LoggerConfig
-	debugLogger *log.Logger
-	infoLogger  *log.Logger
-	warnLogger  *log.Logger
-	errorLogger *log.Logger
-	file        *os.File
+	handler slog.Handler
+	file    *os.File
+	details bool
 }
 
 // NewLogger creates a new Logger instance with the provided configuration
 func NewLogger(config LoggerConfig) (*Logger, error) {
-	l := &Logger{
-		config: config,
-	}
-
-	// Set up loggers for different levels
-	l.debugLogger = log.New(io.Discard, "", 0)
-	l.infoLogger = log.New(io.Discard, "", 0)
-	l.warnLogger = log.New(io.Discard, "", 0)
-	l.errorLogger = log.New(io.Discard, "", 0)
-
-	// Configure loggers based on level
-	switch config.Level {
-	case DEBUG:
-		l.debugLogger = log.New(os.Stdout, "", 0)
-		fallthrough
-	case INFO:
-		l.infoLogger = log.New(os.Stdout, "", 0)
-		fallthrough
-	case WARN:
-		l.warnLogger = log.New(os.Stdout, "", 0)
-		fallthrough
-	case ERROR:
-		l.errorLogger = log.New(os.Stdout, "", 0)
-	}
+	l := &Logger{details: config.Details}
 
 	// Configure file output if needed
+	console := config.Console
+	if console == nil {
+		console = os.Stdout
+	}
+	w := console
 	if config.Output == File || config.Output == Both {
 		if config.FilePath == "" {
 			config.FilePath = "crawlr.log"
 		}
+		if err := os.MkdirAll(filepath.Dir(config.FilePath), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %w", err)
+		}
 
 		file, err := os.OpenFile(config.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
@@ -103,39 +120,35 @@ func NewLogger(config LoggerConfig) (*Logger, error) {
 		}
 		l.file = file
 
-		// Set up file loggers
-		fileDebugLogger := log.New(file, "", 0)
-		fileInfoLogger := log.New(file, "", 0)
-		fileWarnLogger := log.New(file, "", 0)
-		fileErrorLogger := log.New(file, "", 0)
-
-		// Configure file loggers based on level
-		switch config.Level {
-		case DEBUG:
-			l.debugLogger = fileDebugLogger
-			fallthrough
-		case INFO:
-			l.infoLogger = fileInfoLogger
-			fallthrough
-		case WARN:
-			l.warnLogger = fileWarnLogger
-			fallthrough
-		case ERROR:
-			l.errorLogger = fileErrorLogger
-		}
-
-		// If output is both, create multiwriters
+		w = file
 		if config.Output == Both {
-			l.debugLogger = log.New(io.MultiWriter(os.Stdout, fileDebugLogger.Writer()), "", 0)
-			l.infoLogger = log.New(io.MultiWriter(os.Stdout, fileInfoLogger.Writer()), "", 0)
-			l.warnLogger = log.New(io.MultiWriter(os.Stdout, fileWarnLogger.Writer()), "", 0)
-			l.errorLogger = log.New(io.MultiWriter(os.Stdout, fileErrorLogger.Writer()), "", 0)
+			w = io.MultiWriter(console, file)
 		}
 	}
 
+	if config.Format == JSONFormat {
+		l.handler = newJSONHandler(w, config.Level.slogLevel())
+	} else {
+		l.handler = newTextHandler(w, config.Level.slogLevel(), config.IncludeTime, config.Structured)
+	}
 	return l, nil
 }
 
+// NewWithHandler creates a Logger writing every message through handler
+func NewWithHandler(handler slog.Handler) *Logger {
+	return &Logger{handler: handler}
+}
+
+// Handler returns the slog handler messages are written through
+func (l *Logger) Handler() slog.Handler {
+	return l.handler
+}
+
+// Slog returns a slog.Logger writing through the handler of l
+func (l *Logger) Slog() *slog.Logger {
+	return slog.New(l.handler)
+}
+
 // Close closes any open resources used by the logger
 func (l *Logger) Close() error {
 	if l.file != nil {
@@ -144,153 +157,118 @@ func (l *Logger) Close() error {
 	return nil
 }
 
-// formatMessage formats a log message with optional timestamp and level
-func (l *Logger) formatMessage(level LogLevel, message string) string {
-	var parts []string
-
-	if l.config.IncludeTime {
-		parts = append(parts, time.Now().Format("2006-01-02 15:04:05"))
+// log writes a message with its fields through the handler, reporting the
+// caller of the Logger method that called log
+func (l *Logger) log(level slog.Level, message string, fields map[string]interface{}) {
+	ctx := context.Background()
+	if !l.handler.Enabled(ctx, level) {
+		return
 	}
 
-	parts = append(parts, fmt.Sprintf("[%s]", level.String()))
-	parts = append(parts, message)
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip Callers, log and the Logger method
+	r := slog.NewRecord(time.Now(), level, message, pcs[0])
+	r.AddAttrs(fieldAttrs(fields)...)
+	_ = l.handler.Handle(ctx, r)
+}
 
-	return strings.Join(parts, " ")
+// fieldAttrs converts the fields of a message to attributes sorted by key.
+// Fields named like the timestamp, level, message and caller of the message
+// are prefixed with "fields.". Values with a String method and no JSON
+// encoding of their own, such as durations, are logged as their string.
+func fieldAttrs(fields map[string]interface{}) []slog.Attr {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		value := fields[k]
+		switch k {
+		case "timestamp", "level", "message", "caller":
+			k = "fields." + k
+		}
+		switch v := value.(type) {
+		case error, json.Marshaler:
+		case fmt.Stringer:
+			value = v.String()
+		}
+		attrs = append(attrs, slog.Any(k, value))
+	}
+	return attrs
 }
 
-// getCallerInfo returns the file and line number of the caller
-func getCallerInfo() string {
-	_, file, line, ok := runtime.Caller(2)
-	if !ok {
-		return "unknown:0"
+// firstFields returns the optional fields of a message
+func firstFields(fields []map[string]interface{}) map[string]interface{} {
+	if len(fields) > 0 {
+		return fields[0]
 	}
-	return fmt.Sprintf("%s:%d", file, line)
+	return nil
 }
 
 // Debug logs a debug message
 func (l *Logger) Debug(message string, fields ...map[string]interface{}) {
-	if l.config.Level > DEBUG {
-		return
-	}
-
-	formatted := l.formatMessage(DEBUG, message)
-	if l.config.Structured && len(fields) > 0 {
-		formatted = l.formatStructured(DEBUG, message, fields[0])
-	}
-
-	l.debugLogger.Output(2, formatted)
+	l.log(slog.LevelDebug, message, firstFields(fields))
 }
 
 // Debugf logs a formatted debug message
 func (l *Logger) Debugf(format string, args ...interface{}) {
-	if l.config.Level > DEBUG {
-		return
-	}
-
-	message := fmt.Sprintf(format, args...)
-	formatted := l.formatMessage(DEBUG, message)
-	l.debugLogger.Output(2, formatted)
+	l.log(slog.LevelDebug, fmt.Sprintf(format, args...), nil)
 }
 
 // Info logs an info message
 func (l *Logger) Info(message string, fields ...map[string]interface{}) {
-	if l.config.Level > INFO {
-		return
-	}
-
-	formatted := l.formatMessage(INFO, message)
-	if l.config.Structured && len(fields) > 0 {
-		formatted = l.formatStructured(INFO, message, fields[0])
-	}
-
-	l.infoLogger.Output(2, formatted)
+	l.log(slog.LevelInfo, message, firstFields(fields))
 }
 
 // Infof logs a formatted info message
 func (l *Logger) Infof(format string, args ...interface{}) {
-	if l.config.Level > INFO {
-		return
-	}
+	l.log(slog.LevelInfo, fmt.Sprintf(format, args...), nil)
+}
 
-	message := fmt.Sprintf(format, args...)
-	formatted := l.formatMessage(INFO, message)
-	l.infoLogger.Output(2, formatted)
+// Detail logs an info message about a single page or file. Such messages
+// would drown the progress of a crawl, so they are logged at DEBUG level
+// unless the logger is configured with Details.
+func (l *Logger) Detail(message string, fields ...map[string]interface{}) {
+	level := slog.LevelDebug
+	if l.details {
+		level = slog.LevelInfo
+	}
+	l.log(level, message, firstFields(fields))
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(message string, fields ...map[string]interface{}) {
-	if l.config.Level > WARN {
-		return
-	}
-
-	formatted := l.formatMessage(WARN, message)
-	if l.config.Structured && len(fields) > 0 {
-		formatted = l.formatStructured(WARN, message, fields[0])
-	}
-
-	l.warnLogger.Output(2, formatted)
+	l.log(slog.LevelWarn, message, firstFields(fields))
 }
 
 // Warnf logs a formatted warning message
 func (l *Logger) Warnf(format string, args ...interface{}) {
-	if l.config.Level > WARN {
-		return
-	}
-
-	message := fmt.Sprintf(format, args...)
-	formatted := l.formatMessage(WARN, message)
-	l.warnLogger.Output(2, formatted)
+	l.log(slog.LevelWarn, fmt.Sprintf(format, args...), nil)
 }
 
 // Error logs an error message
 func (l *Logger) Error(message string, fields ...map[string]interface{}) {
-	if l.config.Level > ERROR {
-		return
-	}
-
-	formatted := l.formatMessage(ERROR, message)
-	if l.config.Structured && len(fields) > 0 {
-		formatted = l.formatStructured(ERROR, message, fields[0])
-	}
-
-	l.errorLogger.Output(2, formatted)
+	l.log(slog.LevelError, message, firstFields(fields))
 }
 
 // Errorf logs a formatted error message
 func (l *Logger) Errorf(format string, args ...interface{}) {
-	if l.config.Level > ERROR {
-		return
-	}
-
-	message := fmt.Sprintf(format, args...)
-	formatted := l.formatMessage(ERROR, message)
-	l.errorLogger.Output(2, formatted)
+	l.log(slog.LevelError, fmt.Sprintf(format, args...), nil)
 }
 
 // ErrorWithStack logs an error message with stack trace
 func (l *Logger) ErrorWithStack(err error, message string, fields ...map[string]interface{}) {
-	if l.config.Level > ERROR {
-		return
+	mergedFields := map[string]interface{}{
+		"error":      err.Error(),
+		"stackTrace": getStackTrace(),
 	}
-
-	stackTrace := getStackTrace()
-	baseMessage := fmt.Sprintf("%s: %v\n%s", message, err, stackTrace)
-	formatted := l.formatMessage(ERROR, baseMessage)
-
-	if l.config.Structured {
-		mergedFields := map[string]interface{}{
-			"error":      err.Error(),
-			"stackTrace": stackTrace,
-		}
-		if len(fields) > 0 {
-			for k, v := range fields[0] {
-				mergedFields[k] = v
-			}
-		}
-		formatted = l.formatStructured(ERROR, message, mergedFields)
+	for k, v := range firstFields(fields) {
+		mergedFields[k] = v
 	}
-
-	l.errorLogger.Output(2, formatted)
+	l.log(slog.LevelError, message, mergedFields)
 }
 
 // getStackTrace returns a formatted stack trace
@@ -300,104 +278,44 @@ func getStackTrace() string {
 	return string(buf[:n])
 }
 
-// formatStructured formats a log message in structured format
-func (l *Logger) formatStructured(level LogLevel, message string, fields map[string]interface{}) string {
-	baseFields := map[string]interface{}{
-		"timestamp": time.Now().Format(time.RFC3339),
-		"level":     level.String(),
-		"message":   message,
-		"caller":    getCallerInfo(),
-	}
-
-	// Merge user fields with base fields
-	for k, v := range fields {
-		baseFields[k] = v
-	}
-
-	// Convert to JSON-like string
-	var parts []string
-	for k, v := range baseFields {
-		parts = append(parts, fmt.Sprintf("%s=%v", k, v))
-	}
-
-	return strings.Join(parts, " ")
-}
-
 // Progress logs progress information for long-running operations
 func (l *Logger) Progress(operation string, current, total int, fields ...map[string]interface{}) {
-	if l.config.Level > INFO {
-		return
-	}
-
 	percentage := 0
 	if total > 0 {
 		percentage = (current * 100) / total
 	}
 
-	message := fmt.Sprintf("Progress: %s - %d/%d (%d%%)", operation, current, total, percentage)
-	formatted := l.formatMessage(INFO, message)
-
-	if l.config.Structured {
-		progressFields := map[string]interface{}{
-			"operation":  operation,
-			"current":    current,
-			"total":      total,
-			"percentage": percentage,
-		}
-		if len(fields) > 0 {
-			for k, v := range fields[0] {
-				progressFields[k] = v
-			}
-		}
-		formatted = l.formatStructured(INFO, message, progressFields)
+	progressFields := map[string]interface{}{
+		"operation":  operation,
+		"current":    current,
+		"total":      total,
+		"percentage": percentage,
 	}
-
-	l.infoLogger.Output(2, formatted)
+	for k, v := range firstFields(fields) {
+		progressFields[k] = v
+	}
+	l.log(slog.LevelInfo, fmt.Sprintf("Progress: %s - %d/%d (%d%%)", operation, current, total, percentage), progressFields)
 }
 
 // APIRequest logs information about an API request
 func (l *Logger) APIRequest(method, url string, headers map[string]string, body interface{}) {
-	if l.config.Level > DEBUG {
-		return
-	}
-
-	message := fmt.Sprintf("API Request: %s %s", method, url)
-	formatted := l.formatMessage(DEBUG, message)
-
-	if l.config.Structured {
-		requestFields := map[string]interface{}{
-			"type":    "api_request",
-			"method":  method,
-			"url":     url,
-			"headers": headers,
-			"body":    body,
-		}
-		formatted = l.formatStructured(DEBUG, message, requestFields)
-	}
-
-	l.debugLogger.Output(2, formatted)
+	l.log(slog.LevelDebug, fmt.Sprintf("API Request: %s %s", method, url), map[string]interface{}{
+		"type":    "api_request",
+		"method":  method,
+		"url":     url,
+		"headers": headers,
+		"body":    body,
+	})
 }
 
 // APIResponse logs information about an API response
 func (l *Logger) APIResponse(method, url string, statusCode int, headers map[string]string, body interface{}) {
-	if l.config.Level > DEBUG {
-		return
-	}
-
-	message := fmt.Sprintf("API Response: %s %s - Status: %d", method, url, statusCode)
-	formatted := l.formatMessage(DEBUG, message)
-
-	if l.config.Structured {
-		responseFields := map[string]interface{}{
-			"type":       "api_response",
-			"method":     method,
-			"url":        url,
-			"statusCode": statusCode,
-			"headers":    headers,
-			"body":       body,
-		}
-		formatted = l.formatStructured(DEBUG, message, responseFields)
-	}
-
-	l.debugLogger.Output(2, formatted)
+	l.log(slog.LevelDebug, fmt.Sprintf("API Response: %s %s - Status: %d", method, url, statusCode), map[string]interface{}{
+		"type":       "api_response",
+		"method":     method,
+		"url":        url,
+		"statusCode": statusCode,
+		"headers":    headers,
+		"body":       body,
+	})
 }
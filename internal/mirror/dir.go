@@ -0,0 +1,76 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// DirTarget mirrors a library to a local directory, such as a mounted network
+// share
+type DirTarget struct {
+	root string
+}
+
+// NewDirTarget returns a target writing to the directory root, created if needed
+func NewDirTarget(root string) (*DirTarget, error) {
+	if root == "" {
+		return nil, fmt.Errorf("sync target directory is empty")
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sync target directory: %w", err)
+	}
+	return &DirTarget{root: root}, nil
+}
+
+// Put implements the Target interface. Files are replaced atomically.
+func (t *DirTarget) Put(ctx context.Context, path string, r io.Reader, size int64) error {
+	dest := filepath.Join(t.root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+// Get implements the Target interface
+func (t *DirTarget) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(t.root, filepath.FromSlash(path)))
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+	return file, err
+}
+
+// Delete implements the Target interface
+func (t *DirTarget) Delete(ctx context.Context, path string) error {
+	err := os.Remove(filepath.Join(t.root, filepath.FromSlash(path)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Close implements the Target interface
+func (t *DirTarget) Close() error {
+	return nil
+}
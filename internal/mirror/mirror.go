@@ -0,0 +1,278 @@
+// Package mirror pushes libraries to remote targets (another directory, an S3
+// bucket or an SFTP server), transferring only the files that changed since
+// the last push.
+package mirror
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"crawlr/internal/storage"
+)
+
+// StateFile is the file, at the root of a mirror, recording the files pushed
+// to it with their hashes
+const StateFile = ".crawlr-sync.json"
+
+// ErrNotExist is returned by Target.Get for missing files
+var ErrNotExist = errors.New("file does not exist")
+
+// Target is a remote location holding a copy of a library. Paths are
+// slash-separated and relative to the root of the target.
+type Target interface {
+	// Put creates or replaces a file
+	Put(ctx context.Context, path string, r io.Reader, size int64) error
+	// Get opens a file, returning ErrNotExist if it is missing
+	Get(ctx context.Context, path string) (io.ReadCloser, error)
+	// Delete removes a file; removing a missing file is not an error
+	Delete(ctx context.Context, path string) error
+	// Close releases any connection held by the target
+	Close() error
+}
+
+// Open returns the target at location: s3://bucket/prefix,
+// sftp://user@host[:port]/path, or a local directory given as a path or a
+// file:// URL
+func Open(ctx context.Context, location string) (Target, error) {
+	scheme, rest, ok := strings.Cut(location, "://")
+	if !ok {
+		return NewDirTarget(location)
+	}
+	switch scheme {
+	case "file":
+		return NewDirTarget(rest)
+	case "s3":
+		return NewS3Target(location)
+	case "sftp":
+		return NewSFTPTarget(ctx, location)
+	default:
+		return nil, fmt.Errorf("unsupported sync target %q (use a directory, s3:// or sftp://)", location)
+	}
+}
+
+// State lists the files of a mirror with their hashes
+type State struct {
+	Library  string               `json:"library"`
+	SyncedAt time.Time            `json:"synced_at"`
+	Files    map[string]FileState `json:"files"`
+}
+
+// FileState is the hash and size of a mirrored file
+type FileState struct {
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// Options configures a sync
+type Options struct {
+	Delete bool // remove files of the mirror that the library no longer has
+	DryRun bool // report what would be transferred without changing the mirror
+}
+
+// Result summarizes a sync
+type Result struct {
+	Uploaded    []string
+	Deleted     []string
+	Unchanged   int
+	Transferred int64 // bytes uploaded
+}
+
+// Sync pushes the library at libraryPath to target. Files are compared with
+// the state recorded by the previous sync: only new and changed files are
+// uploaded, the manifest last so that the mirror never lists missing files.
+// Hashes are taken from the manifest where it covers a file.
+func Sync(ctx context.Context, libraryPath string, target Target, opts Options) (*Result, error) {
+	remote, err := readState(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	local, err := libraryState(libraryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	var uploads []string
+	for path, file := range local.Files {
+		if remote.Files[path] == file {
+			result.Unchanged++
+			continue
+		}
+		uploads = append(uploads, path)
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		if (uploads[i] == storage.ManifestFile) != (uploads[j] == storage.ManifestFile) {
+			return uploads[j] == storage.ManifestFile
+		}
+		return uploads[i] < uploads[j]
+	})
+
+	for _, path := range uploads {
+		result.Uploaded = append(result.Uploaded, path)
+		result.Transferred += local.Files[path].Size
+		if opts.DryRun {
+			continue
+		}
+		if err := putFile(ctx, target, libraryPath, path, local.Files[path].Size); err != nil {
+			return result, err
+		}
+	}
+
+	if opts.Delete {
+		for path := range remote.Files {
+			if _, ok := local.Files[path]; !ok {
+				result.Deleted = append(result.Deleted, path)
+			}
+		}
+		sort.Strings(result.Deleted)
+		for _, path := range result.Deleted {
+			if opts.DryRun {
+				continue
+			}
+			if err := target.Delete(ctx, path); err != nil {
+				return result, fmt.Errorf("failed to delete %s: %w", path, err)
+			}
+		}
+	} else {
+		// Files left on the mirror stay recorded, so that a later sync with
+		// Delete removes them
+		for path, file := range remote.Files {
+			if _, ok := local.Files[path]; !ok {
+				local.Files[path] = file
+			}
+		}
+	}
+
+	if opts.DryRun || (len(result.Uploaded) == 0 && len(result.Deleted) == 0) {
+		return result, nil
+	}
+	return result, writeState(ctx, target, local)
+}
+
+// putFile uploads a file of the library
+func putFile(ctx context.Context, target Target, libraryPath string, path string, size int64) error {
+	file, err := os.Open(filepath.Join(libraryPath, filepath.FromSlash(path)))
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if err := target.Put(ctx, path, file, size); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", path, err)
+	}
+	return nil
+}
+
+// libraryState lists the files of a library with their hashes. Files of the
+// manifest whose size still matches keep their recorded hash; others are
+// hashed. Leftovers of interrupted writes are skipped.
+func libraryState(libraryPath string) (*State, error) {
+	manifest, err := storage.LoadManifest(libraryPath)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]FileState)
+	for _, entries := range [][]storage.ManifestEntry{manifest.Pages, manifest.Media} {
+		for _, entry := range entries {
+			if entry.Hash != "" {
+				known[entry.Path] = FileState{Hash: entry.Hash, Size: entry.Size}
+			}
+		}
+	}
+
+	state := &State{Library: filepath.Base(libraryPath), Files: make(map[string]FileState)}
+	err = filepath.WalkDir(libraryPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(libraryPath, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if file, ok := known[relPath]; ok && file.Size == info.Size() {
+			state.Files[relPath] = file
+			return nil
+		}
+		hash, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		state.Files[relPath] = FileState{Hash: hash, Size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read library: %w", err)
+	}
+	return state, nil
+}
+
+// hashFile returns the hex-encoded SHA-256 of a file
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// readState reads the state recorded on the target by the previous sync. A
+// target never synced yields an empty state.
+func readState(ctx context.Context, target Target) (*State, error) {
+	state := &State{Files: make(map[string]FileState)}
+	reader, err := target.Get(ctx, StateFile)
+	if errors.Is(err, ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync state: %w", err)
+	}
+	defer reader.Close()
+
+	if err := json.NewDecoder(reader).Decode(state); err != nil {
+		return nil, fmt.Errorf("failed to parse sync state: %w", err)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]FileState)
+	}
+	return state, nil
+}
+
+// writeState records the files of the mirror on the target
+func writeState(ctx context.Context, target Target, state *State) error {
+	state.SyncedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync state: %w", err)
+	}
+	data = append(data, '\n')
+	if err := target.Put(ctx, StateFile, strings.NewReader(string(data)), int64(len(data))); err != nil {
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	return nil
+}
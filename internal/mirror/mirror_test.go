@@ -0,0 +1,136 @@
+package mirror
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSyncDirTarget(t *testing.T) {
+	library := filepath.Join(t.TempDir(), "docs")
+	mirrorDir := t.TempDir()
+	target, err := NewDirTarget(mirrorDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeLibraryFile(t, library, "manifest.json", `{"library": "docs"}`)
+	writeLibraryFile(t, library, "markdown/index.md", "# Index")
+	writeLibraryFile(t, library, "markdown/guide.md", "# Guide")
+	writeLibraryFile(t, library, ".index.md.tmp-1", "partial write")
+
+	steps := []struct {
+		name      string
+		change    func()
+		opts      Options
+		uploaded  []string
+		deleted   []string
+		unchanged int
+		mirrored  []string
+	}{
+		{
+			name:     "first sync",
+			uploaded: []string{"markdown/guide.md", "markdown/index.md", "manifest.json"},
+			mirrored: []string{"manifest.json", "markdown/guide.md", "markdown/index.md"},
+		},
+		{
+			name:      "unchanged",
+			unchanged: 3,
+			mirrored:  []string{"manifest.json", "markdown/guide.md", "markdown/index.md"},
+		},
+		{
+			name: "changed and removed file",
+			change: func() {
+				writeLibraryFile(t, library, "markdown/index.md", "# Index, revised")
+				if err := os.Remove(filepath.Join(library, "markdown", "guide.md")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			uploaded:  []string{"markdown/index.md"},
+			unchanged: 1,
+			mirrored:  []string{"manifest.json", "markdown/guide.md", "markdown/index.md"},
+		},
+		{
+			name:      "dry run delete",
+			opts:      Options{Delete: true, DryRun: true},
+			deleted:   []string{"markdown/guide.md"},
+			unchanged: 2,
+			mirrored:  []string{"manifest.json", "markdown/guide.md", "markdown/index.md"},
+		},
+		{
+			name:      "delete",
+			opts:      Options{Delete: true},
+			deleted:   []string{"markdown/guide.md"},
+			unchanged: 2,
+			mirrored:  []string{"manifest.json", "markdown/index.md"},
+		},
+	}
+	for _, step := range steps {
+		if step.change != nil {
+			step.change()
+		}
+		result, err := Sync(context.Background(), library, target, step.opts)
+		if err != nil {
+			t.Fatalf("%s: Sync() error = %v", step.name, err)
+		}
+		if !slices.Equal(result.Uploaded, step.uploaded) {
+			t.Errorf("%s: uploaded %v, want %v", step.name, result.Uploaded, step.uploaded)
+		}
+		if !slices.Equal(result.Deleted, step.deleted) {
+			t.Errorf("%s: deleted %v, want %v", step.name, result.Deleted, step.deleted)
+		}
+		if result.Unchanged != step.unchanged {
+			t.Errorf("%s: %d unchanged, want %d", step.name, result.Unchanged, step.unchanged)
+		}
+		if got := mirroredFiles(t, mirrorDir); !slices.Equal(got, step.mirrored) {
+			t.Errorf("%s: mirror holds %v, want %v", step.name, got, step.mirrored)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(mirrorDir, "markdown", "index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "# Index, revised" {
+		t.Errorf("mirrored index.md = %q, want the revised page", data)
+	}
+	state, err := readState(context.Background(), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Files) != 2 {
+		t.Errorf("sync state records %d files, want 2", len(state.Files))
+	}
+}
+
+// writeLibraryFile writes a file of the library at libraryPath
+func writeLibraryFile(t *testing.T, libraryPath, path, content string) {
+	t.Helper()
+	path = filepath.Join(libraryPath, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// mirroredFiles lists the files of a mirror, sorted, except the sync state
+func mirroredFiles(t *testing.T, root string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() == StateFile {
+			return err
+		}
+		relPath, err := filepath.Rel(root, path)
+		files = append(files, filepath.ToSlash(relPath))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(files)
+	return files
+}
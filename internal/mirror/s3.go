@@ -0,0 +1,229 @@
+package mirror
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// unsignedPayload is the payload hash of requests whose body is not signed
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Target mirrors a library to an S3 bucket, or an S3-compatible store such
+// as MinIO. Credentials, region and endpoint are read from the standard AWS
+// environment variables.
+type S3Target struct {
+	client       *http.Client
+	endpoint     *neturl.URL // bucket endpoint
+	prefix       string      // key prefix, without leading or trailing slash
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// NewS3Target returns a target writing to s3://bucket/prefix. Requests are
+// signed with AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+// for AWS_REGION (us-east-1 by default). AWS_ENDPOINT_URL_S3 or
+// AWS_ENDPOINT_URL selects an S3-compatible endpoint, addressed path-style.
+func NewS3Target(location string) (*S3Target, error) {
+	u, err := neturl.Parse(location)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 target %q, expected s3://bucket/prefix", location)
+	}
+	bucket := u.Host
+
+	target := &S3Target{
+		client:       &http.Client{Timeout: 5 * time.Minute},
+		prefix:       strings.Trim(u.Path, "/"),
+		region:       firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if target.region == "" {
+		target.region = "us-east-1"
+	}
+	if target.accessKey == "" || target.secretKey == "" {
+		return nil, fmt.Errorf("S3 credentials missing: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	if endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); endpoint != "" {
+		target.endpoint, err = neturl.Parse(strings.TrimSuffix(endpoint, "/") + "/" + bucket)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+		}
+	} else {
+		target.endpoint = &neturl.URL{Scheme: "https", Host: bucket + ".s3." + target.region + ".amazonaws.com"}
+	}
+	return target, nil
+}
+
+// firstEnv returns the first non-empty environment variable of names
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Put implements the Target interface
+func (t *S3Target) Put(ctx context.Context, path string, r io.Reader, size int64) error {
+	resp, err := t.do(ctx, http.MethodPut, path, r, size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkS3Response(resp)
+}
+
+// Get implements the Target interface
+func (t *S3Target) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	resp, err := t.do(ctx, http.MethodGet, path, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotExist
+	}
+	if err := checkS3Response(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete implements the Target interface
+func (t *S3Target) Delete(ctx context.Context, path string) error {
+	resp, err := t.do(ctx, http.MethodDelete, path, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkS3Response(resp)
+}
+
+// Close implements the Target interface
+func (t *S3Target) Close() error {
+	return nil
+}
+
+// checkS3Response returns an error for unsuccessful responses, with the S3
+// error document
+func checkS3Response(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("S3 returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// do sends a request for the object at path, signed with AWS Signature
+// Version 4. Bodies are sent unsigned, which HTTPS protects in transit.
+func (t *S3Target) do(ctx context.Context, method string, path string, body io.Reader, size int64) (*http.Response, error) {
+	key := path
+	if t.prefix != "" {
+		key = t.prefix + "/" + path
+	}
+	u := *t.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	// The request line must carry the path encoded as it is signed
+	u.RawPath = s3EscapePath(u.Path)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		// An empty body of unknown type would be sent chunked, which S3
+		// rejects without a length
+		req.ContentLength = size
+		if size == 0 {
+			req.Body = http.NoBody
+		}
+	}
+	t.sign(req, time.Now().UTC())
+	return t.client.Do(req)
+}
+
+// sign adds the AWS Signature Version 4 authorization of a request
+func (t *S3Target) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	if t.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", t.sessionToken)
+	}
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if t.sessionToken != "" {
+		signedHeaders = append(signedHeaders, "x-amz-security-token")
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		unsignedPayload,
+	}, "\n")
+
+	scope := date + "/" + t.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := []byte("AWS4" + t.secretKey)
+	for _, part := range []string{date, t.region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.accessKey, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3EscapePath percent-encodes a path as S3 signatures expect: everything but
+// unreserved characters and slashes
+func s3EscapePath(path string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
@@ -0,0 +1,365 @@
+// Package mockserver replays canned crawl4ai responses so that configurations,
+// filters and storage layouts can be tried without a crawl4ai deployment.
+package mockserver
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"crawlr/internal/logger"
+	"crawlr/internal/replay"
+)
+
+// BaseURLPlaceholder is replaced by the address of the mock server in
+// fixtures, so that fixture sites link to pages and media it serves
+const BaseURLPlaceholder = "{{base}}"
+
+// Version is reported by the health endpoint
+const Version = "mock"
+
+//go:embed fixtures
+var builtinFixtures embed.FS
+
+// Server answers crawl requests with fixtures and serves the fixture pages
+// and files as the origin site
+type Server struct {
+	logger *logger.Logger
+	pages  map[string]json.RawMessage // crawl4ai results keyed by URL
+	files  fs.FS                      // static files such as media
+	replay *replay.Capture            // captured run answering every request, if any
+}
+
+// New creates a mock server replaying the fixtures of a directory, or the
+// built-in example site if dir is empty.
+//
+// Fixtures are JSON files holding a crawl4ai result object, a list of them or
+// a whole crawl response with results. Files under the files/ subdirectory are
+// served as is, e.g. files/img/logo.png at /img/logo.png.
+func New(dir string, logger *logger.Logger) (*Server, error) {
+	var fixtures fs.FS = os.DirFS(dir)
+	if dir == "" {
+		sub, err := fs.Sub(builtinFixtures, "fixtures")
+		if err != nil {
+			return nil, err
+		}
+		fixtures = sub
+	}
+
+	s := &Server{logger: logger, pages: make(map[string]json.RawMessage)}
+	if err := s.loadPages(fixtures); err != nil {
+		return nil, err
+	}
+	if len(s.pages) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s", dir)
+	}
+
+	files, err := fs.Sub(fixtures, "files")
+	if err != nil {
+		return nil, err
+	}
+	s.files = files
+	return s, nil
+}
+
+// NewReplay creates a mock server replaying a run captured with --capture.
+// Crawlers reach it through --replay-server, which sends every request, to
+// crawl4ai and origin servers alike, to the replay endpoint.
+func NewReplay(dir string, logger *logger.Logger) (*Server, error) {
+	capture, err := replay.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{logger: logger, replay: capture}, nil
+}
+
+// Exchanges returns the number of captured exchanges the server replays
+func (s *Server) Exchanges() int {
+	if s.replay == nil {
+		return 0
+	}
+	return s.replay.Exchanges()
+}
+
+// Pages returns the number of pages the server has fixtures for
+func (s *Server) Pages() int {
+	return len(s.pages)
+}
+
+// loadPages reads the crawl4ai results of every JSON file of the fixtures
+func (s *Server) loadPages(fixtures fs.FS) error {
+	return fs.WalkDir(fixtures, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(path.Ext(name), ".json") || strings.HasPrefix(name, "files/") {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fixtures, name)
+		if err != nil {
+			return err
+		}
+		results, err := parseFixture(data)
+		if err != nil {
+			return fmt.Errorf("invalid fixture %s: %w", filepath.FromSlash(name), err)
+		}
+		for _, result := range results {
+			var page struct {
+				URL string `json:"url"`
+			}
+			if err := json.Unmarshal(result, &page); err != nil || page.URL == "" {
+				return fmt.Errorf("invalid fixture %s: result without url", filepath.FromSlash(name))
+			}
+			s.pages[pageKey(page.URL)] = result
+		}
+		return nil
+	})
+}
+
+// parseFixture returns the results of a fixture file
+func parseFixture(data []byte) ([]json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var results []json.RawMessage
+		err := json.Unmarshal(data, &results)
+		return results, err
+	}
+
+	var response struct {
+		Results []json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+	if response.Results != nil {
+		return response.Results, nil
+	}
+	return []json.RawMessage{json.RawMessage(data)}, nil
+}
+
+// pageKey normalizes a URL so that trailing slashes do not matter
+func pageKey(url string) string {
+	key := strings.TrimSuffix(url, "/")
+	if key == BaseURLPlaceholder {
+		return BaseURLPlaceholder + "/"
+	}
+	return key
+}
+
+// Handler returns the HTTP handler of the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	if s.replay != nil {
+		mux.HandleFunc(replay.Path, s.handleReplay)
+		mux.HandleFunc("GET /health", s.handleHealth)
+		return s.logRequests(mux)
+	}
+	mux.HandleFunc("POST /crawl", s.handleCrawl)
+	mux.HandleFunc("POST /md", s.handleMarkdown)
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("GET /", s.handleOrigin)
+	return s.logRequests(mux)
+}
+
+func (s *Server) handleCrawl(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URLs []string `json:"urls"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"detail": "invalid request body"})
+		return
+	}
+
+	base := baseURL(r)
+	results := make([]json.RawMessage, 0, len(req.URLs))
+	for _, url := range req.URLs {
+		results = append(results, s.result(url, base))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":                  true,
+		"results":                  results,
+		"server_processing_time_s": 0,
+	})
+}
+
+// handleMarkdown answers a /md request with the raw markdown of the fixture
+// of its URL, whatever the filter
+func (s *Server) handleMarkdown(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL    string `json:"url"`
+		Filter string `json:"f"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"detail": "invalid request body"})
+		return
+	}
+
+	var page struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"error_message"`
+		Markdown     struct {
+			RawMarkdown string `json:"raw_markdown"`
+		} `json:"markdown"`
+	}
+	if err := json.Unmarshal(s.result(req.URL, baseURL(r)), &page); err != nil || !page.Success {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"detail": page.ErrorMessage})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"url":      req.URL,
+		"filter":   req.Filter,
+		"markdown": page.Markdown.RawMarkdown,
+		"success":  true,
+	})
+}
+
+// result returns the fixture of a URL with the base URL filled in, or a failed
+// result if there is none
+func (s *Server) result(url string, base string) json.RawMessage {
+	key := pageKey(url)
+	if strings.HasPrefix(url, base) {
+		key = pageKey(BaseURLPlaceholder + strings.TrimPrefix(url, base))
+	}
+	if page, ok := s.pages[key]; ok {
+		return bytes.ReplaceAll(page, []byte(BaseURLPlaceholder), []byte(base))
+	}
+	if page, ok := s.pages[pageKey(url)]; ok {
+		return page
+	}
+
+	failed, _ := json.Marshal(map[string]interface{}{
+		"url":           url,
+		"success":       false,
+		"status_code":   http.StatusNotFound,
+		"error_message": "no fixture for " + url,
+	})
+	return failed
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": Version})
+}
+
+// handleReplay answers a redirected request with the response captured for
+// it. Requests that failed during the capture fail again by closing the
+// connection.
+func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
+	url := r.Header.Get(replay.URLHeader)
+	body, err := io.ReadAll(r.Body)
+	if err != nil || url == "" {
+		http.Error(w, "replay requests need the "+replay.URLHeader+" header", http.StatusBadRequest)
+		return
+	}
+
+	exchange, ok := s.replay.Next(r.Method, url, body)
+	if !ok {
+		s.logger.Warn("No captured exchange for request", map[string]interface{}{"method": r.Method, "url": url})
+		http.Error(w, "no captured exchange for "+r.Method+" "+url, http.StatusNotFound)
+		return
+	}
+
+	if exchange.Error != "" {
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+		http.Error(w, exchange.Error, http.StatusBadGateway)
+		return
+	}
+
+	payload, err := s.replay.OpenBody(exchange)
+	if err != nil {
+		s.logger.Error("Failed to read captured body", map[string]interface{}{"url": url, "error": err})
+		http.Error(w, "failed to read captured body", http.StatusInternalServerError)
+		return
+	}
+	defer payload.Close()
+
+	for name, values := range exchange.Header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding", "Connection":
+			continue
+		}
+		w.Header()[name] = values
+	}
+	w.WriteHeader(exchange.Status)
+	io.Copy(w, payload)
+}
+
+// handleOrigin serves fixture files and the HTML of fixture pages, standing in
+// for the crawled site. The ETag and Last-Modified response headers of a
+// fixture page are sent along, answering conditional requests as its server
+// would.
+func (s *Server) handleOrigin(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	if info, err := fs.Stat(s.files, name); err == nil && !info.IsDir() {
+		http.ServeFileFS(w, r, s.files, name)
+		return
+	}
+
+	var page struct {
+		HTML            string                 `json:"html"`
+		ResponseHeaders map[string]interface{} `json:"response_headers"`
+	}
+	raw := s.result(baseURL(r)+r.URL.RequestURI(), baseURL(r))
+	if err := json.Unmarshal(raw, &page); err != nil || page.HTML == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var modTime time.Time
+	for header, value := range page.ResponseHeaders {
+		value, ok := value.(string)
+		if !ok {
+			continue
+		}
+		switch http.CanonicalHeaderKey(header) {
+		case "Etag":
+			w.Header().Set("ETag", value)
+		case "Last-Modified":
+			modTime, _ = http.ParseTime(value)
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	http.ServeContent(w, r, "", modTime, strings.NewReader(page.HTML))
+}
+
+// logRequests logs each request at debug level
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debug("Mock server request", map[string]interface{}{
+			"method": r.Method,
+			"path":   r.URL.Path,
+		})
+		next.ServeHTTP(w, r)
+	})
+}
+
+// baseURL returns the address the client used to reach the server
+func baseURL(r *http.Request) string {
+	u := neturl.URL{Scheme: "http", Host: r.Host}
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	return u.String()
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
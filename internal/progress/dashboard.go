@@ -0,0 +1,246 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Drawing constants of the dashboard
+const (
+	dashboardInterval = 500 * time.Millisecond
+	dashboardRows     = 24 // when the terminal size is unknown
+	recentPages       = 8
+	recentErrors      = 5
+	maxWorkers        = 8
+	logLines          = 50
+)
+
+// Dashboard is a Renderer drawing a full-screen view of a crawl: the pages
+// processed with their rate and ETA, the size of the frontier, the running
+// reporters as workers, the recent pages and errors, and the latest log
+// messages, written to it through Write. The overall reporter is the one
+// named overall in its progress manager.
+type Dashboard struct {
+	mutex    sync.Mutex
+	out      *os.File
+	title    string
+	overall  string
+	started  time.Time
+	pages    State
+	workers  []uint64
+	states   map[uint64]State
+	frontier int
+	recent   []string
+	errors   []string
+	failed   int
+	logs     []string
+	partial  string // log output not ended by a newline yet
+	running  bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewDashboard creates a dashboard titled title drawn on out, showing the
+// reporter named overall as the progress of the crawl
+func NewDashboard(out *os.File, title, overall string) *Dashboard {
+	return &Dashboard{
+		out:     out,
+		title:   title,
+		overall: overall,
+		started: time.Now(),
+		states:  make(map[uint64]State),
+	}
+}
+
+// Start switches the terminal to the dashboard and redraws it every 500ms
+// until Stop
+func (d *Dashboard) Start() {
+	d.mutex.Lock()
+	d.running = true
+	d.mutex.Unlock()
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	// Alternate screen, cursor hidden
+	io.WriteString(d.out, "\x1b[?1049h\x1b[?25l")
+
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(dashboardInterval)
+		defer ticker.Stop()
+		for {
+			d.draw()
+			select {
+			case <-ticker.C:
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops redrawing the dashboard and restores the terminal. Further
+// writes go straight to the terminal.
+func (d *Dashboard) Stop() {
+	if d.stop == nil {
+		return
+	}
+	close(d.stop)
+	<-d.done
+	d.stop = nil
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.running = false
+	io.WriteString(d.out, "\x1b[?25h\x1b[?1049l")
+}
+
+// Update records the new state of a reporter
+func (d *Dashboard) Update(state State) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if state.Name == d.overall {
+		d.pages = state
+		return
+	}
+	if _, ok := d.states[state.ID]; !ok {
+		d.workers = append(d.workers, state.ID)
+	}
+	d.states[state.ID] = state
+}
+
+// Complete removes a completed reporter from the workers
+func (d *Dashboard) Complete(state State) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if state.Name == d.overall {
+		d.pages = state
+		return
+	}
+	delete(d.states, state.ID)
+	for i, id := range d.workers {
+		if id == state.ID {
+			d.workers = append(d.workers[:i], d.workers[i+1:]...)
+			break
+		}
+	}
+}
+
+// SetFrontier records the number of URLs waiting to be crawled
+func (d *Dashboard) SetFrontier(queued int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.frontier = queued
+}
+
+// PageDone adds a processed page to the recent pages
+func (d *Dashboard) PageDone(url string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.recent = appendRecent(d.recent, url, recentPages)
+}
+
+// PageFailed adds an error about a page to the recent errors
+func (d *Dashboard) PageFailed(url, message string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.failed++
+	d.errors = appendRecent(d.errors, url+": "+message, recentErrors)
+}
+
+// Write keeps the lines of p, such as log messages, for the log panel while
+// the dashboard is drawn, and writes them to the terminal otherwise
+func (d *Dashboard) Write(p []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.running {
+		return d.out.Write(p)
+	}
+
+	lines := strings.Split(d.partial+string(p), "\n")
+	d.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		d.logs = appendRecent(d.logs, line, logLines)
+	}
+	return len(p), nil
+}
+
+// appendRecent appends item to items, keeping the last n
+func appendRecent(items []string, item string, n int) []string {
+	items = append(items, item)
+	if len(items) > n {
+		items = items[len(items)-n:]
+	}
+	return items
+}
+
+// draw clears the screen and draws the dashboard
+func (d *Dashboard) draw() {
+	columns, rows := defaultColumns, dashboardRows
+	if width, height, err := term.GetSize(int(d.out.Fd())); err == nil && width > 0 && height > 0 {
+		columns, rows = width, height
+	}
+
+	// The log panel takes the rows left, if any
+	d.mutex.Lock()
+	lines := append(d.lines(columns-1), "", "Log")
+	if n := rows - 1 - len(lines); n > 0 {
+		for _, line := range d.logs[max(len(d.logs)-n, 0):] {
+			lines = append(lines, "  "+line)
+		}
+	}
+	d.mutex.Unlock()
+	lines = lines[:min(len(lines), rows-1)]
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	for _, line := range lines {
+		b.WriteString(truncate(line, columns-1))
+		b.WriteString("\n")
+	}
+	io.WriteString(d.out, b.String())
+}
+
+// lines returns the lines of the dashboard above the log panel, for a
+// terminal of columns
+func (d *Dashboard) lines(columns int) []string {
+	elapsed := time.Since(d.started)
+	lines := []string{
+		fmt.Sprintf("%s  (%s)", d.title, formatElapsed(elapsed)),
+		"",
+		formatBar(d.pages, columns),
+	}
+
+	eta := "-"
+	if d.pages.ETA > 0 {
+		eta = formatElapsed(d.pages.ETA)
+	}
+	lines = append(lines, fmt.Sprintf("%.2f pages/s   %s/s   ETA %s   Frontier %d queued   Errors %d",
+		d.pages.Rate, formatBytes(int64(d.pages.ByteRate)), eta, d.frontier, d.failed))
+
+	lines = append(lines, "", fmt.Sprintf("Workers (%d)", len(d.workers)))
+	for _, id := range d.workers[:min(len(d.workers), maxWorkers)] {
+		lines = append(lines, "  "+formatBar(d.states[id], columns-2))
+	}
+	if len(d.workers) == 0 {
+		lines = append(lines, "  idle")
+	}
+
+	lines = append(lines, "", "Recent pages")
+	for i := len(d.recent) - 1; i >= 0; i-- {
+		lines = append(lines, "  "+d.recent[i])
+	}
+
+	lines = append(lines, "", "Recent errors")
+	for i := len(d.errors) - 1; i >= 0; i-- {
+		lines = append(lines, "  "+d.errors[i])
+	}
+	return lines
+}
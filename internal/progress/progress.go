@@ -3,6 +3,7 @@ package progress
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"crawlr/internal/logger"
@@ -10,18 +11,36 @@ import (
 
 // ProgressReporter represents a progress reporting system
 type ProgressReporter struct {
+	id            uint64
+	name          string
 	logger        *logger.Logger
+	renderer      Renderer
 	operation     string
 	total         int
 	current       int
+	bytes         int64
 	startTime     time.Time
 	lastUpdate    time.Time
 	updateMutex   sync.Mutex
 	complete      bool
 	completeChan  chan bool
 	progressSteps []ProgressStep
+
+	// Sample of the progress the smoothed rate was last updated with
+	sampleTime    time.Time
+	sampleCurrent int
+	smoothedRate  float64
 }
 
+// Smoothing of the rate the ETA is estimated with: the rate over each
+// interval of at least rateSampleInterval weighs rateSmoothing in an
+// exponential moving average, so that the ETA follows the recent pace of the
+// crawl without jumping at every item
+const (
+	rateSampleInterval = time.Second
+	rateSmoothing      = 0.3
+)
+
 // ProgressStep represents a step in the progress
 type ProgressStep struct {
 	Name        string
@@ -32,15 +51,27 @@ type ProgressStep struct {
 	Error       error
 }
 
-// NewProgressReporter creates a new progress reporter
+// lastReporterID numbers the reporters created
+var lastReporterID atomic.Uint64
+
+// NewProgressReporter creates a new progress reporter logging its progress
 func NewProgressReporter(logger *logger.Logger, operation string, total int) *ProgressReporter {
+	return newProgressReporter(logger, NewLogRenderer(logger), "", operation, total)
+}
+
+func newProgressReporter(logger *logger.Logger, renderer Renderer, name, operation string, total int) *ProgressReporter {
+	now := time.Now()
 	return &ProgressReporter{
+		id:           lastReporterID.Add(1),
+		name:         name,
 		logger:       logger,
+		renderer:     renderer,
 		operation:    operation,
 		total:        total,
-		startTime:    time.Now(),
-		lastUpdate:   time.Now(),
+		startTime:    now,
+		lastUpdate:   now,
 		completeChan: make(chan bool, 1),
+		sampleTime:   now,
 	}
 }
 
@@ -51,18 +82,93 @@ func (p *ProgressReporter) Increment() {
 
 	p.current++
 	p.lastUpdate = time.Now()
+	p.sample()
+	p.update()
+}
 
-	// Log progress every 5% or every 10 items, whichever is more frequent
-	if p.total > 0 {
-		percentage := (p.current * 100) / p.total
-		if percentage%5 == 0 || p.current%10 == 0 || p.current == p.total {
-			p.logger.Progress(p.operation, p.current, p.total)
-		}
+// AddBytes counts n bytes processed, such as the bytes written for an item
+func (p *ProgressReporter) AddBytes(n int64) {
+	p.updateMutex.Lock()
+	defer p.updateMutex.Unlock()
+
+	p.bytes += n
+}
+
+// sample updates the smoothed rate once rateSampleInterval elapsed since it
+// was last updated. The reporter must be locked.
+func (p *ProgressReporter) sample() {
+	elapsed := p.lastUpdate.Sub(p.sampleTime)
+	if elapsed < rateSampleInterval {
+		return
+	}
+	rate := float64(p.current-p.sampleCurrent) / elapsed.Seconds()
+	if p.smoothedRate == 0 {
+		p.smoothedRate = rate
 	} else {
-		// If total is unknown, log every 10 items
-		if p.current%10 == 0 {
-			p.logger.Progress(p.operation, p.current, p.total)
-		}
+		p.smoothedRate = rateSmoothing*rate + (1-rateSmoothing)*p.smoothedRate
+	}
+	p.sampleTime = p.lastUpdate
+	p.sampleCurrent = p.current
+}
+
+// rate returns the items processed per second since the start. The reporter
+// must be locked.
+func (p *ProgressReporter) rate() float64 {
+	elapsed := time.Since(p.startTime).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.current) / elapsed
+}
+
+// byteRate returns the bytes processed per second since the start. The
+// reporter must be locked.
+func (p *ProgressReporter) byteRate() float64 {
+	elapsed := time.Since(p.startTime).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.bytes) / elapsed
+}
+
+// eta returns the time left to process the remaining items at the smoothed
+// rate, or at the rate since the start until it is known, and 0 when the
+// total or the rate is unknown. The reporter must be locked.
+func (p *ProgressReporter) eta() time.Duration {
+	if p.total <= 0 || p.current >= p.total {
+		return 0
+	}
+	rate := p.smoothedRate
+	if rate <= 0 {
+		rate = p.rate()
+	}
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(p.total-p.current) / rate * float64(time.Second))
+}
+
+// update displays the new state of the reporter, which must be locked,
+// unless it completed
+func (p *ProgressReporter) update() {
+	if !p.complete {
+		p.renderer.Update(p.state())
+	}
+}
+
+// state returns the current state of the reporter, which must be locked
+func (p *ProgressReporter) state() State {
+	return State{
+		ID:        p.id,
+		Name:      p.name,
+		Operation: p.operation,
+		Current:   p.current,
+		Total:     p.total,
+		Bytes:     p.bytes,
+		Elapsed:   time.Since(p.startTime),
+		Rate:      p.rate(),
+		ByteRate:  p.byteRate(),
+		ETA:       p.eta(),
 	}
 }
 
@@ -72,7 +178,7 @@ func (p *ProgressReporter) SetTotal(total int) {
 	defer p.updateMutex.Unlock()
 
 	p.total = total
-	p.logger.Progress(p.operation, p.current, p.total)
+	p.update()
 }
 
 // SetCurrent sets the current progress
@@ -82,7 +188,8 @@ func (p *ProgressReporter) SetCurrent(current int) {
 
 	p.current = current
 	p.lastUpdate = time.Now()
-	p.logger.Progress(p.operation, p.current, p.total)
+	p.sample()
+	p.update()
 }
 
 // GetProgress returns the current progress
@@ -112,18 +219,29 @@ func (p *ProgressReporter) GetElapsedTime() time.Duration {
 	return time.Since(p.startTime)
 }
 
-// GetEstimatedTimeRemaining returns the estimated time remaining
-func (p *ProgressReporter) GetEstimatedTimeRemaining() time.Duration {
+// GetRate returns the items processed per second since the start
+func (p *ProgressReporter) GetRate() float64 {
 	p.updateMutex.Lock()
 	defer p.updateMutex.Unlock()
 
-	if p.total <= 0 || p.current <= 0 {
-		return 0
-	}
+	return p.rate()
+}
+
+// GetByteRate returns the bytes processed per second since the start
+func (p *ProgressReporter) GetByteRate() float64 {
+	p.updateMutex.Lock()
+	defer p.updateMutex.Unlock()
 
-	elapsed := time.Since(p.startTime)
-	remaining := (elapsed / time.Duration(p.current)) * time.Duration(p.total-p.current)
-	return remaining
+	return p.byteRate()
+}
+
+// GetEstimatedTimeRemaining returns the estimated time remaining, at the
+// recent pace smoothed over the last seconds
+func (p *ProgressReporter) GetEstimatedTimeRemaining() time.Duration {
+	p.updateMutex.Lock()
+	defer p.updateMutex.Unlock()
+
+	return p.eta()
 }
 
 // Complete marks the progress as complete
@@ -135,10 +253,7 @@ func (p *ProgressReporter) Complete() {
 		p.complete = true
 		p.current = p.total
 		p.lastUpdate = time.Now()
-		elapsed := time.Since(p.startTime)
-
-		p.logger.Info(fmt.Sprintf("Progress completed: %s - %d/%d in %v",
-			p.operation, p.current, p.total, elapsed.Round(time.Millisecond)))
+		p.renderer.Complete(p.state())
 
 		// Notify any listeners that progress is complete
 		select {
@@ -243,13 +358,22 @@ type ProgressManager struct {
 	reporters map[string]*ProgressReporter
 	mutex     sync.Mutex
 	logger    *logger.Logger
+	renderer  Renderer
 }
 
-// NewProgressManager creates a new progress manager
+// NewProgressManager creates a new progress manager whose reporters log
+// their progress
 func NewProgressManager(logger *logger.Logger) *ProgressManager {
+	return NewProgressManagerWithRenderer(logger, NewLogRenderer(logger))
+}
+
+// NewProgressManagerWithRenderer creates a new progress manager whose
+// reporters display their progress with renderer
+func NewProgressManagerWithRenderer(logger *logger.Logger, renderer Renderer) *ProgressManager {
 	return &ProgressManager{
 		reporters: make(map[string]*ProgressReporter),
 		logger:    logger,
+		renderer:  renderer,
 	}
 }
 
@@ -258,7 +382,7 @@ func (m *ProgressManager) CreateReporter(id, operation string, total int) *Progr
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	reporter := NewProgressReporter(m.logger, operation, total)
+	reporter := newProgressReporter(m.logger, m.renderer, id, operation, total)
 	m.reporters[id] = reporter
 	return reporter
 }
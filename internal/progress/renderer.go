@@ -0,0 +1,256 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"crawlr/internal/logger"
+
+	"golang.org/x/term"
+)
+
+// State is the progress of a reporter when it changes
+type State struct {
+	ID        uint64 // unique to the reporter
+	Name      string // ID of the reporter in its progress manager, if any
+	Operation string
+	Current   int
+	Total     int   // 0 when unknown
+	Bytes     int64 // bytes counted with AddBytes
+	Elapsed   time.Duration
+	Rate      float64       // items per second since the start
+	ByteRate  float64       // bytes per second since the start
+	ETA       time.Duration // time left at the recent pace, 0 when unknown
+}
+
+// Renderer displays the progress of reporters. Its methods are called with
+// the reporter locked, so they must not call back into the reporter.
+type Renderer interface {
+	// Update displays the new state of a reporter
+	Update(state State)
+	// Complete displays the final state of a reporter, which will not change
+	// any more
+	Complete(state State)
+}
+
+// LogRenderer logs the progress of reporters every 5% or every 10 items,
+// whichever is more frequent, for output that is not a terminal
+type LogRenderer struct {
+	logger *logger.Logger
+}
+
+// NewLogRenderer creates a renderer logging progress through logger
+func NewLogRenderer(logger *logger.Logger) *LogRenderer {
+	return &LogRenderer{logger: logger}
+}
+
+// Update logs the state of a reporter, with its throughput and ETA, when it
+// crosses a step
+func (r *LogRenderer) Update(state State) {
+	if state.Total > 0 {
+		percentage := (state.Current * 100) / state.Total
+		if percentage%5 == 0 || state.Current%10 == 0 || state.Current == state.Total {
+			r.logger.Progress(state.Operation, state.Current, state.Total, throughputFields(state))
+		}
+	} else if state.Current%10 == 0 {
+		// If total is unknown, log every 10 items
+		r.logger.Progress(state.Operation, state.Current, state.Total, throughputFields(state))
+	}
+}
+
+// Complete logs the completion of a reporter
+func (r *LogRenderer) Complete(state State) {
+	r.logger.Info(fmt.Sprintf("Progress completed: %s - %d/%d in %v (%.2f/s)",
+		state.Operation, state.Current, state.Total, state.Elapsed.Round(time.Millisecond), state.Rate),
+		throughputFields(state))
+}
+
+// throughputFields returns the rates and ETA of a state as log fields
+func throughputFields(state State) map[string]interface{} {
+	fields := map[string]interface{}{
+		"perSecond": math.Round(state.Rate*100) / 100,
+	}
+	if state.Bytes > 0 {
+		fields["bytesPerSecond"] = int64(state.ByteRate)
+	}
+	if state.ETA > 0 {
+		fields["eta"] = state.ETA.Round(time.Second)
+	}
+	return fields
+}
+
+// IsTerminal reports whether f is a terminal, on which progress bars can be
+// drawn
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Drawing constants of the terminal renderer
+const (
+	redrawInterval    = 100 * time.Millisecond
+	barWidth          = 25
+	operationWidth    = 40 // at most, when the terminal is wide enough
+	minOperationWidth = 10
+	defaultColumns    = 80
+)
+
+// TerminalRenderer draws a progress bar per running reporter at the bottom
+// of a terminal, in the order the reporters started, and removes the bar of
+// a reporter once it completes. Other output written to the terminal while
+// bars are drawn must go through Write, which prints it above the bars.
+type TerminalRenderer struct {
+	mutex    sync.Mutex
+	out      *os.File
+	order    []uint64
+	states   map[uint64]State
+	drawn    int // lines of bars drawn last
+	lastDraw time.Time
+}
+
+// NewTerminalRenderer creates a renderer drawing bars on out
+func NewTerminalRenderer(out *os.File) *TerminalRenderer {
+	return &TerminalRenderer{
+		out:    out,
+		states: make(map[uint64]State),
+	}
+}
+
+// Update redraws the bars with the new state of a reporter, at most every
+// 100ms
+func (r *TerminalRenderer) Update(state State) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.states[state.ID]; !ok {
+		r.order = append(r.order, state.ID)
+	}
+	r.states[state.ID] = state
+	if time.Since(r.lastDraw) >= redrawInterval {
+		r.redraw(nil)
+	}
+}
+
+// Complete removes the bar of a reporter
+func (r *TerminalRenderer) Complete(state State) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.states[state.ID]; !ok {
+		return
+	}
+	delete(r.states, state.ID)
+	for i, id := range r.order {
+		if id == state.ID {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	r.redraw(nil)
+}
+
+// Write prints p, made of whole lines such as log messages, above the bars
+func (r *TerminalRenderer) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.redraw(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redraw erases the bars drawn last, prints text and draws the bars again
+func (r *TerminalRenderer) redraw(text []byte) error {
+	var b strings.Builder
+	if r.drawn > 0 {
+		// Move to the start of the first bar and clear the screen below
+		fmt.Fprintf(&b, "\x1b[%dF", r.drawn)
+	}
+	b.WriteString("\x1b[J")
+	b.Write(text)
+
+	columns := defaultColumns
+	if width, _, err := term.GetSize(int(r.out.Fd())); err == nil && width > 0 {
+		columns = width
+	}
+	for _, id := range r.order {
+		b.WriteString(formatBar(r.states[id], columns-1))
+		b.WriteByte('\n')
+	}
+	r.drawn = len(r.order)
+	r.lastDraw = time.Now()
+
+	_, err := io.WriteString(r.out, b.String())
+	return err
+}
+
+// formatBar formats the state of a reporter as a line of at most columns
+// runes with its operation, a bar filled to its percentage, its count, its
+// elapsed time, its rate and its ETA. The operation is shortened to fit.
+func formatBar(state State, columns int) string {
+	elapsed := formatElapsed(state.Elapsed)
+	if state.Rate > 0 {
+		elapsed += fmt.Sprintf("  %.1f/s", state.Rate)
+	}
+	if state.ETA > 0 {
+		elapsed += "  ETA " + formatElapsed(state.ETA)
+	}
+	var suffix string
+	if state.Total <= 0 {
+		suffix = fmt.Sprintf(" %d  %s", state.Current, elapsed)
+	} else {
+		current := min(state.Current, state.Total)
+		filled := current * barWidth / state.Total
+		bar := strings.Repeat("=", filled)
+		if filled < barWidth {
+			bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+		}
+		suffix = fmt.Sprintf(" [%s] %d/%d %3d%%  %s",
+			bar, state.Current, state.Total, current*100/state.Total, elapsed)
+	}
+
+	width := min(operationWidth, max(columns-len(suffix), minOperationWidth))
+	operation := fmt.Sprintf("%-*s", width, truncate(state.Operation, width))
+	return truncate(operation+suffix, columns)
+}
+
+// formatElapsed formats a duration as minutes and seconds, or hours,
+// minutes and seconds past an hour
+func formatElapsed(d time.Duration) string {
+	seconds := int(d.Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// formatBytes formats a number of bytes with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// truncate shortens s to width runes, ending it with an ellipsis
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	if width <= 1 {
+		return string(runes[:max(width, 0)])
+	}
+	return string(runes[:width-1]) + "…"
+}
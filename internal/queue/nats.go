@@ -0,0 +1,226 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// replyHeader carries the inbox a submitter waits on for the job result
+const replyHeader = "Crawlr-Reply-To"
+
+// setupTimeout bounds the JetStream calls made while opening the queue
+const setupTimeout = 10 * time.Second
+
+// fetchWait is how long a single pull waits for a job before being renewed
+const fetchWait = 30 * time.Second
+
+// natsQueue distributes jobs using a JetStream work-queue stream: jobs are
+// stored until an agent takes them, and the agents share a durable consumer so
+// each job is delivered to exactly one of them
+type natsQueue struct {
+	conn    *nats.Conn
+	js      jetstream.JetStream
+	stream  string
+	opts    Options
+	subOnce sync.Once
+	cons    jetstream.Consumer
+	subErr  error
+}
+
+// openNATS connects to a NATS server and creates the job stream if needed
+func openNATS(url string, opts Options) (*natsQueue, error) {
+	natsOpts := []nats.Option{
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(2 * time.Second),
+	}
+	if opts.Name != "" {
+		natsOpts = append(natsOpts, nats.Name(opts.Name))
+	}
+
+	conn, err := nats.Connect(url, natsOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+
+	q := &natsQueue{conn: conn, js: js, stream: streamName(opts.Subject), opts: opts}
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+	if err := q.ensureStream(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// streamName derives the name of the job stream from its subject, such as
+// CRAWLR_JOBS for crawlr.jobs
+func streamName(subject string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, subject)
+}
+
+// ensureStream creates the work-queue stream of the subject, leaving an
+// existing one as configured
+func (q *natsQueue) ensureStream(ctx context.Context) error {
+	_, err := q.js.Stream(ctx, q.stream)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, jetstream.ErrStreamNotFound) {
+		if errors.Is(err, jetstream.ErrJetStreamNotEnabled) {
+			return fmt.Errorf("JetStream is not enabled on the NATS server (run nats-server with -js): %w", err)
+		}
+		return fmt.Errorf("failed to look up stream %s: %w", q.stream, err)
+	}
+
+	_, err = q.js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:      q.stream,
+		Subjects:  []string{q.opts.Subject},
+		Retention: jetstream.WorkQueuePolicy,
+	})
+	if err != nil && !errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+		return fmt.Errorf("failed to create stream %s: %w", q.stream, err)
+	}
+	return nil
+}
+
+// Publish implements the Queue interface. It returns once the server has
+// stored the job, which is then kept until an agent takes it.
+func (q *natsQueue) Publish(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	if _, err := q.js.Publish(ctx, q.opts.Subject, data); err != nil {
+		return fmt.Errorf("failed to publish job: %w", err)
+	}
+	return nil
+}
+
+// Request implements the Queue interface
+func (q *natsQueue) Request(ctx context.Context, job *Job) (*JobResult, error) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	// Listen for the result before publishing, so it cannot be missed
+	inbox := q.conn.NewRespInbox()
+	sub, err := q.conn.SubscribeSync(inbox)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", inbox, err)
+	}
+	defer sub.Unsubscribe()
+
+	msg := nats.NewMsg(q.opts.Subject)
+	msg.Data = data
+	msg.Header.Set(replyHeader, inbox)
+	if _, err := q.js.PublishMsg(ctx, msg); err != nil {
+		return nil, fmt.Errorf("failed to submit job: %w", err)
+	}
+
+	reply, err := sub.NextMsgWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for job result: %w", err)
+	}
+
+	var result JobResult
+	if err := json.Unmarshal(reply.Data, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job result: %w", err)
+	}
+	return &result, nil
+}
+
+// Receive implements the Queue interface. A job is acknowledged as soon as it
+// is received: a job whose agent stops while crawling it is not run again.
+func (q *natsQueue) Receive(ctx context.Context) (*Delivery, error) {
+	q.subOnce.Do(func() {
+		q.cons, q.subErr = q.js.CreateOrUpdateConsumer(ctx, q.stream, jetstream.ConsumerConfig{
+			Durable:   q.opts.Group,
+			AckPolicy: jetstream.AckExplicitPolicy,
+		})
+	})
+	if q.subErr != nil {
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", q.opts.Subject, q.subErr)
+	}
+
+	msg, err := q.next(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal(msg.Data(), &job); err != nil {
+		_ = msg.Term()
+		return nil, fmt.Errorf("%w: %v", ErrMalformedJob, err)
+	}
+	if err := msg.DoubleAck(ctx); err != nil {
+		return nil, fmt.Errorf("failed to acknowledge job: %w", err)
+	}
+
+	delivery := &Delivery{Job: &job}
+	if reply := msg.Headers().Get(replyHeader); reply != "" {
+		delivery.respond = func(result *JobResult) error {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return fmt.Errorf("failed to marshal job result: %w", err)
+			}
+			if err := q.conn.Publish(reply, data); err != nil {
+				return err
+			}
+			return q.conn.Flush()
+		}
+	}
+	return delivery, nil
+}
+
+// next pulls the next job from the consumer, renewing the pull until a job
+// arrives or the context is done
+func (q *natsQueue) next(ctx context.Context) (jetstream.Msg, error) {
+	for {
+		batch, err := q.cons.Fetch(1, jetstream.FetchMaxWait(fetchWait))
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case msg, ok := <-batch.Messages():
+			if ok {
+				return msg, nil
+			}
+		}
+		// An expired pull or a missed heartbeat, while reconnecting, is retried
+		if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) && !errors.Is(err, jetstream.ErrNoHeartbeat) {
+			return nil, err
+		}
+	}
+}
+
+// Close implements the Queue interface. Pending messages are drained first.
+func (q *natsQueue) Close() error {
+	return q.conn.Drain()
+}
@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"time"
+)
+
+// DefaultSubject is the subject crawl jobs are published on
+const DefaultSubject = "crawlr.jobs"
+
+// DefaultGroup is the durable consumer agents share so each job is delivered once
+const DefaultGroup = "crawlr-agents"
+
+// ErrMalformedJob is returned by Receive when a message cannot be decoded as a
+// job. The message is discarded and the caller may keep receiving.
+var ErrMalformedJob = errors.New("malformed job")
+
+// Job describes a crawl to be performed by an agent. Zero values fall back to
+// the agent's own configuration.
+type Job struct {
+	ID              string    `json:"id"`
+	URL             string    `json:"url"`
+	Library         string    `json:"library"`
+	MaxDepth        int       `json:"max_depth,omitempty"`
+	MaxURLs         int       `json:"max_urls,omitempty"`
+	BatchSize       int       `json:"batch_size,omitempty"`
+	ExcludePatterns string    `json:"exclude_patterns,omitempty"`
+	SubmittedAt     time.Time `json:"submitted_at"`
+}
+
+// JobResult reports the outcome of a job back to its submitter
+type JobResult struct {
+	JobID      string    `json:"job_id"`
+	Agent      string    `json:"agent"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Delivery is a job received from the queue
+type Delivery struct {
+	Job     *Job
+	respond func(result *JobResult) error
+}
+
+// Respond sends the job result to the submitter if it is waiting for one
+func (d *Delivery) Respond(result *JobResult) error {
+	if d.respond == nil {
+		return nil
+	}
+	return d.respond(result)
+}
+
+// Queue is a shared job queue that multiple agents pull from
+type Queue interface {
+	// Publish submits a job to the queue
+	Publish(ctx context.Context, job *Job) error
+	// Request submits a job and waits for its result
+	Request(ctx context.Context, job *Job) (*JobResult, error)
+	// Receive blocks until a job is available or the context is done
+	Receive(ctx context.Context) (*Delivery, error)
+	// Close releases the connection to the queue
+	Close() error
+}
+
+// Options configures how a queue is opened
+type Options struct {
+	Subject string
+	Group   string
+	Name    string
+}
+
+// Open connects to the queue at queueURL. The scheme selects the backend.
+func Open(queueURL string, opts Options) (Queue, error) {
+	u, err := neturl.Parse(queueURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse queue URL: %w", err)
+	}
+
+	if opts.Subject == "" {
+		opts.Subject = DefaultSubject
+	}
+	if opts.Group == "" {
+		opts.Group = DefaultGroup
+	}
+
+	switch u.Scheme {
+	case "nats", "tls":
+		return openNATS(queueURL, opts)
+	default:
+		return nil, fmt.Errorf("unsupported queue scheme: %q", u.Scheme)
+	}
+}
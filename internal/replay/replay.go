@@ -0,0 +1,325 @@
+// Package replay captures the HTTP exchanges of a crawl, with crawl4ai and
+// origin servers alike, and replays them so that a run can be reproduced
+// offline with the same responses in the same order.
+//
+// A capture directory holds exchanges.jsonl, one Exchange per line, and the
+// response bodies under bodies/.
+package replay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IndexFile is the file listing the exchanges of a capture
+const IndexFile = "exchanges.jsonl"
+
+// bodiesDir is the directory holding the response bodies of a capture
+const bodiesDir = "bodies"
+
+// URLHeader carries the original URL of a request redirected to a replay server
+const URLHeader = "X-Crawlr-Replay-URL"
+
+// Path is the path of the replay endpoint of a replay server
+const Path = "/replay"
+
+// Exchange is a captured request and its response
+type Exchange struct {
+	Seq         int64       `json:"seq"`
+	Time        time.Time   `json:"time"`
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body,omitempty"` // relative to the capture directory
+	Size        int64       `json:"size,omitempty"`
+	Truncated   bool        `json:"truncated,omitempty"` // the crawler stopped reading early
+	Error       string      `json:"error,omitempty"`     // the request failed without a response
+}
+
+// Recorder is an http.RoundTripper capturing every exchange it carries
+type Recorder struct {
+	dir   string
+	next  http.RoundTripper
+	mutex sync.Mutex
+	seq   int64
+}
+
+// NewRecorder creates a recorder writing to dir. A capture already in dir is
+// replaced.
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.RemoveAll(filepath.Join(dir, bodiesDir)); err != nil {
+		return nil, fmt.Errorf("failed to clear capture directory: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, bodiesDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, IndexFile), nil, 0644); err != nil {
+		return nil, fmt.Errorf("failed to create capture index: %w", err)
+	}
+	return &Recorder{dir: dir}, nil
+}
+
+// Wrap returns a transport capturing the exchanges carried by next, or by the
+// default transport if next is nil
+func (r *Recorder) Wrap(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &recordingTransport{recorder: r, next: next}
+}
+
+// Exchanges returns the number of exchanges captured so far
+func (r *Recorder) Exchanges() int64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.seq
+}
+
+type recordingTransport struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		requestBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	r := t.recorder
+	r.mutex.Lock()
+	r.seq++
+	exchange := Exchange{
+		Seq:         r.seq,
+		Time:        time.Now().UTC(),
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: string(requestBody),
+	}
+	r.mutex.Unlock()
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		exchange.Error = err.Error()
+		if writeErr := r.append(exchange); writeErr != nil {
+			return nil, writeErr
+		}
+		return nil, err
+	}
+
+	exchange.Status = resp.StatusCode
+	exchange.Header = resp.Header.Clone()
+	exchange.Body = filepath.ToSlash(filepath.Join(bodiesDir, fmt.Sprintf("%06d", exchange.Seq)))
+	file, err := os.Create(filepath.Join(r.dir, filepath.FromSlash(exchange.Body)))
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to create capture body: %w", err)
+	}
+	resp.Body = &capturedBody{body: resp.Body, file: file, recorder: r, exchange: exchange}
+	return resp, nil
+}
+
+// capturedBody copies a response body to the capture as it is read, and
+// records the exchange once the body is closed
+type capturedBody struct {
+	body     io.ReadCloser
+	file     *os.File
+	recorder *Recorder
+	exchange Exchange
+	eof      bool
+	once     sync.Once
+}
+
+func (b *capturedBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		if _, writeErr := b.file.Write(p[:n]); writeErr != nil {
+			return n, fmt.Errorf("failed to capture response body: %w", writeErr)
+		}
+		b.exchange.Size += int64(n)
+	}
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *capturedBody) Close() error {
+	err := b.body.Close()
+	b.once.Do(func() {
+		b.exchange.Truncated = !b.eof
+		closeErr := b.file.Close()
+		if appendErr := b.recorder.append(b.exchange); closeErr == nil {
+			closeErr = appendErr
+		}
+		if err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+// append adds an exchange to the capture index
+func (r *Recorder) append(exchange Exchange) error {
+	line, err := json.Marshal(exchange)
+	if err != nil {
+		return fmt.Errorf("failed to encode exchange: %w", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	file, err := os.OpenFile(filepath.Join(r.dir, IndexFile), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open capture index: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write capture index: %w", err)
+	}
+	return file.Close()
+}
+
+// Capture is a loaded capture answering requests with the responses recorded
+// for them
+type Capture struct {
+	dir       string
+	mutex     sync.Mutex
+	exchanges map[string][]Exchange // by request key, in capture order
+	total     int
+}
+
+// Load reads the capture stored in dir
+func Load(dir string) (*Capture, error) {
+	data, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capture: %w", err)
+	}
+
+	var exchanges []Exchange
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var exchange Exchange
+		if err := json.Unmarshal(line, &exchange); err != nil {
+			return nil, fmt.Errorf("invalid exchange on line %d of %s: %w", i+1, IndexFile, err)
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	if len(exchanges) == 0 {
+		return nil, fmt.Errorf("no exchanges captured in %s", dir)
+	}
+
+	// Exchanges are indexed when their body is closed; replay them in the
+	// order the requests were sent
+	sort.Slice(exchanges, func(i, j int) bool { return exchanges[i].Seq < exchanges[j].Seq })
+
+	capture := &Capture{dir: dir, exchanges: make(map[string][]Exchange), total: len(exchanges)}
+	for _, exchange := range exchanges {
+		key := requestKey(exchange.Method, exchange.URL, []byte(exchange.RequestBody))
+		capture.exchanges[key] = append(capture.exchanges[key], exchange)
+	}
+	return capture, nil
+}
+
+// Exchanges returns the number of exchanges of the capture
+func (c *Capture) Exchanges() int {
+	return c.total
+}
+
+// Next returns the next captured exchange of a request. Requests sent more
+// often than captured get the last response again.
+func (c *Capture) Next(method string, url string, body []byte) (Exchange, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := requestKey(method, url, body)
+	queue := c.exchanges[key]
+	if len(queue) == 0 {
+		return Exchange{}, false
+	}
+	exchange := queue[0]
+	if len(queue) > 1 {
+		c.exchanges[key] = queue[1:]
+	}
+	return exchange, true
+}
+
+// OpenBody opens the captured response body of an exchange
+func (c *Capture) OpenBody(exchange Exchange) (io.ReadCloser, error) {
+	if exchange.Body == "" {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	return os.Open(filepath.Join(c.dir, filepath.FromSlash(exchange.Body)))
+}
+
+// requestKey identifies the requests that get the same captured responses.
+// Crawl requests are matched on the URLs they crawl only, so that crawler
+// options such as selectors can change between the capture and its replay.
+func requestKey(method string, url string, body []byte) string {
+	key := method + " " + url
+	if len(body) == 0 {
+		return key
+	}
+
+	var crawl struct {
+		URLs []string `json:"urls"`
+	}
+	if json.Unmarshal(body, &crawl) == nil && len(crawl.URLs) > 0 {
+		urls := append([]string(nil), crawl.URLs...)
+		sort.Strings(urls)
+		return key + " " + strings.Join(urls, " ")
+	}
+	sum := sha256.Sum256(body)
+	return key + " " + hex.EncodeToString(sum[:])
+}
+
+// Redirect returns a transport sending every request to the replay server at
+// serverURL instead of its destination, which travels in URLHeader
+func Redirect(serverURL string, next http.RoundTripper) (http.RoundTripper, error) {
+	server, err := neturl.Parse(strings.TrimSuffix(serverURL, "/") + Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replay server URL: %w", err)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &redirectTransport{server: server, next: next}, nil
+}
+
+type redirectTransport struct {
+	server *neturl.URL
+	next   http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	redirected := req.Clone(req.Context())
+	redirected.Header.Set(URLHeader, req.URL.String())
+	redirected.URL = t.server
+	redirected.Host = t.server.Host
+	// Credentials are meant for the original destination
+	redirected.Header.Del("Authorization")
+	return t.next.RoundTrip(redirected)
+}
@@ -0,0 +1,94 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"sort"
+
+	"crawlr/internal/errors"
+)
+
+// ErrorsFile is the name of the error report written to the library after
+// every run
+const ErrorsFile = "errors.json"
+
+// ErrorGroup is the failures of one type on one host
+type ErrorGroup struct {
+	Type   string  `json:"type"`
+	Host   string  `json:"host"`
+	Count  int     `json:"count"`
+	Errors []Error `json:"errors"`
+}
+
+// AddError records a failure of type errorType affecting url
+func (s *Summary) AddError(url string, errorType errors.ErrorType, message string, err error) {
+	if err != nil {
+		message = fmt.Sprintf("%s: %v", message, err)
+	}
+	s.Errors = append(s.Errors, Error{URL: url, Type: errorType.String(), Message: message})
+}
+
+// ErrorGroups returns the errors of the run grouped by type and host, the
+// largest groups first
+func (s *Summary) ErrorGroups() []ErrorGroup {
+	return GroupErrors(s.Errors)
+}
+
+// GroupErrors groups errors by type and host, the largest groups first
+func GroupErrors(errs []Error) []ErrorGroup {
+	type key struct{ errorType, host string }
+	index := make(map[key]int)
+	var groups []ErrorGroup
+	for _, e := range errs {
+		k := key{e.Type, errorHost(e.URL)}
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, ErrorGroup{Type: k.errorType, Host: k.host})
+		}
+		groups[i].Count++
+		groups[i].Errors = append(groups[i].Errors, e)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		if groups[i].Type != groups[j].Type {
+			return groups[i].Type < groups[j].Type
+		}
+		return groups[i].Host < groups[j].Host
+	})
+	return groups
+}
+
+// errorHost returns the host of the URL of an error, or the URL itself if it
+// has none
+func errorHost(url string) string {
+	if u, err := neturl.Parse(url); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	return url
+}
+
+// WriteErrors writes the errors of the summary, grouped by type and host,
+// into path, replacing the file atomically. The file is written without
+// errors too, so that it never describes an earlier run.
+func WriteErrors(path string, summary *Summary) error {
+	groups := summary.ErrorGroups()
+	if groups == nil {
+		groups = []ErrorGroup{}
+	}
+	data, err := json.MarshalIndent(struct {
+		Library string       `json:"library"`
+		URL     string       `json:"url"`
+		Total   int          `json:"total"`
+		Groups  []ErrorGroup `json:"groups"`
+	}{summary.Library, summary.URL, len(summary.Errors), groups}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal error report: %w", err)
+	}
+	return writeAtomic(path, append(data, '\n'))
+}
@@ -0,0 +1,299 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"crawlr/internal/breaker"
+	"crawlr/internal/budget"
+	"crawlr/internal/i18n"
+)
+
+// JSONFile is the name of the JSON summary written to the library after
+// every run
+const JSONFile = "report.json"
+
+// Summary is the model of a crawl run passed to report templates
+type Summary struct {
+	Library    string        `json:"library"`
+	URL        string        `json:"url"`
+	Output     string        `json:"output"` // path of the library directory
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Duration   time.Duration `json:"-"`
+
+	PagesCrawled   int     `json:"pages_crawled"` // results returned by the crawler, successful or not
+	PagesSaved     int     `json:"pages_saved"`
+	PagesFailed    int     `json:"pages_failed"`
+	PagesOptedOut  int     `json:"pages_opted_out"` // pages skipped because they opt out of archiving
+	PagesNoIndex   int     `json:"pages_noindex"`   // pages skipped because they are marked noindex
+	PagesRejected  int     `json:"pages_rejected"`  // pages a pre-save hook rejected
+	PagesUnchanged int     `json:"pages_unchanged"` // pages an incremental re-crawl found unchanged
+	PagesRemoved   int     `json:"pages_removed"`   // pages of earlier crawls pruned by --sync
+	MediaSaved     int     `json:"media_saved"`
+	MediaSkipped   int     `json:"media_skipped"` // media found on pages but not downloaded: filtered, already stored or failed
+	BytesWritten   int64   `json:"bytes_written"`
+	PagesPerSecond float64 `json:"pages_per_s"`              // pages crawled per second of the run
+	BytesPerSecond float64 `json:"bytes_per_s"`              // bytes written per second of the run
+	Cancelled      bool    `json:"cancelled,omitempty"`      // the crawl stopped early on interruption
+	DurationLimit  bool    `json:"duration_limit,omitempty"` // the crawl stopped early once --max-duration elapsed
+	FailedFast     bool    `json:"failed_fast,omitempty"`    // the crawl stopped early on an error with --fail-fast
+	ErrorRate      float64 `json:"error_rate"`               // percentage of pages that could not be crawled or saved
+
+	StatusCodes map[int]int   `json:"status_codes"` // crawled pages by HTTP status
+	ServerTime  time.Duration `json:"-"`            // processing time reported by crawl4ai
+
+	SitemapURLs    int `json:"sitemap_urls,omitempty"`    // in-scope pages listed in the sitemap, with --sitemap-report
+	SitemapOrphans int `json:"sitemap_orphans,omitempty"` // sitemap pages linked from no crawled page
+	NotInSitemap   int `json:"not_in_sitemap,omitempty"`  // crawled pages missing from the sitemap
+
+	Budget   *budget.Report  `json:"budget,omitempty"`           // trade-off between pages and media, with a crawl budget
+	Circuits []breaker.State `json:"circuit_breakers,omitempty"` // hosts that failed during the crawl
+
+	Pages  []Page  `json:"pages"`
+	Errors []Error `json:"errors"`
+}
+
+// Page describes a crawled page
+type Page struct {
+	URL        string `json:"url"`
+	Title      string `json:"title,omitempty"`
+	Path       string `json:"path,omitempty"` // saved markdown file, empty if none was written
+	StatusCode int    `json:"status_code"`
+	Depth      int    `json:"depth"`
+	Size       int64  `json:"size"`
+	Media      int    `json:"media"`
+}
+
+// Error describes a failure affecting a single URL
+type Error struct {
+	URL     string `json:"url"`
+	Type    string `json:"type"` // crawlr error type, such as NetworkError
+	Message string `json:"message"`
+}
+
+// MarshalJSON encodes the summary with durations in seconds
+func (s *Summary) MarshalJSON() ([]byte, error) {
+	type summary Summary
+	return json.Marshal(struct {
+		*summary
+		Duration   float64 `json:"duration_s"`
+		ServerTime float64 `json:"server_processing_time_s"`
+	}{(*summary)(s), s.Duration.Seconds(), s.ServerTime.Seconds()})
+}
+
+// Merge adds the page counts, pages and errors of a partial summary, filled
+// by a writer saving part of the pages
+func (s *Summary) Merge(partial *Summary) {
+	s.PagesSaved += partial.PagesSaved
+	s.PagesFailed += partial.PagesFailed
+	s.PagesOptedOut += partial.PagesOptedOut
+	s.PagesNoIndex += partial.PagesNoIndex
+	s.PagesRejected += partial.PagesRejected
+	s.PagesUnchanged += partial.PagesUnchanged
+	s.MediaSaved += partial.MediaSaved
+	s.MediaSkipped += partial.MediaSkipped
+	s.BytesWritten += partial.BytesWritten
+	s.Pages = append(s.Pages, partial.Pages...)
+	s.Errors = append(s.Errors, partial.Errors...)
+}
+
+// Finish sets the end time, duration and throughput of the run
+func (s *Summary) Finish(finishedAt time.Time) {
+	s.FinishedAt = finishedAt
+	s.Duration = finishedAt.Sub(s.StartedAt)
+	if seconds := s.Duration.Seconds(); seconds > 0 {
+		s.PagesPerSecond = float64(s.PagesCrawled) / seconds
+		s.BytesPerSecond = float64(s.BytesWritten) / seconds
+	}
+}
+
+// funcs are the helpers available to report templates in addition to those
+// of the i18n package
+var funcs = map[string]interface{}{
+	"bytes":    formatBytes,
+	"duration": func(d time.Duration) string { return d.Round(time.Second).String() },
+	"date":     func(t time.Time) string { return t.Format(time.RFC3339) },
+}
+
+// IsHTML reports whether a template file produces HTML and must be escaped
+// accordingly. A trailing .tmpl or .tpl extension is ignored.
+func IsHTML(templatePath string) bool {
+	ext := strings.ToLower(filepath.Ext(OutputName(templatePath)))
+	return ext == ".html" || ext == ".htm"
+}
+
+// OutputName returns the name of the report generated from a template file:
+// "summary.html.tmpl" yields "summary.html"
+func OutputName(templatePath string) string {
+	name := filepath.Base(templatePath)
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".tmpl", ".tpl", ".gotmpl":
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name
+}
+
+// Render executes the template file at templatePath with summary and writes
+// the result to w. Templates whose name ends in .html (optionally followed by
+// .tmpl) are parsed with html/template so that page data is escaped.
+func Render(w io.Writer, templatePath string, summary *Summary) error {
+	data, err := os.ReadFile(templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to read report template: %w", err)
+	}
+
+	name := filepath.Base(templatePath)
+	var buf bytes.Buffer
+	if IsHTML(templatePath) {
+		tmpl, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(i18n.FuncMap())).Funcs(funcs).Parse(string(data))
+		if err != nil {
+			return fmt.Errorf("failed to parse report template: %w", err)
+		}
+		err = tmpl.Execute(&buf, summary)
+		if err != nil {
+			return fmt.Errorf("failed to execute report template: %w", err)
+		}
+	} else {
+		tmpl, err := template.New(name).Funcs(i18n.FuncMap()).Funcs(funcs).Parse(string(data))
+		if err != nil {
+			return fmt.Errorf("failed to parse report template: %w", err)
+		}
+		err = tmpl.Execute(&buf, summary)
+		if err != nil {
+			return fmt.Errorf("failed to execute report template: %w", err)
+		}
+	}
+
+	// Nothing is written if the template fails half-way
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// WriteFile renders the template at templatePath into path, replacing the
+// file atomically
+func WriteFile(path, templatePath string, summary *Summary) error {
+	var buf bytes.Buffer
+	if err := Render(&buf, templatePath, summary); err != nil {
+		return err
+	}
+	return writeAtomic(path, buf.Bytes())
+}
+
+// WriteJSON writes the summary as JSON into path, replacing the file
+// atomically
+func WriteJSON(path string, summary *Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	return writeAtomic(path, append(data, '\n'))
+}
+
+// maxTextErrorGroups bounds the error groups printed with the summary; all of
+// them are written to ErrorsFile
+const maxTextErrorGroups = 10
+
+// WriteJSONLine prints the summary as a single line of JSON, for scripts
+// reading the output of quiet crawls
+func WriteJSONLine(w io.Writer, summary *Summary) error {
+	return json.NewEncoder(w).Encode(summary)
+}
+
+// WriteText prints the totals of the summary, one per line, with the labels
+// of the current language
+func WriteText(w io.Writer, summary *Summary) error {
+	codes := make([]int, 0, len(summary.StatusCodes))
+	for code := range summary.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	statuses := make([]string, len(codes))
+	for i, code := range codes {
+		statuses[i] = fmt.Sprintf("%d: %d", code, summary.StatusCodes[code])
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s: %s\n", i18n.T("report.title"), summary.Library)
+	if summary.FailedFast {
+		fmt.Fprintf(tw, "  %s\n", i18n.T("report.failed_fast"))
+	} else if summary.Cancelled {
+		fmt.Fprintf(tw, "  %s\n", i18n.T("report.cancelled"))
+	}
+	if summary.DurationLimit {
+		fmt.Fprintf(tw, "  %s\n", i18n.T("report.duration_limit"))
+	}
+	for _, line := range [][2]string{
+		{i18n.T("report.duration"), summary.Duration.Round(time.Second).String()},
+		{i18n.T("report.pages_crawled"), fmt.Sprint(summary.PagesCrawled)},
+		{i18n.T("report.pages_saved"), fmt.Sprint(summary.PagesSaved)},
+		{i18n.T("report.pages_failed"), fmt.Sprint(summary.PagesFailed)},
+		{i18n.T("report.status_codes"), strings.Join(statuses, ", ")},
+		{i18n.T("report.media_saved"), fmt.Sprint(summary.MediaSaved)},
+		{i18n.T("report.media_skipped"), fmt.Sprint(summary.MediaSkipped)},
+		{i18n.T("report.bytes_written"), formatBytes(summary.BytesWritten)},
+		{i18n.T("report.throughput"), i18n.T("report.throughput_rates", summary.PagesPerSecond, formatBytes(int64(summary.BytesPerSecond)))},
+		{i18n.T("report.server_time"), summary.ServerTime.Round(time.Millisecond).String()},
+	} {
+		fmt.Fprintf(tw, "  %s:\t%s\n", line[0], line[1])
+	}
+	if b := summary.Budget; b != nil {
+		fmt.Fprintf(tw, "  %s:\t%s\n", i18n.T("report.budget"), i18n.T("report.budget_tradeoff",
+			b.Policy, formatBytes(b.PageBytes), formatBytes(b.MediaBytes), b.MediaShare*100, b.MediaSkipped))
+	}
+	if len(summary.Errors) > 0 {
+		fmt.Fprintf(tw, "  %s:\t%s\n", i18n.T("report.errors"), i18n.T("report.errors_total", len(summary.Errors), ErrorsFile))
+		fmt.Fprintf(tw, "  %s:\t%.1f%%\n", i18n.T("report.error_rate"), summary.ErrorRate)
+		groups := summary.ErrorGroups()
+		for _, group := range groups[:min(len(groups), maxTextErrorGroups)] {
+			fmt.Fprintf(tw, "  \t  %s\n", i18n.T("report.error_group", group.Type, group.Host, group.Count))
+		}
+	}
+	for _, circuit := range summary.Circuits {
+		if circuit.Opened > 0 {
+			fmt.Fprintf(tw, "  %s:\t%s\n", i18n.T("report.circuit"), i18n.T("report.circuit_opened",
+				circuit.Host, circuit.Opened, circuit.Failures))
+		}
+	}
+	return tw.Flush()
+}
+
+// writeAtomic writes data to a temporary file renamed over path
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace report: %w", err)
+	}
+	return nil
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
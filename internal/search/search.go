@@ -0,0 +1,307 @@
+package search
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"crawlr/internal/storage"
+)
+
+// Document is a markdown page of a library
+type Document struct {
+	Path    string `json:"path"`
+	Title   string `json:"title"`
+	content string
+	terms   map[string]int
+	length  int
+}
+
+// Result is a single search hit
+type Result struct {
+	Path    string  `json:"path"`
+	Title   string  `json:"title"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+// Index is an in-memory full-text index over the markdown pages of a library
+type Index struct {
+	mutex    sync.RWMutex
+	root     string
+	docs     []*Document
+	postings map[string][]int
+	builtAt  time.Time
+}
+
+// snippetRadius is the number of characters kept on each side of the first match
+const snippetRadius = 80
+
+// BuildIndex indexes every markdown file below root
+func BuildIndex(root string) (*Index, error) {
+	idx := &Index{root: root}
+	if err := idx.Rebuild(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Rebuild re-reads the markdown files and replaces the index contents
+func (idx *Index) Rebuild() error {
+	var docs []*Document
+	postings := make(map[string][]int)
+
+	err := filepath.WalkDir(idx.root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".md") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		relPath, err := filepath.Rel(idx.root, path)
+		if err != nil {
+			relPath = path
+		}
+
+		content := storage.StripFrontMatter(string(data))
+		doc := &Document{
+			Path:    filepath.ToSlash(relPath),
+			Title:   MarkdownTitle(content, relPath),
+			content: content,
+			terms:   make(map[string]int),
+		}
+		for _, term := range Tokenize(content) {
+			doc.terms[term]++
+			doc.length++
+		}
+
+		id := len(docs)
+		for term := range doc.terms {
+			postings[term] = append(postings[term], id)
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to index %s: %w", idx.root, err)
+	}
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	idx.docs = docs
+	idx.postings = postings
+	idx.builtAt = time.Now()
+	return nil
+}
+
+// Len returns the number of indexed documents
+func (idx *Index) Len() int {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	return len(idx.docs)
+}
+
+// BuiltAt returns when the index was last built
+func (idx *Index) BuiltAt() time.Time {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	return idx.builtAt
+}
+
+// Search returns the documents containing every term of the query, ranked by
+// TF-IDF, with an HTML-escaped snippet in which matches are wrapped in <mark>
+func (idx *Index) Search(query string, limit int) []Result {
+	terms := uniqueTerms(Tokenize(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	// Intersect postings, starting from the rarest term
+	sort.Slice(terms, func(i, j int) bool {
+		return len(idx.postings[terms[i]]) < len(idx.postings[terms[j]])
+	})
+	candidates := make(map[int]bool)
+	for _, id := range idx.postings[terms[0]] {
+		candidates[id] = true
+	}
+	for _, term := range terms[1:] {
+		next := make(map[int]bool)
+		for _, id := range idx.postings[term] {
+			if candidates[id] {
+				next[id] = true
+			}
+		}
+		candidates = next
+	}
+
+	var results []Result
+	docCount := float64(len(idx.docs))
+	for id := range candidates {
+		doc := idx.docs[id]
+		score := 0.0
+		for _, term := range terms {
+			tf := float64(doc.terms[term]) / float64(doc.length)
+			idf := math.Log(1 + docCount/float64(len(idx.postings[term])))
+			score += tf * idf
+		}
+		// Title matches are a strong relevance signal
+		lowerTitle := strings.ToLower(doc.Title)
+		for _, term := range terms {
+			if strings.Contains(lowerTitle, term) {
+				score *= 1.5
+			}
+		}
+		results = append(results, Result{
+			Path:    doc.Path,
+			Title:   doc.Title,
+			Score:   math.Round(score*10000) / 10000,
+			Snippet: Snippet(doc.content, terms),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Path < results[j].Path
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Tokenize splits text into lower-cased alphanumeric terms
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Snippet extracts the text around the first match of any term, escapes it for
+// HTML and wraps every matching term in <mark> tags
+func Snippet(content string, terms []string) string {
+	lower := strings.ToLower(content)
+	first := -1
+	for _, term := range terms {
+		if pos := strings.Index(lower, term); pos >= 0 && (first < 0 || pos < first) {
+			first = pos
+		}
+	}
+	if first < 0 {
+		first = 0
+	}
+
+	start := max(0, first-snippetRadius)
+	end := min(len(content), first+snippetRadius)
+	// Avoid cutting multi-byte characters in half
+	for start > 0 && !isRuneStart(content[start]) {
+		start--
+	}
+	for end < len(content) && !isRuneStart(content[end]) {
+		end++
+	}
+
+	excerpt := strings.Join(strings.Fields(content[start:end]), " ")
+	snippet := highlight(excerpt, terms)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(content) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// highlight escapes text and wraps case-insensitive whole-word term matches in <mark>
+func highlight(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// Lower-casing changed byte offsets; skip highlighting rather than misalign
+		return html.EscapeString(text)
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		matched := ""
+		if i == 0 || !isWordByte(lower, i-1) {
+			for _, term := range terms {
+				end := i + len(term)
+				if strings.HasPrefix(lower[i:], term) && (end == len(lower) || !isWordByte(lower, end)) && len(term) > len(matched) {
+					matched = term
+				}
+			}
+		}
+		if matched == "" {
+			j := i + 1
+			for j < len(text) && !isRuneStart(text[j]) {
+				j++
+			}
+			b.WriteString(html.EscapeString(text[i:j]))
+			i = j
+			continue
+		}
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[i : i+len(matched)]))
+		b.WriteString("</mark>")
+		i += len(matched)
+	}
+	return b.String()
+}
+
+// MarkdownTitle returns the first level-one heading of a page, or its file name
+func MarkdownTitle(content, path string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "# "))
+		}
+	}
+	return strings.TrimSuffix(filepath.Base(path), ".md")
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+// isWordByte reports whether the character starting or continuing at byte i
+// of s is a letter or a number
+func isWordByte(s string, i int) bool {
+	for i > 0 && !isRuneStart(s[i]) {
+		i--
+	}
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"crawlr/internal/storage"
+
+	"github.com/graphql-go/graphql"
+)
+
+// graphqlRequest is the body of a GraphQL POST request
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// entryFilter selects manifest entries from GraphQL arguments
+type entryFilter struct {
+	pathPrefix   string
+	urlPrefix    string
+	crawledAfter time.Time
+	statusCode   int
+	minStatus    int
+	limit        int
+}
+
+// entryArgs are the filter arguments accepted by the pages and media fields
+var entryArgs = graphql.FieldConfigArgument{
+	"pathPrefix": &graphql.ArgumentConfig{
+		Type:        graphql.String,
+		Description: "Only entries whose URL path starts with this prefix, e.g. /api",
+	},
+	"urlPrefix": &graphql.ArgumentConfig{
+		Type:        graphql.String,
+		Description: "Only entries whose URL starts with this prefix",
+	},
+	"crawledAfter": &graphql.ArgumentConfig{
+		Type:        graphql.DateTime,
+		Description: "Only entries crawled after this time (RFC 3339)",
+	},
+	"statusCode": &graphql.ArgumentConfig{
+		Type:        graphql.Int,
+		Description: "Only entries fetched with this HTTP status",
+	},
+	"minStatus": &graphql.ArgumentConfig{
+		Type:        graphql.Int,
+		Description: "Only entries fetched with at least this HTTP status, e.g. 400 for errors",
+	},
+	"limit": &graphql.ArgumentConfig{
+		Type:        graphql.Int,
+		Description: "Maximum number of entries returned",
+	},
+}
+
+// newGraphQLSchema builds the schema served at /graphql
+func (s *Server) newGraphQLSchema() (graphql.Schema, error) {
+	entryFields := func(kind string) graphql.Fields {
+		return graphql.Fields{
+			"url":        &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "Source URL of the " + kind},
+			"path":       &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "Path relative to the library"},
+			"hash":       &graphql.Field{Type: graphql.String, Description: "Hex-encoded SHA-256 of the content"},
+			"size":       &graphql.Field{Type: graphql.Int, Description: "Size in bytes"},
+			"crawledAt":  &graphql.Field{Type: graphql.DateTime, Description: "When the " + kind + " was crawled"},
+			"statusCode": &graphql.Field{Type: graphql.Int, Description: "HTTP status of the fetch"},
+		}
+	}
+
+	pageType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Page",
+		Fields: entryFields("page"),
+	})
+	mediaType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Media",
+		Fields: entryFields("media file"),
+	})
+
+	libraryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Library",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*storage.Manifest).Library, nil
+				},
+			},
+			"updatedAt": &graphql.Field{
+				Type:        graphql.DateTime,
+				Description: "When the manifest was last written",
+			},
+			"pageCount": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return len(p.Source.(*storage.Manifest).Pages), nil
+				},
+			},
+			"mediaCount": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return len(p.Source.(*storage.Manifest).Media), nil
+				},
+			},
+			"pages": &graphql.Field{
+				Type: graphql.NewList(pageType),
+				Args: entryArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return filterEntries(p.Source.(*storage.Manifest).Pages, newEntryFilter(p.Args)), nil
+				},
+			},
+			"media": &graphql.Field{
+				Type: graphql.NewList(mediaType),
+				Args: entryArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return filterEntries(p.Source.(*storage.Manifest).Media, newEntryFilter(p.Args)), nil
+				},
+			},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"libraries": &graphql.Field{
+				Type: graphql.NewList(libraryType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.loadManifests()
+				},
+			},
+			"library": &graphql.Field{
+				Type: libraryType,
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					name, _ := p.Args["name"].(string)
+					if !validLibraryName(name) {
+						return nil, errInvalidLibrary
+					}
+					if _, err := os.Stat(filepath.Join(s.config.Output, name)); err != nil {
+						return nil, errLibraryNotFound
+					}
+					return s.loadManifest(name)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphqlRequest
+	if r.Method == http.MethodGet {
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if raw := r.URL.Query().Get("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				s.writeError(w, http.StatusBadRequest, "invalid variables")
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid GraphQL request body")
+		return
+	}
+
+	if req.Query == "" {
+		s.writeError(w, http.StatusBadRequest, "missing query")
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         s.graphqlSchema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        r.Context(),
+	})
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// loadManifest reads the manifest of a library and names it after the library folder
+func (s *Server) loadManifest(name string) (*storage.Manifest, error) {
+	manifest, err := storage.LoadManifest(filepath.Join(s.config.Output, name))
+	if err != nil {
+		return nil, err
+	}
+	manifest.Library = name
+	return manifest, nil
+}
+
+// loadManifests reads the manifests of every library in the output folder
+func (s *Server) loadManifests() ([]*storage.Manifest, error) {
+	entries, err := os.ReadDir(s.config.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []*storage.Manifest
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		manifest, err := s.loadManifest(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+func newEntryFilter(args map[string]interface{}) entryFilter {
+	var f entryFilter
+	f.pathPrefix, _ = args["pathPrefix"].(string)
+	f.urlPrefix, _ = args["urlPrefix"].(string)
+	f.crawledAfter, _ = args["crawledAfter"].(time.Time)
+	f.statusCode, _ = args["statusCode"].(int)
+	f.minStatus, _ = args["minStatus"].(int)
+	f.limit, _ = args["limit"].(int)
+	return f
+}
+
+func filterEntries(entries []storage.ManifestEntry, f entryFilter) []storage.ManifestEntry {
+	filtered := []storage.ManifestEntry{}
+	for _, entry := range entries {
+		if f.limit > 0 && len(filtered) >= f.limit {
+			break
+		}
+		if f.urlPrefix != "" && !strings.HasPrefix(entry.URL, f.urlPrefix) {
+			continue
+		}
+		if f.pathPrefix != "" && !strings.HasPrefix(urlPath(entry.URL), f.pathPrefix) {
+			continue
+		}
+		if !f.crawledAfter.IsZero() && !entry.CrawledAt.After(f.crawledAfter) {
+			continue
+		}
+		if f.statusCode != 0 && entry.StatusCode != f.statusCode {
+			continue
+		}
+		if f.minStatus != 0 && entry.StatusCode < f.minStatus {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// urlPath returns the path of a URL, or an empty string if it cannot be parsed
+func urlPath(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
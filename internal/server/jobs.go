@@ -0,0 +1,325 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"crawlr/internal/progress"
+	"crawlr/internal/queue"
+	"crawlr/internal/storage"
+)
+
+// JobRunner crawls a job into the output folder, reporting the pages saved
+// to the "crawl" reporter of the progress manager
+type JobRunner func(ctx context.Context, job *queue.Job, progress *progress.ProgressManager) error
+
+// States of a crawl job
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// maxJobBody bounds the size of a job submission
+const maxJobBody = 64 << 10
+
+// jobRetention is how long a finished job stays listed
+const jobRetention = time.Hour
+
+// maxFinishedJobs caps the finished jobs listed; the oldest are forgotten
+// first
+const maxFinishedJobs = 100
+
+var errJobNotFound = errors.New("job not found")
+
+// JobStatus is the body returned by the job endpoints
+type JobStatus struct {
+	queue.Job
+	State      string     `json:"state"`
+	Error      string     `json:"error,omitempty"`
+	Pages      int        `json:"pages"`           // pages processed so far
+	Total      int        `json:"total,omitempty"` // pages the crawl may process at most
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// job is a crawl job submitted to the server
+type job struct {
+	spec     queue.Job
+	cancel   context.CancelFunc
+	progress *progress.ProgressManager
+
+	// Guarded by jobs.mutex
+	state      string
+	err        string
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+// jobs runs the crawl jobs submitted to the server, at most limit at a time.
+// Jobs are kept in memory and forgotten when the server stops, or once
+// finished for longer than retention or beyond the maxFinished latest.
+type jobs struct {
+	runner      JobRunner
+	slots       chan struct{}
+	ctx         context.Context // cancelled on shutdown
+	stop        context.CancelFunc
+	wg          sync.WaitGroup
+	retention   time.Duration
+	maxFinished int
+
+	mutex sync.Mutex
+	byID  map[string]*job
+}
+
+func newJobs(runner JobRunner, limit int) *jobs {
+	ctx, stop := context.WithCancel(context.Background())
+	return &jobs{
+		runner:      runner,
+		slots:       make(chan struct{}, max(limit, 1)),
+		ctx:         ctx,
+		stop:        stop,
+		retention:   jobRetention,
+		maxFinished: maxFinishedJobs,
+		byID:        make(map[string]*job),
+	}
+}
+
+// SetJobRunner enables the job endpoints, crawling submitted jobs with runner
+// at most serve_max_jobs at a time
+func (s *Server) SetJobRunner(runner JobRunner) {
+	s.jobs = newJobs(runner, s.config.ServeMaxJobs)
+}
+
+// Close cancels the running and queued jobs and waits for them to stop, so
+// that cancelled crawls save their pages and checkpoint
+func (s *Server) Close() {
+	if s.jobs == nil {
+		return
+	}
+	s.jobs.stop()
+	s.jobs.wg.Wait()
+}
+
+func (s *Server) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	var spec queue.Job
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJobBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&spec); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid job: "+err.Error())
+		return
+	}
+	if u, err := neturl.Parse(spec.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		s.writeError(w, http.StatusBadRequest, "url must be an absolute http(s) URL")
+		return
+	}
+	if !validLibraryName(spec.Library) {
+		s.writeError(w, http.StatusBadRequest, errInvalidLibrary.Error())
+		return
+	}
+	spec.ID = newJobID()
+	spec.SubmittedAt = time.Now().UTC()
+
+	j := s.jobs.start(spec, s)
+	s.logger.Info("Accepted job", map[string]interface{}{
+		"jobID":   spec.ID,
+		"url":     spec.URL,
+		"library": spec.Library,
+	})
+	s.writeJSON(w, http.StatusAccepted, s.jobs.status(j))
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	s.jobs.mutex.Lock()
+	list := make([]*job, 0, len(s.jobs.byID))
+	for _, j := range s.jobs.byID {
+		list = append(list, j)
+	}
+	s.jobs.mutex.Unlock()
+	sort.Slice(list, func(i, k int) bool { return list[i].spec.SubmittedAt.Before(list[k].spec.SubmittedAt) })
+
+	statuses := make([]JobStatus, len(list))
+	for i, j := range list {
+		statuses[i] = s.jobs.status(j)
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": statuses})
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		s.writeError(w, http.StatusNotFound, errJobNotFound.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.jobs.status(j))
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		s.writeError(w, http.StatusNotFound, errJobNotFound.Error())
+		return
+	}
+	j.cancel()
+	s.logger.Info("Cancelling job", map[string]interface{}{"jobID": j.spec.ID})
+	s.writeJSON(w, http.StatusAccepted, s.jobs.status(j))
+}
+
+// handleJobManifest serves the manifest of the library a job crawled into
+func (s *Server) handleJobManifest(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		s.writeError(w, http.StatusNotFound, errJobNotFound.Error())
+		return
+	}
+	path := filepath.Join(s.config.Output, j.spec.Library, storage.ManifestFile)
+	if _, err := os.Stat(path); err != nil {
+		s.writeError(w, http.StatusNotFound, "manifest not written yet")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	http.ServeFile(w, r, path)
+}
+
+// start queues a job and runs it once a slot is free
+func (js *jobs) start(spec queue.Job, s *Server) *job {
+	ctx, cancel := context.WithCancel(js.ctx)
+	j := &job{
+		spec:     spec,
+		cancel:   cancel,
+		progress: progress.NewProgressManager(s.logger),
+		state:    JobQueued,
+	}
+	js.mutex.Lock()
+	js.evict(time.Now().UTC())
+	js.byID[spec.ID] = j
+	js.mutex.Unlock()
+
+	js.wg.Add(1)
+	go func() {
+		defer js.wg.Done()
+		defer cancel()
+
+		select {
+		case js.slots <- struct{}{}:
+			defer func() { <-js.slots }()
+		case <-ctx.Done():
+			js.finish(j, ctx.Err())
+			return
+		}
+		if ctx.Err() != nil {
+			js.finish(j, ctx.Err())
+			return
+		}
+
+		js.mutex.Lock()
+		j.state = JobRunning
+		j.startedAt = time.Now().UTC()
+		js.mutex.Unlock()
+
+		err := js.runner(ctx, &j.spec, j.progress)
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		js.finish(j, err)
+		if err != nil {
+			s.logger.Error("Job failed", map[string]interface{}{"jobID": spec.ID, "error": err})
+		} else {
+			s.logger.Info("Job completed", map[string]interface{}{"jobID": spec.ID})
+		}
+	}()
+	return j
+}
+
+// finish records the outcome of a job
+func (js *jobs) finish(j *job, err error) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+	j.finishedAt = time.Now().UTC()
+	switch {
+	case errors.Is(err, context.Canceled):
+		j.state = JobCancelled
+	case err != nil:
+		j.state = JobFailed
+		j.err = err.Error()
+	default:
+		j.state = JobSucceeded
+	}
+	js.evict(j.finishedAt)
+}
+
+// evict forgets the jobs finished before the retention period and the
+// oldest finished jobs beyond maxFinished. It must be called with the mutex
+// held.
+func (js *jobs) evict(now time.Time) {
+	var finished []*job
+	for id, j := range js.byID {
+		switch {
+		case j.finishedAt.IsZero():
+		case now.Sub(j.finishedAt) > js.retention:
+			delete(js.byID, id)
+		default:
+			finished = append(finished, j)
+		}
+	}
+	if len(finished) <= js.maxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, k int) bool { return finished[i].finishedAt.Before(finished[k].finishedAt) })
+	for _, j := range finished[:len(finished)-js.maxFinished] {
+		delete(js.byID, j.spec.ID)
+	}
+}
+
+func (js *jobs) get(id string) (*job, bool) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+	j, ok := js.byID[id]
+	return j, ok
+}
+
+// status returns the status of a job, with the progress of its crawl
+func (js *jobs) status(j *job) JobStatus {
+	js.mutex.Lock()
+	status := JobStatus{
+		Job:   j.spec,
+		State: j.state,
+		Error: j.err,
+		Total: j.spec.MaxURLs,
+	}
+	if !j.startedAt.IsZero() {
+		startedAt := j.startedAt
+		status.StartedAt = &startedAt
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		status.FinishedAt = &finishedAt
+	}
+	js.mutex.Unlock()
+
+	if reporter, ok := j.progress.GetReporter("crawl"); ok {
+		status.Pages, status.Total = reporter.GetProgress()
+	}
+	return status
+}
+
+// newJobID returns a random identifier for a job
+func newJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
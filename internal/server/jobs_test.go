@@ -0,0 +1,301 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"crawlr/internal/config"
+	"crawlr/internal/logger"
+	"crawlr/internal/progress"
+	"crawlr/internal/queue"
+	"crawlr/internal/storage"
+)
+
+// testManifest is the manifest written by the jobs of newJobServer
+const testManifest = `{"library":"docs","pages":[]}`
+
+// newJobServer starts a server running one job at a time. Its jobs report a
+// page, write the manifest of their library, then wait for release to be
+// closed or for their cancellation; the jobs of the library "broken" fail.
+func newJobServer(t *testing.T) (server *httptest.Server, release chan struct{}) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Output = t.TempDir()
+	cfg.ServeJobs = true
+	cfg.ServeMaxJobs = 1
+	s, err := NewServer(cfg, logger.NewWithHandler(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release = make(chan struct{})
+	s.SetJobRunner(func(ctx context.Context, job *queue.Job, progress *progress.ProgressManager) error {
+		progress.CreateReporter("crawl", "Crawling URLs", job.MaxURLs).Increment()
+		if job.Library == "broken" {
+			return errors.New("crawl4ai unavailable")
+		}
+		dir := filepath.Join(cfg.Output, job.Library)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, storage.ManifestFile), []byte(testManifest), 0644); err != nil {
+			return err
+		}
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	server = httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		server.Close()
+		s.Close()
+	})
+	return server, release
+}
+
+// request sends a request to the server and decodes its JSON body into v
+func request(t *testing.T, method, url, body string, v interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: invalid body: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func submitJob(t *testing.T, server *httptest.Server, body string) JobStatus {
+	t.Helper()
+	var status JobStatus
+	if code := request(t, http.MethodPost, server.URL+"/jobs", body, &status); code != http.StatusAccepted {
+		t.Fatalf("POST /jobs = %d, want %d", code, http.StatusAccepted)
+	}
+	if status.ID == "" || status.SubmittedAt.IsZero() {
+		t.Fatalf("POST /jobs returned %+v, want an ID and a submission time", status)
+	}
+	return status
+}
+
+// waitJob polls the status of a job until it reaches state
+func waitJob(t *testing.T, server *httptest.Server, id, state string) JobStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var status JobStatus
+		if code := request(t, http.MethodGet, server.URL+"/jobs/"+id, "", &status); code != http.StatusOK {
+			t.Fatalf("GET /jobs/%s = %d, want %d", id, code, http.StatusOK)
+		}
+		if status.State == state {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", id, status.State, state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSubmitJobErrors(t *testing.T) {
+	server, _ := newJobServer(t)
+
+	tests := []struct {
+		name  string
+		body  string
+		error string
+	}{
+		{"invalid JSON", `{"url":`, "invalid job"},
+		{"unknown field", `{"url":"https://example.com","library":"docs","depth":2}`, "invalid job"},
+		{"relative URL", `{"url":"/docs","library":"docs"}`, "url must be an absolute http(s) URL"},
+		{"unsupported scheme", `{"url":"ftp://example.com","library":"docs"}`, "url must be an absolute http(s) URL"},
+		{"missing library", `{"url":"https://example.com"}`, "invalid library name"},
+		{"library path", `{"url":"https://example.com","library":"../docs"}`, "invalid library name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp errorResponse
+			code := request(t, http.MethodPost, server.URL+"/jobs", tt.body, &resp)
+			if code != http.StatusBadRequest || !strings.HasPrefix(resp.Error, tt.error) {
+				t.Errorf("POST /jobs = %d %q, want %d %q", code, resp.Error, http.StatusBadRequest, tt.error)
+			}
+		})
+	}
+
+	var list struct{ Jobs []JobStatus }
+	request(t, http.MethodGet, server.URL+"/jobs", "", &list)
+	if len(list.Jobs) != 0 {
+		t.Errorf("GET /jobs listed %d jobs, want none", len(list.Jobs))
+	}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	server, release := newJobServer(t)
+
+	first := submitJob(t, server, `{"url":"https://example.com/docs","library":"docs","max_urls":10}`)
+	running := waitJob(t, server, first.ID, JobRunning)
+	if running.StartedAt == nil || running.FinishedAt != nil {
+		t.Errorf("running job started at %v, finished at %v, want started only", running.StartedAt, running.FinishedAt)
+	}
+	if running.Pages != 1 || running.Total != 10 {
+		t.Errorf("running job processed %d/%d pages, want 1/10", running.Pages, running.Total)
+	}
+
+	// A single job runs at a time
+	second := submitJob(t, server, `{"url":"https://example.com/api","library":"api"}`)
+	if status := waitJob(t, server, second.ID, JobQueued); status.StartedAt != nil {
+		t.Errorf("queued job started at %v", status.StartedAt)
+	}
+	var resp errorResponse
+	if code := request(t, http.MethodGet, server.URL+"/jobs/"+second.ID+"/manifest", "", &resp); code != http.StatusNotFound {
+		t.Errorf("GET manifest of a queued job = %d, want %d", code, http.StatusNotFound)
+	}
+
+	res, err := http.Get(server.URL + "/jobs/" + first.ID + "/manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(manifest) != testManifest {
+		t.Errorf("GET manifest = %d %s, want %d %s", res.StatusCode, manifest, http.StatusOK, testManifest)
+	}
+
+	close(release)
+	if status := waitJob(t, server, first.ID, JobSucceeded); status.FinishedAt == nil || status.Error != "" {
+		t.Errorf("succeeded job finished at %v with error %q", status.FinishedAt, status.Error)
+	}
+	waitJob(t, server, second.ID, JobSucceeded)
+
+	var list struct{ Jobs []JobStatus }
+	if code := request(t, http.MethodGet, server.URL+"/jobs", "", &list); code != http.StatusOK {
+		t.Fatalf("GET /jobs = %d, want %d", code, http.StatusOK)
+	}
+	if len(list.Jobs) != 2 || list.Jobs[0].ID != first.ID || list.Jobs[1].ID != second.ID {
+		t.Errorf("GET /jobs = %+v, want the two jobs in submission order", list.Jobs)
+	}
+}
+
+func TestJobFailed(t *testing.T) {
+	server, _ := newJobServer(t)
+
+	job := submitJob(t, server, `{"url":"https://example.com","library":"broken"}`)
+	if status := waitJob(t, server, job.ID, JobFailed); status.Error != "crawl4ai unavailable" {
+		t.Errorf("failed job error = %q, want %q", status.Error, "crawl4ai unavailable")
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	server, _ := newJobServer(t)
+
+	running := submitJob(t, server, `{"url":"https://example.com/docs","library":"docs"}`)
+	waitJob(t, server, running.ID, JobRunning)
+	queued := submitJob(t, server, `{"url":"https://example.com/api","library":"api"}`)
+
+	for _, id := range []string{queued.ID, running.ID} {
+		var status JobStatus
+		if code := request(t, http.MethodPost, server.URL+"/jobs/"+id+"/cancel", "", &status); code != http.StatusAccepted {
+			t.Errorf("POST /jobs/%s/cancel = %d, want %d", id, code, http.StatusAccepted)
+		}
+		waitJob(t, server, id, JobCancelled)
+	}
+	if status := waitJob(t, server, queued.ID, JobCancelled); status.StartedAt != nil {
+		t.Errorf("cancelled queued job started at %v", status.StartedAt)
+	}
+}
+
+func TestJobNotFound(t *testing.T) {
+	server, _ := newJobServer(t)
+
+	for _, tt := range []struct{ method, path string }{
+		{http.MethodGet, "/jobs/unknown"},
+		{http.MethodPost, "/jobs/unknown/cancel"},
+		{http.MethodGet, "/jobs/unknown/manifest"},
+	} {
+		var resp errorResponse
+		code := request(t, tt.method, server.URL+tt.path, "", &resp)
+		if code != http.StatusNotFound || resp.Error != errJobNotFound.Error() {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, code, resp.Error, http.StatusNotFound, errJobNotFound)
+		}
+	}
+}
+
+func TestEvictJobs(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		finished    map[string]time.Duration // job ID to age; unfinished if 0
+		maxFinished int
+		want        []string
+	}{
+		{
+			name:        "within limits",
+			finished:    map[string]time.Duration{"a": time.Minute, "b": 2 * time.Minute, "running": 0},
+			maxFinished: 2,
+			want:        []string{"a", "b", "running"},
+		},
+		{
+			name:        "retention",
+			finished:    map[string]time.Duration{"recent": 59 * time.Minute, "old": 61 * time.Minute, "running": 0},
+			maxFinished: 10,
+			want:        []string{"recent", "running"},
+		},
+		{
+			name: "count cap",
+			finished: map[string]time.Duration{
+				"a": time.Minute, "b": 2 * time.Minute, "c": 3 * time.Minute, "d": 4 * time.Minute,
+				"running": 0, "queued": 0,
+			},
+			maxFinished: 2,
+			want:        []string{"a", "b", "queued", "running"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js := newJobs(nil, 1)
+			js.maxFinished = tt.maxFinished
+			for id, age := range tt.finished {
+				j := &job{spec: queue.Job{ID: id}}
+				if age > 0 {
+					j.finishedAt = now.Add(-age)
+				}
+				js.byID[id] = j
+			}
+
+			js.evict(now)
+			var got []string
+			for id := range js.byID {
+				got = append(got, id)
+			}
+			sort.Strings(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("evict() kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
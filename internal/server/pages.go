@@ -0,0 +1,195 @@
+package server
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"crawlr/internal/search"
+	"crawlr/internal/storage"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// pageTemplate wraps rendered markdown in a minimal standalone document
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} - {{.Library}}</title>
+{{- if .Canonical}}
+<link rel="canonical" href="{{.Canonical}}">
+{{- end}}
+{{- if .NoIndex}}
+<meta name="robots" content="noindex, follow">
+{{- end}}
+<style>
+body { max-width: 50rem; margin: 2rem auto; padding: 0 1rem; font-family: sans-serif; line-height: 1.6; }
+pre { overflow-x: auto; padding: 1rem; background: #f5f5f5; }
+img { max-width: 100%; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ddd; padding: 0.25rem 0.5rem; }
+</style>
+</head>
+<body>
+{{.Body}}
+</body>
+</html>
+`))
+
+// pageData is the input of pageTemplate
+type pageData struct {
+	Library   string
+	Title     string
+	Body      template.HTML
+	Canonical string
+	NoIndex   bool
+}
+
+// markdownRenderer converts stored markdown to HTML. Raw HTML embedded in the
+// markdown is dropped, since pages come from arbitrary crawled sites.
+var markdownRenderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+)
+
+func (s *Server) handlePage(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !validLibraryName(name) {
+		s.writeError(w, http.StatusBadRequest, errInvalidLibrary.Error())
+		return
+	}
+
+	pagePath := markdownPagePath(r.PathValue("path"))
+
+	stored, err := os.ReadFile(filepath.Join(s.markdownDir(name), filepath.FromSlash(pagePath)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.writeError(w, http.StatusNotFound, "page not found")
+			return
+		}
+		s.writeError(w, http.StatusInternalServerError, "failed to read page")
+		return
+	}
+	source := []byte(storage.StripFrontMatter(string(stored)))
+
+	body, err := s.renderMarkdown(name, pagePath, source)
+	if err != nil {
+		s.logger.Error("Failed to render page", map[string]interface{}{"library": name, "page": pagePath, "error": err})
+		s.writeError(w, http.StatusInternalServerError, "failed to render page")
+		return
+	}
+
+	var buf bytes.Buffer
+	data := pageData{
+		Library: name,
+		Title:   search.MarkdownTitle(string(source), pagePath),
+		Body:    template.HTML(body),
+	}
+	// Public mirrors point search engines at the origin instead of competing with it
+	if s.config.ServePublic {
+		data.Canonical = s.sourceURL(name, pagePath, stored)
+		data.NoIndex = true
+		w.Header().Set("X-Robots-Tag", "noindex, follow")
+	}
+	if err := pageTemplate.Execute(&buf, data); err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to render page")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !validLibraryName(name) {
+		s.writeError(w, http.StatusBadRequest, errInvalidLibrary.Error())
+		return
+	}
+
+	// os.DirFS rejects paths escaping the media folder
+	http.ServeFileFS(w, r, os.DirFS(s.mediaDir(name)), r.PathValue("path"))
+}
+
+// renderMarkdown converts a page to HTML, pointing image references at the
+// media files downloaded during the crawl when they exist
+func (s *Server) renderMarkdown(library, pagePath string, source []byte) ([]byte, error) {
+	doc := markdownRenderer.Parser().Parse(text.NewReader(source), parser.WithContext(parser.NewContext()))
+
+	// Relative references are resolved against the page URL path, which the
+	// markdown path mirrors
+	base := &neturl.URL{Path: "/" + strings.TrimSuffix(pagePath, ".md")}
+
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if img, ok := n.(*ast.Image); ok {
+			if local := s.localMediaURL(library, base, string(img.Destination)); local != "" {
+				img.Destination = []byte(local)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := markdownRenderer.Renderer().Render(&buf, source, doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// localMediaURL returns the serve URL of the downloaded copy of a media
+// reference, or an empty string if it was not downloaded
+func (s *Server) localMediaURL(library string, base *neturl.URL, ref string) string {
+	refURL, err := neturl.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	mediaURL := base.ResolveReference(refURL)
+	if mediaURL.Scheme != "" && mediaURL.Scheme != "http" && mediaURL.Scheme != "https" {
+		return ""
+	}
+
+	relPath := path.Clean(storage.MediaRelPath(mediaURL))
+	if relPath == "." || strings.HasPrefix(relPath, "../") {
+		return ""
+	}
+	if info, err := os.Stat(filepath.Join(s.mediaDir(library), filepath.FromSlash(relPath))); err != nil || info.IsDir() {
+		return ""
+	}
+
+	return (&neturl.URL{Path: "/libraries/" + library + "/media/" + relPath}).EscapedPath()
+}
+
+// mediaDir returns the media folder of a library
+func (s *Server) mediaDir(name string) string {
+	return filepath.Join(s.config.Output, name, "media")
+}
+
+// markdownPagePath maps the path of a page request to a markdown file relative
+// to the markdown folder, the way storage maps page URLs: "" maps to index.md
+// and the .md extension is optional
+func markdownPagePath(requestPath string) string {
+	cleaned := strings.TrimPrefix(path.Clean("/"+requestPath), "/")
+	if cleaned == "" {
+		return "index.md"
+	}
+	if !strings.HasSuffix(cleaned, ".md") {
+		cleaned += ".md"
+	}
+	return cleaned
+}
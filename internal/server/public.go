@@ -0,0 +1,139 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"crawlr/internal/storage"
+
+	"golang.org/x/time/rate"
+)
+
+// publicRobotsTxt lets search engines follow mirrored pages to their canonical
+// origin while keeping them away from the API endpoints
+const publicRobotsTxt = `User-agent: *
+Allow: /libraries/*/pages/
+Disallow: /libraries/
+Disallow: /graphql
+`
+
+// clientIdleTimeout is how long an idle client keeps its rate limiter
+const clientIdleTimeout = 10 * time.Minute
+
+// clientLimiter is the rate limiter of a single client address
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter limits the request rate of each client address
+type rateLimiter struct {
+	mutex     sync.Mutex
+	limit     rate.Limit
+	burst     int
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+// cachedManifest maps markdown paths to source URLs for a library manifest
+type cachedManifest struct {
+	modTime time.Time
+	sources map[string]string
+}
+
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	return &rateLimiter{
+		limit:     rate.Limit(requestsPerSecond),
+		burst:     max(1, int(math.Ceil(requestsPerSecond*2))),
+		clients:   make(map[string]*clientLimiter),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow reports whether a request from client may proceed
+func (rl *rateLimiter) allow(client string) bool {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(rl.lastSweep) > clientIdleTimeout {
+		for addr, c := range rl.clients {
+			if now.Sub(c.lastSeen) > clientIdleTimeout {
+				delete(rl.clients, addr)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	c, ok := rl.clients[client]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(rl.limit, rl.burst)}
+		rl.clients[client] = c
+	}
+	c.lastSeen = now
+	return c.limiter.Allow()
+}
+
+// limitRate rejects clients exceeding the configured request rate
+func (s *Server) limitRate(next http.Handler) http.Handler {
+	rl := newRateLimiter(s.config.ServeRateLimit)
+	retryAfter := fmt.Sprintf("%d", max(1, int(math.Ceil(1/s.config.ServeRateLimit))))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		if !rl.allow(client) {
+			w.Header().Set("Retry-After", retryAfter)
+			s.writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleRobots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, publicRobotsTxt)
+}
+
+// sourceURL returns the origin URL of a stored page, read from its front
+// matter or, failing that, from the library manifest
+func (s *Server) sourceURL(library, pagePath string, stored []byte) string {
+	if fm, err := storage.ParseFrontMatter(string(stored)); err == nil && fm != nil && fm.Source != "" {
+		return fm.Source
+	}
+
+	manifestPath := filepath.Join(s.config.Output, library, storage.ManifestFile)
+	info, err := os.Stat(manifestPath)
+	if err != nil {
+		return ""
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cached, ok := s.manifests[library]
+	if !ok || !cached.modTime.Equal(info.ModTime()) {
+		manifest, err := storage.LoadManifest(filepath.Join(s.config.Output, library))
+		if err != nil {
+			s.logger.Warn("Failed to load manifest", map[string]interface{}{"library": library, "error": err})
+			return ""
+		}
+		cached = cachedManifest{modTime: info.ModTime(), sources: make(map[string]string, len(manifest.Pages))}
+		for _, entry := range manifest.Pages {
+			cached.sources[strings.TrimPrefix(entry.Path, "markdown/")] = entry.URL
+		}
+		s.manifests[library] = cached
+	}
+
+	return cached.sources[pagePath]
+}
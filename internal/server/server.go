@@ -0,0 +1,262 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"crawlr/internal/config"
+	"crawlr/internal/logger"
+	"crawlr/internal/search"
+
+	"github.com/graphql-go/graphql"
+)
+
+// defaultSearchLimit is the number of hits returned when the request has no limit
+const defaultSearchLimit = 20
+
+// maxSearchLimit caps the limit parameter of search requests
+const maxSearchLimit = 100
+
+var (
+	errInvalidLibrary  = errors.New("invalid library name")
+	errLibraryNotFound = errors.New("library not found")
+	errIndexFailed     = errors.New("failed to build search index")
+)
+
+// Server exposes crawled libraries over HTTP
+type Server struct {
+	config    *config.Config
+	logger    *logger.Logger
+	mutex     sync.Mutex
+	indexes   map[string]*search.Index
+	manifests map[string]cachedManifest
+
+	graphqlSchema graphql.Schema
+	jobs          *jobs // nil unless the job endpoints are enabled
+}
+
+// LibraryInfo describes a library in listings
+type LibraryInfo struct {
+	Name  string `json:"name"`
+	Pages int    `json:"pages,omitempty"`
+}
+
+// SearchResponse is the body returned by the search endpoint
+type SearchResponse struct {
+	Library string          `json:"library"`
+	Query   string          `json:"query"`
+	Total   int             `json:"total"`
+	Results []search.Result `json:"results"`
+	TookMs  int64           `json:"took_ms"`
+}
+
+// errorResponse is the body returned for failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer creates a server serving the libraries stored under cfg.Output
+func NewServer(cfg *config.Config, logger *logger.Logger) (*Server, error) {
+	s := &Server{
+		config:    cfg,
+		logger:    logger,
+		indexes:   make(map[string]*search.Index),
+		manifests: make(map[string]cachedManifest),
+	}
+
+	if cfg.ServeGraphQL {
+		schema, err := s.newGraphQLSchema()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build GraphQL schema: %w", err)
+		}
+		s.graphqlSchema = schema
+	}
+
+	return s, nil
+}
+
+// Handler returns the HTTP handler of the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /libraries", s.handleListLibraries)
+	mux.HandleFunc("GET /libraries/{name}/search", s.handleSearch)
+	mux.HandleFunc("POST /libraries/{name}/reindex", s.handleReindex)
+	mux.HandleFunc("GET /libraries/{name}/pages/{path...}", s.handlePage)
+	mux.HandleFunc("GET /libraries/{name}/media/{path...}", s.handleMedia)
+	if s.config.ServeGraphQL {
+		mux.HandleFunc("GET /graphql", s.handleGraphQL)
+		mux.HandleFunc("POST /graphql", s.handleGraphQL)
+	}
+	if s.config.ServePublic {
+		mux.HandleFunc("GET /robots.txt", s.handleRobots)
+	}
+	if s.jobs != nil {
+		mux.HandleFunc("POST /jobs", s.handleSubmitJob)
+		mux.HandleFunc("GET /jobs", s.handleListJobs)
+		mux.HandleFunc("GET /jobs/{id}", s.handleGetJob)
+		mux.HandleFunc("POST /jobs/{id}/cancel", s.handleCancelJob)
+		mux.HandleFunc("GET /jobs/{id}/manifest", s.handleJobManifest)
+	}
+
+	var handler http.Handler = mux
+	if s.config.ServeRateLimit > 0 {
+		handler = s.limitRate(handler)
+	}
+	return s.logRequests(handler)
+}
+
+func (s *Server) handleListLibraries(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir(s.config.Output)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to list libraries")
+		return
+	}
+
+	libraries := []LibraryInfo{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(s.markdownDir(entry.Name())); err != nil {
+			continue
+		}
+		info := LibraryInfo{Name: entry.Name()}
+		s.mutex.Lock()
+		if idx, ok := s.indexes[entry.Name()]; ok {
+			info.Pages = idx.Len()
+		}
+		s.mutex.Unlock()
+		libraries = append(libraries, info)
+	}
+	sort.Slice(libraries, func(i, j int) bool { return libraries[i].Name < libraries[j].Name })
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"libraries": libraries})
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		s.writeError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			s.writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	idx, status, err := s.index(name, false)
+	if err != nil {
+		s.writeError(w, status, err.Error())
+		return
+	}
+
+	start := time.Now()
+	results := idx.Search(query, 0)
+	resp := SearchResponse{
+		Library: name,
+		Query:   query,
+		Total:   len(results),
+		Results: results,
+	}
+	if len(resp.Results) > limit {
+		resp.Results = resp.Results[:limit]
+	}
+	if resp.Results == nil {
+		resp.Results = []search.Result{}
+	}
+	resp.TookMs = time.Since(start).Milliseconds()
+
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleReindex(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	idx, status, err := s.index(name, true)
+	if err != nil {
+		s.writeError(w, status, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, LibraryInfo{Name: name, Pages: idx.Len()})
+}
+
+// index returns the search index of a library, building it on first use or
+// when rebuild is set
+func (s *Server) index(name string, rebuild bool) (*search.Index, int, error) {
+	if !validLibraryName(name) {
+		return nil, http.StatusBadRequest, errInvalidLibrary
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if idx, ok := s.indexes[name]; ok && !rebuild {
+		return idx, http.StatusOK, nil
+	}
+
+	dir := s.markdownDir(name)
+	if _, err := os.Stat(dir); err != nil {
+		return nil, http.StatusNotFound, errLibraryNotFound
+	}
+
+	idx, err := search.BuildIndex(dir)
+	if err != nil {
+		s.logger.Error("Failed to build search index", map[string]interface{}{"library": name, "error": err})
+		return nil, http.StatusInternalServerError, errIndexFailed
+	}
+	s.indexes[name] = idx
+	s.logger.Info("Built search index", map[string]interface{}{"library": name, "pages": idx.Len()})
+	return idx, http.StatusOK, nil
+}
+
+// markdownDir returns the markdown folder of a library
+func (s *Server) markdownDir(name string) string {
+	return filepath.Join(s.config.Output, name, "markdown")
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	// Snippets carry <mark> tags meant to be embedded as-is
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(body); err != nil {
+		s.logger.Warn("Failed to write response", map[string]interface{}{"error": err})
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeJSON(w, status, errorResponse{Error: message})
+}
+
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		s.logger.Debug("Handled request", map[string]interface{}{
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"duration": time.Since(start).Round(time.Microsecond),
+		})
+	})
+}
+
+// validLibraryName rejects names that would escape the output folder
+func validLibraryName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
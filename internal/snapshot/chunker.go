@@ -0,0 +1,76 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+)
+
+// Chunk sizes of the content-defined chunker. Cut points are found with a
+// gear rolling hash, so an edit only changes the chunks around it and the
+// rest of a file dedups against earlier snapshots.
+const (
+	minChunkSize = 2 << 10
+	maxChunkSize = 64 << 10
+	chunkMask    = 1<<13 - 1 // 8 KiB average chunks
+)
+
+// gearTable maps bytes to the random values of the gear hash. It is derived
+// from SHA-256 so that cut points never change between versions.
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+	for i := range table {
+		sum := sha256.Sum256([]byte{byte(i)})
+		table[i] = binary.LittleEndian.Uint64(sum[:8])
+	}
+	return table
+}()
+
+// chunker splits a stream into content-defined chunks
+type chunker struct {
+	r   io.Reader
+	buf []byte
+	n   int // bytes buffered
+	eof bool
+}
+
+func newChunker(r io.Reader) *chunker {
+	return &chunker{r: r, buf: make([]byte, maxChunkSize)}
+}
+
+// next returns the next chunk, or io.EOF at the end of the stream
+func (c *chunker) next() ([]byte, error) {
+	if !c.eof && c.n < len(c.buf) {
+		read, err := io.ReadFull(c.r, c.buf[c.n:])
+		c.n += read
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			c.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if c.n == 0 {
+		return nil, io.EOF
+	}
+
+	cut := cutPoint(c.buf[:c.n])
+	chunk := make([]byte, cut)
+	copy(chunk, c.buf[:cut])
+	c.n = copy(c.buf, c.buf[cut:c.n])
+	return chunk, nil
+}
+
+// cutPoint returns the length of the first chunk of data
+func cutPoint(data []byte) int {
+	if len(data) <= minChunkSize {
+		return len(data)
+	}
+	var hash uint64
+	for i := minChunkSize; i < len(data); i++ {
+		hash = hash<<1 + gearTable[data[i]]
+		if hash&chunkMask == 0 {
+			return i + 1
+		}
+	}
+	return len(data)
+}
@@ -0,0 +1,110 @@
+package snapshot
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// randomBytes returns n pseudo-random bytes, the same for a given seed
+func randomBytes(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+// chunks splits data with the chunker
+func chunks(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	var result [][]byte
+	c := newChunker(bytes.NewReader(data))
+	for {
+		chunk, err := c.next()
+		if err == io.EOF {
+			return result
+		}
+		if err != nil {
+			t.Fatalf("next() error = %v", err)
+		}
+		result = append(result, chunk)
+	}
+}
+
+func TestChunkerSizes(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		chunks int // expected number of chunks, 0 to only check sizes
+	}{
+		{"empty", nil, 0},
+		{"shorter than a chunk", randomBytes(1, minChunkSize), 1},
+		{"random", randomBytes(2, 1<<20), 0},
+		{"without cut points", make([]byte, 3*maxChunkSize+100), 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chunks(t, tt.data)
+			if tt.chunks > 0 && len(got) != tt.chunks {
+				t.Errorf("got %d chunks, want %d", len(got), tt.chunks)
+			}
+			for i, chunk := range got {
+				if len(chunk) > maxChunkSize {
+					t.Errorf("chunk %d is %d bytes, above the maximum %d", i, len(chunk), maxChunkSize)
+				}
+				if i < len(got)-1 && len(chunk) <= minChunkSize {
+					t.Errorf("chunk %d is %d bytes, not above the minimum %d", i, len(chunk), minChunkSize)
+				}
+			}
+			if joined := bytes.Join(got, nil); !bytes.Equal(joined, tt.data) {
+				t.Errorf("chunks join into %d bytes differing from the %d bytes of input", len(joined), len(tt.data))
+			}
+		})
+	}
+}
+
+func TestChunkerStableAfterInsertion(t *testing.T) {
+	original := randomBytes(3, 512<<10)
+	offset := 200 << 10
+	edited := append(append(append([]byte{}, original[:offset]...), randomBytes(4, 100)...), original[offset:]...)
+
+	before := chunks(t, original)
+	after := chunks(t, edited)
+	if len(before) < 20 {
+		t.Fatalf("got %d chunks of %d bytes, too few for the test", len(before), len(original))
+	}
+
+	known := make(map[string]bool)
+	for _, chunk := range after {
+		known[string(chunk)] = true
+	}
+	var changed int
+	for _, chunk := range before {
+		if !known[string(chunk)] {
+			changed++
+		}
+	}
+	// Only the chunk holding the insertion, and at worst the next one when the
+	// cut point after it moved, differ
+	if changed > 2 {
+		t.Errorf("%d of %d chunks changed after inserting 100 bytes, want at most 2", changed, len(before))
+	}
+	if changed == 0 {
+		t.Error("no chunk changed after inserting 100 bytes")
+	}
+}
+
+func TestCutPoint(t *testing.T) {
+	data := randomBytes(5, maxChunkSize)
+	cut := cutPoint(data)
+	if cut <= minChunkSize || cut > len(data) {
+		t.Fatalf("cutPoint() = %d, want within (%d, %d]", cut, minChunkSize, len(data))
+	}
+	// The cut point only depends on the bytes before it
+	if got := cutPoint(data[:cut]); got != cut {
+		t.Errorf("cutPoint() of the first chunk = %d, want %d", got, cut)
+	}
+	if got := cutPoint(append(data[:cut:cut], randomBytes(6, 4096)...)); got != cut {
+		t.Errorf("cutPoint() with other trailing bytes = %d, want %d", got, cut)
+	}
+}
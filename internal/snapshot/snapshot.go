@@ -0,0 +1,398 @@
+// Package snapshot keeps point-in-time copies of a library in a
+// content-addressed store. Files are split into content-defined chunks stored
+// once under their SHA-256, and each snapshot is a manifest listing the chunks
+// of its files, so snapshots of a mostly unchanged site take little space.
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	chunksDir    = "chunks"
+	snapshotsDir = "snapshots"
+	idFormat     = "20060102T150405Z"
+)
+
+// Store is a directory of chunks and snapshot manifests
+type Store struct {
+	dir string
+}
+
+// Open returns the store in dir. The directory is created on the first
+// snapshot.
+func Open(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// DefaultDir returns the store directory of a library: <library>.snapshots
+// next to it
+func DefaultDir(libraryPath string) string {
+	return filepath.Clean(libraryPath) + ".snapshots"
+}
+
+// Snapshot is the manifest of a snapshot
+type Snapshot struct {
+	ID        string    `json:"id"`
+	Library   string    `json:"library"`
+	CreatedAt time.Time `json:"created_at"`
+	Files     []File    `json:"files"`
+}
+
+// File is a file of a snapshot with the chunks of its content, in order
+type File struct {
+	Path   string   `json:"path"` // relative to the library, slash-separated
+	Size   int64    `json:"size"`
+	Hash   string   `json:"hash"` // SHA-256 of the whole file
+	Chunks []string `json:"chunks"`
+}
+
+// Size returns the total size of the files of the snapshot
+func (s *Snapshot) Size() int64 {
+	var size int64
+	for _, file := range s.Files {
+		size += file.Size
+	}
+	return size
+}
+
+// CreateResult summarizes a new snapshot
+type CreateResult struct {
+	Snapshot  *Snapshot
+	Chunks    int   // chunks of the snapshot
+	NewChunks int   // chunks not stored by an earlier snapshot
+	Stored    int64 // compressed bytes of the new chunks
+}
+
+// Create snapshots the library at libraryPath. Leftovers of interrupted
+// writes (dot files) are skipped.
+func (s *Store) Create(libraryPath string) (*CreateResult, error) {
+	if err := os.MkdirAll(filepath.Join(s.dir, snapshotsDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot store: %w", err)
+	}
+
+	now := time.Now().UTC()
+	snap := &Snapshot{ID: s.newID(now), Library: filepath.Base(libraryPath), CreatedAt: now, Files: []File{}}
+	result := &CreateResult{Snapshot: snap}
+	err := filepath.WalkDir(libraryPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		relPath, err := filepath.Rel(libraryPath, path)
+		if err != nil {
+			return err
+		}
+		file, err := s.storeFile(path, result)
+		if err != nil {
+			return fmt.Errorf("failed to store %s: %w", relPath, err)
+		}
+		file.Path = filepath.ToSlash(relPath)
+		snap.Files = append(snap.Files, *file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot library: %w", err)
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if err := writeFileAtomic(s.snapshotPath(snap.ID), append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return result, nil
+}
+
+// newID returns an unused snapshot ID for the time now
+func (s *Store) newID(now time.Time) string {
+	id := now.Format(idFormat)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(s.snapshotPath(id)); os.IsNotExist(err) {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", now.Format(idFormat), i)
+	}
+}
+
+// storeFile chunks a file, storing the chunks not already in the store
+func (s *Store) storeFile(path string, result *CreateResult) (*File, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	file := &File{Chunks: []string{}}
+	hasher := sha256.New()
+	chunks := newChunker(io.TeeReader(in, hasher))
+	for {
+		chunk, err := chunks.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(chunk)
+		hash := hex.EncodeToString(sum[:])
+		stored, err := s.putChunk(hash, chunk)
+		if err != nil {
+			return nil, err
+		}
+		if stored > 0 {
+			result.NewChunks++
+			result.Stored += stored
+		}
+		result.Chunks++
+		file.Chunks = append(file.Chunks, hash)
+		file.Size += int64(len(chunk))
+	}
+	file.Hash = hex.EncodeToString(hasher.Sum(nil))
+	return file, nil
+}
+
+// putChunk stores a gzip-compressed chunk under its hash, unless the store
+// already has it. It returns the bytes written.
+func (s *Store) putChunk(hash string, chunk []byte) (int64, error) {
+	path := s.chunkPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return 0, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(chunk); err != nil {
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, err
+	}
+	if err := writeFileAtomic(path, compressed.Bytes()); err != nil {
+		return 0, err
+	}
+	return int64(compressed.Len()), nil
+}
+
+// readChunk returns the content of a stored chunk
+func (s *Store) readChunk(hash string) ([]byte, error) {
+	file, err := os.Open(s.chunkPath(hash))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt chunk %s: %w", hash, err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt chunk %s: %w", hash, err)
+	}
+	return data, nil
+}
+
+func (s *Store) chunkPath(hash string) string {
+	return filepath.Join(s.dir, chunksDir, hash[:2], hash)
+}
+
+func (s *Store) snapshotPath(id string) string {
+	return filepath.Join(s.dir, snapshotsDir, id+".json")
+}
+
+// List returns the snapshots of the store, oldest first
+func (s *Store) List() ([]*Snapshot, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, snapshotsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var snapshots []*Snapshot
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || strings.HasPrefix(id, ".") {
+			continue
+		}
+		snap, err := s.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snap)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Get reads the manifest of a snapshot
+func (s *Store) Get(id string) (*Snapshot, error) {
+	data, err := os.ReadFile(s.snapshotPath(id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("snapshot %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", id, err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", id, err)
+	}
+	return &snap, nil
+}
+
+// Restore writes the files of a snapshot to dest, which must not exist or be
+// empty. The content of every file is checked against its hash.
+func (s *Store) Restore(id string, dest string) error {
+	snap, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if entries, err := os.ReadDir(dest); err == nil && len(entries) > 0 {
+		return fmt.Errorf("restore destination %s is not empty", dest)
+	}
+
+	for _, file := range snap.Files {
+		if err := s.restoreFile(file, filepath.Join(dest, filepath.FromSlash(file.Path))); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", file.Path, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) restoreFile(file File, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	hasher := sha256.New()
+	for _, hash := range file.Chunks {
+		chunk, err := s.readChunk(hash)
+		if err != nil {
+			return err
+		}
+		hasher.Write(chunk)
+		if _, err := out.Write(chunk); err != nil {
+			return err
+		}
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != file.Hash {
+		return fmt.Errorf("content does not match its hash")
+	}
+	return out.Close()
+}
+
+// PruneResult summarizes a pruning of the store
+type PruneResult struct {
+	Removed []string // IDs of the removed snapshots
+	Chunks  int      // chunks no remaining snapshot references
+	Freed   int64    // bytes of those chunks
+}
+
+// Prune removes all but the keep most recent snapshots, then the chunks no
+// remaining snapshot references. With dryRun, nothing is removed and the
+// result lists what would be.
+func (s *Store) Prune(keep int, dryRun bool) (*PruneResult, error) {
+	snapshots, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PruneResult{}
+	referenced := make(map[string]bool)
+	for i, snap := range snapshots {
+		if i < len(snapshots)-keep {
+			result.Removed = append(result.Removed, snap.ID)
+			continue
+		}
+		for _, file := range snap.Files {
+			for _, hash := range file.Chunks {
+				referenced[hash] = true
+			}
+		}
+	}
+	if !dryRun {
+		for _, id := range result.Removed {
+			if err := os.Remove(s.snapshotPath(id)); err != nil {
+				return nil, fmt.Errorf("failed to remove snapshot %s: %w", id, err)
+			}
+		}
+	}
+
+	chunks := filepath.Join(s.dir, chunksDir)
+	err = filepath.WalkDir(chunks, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == chunks {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		// Skip directories and leftovers of interrupted writes
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") || referenced[d.Name()] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		result.Chunks++
+		result.Freed += info.Size()
+		if dryRun {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove unreferenced chunks: %w", err)
+	}
+	return result, nil
+}
+
+// writeFileAtomic writes data to a temporary file renamed over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,103 @@
+package snapshot
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateRestoreRoundTrip(t *testing.T) {
+	library := filepath.Join(t.TempDir(), "docs")
+	files := map[string][]byte{
+		"manifest.json":           []byte(`{"library": "docs"}`),
+		"markdown/index.md":       []byte("# Index\n"),
+		"markdown/guide/large.md": randomBytes(7, 300<<10),
+		"media/empty.png":         {},
+	}
+	for path, content := range files {
+		writeFile(t, filepath.Join(library, filepath.FromSlash(path)), content)
+	}
+	writeFile(t, filepath.Join(library, "markdown", ".index.md.tmp-1"), []byte("partial write"))
+
+	store := Open(DefaultDir(library))
+	first, err := store.Create(library)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(first.Snapshot.Files) != len(files) {
+		t.Errorf("snapshot holds %d files, want %d", len(first.Snapshot.Files), len(files))
+	}
+
+	// An edit in the large file only stores the chunks around it
+	edited := append([]byte{}, files["markdown/guide/large.md"]...)
+	copy(edited[150<<10:], "an edited line")
+	writeFile(t, filepath.Join(library, "markdown", "guide", "large.md"), edited)
+	second, err := store.Create(library)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if second.NewChunks == 0 || second.NewChunks > 2 {
+		t.Errorf("second snapshot stored %d new chunks, want 1 or 2", second.NewChunks)
+	}
+
+	for _, tt := range []struct {
+		id   string
+		want map[string][]byte
+	}{
+		{first.Snapshot.ID, files},
+		{second.Snapshot.ID, withFile(files, "markdown/guide/large.md", edited)},
+	} {
+		dest := filepath.Join(t.TempDir(), "restored")
+		if err := store.Restore(tt.id, dest); err != nil {
+			t.Fatalf("Restore(%s) error = %v", tt.id, err)
+		}
+		for path, want := range tt.want {
+			got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(path)))
+			if err != nil {
+				t.Errorf("Restore(%s): %v", tt.id, err)
+				continue
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Restore(%s): %s differs from the snapshotted file", tt.id, path)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dest, "markdown", ".index.md.tmp-1")); !os.IsNotExist(err) {
+			t.Errorf("Restore(%s) restored a leftover of an interrupted write", tt.id)
+		}
+	}
+}
+
+func TestRestoreNonEmptyDestination(t *testing.T) {
+	library := filepath.Join(t.TempDir(), "docs")
+	writeFile(t, filepath.Join(library, "index.md"), []byte("# Index\n"))
+	store := Open(DefaultDir(library))
+	result, err := store.Create(library)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Restore(result.Snapshot.ID, library); err == nil {
+		t.Error("Restore() into a non-empty directory succeeded")
+	}
+}
+
+// writeFile writes a file, creating its directory
+func writeFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// withFile returns a copy of files with the content of path replaced
+func withFile(files map[string][]byte, path string, content []byte) map[string][]byte {
+	result := make(map[string][]byte, len(files))
+	for name, data := range files {
+		result[name] = data
+	}
+	result[path] = content
+	return result
+}
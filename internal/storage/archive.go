@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteArchive packs the library directory into a single archive next to it,
+// named after the library with the extension of the format, and returns the
+// path of the archive. Entries are stored under the library name.
+func (s *Storage) WriteArchive(format string) (string, error) {
+	return ArchiveDirectory(s.libraryPath, format)
+}
+
+// ArchiveDirectory packs dir into a single archive next to it, named after
+// the directory with the extension of the format (zip or tar.gz), and returns
+// the path of the archive. Entries are stored under the directory name.
+func ArchiveDirectory(dir string, format string) (string, error) {
+	archivePath := dir + "." + format
+	tmpPath := archivePath + ".tmp"
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	switch format {
+	case "zip":
+		err = writeZip(file, dir)
+	case "tar.gz":
+		err = writeTarGz(file, dir)
+	default:
+		err = fmt.Errorf("unsupported archive format %q", format)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+
+	if err := os.Rename(tmpPath, archivePath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to replace archive: %w", err)
+	}
+	return archivePath, nil
+}
+
+// RemoveLibrary deletes the library directory, once it has been archived
+func (s *Storage) RemoveLibrary() error {
+	return os.RemoveAll(s.libraryPath)
+}
+
+// walkArchived calls fn for every regular file of dir with its path in the
+// archive
+func walkArchived(dir string, fn func(path string, name string, info fs.FileInfo) error) error {
+	base := filepath.Dir(dir)
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		return fn(path, filepath.ToSlash(rel), info)
+	})
+}
+
+func writeZip(w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+	err := walkArchived(dir, func(path string, name string, info fs.FileInfo) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		header.Method = zip.Deflate
+		entry, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		return copyFile(entry, path)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write zip archive: %w", err)
+	}
+	return zw.Close()
+}
+
+func writeTarGz(w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := walkArchived(dir, func(path string, name string, info fs.FileInfo) error {
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		return copyFile(tw, path)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write tar archive: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// copyFile copies the content of the file at path to w
+func copyFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
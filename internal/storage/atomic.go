@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes data to path through a temporary file in the same
+// directory that is renamed into place, so that readers and crashes never see
+// a partial file. With durable, the file and its directory are flushed to
+// disk before returning.
+func writeFileAtomic(path string, data []byte, durable bool) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	return commitTemp(tmp, path, durable)
+}
+
+// commitTemp closes a fully written temporary file and renames it to path,
+// flushing both to disk first with durable. The temporary file is removed if
+// anything fails.
+func commitTemp(tmp *os.File, path string, durable bool) error {
+	tmpPath := tmp.Name()
+	// Temporary files are private; library files are shared
+	err := tmp.Chmod(0644)
+	if err == nil && durable {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to move file into place: %w", err)
+	}
+	if durable {
+		if err := syncDir(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("failed to flush directory: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runFiles are the files a crawl leaves at the top of the library besides its
+// content: the state of a cancelled crawl and the reports of the last runs
+var runFiles = []string{
+	CheckpointFile,
+	PreviousManifestFile,
+	CrawlTreeFile,
+	SitemapCoverageFile,
+}
+
+// CleanLibrary removes the files earlier crawls left in the library stored at
+// libraryPath besides its content, such as the checkpoint of a cancelled
+// crawl, and returns their names. With all, the whole library is removed.
+// With dryRun, nothing is removed and the result lists what would be.
+func CleanLibrary(libraryPath string, all bool, dryRun bool) ([]string, error) {
+	if all {
+		if _, err := os.Stat(libraryPath); err != nil {
+			return nil, err
+		}
+		if !dryRun {
+			if err := os.RemoveAll(libraryPath); err != nil {
+				return nil, fmt.Errorf("failed to remove library: %w", err)
+			}
+		}
+		return []string{libraryPath}, nil
+	}
+
+	var removed []string
+	for _, name := range runFiles {
+		path := filepath.Join(libraryPath, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		removed = append(removed, name)
+		if dryRun {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// PageChange describes a page added, removed or changed between two crawls
+type PageChange struct {
+	URL          string `json:"url"`
+	Path         string `json:"path"`
+	PreviousHash string `json:"previous_hash,omitempty"`
+	Hash         string `json:"hash,omitempty"`
+	PreviousSize int64  `json:"previous_size,omitempty"`
+	Size         int64  `json:"size,omitempty"`
+}
+
+// ManifestDiff lists the pages that differ between two manifests of a
+// library. Pages are matched by URL and compared by content hash.
+type ManifestDiff struct {
+	Library           string       `json:"library"`
+	PreviousUpdatedAt time.Time    `json:"previous_updated_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+	Added             []PageChange `json:"added"`
+	Removed           []PageChange `json:"removed"`
+	Changed           []PageChange `json:"changed"`
+	Unchanged         int          `json:"unchanged"`
+}
+
+// Empty reports whether no page differs
+func (d *ManifestDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffManifests compares the pages of the previous and current manifests
+func DiffManifests(previous *Manifest, current *Manifest) *ManifestDiff {
+	diff := &ManifestDiff{
+		Library:           current.Library,
+		PreviousUpdatedAt: previous.UpdatedAt,
+		UpdatedAt:         current.UpdatedAt,
+		Added:             []PageChange{},
+		Removed:           []PageChange{},
+		Changed:           []PageChange{},
+	}
+
+	before := make(map[string]ManifestEntry, len(previous.Pages))
+	for _, entry := range previous.Pages {
+		before[entry.URL] = entry
+	}
+
+	seen := make(map[string]bool, len(current.Pages))
+	for _, entry := range current.Pages {
+		seen[entry.URL] = true
+		old, ok := before[entry.URL]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, PageChange{URL: entry.URL, Path: entry.Path, Hash: entry.Hash, Size: entry.Size})
+		case old.Hash != entry.Hash:
+			diff.Changed = append(diff.Changed, PageChange{
+				URL:          entry.URL,
+				Path:         entry.Path,
+				PreviousHash: old.Hash,
+				Hash:         entry.Hash,
+				PreviousSize: old.Size,
+				Size:         entry.Size,
+			})
+		default:
+			diff.Unchanged++
+		}
+	}
+	for _, entry := range previous.Pages {
+		if !seen[entry.URL] {
+			diff.Removed = append(diff.Removed, PageChange{URL: entry.URL, Path: entry.Path, PreviousHash: entry.Hash, PreviousSize: entry.Size})
+		}
+	}
+
+	for _, changes := range [][]PageChange{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].URL < changes[j].URL })
+	}
+	return diff
+}
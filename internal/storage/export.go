@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PagesExportFile is the name of the JSONL export inside the library
+const PagesExportFile = "pages.jsonl"
+
+// PageRecord is a single crawled page as written to the JSONL export
+type PageRecord struct {
+	URL       string                 `json:"url"`
+	Title     string                 `json:"title,omitempty"`
+	Markdown  string                 `json:"markdown"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	CrawledAt time.Time              `json:"crawled_at"`
+	SavedAt   time.Time              `json:"saved_at"`
+}
+
+// GetExportPath returns the path of the JSONL export for the library
+func (s *Storage) GetExportPath() string {
+	return filepath.Join(s.libraryPath, PagesExportFile)
+}
+
+// AppendPageRecord appends a page as one JSON line to the library export.
+// The export file is opened on first use and kept open until Close.
+func (s *Storage) AppendPageRecord(record *PageRecord) error {
+	s.exportMutex.Lock()
+	defer s.exportMutex.Unlock()
+
+	if s.exportFile == nil {
+		file, err := os.OpenFile(s.GetExportPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open export file: %w", err)
+		}
+		s.exportFile = file
+	}
+
+	if record.SavedAt.IsZero() {
+		record.SavedAt = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal page record: %w", err)
+	}
+
+	// A single write per line keeps records intact when several crawls append concurrently
+	if _, err := s.exportFile.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write page record: %w", err)
+	}
+
+	return nil
+}
+
+// Close writes the manifest and releases any files held open by the storage
+func (s *Storage) Close() error {
+	err := s.SaveManifest()
+	if warcErr := s.closeWARC(); err == nil {
+		err = warcErr
+	}
+
+	s.exportMutex.Lock()
+	defer s.exportMutex.Unlock()
+
+	if s.exportFile != nil {
+		if closeErr := s.exportFile.Close(); err == nil {
+			err = closeErr
+		}
+		s.exportFile = nil
+	}
+	return err
+}
+
+// ExportResult summarizes a filtered export of a library
+type ExportResult struct {
+	Pages int   // pages exported
+	Media int   // media objects exported with them
+	Files int   // files written, manifest included
+	Bytes int64 // bytes written
+}
+
+// pageSidecarExts are the extensions of the files stored next to the markdown
+// of a page
+var pageSidecarExts = []string{".html", ".cleaned.html", ".json", mediaLinksExt}
+
+// ExportPages copies the pages of the library at libraryPath selected by match
+// into dest, which must not exist or be empty, with their HTML, crawl result,
+// extraction and media link files. With the cas media layout, the media
+// objects linked by the pages are copied too. dest gets a manifest listing
+// the exported files only. Pages recorded without tags fall back to the tags
+// of their front matter.
+func ExportPages(libraryPath string, dest string, match func(ManifestEntry) bool) (*ExportResult, error) {
+	manifest, err := LoadManifest(libraryPath)
+	if err != nil {
+		return nil, err
+	}
+	if entries, err := os.ReadDir(dest); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("export destination %s is not empty", dest)
+	}
+
+	result := &ExportResult{}
+	exported := &Manifest{Library: manifest.Library, UpdatedAt: time.Now().UTC(), Pages: []ManifestEntry{}, Media: []ManifestEntry{}}
+	objects := make(map[string]bool)
+	copyToDest := func(relPath string) (bool, error) {
+		src := filepath.Join(libraryPath, filepath.FromSlash(relPath))
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			return false, nil
+		}
+		target := filepath.Join(dest, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return false, err
+		}
+		out, err := os.Create(target)
+		if err != nil {
+			return false, err
+		}
+		err = copyFile(out, src)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to copy %s: %w", relPath, err)
+		}
+		if info, err := os.Stat(target); err == nil {
+			result.Bytes += info.Size()
+		}
+		result.Files++
+		return true, nil
+	}
+
+	for _, entry := range manifest.Pages {
+		if len(entry.Tags) == 0 {
+			entry.Tags = frontMatterTags(filepath.Join(libraryPath, filepath.FromSlash(entry.Path)))
+		}
+		if !match(entry) {
+			continue
+		}
+		if ok, err := copyToDest(entry.Path); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		result.Pages++
+		exported.Pages = append(exported.Pages, entry)
+
+		base := strings.TrimSuffix(entry.Path, ".md")
+		related := []string{"extracted/" + strings.TrimPrefix(base, "markdown/") + ".json"}
+		for _, ext := range pageSidecarExts {
+			related = append(related, base+ext)
+		}
+		for _, relPath := range related {
+			if _, err := copyToDest(relPath); err != nil {
+				return nil, err
+			}
+		}
+
+		// Media objects linked by the page, with the cas layout
+		data, err := os.ReadFile(filepath.Join(libraryPath, filepath.FromSlash(base+mediaLinksExt)))
+		if err != nil {
+			continue
+		}
+		var links MediaLinks
+		if err := json.Unmarshal(data, &links); err != nil {
+			return nil, fmt.Errorf("invalid media link file of %s: %w", entry.URL, err)
+		}
+		for _, link := range links.Media {
+			objects[link.Object] = true
+		}
+	}
+
+	// Objects are shared by media URLs of the same content: each is copied
+	// once, and listed with all its URLs
+	copied := make(map[string]bool)
+	for _, entry := range manifest.Media {
+		if !objects[entry.Path] {
+			continue
+		}
+		if !copied[entry.Path] {
+			ok, err := copyToDest(entry.Path)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			copied[entry.Path] = true
+			result.Media++
+		}
+		exported.Media = append(exported.Media, entry)
+	}
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	if err := writeManifest(dest, exported, false); err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(filepath.Join(dest, ManifestFile)); err == nil {
+		result.Bytes += info.Size()
+	}
+	result.Files++
+	return result, nil
+}
+
+// frontMatterTags returns the tags of the front matter of a markdown file, if any
+func frontMatterTags(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	fm, err := ParseFrontMatter(string(data))
+	if err != nil || fm == nil {
+		return nil
+	}
+	return fm.Tags
+}
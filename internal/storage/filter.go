@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// PageFilter selects pages of a library by tag, URL path or URL. Filters are
+// boolean expressions of terms:
+//
+//	tag:api                 pages tagged api (case-insensitive)
+//	path:/reference/*       pages whose URL path matches the glob
+//	url:https://*.example.com/*
+//
+// combined with AND, OR, NOT and parentheses. Adjacent terms are ANDed, and
+// values with spaces are quoted. In globs, * matches any run of characters,
+// slashes included, and ? a single character.
+type PageFilter struct {
+	root filterNode
+}
+
+// filterNode is a node of a parsed filter expression
+type filterNode interface {
+	match(entry *ManifestEntry) bool
+}
+
+type andNode struct{ left, right filterNode }
+type orNode struct{ left, right filterNode }
+type notNode struct{ node filterNode }
+
+type tagNode struct{ tag string }
+type globNode struct {
+	field   string // "path" or "url"
+	pattern *regexp.Regexp
+}
+
+func (n andNode) match(e *ManifestEntry) bool { return n.left.match(e) && n.right.match(e) }
+func (n orNode) match(e *ManifestEntry) bool  { return n.left.match(e) || n.right.match(e) }
+func (n notNode) match(e *ManifestEntry) bool { return !n.node.match(e) }
+
+func (n tagNode) match(e *ManifestEntry) bool {
+	for _, tag := range e.Tags {
+		if strings.EqualFold(tag, n.tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n globNode) match(e *ManifestEntry) bool {
+	if n.field == "url" {
+		return n.pattern.MatchString(e.URL)
+	}
+	path := "/"
+	if u, err := url.Parse(e.URL); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	return n.pattern.MatchString(path)
+}
+
+// Match reports whether a page entry is selected by the filter
+func (f *PageFilter) Match(entry ManifestEntry) bool {
+	return f.root.match(&entry)
+}
+
+// ParsePageFilter parses a filter expression
+func ParsePageFilter(expr string) (*PageFilter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("filter is empty")
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter", p.tokens[p.pos])
+	}
+	return &PageFilter{root: root}, nil
+}
+
+// tokenizeFilter splits a filter expression into parentheses and words,
+// unquoting quoted values
+func tokenizeFilter(expr string) ([]string, error) {
+	var tokens []string
+	var word strings.Builder
+	inWord := false
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			flush()
+		case (c == '(' || c == ')') && !inWord:
+			tokens = append(tokens, string(c))
+		case c == ')':
+			flush()
+			tokens = append(tokens, ")")
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in filter")
+			}
+			word.WriteString(expr[i+1 : i+1+end])
+			inWord = true
+			i += end + 1
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+// filterParser is a recursive descent parser of filter expressions: OR binds
+// looser than AND, which binds looser than NOT
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		next := p.peek()
+		if next == "" || next == ")" || strings.EqualFold(next, "OR") {
+			return left, nil
+		}
+		if strings.EqualFold(next, "AND") {
+			p.pos++
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if strings.EqualFold(p.peek(), "NOT") {
+		p.pos++
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *filterParser) parseTerm() (filterNode, error) {
+	token := p.peek()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of filter")
+	case token == ")" || strings.EqualFold(token, "AND") || strings.EqualFold(token, "OR"):
+		return nil, fmt.Errorf("unexpected %q in filter", token)
+	}
+	p.pos++
+
+	if token == "(" {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ) in filter")
+		}
+		p.pos++
+		return node, nil
+	}
+
+	field, value, ok := strings.Cut(token, ":")
+	if !ok || value == "" {
+		return nil, fmt.Errorf("invalid filter term %q, expected tag:, path: or url: followed by a value", token)
+	}
+	switch strings.ToLower(field) {
+	case "tag":
+		return tagNode{tag: value}, nil
+	case "path", "url":
+		return globNode{field: strings.ToLower(field), pattern: globPattern(value)}, nil
+	default:
+		return nil, fmt.Errorf("unknown filter field %q (use tag, path or url)", field)
+	}
+}
+
+// globPattern compiles a glob where * matches any run of characters and ?
+// a single character
+func globPattern(glob string) *regexp.Regexp {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			pattern.WriteString(".*")
+		case '?':
+			pattern.WriteString(".")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	pattern.WriteString("$")
+	return regexp.MustCompile(pattern.String())
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestPageFilterMatch(t *testing.T) {
+	entries := []ManifestEntry{
+		{URL: "https://docs.example.com/", Tags: []string{"home"}},
+		{URL: "https://docs.example.com/reference/api", Tags: []string{"API", "reference"}},
+		{URL: "https://docs.example.com/reference/cli", Tags: []string{"reference"}},
+		{URL: "https://docs.example.com/guide/install", Tags: []string{"guide"}},
+		{URL: "https://blog.example.com/release notes", Tags: []string{"blog"}},
+	}
+	tests := []struct {
+		expr string
+		want []string // paths of the selected pages
+	}{
+		{"tag:reference", []string{"/reference/api", "/reference/cli"}},
+		{"tag:api", []string{"/reference/api"}},
+		{"path:/reference/*", []string{"/reference/api", "/reference/cli"}},
+		{"path:/", []string{"/"}},
+		{"path:/guide/???????", []string{"/guide/install"}},
+		{"url:https://blog.example.com/*", []string{"/release notes"}},
+		{`path:"/release notes"`, []string{"/release notes"}},
+		{"path:'/release notes'", []string{"/release notes"}},
+		{"NOT tag:reference", []string{"/", "/guide/install", "/release notes"}},
+		{"NOT NOT tag:guide", []string{"/guide/install"}},
+		{"not tag:reference and not tag:home", []string{"/guide/install", "/release notes"}},
+		{"tag:reference path:*/api", []string{"/reference/api"}},
+		{"tag:reference AND path:*/cli", []string{"/reference/cli"}},
+		{"tag:home OR tag:guide", []string{"/", "/guide/install"}},
+		// AND binds tighter than OR, and NOT tighter than AND
+		{"tag:home OR tag:reference AND path:*/cli", []string{"/", "/reference/cli"}},
+		{"(tag:home OR tag:reference) AND path:*/cli", []string{"/reference/cli"}},
+		{"NOT tag:reference AND tag:guide", []string{"/guide/install"}},
+		{"NOT (tag:reference OR tag:home)", []string{"/guide/install", "/release notes"}},
+		{"(tag:home)", []string{"/"}},
+		{"TAG:Home", []string{"/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := ParsePageFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("ParsePageFilter() error = %v", err)
+			}
+			var got []string
+			for _, entry := range entries {
+				if filter.Match(entry) {
+					got = append(got, entryPath(entry))
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("selected %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// entryPath returns the URL path of a page entry
+func entryPath(entry ManifestEntry) string {
+	u, err := url.Parse(entry.URL)
+	if err != nil || u.Path == "" {
+		return "/"
+	}
+	return u.Path
+}
+
+func TestParsePageFilterErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"   ",
+		"api",
+		"tag:",
+		"size:10",
+		`path:"/unterminated`,
+		"(tag:api",
+		"tag:api)",
+		"()",
+		"tag:api AND",
+		"OR tag:api",
+		"tag:api OR OR tag:cli",
+		"NOT",
+		"AND tag:api",
+	}
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParsePageFilter(expr); err == nil {
+				t.Errorf("ParsePageFilter(%q) succeeded, want an error", expr)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// frontMatterDelimiter opens and closes a YAML front matter block
+const frontMatterDelimiter = "---"
+
+// FrontMatter is the metadata prepended to saved markdown files
+type FrontMatter struct {
+	Source      string    `yaml:"source"`
+	Canonical   string    `yaml:"canonical,omitempty"`
+	Title       string    `yaml:"title,omitempty"`
+	Description string    `yaml:"description,omitempty"`
+	Date        time.Time `yaml:"date"`
+	Depth       int       `yaml:"depth"`
+	Tags        []string  `yaml:"tags,omitempty"`
+}
+
+// WithFrontMatter returns markdown prefixed with a YAML front matter block
+func WithFrontMatter(markdown string, fm *FrontMatter) (string, error) {
+	var buf bytes.Buffer
+	buf.WriteString(frontMatterDelimiter + "\n")
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(fm); err != nil {
+		return "", fmt.Errorf("failed to encode front matter: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode front matter: %w", err)
+	}
+
+	buf.WriteString(frontMatterDelimiter + "\n\n")
+	buf.WriteString(markdown)
+	return buf.String(), nil
+}
+
+// StripFrontMatter returns markdown without its leading YAML front matter block, if any
+func StripFrontMatter(markdown string) string {
+	_, body, ok := SplitFrontMatter(markdown)
+	if !ok {
+		return markdown
+	}
+	return body
+}
+
+// ParseFrontMatter decodes the leading YAML front matter block of markdown. It
+// returns nil if the markdown has none.
+func ParseFrontMatter(markdown string) (*FrontMatter, error) {
+	block, _, ok := SplitFrontMatter(markdown)
+	if !ok {
+		return nil, nil
+	}
+
+	var fm FrontMatter
+	if err := yaml.Unmarshal([]byte(block), &fm); err != nil {
+		return nil, fmt.Errorf("failed to decode front matter: %w", err)
+	}
+	return &fm, nil
+}
+
+// SplitFrontMatter separates the front matter block of markdown from its body
+func SplitFrontMatter(markdown string) (block string, body string, ok bool) {
+	if !strings.HasPrefix(markdown, frontMatterDelimiter+"\n") {
+		return "", markdown, false
+	}
+
+	rest := markdown[len(frontMatterDelimiter)+1:]
+	end := strings.Index(rest, "\n"+frontMatterDelimiter+"\n")
+	if end < 0 {
+		return "", markdown, false
+	}
+	return rest[:end+1], strings.TrimLeft(rest[end+len(frontMatterDelimiter)+2:], "\n"), true
+}
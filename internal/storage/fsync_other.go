@@ -0,0 +1,9 @@
+//go:build !unix
+
+package storage
+
+// syncDir is a no-op on platforms where directories cannot be flushed; their
+// filesystems journal renames themselves
+func syncDir(path string) error {
+	return nil
+}
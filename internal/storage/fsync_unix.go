@@ -0,0 +1,15 @@
+//go:build unix
+
+package storage
+
+import "os"
+
+// syncDir flushes a directory to disk so that renames into it survive a crash
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
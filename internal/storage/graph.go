@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// LinkGraph is the page-to-page link structure of a library, built from the
+// links recorded in its manifest
+type LinkGraph struct {
+	Library string
+	Nodes   []GraphNode
+	Edges   []GraphEdge
+}
+
+// GraphNode is a page of the graph
+type GraphNode struct {
+	ID       string // stable identifier, n0, n1...
+	URL      string
+	Path     string // markdown file relative to the library, empty for external pages
+	External bool   // linked from the library but not saved in it
+}
+
+// GraphEdge is a link between two pages, by node ID
+type GraphEdge struct {
+	From string
+	To   string
+}
+
+// BuildLinkGraph returns the links between the pages of a manifest. Links to
+// pages missing from the library are only kept with external. Pages are
+// matched by PageKey, so links differing by fragment or trailing slash point
+// to the same node, and self-links are dropped.
+func BuildLinkGraph(manifest *Manifest, external bool) *LinkGraph {
+	graph := &LinkGraph{Library: manifest.Library}
+	pages := append([]ManifestEntry(nil), manifest.Pages...)
+	sort.Slice(pages, func(i, j int) bool { return pages[i].URL < pages[j].URL })
+
+	nodes := make(map[string]string) // page key to node ID
+	addNode := func(node GraphNode) string {
+		node.ID = fmt.Sprintf("n%d", len(graph.Nodes))
+		graph.Nodes = append(graph.Nodes, node)
+		return node.ID
+	}
+	for _, page := range pages {
+		key := PageKey(page.URL)
+		if _, ok := nodes[key]; !ok {
+			nodes[key] = addNode(GraphNode{URL: page.URL, Path: page.Path})
+		}
+	}
+
+	seen := make(map[GraphEdge]bool)
+	for _, page := range pages {
+		from := nodes[PageKey(page.URL)]
+		for _, link := range page.Links {
+			key := PageKey(link)
+			to, ok := nodes[key]
+			if !ok {
+				if !external {
+					continue
+				}
+				to = addNode(GraphNode{URL: link, External: true})
+				nodes[key] = to
+			}
+			edge := GraphEdge{From: from, To: to}
+			if from == to || seen[edge] {
+				continue
+			}
+			seen[edge] = true
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+	return graph
+}
+
+// labels returns the label of each node: URL paths when all pages share a
+// host, full URLs otherwise
+func (g *LinkGraph) labels() []string {
+	hosts := make(map[string]bool)
+	for _, node := range g.Nodes {
+		if u, err := url.Parse(node.URL); err == nil {
+			hosts[strings.ToLower(u.Host)] = true
+		}
+	}
+
+	labels := make([]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		labels[i] = node.URL
+		if u, err := url.Parse(node.URL); err == nil && len(hosts) == 1 {
+			labels[i] = u.Path
+			if labels[i] == "" {
+				labels[i] = "/"
+			}
+		}
+	}
+	return labels
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language
+func (g *LinkGraph) WriteDOT(w io.Writer) error {
+	labels := g.labels()
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(g.Library))
+	b.WriteString("  rankdir=LR;\n  node [shape=box];\n")
+	for i, node := range g.Nodes {
+		style := ""
+		if node.External {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %s [label=%s, URL=%s%s];\n", node.ID, dotQuote(labels[i]), dotQuote(node.URL), style)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", edge.From, edge.To)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote returns s as a quoted DOT identifier
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// WriteGraphML writes the graph as GraphML, with the URL, markdown path and
+// external flag of each page as node data
+func (g *LinkGraph) WriteGraphML(w io.Writer) error {
+	type data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	type node struct {
+		ID   string `xml:"id,attr"`
+		Data []data `xml:"data"`
+	}
+	type edge struct {
+		ID     string `xml:"id,attr"`
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+	}
+	type key struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+		Type string `xml:"attr.type,attr"`
+	}
+	type graph struct {
+		ID          string `xml:"id,attr"`
+		EdgeDefault string `xml:"edgedefault,attr"`
+		Nodes       []node `xml:"node"`
+		Edges       []edge `xml:"edge"`
+	}
+	type graphML struct {
+		XMLName xml.Name `xml:"graphml"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		Keys    []key    `xml:"key"`
+		Graph   graph    `xml:"graph"`
+	}
+
+	labels := g.labels()
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []key{
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "url", For: "node", Name: "url", Type: "string"},
+			{ID: "path", For: "node", Name: "path", Type: "string"},
+			{ID: "external", For: "node", Name: "external", Type: "boolean"},
+		},
+		Graph: graph{ID: g.Library, EdgeDefault: "directed"},
+	}
+	for i, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node{ID: n.ID, Data: []data{
+			{Key: "label", Value: labels[i]},
+			{Key: "url", Value: n.URL},
+			{Key: "path", Value: n.Path},
+			{Key: "external", Value: fmt.Sprint(n.External)},
+		}})
+	}
+	for i, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, edge{ID: fmt.Sprintf("e%d", i), Source: e.From, Target: e.To})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode GraphML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// License sources recorded in License.Sources
+const (
+	LicenseSourceRelLicense = "rel-license"
+	LicenseSourceSchemaOrg  = "schema.org"
+	LicenseSourceEXIF       = "exif"
+)
+
+// exifReadLimit bounds how much of a JPEG file is scanned for EXIF metadata
+const exifReadLimit = 256 << 10
+
+// License holds the reuse hints found for a media file. They are hints only:
+// crawlr cannot tell whether a page license also covers the media it embeds.
+type License struct {
+	URL       string   `json:"url,omitempty"`       // license URL (rel=license or schema.org)
+	Copyright string   `json:"copyright,omitempty"` // EXIF copyright notice
+	Artist    string   `json:"artist,omitempty"`    // EXIF artist
+	Sources   []string `json:"sources,omitempty"`   // where the hints were found
+}
+
+// Empty reports whether no hint was found
+func (l *License) Empty() bool {
+	return l == nil || (l.URL == "" && l.Copyright == "" && l.Artist == "")
+}
+
+// Merge fills the fields of l that are empty from other, adding the sources
+// of other if any of its hints was used
+func (l *License) Merge(other *License) {
+	if other.Empty() {
+		return
+	}
+	used := false
+	if l.URL == "" && other.URL != "" {
+		l.URL = other.URL
+		used = true
+	}
+	if l.Copyright == "" && other.Copyright != "" {
+		l.Copyright = other.Copyright
+		used = true
+	}
+	if l.Artist == "" && other.Artist != "" {
+		l.Artist = other.Artist
+		used = true
+	}
+	if !used {
+		return
+	}
+	for _, source := range other.Sources {
+		if !slices.Contains(l.Sources, source) {
+			l.Sources = append(l.Sources, source)
+		}
+	}
+}
+
+// ReadEXIFLicense reads the copyright and artist tags of a JPEG file. Other
+// files and JPEGs without these tags yield nil.
+func ReadEXIFLicense(path string) (*License, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+	default:
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, exifReadLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	tiff := findEXIF(data)
+	if tiff == nil {
+		return nil, nil
+	}
+	tags, err := readIFD0Strings(tiff, 0x8298, 0x013B)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXIF data: %w", err)
+	}
+
+	license := &License{Copyright: tags[0x8298], Artist: tags[0x013B]}
+	if license.Empty() {
+		return nil, nil
+	}
+	license.Sources = []string{LicenseSourceEXIF}
+	return license, nil
+}
+
+// findEXIF returns the TIFF structure of the EXIF APP1 segment of a JPEG
+func findEXIF(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return nil
+		}
+		marker := data[pos+1]
+		// Start of scan: the metadata segments are over
+		if marker == 0xDA || marker == 0xD9 {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+		segment := data[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		pos = end
+	}
+	return nil
+}
+
+// readIFD0Strings returns the ASCII values of the given tags in the first IFD
+// of a TIFF structure
+func readIFD0Strings(tiff []byte, tags ...uint16) (map[uint16]string, error) {
+	if len(tiff) < 8 {
+		return nil, fmt.Errorf("truncated TIFF header")
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("unknown byte order")
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset+2 > len(tiff) {
+		return nil, fmt.Errorf("IFD offset out of range")
+	}
+	count := int(order.Uint16(tiff[offset:]))
+
+	values := make(map[uint16]string)
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		tag := order.Uint16(tiff[entry:])
+		// Only ASCII values are of interest
+		if order.Uint16(tiff[entry+2:]) != 2 || !slices.Contains(tags, tag) {
+			continue
+		}
+
+		size := int(order.Uint32(tiff[entry+4:]))
+		start := entry + 8
+		if size > 4 {
+			start = int(order.Uint32(tiff[entry+8:]))
+		}
+		if start < 0 || size < 0 || start+size > len(tiff) {
+			continue
+		}
+		value := strings.TrimSpace(strings.TrimRight(string(tiff[start:start+size]), "\x00"))
+		if value != "" {
+			values[tag] = value
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// markdownLinkPattern matches inline link destinations such as [text](url) or
+// [text](url "title"), capturing the destination
+var markdownLinkPattern = regexp.MustCompile(`\]\(\s*<?([^)\s>]+)>?(\s+"[^"]*")?\s*\)`)
+
+// RewriteInternalLinks rewrites links between pages of the library to relative
+// .md paths so that the library can be browsed offline. Every page listed in
+// the manifest is processed, so links to pages saved by earlier crawls are
+// rewritten as well. It returns the number of files changed.
+func (s *Storage) RewriteInternalLinks() (int, error) {
+	s.manifestMutex.Lock()
+	defer s.manifestMutex.Unlock()
+
+	if err := s.loadManifestState(); err != nil {
+		return 0, err
+	}
+
+	// Map each crawled page URL to its markdown file
+	pages := make(map[string]string, len(s.manifest.pages))
+	for _, entry := range s.manifest.pages {
+		pages[PageKey(entry.URL)] = filepath.Join(s.libraryPath, filepath.FromSlash(entry.Path))
+	}
+
+	changed := 0
+	for path, entry := range s.manifest.pages {
+		filePath := filepath.Join(s.libraryPath, filepath.FromSlash(path))
+		data, err := os.ReadFile(filePath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return changed, fmt.Errorf("failed to read markdown file: %w", err)
+		}
+
+		rewritten := rewriteLinks(string(data), entry.URL, filePath, pages)
+		if rewritten == string(data) {
+			continue
+		}
+
+		if err := writeFileAtomic(filePath, []byte(rewritten), s.config.DurableWrites); err != nil {
+			return changed, fmt.Errorf("failed to write markdown file: %w", err)
+		}
+
+		entry.Hash = contentHash([]byte(rewritten))
+		entry.Size = int64(len(rewritten))
+		s.manifest.pages[path] = entry
+		s.manifest.dirty = true
+		changed++
+	}
+
+	return changed, nil
+}
+
+// rewriteLinks replaces the destinations of links to known pages in markdown
+// with paths relative to filePath
+func rewriteLinks(markdown string, pageURL string, filePath string, pages map[string]string) string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return markdown
+	}
+
+	return markdownLinkPattern.ReplaceAllStringFunc(markdown, func(match string) string {
+		groups := markdownLinkPattern.FindStringSubmatch(match)
+		// Anchors within the same page already work offline
+		if strings.HasPrefix(groups[1], "#") {
+			return match
+		}
+		ref, err := url.Parse(groups[1])
+		if err != nil {
+			return match
+		}
+
+		target := base.ResolveReference(ref)
+		if target.Scheme != "http" && target.Scheme != "https" {
+			return match
+		}
+		targetPath, ok := pages[PageKey(target.String())]
+		if !ok {
+			return match
+		}
+
+		rel, err := filepath.Rel(filepath.Dir(filePath), targetPath)
+		if err != nil {
+			return match
+		}
+
+		local := &url.URL{Path: filepath.ToSlash(rel), Fragment: target.Fragment}
+		return "](" + local.String() + groups[2] + ")"
+	})
+}
+
+// PageKey normalizes a page URL so that links differing only by fragment,
+// query, host case or trailing slash map to the same page, mirroring how
+// pages are mapped to markdown files
+func PageKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + path
+}
@@ -0,0 +1,382 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ManifestFile is the name of the manifest inside the library
+const ManifestFile = "manifest.json"
+
+// PreviousManifestFile is the manifest as it was before the last crawl that
+// changed the library, kept for crawlr diff
+const PreviousManifestFile = "manifest.previous.json"
+
+// ManifestEntry describes a saved page or media file
+type ManifestEntry struct {
+	URL          string    `json:"url"`
+	FinalURL     string    `json:"final_url,omitempty"`     // pages only: URL served after redirects, if different
+	CanonicalURL string    `json:"canonical_url,omitempty"` // pages only: canonical URL the page declares
+	Path         string    `json:"path"`                    // relative to the library, slash-separated
+	Hash         string    `json:"hash"`                    // hex-encoded SHA-256 of the content
+	Size         int64     `json:"size"`
+	CrawledAt    time.Time `json:"crawled_at"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	License      *License  `json:"license,omitempty"` // media only
+	OptOut       []string  `json:"opt_out,omitempty"` // pages only
+	Tags         []string  `json:"tags,omitempty"`    // pages only: keywords of the page
+
+	// Validators of the fetched content, for incremental re-crawls
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+	Links        []string `json:"links,omitempty"` // pages only: links followed from the page
+}
+
+// Manifest lists every page and media file saved in a library
+type Manifest struct {
+	Library   string          `json:"library"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Pages     []ManifestEntry `json:"pages"`
+	Media     []ManifestEntry `json:"media"`
+}
+
+// manifestState is the in-memory manifest maintained during a crawl. Pages are
+// keyed by path and media by URL, since media objects of the cas layout are
+// shared by every URL with the same content.
+type manifestState struct {
+	pages     map[string]ManifestEntry
+	media     map[string]ManifestEntry
+	pageByURL map[string]string // page URL to path
+	dirty     bool
+	kept      bool // the manifest of the previous crawl was kept
+
+	// Media lookups used for deduplication
+	mediaByURL  map[string]ManifestEntry
+	mediaByHash map[string]string
+	mediaSaved  map[string]bool // media URLs saved during this run
+}
+
+// GetManifestPath returns the path of the library manifest
+func (s *Storage) GetManifestPath() string {
+	return filepath.Join(s.libraryPath, ManifestFile)
+}
+
+// LoadManifest reads the manifest of the library stored at libraryPath. A
+// library without a manifest yields an empty one.
+func LoadManifest(libraryPath string) (*Manifest, error) {
+	manifest, err := ReadManifestFile(filepath.Join(libraryPath, ManifestFile))
+	if os.IsNotExist(err) {
+		return &Manifest{Library: filepath.Base(libraryPath)}, nil
+	}
+	return manifest, err
+}
+
+// ReadManifestFile reads a manifest file. The error of a missing file
+// satisfies os.IsNotExist.
+func ReadManifestFile(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// RecordPage adds or replaces the manifest entry of a saved page
+func (s *Storage) RecordPage(info *FileInfo, statusCode int, crawledAt time.Time) error {
+	return s.recordManifestEntry(info, statusCode, crawledAt, false)
+}
+
+// RecordMedia adds or replaces the manifest entry of a saved media file
+func (s *Storage) RecordMedia(info *FileInfo, statusCode int, crawledAt time.Time) error {
+	return s.recordManifestEntry(info, statusCode, crawledAt, true)
+}
+
+func (s *Storage) recordManifestEntry(info *FileInfo, statusCode int, crawledAt time.Time, media bool) error {
+	if info == nil {
+		return nil
+	}
+
+	entry, err := newManifestEntry(s.libraryPath, info, statusCode, crawledAt, media)
+	if err != nil {
+		return err
+	}
+
+	s.manifestMutex.Lock()
+	defer s.manifestMutex.Unlock()
+
+	if err := s.loadManifestState(); err != nil {
+		return err
+	}
+
+	if media {
+		s.manifest.addMedia(entry)
+		s.manifest.mediaSaved[entry.URL] = true
+	} else {
+		s.manifest.addPage(entry)
+	}
+	s.manifest.dirty = true
+
+	return nil
+}
+
+// newManifestEntry returns the manifest entry of a file saved in the library
+// stored at libraryPath
+func newManifestEntry(libraryPath string, info *FileInfo, statusCode int, crawledAt time.Time, media bool) (ManifestEntry, error) {
+	relPath, err := filepath.Rel(libraryPath, info.Path)
+	if err != nil {
+		return ManifestEntry{}, fmt.Errorf("failed to resolve manifest path: %w", err)
+	}
+
+	entry := ManifestEntry{
+		URL:        info.URL,
+		Path:       filepath.ToSlash(relPath),
+		Hash:       info.Hash,
+		Size:       info.Size,
+		CrawledAt:  crawledAt.UTC(),
+		StatusCode: statusCode,
+
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}
+	if media {
+		if !info.License.Empty() {
+			entry.License = info.License
+		}
+	} else {
+		entry.FinalURL = info.FinalURL
+		entry.CanonicalURL = info.CanonicalURL
+		entry.OptOut = info.OptOut
+		entry.Tags = info.Tags
+		entry.Links = info.Links
+	}
+	return entry, nil
+}
+
+// ManifestSegment collects the page entries recorded by a single writer, to
+// be merged into the manifest at once by MergeManifestSegment. Writers saving
+// sections of a library in parallel each record into their own segment
+// rather than contending for the manifest on every page.
+type ManifestSegment struct {
+	libraryPath string
+	pages       []ManifestEntry
+}
+
+// NewManifestSegment returns an empty manifest segment of the library
+func (s *Storage) NewManifestSegment() *ManifestSegment {
+	return &ManifestSegment{libraryPath: s.libraryPath}
+}
+
+// RecordPage adds the entry of a saved page to the segment
+func (m *ManifestSegment) RecordPage(info *FileInfo, statusCode int, crawledAt time.Time) error {
+	if info == nil {
+		return nil
+	}
+	entry, err := newManifestEntry(m.libraryPath, info, statusCode, crawledAt, false)
+	if err != nil {
+		return err
+	}
+	m.pages = append(m.pages, entry)
+	return nil
+}
+
+// MergeManifestSegment adds or replaces the page entries of a segment in the
+// manifest, and empties the segment
+func (s *Storage) MergeManifestSegment(segment *ManifestSegment) error {
+	if len(segment.pages) == 0 {
+		return nil
+	}
+
+	s.manifestMutex.Lock()
+	defer s.manifestMutex.Unlock()
+
+	if err := s.loadManifestState(); err != nil {
+		return err
+	}
+	for _, entry := range segment.pages {
+		s.manifest.addPage(entry)
+	}
+	s.manifest.dirty = true
+	segment.pages = nil
+	return nil
+}
+
+// loadManifestState reads the existing manifest on first use so that entries
+// of earlier crawls are kept. The caller must hold manifestMutex.
+func (s *Storage) loadManifestState() error {
+	if s.manifest != nil {
+		return nil
+	}
+
+	existing, err := LoadManifest(s.libraryPath)
+	if err != nil {
+		return err
+	}
+
+	state := &manifestState{
+		pages:       make(map[string]ManifestEntry),
+		media:       make(map[string]ManifestEntry),
+		pageByURL:   make(map[string]string),
+		mediaByURL:  make(map[string]ManifestEntry),
+		mediaByHash: make(map[string]string),
+		mediaSaved:  make(map[string]bool),
+	}
+	for _, entry := range existing.Pages {
+		state.addPage(entry)
+	}
+	for _, entry := range existing.Media {
+		state.addMedia(entry)
+	}
+	s.manifest = state
+	return nil
+}
+
+// KnownPage returns the manifest entry of a page saved by an earlier crawl
+// whose markdown file still exists
+func (s *Storage) KnownPage(pageURL string) (ManifestEntry, bool) {
+	s.manifestMutex.Lock()
+	defer s.manifestMutex.Unlock()
+
+	if err := s.loadManifestState(); err != nil {
+		return ManifestEntry{}, false
+	}
+	path, ok := s.manifest.pageByURL[pageURL]
+	if !ok {
+		return ManifestEntry{}, false
+	}
+	entry := s.manifest.pages[path]
+	if _, err := os.Stat(filepath.Join(s.libraryPath, filepath.FromSlash(entry.Path))); err != nil {
+		return ManifestEntry{}, false
+	}
+	return entry, true
+}
+
+// KnownMedia returns the manifest entry of a media file saved by an earlier
+// crawl whose file still exists
+func (s *Storage) KnownMedia(mediaURL string) (ManifestEntry, bool) {
+	s.manifestMutex.Lock()
+	defer s.manifestMutex.Unlock()
+
+	if err := s.loadManifestState(); err != nil {
+		return ManifestEntry{}, false
+	}
+	entry, ok := s.manifest.mediaByURL[mediaURL]
+	if !ok {
+		return ManifestEntry{}, false
+	}
+	if _, err := os.Stat(filepath.Join(s.libraryPath, filepath.FromSlash(entry.Path))); err != nil {
+		return ManifestEntry{}, false
+	}
+	return entry, true
+}
+
+// addPage adds or replaces a page entry and indexes it by URL
+func (m *manifestState) addPage(entry ManifestEntry) {
+	m.pages[entry.Path] = entry
+	m.pageByURL[entry.URL] = entry.Path
+}
+
+// addMedia adds or replaces a media entry and indexes it by URL and hash
+func (m *manifestState) addMedia(entry ManifestEntry) {
+	m.media[entry.URL] = entry
+	m.mediaByURL[entry.URL] = entry
+	if entry.Hash != "" {
+		if _, ok := m.mediaByHash[entry.Hash]; !ok {
+			m.mediaByHash[entry.Hash] = entry.Path
+		}
+	}
+}
+
+// SaveManifest writes the manifest if entries were recorded since it was last
+// written. The file is replaced atomically.
+func (s *Storage) SaveManifest() error {
+	s.manifestMutex.Lock()
+	defer s.manifestMutex.Unlock()
+
+	if s.manifest == nil || !s.manifest.dirty {
+		return nil
+	}
+
+	// Keep the manifest of the previous crawl before replacing it for the
+	// first time in this run
+	if !s.manifest.kept {
+		if err := s.keepPreviousManifest(); err != nil {
+			return err
+		}
+		s.manifest.kept = true
+	}
+
+	manifest := Manifest{
+		Library:   filepath.Base(s.libraryPath),
+		UpdatedAt: time.Now().UTC(),
+		Pages:     sortedEntries(s.manifest.pages),
+		Media:     sortedEntries(s.manifest.media),
+	}
+
+	if err := writeManifest(s.libraryPath, &manifest, s.config.DurableWrites); err != nil {
+		return err
+	}
+
+	s.manifest.dirty = false
+	s.logger.Info("Saved manifest", map[string]interface{}{
+		"path":  s.GetManifestPath(),
+		"pages": len(manifest.Pages),
+		"media": len(manifest.Media),
+	})
+	return nil
+}
+
+// keepPreviousManifest copies the manifest to PreviousManifestFile, if the
+// library has one. The caller must hold manifestMutex.
+func (s *Storage) keepPreviousManifest() error {
+	data, err := os.ReadFile(s.GetManifestPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(s.libraryPath, PreviousManifestFile), data, s.config.DurableWrites); err != nil {
+		return fmt.Errorf("failed to keep previous manifest: %w", err)
+	}
+	return nil
+}
+
+// writeManifest replaces the manifest of the library stored at libraryPath
+// atomically, flushing it to disk with durable
+func writeManifest(libraryPath string, manifest *Manifest, durable bool) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if err := writeFileAtomic(filepath.Join(libraryPath, ManifestFile), append(data, '\n'), durable); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+func sortedEntries(entries map[string]ManifestEntry) []ManifestEntry {
+	sorted := make([]ManifestEntry, 0, len(entries))
+	for _, entry := range entries {
+		sorted = append(sorted, entry)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].URL < sorted[j].URL
+	})
+	return sorted
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MediaType classifies a media file name or URL path by its extension as
+// "image", "video", "audio" or "other"
+func MediaType(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".svg", ".webp":
+		return "image"
+	case ".mp4", ".avi", ".mov", ".wmv", ".flv", ".webm":
+		return "video"
+	case ".mp3", ".wav", ".ogg", ".flac", ".aac":
+		return "audio"
+	default:
+		return "other"
+	}
+}
+
+// writeMedia streams a media file to path while hashing it, through a
+// temporary file renamed into place so that an interrupted download never
+// leaves a partial file. When a file with the same content was already saved
+// under another path, the new path is hard-linked to it so that the library
+// keeps a single copy on disk.
+func (s *Storage) writeMedia(reader io.Reader, path string) (int64, string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".media-*")
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), reader)
+	if err != nil {
+		tmp.Close()
+		return 0, "", err
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	if existing := s.mediaPathByHash(hash); existing != "" && existing != path {
+		os.Remove(path)
+		if err := os.Link(existing, path); err == nil {
+			tmp.Close()
+			s.logger.Detail("Deduplicated media file", map[string]interface{}{
+				"path":     path,
+				"original": existing,
+			})
+			return size, hash, nil
+		}
+		// Filesystems without hard links fall back to a separate copy
+	}
+
+	if err := commitTemp(tmp, path, s.config.DurableWrites); err != nil {
+		return 0, "", fmt.Errorf("failed to move media file into place: %w", err)
+	}
+	return size, hash, nil
+}
+
+// HasMedia reports whether a media URL was saved during this run or, unless
+// files are overwritten or re-crawled incrementally, by an earlier crawl
+// recorded in the manifest
+func (s *Storage) HasMedia(mediaURL string) bool {
+	s.manifestMutex.Lock()
+	defer s.manifestMutex.Unlock()
+
+	if err := s.loadManifestState(); err != nil {
+		return false
+	}
+
+	if s.manifest.mediaSaved[mediaURL] {
+		return true
+	}
+	entry, ok := s.manifest.mediaByURL[mediaURL]
+	if !ok || s.overwrite() {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(s.libraryPath, filepath.FromSlash(entry.Path)))
+	return err == nil
+}
+
+// mediaPathByHash returns the path of a saved media file with the given
+// content hash, or an empty string if there is none
+func (s *Storage) mediaPathByHash(hash string) string {
+	s.manifestMutex.Lock()
+	defer s.manifestMutex.Unlock()
+
+	if err := s.loadManifestState(); err != nil {
+		return ""
+	}
+
+	relPath, ok := s.manifest.mediaByHash[hash]
+	if !ok {
+		return ""
+	}
+	path := filepath.Join(s.libraryPath, filepath.FromSlash(relPath))
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
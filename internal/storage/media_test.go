@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"crawlr/internal/config"
+	"crawlr/internal/logger"
+)
+
+// newTestStorage returns a storage writing a library to a temporary directory,
+// with the default configuration changed by modify
+func newTestStorage(t *testing.T, modify func(cfg *config.Config)) *Storage {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Output = t.TempDir()
+	cfg.Library = "docs"
+	cfg.IncludeMedia = true
+	if modify != nil {
+		modify(cfg)
+	}
+	s, err := NewStorage(cfg, logger.NewWithHandler(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSaveIdenticalMedia(t *testing.T) {
+	urls := []string{"https://docs.example.com/img/logo.png", "https://cdn.example.com/assets/logo-copy.png"}
+	for _, tt := range []struct{ name, layout string }{{"by URL", ""}, {"cas", "cas"}} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t, func(cfg *config.Config) { cfg.MediaLayout = tt.layout })
+
+			var infos []*FileInfo
+			for _, url := range append(urls, "https://docs.example.com/img/other.png") {
+				content := "identical image bytes"
+				if strings.HasSuffix(url, "other.png") {
+					content = "other image bytes"
+				}
+				info, err := s.SaveTypedMediaFile(strings.NewReader(content), url, "image")
+				if err != nil {
+					t.Fatalf("SaveTypedMediaFile(%s) error = %v", url, err)
+				}
+				if err := s.RecordMedia(info, 200, time.Now()); err != nil {
+					t.Fatal(err)
+				}
+				infos = append(infos, info)
+			}
+			if err := s.SaveManifest(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The two URLs share one object on disk, the third has its own
+			if objects := countObjects(t, filepath.Join(s.libraryPath, "media")); objects != 2 {
+				t.Errorf("media holds %d distinct files, want 2", objects)
+			}
+			if infos[0].Hash != infos[1].Hash || infos[0].Hash == infos[2].Hash {
+				t.Errorf("hashes %s, %s, %s: want the first two equal and the third apart",
+					infos[0].Hash, infos[1].Hash, infos[2].Hash)
+			}
+
+			manifest, err := LoadManifest(s.libraryPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(manifest.Media) != 3 {
+				t.Fatalf("manifest lists %d media, want 3", len(manifest.Media))
+			}
+			byURL := make(map[string]ManifestEntry)
+			for _, entry := range manifest.Media {
+				byURL[entry.URL] = entry
+			}
+			for _, url := range urls {
+				entry, ok := byURL[url]
+				if !ok {
+					t.Errorf("manifest has no entry for %s", url)
+					continue
+				}
+				if entry.Hash != infos[0].Hash {
+					t.Errorf("manifest hash of %s = %s, want %s", url, entry.Hash, infos[0].Hash)
+				}
+				data, err := os.ReadFile(filepath.Join(s.libraryPath, filepath.FromSlash(entry.Path)))
+				if err != nil || string(data) != "identical image bytes" {
+					t.Errorf("file of %s = %q, %v, want the saved bytes", url, data, err)
+				}
+			}
+		})
+	}
+}
+
+// countObjects returns the number of distinct files under dir, counting hard
+// links to one file once
+func countObjects(t *testing.T, dir string) int {
+	t.Helper()
+	var files []os.FileInfo
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if os.SameFile(file, info) {
+				return nil
+			}
+		}
+		files = append(files, info)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(files)
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MediaObjectsDir is the directory, inside the media folder, holding the media
+// files of the cas layout under their SHA-256
+const MediaObjectsDir = "objects"
+
+// mediaLinksExt is the extension of the link file listing the media of a page
+const mediaLinksExt = ".media.json"
+
+// MediaLink references the media object of a media URL found on a page
+type MediaLink struct {
+	URL    string `json:"url"`
+	Type   string `json:"type"`
+	Object string `json:"object"` // relative to the library, slash-separated
+	Hash   string `json:"hash"`
+	Size   int64  `json:"size"`
+}
+
+// MediaLinks is the link file of a page, stored next to its markdown
+type MediaLinks struct {
+	URL   string      `json:"url"`
+	Media []MediaLink `json:"media"`
+}
+
+// casLayout reports whether media files are stored as content-addressed objects
+func (s *Storage) casLayout() bool {
+	return s.config.MediaLayout == "cas"
+}
+
+// saveMediaObject stores a media file under media/objects/<sha256>. Content
+// already stored by another URL or page is not written twice.
+func (s *Storage) saveMediaObject(reader io.Reader, mediaURL string, fileType string) (*FileInfo, error) {
+	dir := filepath.Join(s.mediaPath, MediaObjectsDir)
+	if err := s.ensureDir(dir); err != nil {
+		return nil, fmt.Errorf("failed to create media objects directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".object-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), reader)
+	if err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write media object: %w", err)
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	path := filepath.Join(dir, hash)
+	if _, err := os.Stat(path); err == nil {
+		tmp.Close()
+		s.logger.Debug("Media object already stored", map[string]interface{}{"url": mediaURL, "object": hash})
+	} else if err := commitTemp(tmp, path, s.config.DurableWrites); err != nil {
+		return nil, fmt.Errorf("failed to move media object into place: %w", err)
+	} else {
+		s.logger.Detail("Saving media object", map[string]interface{}{"url": mediaURL, "object": hash})
+	}
+
+	return &FileInfo{
+		Path:     path,
+		Filename: hash,
+		Size:     size,
+		Type:     fileType,
+		URL:      mediaURL,
+		Hash:     hash,
+	}, nil
+}
+
+// GetMediaLinksPath returns the path of the link file listing the media of a page
+func (s *Storage) GetMediaLinksPath(pageURL string) string {
+	return s.pagePath(s.markdownPath, pageURL, mediaLinksExt)
+}
+
+// SaveMediaLinks writes the link file of a page with the cas layout, listing
+// the objects of its media. Media never saved, by this run or an earlier
+// crawl, is left out. It does nothing with the path layout or when the page
+// has no saved media.
+func (s *Storage) SaveMediaLinks(pageURL string, media []MediaLink) (*FileInfo, error) {
+	if !s.casLayout() || !s.config.IncludeMedia {
+		return nil, nil
+	}
+
+	s.manifestMutex.Lock()
+	if err := s.loadManifestState(); err != nil {
+		s.manifestMutex.Unlock()
+		return nil, err
+	}
+	links := MediaLinks{URL: pageURL, Media: []MediaLink{}}
+	for _, link := range media {
+		entry, ok := s.manifest.mediaByURL[link.URL]
+		if !ok {
+			continue
+		}
+		link.Object, link.Hash, link.Size = entry.Path, entry.Hash, entry.Size
+		links.Media = append(links.Media, link)
+	}
+	s.manifestMutex.Unlock()
+
+	if len(links.Media) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.MarshalIndent(links, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal media links: %w", err)
+	}
+	path := s.GetMediaLinksPath(pageURL)
+	if err := s.ensureDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, append(data, '\n'), s.config.DurableWrites); err != nil {
+		return nil, fmt.Errorf("failed to write media links: %w", err)
+	}
+
+	return &FileInfo{
+		Path:     path,
+		Filename: filepath.Base(path),
+		Size:     int64(len(data) + 1),
+		Type:     "media_links",
+		URL:      pageURL,
+	}, nil
+}
+
+// MediaGCResult summarizes a garbage collection of media objects
+type MediaGCResult struct {
+	Objects    int      // objects found
+	Referenced int      // objects referenced by link files
+	Removed    []string // unreferenced objects, relative to the library
+	Freed      int64    // bytes of the unreferenced objects
+}
+
+// CollectMediaGarbage removes the media objects of the library at libraryPath
+// that no page link file references, along with their manifest entries. With
+// dryRun, nothing is removed and the result lists what would be.
+func CollectMediaGarbage(libraryPath string, dryRun bool) (*MediaGCResult, error) {
+	referenced := make(map[string]bool)
+	markdownDir := filepath.Join(libraryPath, "markdown")
+	err := filepath.WalkDir(markdownDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == markdownDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), mediaLinksExt) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var links MediaLinks
+		if err := json.Unmarshal(data, &links); err != nil {
+			return fmt.Errorf("invalid media link file %s: %w", path, err)
+		}
+		for _, link := range links.Media {
+			referenced[link.Object] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read media link files: %w", err)
+	}
+
+	result := &MediaGCResult{}
+	objectsDir := filepath.Join(libraryPath, "media", MediaObjectsDir)
+	entries, err := os.ReadDir(objectsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list media objects: %w", err)
+	}
+	removed := make(map[string]bool)
+	for _, entry := range entries {
+		// Skip directories and leftovers of interrupted writes
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		result.Objects++
+		relPath := "media/" + MediaObjectsDir + "/" + entry.Name()
+		if referenced[relPath] {
+			result.Referenced++
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		result.Removed = append(result.Removed, relPath)
+		result.Freed += info.Size()
+		removed[relPath] = true
+		if dryRun {
+			continue
+		}
+		if err := os.Remove(filepath.Join(objectsDir, entry.Name())); err != nil {
+			return nil, fmt.Errorf("failed to remove media object: %w", err)
+		}
+	}
+	sort.Strings(result.Removed)
+
+	if dryRun || len(removed) == 0 {
+		return result, nil
+	}
+
+	// Drop the manifest entries of the removed objects
+	manifest, err := LoadManifest(libraryPath)
+	if err != nil {
+		return nil, err
+	}
+	kept := manifest.Media[:0]
+	for _, entry := range manifest.Media {
+		if !removed[entry.Path] {
+			kept = append(kept, entry)
+		}
+	}
+	manifest.Media = kept
+	manifest.UpdatedAt = time.Now().UTC()
+	if err := writeManifest(libraryPath, manifest, false); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// RemovedDir is the directory of the library where pruned pages are moved
+const RemovedDir = "_removed"
+
+// PrunePages removes the pages of the library for which keep returns false,
+// along with their sidecar files (HTML, crawl result, extraction and media
+// links), and drops them from the manifest. With move, the files are moved
+// under RemovedDir at the same relative path instead of being deleted. It
+// returns the manifest entries of the pruned pages.
+func (s *Storage) PrunePages(keep func(pageURL string) bool, move bool) ([]ManifestEntry, error) {
+	s.manifestMutex.Lock()
+	defer s.manifestMutex.Unlock()
+
+	if err := s.loadManifestState(); err != nil {
+		return nil, err
+	}
+
+	var pruned []ManifestEntry
+	for path, entry := range s.manifest.pages {
+		if keep(entry.URL) {
+			continue
+		}
+
+		files := []string{
+			filepath.Join(s.libraryPath, filepath.FromSlash(entry.Path)),
+			s.GetHTMLPath(entry.URL, false),
+			s.GetHTMLPath(entry.URL, true),
+			s.GetResultJSONPath(entry.URL),
+			s.GetExtractionPath(entry.URL),
+			s.GetMediaLinksPath(entry.URL),
+		}
+		for _, file := range files {
+			if err := s.removePageFile(file, move); err != nil {
+				return pruned, err
+			}
+		}
+
+		delete(s.manifest.pages, path)
+		if s.manifest.pageByURL[entry.URL] == path {
+			delete(s.manifest.pageByURL, entry.URL)
+		}
+		s.manifest.dirty = true
+		pruned = append(pruned, entry)
+	}
+
+	sort.Slice(pruned, func(i, j int) bool { return pruned[i].URL < pruned[j].URL })
+	return pruned, nil
+}
+
+// removePageFile deletes a file of the library, or moves it under RemovedDir.
+// Missing files are ignored.
+func (s *Storage) removePageFile(path string, move bool) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if !move {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove page file: %w", err)
+		}
+		return nil
+	}
+
+	relPath, err := filepath.Rel(s.libraryPath, path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve page file path: %w", err)
+	}
+	dest := filepath.Join(s.libraryPath, RemovedDir, relPath)
+	if err := s.ensureDir(filepath.Dir(dest)); err != nil {
+		return err
+	}
+	if err := os.Rename(path, dest); err != nil {
+		return fmt.Errorf("failed to move page file: %w", err)
+	}
+	return nil
+}
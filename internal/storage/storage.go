@@ -1,6 +1,10 @@
 package storage
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
@@ -8,12 +12,16 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
 	"crawlr/internal/config"
 	"crawlr/internal/errors"
 	"crawlr/internal/logger"
 )
 
+// filenameSanitizer matches characters that are not allowed in file names
+var filenameSanitizer = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1F]`)
+
 // Storage handles file operations for crawled content
 type Storage struct {
 	config         *config.Config
@@ -22,7 +30,15 @@ type Storage struct {
 	libraryPath    string
 	markdownPath   string
 	mediaPath      string
+	extractedPath  string
 	sanitizeRegexp *regexp.Regexp
+	exportMutex    sync.Mutex
+	exportFile     *os.File
+	manifestMutex  sync.Mutex
+	manifest       *manifestState
+	warcMutex      sync.Mutex
+	warcFile       *os.File
+	warcPath       string
 }
 
 // FileInfo represents information about a stored file
@@ -32,6 +48,29 @@ type FileInfo struct {
 	Size     int64  `json:"size"`
 	Type     string `json:"type"` // "markdown", "image", "video", etc.
 	URL      string `json:"url,omitempty"`
+	Hash     string `json:"hash,omitempty"` // hex-encoded SHA-256 of the content
+
+	// License holds the reuse hints found for a media file, if any
+	License *License `json:"license,omitempty"`
+
+	// OptOut lists the opt-out signals of a page archived despite them
+	OptOut []string `json:"opt_out,omitempty"`
+
+	// Tags are the keywords of a page, recorded for filtered exports
+	Tags []string `json:"tags,omitempty"`
+
+	// FinalURL is the URL a page was served from after redirects, when it
+	// differs from URL
+	FinalURL string `json:"final_url,omitempty"`
+
+	// CanonicalURL is the canonical URL a page declares, if any
+	CanonicalURL string `json:"canonical_url,omitempty"`
+
+	// ETag and LastModified are the validators of the fetched content, and
+	// Links the links followed from a page, kept for incremental re-crawls
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+	Links        []string `json:"links,omitempty"`
 }
 
 // NewStorage creates a new Storage instance with the provided configuration
@@ -67,6 +106,7 @@ func (s *Storage) initializePaths() error {
 	// Create content type paths
 	s.markdownPath = filepath.Join(s.libraryPath, "markdown")
 	s.mediaPath = filepath.Join(s.libraryPath, "media")
+	s.extractedPath = filepath.Join(s.libraryPath, "extracted")
 
 	// Create all directories
 	if err := s.ensureDir(s.basePath); err != nil {
@@ -87,13 +127,44 @@ func (s *Storage) initializePaths() error {
 		}
 	}
 
+	if s.config.Extraction.Enabled() {
+		if err := s.ensureDir(s.extractedPath); err != nil {
+			return fmt.Errorf("failed to create extracted directory: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// overwrite reports whether existing files are replaced. Incremental
+// re-crawls replace the files of pages and media that changed.
+func (s *Storage) overwrite() bool {
+	return s.config.OverwriteFiles || s.config.Incremental
+}
+
+// Names of the crawl reports written at the top of the library
+const (
+	CrawlTreeFile       = "crawl-tree.json"
+	SitemapCoverageFile = "sitemap-coverage.json"
+	CheckpointFile      = "checkpoint.json"
+)
+
+// WriteLibraryFile replaces a file at the top of the library, such as a crawl
+// report
+func (s *Storage) WriteLibraryFile(name string, data []byte) error {
+	return writeFileAtomic(filepath.Join(s.libraryPath, name), data, s.config.DurableWrites)
+}
+
+// contentHash returns the hex-encoded SHA-256 of data
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 // ensureDir creates a directory if it doesn't exist
 func (s *Storage) ensureDir(path string) error {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		s.logger.Info("Creating directory", map[string]interface{}{"path": path})
+		s.logger.Detail("Creating directory", map[string]interface{}{"path": path})
 		if err := os.MkdirAll(path, 0755); err != nil {
 			return fmt.Errorf("failed to create directory %s: %w", path, err)
 		}
@@ -106,8 +177,38 @@ func (s *Storage) sanitizeFilename(filename string) string {
 	return s.sanitizeRegexp.ReplaceAllString(filename, "_")
 }
 
+// GetLibraryPath returns the directory of the library
+func (s *Storage) GetLibraryPath() string {
+	return s.libraryPath
+}
+
 // GetMarkdownPath returns the path for storing markdown content for a given URL
 func (s *Storage) GetMarkdownPath(pageURL string) string {
+	return s.pagePath(s.markdownPath, pageURL, ".md")
+}
+
+// GetHTMLPath returns the path for storing the HTML of a page next to its markdown.
+// Cleaned HTML uses a ".cleaned.html" extension so both variants can coexist.
+func (s *Storage) GetHTMLPath(pageURL string, cleaned bool) string {
+	if cleaned {
+		return s.pagePath(s.markdownPath, pageURL, ".cleaned.html")
+	}
+	return s.pagePath(s.markdownPath, pageURL, ".html")
+}
+
+// GetResultJSONPath returns the path of the JSON sidecar holding the full crawl result of a page
+func (s *Storage) GetResultJSONPath(pageURL string) string {
+	return s.pagePath(s.markdownPath, pageURL, ".json")
+}
+
+// GetExtractionPath returns the path for storing structured extraction results for a given URL
+func (s *Storage) GetExtractionPath(pageURL string) string {
+	return s.pagePath(s.extractedPath, pageURL, ".json")
+}
+
+// pagePath maps a page URL to a file under dir, mirroring the URL path and
+// using ext as the file extension
+func (s *Storage) pagePath(dir string, pageURL string, ext string) string {
 	// Parse URL to extract path
 	parsedURL, err := url.Parse(pageURL)
 	if err != nil {
@@ -115,15 +216,15 @@ func (s *Storage) GetMarkdownPath(pageURL string) string {
 			"url":   pageURL,
 			"error": err,
 		})
-		return filepath.Join(s.markdownPath, "index.md")
+		return filepath.Join(dir, "index"+ext)
 	}
 
 	// Get path without leading slash
 	path := strings.TrimPrefix(parsedURL.Path, "/")
 
-	// If path is empty, use index.md
+	// If path is empty, use the index file
 	if path == "" {
-		return filepath.Join(s.markdownPath, "index.md")
+		return filepath.Join(dir, "index"+ext)
 	}
 
 	// Sanitize path components
@@ -132,13 +233,28 @@ func (s *Storage) GetMarkdownPath(pageURL string) string {
 		pathComponents[i] = s.sanitizeFilename(component)
 	}
 
-	// Join path components and add .md extension
+	// Join path components and add the extension
 	sanitizedPath := filepath.Join(pathComponents...)
-	if !strings.HasSuffix(sanitizedPath, ".md") {
-		sanitizedPath += ".md"
+	if !strings.HasSuffix(sanitizedPath, ext) {
+		sanitizedPath += ext
 	}
 
-	return filepath.Join(s.markdownPath, sanitizedPath)
+	return filepath.Join(dir, sanitizedPath)
+}
+
+// PageSection returns the top-level section of a page URL: the sanitized
+// first component of its path, or an empty string for pages at the root of
+// the site. Pages of a section are stored under the same folder.
+func PageSection(pageURL string) string {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	section, _, ok := strings.Cut(strings.TrimPrefix(parsedURL.Path, "/"), "/")
+	if !ok {
+		return ""
+	}
+	return filenameSanitizer.ReplaceAllString(section, "_")
 }
 
 // GetMediaPath returns the path for storing a media file
@@ -153,24 +269,55 @@ func (s *Storage) GetMediaPath(mediaURL string, filename string) string {
 		return filepath.Join(s.mediaPath, s.sanitizeFilename(filename))
 	}
 
-	// Get path without leading slash
-	path := strings.TrimPrefix(parsedURL.Path, "/")
+	// If the URL has no path, use the filename
+	relPath := MediaRelPath(parsedURL)
+	if relPath == "" {
+		return filepath.Join(s.mediaPath, s.sanitizeFilename(filename))
+	}
+
+	return filepath.Join(s.mediaPath, filepath.FromSlash(relPath))
+}
+
+// mediaSubdirs are the folders inside media/ of media types not stored at the
+// top level. Images keep the layout of their URLs for compatibility with
+// existing libraries.
+var mediaSubdirs = map[string]string{
+	"video":    "videos",
+	"audio":    "audio",
+	"document": "documents",
+}
+
+// GetTypedMediaPath returns the path for storing a media file of the given type
+func (s *Storage) GetTypedMediaPath(mediaURL string, mediaType string) string {
+	path := s.GetMediaPath(mediaURL, "")
+	subdir, ok := mediaSubdirs[mediaType]
+	if !ok {
+		return path
+	}
+	rel, err := filepath.Rel(s.mediaPath, path)
+	if err != nil {
+		return path
+	}
+	return filepath.Join(s.mediaPath, subdir, rel)
+}
 
-	// If path is empty, use the filename
+// MediaRelPath returns the slash-separated path, relative to the media folder
+// of a library, at which the media file of a URL is stored. It returns an
+// empty string for URLs without a path.
+func MediaRelPath(mediaURL *url.URL) string {
+	// Get path without leading slash
+	path := strings.TrimPrefix(mediaURL.Path, "/")
 	if path == "" {
-		return filepath.Join(s.mediaPath, s.sanitizeFilename(filename))
+		return ""
 	}
 
 	// Sanitize path components
 	pathComponents := strings.Split(path, "/")
 	for i, component := range pathComponents {
-		pathComponents[i] = s.sanitizeFilename(component)
+		pathComponents[i] = filenameSanitizer.ReplaceAllString(component, "_")
 	}
 
-	// Join path components
-	sanitizedPath := filepath.Join(pathComponents...)
-
-	return filepath.Join(s.mediaPath, sanitizedPath)
+	return strings.Join(pathComponents, "/")
 }
 
 // SaveMarkdown saves markdown content to a file
@@ -178,7 +325,7 @@ func (s *Storage) SaveMarkdown(content string, pageURL string) (*FileInfo, error
 	path := s.GetMarkdownPath(pageURL)
 
 	// Check if file exists and handle overwrite logic
-	if !s.config.OverwriteFiles {
+	if !s.overwrite() {
 		if _, err := os.Stat(path); err == nil {
 			return nil, fmt.Errorf("file already exists and overwrite is disabled: %s", path)
 		}
@@ -191,9 +338,8 @@ func (s *Storage) SaveMarkdown(content string, pageURL string) (*FileInfo, error
 	}
 
 	// Write content to file
-	s.logger.Info("Saving markdown content", map[string]interface{}{"path": path})
-	err := os.WriteFile(path, []byte(content), 0644)
-	if err != nil {
+	s.logger.Detail("Saving markdown content", map[string]interface{}{"path": path})
+	if err := writeFileAtomic(path, []byte(content), s.config.DurableWrites); err != nil {
 		return nil, fmt.Errorf("failed to write markdown file: %w", err)
 	}
 
@@ -209,6 +355,123 @@ func (s *Storage) SaveMarkdown(content string, pageURL string) (*FileInfo, error
 		Size:     fileInfo.Size(),
 		Type:     "markdown",
 		URL:      pageURL,
+		Hash:     contentHash([]byte(content)),
+	}, nil
+}
+
+// SaveHTML saves the raw or cleaned HTML of a page next to its markdown
+func (s *Storage) SaveHTML(content string, pageURL string, cleaned bool) (*FileInfo, error) {
+	path := s.GetHTMLPath(pageURL, cleaned)
+
+	// Check if file exists and handle overwrite logic
+	if !s.overwrite() {
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("file already exists and overwrite is disabled: %s", path)
+		}
+	}
+
+	// Ensure directory exists
+	dir := filepath.Dir(path)
+	if err := s.ensureDir(dir); err != nil {
+		return nil, fmt.Errorf("failed to create directory for HTML file: %w", err)
+	}
+
+	fileType := "html"
+	if cleaned {
+		fileType = "cleaned_html"
+	}
+
+	s.logger.Detail("Saving HTML content", map[string]interface{}{"path": path, "type": fileType})
+	if err := writeFileAtomic(path, []byte(content), s.config.DurableWrites); err != nil {
+		return nil, fmt.Errorf("failed to write HTML file: %w", err)
+	}
+
+	return &FileInfo{
+		Path:     path,
+		Filename: filepath.Base(path),
+		Size:     int64(len(content)),
+		Type:     fileType,
+		URL:      pageURL,
+	}, nil
+}
+
+// SaveResultJSON saves the full crawl result of a page as a JSON sidecar next to its markdown
+func (s *Storage) SaveResultJSON(raw []byte, pageURL string) (*FileInfo, error) {
+	path := s.GetResultJSONPath(pageURL)
+
+	// Check if file exists and handle overwrite logic
+	if !s.overwrite() {
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("file already exists and overwrite is disabled: %s", path)
+		}
+	}
+
+	// Ensure directory exists
+	dir := filepath.Dir(path)
+	if err := s.ensureDir(dir); err != nil {
+		return nil, fmt.Errorf("failed to create directory for JSON sidecar: %w", err)
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err != nil {
+		return nil, fmt.Errorf("failed to format crawl result: %w", err)
+	}
+
+	s.logger.Detail("Saving crawl result", map[string]interface{}{"path": path})
+	if err := writeFileAtomic(path, indented.Bytes(), s.config.DurableWrites); err != nil {
+		return nil, fmt.Errorf("failed to write JSON sidecar: %w", err)
+	}
+
+	return &FileInfo{
+		Path:     path,
+		Filename: filepath.Base(path),
+		Size:     int64(indented.Len()),
+		Type:     "json",
+		URL:      pageURL,
+	}, nil
+}
+
+// SaveExtraction saves the structured extraction result for a page as JSON
+func (s *Storage) SaveExtraction(content string, pageURL string) (*FileInfo, error) {
+	path := s.GetExtractionPath(pageURL)
+
+	// Check if file exists and handle overwrite logic
+	if !s.overwrite() {
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("file already exists and overwrite is disabled: %s", path)
+		}
+	}
+
+	// Ensure directory exists
+	dir := filepath.Dir(path)
+	if err := s.ensureDir(dir); err != nil {
+		return nil, fmt.Errorf("failed to create directory for extraction file: %w", err)
+	}
+
+	// crawl4ai returns the extraction result as a JSON string; pretty-print it
+	// when possible and fall back to the raw content otherwise
+	data := []byte(content)
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err == nil {
+		data = indented.Bytes()
+	} else {
+		s.logger.Warn("Extraction result is not valid JSON, saving as-is", map[string]interface{}{
+			"url":   pageURL,
+			"error": err,
+		})
+	}
+
+	s.logger.Detail("Saving extraction result", map[string]interface{}{"path": path})
+	if err := writeFileAtomic(path, data, s.config.DurableWrites); err != nil {
+		return nil, fmt.Errorf("failed to write extraction file: %w", err)
+	}
+
+	return &FileInfo{
+		Path:     path,
+		Filename: filepath.Base(path),
+		Size:     int64(len(data)),
+		Type:     "extraction",
+		URL:      pageURL,
 	}, nil
 }
 
@@ -221,7 +484,7 @@ func (s *Storage) SaveMedia(reader io.Reader, mediaURL string, filename string)
 	path := s.GetMediaPath(mediaURL, filename)
 
 	// Check if file exists and handle overwrite logic
-	if !s.config.OverwriteFiles {
+	if !s.overwrite() {
 		if _, err := os.Stat(path); err == nil {
 			return nil, fmt.Errorf("file already exists and overwrite is disabled: %s", path)
 		}
@@ -233,31 +496,15 @@ func (s *Storage) SaveMedia(reader io.Reader, mediaURL string, filename string)
 		return nil, fmt.Errorf("failed to create directory for media file: %w", err)
 	}
 
-	// Create file
-	file, err := os.Create(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create media file: %w", err)
-	}
-	defer file.Close()
-
-	// Copy content from reader to file
-	s.logger.Info("Saving media file", map[string]interface{}{"path": path})
-	size, err := io.Copy(file, reader)
+	// Write the file, sharing the content of an identical file saved earlier
+	s.logger.Detail("Saving media file", map[string]interface{}{"path": path})
+	size, hash, err := s.writeMedia(reader, path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to write media file: %w", err)
 	}
 
 	// Determine file type based on extension
-	ext := strings.ToLower(filepath.Ext(filename))
-	fileType := "other"
-	switch ext {
-	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".svg", ".webp":
-		fileType = "image"
-	case ".mp4", ".avi", ".mov", ".wmv", ".flv", ".webm":
-		fileType = "video"
-	case ".mp3", ".wav", ".ogg", ".flac", ".aac":
-		fileType = "audio"
-	}
+	fileType := MediaType(path)
 
 	return &FileInfo{
 		Path:     path,
@@ -265,19 +512,37 @@ func (s *Storage) SaveMedia(reader io.Reader, mediaURL string, filename string)
 		Size:     size,
 		Type:     fileType,
 		URL:      mediaURL,
+		Hash:     hash,
 	}, nil
 }
 
 // SaveMediaFile saves a media file from a reader with a specific filename
 func (s *Storage) SaveMediaFile(reader io.Reader, mediaURL string, filename string) (*FileInfo, error) {
+	path := s.GetMediaPath(mediaURL, filename)
+	return s.saveMediaFileAt(reader, mediaURL, path, MediaType(path))
+}
+
+// SaveTypedMediaFile saves a media file of the given type (image, video or
+// audio) from a reader. Videos and audio files are kept in their own folders,
+// unless the cas layout stores every file as a content-addressed object.
+func (s *Storage) SaveTypedMediaFile(reader io.Reader, mediaURL string, mediaType string) (*FileInfo, error) {
+	if s.casLayout() && s.config.IncludeMedia {
+		fileInfo, err := s.saveMediaObject(reader, mediaURL, mediaType)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.StorageError, "failed to write media file")
+		}
+		return fileInfo, nil
+	}
+	return s.saveMediaFileAt(reader, mediaURL, s.GetTypedMediaPath(mediaURL, mediaType), mediaType)
+}
+
+func (s *Storage) saveMediaFileAt(reader io.Reader, mediaURL string, path string, fileType string) (*FileInfo, error) {
 	if !s.config.IncludeMedia {
 		return nil, nil // Skip media files if not configured to include them
 	}
 
-	path := s.GetMediaPath(mediaURL, filename)
-
 	// Check if file exists and handle overwrite logic
-	if !s.config.OverwriteFiles {
+	if !s.overwrite() {
 		if _, err := os.Stat(path); err == nil {
 			return nil, errors.New(errors.StorageError, fmt.Sprintf("file already exists and overwrite is disabled: %s", path))
 		}
@@ -289,37 +554,19 @@ func (s *Storage) SaveMediaFile(reader io.Reader, mediaURL string, filename stri
 		return nil, errors.Wrap(err, errors.StorageError, "failed to create directory for media file")
 	}
 
-	// Create file
-	file, err := os.Create(path)
-	if err != nil {
-		return nil, errors.Wrap(err, errors.StorageError, "failed to create media file")
-	}
-	defer file.Close()
-
-	// Copy content from reader to file
-	s.logger.Info("Saving media file", map[string]interface{}{"path": path})
-	size, err := io.Copy(file, reader)
+	// Write the file, sharing the content of an identical file saved earlier
+	s.logger.Detail("Saving media file", map[string]interface{}{"path": path})
+	size, hash, err := s.writeMedia(reader, path)
 	if err != nil {
 		return nil, errors.Wrap(err, errors.StorageError, "failed to write media file")
 	}
 
-	// Determine file type based on extension
-	ext := strings.ToLower(filepath.Ext(filename))
-	fileType := "other"
-	switch ext {
-	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".svg", ".webp":
-		fileType = "image"
-	case ".mp4", ".avi", ".mov", ".wmv", ".flv", ".webm":
-		fileType = "video"
-	case ".mp3", ".wav", ".ogg", ".flac", ".aac":
-		fileType = "audio"
-	}
-
 	return &FileInfo{
 		Path:     path,
 		Filename: filepath.Base(path),
 		Size:     size,
 		Type:     fileType,
 		URL:      mediaURL,
+		Hash:     hash,
 	}, nil
 }
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// VerifyResult lists the files of a library that do not match its manifest
+type VerifyResult struct {
+	Checked  int      // files listed in the manifest
+	Missing  []string // files listed in the manifest but absent, relative to the library
+	Modified []string // files whose size or hash differs from the manifest
+}
+
+// OK reports whether every file listed in the manifest is intact
+func (r *VerifyResult) OK() bool {
+	return len(r.Missing) == 0 && len(r.Modified) == 0
+}
+
+// VerifyLibrary checks the files of the library stored at libraryPath against
+// the sizes and hashes recorded in its manifest. Media objects shared by
+// several URLs are checked once.
+func VerifyLibrary(libraryPath string) (*VerifyResult, error) {
+	manifest, err := LoadManifest(libraryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{}
+	checked := make(map[string]bool)
+	for _, entry := range append(manifest.Pages, manifest.Media...) {
+		if checked[entry.Path] {
+			continue
+		}
+		checked[entry.Path] = true
+		result.Checked++
+
+		size, hash, err := fileHash(filepath.Join(libraryPath, filepath.FromSlash(entry.Path)))
+		if os.IsNotExist(err) {
+			result.Missing = append(result.Missing, entry.Path)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify %s: %w", entry.Path, err)
+		}
+		if size != entry.Size || (entry.Hash != "" && hash != entry.Hash) {
+			result.Modified = append(result.Modified, entry.Path)
+		}
+	}
+	sort.Strings(result.Missing)
+	sort.Strings(result.Modified)
+	return result, nil
+}
+
+// fileHash returns the size and hex-encoded SHA-256 of the file at path
+func fileHash(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WARCDir is the directory of the WARC files inside the library
+const WARCDir = "warc"
+
+// warcVersion is the version of the WARC format written
+const warcVersion = "WARC/1.1"
+
+// GetWARCPath returns the path of the WARC file of the current crawl
+func (s *Storage) GetWARCPath() string {
+	return s.warcPath
+}
+
+// WriteWARCPage records a page fetched by crawl4ai as a WARC response record.
+// It does nothing unless WARC output is enabled.
+func (s *Storage) WriteWARCPage(targetURL string, statusCode int, header http.Header, html string) error {
+	if !s.config.WARC {
+		return nil
+	}
+	if header.Get("Content-Type") == "" {
+		header = header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set("Content-Type", "text/html; charset=utf-8")
+	}
+	body := []byte(html)
+	return s.writeWARCResponse(targetURL, statusCode, header, int64(len(body)), func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	})
+}
+
+// WriteWARCMedia records a saved media file as a WARC response record, reading
+// the payload back from the library. It does nothing unless WARC output is
+// enabled.
+func (s *Storage) WriteWARCMedia(fileInfo *FileInfo, statusCode int, header http.Header) error {
+	if !s.config.WARC {
+		return nil
+	}
+	return s.writeWARCResponse(fileInfo.URL, statusCode, header, fileInfo.Size, func() (io.ReadCloser, error) {
+		return os.Open(fileInfo.Path)
+	})
+}
+
+// writeWARCResponse writes a response record whose block is an HTTP response
+// with the given status, headers and payload. The payload is read twice: once
+// for the digests and once to write it.
+func (s *Storage) writeWARCResponse(targetURL string, statusCode int, header http.Header, size int64, open func() (io.ReadCloser, error)) error {
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	httpHead := httpResponseHead(statusCode, header, size)
+
+	blockDigest, payloadDigest := sha1.New(), sha1.New()
+	blockDigest.Write(httpHead)
+	if err := readPayload(open, io.MultiWriter(blockDigest, payloadDigest)); err != nil {
+		return err
+	}
+
+	recordID, err := newRecordID()
+	if err != nil {
+		return err
+	}
+	fields := [][2]string{
+		{"WARC-Type", "response"},
+		{"WARC-Record-ID", recordID},
+		{"WARC-Date", time.Now().UTC().Format(time.RFC3339)},
+		{"WARC-Target-URI", targetURL},
+		{"WARC-Block-Digest", warcDigest(blockDigest)},
+		{"WARC-Payload-Digest", warcDigest(payloadDigest)},
+		{"Content-Type", "application/http;msgtype=response"},
+		{"Content-Length", fmt.Sprint(int64(len(httpHead)) + size)},
+	}
+
+	return s.writeWARCRecord(fields, func(w io.Writer) error {
+		if _, err := w.Write(httpHead); err != nil {
+			return err
+		}
+		return readPayload(open, w)
+	})
+}
+
+// writeWARCRecord appends a record to the WARC file as its own gzip member,
+// opening the file with a warcinfo record on first use
+func (s *Storage) writeWARCRecord(fields [][2]string, writeBlock func(io.Writer) error) error {
+	s.warcMutex.Lock()
+	defer s.warcMutex.Unlock()
+
+	if s.warcFile == nil {
+		if err := s.openWARC(); err != nil {
+			return err
+		}
+	}
+	return appendWARCRecord(s.warcFile, fields, writeBlock)
+}
+
+// openWARC creates the WARC file of the crawl and writes its warcinfo record.
+// Must be called with warcMutex held.
+func (s *Storage) openWARC() error {
+	dir := filepath.Join(s.libraryPath, WARCDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create WARC directory: %w", err)
+	}
+	name := fmt.Sprintf("crawlr-%s.warc.gz", time.Now().UTC().Format("20060102150405"))
+	path := filepath.Join(dir, name)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open WARC file: %w", err)
+	}
+
+	info := []byte(fmt.Sprintf("software: crawlr\r\nformat: WARC File Format 1.1\r\n"+
+		"conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n"+
+		"isPartOf: %s\r\n", s.config.Library))
+	recordID, err := newRecordID()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	fields := [][2]string{
+		{"WARC-Type", "warcinfo"},
+		{"WARC-Record-ID", recordID},
+		{"WARC-Date", time.Now().UTC().Format(time.RFC3339)},
+		{"WARC-Filename", name},
+		{"Content-Type", "application/warc-fields"},
+		{"Content-Length", fmt.Sprint(len(info))},
+	}
+	if err := appendWARCRecord(file, fields, func(w io.Writer) error {
+		_, err := w.Write(info)
+		return err
+	}); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write warcinfo record: %w", err)
+	}
+
+	s.warcFile = file
+	s.warcPath = path
+	return nil
+}
+
+// closeWARC closes the WARC file, if open
+func (s *Storage) closeWARC() error {
+	s.warcMutex.Lock()
+	defer s.warcMutex.Unlock()
+
+	if s.warcFile == nil {
+		return nil
+	}
+	err := s.warcFile.Close()
+	s.warcFile = nil
+	return err
+}
+
+// appendWARCRecord writes a record with its header fields and block, followed
+// by the two CRLFs ending a record, as a gzip member
+func appendWARCRecord(w io.Writer, fields [][2]string, writeBlock func(io.Writer) error) error {
+	gz := gzip.NewWriter(w)
+
+	var head strings.Builder
+	head.WriteString(warcVersion + "\r\n")
+	for _, field := range fields {
+		fmt.Fprintf(&head, "%s: %s\r\n", field[0], field[1])
+	}
+	head.WriteString("\r\n")
+	if _, err := io.WriteString(gz, head.String()); err != nil {
+		return err
+	}
+	if err := writeBlock(gz); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(gz, "\r\n\r\n"); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// httpResponseHead renders the status line and headers of an HTTP response
+// carrying a payload of the given size. Headers describing a transfer or
+// content encoding are dropped since the payload is stored decoded.
+func httpResponseHead(statusCode int, header http.Header, size int64) []byte {
+	var head strings.Builder
+	fmt.Fprintf(&head, "HTTP/1.1 %d %s\r\n", statusCode, http.StatusText(statusCode))
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+			continue
+		}
+		for _, value := range header[name] {
+			fmt.Fprintf(&head, "%s: %s\r\n", name, value)
+		}
+	}
+	fmt.Fprintf(&head, "Content-Length: %d\r\n\r\n", size)
+	return []byte(head.String())
+}
+
+// readPayload copies a payload to w
+func readPayload(open func() (io.ReadCloser, error), w io.Writer) error {
+	reader, err := open()
+	if err != nil {
+		return fmt.Errorf("failed to read WARC payload: %w", err)
+	}
+	defer reader.Close()
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to read WARC payload: %w", err)
+	}
+	return nil
+}
+
+// warcDigest formats a SHA-1 digest the way WARC tools expect it
+func warcDigest(h hash.Hash) string {
+	return "sha1:" + base32.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// newRecordID returns a random UUID URN identifying a record
+func newRecordID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate record ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,235 @@
+// Package transform applies the page transformation pipeline configured under
+// transforms to the markdown of crawled pages: ordered cleanup steps that
+// would otherwise need hooks or external scripts.
+package transform
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"crawlr/internal/config"
+	"crawlr/internal/storage"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Page identifies the page being transformed, for placeholders of injected
+// front matter values
+type Page struct {
+	URL   string
+	Title string
+}
+
+// document is a markdown page split into its front matter and body
+type document struct {
+	block string     // raw front matter, without delimiters
+	node  *yaml.Node // decoded front matter, once a step changes it
+	body  string
+}
+
+// step transforms a document
+type step func(doc *document, page Page) error
+
+// Pipeline is a compiled list of transformation steps
+type Pipeline struct {
+	steps []step
+}
+
+// New compiles the transformation steps of the configuration. strip_selector
+// steps are left out: crawl4ai applies them while converting pages, see
+// StripSelectors.
+func New(steps []config.TransformStep) (*Pipeline, error) {
+	p := &Pipeline{}
+	for i, s := range steps {
+		var compiled step
+		var err error
+		switch {
+		case s.StripSelector != "":
+			continue
+		case s.RegexReplace != nil:
+			compiled, err = regexReplace(s.RegexReplace)
+		case len(s.InjectFrontMatter) > 0:
+			compiled = injectFrontMatter(s.InjectFrontMatter)
+		case s.RewriteLinks != nil:
+			compiled, err = rewriteLinks(s.RewriteLinks)
+		case s.Truncate != nil:
+			compiled = truncate(s.Truncate)
+		default:
+			err = fmt.Errorf("no step set")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transforms[%d]: %w", i, err)
+		}
+		p.steps = append(p.steps, compiled)
+	}
+	return p, nil
+}
+
+// StripSelectors returns the selectors of the strip_selector steps as a
+// single CSS selector list, to be excluded by crawl4ai. crawlr receives pages
+// already converted to markdown, so elements can only be stripped there.
+func StripSelectors(steps []config.TransformStep) string {
+	var selectors []string
+	for _, s := range steps {
+		if s.StripSelector != "" {
+			selectors = append(selectors, s.StripSelector)
+		}
+	}
+	return strings.Join(selectors, ", ")
+}
+
+// Empty reports whether the pipeline has no step to run locally
+func (p *Pipeline) Empty() bool {
+	return p == nil || len(p.steps) == 0
+}
+
+// Apply runs the steps in order on the markdown of a page
+func (p *Pipeline) Apply(markdown string, page Page) (string, error) {
+	if p.Empty() {
+		return markdown, nil
+	}
+
+	doc := &document{body: markdown}
+	if block, body, ok := storage.SplitFrontMatter(markdown); ok {
+		doc.block, doc.body = block, body
+	}
+	for _, s := range p.steps {
+		if err := s(doc, page); err != nil {
+			return markdown, err
+		}
+	}
+	return doc.render()
+}
+
+// render joins the front matter and body of a document again
+func (d *document) render() (string, error) {
+	block := d.block
+	if d.node != nil {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(d.node); err != nil {
+			return "", fmt.Errorf("failed to encode front matter: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return "", fmt.Errorf("failed to encode front matter: %w", err)
+		}
+		block = buf.String()
+	}
+	if block == "" {
+		return d.body, nil
+	}
+	return "---\n" + block + "---\n\n" + d.body, nil
+}
+
+// regexReplace replaces the matches of a pattern in the body
+func regexReplace(r *config.ReplaceStep) (step, error) {
+	pattern, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex_replace pattern: %w", err)
+	}
+	return func(doc *document, page Page) error {
+		doc.body = pattern.ReplaceAllString(doc.body, r.Replacement)
+		return nil
+	}, nil
+}
+
+// linkTargetPattern matches the targets of inline links and images, and of
+// reference definitions
+var linkTargetPattern = regexp.MustCompile(`(\]\(\s*)(<[^>]*>|[^)\s]+)|(?m)(^[ \t]*\[[^\]]+\]:[ \t]*)(\S+)`)
+
+// rewriteLinks replaces the matches of a pattern in link targets only
+func rewriteLinks(r *config.ReplaceStep) (step, error) {
+	pattern, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rewrite_links pattern: %w", err)
+	}
+	return func(doc *document, page Page) error {
+		doc.body = linkTargetPattern.ReplaceAllStringFunc(doc.body, func(match string) string {
+			groups := linkTargetPattern.FindStringSubmatch(match)
+			prefix, target := groups[1], groups[2]
+			if prefix == "" {
+				prefix, target = groups[3], groups[4]
+			}
+			if strings.HasPrefix(target, "<") && strings.HasSuffix(target, ">") {
+				return prefix + "<" + pattern.ReplaceAllString(target[1:len(target)-1], r.Replacement) + ">"
+			}
+			return prefix + pattern.ReplaceAllString(target, r.Replacement)
+		})
+		return nil
+	}, nil
+}
+
+// injectFrontMatter sets keys of the front matter, creating it if needed.
+// {url} and {title} in string values are replaced with those of the page.
+func injectFrontMatter(values map[string]interface{}) step {
+	return func(doc *document, page Page) error {
+		if doc.node == nil {
+			doc.node = &yaml.Node{Kind: yaml.MappingNode}
+			if doc.block != "" {
+				var root yaml.Node
+				if err := yaml.Unmarshal([]byte(doc.block), &root); err != nil {
+					return fmt.Errorf("failed to decode front matter: %w", err)
+				}
+				if len(root.Content) > 0 && root.Content[0].Kind == yaml.MappingNode {
+					doc.node = root.Content[0]
+				}
+			}
+		}
+
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		placeholders := strings.NewReplacer("{url}", page.URL, "{title}", page.Title)
+		for _, key := range keys {
+			value := values[key]
+			if s, ok := value.(string); ok {
+				value = placeholders.Replace(s)
+			}
+			var valueNode yaml.Node
+			if err := valueNode.Encode(value); err != nil {
+				return fmt.Errorf("failed to encode front matter value of %s: %w", key, err)
+			}
+			setKey(doc.node, key, &valueNode)
+		}
+		return nil
+	}
+}
+
+// setKey sets a key of a YAML mapping, replacing its value if present
+func setKey(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// truncate cuts the body after MaxChars characters, at the last paragraph
+// break before the limit when there is one
+func truncate(t *config.TruncateStep) step {
+	return func(doc *document, page Page) error {
+		if utf8.RuneCountInString(doc.body) <= t.MaxChars {
+			return nil
+		}
+		cut := 0
+		for i := 0; i < t.MaxChars; i++ {
+			_, size := utf8.DecodeRuneInString(doc.body[cut:])
+			cut += size
+		}
+		if paragraph := strings.LastIndex(doc.body[:cut], "\n\n"); paragraph > 0 {
+			cut = paragraph
+		}
+		doc.body = strings.TrimRight(doc.body[:cut], "\n") + t.Marker
+		return nil
+	}
+}
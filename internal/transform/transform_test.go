@@ -0,0 +1,164 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"crawlr/internal/config"
+)
+
+func TestApply(t *testing.T) {
+	page := Page{URL: "https://docs.example.com/guide", Title: "Guide"}
+	tests := []struct {
+		name     string
+		steps    []config.TransformStep
+		markdown string
+		want     string
+	}{
+		{
+			name:     "no steps",
+			markdown: "# Guide\n",
+			want:     "# Guide\n",
+		},
+		{
+			name:     "strip_selector runs in crawl4ai",
+			steps:    []config.TransformStep{{StripSelector: "nav"}},
+			markdown: "# Guide\n",
+			want:     "# Guide\n",
+		},
+		{
+			name:     "regex_replace",
+			steps:    []config.TransformStep{{RegexReplace: &config.ReplaceStep{Pattern: `v(\d+)\.x`, Replacement: "version $1"}}},
+			markdown: "Install v2.x or v3.x.\n",
+			want:     "Install version 2 or version 3.\n",
+		},
+		{
+			name:     "regex_replace leaves front matter alone",
+			steps:    []config.TransformStep{{RegexReplace: &config.ReplaceStep{Pattern: "Guide", Replacement: "Manual"}}},
+			markdown: "---\ntitle: Guide\n---\n\n# Guide\n",
+			want:     "---\ntitle: Guide\n---\n\n# Manual\n",
+		},
+		{
+			name: "inject_frontmatter creates it",
+			steps: []config.TransformStep{{InjectFrontMatter: map[string]interface{}{
+				"source": "{url}",
+				"title":  "{title} | Docs",
+				"weight": 3,
+			}}},
+			markdown: "# Guide\n",
+			want:     "---\nsource: https://docs.example.com/guide\ntitle: Guide | Docs\nweight: 3\n---\n\n# Guide\n",
+		},
+		{
+			name:     "inject_frontmatter replaces keys",
+			steps:    []config.TransformStep{{InjectFrontMatter: map[string]interface{}{"title": "{title}", "draft": false}}},
+			markdown: "---\ntitle: Old\ntags:\n  - api\n---\n\n# Guide\n",
+			want:     "---\ntitle: Guide\ntags:\n  - api\ndraft: false\n---\n\n# Guide\n",
+		},
+		{
+			name:  "rewrite_links",
+			steps: []config.TransformStep{{RewriteLinks: &config.ReplaceStep{Pattern: `^https://docs\.example\.com/`, Replacement: "/"}}},
+			markdown: "See [the API](https://docs.example.com/api) and ![logo](<https://docs.example.com/logo.png>).\n" +
+				"Text https://docs.example.com/ stays.\n\n[ref]: https://docs.example.com/ref\n",
+			want: "See [the API](/api) and ![logo](</logo.png>).\n" +
+				"Text https://docs.example.com/ stays.\n\n[ref]: /ref\n",
+		},
+		{
+			name:     "truncate at a paragraph break",
+			steps:    []config.TransformStep{{Truncate: &config.TruncateStep{MaxChars: 20, Marker: "\n\n…"}}},
+			markdown: "First paragraph.\n\nSecond paragraph.\n",
+			want:     "First paragraph.\n\n…",
+		},
+		{
+			name:     "truncate within a paragraph",
+			steps:    []config.TransformStep{{Truncate: &config.TruncateStep{MaxChars: 9}}},
+			markdown: "ééééé ééééé ééééé",
+			want:     "ééééé ééé",
+		},
+		{
+			name:     "truncate short body",
+			steps:    []config.TransformStep{{Truncate: &config.TruncateStep{MaxChars: 100, Marker: "…"}}},
+			markdown: "Short.\n",
+			want:     "Short.\n",
+		},
+		{
+			name: "steps run in order",
+			steps: []config.TransformStep{
+				{RegexReplace: &config.ReplaceStep{Pattern: "beta", Replacement: "stable"}},
+				{RegexReplace: &config.ReplaceStep{Pattern: "stable", Replacement: "final"}},
+			},
+			markdown: "beta release\n",
+			want:     "final release\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := New(tt.steps)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			got, err := pipeline.Apply(tt.markdown, page)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []config.TransformStep
+		want  string
+	}{
+		{"empty step", []config.TransformStep{{}}, "transforms[0]: no step set"},
+		{"invalid regex_replace", []config.TransformStep{{RegexReplace: &config.ReplaceStep{Pattern: "("}}}, "transforms[0]: invalid regex_replace pattern"},
+		{
+			name: "invalid rewrite_links after a valid step",
+			steps: []config.TransformStep{
+				{Truncate: &config.TruncateStep{MaxChars: 10}},
+				{RewriteLinks: &config.ReplaceStep{Pattern: "[a-"}},
+			},
+			want: "transforms[1]: invalid rewrite_links pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.steps)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("New() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyInvalidFrontMatter(t *testing.T) {
+	pipeline, err := New([]config.TransformStep{{InjectFrontMatter: map[string]interface{}{"title": "{title}"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	markdown := "---\ntitle: [unclosed\n---\n\n# Guide\n"
+	got, err := pipeline.Apply(markdown, Page{Title: "Guide"})
+	if err == nil {
+		t.Fatal("Apply() with invalid front matter succeeded")
+	}
+	if got != markdown {
+		t.Errorf("Apply() = %q on error, want the markdown unchanged", got)
+	}
+}
+
+func TestStripSelectors(t *testing.T) {
+	steps := []config.TransformStep{
+		{StripSelector: "nav"},
+		{Truncate: &config.TruncateStep{MaxChars: 10}},
+		{StripSelector: ".sidebar, footer"},
+	}
+	if got, want := StripSelectors(steps), "nav, .sidebar, footer"; got != want {
+		t.Errorf("StripSelectors() = %q, want %q", got, want)
+	}
+	if got := StripSelectors(nil); got != "" {
+		t.Errorf("StripSelectors(nil) = %q, want empty", got)
+	}
+}
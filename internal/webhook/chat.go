@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"crawlr/internal/config"
+)
+
+// AddChat adds the Slack and Discord webhooks of the notifications
+// configuration, posted a formatted message when a crawl completes or fails
+func (n *Notifier) AddChat(cfg config.NotificationsConfig) {
+	if cfg.Slack.WebhookURL != "" {
+		n.targets = append(n.targets, target{
+			url:  cfg.Slack.WebhookURL,
+			body: chatBody("text", "*", cfg.Slack.OnlyFailures),
+		})
+	}
+	if cfg.Discord.WebhookURL != "" {
+		n.targets = append(n.targets, target{
+			url:  cfg.Discord.WebhookURL,
+			body: chatBody("content", "**", cfg.Discord.OnlyFailures),
+		})
+	}
+}
+
+// chatBody returns the body of a chat webhook, a JSON object with the message
+// under field, bold text being wrapped in bold. Only completions and failures
+// are posted, failures alone with onlyFailures.
+func chatBody(field, bold string, onlyFailures bool) func(Payload) ([]byte, bool) {
+	return func(payload Payload) ([]byte, bool) {
+		if payload.Event != EventFailed && (payload.Event != EventCompleted || onlyFailures) {
+			return nil, false
+		}
+		body, err := json.Marshal(map[string]string{field: chatMessage(payload, bold)})
+		return body, err == nil
+	}
+}
+
+// chatMessage formats a completed or failed crawl for a chat channel
+func chatMessage(payload Payload, bold string) string {
+	var b strings.Builder
+	if payload.Event == EventFailed {
+		fmt.Fprintf(&b, "❌ %scrawlr: crawl of %s failed%s\n", bold, payload.URL, bold)
+		fmt.Fprintf(&b, "Error: %s\n", payload.Error)
+	} else {
+		fmt.Fprintf(&b, "✅ %scrawlr: crawl of %s completed%s\n", bold, payload.URL, bold)
+	}
+
+	stats := payload.Stats
+	pages := stats.PagesCrawled
+	if pages == 0 {
+		pages = stats.PagesProcessed
+	}
+	fmt.Fprintf(&b, "Pages crawled: %d (%d saved, %d failed)\n", pages, stats.PagesSaved, stats.PagesFailed)
+	fmt.Fprintf(&b, "Duration: %s\n", time.Duration(stats.Duration*float64(time.Second)).Round(time.Second))
+	fmt.Fprintf(&b, "Library: %s", payload.Output)
+	return b.String()
+}
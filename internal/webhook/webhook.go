@@ -0,0 +1,155 @@
+// Package webhook notifies webhook URLs of the progress of a crawl, posting a
+// JSON payload with its summary stats when it starts, every N pages if
+// configured, and when it completes or fails, so that CI systems and chat bots
+// can react to crawl results. Slack and Discord webhooks are posted a
+// formatted message instead, when the crawl completes or fails.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"crawlr/internal/report"
+)
+
+// Events notified to webhooks
+const (
+	EventStarted   = "crawl.started"
+	EventProgress  = "crawl.progress"
+	EventCompleted = "crawl.completed"
+	EventFailed    = "crawl.failed"
+)
+
+// Payload is the JSON body posted to webhooks
+type Payload struct {
+	Event     string    `json:"event"`
+	Library   string    `json:"library"`
+	URL       string    `json:"url"`
+	Output    string    `json:"output"`
+	Timestamp time.Time `json:"timestamp"`
+	Error     string    `json:"error,omitempty"` // why the crawl failed, for crawl.failed
+	Stats     Stats     `json:"stats"`
+}
+
+// Stats are the summary stats of a crawl so far
+type Stats struct {
+	PagesProcessed int         `json:"pages_processed"` // pages saved, skipped or failed so far
+	PagesCrawled   int         `json:"pages_crawled"`   // results returned by the crawler, once it completes
+	PagesSaved     int         `json:"pages_saved"`
+	PagesFailed    int         `json:"pages_failed"`
+	PagesRejected  int         `json:"pages_rejected"`
+	PagesUnchanged int         `json:"pages_unchanged"`
+	MediaSaved     int         `json:"media_saved"`
+	BytesWritten   int64       `json:"bytes_written"`
+	Errors         int         `json:"errors"`
+	Duration       float64     `json:"duration_s"`
+	Cancelled      bool        `json:"cancelled,omitempty"`
+	StatusCodes    map[int]int `json:"status_codes,omitempty"`
+}
+
+// Notifier posts payloads to webhook URLs. A Notifier without URLs does
+// nothing.
+type Notifier struct {
+	targets []target
+	client  *http.Client
+}
+
+// target is a webhook URL with the body it expects for a payload
+type target struct {
+	url  string
+	body func(payload Payload) ([]byte, bool) // false to skip the payload
+}
+
+// New returns a notifier posting the JSON payload to urls, each request
+// bounded by timeout
+func New(urls []string, timeout time.Duration) *Notifier {
+	n := &Notifier{client: &http.Client{Timeout: timeout}}
+	for _, url := range urls {
+		n.targets = append(n.targets, target{url: url, body: jsonBody})
+	}
+	return n
+}
+
+// Enabled reports whether the notifier has webhook URLs to post to
+func (n *Notifier) Enabled() bool {
+	return len(n.targets) > 0
+}
+
+func jsonBody(payload Payload) ([]byte, bool) {
+	body, err := json.Marshal(payload)
+	return body, err == nil
+}
+
+// Notify posts event with the stats of summary to every webhook URL, returning
+// the failures joined. processed is the number of pages processed so far.
+// crawlErr is the reason of a crawl.failed event.
+func (n *Notifier) Notify(ctx context.Context, event string, summary *report.Summary, processed int, crawlErr error) error {
+	if !n.Enabled() {
+		return nil
+	}
+	payload := newPayload(event, summary, processed, time.Now().UTC())
+	if crawlErr != nil {
+		payload.Error = crawlErr.Error()
+	}
+
+	var errs []error
+	for _, t := range n.targets {
+		body, ok := t.body(payload)
+		if !ok {
+			continue
+		}
+		if err := n.post(ctx, t.url, body); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.url, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *Notifier) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func newPayload(event string, summary *report.Summary, processed int, now time.Time) Payload {
+	return Payload{
+		Event:     event,
+		Library:   summary.Library,
+		URL:       summary.URL,
+		Output:    summary.Output,
+		Timestamp: now,
+		Stats: Stats{
+			PagesProcessed: processed,
+			PagesCrawled:   summary.PagesCrawled,
+			PagesSaved:     summary.PagesSaved,
+			PagesFailed:    summary.PagesFailed,
+			PagesRejected:  summary.PagesRejected,
+			PagesUnchanged: summary.PagesUnchanged,
+			MediaSaved:     summary.MediaSaved,
+			BytesWritten:   summary.BytesWritten,
+			Errors:         len(summary.Errors),
+			Duration:       now.Sub(summary.StartedAt).Seconds(),
+			Cancelled:      summary.Cancelled,
+			StatusCodes:    summary.StatusCodes,
+		},
+	}
+}
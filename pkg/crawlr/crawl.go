@@ -0,0 +1,115 @@
+package crawlr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+
+	"crawlr/internal/crawler"
+)
+
+// ErrNoResults is returned by a crawl that crawled no page
+var ErrNoResults = errors.New("no page crawled")
+
+// Crawl crawls opts.URL and the pages it links to, yielding the pages as
+// they are crawled, failed ones included. Pages crawled successfully go
+// through the hooks and are saved to opts.Storage before being yielded; a
+// page that could not be saved, or whose hooks failed, is yielded with the
+// error, and a page a hook rejected is skipped. A crawl that fails or is
+// cancelled ends with a nil page and the error. Breaking out of the loop
+// stops the crawl.
+func (c *Client) Crawl(ctx context.Context, opts CrawlOptions) iter.Seq2[*Result, error] {
+	return func(yield func(*Result, error) bool) {
+		cfg := c.config(opts)
+		if err := cfg.Validate(); err != nil {
+			yield(nil, fmt.Errorf("invalid crawl options: %w", err))
+			return
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		pageCrawler := crawler.NewCrawler(cfg, c.logger)
+		if c.authToken != "" {
+			pageCrawler.SetAuthToken(c.authToken)
+		}
+
+		// The crawl hands each batch over and waits for it to be consumed,
+		// so it goes no faster than the loop of the caller
+		batches := make(chan []crawler.PageResult)
+		pageCrawler.SetBatchHandler(func(ctx context.Context, results []crawler.PageResult) {
+			select {
+			case batches <- results:
+			case <-ctx.Done():
+			}
+		})
+		done := make(chan error, 1)
+		go func() {
+			defer close(batches)
+			resp, err := pageCrawler.StartBatchRecursiveCrawling(ctx, cfg.URL, nil, cfg.MaxDepth, cfg.MaxURLs, cfg.BatchSize)
+			if err == nil && !resp.Success && !resp.Cancelled {
+				err = ErrNoResults
+			}
+			done <- err
+		}()
+
+		// stop cancels the crawl and waits for it to end
+		stop := func() {
+			cancel()
+			for range batches {
+			}
+		}
+
+		for results := range batches {
+			for _, page := range results {
+				result := newResult(page)
+				var err error
+				if result.Success {
+					err = save(ctx, result, opts)
+					if errors.Is(err, ErrReject) {
+						continue
+					}
+				}
+				if !yield(result, err) {
+					stop()
+					return
+				}
+			}
+			pageCrawler.ReleaseResults(results)
+		}
+
+		if err := <-done; err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := ctx.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// save runs the hooks of a page around saving it to the storage of opts. A
+// page whose pre-save hooks fail is not saved.
+func save(ctx context.Context, result *Result, opts CrawlOptions) error {
+	for _, hook := range opts.Hooks {
+		if err := hook.PreSave(ctx, result); err != nil {
+			if errors.Is(err, ErrReject) {
+				return err
+			}
+			return fmt.Errorf("pre-save hook failed on %s: %w", result.URL, err)
+		}
+	}
+	if opts.Storage != nil {
+		if err := opts.Storage.SavePage(ctx, result); err != nil {
+			return fmt.Errorf("failed to save %s: %w", result.URL, err)
+		}
+	}
+	var errs []error
+	for _, hook := range opts.Hooks {
+		if err := hook.PostSave(ctx, result); err != nil {
+			errs = append(errs, fmt.Errorf("post-save hook failed on %s: %w", result.URL, err))
+		}
+	}
+	return errors.Join(errs...)
+}
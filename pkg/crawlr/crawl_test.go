@@ -0,0 +1,160 @@
+package crawlr
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"crawlr/internal/logger"
+	"crawlr/internal/mockserver"
+)
+
+// newMockServer serves the built-in site of the mock server, counting the
+// crawl requests it answers
+func newMockServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	mock, err := mockserver.New("", logger.NewWithHandler(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var crawls atomic.Int32
+	handler := mock.Handler()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/crawl" {
+			crawls.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &crawls
+}
+
+// storageFunc is a Storage calling the function
+type storageFunc func(ctx context.Context, page *Result) error
+
+func (f storageFunc) SavePage(ctx context.Context, page *Result) error {
+	return f(ctx, page)
+}
+
+func TestCrawl(t *testing.T) {
+	server, _ := newMockServer(t)
+	client := New(server.URL)
+
+	var saved []string
+	opts := CrawlOptions{
+		URL:       server.URL + "/",
+		MaxDepth:  2,
+		BatchSize: 1,
+		Storage: storageFunc(func(ctx context.Context, page *Result) error {
+			saved = append(saved, page.URL)
+			return nil
+		}),
+		Hooks: []Hook{HookFuncs{Pre: func(ctx context.Context, page *Result) error {
+			if strings.HasSuffix(page.URL, "/api") {
+				return ErrReject
+			}
+			page.Markdown = "> crawled\n\n" + page.Markdown
+			return nil
+		}}},
+	}
+
+	var yielded []string
+	for page, err := range client.Crawl(context.Background(), opts) {
+		if err != nil {
+			t.Fatalf("Crawl() yielded error %v", err)
+		}
+		if !page.Success || page.Title == "" || !strings.HasPrefix(page.Markdown, "> crawled\n\n") {
+			t.Errorf("Crawl() yielded %s: success %v, title %q, markdown %q",
+				page.URL, page.Success, page.Title, page.Markdown)
+		}
+		yielded = append(yielded, strings.TrimPrefix(page.URL, server.URL))
+	}
+
+	slices.Sort(yielded)
+	if want := []string{"/", "/guide/", "/guide/install"}; !slices.Equal(yielded, want) {
+		t.Errorf("Crawl() yielded %v, want %v", yielded, want)
+	}
+	if len(saved) != len(yielded) {
+		t.Errorf("saved %d pages, want %d", len(saved), len(yielded))
+	}
+}
+
+func TestCrawlBreak(t *testing.T) {
+	server, crawls := newMockServer(t)
+	client := New(server.URL)
+
+	pages := 0
+	for page, err := range client.Crawl(context.Background(), CrawlOptions{URL: server.URL + "/", BatchSize: 1}) {
+		if err != nil {
+			t.Fatalf("Crawl() yielded error %v", err)
+		}
+		if page == nil {
+			t.Fatal("Crawl() yielded a nil page")
+		}
+		pages++
+		break
+	}
+	if pages != 1 {
+		t.Fatalf("loop ran %d times, want 1", pages)
+	}
+
+	// The crawl has ended once the loop is left: the request of the next
+	// batch, sent while the first page was yielded, may still reach the
+	// server, but no batch is requested afterwards
+	time.Sleep(50 * time.Millisecond)
+	requested := crawls.Load()
+	time.Sleep(100 * time.Millisecond)
+	if n := crawls.Load(); n != requested {
+		t.Errorf("crawl requested %d batches after the loop ended", n-requested)
+	}
+	if requested > 2 {
+		t.Errorf("crawl requested %d batches of one page, want at most 2", requested)
+	}
+}
+
+func TestCrawlCancelled(t *testing.T) {
+	server, _ := newMockServer(t)
+	client := New(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pages int
+	var last error
+	for page, err := range client.Crawl(ctx, CrawlOptions{URL: server.URL + "/", BatchSize: 1}) {
+		if page != nil {
+			pages++
+			cancel()
+			continue
+		}
+		last = err
+	}
+	if !errors.Is(last, context.Canceled) {
+		t.Errorf("cancelled crawl ended with %v, want %v", last, context.Canceled)
+	}
+	if pages == 0 || pages >= 4 {
+		t.Errorf("cancelled crawl yielded %d pages, want between 1 and 3", pages)
+	}
+}
+
+func TestCrawlInvalidOptions(t *testing.T) {
+	client := New("http://127.0.0.1:1")
+
+	var errs []error
+	for page, err := range client.Crawl(context.Background(), CrawlOptions{URL: "not a url"}) {
+		if page != nil {
+			t.Errorf("Crawl() yielded page %s", page.URL)
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || errs[0] == nil || !strings.HasPrefix(errs[0].Error(), "invalid crawl options") {
+		t.Errorf("Crawl() yielded %v, want a single invalid options error", errs)
+	}
+}
@@ -0,0 +1,96 @@
+// Package crawlr crawls websites through a crawl4ai server from Go programs.
+//
+// A Client crawls a site from a start URL, following its links, and hands
+// the pages over as they are crawled:
+//
+//	client := crawlr.New("http://localhost:11235")
+//	for page, err := range client.Crawl(ctx, crawlr.CrawlOptions{URL: "https://example.com", MaxDepth: 2}) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(page.URL, len(page.Markdown))
+//	}
+//
+// Pages are saved as they are crawled to the Storage of the options, if any,
+// such as a library directory opened with NewDirStorage.
+package crawlr
+
+import (
+	"log/slog"
+	"time"
+
+	"crawlr/internal/logger"
+)
+
+// Client crawls websites through a crawl4ai server. It is safe for
+// concurrent use.
+type Client struct {
+	serverURL     string
+	authToken     string
+	timeout       time.Duration
+	maxConcurrent int
+	logger        *logger.Logger
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAuthToken sets the token sent to the crawl4ai server
+func WithAuthToken(token string) Option {
+	return func(c *Client) { c.authToken = token }
+}
+
+// WithTimeout sets the timeout of each HTTP request, 30 seconds by default
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) { c.timeout = timeout }
+}
+
+// WithMaxConcurrent sets the maximum number of concurrent requests, 5 by
+// default
+func WithMaxConcurrent(n int) Option {
+	return func(c *Client) { c.maxConcurrent = n }
+}
+
+// WithLogFile logs the crawls of the client to the file at path. Clients log
+// nothing by default.
+func WithLogFile(path string) Option {
+	return func(c *Client) {
+		l, err := logger.NewLogger(logger.LoggerConfig{
+			Level:       logger.INFO,
+			Output:      logger.File,
+			FilePath:    path,
+			IncludeTime: true,
+			Structured:  true,
+		})
+		if err == nil {
+			c.logger = l
+		}
+	}
+}
+
+// WithLogHandler logs the crawls of the client through handler, e.g. the
+// handler of the slog.Logger of the program
+func WithLogHandler(handler slog.Handler) Option {
+	return func(c *Client) { c.logger = logger.NewWithHandler(handler) }
+}
+
+// New returns a client of the crawl4ai server at serverURL
+func New(serverURL string, opts ...Option) *Client {
+	c := &Client{
+		serverURL:     serverURL,
+		timeout:       30 * time.Second,
+		maxConcurrent: 5,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.logger == nil {
+		c.logger = silentLogger()
+	}
+	return c
+}
+
+// silentLogger returns a logger discarding every message
+func silentLogger() *logger.Logger {
+	return logger.NewWithHandler(slog.DiscardHandler)
+}
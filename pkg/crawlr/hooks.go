@@ -0,0 +1,41 @@
+package crawlr
+
+import (
+	"context"
+
+	"crawlr/internal/hooks"
+)
+
+// ErrReject is returned by a pre-save hook rejecting a page, which is then
+// neither saved nor yielded
+var ErrReject = hooks.ErrReject
+
+// Hook processes the pages of a crawl around saving. PreSave may change the
+// page, e.g. its markdown, or return ErrReject; PostSave runs once the page
+// is saved to the storage of the crawl, or right after PreSave without one.
+type Hook interface {
+	PreSave(ctx context.Context, page *Result) error
+	PostSave(ctx context.Context, page *Result) error
+}
+
+// HookFuncs is a Hook calling its functions, either of which may be nil
+type HookFuncs struct {
+	Pre  func(ctx context.Context, page *Result) error
+	Post func(ctx context.Context, page *Result) error
+}
+
+// PreSave calls f.Pre if set
+func (f HookFuncs) PreSave(ctx context.Context, page *Result) error {
+	if f.Pre == nil {
+		return nil
+	}
+	return f.Pre(ctx, page)
+}
+
+// PostSave calls f.Post if set
+func (f HookFuncs) PostSave(ctx context.Context, page *Result) error {
+	if f.Post == nil {
+		return nil
+	}
+	return f.Post(ctx, page)
+}
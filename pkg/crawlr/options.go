@@ -0,0 +1,70 @@
+package crawlr
+
+import (
+	"strings"
+
+	"crawlr/internal/config"
+)
+
+// CrawlOptions describes a crawl. Zero values fall back to the defaults of
+// the crawlr command.
+type CrawlOptions struct {
+	// URL is the start URL of the crawl (required)
+	URL string
+
+	MaxDepth  int // maximum link distance from the start URL, 2 by default
+	MaxURLs   int // maximum number of pages crawled, 50 by default
+	BatchSize int // pages sent to crawl4ai per request, 5 by default
+
+	// Strategy is the order in which discovered pages are crawled: bfs
+	// (default), dfs or bestfirst
+	Strategy string
+
+	// ExcludePatterns is a regular expression of URLs not to crawl
+	ExcludePatterns string
+
+	// IncludeSubdomains also crawls the subdomains of the start URL's host,
+	// and AllowedDomains lists other domains crawled with their subdomains
+	IncludeSubdomains bool
+	AllowedDomains    []string
+
+	// CSSSelector restricts the markdown to the page content it selects
+	CSSSelector string
+
+	// Storage receives the pages as they are crawled, if not nil
+	Storage Storage
+
+	// Hooks run in order on every page crawled successfully, around saving
+	Hooks []Hook
+}
+
+// config returns the crawlr configuration of a crawl with opts
+func (c *Client) config(opts CrawlOptions) *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.ServerURL = c.serverURL
+	cfg.Timeout = int(c.timeout.Seconds())
+	cfg.MaxConcurrent = c.maxConcurrent
+
+	cfg.URL = opts.URL
+	if opts.MaxDepth > 0 {
+		cfg.MaxDepth = opts.MaxDepth
+	}
+	if opts.MaxURLs > 0 {
+		cfg.MaxURLs = opts.MaxURLs
+	}
+	if opts.BatchSize > 0 {
+		cfg.BatchSize = opts.BatchSize
+	}
+	if opts.Strategy != "" {
+		cfg.Strategy = opts.Strategy
+	}
+	cfg.ExcludePatterns = opts.ExcludePatterns
+	cfg.IncludeSubdomains = opts.IncludeSubdomains
+	cfg.AllowedDomains = strings.Join(opts.AllowedDomains, ",")
+	cfg.CSSSelector = opts.CSSSelector
+
+	// The crawler itself writes nothing; pages go to opts.Storage
+	cfg.Library = "crawlr"
+	cfg.Output = "."
+	return cfg
+}
@@ -0,0 +1,63 @@
+package crawlr
+
+import (
+	"crawlr/internal/crawler"
+)
+
+// Result is a page of a crawl
+type Result struct {
+	URL        string
+	FinalURL   string // URL served after redirects, the same as URL without redirect
+	StatusCode int
+	Depth      int // link distance from the start URL
+
+	// Success is false for pages that could not be crawled, Error then
+	// telling why
+	Success bool
+	Error   string
+
+	Title    string
+	Markdown string
+	HTML     string
+
+	Links []string // links followed from the page
+	Media []Media  // media files found on the page
+}
+
+// Media is a media file found on a page
+type Media struct {
+	URL  string
+	Type string // image, video, audio or document
+}
+
+// newResult converts a crawled page to a Result
+func newResult(page crawler.PageResult) *Result {
+	result := &Result{
+		URL:        page.URL,
+		FinalURL:   page.FinalURL(),
+		StatusCode: page.StatusCode,
+		Depth:      page.Depth,
+		Success:    page.Success,
+		Error:      page.ErrorMessage,
+		Markdown:   page.Markdown.RawMarkdown,
+		HTML:       page.HTML,
+		Links:      page.Links,
+	}
+	if title, ok := page.Metadata["title"].(string); ok {
+		result.Title = title
+	}
+	for _, group := range []struct {
+		mediaType string
+		items     []crawler.MediaItem
+	}{
+		{"image", page.Media.Images},
+		{"video", page.Media.Videos},
+		{"audio", page.Media.Audios},
+		{"document", page.Media.Documents},
+	} {
+		for _, item := range group.items {
+			result.Media = append(result.Media, Media{URL: item.URL, Type: group.mediaType})
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package crawlr
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"crawlr/internal/config"
+	"crawlr/internal/storage"
+)
+
+// Storage receives the pages of a crawl as they are crawled. SavePage is
+// called from the goroutine iterating over the crawl.
+type Storage interface {
+	SavePage(ctx context.Context, page *Result) error
+}
+
+// DirStorage saves pages as markdown files in a library directory laid out
+// like those of the crawlr command, listed in its manifest.json
+type DirStorage struct {
+	store *storage.Storage
+}
+
+// NewDirStorage opens the library named library in the output directory,
+// creating it if needed. Pages already in the library are overwritten.
+func NewDirStorage(output, library string) (*DirStorage, error) {
+	cfg := config.DefaultConfig()
+	cfg.Output = output
+	cfg.Library = library
+	cfg.OverwriteFiles = true
+
+	store, err := storage.NewStorage(cfg, silentLogger())
+	if err != nil {
+		return nil, err
+	}
+	return &DirStorage{store: store}, nil
+}
+
+// Path returns the directory of the library
+func (s *DirStorage) Path() string {
+	return s.store.GetLibraryPath()
+}
+
+// SavePage saves the markdown of a page and records it in the manifest
+func (s *DirStorage) SavePage(ctx context.Context, page *Result) error {
+	if page.Markdown == "" {
+		return nil
+	}
+	info, err := s.store.SaveMarkdown(page.Markdown, page.URL)
+	if err != nil {
+		return err
+	}
+	if page.FinalURL != page.URL {
+		info.FinalURL = page.FinalURL
+	}
+	info.Links = page.Links
+	if err := s.store.RecordPage(info, page.StatusCode, time.Now()); err != nil {
+		return fmt.Errorf("failed to record page in manifest: %w", err)
+	}
+	return nil
+}
+
+// Close writes the manifest of the library
+func (s *DirStorage) Close() error {
+	return s.store.Close()
+}